@@ -25,6 +25,7 @@ import (
 	"github.com/kokprojects/go-kok"
 	"github.com/kokprojects/go-kok/accounts/abi"
 	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/crypto"
 )
@@ -78,6 +79,9 @@ func NewBoundContract(address common.Address, abi abi.ABI, caller ContractCaller
 
 // DeployContract deploys a contract onto the kokereum blockchain and binds the
 // deployment address with a Go wrapper.
+//
+// Note, on kok a contract creation transaction deploys a template and not a
+// runnable contract, use DeployTemplate and InstantiateTemplate instead.
 func DeployContract(opts *TransactOpts, abi abi.ABI, bytecode []byte, backend ContractBackend, params ...interface{}) (common.Address, *types.Transaction, *BoundContract, error) {
 	// Otherwise try to deploy the contract
 	c := NewBoundContract(common.Address{}, abi, backend, backend)
@@ -86,7 +90,39 @@ func DeployContract(opts *TransactOpts, abi abi.ABI, bytecode []byte, backend Co
 	if err != nil {
 		return common.Address{}, nil, nil, err
 	}
-	tx, err := c.transact(opts, nil, append(bytecode, input...))
+	tx, err := c.transact(opts, types.Binary, nil, append(bytecode, input...))
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	c.address = crypto.CreateAddress(opts.From, tx.Nonce())
+	return c.address, tx, c, nil
+}
+
+// DeployTemplate deploys the bytecode of a contract as a template onto the kok
+// blockchain. Contracts instantiated from the template pay the developer share
+// of their transaction fees to coinbase.
+func DeployTemplate(opts *TransactOpts, bytecode []byte, coinbase common.Address, backend ContractBackend) (common.Address, *types.Transaction, error) {
+	c := NewBoundContract(common.Address{}, abi.ABI{}, backend, backend)
+
+	code := append(common.CopyBytes(bytecode), coinbase.Bytes()...)
+	tx, err := c.transact(opts, types.Binary, nil, code)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return crypto.CreateAddress(opts.From, tx.Nonce()), tx, nil
+}
+
+// InstantiateTemplate creates a new contract from a template deployed with
+// DeployTemplate, running the constructor with params as input values, and
+// binds the address of the new contract with a Go wrapper.
+func InstantiateTemplate(opts *TransactOpts, abi abi.ABI, template common.Address, backend ContractBackend, params ...interface{}) (common.Address, *types.Transaction, *BoundContract, error) {
+	c := NewBoundContract(template, abi, backend, backend)
+
+	input, err := c.abi.Pack("", params...)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	tx, err := c.transact(opts, types.Binary, &template, input)
 	if err != nil {
 		return common.Address{}, nil, nil, err
 	}
@@ -152,18 +188,29 @@ func (c *BoundContract) Transact(opts *TransactOpts, mkokod string, params ...in
 	if err != nil {
 		return nil, err
 	}
-	return c.transact(opts, &c.address, input)
+	return c.transact(opts, types.Binary, &c.address, input)
 }
 
 // Transfer initiates a plain transaction to move funds to the contract, calling
 // its default mkokod if one is available.
 func (c *BoundContract) Transfer(opts *TransactOpts) (*types.Transaction, error) {
-	return c.transact(opts, &c.address, nil)
+	return c.transact(opts, types.Binary, &c.address, nil)
+}
+
+// SourceCode records a source chain transaction with the given payload on the
+// contract or template.
+func (c *BoundContract) SourceCode(opts *TransactOpts, input []byte) (*types.Transaction, error) {
+	return c.transact(opts, types.SourceCode, &c.address, input)
+}
+
+// Endorse endorses the contract or template under the given tag.
+func (c *BoundContract) Endorse(opts *TransactOpts, tag []byte) (*types.Transaction, error) {
+	return c.transact(opts, types.Endorse, &c.address, tag)
 }
 
 // transact executes an actual transaction invocation, first deriving any missing
 // authorization fields, and then scheduling the transaction for execution.
-func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, input []byte) (*types.Transaction, error) {
+func (c *BoundContract) transact(opts *TransactOpts, txType types.TxType, contract *common.Address, input []byte) (*types.Transaction, error) {
 	var err error
 
 	// Ensure a valid value field and resolve the account nonce
@@ -189,6 +236,11 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 		}
 	}
 	gasLimit := opts.GasLimit
+	if gasLimit == nil && txType != types.Binary {
+		// Source code and endorsement transactions don't execute any code, so
+		// only the intrinsic gas (priced as a template creation) is needed
+		gasLimit = core.IntrinsicGas(input, true, true)
+	}
 	if gasLimit == nil {
 		// Gas estimation cannot succeed without code for mkokod invocations
 		if contract != nil {
@@ -210,7 +262,7 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 	if contract == nil {
 		rawTx = types.NewContractCreation(nonce, value, gasLimit, gasPrice, input)
 	} else {
-		rawTx = types.NewTransaction(types.Binary, nonce, c.address, value, gasLimit, gasPrice, input)
+		rawTx = types.NewTransaction(txType, nonce, c.address, value, gasLimit, gasPrice, input)
 	}
 	if opts.Signer == nil {
 		return nil, errors.New("no signer to authorize the transaction with")
@@ -0,0 +1,138 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/kokprojects/go-kok"
+	"github.com/kokprojects/go-kok/accounts/abi"
+	"github.com/kokprojects/go-kok/accounts/abi/bind"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+)
+
+// mockBackend is a contract backend recording the transactions sent through it.
+type mockBackend struct {
+	code  map[common.Address][]byte
+	nonce uint64
+	sent  []*types.Transaction
+}
+
+func (b *mockBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return b.code[contract], nil
+}
+
+func (b *mockBackend) CallContract(ctx context.Context, call kokereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (b *mockBackend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return b.code[account], nil
+}
+
+func (b *mockBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return b.nonce, nil
+}
+
+func (b *mockBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (b *mockBackend) EstimateGas(ctx context.Context, call kokereum.CallMsg) (*big.Int, error) {
+	return big.NewInt(100000), nil
+}
+
+func (b *mockBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	b.nonce++
+	return nil
+}
+
+// Tests that templates are deployed and instantiated with the transactions the
+// kok state transition expects, and that source code and endorsement transactions
+// are sent with their own types.
+func TestTemplateDeployment(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(`[{"inputs":[{"name":"value","type":"uint256"}],"type":"constructor"}]`))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	var (
+		backend  = &mockBackend{code: make(map[common.Address][]byte), nonce: 3}
+		auth     = bind.NewKeyedTransactor(testKey)
+		coinbase = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+		bytecode = common.FromHex("6060604052600a8060106000396000f360606040526008565b00")
+	)
+	// Deploy the template and check that the coinbase is appended to the code
+	template, tx, err := bind.DeployTemplate(auth, bytecode, coinbase, backend)
+	if err != nil {
+		t.Fatalf("failed to deploy template: %v", err)
+	}
+	if want := crypto.CreateAddress(auth.From, 3); template != want {
+		t.Errorf("template address mismatch: have %x, want %x", template, want)
+	}
+	if tx.To() != nil || tx.Type() != types.Binary {
+		t.Errorf("template deployment is not a contract creation")
+	}
+	if want := append(common.CopyBytes(bytecode), coinbase.Bytes()...); !bytes.Equal(tx.Data(), want) {
+		t.Errorf("template code mismatch: have %x, want %x", tx.Data(), want)
+	}
+	// Instantiate a contract from the template
+	backend.code[template] = bytecode
+
+	address, tx, contract, err := bind.InstantiateTemplate(auth, parsed, template, backend, big.NewInt(42))
+	if err != nil {
+		t.Fatalf("failed to instantiate template: %v", err)
+	}
+	if want := crypto.CreateAddress(auth.From, 4); address != want {
+		t.Errorf("contract address mismatch: have %x, want %x", address, want)
+	}
+	if tx.To() == nil || *tx.To() != template || tx.Type() != types.Binary {
+		t.Errorf("instantiation not sent to the template")
+	}
+	if want := common.LeftPadBytes([]byte{42}, 32); !bytes.Equal(tx.Data(), want) {
+		t.Errorf("constructor input mismatch: have %x, want %x", tx.Data(), want)
+	}
+	// Send the kok specific transactions to the new contract
+	backend.code[address] = bytecode
+
+	payload := []byte("source")
+	if tx, err = contract.SourceCode(auth, payload); err != nil {
+		t.Fatalf("failed to send source code transaction: %v", err)
+	}
+	if tx.Type() != types.SourceCode || *tx.To() != address || !bytes.Equal(tx.Data(), payload) {
+		t.Errorf("source code transaction mismatch: type %v, to %x, data %x", tx.Type(), tx.To(), tx.Data())
+	}
+	if want := core.IntrinsicGas(payload, true, true); tx.Gas().Cmp(want) != 0 {
+		t.Errorf("source code gas mismatch: have %v, want %v", tx.Gas(), want)
+	}
+	if tx, err = contract.Endorse(auth, []byte("tag")); err != nil {
+		t.Fatalf("failed to send endorsement: %v", err)
+	}
+	if tx.Type() != types.Endorse || *tx.To() != address {
+		t.Errorf("endorsement mismatch: type %v, to %x", tx.Type(), tx.To())
+	}
+	if len(backend.sent) != 4 {
+		t.Errorf("sent transaction count mismatch: have %d, want %d", len(backend.sent), 4)
+	}
+}
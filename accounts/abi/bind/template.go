@@ -77,8 +77,27 @@ package {{.Package}}
 		  }
 		  return address, tx, &{{.Type}}{ {{.Type}}Caller: {{.Type}}Caller{contract: contract}, {{.Type}}Transactor: {{.Type}}Transactor{contract: contract} }, nil
 		}
+
+		// Deploy{{.Type}}Template deploys the bytecode of {{.Type}} as a kok template, paying the
+		// developer share of the fees of contracts instantiated from it to coinbase.
+		func Deploy{{.Type}}Template(auth *bind.TransactOpts, backend bind.ContractBackend, coinbase common.Address) (common.Address, *types.Transaction, error) {
+		  return bind.DeployTemplate(auth, common.FromHex({{.Type}}Bin), coinbase, backend)
+		}
 	{{end}}
 
+	// Instantiate{{.Type}} creates a new {{.Type}} contract from a deployed kok template, binding an instance of {{.Type}} to it.
+	func Instantiate{{.Type}}(auth *bind.TransactOpts, backend bind.ContractBackend, template common.Address {{range .Constructor.Inputs}}, {{.Name}} {{bindtype .Type}}{{end}}) (common.Address, *types.Transaction, *{{.Type}}, error) {
+	  parsed, err := abi.JSON(strings.NewReader({{.Type}}ABI))
+	  if err != nil {
+	    return common.Address{}, nil, nil, err
+	  }
+	  address, tx, contract, err := bind.InstantiateTemplate(auth, parsed, template, backend {{range .Constructor.Inputs}}, {{.Name}}{{end}})
+	  if err != nil {
+	    return common.Address{}, nil, nil, err
+	  }
+	  return address, tx, &{{.Type}}{ {{.Type}}Caller: {{.Type}}Caller{contract: contract}, {{.Type}}Transactor: {{.Type}}Transactor{contract: contract} }, nil
+	}
+
 	// {{.Type}} is an auto generated Go binding around an kokereum contract.
 	type {{.Type}} struct {
 	  {{.Type}}Caller     // Read-only binding to the contract
@@ -187,6 +206,16 @@ package {{.Package}}
 		return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.Transact(opts, mkokod, params...)
 	}
 
+	// SourceCode records a source chain transaction with the given payload on the contract.
+	func (_{{$contract.Type}} *{{$contract.Type}}Raw) SourceCode(opts *bind.TransactOpts, input []byte) (*types.Transaction, error) {
+		return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.SourceCode(opts, input)
+	}
+
+	// Endorse endorses the contract under the given tag.
+	func (_{{$contract.Type}} *{{$contract.Type}}Raw) Endorse(opts *bind.TransactOpts, tag []byte) (*types.Transaction, error) {
+		return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.Endorse(opts, tag)
+	}
+
 	// Call invokes the (constant) contract mkokod with params as input values and
 	// sets the output to result. The result type might be a single field for simple
 	// returns, a slice of interfaces for anonymous returns and a struct for named
@@ -206,6 +235,16 @@ package {{.Package}}
 		return _{{$contract.Type}}.Contract.contract.Transact(opts, mkokod, params...)
 	}
 
+	// SourceCode records a source chain transaction with the given payload on the contract.
+	func (_{{$contract.Type}} *{{$contract.Type}}TransactorRaw) SourceCode(opts *bind.TransactOpts, input []byte) (*types.Transaction, error) {
+		return _{{$contract.Type}}.Contract.contract.SourceCode(opts, input)
+	}
+
+	// Endorse endorses the contract under the given tag.
+	func (_{{$contract.Type}} *{{$contract.Type}}TransactorRaw) Endorse(opts *bind.TransactOpts, tag []byte) (*types.Transaction, error) {
+		return _{{$contract.Type}}.Contract.contract.Endorse(opts, tag)
+	}
+
 	{{range .Calls}}
 		// {{.Normalized.Name}} is a free data retrieval call binding the contract mkokod 0x{{printf "%x" .Original.Id}}.
 		//
@@ -23,7 +23,8 @@ import (
 )
 
 var (
-	errBadBool = errors.New("abi: improperly encoded boolean value")
+	errBadBool   = errors.New("abi: improperly encoded boolean value")
+	errBadRevert = errors.New("abi: improperly encoded revert reason")
 )
 
 // formatSliceString formats the reflection kind with the given slice size
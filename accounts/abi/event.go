@@ -65,7 +65,7 @@ func (e Event) tupleUnpack(v interface{}, output []byte) error {
 		return fmt.Errorf("abi: cannot unmarshal tuple in to %v", typ)
 	}
 
-	j := 0
+	j, k := 0, 0
 	for i := 0; i < len(e.Inputs); i++ {
 		input := e.Inputs[i]
 		if input.Indexed {
@@ -75,10 +75,12 @@ func (e Event) tupleUnpack(v interface{}, output []byte) error {
 			// need to move this up because they read sequentially
 			j += input.Type.Size
 		}
-		marshalledValue, err := toGoType((i+j)*32, input.Type, output)
+		// indexed inputs are stored in the topics, so only count the ones in the data
+		marshalledValue, err := toGoType((k+j)*32, input.Type, output)
 		if err != nil {
 			return err
 		}
+		k++
 		reflectValue := reflect.ValueOf(marshalledValue)
 
 		switch value.Kind() {
@@ -17,6 +17,7 @@
 package abi
 
 import (
+	"math/big"
 	"strings"
 	"testing"
 
@@ -54,3 +55,22 @@ func TestEventId(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the non-indexed inputs of an event are unpacked from the log data,
+// skipping over the indexed ones stored in the topics.
+func TestEventUnpackIndexed(t *testing.T) {
+	definition := `[{ "type" : "event", "name" : "Transfer", "inputs": [{ "indexed": true, "name" : "from", "type": "address" }, { "indexed": true, "name" : "to", "type": "address" }, { "name": "value", "type": "uint256" }] }]`
+	abi, err := JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var transfer struct {
+		Value *big.Int
+	}
+	if err := abi.Unpack(&transfer, "Transfer", common.LeftPadBytes([]byte{42}, 32)); err != nil {
+		t.Fatalf("failed to unpack event: %v", err)
+	}
+	if transfer.Value == nil || transfer.Value.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("value mismatch: have %v, want 42", transfer.Value)
+	}
+}
@@ -17,14 +17,20 @@
 package abi
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"math/big"
 	"reflect"
 
 	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/crypto"
 )
 
+// revertSelector is the mkokod id of Error(string), the return data of a revert
+// with a reason string being the encoding of a call to it.
+var revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
 // unpacker is a utility interface that enables us to have
 // abstraction between events and mkokods and also to properly
 // "unpack" them; e.g. events use Inputs, mkokods use Outputs.
@@ -204,6 +210,27 @@ func lengthPrefixPointsTo(index int, output []byte) (start int, length int, err
 	return
 }
 
+// UnpackRevert resolves the reason string of a reverted execution from its return
+// data, expected to be the ABI encoding of a call to Error(string).
+func UnpackRevert(data []byte) (string, error) {
+	if len(data) < 4+32 || !bytes.Equal(data[:4], revertSelector) {
+		return "", errBadRevert
+	}
+	data = data[4:]
+
+	offset := new(big.Int).SetBytes(data[:32])
+	if offset.BitLen() > 32 || offset.Uint64()+32 > uint64(len(data)) {
+		return "", errBadRevert
+	}
+	start := offset.Uint64() + 32
+
+	length := new(big.Int).SetBytes(data[start-32 : start])
+	if length.BitLen() > 32 || start+length.Uint64() > uint64(len(data)) {
+		return "", errBadRevert
+	}
+	return string(data[start : start+length.Uint64()]), nil
+}
+
 // checks for proper formatting of byte output
 func bytesAreProper(output []byte) error {
 	if len(output) == 0 {
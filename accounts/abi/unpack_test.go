@@ -584,3 +584,27 @@ func TestUnmarshal(t *testing.T) {
 		t.Fatal("expected error:", err)
 	}
 }
+
+func TestUnpackRevert(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"", "", errBadRevert},
+		{"08c379a1", "", errBadRevert},
+		{"08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000000d72657665727420726561736f6e00000000000000000000000000000000000000", "revert reason", nil},
+		{"08c379a0ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe0", "", errBadRevert},
+		{"08c379a00000000000000000000000000000000000000000000000000000000000000020ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "", errBadRevert},
+	}
+	for i, test := range tests {
+		input, _ := hex.DecodeString(test.input)
+		reason, err := UnpackRevert(input)
+		if err != test.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+		}
+		if reason != test.want {
+			t.Errorf("test %d: reason mismatch: have %q, want %q", i, reason, test.want)
+		}
+	}
+}
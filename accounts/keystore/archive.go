@@ -0,0 +1,251 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/kokprojects/go-kok/accounts"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/crypto/randentropy"
+	"golang.org/x/crypto/scrypt"
+)
+
+// archiveVersion is the current version of the encrypted keystore archive format.
+const archiveVersion = 1
+
+// archiveJSON is the on-disk representation of a keystore archive: a list of
+// keystore files, serialized as a JSON array and encrypted as a whole with a
+// master passphrase using the same scheme as individual key files.
+type archiveJSON struct {
+	Version  int        `json:"version"`
+	Accounts []string   `json:"accounts"`
+	Crypto   cryptoJSON `json:"crypto"`
+}
+
+// ExportArchive bundles the key files of the given accounts, as they are stored
+// on disk, into a single archive encrypted with the master passphrase. The keys
+// themselves stay encrypted with their own passphrases, so unencrypted key files
+// can't be archived.
+func (ks *KeyStore) ExportArchive(accs []accounts.Account, auth string) ([]byte, error) {
+	var (
+		addrs []string
+		keys  []json.RawMessage
+	)
+	for _, a := range accs {
+		a, err := ks.Find(a)
+		if err != nil {
+			return nil, err
+		}
+		keyjson, err := ioutil.ReadFile(a.URL.Path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := parseArchivedKey(keyjson); err != nil {
+			return nil, fmt.Errorf("key file %s: %v", a.URL.Path, err)
+		}
+		addrs = append(addrs, a.Address.Hex())
+		keys = append(keys, json.RawMessage(keyjson))
+	}
+	plain, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+	N, P := StandardScryptN, StandardScryptP
+	if store, ok := ks.storage.(*keyStorePassphrase); ok {
+		N, P = store.scryptN, store.scryptP
+	}
+	crypted, err := encryptData(plain, auth, N, P)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&archiveJSON{Version: archiveVersion, Accounts: addrs, Crypto: crypted})
+}
+
+// ImportArchive decrypts an archive created by ExportArchive and stores every
+// contained key file into the key directory. The archive is rejected as a whole
+// if any of its key files isn't a valid version 3 key, or if an address occurs
+// more than once. Keys for addresses already present in the keystore are skipped.
+// The list of newly imported accounts is returned.
+func (ks *KeyStore) ImportArchive(archive []byte, auth string) ([]accounts.Account, error) {
+	keys, err := DecryptArchive(archive, auth)
+	if err != nil {
+		return nil, err
+	}
+	// Validate all the keys before touching the key directory
+	addrs := make([]common.Address, len(keys))
+	seen := make(map[common.Address]bool)
+	for i, keyjson := range keys {
+		addr, err := parseArchivedKey(keyjson)
+		if err != nil {
+			return nil, fmt.Errorf("archived key %d: %v", i, err)
+		}
+		if seen[addr] {
+			return nil, fmt.Errorf("archived key %d: duplicate address %x", i, addr)
+		}
+		seen[addr] = true
+		addrs[i] = addr
+	}
+	var imported []accounts.Account
+	for i, keyjson := range keys {
+		addr := addrs[i]
+		if ks.cache.hasAddress(addr) {
+			continue
+		}
+		a := accounts.Account{Address: addr, URL: accounts.URL{Scheme: KeyStoreScheme, Path: ks.storage.JoinPath(keyFileName(addr))}}
+		if err := writeKeyFile(a.URL.Path, keyjson); err != nil {
+			return imported, err
+		}
+		ks.cache.add(a)
+		imported = append(imported, a)
+	}
+	ks.refreshWallets()
+	return imported, nil
+}
+
+// parseArchivedKey checks that an archived key file is a version 3 encrypted key
+// the keystore is able to decrypt, returning the address of the key.
+func parseArchivedKey(keyjson []byte) (common.Address, error) {
+	k := new(encryptedKeyJSONV3)
+	if err := json.Unmarshal(keyjson, k); err != nil {
+		return common.Address{}, err
+	}
+	if k.Version != version {
+		return common.Address{}, fmt.Errorf("version not supported: %v", k.Version)
+	}
+	if !common.IsHexAddress(k.Address) {
+		return common.Address{}, fmt.Errorf("invalid address %q", k.Address)
+	}
+	addr := common.HexToAddress(k.Address)
+	if addr == (common.Address{}) {
+		return common.Address{}, errors.New("zero address")
+	}
+	if k.Crypto.Cipher != "aes-128-ctr" {
+		return common.Address{}, fmt.Errorf("cipher not supported: %v", k.Crypto.Cipher)
+	}
+	// Ensure the KDF parameters are present, getKDFKey doesn't check them
+	var numeric []string
+	switch k.Crypto.KDF {
+	case keyHeaderKDF:
+		numeric = []string{"dklen", "n", "r", "p"}
+	case "pbkdf2":
+		if prf, _ := k.Crypto.KDFParams["prf"].(string); prf != "hmac-sha256" {
+			return common.Address{}, fmt.Errorf("PBKDF2 PRF not supported: %v", k.Crypto.KDFParams["prf"])
+		}
+		numeric = []string{"dklen", "c"}
+	default:
+		return common.Address{}, fmt.Errorf("KDF not supported: %v", k.Crypto.KDF)
+	}
+	for _, param := range numeric {
+		if _, ok := k.Crypto.KDFParams[param].(float64); !ok {
+			return common.Address{}, fmt.Errorf("missing KDF parameter %q", param)
+		}
+	}
+	salt, _ := k.Crypto.KDFParams["salt"].(string)
+	for _, field := range []string{salt, k.Crypto.CipherText, k.Crypto.CipherParams.IV, k.Crypto.MAC} {
+		if _, err := hex.DecodeString(field); err != nil || len(field) == 0 {
+			return common.Address{}, errors.New("malformed crypto parameters")
+		}
+	}
+	return addr, nil
+}
+
+// DecryptArchive decrypts a keystore archive with the master passphrase, returning
+// the raw (still individually encrypted) key files contained within.
+func DecryptArchive(archive []byte, auth string) ([]json.RawMessage, error) {
+	a := new(archiveJSON)
+	if err := json.Unmarshal(archive, a); err != nil {
+		return nil, err
+	}
+	if a.Version != archiveVersion {
+		return nil, fmt.Errorf("archive version not supported: %v", a.Version)
+	}
+	plain, err := decryptData(a.Crypto, auth)
+	if err != nil {
+		return nil, err
+	}
+	var keys []json.RawMessage
+	if err := json.Unmarshal(plain, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// encryptData encrypts an arbitrary blob with a passphrase using scrypt and
+// aes-128-ctr, mirroring the version 3 key file format.
+func encryptData(data []byte, auth string, scryptN, scryptP int) (cryptoJSON, error) {
+	salt := randentropy.GetEntropyCSPRNG(32)
+	derivedKey, err := scrypt.Key([]byte(auth), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return cryptoJSON{}, err
+	}
+	iv := randentropy.GetEntropyCSPRNG(aes.BlockSize)
+	cipherText, err := aesCTRXOR(derivedKey[:16], data, iv)
+	if err != nil {
+		return cryptoJSON{}, err
+	}
+	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+
+	return cryptoJSON{
+		Cipher:       "aes-128-ctr",
+		CipherText:   hex.EncodeToString(cipherText),
+		CipherParams: cipherparamsJSON{IV: hex.EncodeToString(iv)},
+		KDF:          keyHeaderKDF,
+		KDFParams: map[string]interface{}{
+			"n":     scryptN,
+			"r":     scryptR,
+			"p":     scryptP,
+			"dklen": scryptDKLen,
+			"salt":  hex.EncodeToString(salt),
+		},
+		MAC: hex.EncodeToString(mac),
+	}, nil
+}
+
+// decryptData is the inverse of encryptData.
+func decryptData(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
+	if cryptoJSON.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("Cipher not supported: %v", cryptoJSON.Cipher)
+	}
+	mac, err := hex.DecodeString(cryptoJSON.MAC)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(cryptoJSON.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(cryptoJSON.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := getKDFKey(cryptoJSON, auth)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrDecrypt
+	}
+	return aesCTRXOR(derivedKey[:16], cipherText, iv)
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Tests that a set of accounts can be exported into an encrypted archive and
+// imported into a fresh keystore, keeping their original passphrases.
+func TestArchiveRoundtrip(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a1, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := ks.NewAccount("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := ks.ExportArchive(ks.Accounts(), "master")
+	if err != nil {
+		t.Fatalf("failed to export archive: %v", err)
+	}
+	if _, err := DecryptArchive(archive, "wrong"); err != ErrDecrypt {
+		t.Fatalf("wrong master passphrase error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	dir2, ks2 := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir2)
+
+	imported, err := ks2.ImportArchive(archive, "master")
+	if err != nil {
+		t.Fatalf("failed to import archive: %v", err)
+	}
+	if len(imported) != 2 {
+		t.Fatalf("imported account count mismatch: have %d, want %d", len(imported), 2)
+	}
+	if err := ks2.Unlock(a1, "foo"); err != nil {
+		t.Errorf("failed to unlock first imported account: %v", err)
+	}
+	if err := ks2.Unlock(a2, "bar"); err != nil {
+		t.Errorf("failed to unlock second imported account: %v", err)
+	}
+	// Reimporting should skip the already known accounts
+	if imported, err = ks2.ImportArchive(archive, "master"); err != nil || len(imported) != 0 {
+		t.Errorf("reimport mismatch: have %d accounts (err %v), want none", len(imported), err)
+	}
+}
+
+// Tests that archives containing malformed keys or duplicate addresses are
+// rejected as a whole, without importing any of their keys.
+func TestArchiveImportInvalid(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := ioutil.ReadFile(a.URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero := bytes.Replace(valid, []byte(hex.EncodeToString(a.Address[:])), []byte(strings.Repeat("0", 40)), 1)
+	unencrypted := []byte(`{"address":"` + hex.EncodeToString(a.Address[:]) + `","privatekey":"00","id":"","version":3}`)
+
+	tests := []struct {
+		name string
+		keys [][]byte
+	}{
+		{"duplicate", [][]byte{valid, valid}},
+		{"zero address", [][]byte{valid, zero}},
+		{"unencrypted", [][]byte{unencrypted}},
+		{"malformed", [][]byte{valid, []byte(`[]`)}},
+	}
+	for _, tt := range tests {
+		keys := make([]json.RawMessage, len(tt.keys))
+		for i, key := range tt.keys {
+			keys[i] = key
+		}
+		plain, _ := json.Marshal(keys)
+		crypted, err := encryptData(plain, "master", veryLightScryptN, veryLightScryptP)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive, _ := json.Marshal(&archiveJSON{Version: archiveVersion, Crypto: crypted})
+
+		dir2, ks2 := tmpKeyStore(t, true)
+		if _, err := ks2.ImportArchive(archive, "master"); err == nil {
+			t.Errorf("%s: archive imported", tt.name)
+		}
+		if accs := ks2.Accounts(); len(accs) != 0 {
+			t.Errorf("%s: accounts imported from rejected archive: %v", tt.name, accs)
+		}
+		os.RemoveAll(dir2)
+	}
+	// Unencrypted keys must not make it into archives in the first place
+	dir3, ks3 := tmpKeyStore(t, false)
+	defer os.RemoveAll(dir3)
+
+	if _, err := ks3.NewAccount(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks3.ExportArchive(ks3.Accounts(), "master"); err == nil {
+		t.Errorf("unencrypted key exported")
+	}
+}
@@ -61,6 +61,10 @@ import (
 	"github.com/kokprojects/go-kok/internal/build"
 )
 
+// versionPackage is the import path of the package holding the build metadata
+// injected at link time.
+const versionPackage = "github.com/kokprojects/go-kok/internal/version"
+
 var (
 	// Files that end up in the gkok*.zip archive.
 	gkokArchiveFiles = []string{
@@ -240,7 +244,16 @@ func doInstall(cmdline []string) {
 func buildFlags(env build.Environment) (flags []string) {
 	var ld []string
 	if env.Commit != "" {
-		ld = append(ld, "-X", "main.gitCommit="+env.Commit)
+		ld = append(ld, "-X", versionPackage+".gitCommit="+env.Commit)
+	}
+	if env.Branch != "" {
+		ld = append(ld, "-X", versionPackage+".gitBranch="+env.Branch)
+	}
+	if env.Date != "" {
+		ld = append(ld, "-X", versionPackage+".buildDate="+env.Date)
+	}
+	if env.Dirty {
+		ld = append(ld, "-X", versionPackage+".gitDirty=true")
 	}
 	if runtime.GOOS == "darwin" {
 		ld = append(ld, "-s")
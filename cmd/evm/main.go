@@ -23,13 +23,12 @@ import (
 	"os"
 
 	"github.com/kokprojects/go-kok/cmd/utils"
+	"github.com/kokprojects/go-kok/internal/version"
 	"gopkg.in/urfave/cli.v1"
 )
 
-var gitCommit = "" // Git SHA1 commit hash of the release (set via linker flags)
-
 var (
-	app = utils.NewApp(gitCommit, "the evm command line interface")
+	app = utils.NewApp(version.Commit(), "the evm command line interface")
 
 	DebugFlag = cli.BoolFlag{
 		Name:  "debug",
@@ -244,7 +244,7 @@ func newFaucet(genesis *core.Genesis, port int, enodes []*discv5.Node, network u
 		if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 			var serv *les.Lightkokereum
 			ctx.Service(&serv)
-			return kokstats.New(stats, nil, serv)
+			return kokstats.New(kokstats.ParseTargets(stats), nil, serv)
 		}); err != nil {
 			return nil, err
 		}
@@ -19,6 +19,7 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 
 	"github.com/kokprojects/go-kok/accounts"
 	"github.com/kokprojects/go-kok/accounts/keystore"
@@ -30,6 +31,11 @@ import (
 )
 
 var (
+	exportAllFlag = cli.BoolFlag{
+		Name:  "all",
+		Usage: "Export all accounts in the keystore",
+	}
+
 	walletCommand = cli.Command{
 		Name:      "wallet",
 		Usage:     "Manage kokereum presale wallets",
@@ -186,6 +192,48 @@ Note:
 As you can directly copy your encrypted accounts to another kokereum instance,
 this import mechanism is not needed when you transfer an account between
 nodes.
+`,
+			},
+			{
+				Name:   "export",
+				Usage:  "Export accounts into an encrypted archive",
+				Action: utils.MigrateFlags(accountExport),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					exportAllFlag,
+				},
+				ArgsUsage: "<archiveFile> [address...]",
+				Description: `
+    gkok account export [--all] <archiveFile> [address...]
+
+Bundles the key files of the given accounts (or every account if --all is set)
+into a single archive, encrypted with a master passphrase. The keys inside the
+archive remain encrypted with their own passphrases, so the archive can be used
+to migrate all accounts of a validator machine in one step.
+
+For non-interactive use the master passphrase can be specified with the --password flag.
+`,
+			},
+			{
+				Name:   "import-archive",
+				Usage:  "Import all accounts from an encrypted archive",
+				Action: utils.MigrateFlags(accountImportArchive),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+				},
+				ArgsUsage: "<archiveFile>",
+				Description: `
+    gkok account import-archive <archiveFile>
+
+Decrypts an archive created by 'gkok account export' with its master passphrase
+and stores the contained key files into the keystore. Accounts that already exist
+in the keystore are skipped. The imported accounts keep their original passphrases.
+
+For non-interactive use the master passphrase can be specified with the --password flag.
 `,
 			},
 		},
@@ -377,3 +425,72 @@ func accountImport(ctx *cli.Context) error {
 	fmt.Printf("Address: {%x}\n", acct.Address)
 	return nil
 }
+
+// accountExport bundles a set of accounts from the keystore into an encrypted
+// archive file.
+func accountExport(ctx *cli.Context) error {
+	if len(ctx.Args()) == 0 {
+		utils.Fatalf("archive file must be given as argument")
+	}
+	archive := ctx.Args().First()
+
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	var accs []accounts.Account
+	if ctx.Bool(exportAllFlag.Name) {
+		accs = ks.Accounts()
+	} else {
+		for _, addr := range ctx.Args().Tail() {
+			account, err := utils.MakeAddress(ks, addr)
+			if err != nil {
+				utils.Fatalf("Could not find account %s: %v", addr, err)
+			}
+			accs = append(accs, account)
+		}
+	}
+	if len(accs) == 0 {
+		utils.Fatalf("No accounts specified to export")
+	}
+	if _, err := os.Stat(archive); err == nil {
+		utils.Fatalf("Archive file %s already exists", archive)
+	}
+	passphrase := getPassPhrase("The archive is locked with a master password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	blob, err := ks.ExportArchive(accs, passphrase)
+	if err != nil {
+		utils.Fatalf("Failed to export accounts: %v", err)
+	}
+	if err := ioutil.WriteFile(archive, blob, 0600); err != nil {
+		utils.Fatalf("Failed to write archive: %v", err)
+	}
+	for _, account := range accs {
+		fmt.Printf("Exported: {%x}\n", account.Address)
+	}
+	return nil
+}
+
+// accountImportArchive restores all accounts from an encrypted archive into the
+// keystore.
+func accountImportArchive(ctx *cli.Context) error {
+	archive := ctx.Args().First()
+	if len(archive) == 0 {
+		utils.Fatalf("archive file must be given as argument")
+	}
+	blob, err := ioutil.ReadFile(archive)
+	if err != nil {
+		utils.Fatalf("Could not read archive file: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	passphrase := getPassPhrase("", false, 0, utils.MakePasswordList(ctx))
+
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	imported, err := ks.ImportArchive(blob, passphrase)
+	if err != nil {
+		utils.Fatalf("Could not import the archive: %v", err)
+	}
+	for _, acct := range imported {
+		fmt.Printf("Address: {%x}\n", acct.Address)
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of go-kokereum.
+//
+// go-kokereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-kokereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-kokereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kokprojects/go-kok/cmd/utils"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/internal/parquet"
+	"github.com/kokprojects/go-kok/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	analyticsFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Value: "parquet",
+		Usage: `Output file format ("parquet" or "csv")`,
+	}
+	analyticsCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportAnalytics),
+		Name:      "export-analytics",
+		Usage:     "Export chain data into columnar files for analytics",
+		ArgsUsage: "<directory> [<blockNumFirst> <blockNumLast>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			analyticsFormatFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-analytics command walks the canonical chain and writes its blocks,
+transactions, logs and DPoS operations (candidate logins and logouts, delegations
+and undelegations) into one table file each within the given directory, ready to
+be loaded into a data warehouse.
+
+Requires a first argument of the directory to write the tables into, which is
+created if missing. Optional second and third arguments restrict the export to
+the given inclusive range of blocks, the whole chain being exported otherwise.`,
+	}
+)
+
+// Columns of the exported analytics tables.
+var (
+	analyticsBlockColumns = []parquet.Column{
+		{Name: "number", Type: parquet.Int64},
+		{Name: "hash", Type: parquet.String},
+		{Name: "parent_hash", Type: parquet.String},
+		{Name: "timestamp", Type: parquet.Int64},
+		{Name: "validator", Type: parquet.String},
+		{Name: "coinbase", Type: parquet.String},
+		{Name: "gas_limit", Type: parquet.Int64},
+		{Name: "gas_used", Type: parquet.Int64},
+		{Name: "transaction_count", Type: parquet.Int64},
+		{Name: "size", Type: parquet.Int64},
+	}
+	analyticsTransactionColumns = []parquet.Column{
+		{Name: "block_number", Type: parquet.Int64},
+		{Name: "transaction_index", Type: parquet.Int64},
+		{Name: "hash", Type: parquet.String},
+		{Name: "type", Type: parquet.String},
+		{Name: "from", Type: parquet.String},
+		{Name: "to", Type: parquet.String},
+		{Name: "value", Type: parquet.String},
+		{Name: "nonce", Type: parquet.Int64},
+		{Name: "gas", Type: parquet.Int64},
+		{Name: "gas_price", Type: parquet.String},
+		{Name: "gas_used", Type: parquet.Int64},
+		{Name: "status", Type: parquet.Int64},
+		{Name: "contract_address", Type: parquet.String},
+	}
+	analyticsLogColumns = []parquet.Column{
+		{Name: "block_number", Type: parquet.Int64},
+		{Name: "transaction_index", Type: parquet.Int64},
+		{Name: "transaction_hash", Type: parquet.String},
+		{Name: "log_index", Type: parquet.Int64},
+		{Name: "address", Type: parquet.String},
+		{Name: "topics", Type: parquet.String},
+		{Name: "data", Type: parquet.String},
+	}
+	analyticsDposColumns = []parquet.Column{
+		{Name: "block_number", Type: parquet.Int64},
+		{Name: "transaction_index", Type: parquet.Int64},
+		{Name: "transaction_hash", Type: parquet.String},
+		{Name: "operation", Type: parquet.String},
+		{Name: "from", Type: parquet.String},
+		{Name: "candidate", Type: parquet.String},
+		{Name: "status", Type: parquet.Int64},
+	}
+)
+
+// analyticsTable is a single output table of the analytics export.
+type analyticsTable interface {
+	Write(row []interface{}) error
+	Close() error
+}
+
+// csvTable writes rows into a CSV file, starting with a header of the column names.
+type csvTable struct {
+	file *os.File
+	csv  *csv.Writer
+}
+
+func newCSVTable(path string, columns []parquet.Column) (*csvTable, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	table := &csvTable{file: file, csv: csv.NewWriter(file)}
+	if err := table.csv.Write(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return table, nil
+}
+
+func (t *csvTable) Write(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = fmt.Sprint(value)
+	}
+	return t.csv.Write(record)
+}
+
+func (t *csvTable) Close() error {
+	t.csv.Flush()
+	if err := t.csv.Error(); err != nil {
+		t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
+
+// parquetTable writes rows into a Parquet file.
+type parquetTable struct {
+	file    *os.File
+	buffer  *bufio.Writer
+	parquet *parquet.Writer
+}
+
+func newParquetTable(path string, columns []parquet.Column) (*parquetTable, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	buffer := bufio.NewWriter(file)
+	writer, err := parquet.NewWriter(buffer, columns)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &parquetTable{file: file, buffer: buffer, parquet: writer}, nil
+}
+
+func (t *parquetTable) Write(row []interface{}) error {
+	return t.parquet.Write(row)
+}
+
+func (t *parquetTable) Close() error {
+	if err := t.parquet.Close(); err != nil {
+		t.file.Close()
+		return err
+	}
+	if err := t.buffer.Flush(); err != nil {
+		t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
+
+// exportAnalytics walks the requested range of the canonical chain, writing its
+// blocks, transactions, logs and DPoS operations into analytics tables.
+func exportAnalytics(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) != 1 && len(args) != 3 {
+		utils.Fatalf("This command requires a directory argument and an optional block range.")
+	}
+	format := ctx.String(analyticsFormatFlag.Name)
+	if format != "parquet" && format != "csv" {
+		utils.Fatalf("Unknown export format %q, want parquet or csv", format)
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	first, last := uint64(0), chain.CurrentBlock().NumberU64()
+	if len(args) == 3 {
+		var ferr, lerr error
+		first, ferr = strconv.ParseUint(args.Get(1), 10, 64)
+		last, lerr = strconv.ParseUint(args.Get(2), 10, 64)
+		if ferr != nil || lerr != nil {
+			utils.Fatalf("Export error in parsing parameters: block number not a positive integer")
+		}
+		if first > last {
+			utils.Fatalf("Export error: first block %d after last block %d", first, last)
+		}
+	}
+	dir := args.First()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		utils.Fatalf("Failed to create export directory: %v", err)
+	}
+	// Create all the output tables of the export
+	var (
+		names  = []string{"blocks", "transactions", "logs", "dpos"}
+		schema = [][]parquet.Column{analyticsBlockColumns, analyticsTransactionColumns, analyticsLogColumns, analyticsDposColumns}
+		tables = make([]analyticsTable, len(names))
+	)
+	for i, name := range names {
+		path := filepath.Join(dir, name+"."+format)
+
+		var err error
+		if format == "csv" {
+			tables[i], err = newCSVTable(path, schema[i])
+		} else {
+			tables[i], err = newParquetTable(path, schema[i])
+		}
+		if err != nil {
+			utils.Fatalf("Failed to create %s table: %v", name, err)
+		}
+	}
+	start, report := time.Now(), time.Now()
+	log.Info("Exporting chain analytics", "dir", dir, "format", format, "first", first, "last", last)
+
+	var txs int
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			utils.Fatalf("Export error: block #%d not found", number)
+		}
+		signer := types.MakeSigner(chain.Config(), block.Number())
+		if err := exportAnalyticsBlock(tables, signer, block, core.GetBlockReceipts(chainDb, block.Hash(), number)); err != nil {
+			utils.Fatalf("Export error at block #%d: %v", number, err)
+		}
+		txs += len(block.Transactions())
+
+		if time.Since(report) > 8*time.Second {
+			log.Info("Exporting chain analytics", "number", number, "txs", txs, "elapsed", common.PrettyDuration(time.Since(start)))
+			report = time.Now()
+		}
+		if number == last {
+			break // Prevent the counter from wrapping at the maximum block number
+		}
+	}
+	for i, table := range tables {
+		if err := table.Close(); err != nil {
+			utils.Fatalf("Failed to finalize %s table: %v", names[i], err)
+		}
+	}
+	log.Info("Exported chain analytics", "blocks", last-first+1, "txs", txs, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// exportAnalyticsBlock writes the rows derived from a single block into the
+// blocks, transactions, logs and dpos tables, in this order.
+func exportAnalyticsBlock(tables []analyticsTable, signer types.Signer, block *types.Block, receipts types.Receipts) error {
+	number := block.NumberU64()
+	if err := tables[0].Write([]interface{}{
+		number,
+		block.Hash().Hex(),
+		block.ParentHash().Hex(),
+		block.Time().Uint64(),
+		block.Validator().Hex(),
+		block.Coinbase().Hex(),
+		block.GasLimit().Uint64(),
+		block.GasUsed().Uint64(),
+		len(block.Transactions()),
+		uint64(block.Size()),
+	}); err != nil {
+		return err
+	}
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		var to string
+		if tx.To() != nil {
+			to = tx.To().Hex()
+		}
+		// Receipts may be missing if the chain was synced without them
+		var (
+			receipt  *types.Receipt
+			gasUsed  uint64
+			status   uint
+			contract string
+		)
+		if i < len(receipts) {
+			receipt = receipts[i]
+			if receipt.GasUsed != nil {
+				gasUsed = receipt.GasUsed.Uint64()
+			}
+			status = receipt.Status
+			if receipt.ContractAddress != (common.Address{}) {
+				contract = receipt.ContractAddress.Hex()
+			}
+		}
+		if err := tables[1].Write([]interface{}{
+			number, i, tx.Hash().Hex(), tx.Type().String(), from.Hex(), to,
+			tx.Value().String(), tx.Nonce(), tx.Gas().Uint64(), tx.GasPrice().String(),
+			gasUsed, uint64(status), contract,
+		}); err != nil {
+			return err
+		}
+		if receipt != nil {
+			for _, l := range receipt.Logs {
+				topics := make([]string, len(l.Topics))
+				for j, topic := range l.Topics {
+					topics[j] = topic.Hex()
+				}
+				if err := tables[2].Write([]interface{}{
+					number, i, tx.Hash().Hex(), uint64(l.Index), l.Address.Hex(),
+					strings.Join(topics, ","), common.ToHex(l.Data),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		// Track the candidate registrations and votes separately
+		var candidate common.Address
+		switch tx.Type() {
+		case types.LoginCandidate, types.LogoutCandidate:
+			candidate = from
+		case types.Delegate, types.UnDelegate:
+			if tx.To() != nil {
+				candidate = *tx.To()
+			}
+		default:
+			continue
+		}
+		if err := tables[3].Write([]interface{}{
+			number, i, tx.Hash().Hex(), tx.Type().String(), from.Hex(), candidate.Hex(), uint64(status),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of go-kokereum.
+//
+// go-kokereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-kokereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-kokereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/internal/parquet"
+)
+
+// memoryTable collects the rows written into an analytics table.
+type memoryTable struct {
+	rows [][]interface{}
+}
+
+func (t *memoryTable) Write(row []interface{}) error {
+	t.rows = append(t.rows, row)
+	return nil
+}
+
+func (t *memoryTable) Close() error { return nil }
+
+// Tests that a block is split into the rows of the analytics tables, with DPoS
+// operations tracked separately from plain transfers.
+func TestAnalyticsBlockRows(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	candidate := common.HexToAddress("0x0102")
+	transfer, _ := types.SignTx(types.NewTransaction(types.Binary, 0, common.HexToAddress("0x03"), big.NewInt(10), big.NewInt(21000), big.NewInt(1), nil), signer, key)
+	delegate, _ := types.SignTx(types.NewTransaction(types.Delegate, 1, candidate, big.NewInt(0), big.NewInt(21000), big.NewInt(1), nil), signer, key)
+
+	receipts := types.Receipts{
+		{Status: types.ReceiptStatusSuccessful, GasUsed: big.NewInt(21000), Logs: []*types.Log{
+			{Address: common.HexToAddress("0x04"), Topics: []common.Hash{{0x05}, {0x06}}, Data: []byte{0x07}, Index: 0},
+		}},
+		{Status: types.ReceiptStatusFailed, GasUsed: big.NewInt(21000)},
+	}
+	header := &types.Header{Number: big.NewInt(7), Time: big.NewInt(1000), GasLimit: big.NewInt(8000000), GasUsed: big.NewInt(42000), Difficulty: big.NewInt(1)}
+	block := types.NewBlock(header, types.Transactions{transfer, delegate}, nil, receipts)
+
+	tables := []*memoryTable{new(memoryTable), new(memoryTable), new(memoryTable), new(memoryTable)}
+	if err := exportAnalyticsBlock([]analyticsTable{tables[0], tables[1], tables[2], tables[3]}, signer, block, receipts); err != nil {
+		t.Fatalf("failed to export block: %v", err)
+	}
+	for i, want := range []int{1, 2, 1, 1} {
+		if have := len(tables[i].rows); have != want {
+			t.Fatalf("table %d: row count mismatch: have %d, want %d", i, have, want)
+		}
+	}
+	schema := [][]parquet.Column{analyticsBlockColumns, analyticsTransactionColumns, analyticsLogColumns, analyticsDposColumns}
+	for i, table := range tables {
+		for _, row := range table.rows {
+			if len(row) != len(schema[i]) {
+				t.Errorf("table %d: column count mismatch: have %d, want %d", i, len(row), len(schema[i]))
+			}
+		}
+	}
+	if have := tables[1].rows[0][4]; have != sender.Hex() {
+		t.Errorf("sender mismatch: have %v, want %v", have, sender.Hex())
+	}
+	if have, want := tables[2].rows[0][5], (common.Hash{0x05}).Hex()+","+(common.Hash{0x06}).Hex(); have != want {
+		t.Errorf("topics mismatch: have %v, want %v", have, want)
+	}
+	if have := tables[3].rows[0]; have[3] != "delegate" || have[5] != candidate.Hex() || have[6] != uint64(types.ReceiptStatusFailed) {
+		t.Errorf("dpos operation mismatch: have %v", have)
+	}
+}
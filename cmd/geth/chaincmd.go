@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
 	"sync/atomic"
@@ -31,15 +32,26 @@ import (
 	"github.com/kokprojects/go-kok/core"
 	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kok"
+	"github.com/kokprojects/go-kok/kok/archive"
 	"github.com/kokprojects/go-kok/kok/downloader"
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/event"
 	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/node"
 	"github.com/kokprojects/go-kok/trie"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"gopkg.in/urfave/cli.v1"
 )
 
+var (
+	chainFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: `Blockchain file format, "rlp" or "json" (one block per line)`,
+		Value: utils.ChainFormatRLP,
+	}
+)
+
 var (
 	initCommand = cli.Command{
 		Action:    utils.MigrateFlags(initGenesis),
@@ -67,11 +79,13 @@ It expects the genesis file as argument.`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			chainFormatFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The import command imports blocks from an RLP-encoded form. The form can be one file
-with several RLP-encoded blocks, or several files can be used.
+with several RLP-encoded blocks, or several files can be used. Files exported with
+--format json are imported with the same flag. Files ending in .gz are decompressed.
 
 If only one file is used, import error will result in failure. If several files are used,
 processing will proceed even if an individual RLP-file import failure occurs.`,
@@ -85,13 +99,18 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.LightModeFlag,
+			chainFormatFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
-if already existing.`,
+if already existing. Files ending in .gz are compressed.
+
+With --format json every block is written as a JSON object on
+its own line, holding the block hash, header, transactions and
+uncles, for processing by audit tooling.`,
 	}
 	copydbCommand = cli.Command{
 		Action:    utils.MigrateFlags(copyDb),
@@ -119,6 +138,22 @@ The first argument must be the directory containing the blockchain to download f
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Remove blockchain and state databases`,
+	}
+	reindexBloomsCommand = cli.Command{
+		Action:    utils.MigrateFlags(reindexBlooms),
+		Name:      "reindex-blooms",
+		Usage:     "Rebuild the bloom bits index of the log filters",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			configFileFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Discards the bloom bits index and generates it again from the canonical chain,
+using the bloom section size of the configuration. Use it after the index got
+corrupted or the section size was changed.`,
 	}
 	dumpCommand = cli.Command{
 		Action:    utils.MigrateFlags(dump),
@@ -198,12 +233,12 @@ func importChain(ctx *cli.Context) error {
 	start := time.Now()
 
 	if len(ctx.Args()) == 1 {
-		if err := utils.ImportChain(chain, ctx.Args().First()); err != nil {
+		if err := utils.ImportChain(chain, ctx.Args().First(), ctx.String(chainFormatFlag.Name)); err != nil {
 			utils.Fatalf("Import error: %v", err)
 		}
 	} else {
 		for _, arg := range ctx.Args() {
-			if err := utils.ImportChain(chain, arg); err != nil {
+			if err := utils.ImportChain(chain, arg, ctx.String(chainFormatFlag.Name)); err != nil {
 				log.Error("Import error", "file", arg, "err", err)
 			}
 		}
@@ -252,6 +287,43 @@ func importChain(ctx *cli.Context) error {
 	return nil
 }
 
+// initFromArchive imports the blocks of a chain archive into the local database
+// before the node is started, leaving only the remaining blocks to be synced from
+// the network.
+func initFromArchive(ctx *cli.Context, stack *node.Node) {
+	light := ctx.GlobalBool(utils.LightModeFlag.Name)
+	if ctx.GlobalIsSet(utils.SyncModeFlag.Name) {
+		light = *utils.GlobalTextMarshaler(ctx, utils.SyncModeFlag.Name).(*downloader.SyncMode) == downloader.LightSync
+	}
+	if light {
+		utils.Fatalf("Bootstrapping from an archive is not supported in light mode")
+	}
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	// Abort the import at the next range on Ctrl-C, keeping the blocks imported so far
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer func() {
+		// Stop the signal delivery before closing, a late Ctrl-C would panic otherwise
+		signal.Stop(interrupt)
+		close(interrupt)
+	}()
+
+	stop := make(chan struct{})
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during archive bootstrap, stopping at next range")
+			close(stop)
+		}
+	}()
+
+	if err := archive.Bootstrap(chain, ctx.GlobalString(utils.InitFromArchiveFlag.Name), stop); err != nil {
+		utils.Fatalf("Archive bootstrap failed: %v", err)
+	}
+}
+
 func exportChain(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -263,7 +335,7 @@ func exportChain(ctx *cli.Context) error {
 	var err error
 	fp := ctx.Args().First()
 	if len(ctx.Args()) < 3 {
-		err = utils.ExportChain(chain, fp)
+		err = utils.ExportChain(chain, fp, ctx.String(chainFormatFlag.Name))
 	} else {
 		// This can be improved to allow for numbers larger than 9223372036854775807
 		first, ferr := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
@@ -274,7 +346,7 @@ func exportChain(ctx *cli.Context) error {
 		if first < 0 || last < 0 {
 			utils.Fatalf("Export error: block number must be greater than 0\n")
 		}
-		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last))
+		err = utils.ExportAppendChain(chain, fp, ctx.String(chainFormatFlag.Name), uint64(first), uint64(last))
 	}
 
 	if err != nil {
@@ -332,6 +404,24 @@ func copyDb(ctx *cli.Context) error {
 	return nil
 }
 
+func reindexBlooms(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	size := cfg.kok.BloomSectionSize
+	if size == 0 || size%8 != 0 {
+		utils.Fatalf("Invalid bloom section size %d, must be a multiple of 8", size)
+	}
+	start := time.Now()
+	if err := kok.RebuildBloomIndex(chain, chainDb, size); err != nil {
+		utils.Fatalf("Bloom index rebuild failed: %v", err)
+	}
+	chain.Stop()
+	fmt.Printf("Bloom index rebuilt in %v\n", time.Since(start))
+	return nil
+}
+
 func removeDB(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 
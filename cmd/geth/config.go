@@ -25,6 +25,7 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"strings"
 	"unicode"
 
 	cli "gopkg.in/urfave/cli.v1"
@@ -32,7 +33,9 @@ import (
 	"github.com/kokprojects/go-kok/cmd/utils"
 	"github.com/kokprojects/go-kok/contracts/release"
 	"github.com/kokprojects/go-kok/dashboard"
+	"github.com/kokprojects/go-kok/internal/version"
 	"github.com/kokprojects/go-kok/kok"
+	"github.com/kokprojects/go-kok/kokstats"
 	"github.com/kokprojects/go-kok/node"
 	"github.com/kokprojects/go-kok/params"
 	whisper "github.com/kokprojects/go-kok/whisper/whisperv5"
@@ -74,7 +77,9 @@ var tomlSettings = toml.Config{
 }
 
 type kokstatsConfig struct {
-	URL string `toml:",omitempty"`
+	URL     string            `toml:",omitempty"` // Comma separated reporting URLs
+	CAFile  string            `toml:",omitempty"` // CA bundle to verify the servers in URL with
+	Targets []kokstats.Target `toml:",omitempty"` // Additional monitoring servers with per server settings
 }
 
 type gkokConfig struct {
@@ -103,7 +108,7 @@ func loadConfig(file string, cfg *gkokConfig) error {
 func defaultNodeConfig() node.Config {
 	cfg := node.DefaultConfig
 	cfg.Name = clientIdentifier
-	cfg.Version = params.VersionWithCommit(gitCommit)
+	cfg.Version = version.WithCommit()
 	cfg.HTTPModules = append(cfg.HTTPModules, "kok", "shh", "dpos")
 	cfg.WSModules = append(cfg.WSModules, "kok", "shh")
 	cfg.IPCPath = "gkok.ipc"
@@ -136,6 +141,9 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gkokConfig) {
 	if ctx.GlobalIsSet(utils.kokStatsURLFlag.Name) {
 		cfg.kokstats.URL = ctx.GlobalString(utils.kokStatsURLFlag.Name)
 	}
+	if ctx.GlobalIsSet(utils.kokStatsCAFlag.Name) {
+		cfg.kokstats.CAFile = ctx.GlobalString(utils.kokStatsCAFlag.Name)
+	}
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
 	utils.SetDashboardConfig(ctx, &cfg.Dashboard)
@@ -175,8 +183,17 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	}
 
 	// Add the kokereum Stats daemon if requested.
-	if cfg.kokstats.URL != "" {
-		utils.RegisterkokStatsService(stack, cfg.kokstats.URL)
+	targets := kokstats.ParseTargets(cfg.kokstats.URL)
+	for i := range targets {
+		targets[i].CAFile = cfg.kokstats.CAFile
+	}
+	if targets = append(targets, cfg.kokstats.Targets...); len(targets) > 0 {
+		utils.RegisterkokStatsService(stack, targets)
+	}
+
+	// Add any third-party services loaded from Go plugins.
+	if paths := ctx.GlobalString(utils.ExecPluginFlag.Name); paths != "" {
+		utils.RegisterPluginService(stack, strings.Split(paths, ","))
 	}
 
 	// Add the release oracle service so it boots along with node.
@@ -187,7 +204,7 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 			Minor:  uint32(params.VersionMinor),
 			Patch:  uint32(params.VersionPatch),
 		}
-		commit, _ := hex.DecodeString(gitCommit)
+		commit, _ := hex.DecodeString(version.Commit())
 		copy(config.Commit[:], commit)
 		return release.NewReleaseService(ctx, config)
 	}); err != nil {
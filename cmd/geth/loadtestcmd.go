@@ -0,0 +1,441 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of go-kokereum.
+//
+// go-kokereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-kokereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-kokereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kokprojects/go-kok/cmd/utils"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokclient"
+	"github.com/kokprojects/go-kok/node"
+	"github.com/kokprojects/go-kok/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	loadtestMaxInflight   = 64               // Maximum number of concurrent transaction submissions
+	loadtestReportCycle   = 10 * time.Second // Interval between progress reports
+	loadtestDrainTimeout  = 30 * time.Second // Time to wait for outstanding inclusions after the run
+	loadtestInclusionPoll = time.Second      // Interval to poll the node for new blocks
+)
+
+var (
+	loadtestAttachFlag = cli.StringFlag{
+		Name:  "attach",
+		Value: node.DefaultIPCEndpoint(clientIdentifier),
+		Usage: "API endpoint to attach to",
+	}
+	loadtestKeyFlag = cli.StringFlag{
+		Name:  "key",
+		Usage: "File containing the hex private key of the funded sending account",
+	}
+	loadtestRateFlag = cli.IntFlag{
+		Name:  "rate",
+		Value: 10,
+		Usage: "Number of transactions to send per second",
+	}
+	loadtestDurationFlag = cli.DurationFlag{
+		Name:  "duration",
+		Value: time.Minute,
+		Usage: "Duration of the load generation",
+	}
+	loadtestMixFlag = cli.StringFlag{
+		Name:  "mix",
+		Value: "transfer=100",
+		Usage: "Comma separated weights of the generated transaction kinds (transfer, call, delegate)",
+	}
+	loadtestContractFlag = cli.StringFlag{
+		Name:  "contract",
+		Usage: "Address of the contract invoked by call transactions",
+	}
+	loadtestCallDataFlag = cli.StringFlag{
+		Name:  "calldata",
+		Usage: "Hex input data of call transactions",
+	}
+	loadtestCallGasFlag = cli.Uint64Flag{
+		Name:  "callgas",
+		Value: 200000,
+		Usage: "Gas allowance of call transactions",
+	}
+	loadtestCandidateFlag = cli.StringFlag{
+		Name:  "candidate",
+		Usage: "Address of the candidate voted for by delegate transactions",
+	}
+	loadtestGasPriceFlag = cli.Uint64Flag{
+		Name:  "gasprice",
+		Usage: "Gas price of the generated transactions (default = suggested by the node)",
+	}
+	loadtestChainIdFlag = cli.Uint64Flag{
+		Name:  "chainid",
+		Value: params.DposChainConfig.ChainId.Uint64(),
+		Usage: "Chain identifier to sign the transactions with",
+	}
+	loadtestCommand = cli.Command{
+		Action:    utils.MigrateFlags(loadtest),
+		Name:      "loadtest",
+		Usage:     "Generate a transaction workload against a node",
+		ArgsUsage: " ",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+The Gkok load test sends a configurable mix of value transfers, contract calls
+and delegate votes from a single funded account to the attached node at a fixed
+rate, reporting the achieved throughput along with the submission and block
+inclusion latencies of the transactions, for capacity planning purposes.
+
+Example:
+
+    gkok loadtest --key key.hex --rate 200 --duration 5m --mix transfer=80,delegate=20 --candidate 0x...
+`,
+		Flags: []cli.Flag{
+			loadtestAttachFlag,
+			loadtestKeyFlag,
+			loadtestRateFlag,
+			loadtestDurationFlag,
+			loadtestMixFlag,
+			loadtestContractFlag,
+			loadtestCallDataFlag,
+			loadtestCallGasFlag,
+			loadtestCandidateFlag,
+			loadtestGasPriceFlag,
+			loadtestChainIdFlag,
+		},
+	}
+)
+
+// loadMix is a weighted set of transaction kinds to generate.
+type loadMix struct {
+	kinds   []string
+	weights []int
+	total   int
+}
+
+// parseLoadMix parses a workload mix in the form of <kind>=<weight>,...
+func parseLoadMix(spec string) (*loadMix, error) {
+	mix := new(loadMix)
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid mix entry %q, want <kind>=<weight>", entry)
+		}
+		switch parts[0] {
+		case "transfer", "call", "delegate":
+		default:
+			return nil, fmt.Errorf("unknown transaction kind %q", parts[0])
+		}
+		weight, err := strconv.Atoi(parts[1])
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q of %s", parts[1], parts[0])
+		}
+		mix.kinds = append(mix.kinds, parts[0])
+		mix.weights = append(mix.weights, weight)
+		mix.total += weight
+	}
+	if mix.total == 0 {
+		return nil, fmt.Errorf("empty transaction mix")
+	}
+	return mix, nil
+}
+
+// has reports whkoker the mix generates transactions of the given kind.
+func (m *loadMix) has(kind string) bool {
+	for i, k := range m.kinds {
+		if k == kind && m.weights[i] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pick selects a transaction kind in proportion to the weights.
+func (m *loadMix) pick(rnd *rand.Rand) string {
+	n := rnd.Intn(m.total)
+	for i, weight := range m.weights {
+		if n < weight {
+			return m.kinds[i]
+		}
+		n -= weight
+	}
+	return m.kinds[len(m.kinds)-1]
+}
+
+// loadStats gathers the outcome of the transactions sent by a load test.
+type loadStats struct {
+	sent      int
+	failed    int
+	skipped   int
+	submits   []time.Duration
+	includes  []time.Duration
+	submitted map[common.Hash]time.Time // Transactions awaiting inclusion
+
+	lock sync.Mutex
+}
+
+// percentiles formats the median, 95th and 99th percentile and maximum of a set
+// of latencies.
+func percentiles(samples []time.Duration) string {
+	if len(samples) == 0 {
+		return "n/a"
+	}
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	at := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+	return fmt.Sprintf("p50=%v p95=%v p99=%v max=%v", at(0.5), at(0.95), at(0.99), sorted[len(sorted)-1])
+}
+
+// loadtest sends a transaction workload to the attached node and reports the
+// achieved throughput and latencies.
+func loadtest(ctx *cli.Context) error {
+	// Assemble the workload configuration
+	mix, err := parseLoadMix(ctx.String(loadtestMixFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid transaction mix: %v", err)
+	}
+	rate := ctx.Int(loadtestRateFlag.Name)
+	if rate <= 0 {
+		utils.Fatalf("Transaction rate must be positive")
+	}
+	if !ctx.IsSet(loadtestKeyFlag.Name) {
+		utils.Fatalf("No sending account key specified (--%s)", loadtestKeyFlag.Name)
+	}
+	key, err := crypto.LoadECDSA(ctx.String(loadtestKeyFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to load sending account key: %v", err)
+	}
+	var contract, candidate common.Address
+	if mix.has("call") {
+		if !common.IsHexAddress(ctx.String(loadtestContractFlag.Name)) {
+			utils.Fatalf("Call transactions require a contract address (--%s)", loadtestContractFlag.Name)
+		}
+		contract = common.HexToAddress(ctx.String(loadtestContractFlag.Name))
+	}
+	if mix.has("delegate") {
+		if !common.IsHexAddress(ctx.String(loadtestCandidateFlag.Name)) {
+			utils.Fatalf("Delegate transactions require a candidate address (--%s)", loadtestCandidateFlag.Name)
+		}
+		candidate = common.HexToAddress(ctx.String(loadtestCandidateFlag.Name))
+	}
+	var calldata []byte
+	if input := ctx.String(loadtestCallDataFlag.Name); input != "" {
+		if calldata, err = hexutil.Decode(input); err != nil {
+			utils.Fatalf("Invalid call data: %v", err)
+		}
+	}
+	// Attach to the node and retrieve the starting point of the sender
+	rpcClient, err := dialRPC(ctx.String(loadtestAttachFlag.Name))
+	if err != nil {
+		utils.Fatalf("Unable to attach to gkok node: %v", err)
+	}
+	defer rpcClient.Close()
+	client := kokclient.NewClient(rpcClient)
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	nonce, err := client.PendingNonceAt(context.Background(), from)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve sender nonce: %v", err)
+	}
+	gasPrice := new(big.Int).SetUint64(ctx.Uint64(loadtestGasPriceFlag.Name))
+	if gasPrice.Sign() == 0 {
+		if gasPrice, err = client.SuggestGasPrice(context.Background()); err != nil {
+			utils.Fatalf("Failed to retrieve suggested gas price: %v", err)
+		}
+	}
+	head, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		utils.Fatalf("Failed to retrieve chain head: %v", err)
+	}
+	signer := types.NewEIP155Signer(new(big.Int).SetUint64(ctx.Uint64(loadtestChainIdFlag.Name)))
+	callGas := new(big.Int).SetUint64(ctx.Uint64(loadtestCallGasFlag.Name))
+
+	// Track the inclusion of the sent transactions in the background
+	stats := &loadStats{submitted: make(map[common.Hash]time.Time)}
+	quit := make(chan struct{})
+	tracked := make(chan struct{})
+	go func() {
+		trackInclusions(client, head.Number.Uint64(), stats, quit)
+		close(tracked)
+	}()
+
+	// Generate the workload at the requested rate until done or interrupted
+	fmt.Printf("Sending %d tx/s from %s for %v (mix %s)\n", rate, from.Hex(), ctx.Duration(loadtestDurationFlag.Name), ctx.String(loadtestMixFlag.Name))
+
+	abort := make(chan os.Signal, 1)
+	signal.Notify(abort, os.Interrupt)
+	defer signal.Stop(abort)
+
+	var (
+		rnd      = rand.New(rand.NewSource(time.Now().UnixNano()))
+		inflight = make(chan struct{}, loadtestMaxInflight)
+		pending  sync.WaitGroup
+		start    = time.Now()
+		ticker   = time.NewTicker(time.Second / time.Duration(rate))
+		report   = time.NewTicker(loadtestReportCycle)
+		deadline = time.After(ctx.Duration(loadtestDurationFlag.Name))
+	)
+	defer ticker.Stop()
+	defer report.Stop()
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			// Skip the slot if too many submissions are still in flight
+			select {
+			case inflight <- struct{}{}:
+			default:
+				stats.lock.Lock()
+				stats.skipped++
+				stats.lock.Unlock()
+				continue
+			}
+			var tx *types.Transaction
+			switch mix.pick(rnd) {
+			case "transfer":
+				to := common.BigToAddress(new(big.Int).SetUint64(nonce + 1))
+				tx = types.NewTransaction(types.Binary, nonce, to, common.Big1, new(big.Int).SetUint64(params.TxGas), gasPrice, nil)
+			case "call":
+				tx = types.NewTransaction(types.Binary, nonce, contract, common.Big0, callGas, gasPrice, calldata)
+			case "delegate":
+				tx = types.NewTransaction(types.Delegate, nonce, candidate, common.Big0, new(big.Int).SetUint64(params.TxGas), gasPrice, nil)
+			}
+			signed, err := types.SignTx(tx, signer, key)
+			if err != nil {
+				utils.Fatalf("Failed to sign transaction: %v", err)
+			}
+			nonce++
+
+			pending.Add(1)
+			go func() {
+				defer func() { <-inflight; pending.Done() }()
+				sendLoadTx(client, signed, stats)
+			}()
+
+		case <-report.C:
+			stats.lock.Lock()
+			fmt.Printf("[%v] sent %d, failed %d, skipped %d, included %d\n", common.PrettyDuration(time.Since(start)), stats.sent, stats.failed, stats.skipped, len(stats.includes))
+			stats.lock.Unlock()
+
+		case <-deadline:
+			break loop
+
+		case <-abort:
+			fmt.Println("Interrupted, stopping load generation")
+			break loop
+		}
+	}
+	pending.Wait()
+	elapsed := time.Since(start)
+
+	// Give the outstanding transactions a chance to be included
+	drain := time.After(loadtestDrainTimeout)
+	for waiting := true; waiting; {
+		stats.lock.Lock()
+		outstanding := len(stats.submitted)
+		stats.lock.Unlock()
+
+		if outstanding == 0 {
+			break
+		}
+		select {
+		case <-time.After(loadtestInclusionPoll):
+		case <-drain:
+			waiting = false
+		case <-abort:
+			waiting = false
+		}
+	}
+	close(quit)
+	<-tracked
+
+	// Report the outcome of the load test
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	fmt.Printf("Sent:               %d transactions in %v (%.2f tx/s)\n", stats.sent, common.PrettyDuration(elapsed), float64(stats.sent)/elapsed.Seconds())
+	fmt.Printf("Failed:             %d\n", stats.failed)
+	fmt.Printf("Skipped:            %d (too many in-flight submissions)\n", stats.skipped)
+	fmt.Printf("Included:           %d (%d outstanding)\n", len(stats.includes), len(stats.submitted))
+	fmt.Printf("Submission latency: %s\n", percentiles(stats.submits))
+	fmt.Printf("Inclusion latency:  %s\n", percentiles(stats.includes))
+	return nil
+}
+
+// sendLoadTx submits a single transaction to the node, recording the outcome.
+func sendLoadTx(client *kokclient.Client, tx *types.Transaction, stats *loadStats) {
+	start := time.Now()
+	err := client.SendTransaction(context.Background(), tx)
+
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	if err != nil {
+		stats.failed++
+		return
+	}
+	stats.sent++
+	stats.submits = append(stats.submits, time.Since(start))
+	stats.submitted[tx.Hash()] = start
+}
+
+// trackInclusions polls the node for new blocks, recording the inclusion latency
+// of any submitted transactions found in them.
+func trackInclusions(client *kokclient.Client, number uint64, stats *loadStats, quit chan struct{}) {
+	ticker := time.NewTicker(loadtestInclusionPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			head, err := client.HeaderByNumber(context.Background(), nil)
+			if err != nil {
+				continue
+			}
+			for ; number < head.Number.Uint64(); number++ {
+				block, err := client.BlockByNumber(context.Background(), new(big.Int).SetUint64(number+1))
+				if err != nil {
+					break
+				}
+				stats.lock.Lock()
+				for _, tx := range block.Transactions() {
+					if sent, ok := stats.submitted[tx.Hash()]; ok {
+						stats.includes = append(stats.includes, time.Since(sent))
+						delete(stats.submitted, tx.Hash())
+					}
+				}
+				stats.lock.Unlock()
+			}
+		case <-quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of go-kokereum.
+//
+// go-kokereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-kokereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-kokereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Tests that transaction mixes are parsed and sampled according to their weights.
+func TestLoadMix(t *testing.T) {
+	mix, err := parseLoadMix("transfer=3, call=1,delegate=0")
+	if err != nil {
+		t.Fatalf("failed to parse mix: %v", err)
+	}
+	if !mix.has("transfer") || !mix.has("call") || mix.has("delegate") {
+		t.Errorf("kind mismatch: have %v with weights %v", mix.kinds, mix.weights)
+	}
+	counts := make(map[string]int)
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 4000; i++ {
+		counts[mix.pick(rnd)]++
+	}
+	if counts["delegate"] != 0 {
+		t.Errorf("zero weight kind picked %d times", counts["delegate"])
+	}
+	if counts["transfer"] < 2800 || counts["transfer"] > 3200 {
+		t.Errorf("weighted kind picked %d times, want about 3000", counts["transfer"])
+	}
+	for _, spec := range []string{"", "transfer", "transfer=x", "deploy=1", "transfer=0"} {
+		if _, err := parseLoadMix(spec); err == nil {
+			t.Errorf("invalid mix %q accepted", spec)
+		}
+	}
+}
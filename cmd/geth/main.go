@@ -33,9 +33,11 @@ import (
 	"github.com/kokprojects/go-kok/kok"
 	"github.com/kokprojects/go-kok/kokclient"
 	"github.com/kokprojects/go-kok/internal/debug"
+	"github.com/kokprojects/go-kok/internal/version"
 	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/metrics"
 	"github.com/kokprojects/go-kok/node"
+	"github.com/kokprojects/go-kok/tracing"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -44,12 +46,10 @@ const (
 )
 
 var (
-	// Git SHA1 commit hash of the release (set via linker flags)
-	gitCommit = ""
 	// kokereum address of the Gkok release oracle.
 	relOracle = common.HexToAddress("0xfa7b9770ca4cb04296cac84f37736d4041251cdf")
 	// The app that holds all commands and flags.
-	app = utils.NewApp(gitCommit, "the go-kokereum command line interface")
+	app = utils.NewApp(version.Commit(), "the go-kokereum command line interface")
 	// flags that configure the node
 	nodeFlags = []cli.Flag{
 		utils.IdentityFlag,
@@ -66,6 +66,7 @@ var (
 		utils.DashboardPortFlag,
 		utils.DashboardRefreshFlag,
 		utils.TxPoolNoLocalsFlag,
+		utils.TxPoolPrivateFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
 		utils.TxPoolPriceLimitFlag,
@@ -75,17 +76,26 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolMaxLifetimeFlag,
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
+		utils.LightSignFlag,
 		utils.LightKDFFlag,
 		utils.CacheFlag,
 		utils.TrieCacheGenFlag,
+		utils.CacheGCFlag,
+		utils.TrieCommitIntervalFlag,
+		utils.GCModeFlag,
+		utils.DBNoRecoverFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
+		utils.MaxPeersPerIPFlag,
+		utils.MaxPeersPerSubnetFlag,
+		utils.InboundThrottleFlag,
 		utils.ValidatorFlag,
 		utils.CoinbaseFlag,
 		utils.GasPriceFlag,
@@ -95,17 +105,45 @@ var (
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.NodeOperatorFlag,
+		utils.NodeContactFlag,
+		utils.NodeRegionFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.VMEnableDebugFlag,
+		utils.BalanceIndexFlag,
+		utils.InternalTxIndexFlag,
+		utils.TxAddressIndexFlag,
+		utils.RevertReasonIndexFlag,
+		utils.AuditFlag,
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.kokStatsURLFlag,
+		utils.kokStatsCAFlag,
 		utils.MetricsEnabledFlag,
+		utils.TracingEnabledFlag,
+		utils.TracingEndpointFlag,
+		utils.TracingSampleRatioFlag,
+		utils.TracingServiceFlag,
+		utils.ArchiveBucketFlag,
+		utils.ArchiveEndpointFlag,
+		utils.ArchiveRegionFlag,
+		utils.ArchivePrefixFlag,
+		utils.ArchiveRangeFlag,
+		utils.InitFromArchiveFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.LogsMaxRangeFlag,
+		utils.LogsMaxResultsFlag,
+		utils.FiltersMaxFlag,
+		utils.FiltersTimeoutFlag,
+		utils.FiltersPersistFlag,
+		utils.FiltersRetentionFlag,
 		utils.ExtraDataFlag,
+		utils.MinerPolicyFlag,
+		utils.MinerOrderingFlag,
+		utils.MinerSkipOfflineFlag,
 		configFileFlag,
 	}
 
@@ -119,8 +157,20 @@ var (
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCBudgetFlag,
+		utils.RPCTimeoutFlag,
+		utils.RPCCertFlag,
+		utils.RPCKeyFlag,
+		utils.RESTEnabledFlag,
+		utils.RESTListenAddrFlag,
+		utils.RESTPortFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
+		utils.ExecPluginFlag,
+		utils.GraphQLEnabledFlag,
+		utils.GraphQLListenAddrFlag,
+		utils.GraphQLPortFlag,
+		utils.GraphQLCORSDomainFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -142,9 +192,16 @@ func init() {
 		exportCommand,
 		copydbCommand,
 		removedbCommand,
+		reindexBloomsCommand,
 		dumpCommand,
+		// See analyticscmd.go:
+		analyticsCommand,
 		// See monitorcmd.go:
 		monitorCommand,
+		// See loadtestcmd.go:
+		loadtestCommand,
+		// See validatorcmd.go:
+		validatorCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -176,10 +233,11 @@ func init() {
 		go metrics.CollectProcessMetrics(3 * time.Second)
 
 		utils.SetupNetwork(ctx)
-		return nil
+		return utils.SetupTracing(ctx)
 	}
 
 	app.After = func(ctx *cli.Context) error {
+		tracing.Stop()
 		debug.Exit()
 		console.Stdin.Close() // Resets terminal mode.
 		return nil
@@ -198,6 +256,9 @@ func main() {
 // blocking mode, waiting for it to be shut down.
 func gkok(ctx *cli.Context) error {
 	node := makeFullNode(ctx)
+	if ctx.GlobalIsSet(utils.InitFromArchiveFlag.Name) {
+		initFromArchive(ctx, node)
+	}
 	startNode(ctx, node)
 	node.Wait()
 	return nil
@@ -17,23 +17,31 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"runtime"
 	"strings"
 
 	"github.com/kokprojects/go-kok/cmd/utils"
+	"github.com/kokprojects/go-kok/internal/version"
 	"github.com/kokprojects/go-kok/kok"
 	"github.com/kokprojects/go-kok/params"
 	"gopkg.in/urfave/cli.v1"
 )
 
 var (
+	versionJSONFlag = cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the build metadata as JSON",
+	}
+
 	versionCommand = cli.Command{
-		Action:    utils.MigrateFlags(version),
+		Action:    utils.MigrateFlags(printVersion),
 		Name:      "version",
 		Usage:     "Print version numbers",
 		ArgsUsage: " ",
+		Flags:     []cli.Flag{versionJSONFlag},
 		Category:  "MISCELLANEOUS COMMANDS",
 		Description: `
 The output of this command is supposed to be machine-readable.
@@ -48,11 +56,29 @@ The output of this command is supposed to be machine-readable.
 	}
 )
 
-func version(ctx *cli.Context) error {
+func printVersion(ctx *cli.Context) error {
+	info := version.Get()
+	if ctx.Bool(versionJSONFlag.Name) {
+		out, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
 	fmt.Println(strings.Title(clientIdentifier))
 	fmt.Println("Version:", params.Version)
-	if gitCommit != "" {
-		fmt.Println("Git Commit:", gitCommit)
+	if info.GitCommit != "" {
+		fmt.Println("Git Commit:", info.GitCommit)
+	}
+	if info.GitBranch != "" {
+		fmt.Println("Git Branch:", info.GitBranch)
+	}
+	if info.Dirty {
+		fmt.Println("Dirty: true")
+	}
+	if info.BuildDate != "" {
+		fmt.Println("Build Date:", info.BuildDate)
 	}
 	fmt.Println("Architecture:", runtime.GOARCH)
 	fmt.Println("Protocol Versions:", kok.ProtocolVersions)
@@ -73,9 +73,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NetworkIdFlag,
 			utils.SyncModeFlag,
 			utils.kokStatsURLFlag,
+			utils.kokStatsCAFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
+			utils.LightSignFlag,
 			utils.LightKDFFlag,
 		},
 	},
@@ -93,6 +95,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "TRANSACTION POOL",
 		Flags: []cli.Flag{
 			utils.TxPoolNoLocalsFlag,
+			utils.TxPoolPrivateFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
 			utils.TxPoolPriceLimitFlag,
@@ -102,6 +105,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolMaxLifetimeFlag,
 		},
 	},
 	{
@@ -109,6 +113,10 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.CacheFlag,
 			utils.TrieCacheGenFlag,
+			utils.CacheGCFlag,
+			utils.TrieCommitIntervalFlag,
+			utils.GCModeFlag,
+			utils.DBNoRecoverFlag,
 		},
 	},
 	{
@@ -130,12 +138,24 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.RPCBudgetFlag,
+			utils.RPCTimeoutFlag,
+			utils.RPCCertFlag,
+			utils.RPCKeyFlag,
+			utils.RESTEnabledFlag,
+			utils.RESTListenAddrFlag,
+			utils.RESTPortFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
+			utils.ExecPluginFlag,
+			utils.GraphQLEnabledFlag,
+			utils.GraphQLListenAddrFlag,
+			utils.GraphQLPortFlag,
+			utils.GraphQLCORSDomainFlag,
 		},
 	},
 	{
@@ -147,10 +167,16 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
+			utils.MaxPeersPerIPFlag,
+			utils.MaxPeersPerSubnetFlag,
+			utils.InboundThrottleFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.NodeOperatorFlag,
+			utils.NodeContactFlag,
+			utils.NodeRegionFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
@@ -164,6 +190,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
+			utils.MinerPolicyFlag,
+			utils.MinerOrderingFlag,
+			utils.MinerSkipOfflineFlag,
 		},
 	},
 	{
@@ -173,10 +202,46 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.GpoPercentileFlag,
 		},
 	},
+	{
+		Name: "LOG FILTERING",
+		Flags: []cli.Flag{
+			utils.LogsMaxRangeFlag,
+			utils.LogsMaxResultsFlag,
+			utils.FiltersMaxFlag,
+			utils.FiltersTimeoutFlag,
+			utils.FiltersPersistFlag,
+			utils.FiltersRetentionFlag,
+		},
+	},
+	{
+		Name: "TRACING",
+		Flags: []cli.Flag{
+			utils.TracingEnabledFlag,
+			utils.TracingEndpointFlag,
+			utils.TracingSampleRatioFlag,
+			utils.TracingServiceFlag,
+		},
+	},
+	{
+		Name: "ARCHIVE",
+		Flags: []cli.Flag{
+			utils.ArchiveBucketFlag,
+			utils.ArchiveEndpointFlag,
+			utils.ArchiveRegionFlag,
+			utils.ArchivePrefixFlag,
+			utils.ArchiveRangeFlag,
+			utils.InitFromArchiveFlag,
+		},
+	},
 	{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.BalanceIndexFlag,
+			utils.InternalTxIndexFlag,
+			utils.TxAddressIndexFlag,
+			utils.RevertReasonIndexFlag,
+			utils.AuditFlag,
 		},
 	},
 	{
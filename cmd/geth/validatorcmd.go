@@ -0,0 +1,228 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of go-kokereum.
+//
+// go-kokereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-kokereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-kokereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kokprojects/go-kok/cmd/utils"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/consensus/dpos"
+	"github.com/kokprojects/go-kok/node"
+	"github.com/kokprojects/go-kok/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	validatorAttachFlag = cli.StringFlag{
+		Name:  "attach",
+		Value: node.DefaultIPCEndpoint(clientIdentifier),
+		Usage: "API endpoint to attach to",
+	}
+	validatorCountFlag = cli.IntFlag{
+		Name:  "count",
+		Value: 10,
+		Usage: "Maximum number of upcoming slots to list",
+	}
+	validatorCommand = cli.Command{
+		Name:     "validator",
+		Usage:    "Operate the validator of a running node",
+		Category: "VALIDATOR COMMANDS",
+		Description: `
+Inspect and control the DPoS validator of a running node over IPC or RPC, as a
+focused alternative to raw console commands. Unless an address is given, the
+commands report on the validator configured on the attached node.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "status",
+				Usage:     "Show the status of the validator",
+				Action:    utils.MigrateFlags(validatorStatus),
+				ArgsUsage: "[<address>]",
+				Flags: []cli.Flag{
+					validatorAttachFlag,
+				},
+				Description: `
+Print whkoker the node is sealing, whkoker the validator is a candidate and a
+member of the current validator set, its liveness as seen from the heartbeats,
+the number of blocks it minted in the current epoch, the number of its slots
+missed over the last epoch and its next scheduled slot.`,
+			},
+			{
+				Name:      "start",
+				Usage:     "Start sealing blocks on the attached node",
+				Action:    utils.MigrateFlags(validatorStart),
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					validatorAttachFlag,
+				},
+				Description: `
+Start sealing blocks with the validator account of the attached node, which must
+be unlocked on the node.`,
+			},
+			{
+				Name:      "stop",
+				Usage:     "Stop sealing blocks on the attached node",
+				Action:    utils.MigrateFlags(validatorStop),
+				ArgsUsage: " ",
+				Flags: []cli.Flag{
+					validatorAttachFlag,
+				},
+			},
+			{
+				Name:      "slots",
+				Usage:     "List the upcoming and the recently missed slots of the validator",
+				Action:    utils.MigrateFlags(validatorSlots),
+				ArgsUsage: "[<address>]",
+				Flags: []cli.Flag{
+					validatorAttachFlag,
+					validatorCountFlag,
+				},
+				Description: `
+List the next scheduled slots of the validator within the current epoch, the
+validators of the following epochs being unknown until elected, followed by the
+slots it missed over the last epoch.`,
+			},
+		},
+	}
+)
+
+// validatorClient attaches to the node given on the command line.
+func validatorClient(ctx *cli.Context) *rpc.Client {
+	client, err := dialRPC(ctx.String(validatorAttachFlag.Name))
+	if err != nil {
+		utils.Fatalf("Unable to attach to gkok node: %v", err)
+	}
+	return client
+}
+
+// validatorAddress resolves the validator to report on, either given as the
+// first argument or configured on the attached node.
+func validatorAddress(ctx *cli.Context, client *rpc.Client) common.Address {
+	if arg := ctx.Args().First(); arg != "" {
+		if !common.IsHexAddress(arg) {
+			utils.Fatalf("Invalid validator address %q", arg)
+		}
+		return common.HexToAddress(arg)
+	}
+	var address common.Address
+	if err := client.Call(&address, "kok_validator"); err != nil {
+		utils.Fatalf("Failed to retrieve the validator of the node: %v", err)
+	}
+	return address
+}
+
+// formatSlot renders the time of a slot along with its distance from now.
+func formatSlot(slot hexutil.Uint64, now time.Time) string {
+	at := time.Unix(int64(slot), 0)
+	if at.After(now) {
+		return fmt.Sprintf("%s (in %v)", at.Format(time.RFC3339), at.Sub(now).Truncate(time.Second))
+	}
+	return fmt.Sprintf("%s (%v ago)", at.Format(time.RFC3339), now.Sub(at).Truncate(time.Second))
+}
+
+func validatorStatus(ctx *cli.Context) error {
+	client := validatorClient(ctx)
+	defer client.Close()
+
+	address := validatorAddress(ctx, client)
+
+	var (
+		mining bool
+		head   hexutil.Uint64
+		status dpos.ValidatorStatus
+	)
+	if err := client.Call(&mining, "kok_mining"); err != nil {
+		utils.Fatalf("Failed to retrieve the sealing status: %v", err)
+	}
+	if err := client.Call(&head, "kok_blockNumber"); err != nil {
+		utils.Fatalf("Failed to retrieve the head block: %v", err)
+	}
+	if err := client.Call(&status, "dpos_getValidatorStatus", address); err != nil {
+		utils.Fatalf("Failed to retrieve the validator status: %v", err)
+	}
+	next := "none in the current epoch"
+	if status.NextSlot != nil {
+		next = formatSlot(*status.NextSlot, time.Now())
+	}
+	fmt.Printf("Validator:  %s\n", status.Address.Hex())
+	fmt.Printf("Sealing:    %v\n", mining)
+	fmt.Printf("Head:       #%d\n", head)
+	fmt.Printf("Candidate:  %v\n", status.Candidate)
+	fmt.Printf("Active:     %v\n", status.Active)
+	fmt.Printf("Online:     %v\n", status.Online)
+	fmt.Printf("Epoch:      %d\n", status.Epoch)
+	fmt.Printf("Minted:     %d\n", status.Minted)
+	fmt.Printf("Missed:     %d\n", status.Missed)
+	fmt.Printf("Next slot:  %s\n", next)
+	return nil
+}
+
+func validatorStart(ctx *cli.Context) error {
+	client := validatorClient(ctx)
+	defer client.Close()
+
+	if err := client.Call(nil, "miner_start"); err != nil {
+		utils.Fatalf("Failed to start sealing: %v", err)
+	}
+	fmt.Println("Sealing started")
+	return nil
+}
+
+func validatorStop(ctx *cli.Context) error {
+	client := validatorClient(ctx)
+	defer client.Close()
+
+	var stopped bool
+	if err := client.Call(&stopped, "miner_stop"); err != nil {
+		utils.Fatalf("Failed to stop sealing: %v", err)
+	}
+	fmt.Println("Sealing stopped")
+	return nil
+}
+
+func validatorSlots(ctx *cli.Context) error {
+	client := validatorClient(ctx)
+	defer client.Close()
+
+	var (
+		address  = validatorAddress(ctx, client)
+		upcoming []dpos.Slot
+		missed   []dpos.Slot
+	)
+	if err := client.Call(&upcoming, "dpos_getSlots", address, ctx.Int(validatorCountFlag.Name)); err != nil {
+		utils.Fatalf("Failed to retrieve the upcoming slots: %v", err)
+	}
+	if err := client.Call(&missed, "dpos_getMissedSlots", address); err != nil {
+		utils.Fatalf("Failed to retrieve the missed slots: %v", err)
+	}
+	now := time.Now()
+
+	fmt.Printf("Upcoming slots of %s:\n", address.Hex())
+	if len(upcoming) == 0 {
+		fmt.Println("  none in the current epoch")
+	}
+	for _, slot := range upcoming {
+		fmt.Printf("  %s\n", formatSlot(slot.Time, now))
+	}
+	fmt.Printf("Missed slots over the last epoch: %d\n", len(missed))
+	for _, slot := range missed {
+		fmt.Printf("  %s\n", formatSlot(slot.Time, now))
+	}
+	return nil
+}
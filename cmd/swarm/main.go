@@ -40,6 +40,7 @@ import (
 	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/kokclient"
 	"github.com/kokprojects/go-kok/internal/debug"
+	"github.com/kokprojects/go-kok/internal/version"
 	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/node"
 	"github.com/kokprojects/go-kok/p2p"
@@ -54,7 +55,6 @@ import (
 const clientIdentifier = "swarm"
 
 var (
-	gitCommit        string // Git SHA1 commit hash of the release (set via linker flags)
 	testbetBootNodes = []string{
 		"enode://ec8ae764f7cb0417bdfb009b9d0f18ab3818a3a4e8e7c67dd5f18971a93510a2e6f43cd0b69a27e439a9629457ea804104f37c85e41eed057d3faabbf7744cdf@13.74.157.139:30429",
 		"enode://c2e1fceb3bf3be19dff71eec6cccf19f2dbf7567ee017d130240c670be8594bc9163353ca55dd8df7a4f161dd94b36d0615c17418b5a3cdcbb4e9d99dfa4de37@13.74.157.139:30430",
@@ -152,14 +152,14 @@ var defaultNodeConfig = node.DefaultConfig
 // This init function sets defaults so cmd/swarm can run alongside gkok.
 func init() {
 	defaultNodeConfig.Name = clientIdentifier
-	defaultNodeConfig.Version = params.VersionWithCommit(gitCommit)
+	defaultNodeConfig.Version = version.WithCommit()
 	defaultNodeConfig.P2P.ListenAddr = ":30399"
 	defaultNodeConfig.IPCPath = "bzzd.ipc"
 	// Set flag defaults for --help display.
 	utils.ListenPortFlag.Value = 30399
 }
 
-var app = utils.NewApp(gitCommit, "kokereum Swarm")
+var app = utils.NewApp(version.Commit(), "kokereum Swarm")
 
 // This init function creates the cli.App.
 func init() {
@@ -168,7 +168,7 @@ func init() {
 	app.Copyright = "Copyright 2013-2016 The go-kokereum Authors"
 	app.Commands = []cli.Command{
 		{
-			Action:    version,
+			Action:    printVersion,
 			Name:      "version",
 			Usage:     "Print version numbers",
 			ArgsUsage: " ",
@@ -362,11 +362,11 @@ func main() {
 	}
 }
 
-func version(ctx *cli.Context) error {
+func printVersion(ctx *cli.Context) error {
 	fmt.Println(strings.Title(clientIdentifier))
 	fmt.Println("Version:", params.Version)
-	if gitCommit != "" {
-		fmt.Println("Git Commit:", gitCommit)
+	if commit := version.Commit(); commit != "" {
+		fmt.Println("Git Commit:", commit)
 	}
 	fmt.Println("Network Id:", ctx.GlobalInt(utils.NetworkIdFlag.Name))
 	fmt.Println("Go Version:", runtime.Version())
@@ -19,6 +19,7 @@ package utils
 
 import (
 	"compress/gzip"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -26,6 +27,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/core"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/internal/debug"
@@ -38,6 +40,41 @@ const (
 	importBatchSize = 2500
 )
 
+// Formats of exported blockchain files.
+const (
+	ChainFormatRLP  = "rlp"  // Concatenated RLP encoded blocks
+	ChainFormatJSON = "json" // One JSON encoded block per line
+)
+
+// jsonBlock is a block in a JSON blockchain file.
+type jsonBlock struct {
+	Hash         common.Hash          `json:"hash"`
+	Header       *types.Header        `json:"header"`
+	Transactions []*types.Transaction `json:"transactions"`
+	Uncles       []*types.Header      `json:"uncles"`
+}
+
+func newJSONBlock(block *types.Block) *jsonBlock {
+	return &jsonBlock{
+		Hash:         block.Hash(),
+		Header:       block.Header(),
+		Transactions: block.Transactions(),
+		Uncles:       block.Uncles(),
+	}
+}
+
+// block assembles the block, checking that it hashes to the exported hash.
+func (jb *jsonBlock) block() (*types.Block, error) {
+	if jb.Header == nil {
+		return nil, fmt.Errorf("missing block header")
+	}
+	b := types.NewBlockWithHeader(jb.Header).WithBody(jb.Transactions, jb.Uncles)
+	if b.Hash() != jb.Hash {
+		return nil, fmt.Errorf("block hash mismatch: have %x, want %x", b.Hash(), jb.Hash)
+	}
+	return b, nil
+}
+
 // Fatalf formats a message to standard error and exits the program.
 // The message is also printed to standard output if standard error
 // is redirected to a different file.
@@ -80,7 +117,7 @@ func StartNode(stack *node.Node) {
 	}()
 }
 
-func ImportChain(chain *core.BlockChain, fn string) error {
+func ImportChain(chain *core.BlockChain, fn string, format string) error {
 	// Watch for Ctrl-C while the import is running.
 	// If a signal is received, the import will stop at the next batch.
 	interrupt := make(chan os.Signal, 1)
@@ -117,7 +154,26 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 		}
 	}
 
-	stream := rlp.NewStream(reader, 0)
+	var decode func() (*types.Block, error)
+	switch format {
+	case ChainFormatRLP:
+		stream := rlp.NewStream(reader, 0)
+		decode = func() (*types.Block, error) {
+			b := new(types.Block)
+			return b, stream.Decode(b)
+		}
+	case ChainFormatJSON:
+		dec := json.NewDecoder(reader)
+		decode = func() (*types.Block, error) {
+			jb := new(jsonBlock)
+			if err := dec.Decode(jb); err != nil {
+				return nil, err
+			}
+			return jb.block()
+		}
+	default:
+		return fmt.Errorf("unknown blockchain file format %q", format)
+	}
 
 	// Run actual the import.
 	blocks := make(types.Blocks, importBatchSize)
@@ -129,8 +185,8 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 		}
 		i := 0
 		for ; i < importBatchSize; i++ {
-			var b types.Block
-			if err := stream.Decode(&b); err == io.EOF {
+			b, err := decode()
+			if err == io.EOF {
 				break
 			} else if err != nil {
 				return fmt.Errorf("at block %d: %v", n, err)
@@ -140,7 +196,7 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 				i--
 				continue
 			}
-			blocks[i] = &b
+			blocks[i] = b
 			n++
 		}
 		if i == 0 {
@@ -171,7 +227,7 @@ func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	return true
 }
 
-func ExportChain(blockchain *core.BlockChain, fn string) error {
+func ExportChain(blockchain *core.BlockChain, fn string, format string) error {
 	log.Info("Exporting blockchain", "file", fn)
 	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
 	if err != nil {
@@ -185,7 +241,7 @@ func ExportChain(blockchain *core.BlockChain, fn string) error {
 		defer writer.(*gzip.Writer).Close()
 	}
 
-	if err := blockchain.Export(writer); err != nil {
+	if err := exportBlocks(blockchain, writer, format, 0, blockchain.CurrentBlock().NumberU64()); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain", "file", fn)
@@ -193,7 +249,7 @@ func ExportChain(blockchain *core.BlockChain, fn string) error {
 	return nil
 }
 
-func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, last uint64) error {
+func ExportAppendChain(blockchain *core.BlockChain, fn string, format string, first uint64, last uint64) error {
 	log.Info("Exporting blockchain", "file", fn)
 	// TODO verify mode perms
 	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.ModePerm)
@@ -208,9 +264,34 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 		defer writer.(*gzip.Writer).Close()
 	}
 
-	if err := blockchain.ExportN(writer, first, last); err != nil {
+	if err := exportBlocks(blockchain, writer, format, first, last); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain to", "file", fn)
 	return nil
 }
+
+// exportBlocks writes the canonical blocks from first to last to the writer in
+// the given blockchain file format.
+func exportBlocks(blockchain *core.BlockChain, w io.Writer, format string, first uint64, last uint64) error {
+	switch format {
+	case ChainFormatRLP:
+		return blockchain.ExportN(w, first, last)
+	case ChainFormatJSON:
+		if first > last {
+			return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+		}
+		enc := json.NewEncoder(w)
+		for nr := first; nr <= last; nr++ {
+			block := blockchain.GetBlockByNumber(nr)
+			if block == nil {
+				return fmt.Errorf("export failed on #%d: not found", nr)
+			}
+			if err := enc.Encode(newJSONBlock(block)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown blockchain file format %q", format)
+}
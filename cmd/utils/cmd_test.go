@@ -0,0 +1,72 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+)
+
+// Tests that blocks survive a round trip through the JSON blockchain file format
+// and that tampered blocks are rejected on import.
+func TestJSONBlockRoundTrip(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	tx, err := types.SignTx(types.NewTransaction(types.Binary, 3, common.HexToAddress("0x01"), big.NewInt(7), big.NewInt(21000), big.NewInt(1), []byte{0xca, 0xfe}), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &types.Header{
+		Number:      big.NewInt(5),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    big.NewInt(4700000),
+		GasUsed:     big.NewInt(21000),
+		Time:        big.NewInt(1500000000),
+		Extra:       []byte("kok"),
+		DposContext: &types.DposContextProto{EpochHash: common.HexToHash("0x02")},
+	}
+	block := types.NewBlock(header, []*types.Transaction{tx}, nil, nil)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(newJSONBlock(block)); err != nil {
+		t.Fatalf("failed to encode block: %v", err)
+	}
+	jb := new(jsonBlock)
+	if err := json.Unmarshal(buf.Bytes(), jb); err != nil {
+		t.Fatalf("failed to decode block: %v", err)
+	}
+	decoded, err := jb.block()
+	if err != nil {
+		t.Fatalf("failed to assemble block: %v", err)
+	}
+	if decoded.Hash() != block.Hash() || decoded.TxHash() != block.TxHash() {
+		t.Errorf("block mismatch: have %x, want %x", decoded.Hash(), block.Hash())
+	}
+	if len(decoded.Transactions()) != 1 || decoded.Transactions()[0].Hash() != tx.Hash() {
+		t.Errorf("transaction mismatch: have %v, want %x", decoded.Transactions(), tx.Hash())
+	}
+	jb.Header.GasUsed = big.NewInt(42000)
+	if _, err := jb.block(); err == nil {
+		t.Errorf("tampered block accepted")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kokprojects/go-kok/accounts"
 	"github.com/kokprojects/go-kok/accounts/keystore"
@@ -37,7 +38,9 @@ import (
 	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/dashboard"
 	"github.com/kokprojects/go-kok/kok"
+	"github.com/kokprojects/go-kok/kok/archive"
 	"github.com/kokprojects/go-kok/kok/downloader"
+	"github.com/kokprojects/go-kok/kok/filters"
 	"github.com/kokprojects/go-kok/kok/gasprice"
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/kokstats"
@@ -51,6 +54,9 @@ import (
 	"github.com/kokprojects/go-kok/p2p/nat"
 	"github.com/kokprojects/go-kok/p2p/netutil"
 	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/plugins"
+	"github.com/kokprojects/go-kok/rpc"
+	"github.com/kokprojects/go-kok/tracing"
 	whisper "github.com/kokprojects/go-kok/whisper/whisperv5"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -161,6 +167,10 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: 20,
 	}
+	LightSignFlag = cli.BoolFlag{
+		Name:  "lightsign",
+		Usage: "Sign served LES headers and proofs with the node key, for clients to prove misbehavior",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -190,6 +200,10 @@ var (
 		Name:  "txpool.nolocals",
 		Usage: "Disables price exemptions for locally submitted transactions",
 	}
+	TxPoolPrivateFlag = cli.BoolFlag{
+		Name:  "txpool.private",
+		Usage: "Keeps locally submitted transactions from being propagated until mined locally",
+	}
 	TxPoolJournalFlag = cli.StringFlag{
 		Name:  "txpool.journal",
 		Usage: "Disk journal for local transaction to survive node restarts",
@@ -235,6 +249,11 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: kok.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolMaxLifetimeFlag = cli.DurationFlag{
+		Name:  "txpool.maxlifetime",
+		Usage: "Maximum amount of time any transaction is pooled without an explicit expiry (0 = unlimited)",
+		Value: kok.DefaultConfig.TxPool.MaxLifetime,
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -246,6 +265,25 @@ var (
 		Usage: "Number of trie node generations to keep in memory",
 		Value: int(state.MaxTrieCacheGen),
 	}
+	CacheGCFlag = cli.IntFlag{
+		Name:  "cache.gc",
+		Usage: "Number of recent state tries to keep in memory before dereferencing them",
+		Value: state.MaxPastTries,
+	}
+	TrieCommitIntervalFlag = cli.Uint64Flag{
+		Name:  "trie.commitinterval",
+		Usage: "Number of blocks whose state is kept in memory before being flushed to disk",
+		Value: 1,
+	}
+	GCModeFlag = cli.StringFlag{
+		Name:  "gcmode",
+		Usage: `Garbage collection mode of the state tries ("full" keeps recent states only, "archive" keeps every state)`,
+		Value: "archive",
+	}
+	DBNoRecoverFlag = cli.BoolFlag{
+		Name:  "db.no-recover",
+		Usage: "Disable the automatic recovery of corrupted databases on startup",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -275,6 +313,18 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	MinerPolicyFlag = cli.StringFlag{
+		Name:  "minerpolicy",
+		Usage: "JSON file listing accounts whose transactions are denied or exclusively allowed in mined blocks",
+	}
+	MinerOrderingFlag = cli.StringFlag{
+		Name:  "minerordering",
+		Usage: `Transaction ordering of mined blocks ("price", "fair" or "fifo", default = price)`,
+	}
+	MinerSkipOfflineFlag = cli.BoolFlag{
+		Name:  "minerskipoffline",
+		Usage: "Stop waiting for the blocks of validators without recent heartbeats before minting",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -291,10 +341,34 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	BalanceIndexFlag = cli.BoolFlag{
+		Name:  "balanceindex",
+		Usage: "Index the balance changes made by each transaction (kok_getBalanceChanges)",
+	}
+	InternalTxIndexFlag = cli.BoolFlag{
+		Name:  "internaltxindex",
+		Usage: "Index the value transfers made by contracts (kok_getInternalTransactions)",
+	}
+	TxAddressIndexFlag = cli.BoolFlag{
+		Name:  "txaddrindex",
+		Usage: "Index the transactions sent and received by each account (kok_getTransactionsByAddress)",
+	}
+	RevertReasonIndexFlag = cli.BoolFlag{
+		Name:  "revertreasonindex",
+		Usage: "Store the return data of reverted transactions (revertReason of kok_getTransactionReceipt)",
+	}
+	AuditFlag = cli.BoolFlag{
+		Name:  "audit",
+		Usage: "Audit the balance and gas refund accounting of each transaction, rejecting blocks that violate it",
+	}
 	// Logging and debug settings
 	kokStatsURLFlag = cli.StringFlag{
 		Name:  "kokstats",
-		Usage: "Reporting URL of a kokstats service (nodename:secret@host:port)",
+		Usage: "Comma separated reporting URLs of kokstats services (nodename:secret@host:port)",
+	}
+	kokStatsCAFlag = cli.StringFlag{
+		Name:  "kokstats.ca",
+		Usage: "PEM encoded CA bundle to verify the kokstats servers with instead of the system roots",
 	}
 	MetricsEnabledFlag = cli.BoolFlag{
 		Name:  metrics.MetricsEnabledFlag,
@@ -304,6 +378,55 @@ var (
 		Name:  "nocompaction",
 		Usage: "Disables db compaction after import",
 	}
+	// Tracing settings
+	TracingEnabledFlag = cli.BoolFlag{
+		Name:  "tracing",
+		Usage: "Enable tracing of RPC requests to an OpenTelemetry collector",
+	}
+	TracingEndpointFlag = cli.StringFlag{
+		Name:  "tracing.endpoint",
+		Usage: "OTLP/HTTP traces endpoint of the collector (e.g. Jaeger or Tempo)",
+		Value: tracing.DefaultConfig.Endpoint,
+	}
+	TracingSampleRatioFlag = cli.Float64Flag{
+		Name:  "tracing.sampleratio",
+		Usage: "Fraction of the requests to trace, unless sampled by the caller",
+		Value: tracing.DefaultConfig.SampleRatio,
+	}
+	TracingServiceFlag = cli.StringFlag{
+		Name:  "tracing.service",
+		Usage: "Service name to report the spans under",
+		Value: tracing.DefaultConfig.ServiceName,
+	}
+	// Archive settings
+	ArchiveBucketFlag = cli.StringFlag{
+		Name:  "archive.bucket",
+		Usage: "Bucket to continuously archive the finalized chain into (credentials from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)",
+	}
+	ArchiveEndpointFlag = cli.StringFlag{
+		Name:  "archive.endpoint",
+		Usage: "URL of the S3 compatible storage service to archive into",
+		Value: kok.DefaultConfig.Archive.Endpoint,
+	}
+	ArchiveRegionFlag = cli.StringFlag{
+		Name:  "archive.region",
+		Usage: "Region of the archive bucket",
+		Value: kok.DefaultConfig.Archive.Region,
+	}
+	ArchivePrefixFlag = cli.StringFlag{
+		Name:  "archive.prefix",
+		Usage: "Key prefix of the archived objects",
+		Value: kok.DefaultConfig.Archive.Prefix,
+	}
+	ArchiveRangeFlag = cli.Uint64Flag{
+		Name:  "archive.range",
+		Usage: "Number of blocks archived per object",
+		Value: kok.DefaultConfig.Archive.RangeSize,
+	}
+	InitFromArchiveFlag = cli.StringFlag{
+		Name:  "init-from-archive",
+		Usage: "URL of a chain archive to import blocks from on startup, before syncing with the network",
+	}
 	// RPC settings
 	RPCEnabledFlag = cli.BoolFlag{
 		Name:  "rpc",
@@ -329,6 +452,26 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCBudgetFlag = cli.StringFlag{
+		Name:  "rpcbudget",
+		Usage: "Comma separated request cost budgets of HTTP and WS clients per API (<api>=<capacity>/<refill per second>)",
+		Value: "",
+	}
+	RPCTimeoutFlag = cli.StringFlag{
+		Name:  "rpctimeout",
+		Usage: "Comma separated execution deadlines of IPC, HTTP and WS requests per API (<api>=<duration>)",
+		Value: "",
+	}
+	RPCCertFlag = cli.StringFlag{
+		Name:  "rpccert",
+		Usage: "TLS certificate file (PEM) to serve the HTTP-RPC and WS-RPC endpoints with",
+		Value: "",
+	}
+	RPCKeyFlag = cli.StringFlag{
+		Name:  "rpckey",
+		Usage: "TLS private key file (PEM) of the HTTP-RPC and WS-RPC certificate",
+		Value: "",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -361,13 +504,50 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	RESTEnabledFlag = cli.BoolFlag{
+		Name:  "rest",
+		Usage: "Enable the read-only REST gateway (blocks, transactions and balances)",
+	}
+	RESTListenAddrFlag = cli.StringFlag{
+		Name:  "restaddr",
+		Usage: "REST gateway listening interface",
+		Value: node.DefaultHTTPHost,
+	}
+	RESTPortFlag = cli.IntFlag{
+		Name:  "restport",
+		Usage: "REST gateway listening port",
+		Value: 8547,
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
 	}
 	PreloadJSFlag = cli.StringFlag{
 		Name:  "preload",
-		Usage: "Comma separated list of JavaScript files to preload into the console",
+		Usage: "Comma separated list of JavaScript files (or directories of them) to preload into the console",
+	}
+	ExecPluginFlag = cli.StringFlag{
+		Name:  "exec-plugin",
+		Usage: "Comma separated list of Go plugins (.so files) to load as node services",
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL query service (served over HTTP at /graphql)",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: node.DefaultHTTPHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: 8548,
+	}
+	GraphQLCORSDomainFlag = cli.StringFlag{
+		Name:  "graphql.corsdomain",
+		Usage: "Comma separated list of domains from which to accept cross origin GraphQL queries (browser enforced)",
+		Value: "",
 	}
 
 	// Network Settings
@@ -381,6 +561,21 @@ var (
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
 		Value: 0,
 	}
+	MaxPeersPerIPFlag = cli.IntFlag{
+		Name:  "maxpeersperip",
+		Usage: "Maximum number of inbound peers from a single IP address (0 = unlimited)",
+		Value: node.DefaultConfig.P2P.MaxPeersPerIP,
+	}
+	MaxPeersPerSubnetFlag = cli.IntFlag{
+		Name:  "maxpeerspersubnet",
+		Usage: "Maximum number of inbound peers from a single /24 (IPv4) or /64 (IPv6) subnet (0 = unlimited)",
+		Value: node.DefaultConfig.P2P.MaxPeersPerSubnet,
+	}
+	InboundThrottleFlag = cli.DurationFlag{
+		Name:  "inboundthrottle",
+		Usage: "Minimum time between inbound connection attempts from the same IP address (0 = disabled)",
+		Value: node.DefaultConfig.P2P.InboundThrottle,
+	}
 	ListenPortFlag = cli.IntFlag{
 		Name:  "port",
 		Usage: "Network listening port",
@@ -426,11 +621,23 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	NodeOperatorFlag = cli.StringFlag{
+		Name:  "nodeoperator",
+		Usage: "Operator name announced to peers in the signed node metadata",
+	}
+	NodeContactFlag = cli.StringFlag{
+		Name:  "nodecontact",
+		Usage: "Operator contact announced to peers in the signed node metadata",
+	}
+	NodeRegionFlag = cli.StringFlag{
+		Name:  "noderegion",
+		Usage: "Node region announced to peers in the signed node metadata",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
 		Name:  "jspath",
-		Usage: "JavaScript root path for `loadScript`",
+		Usage: "JavaScript root path for `loadScript` and `require`",
 		Value: ".",
 	}
 
@@ -445,6 +652,36 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: kok.DefaultConfig.GPO.Percentile,
 	}
+	// Log filtering settings
+	LogsMaxRangeFlag = cli.Uint64Flag{
+		Name:  "logs.maxrange",
+		Usage: "Maximum number of blocks a single log query may span (0 = unlimited)",
+		Value: kok.DefaultConfig.Filters.MaxBlockRange,
+	}
+	LogsMaxResultsFlag = cli.IntFlag{
+		Name:  "logs.maxresults",
+		Usage: "Maximum number of logs a single log query may return (0 = unlimited)",
+		Value: kok.DefaultConfig.Filters.MaxResults,
+	}
+	FiltersMaxFlag = cli.IntFlag{
+		Name:  "filters.max",
+		Usage: "Maximum number of filters and subscriptions of a single RPC connection or HTTP host (0 = unlimited)",
+		Value: kok.DefaultConfig.Filters.MaxFilters,
+	}
+	FiltersTimeoutFlag = cli.DurationFlag{
+		Name:  "filters.timeout",
+		Usage: "Time after which a filter not polled for is uninstalled",
+		Value: kok.DefaultConfig.Filters.FilterTimeout,
+	}
+	FiltersPersistFlag = cli.BoolFlag{
+		Name:  "filters.persist",
+		Usage: "Keep the log filters installed over HTTP across restarts of the node",
+	}
+	FiltersRetentionFlag = cli.DurationFlag{
+		Name:  "filters.retention",
+		Usage: "Time after their last poll persisted filters are dropped by a restarted node (0 = unlimited)",
+		Value: kok.DefaultConfig.Filters.PersistRetention,
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -633,6 +870,65 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCTLS sets the certificate and key the HTTP and WS RPC endpoints serve TLS
+// with from the set command line flags.
+func setRPCTLS(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCCertFlag.Name) {
+		cfg.RPCCertFile = ctx.GlobalString(RPCCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCKeyFlag.Name) {
+		cfg.RPCKeyFile = ctx.GlobalString(RPCKeyFlag.Name)
+	}
+}
+
+// setRPCBudgets creates the per-namespace request cost budgets of the HTTP and
+// WS RPC clients from the set command line flags.
+func setRPCBudgets(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalIsSet(RPCBudgetFlag.Name) {
+		return
+	}
+	cfg.RPCBudgets = make(map[string]rpc.CostBudget)
+	for _, entry := range splitAndTrim(ctx.GlobalString(RPCBudgetFlag.Name)) {
+		parts := strings.Split(entry, "=")
+		if len(parts) != 2 {
+			Fatalf("Invalid RPC budget %q, want <api>=<capacity>/<refill>", entry)
+		}
+		limits := strings.Split(parts[1], "/")
+		if len(limits) != 2 {
+			Fatalf("Invalid RPC budget %q, want <api>=<capacity>/<refill>", entry)
+		}
+		capacity, err := strconv.ParseUint(limits[0], 10, 64)
+		if err != nil {
+			Fatalf("Invalid RPC budget capacity %q: %v", limits[0], err)
+		}
+		refill, err := strconv.ParseUint(limits[1], 10, 64)
+		if err != nil {
+			Fatalf("Invalid RPC budget refill rate %q: %v", limits[1], err)
+		}
+		cfg.RPCBudgets[parts[0]] = rpc.CostBudget{Capacity: capacity, Refill: refill}
+	}
+}
+
+// setRPCTimeouts creates the per-namespace request execution deadlines of the
+// RPC endpoints from the set command line flags.
+func setRPCTimeouts(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalIsSet(RPCTimeoutFlag.Name) {
+		return
+	}
+	cfg.RPCTimeouts = make(map[string]time.Duration)
+	for _, entry := range splitAndTrim(ctx.GlobalString(RPCTimeoutFlag.Name)) {
+		parts := strings.Split(entry, "=")
+		if len(parts) != 2 {
+			Fatalf("Invalid RPC timeout %q, want <api>=<duration>", entry)
+		}
+		timeout, err := time.ParseDuration(parts[1])
+		if err != nil {
+			Fatalf("Invalid RPC timeout duration %q: %v", parts[1], err)
+		}
+		cfg.RPCTimeouts[parts[0]] = timeout
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -754,14 +1050,24 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
-	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || ctx.GlobalBool(LightModeFlag.Name) {
+	if ctx.GlobalIsSet(MaxPeersPerIPFlag.Name) {
+		cfg.MaxPeersPerIP = ctx.GlobalInt(MaxPeersPerIPFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxPeersPerSubnetFlag.Name) {
+		cfg.MaxPeersPerSubnet = ctx.GlobalInt(MaxPeersPerSubnetFlag.Name)
+	}
+	if ctx.GlobalIsSet(InboundThrottleFlag.Name) {
+		cfg.InboundThrottle = ctx.GlobalDuration(InboundThrottleFlag.Name)
+	}
+	lightClient := lightClientMode(ctx)
+	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
 
 	// if we're running a light client or server, force enable the v5 peer discovery
 	// unless it is explicitly disabled with --nodiscover note that explicitly specifying
 	// --v5disc overrides --nodiscover, in which case the later only disables v4 discovery
-	forceV5Discovery := (ctx.GlobalBool(LightModeFlag.Name) || ctx.GlobalInt(LightServFlag.Name) > 0) && !ctx.GlobalBool(NoDiscoverFlag.Name)
+	forceV5Discovery := (lightClient || ctx.GlobalInt(LightServFlag.Name) > 0) && !ctx.GlobalBool(NoDiscoverFlag.Name)
 	if ctx.GlobalIsSet(DiscoveryV5Flag.Name) {
 		cfg.DiscoveryV5 = ctx.GlobalBool(DiscoveryV5Flag.Name)
 	} else if forceV5Discovery {
@@ -775,6 +1081,26 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	setNodeMetadata(ctx, cfg)
+}
+
+// setNodeMetadata applies the operator metadata flags to the p2p config.
+func setNodeMetadata(ctx *cli.Context, cfg *p2p.Config) {
+	if !ctx.GlobalIsSet(NodeOperatorFlag.Name) && !ctx.GlobalIsSet(NodeContactFlag.Name) && !ctx.GlobalIsSet(NodeRegionFlag.Name) {
+		return
+	}
+	if cfg.Metadata == nil {
+		cfg.Metadata = new(p2p.NodeMetadata)
+	}
+	if ctx.GlobalIsSet(NodeOperatorFlag.Name) {
+		cfg.Metadata.Operator = ctx.GlobalString(NodeOperatorFlag.Name)
+	}
+	if ctx.GlobalIsSet(NodeContactFlag.Name) {
+		cfg.Metadata.Contact = ctx.GlobalString(NodeContactFlag.Name)
+	}
+	if ctx.GlobalIsSet(NodeRegionFlag.Name) {
+		cfg.Metadata.Region = ctx.GlobalString(NodeRegionFlag.Name)
+	}
 }
 
 // SetNodeConfig applies node-related command line flags to the config.
@@ -783,6 +1109,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	skokTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCTLS(ctx, cfg)
+	setRPCBudgets(ctx, cfg)
+	setRPCTimeouts(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -799,6 +1128,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalIsSet(DBNoRecoverFlag.Name) {
+		kokdb.NoRecover = ctx.GlobalBool(DBNoRecoverFlag.Name)
+	}
 }
 
 func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
@@ -810,10 +1142,54 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	}
 }
 
+func setArchive(ctx *cli.Context, cfg *archive.Config) {
+	if ctx.GlobalIsSet(ArchiveBucketFlag.Name) {
+		cfg.Bucket = ctx.GlobalString(ArchiveBucketFlag.Name)
+	}
+	if ctx.GlobalIsSet(ArchiveEndpointFlag.Name) {
+		cfg.Endpoint = ctx.GlobalString(ArchiveEndpointFlag.Name)
+	}
+	if ctx.GlobalIsSet(ArchiveRegionFlag.Name) {
+		cfg.Region = ctx.GlobalString(ArchiveRegionFlag.Name)
+	}
+	if ctx.GlobalIsSet(ArchivePrefixFlag.Name) {
+		cfg.Prefix = ctx.GlobalString(ArchivePrefixFlag.Name)
+	}
+	if ctx.GlobalIsSet(ArchiveRangeFlag.Name) {
+		cfg.RangeSize = ctx.GlobalUint64(ArchiveRangeFlag.Name)
+	}
+	cfg.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	cfg.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+}
+
+func setFilters(ctx *cli.Context, cfg *filters.Config) {
+	if ctx.GlobalIsSet(LogsMaxRangeFlag.Name) {
+		cfg.MaxBlockRange = ctx.GlobalUint64(LogsMaxRangeFlag.Name)
+	}
+	if ctx.GlobalIsSet(LogsMaxResultsFlag.Name) {
+		cfg.MaxResults = ctx.GlobalInt(LogsMaxResultsFlag.Name)
+	}
+	if ctx.GlobalIsSet(FiltersMaxFlag.Name) {
+		cfg.MaxFilters = ctx.GlobalInt(FiltersMaxFlag.Name)
+	}
+	if ctx.GlobalIsSet(FiltersTimeoutFlag.Name) {
+		cfg.FilterTimeout = ctx.GlobalDuration(FiltersTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(FiltersPersistFlag.Name) {
+		cfg.PersistFilters = ctx.GlobalBool(FiltersPersistFlag.Name)
+	}
+	if ctx.GlobalIsSet(FiltersRetentionFlag.Name) {
+		cfg.PersistRetention = ctx.GlobalDuration(FiltersRetentionFlag.Name)
+	}
+}
+
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPrivateFlag.Name) {
+		cfg.Private = ctx.GlobalBool(TxPoolPrivateFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolJournalFlag.Name) {
 		cfg.Journal = ctx.GlobalString(TxPoolJournalFlag.Name)
 	}
@@ -841,6 +1217,21 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolMaxLifetimeFlag.Name) {
+		cfg.MaxLifetime = ctx.GlobalDuration(TxPoolMaxLifetimeFlag.Name)
+	}
+}
+
+// lightClientMode reports whkoker the node is configured to run as a light
+// client, either through --light or through --syncmode light.
+func lightClientMode(ctx *cli.Context) bool {
+	if ctx.GlobalBool(LightModeFlag.Name) {
+		return true
+	}
+	if ctx.GlobalIsSet(SyncModeFlag.Name) {
+		return *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode) == downloader.LightSync
+	}
+	return false
 }
 
 func checkExclusive(ctx *cli.Context, flags ...cli.Flag) {
@@ -874,6 +1265,8 @@ func SetkokConfig(ctx *cli.Context, stack *node.Node, cfg *kok.Config) {
 	setValidator(ctx, ks, cfg)
 	setCoinbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
+	setFilters(ctx, &cfg.Filters)
+	setArchive(ctx, &cfg.Archive)
 	setTxPool(ctx, &cfg.TxPool)
 
 	switch {
@@ -887,9 +1280,23 @@ func SetkokConfig(ctx *cli.Context, stack *node.Node, cfg *kok.Config) {
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
+	if cfg.SyncMode == downloader.LightSync {
+		if cfg.LightServ > 0 {
+			Fatalf("Light clients can't serve LES requests, --%s requires a full node", LightServFlag.Name)
+		}
+		if ctx.GlobalBool(MiningEnabledFlag.Name) {
+			Fatalf("Light clients do not support mining, --%s requires a full node", MiningEnabledFlag.Name)
+		}
+	}
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(LightSignFlag.Name) {
+		cfg.LightSign = ctx.GlobalBool(LightSignFlag.Name)
+	}
+	if ctx.GlobalBool(RESTEnabledFlag.Name) {
+		cfg.RESTEndpoint = fmt.Sprintf("%s:%d", ctx.GlobalString(RESTListenAddrFlag.Name), ctx.GlobalInt(RESTPortFlag.Name))
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -897,6 +1304,25 @@ func SetkokConfig(ctx *cli.Context, stack *node.Node, cfg *kok.Config) {
 	if ctx.GlobalIsSet(CacheFlag.Name) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name)
 	}
+	if ctx.GlobalIsSet(TrieCacheGenFlag.Name) {
+		state.MaxTrieCacheGen = uint16(ctx.GlobalInt(TrieCacheGenFlag.Name))
+	}
+	if ctx.GlobalIsSet(CacheGCFlag.Name) {
+		state.MaxPastTries = ctx.GlobalInt(CacheGCFlag.Name)
+	}
+	if ctx.GlobalIsSet(TrieCommitIntervalFlag.Name) {
+		cfg.TrieCommitInterval = ctx.GlobalUint64(TrieCommitIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(GCModeFlag.Name) {
+		switch mode := ctx.GlobalString(GCModeFlag.Name); mode {
+		case "full":
+			cfg.Pruning.Enabled = true
+		case "archive":
+			cfg.Pruning.Enabled = false
+		default:
+			Fatalf("Option %q: unknown garbage collection mode %q", GCModeFlag.Name, mode)
+		}
+	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
 
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
@@ -908,10 +1334,40 @@ func SetkokConfig(ctx *cli.Context, stack *node.Node, cfg *kok.Config) {
 	if ctx.GlobalIsSet(GasPriceFlag.Name) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerPolicyFlag.Name) {
+		cfg.MinerPolicy = ctx.GlobalString(MinerPolicyFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerOrderingFlag.Name) {
+		cfg.MinerOrdering = ctx.GlobalString(MinerOrderingFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerSkipOfflineFlag.Name) {
+		cfg.SkipOffline = ctx.GlobalBool(MinerSkipOfflineFlag.Name)
+	}
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(BalanceIndexFlag.Name) {
+		cfg.EnableBalanceIndex = ctx.GlobalBool(BalanceIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(InternalTxIndexFlag.Name) {
+		cfg.EnableInternalTxIndex = ctx.GlobalBool(InternalTxIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxAddressIndexFlag.Name) {
+		cfg.EnableTxAddressIndex = ctx.GlobalBool(TxAddressIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(RevertReasonIndexFlag.Name) {
+		cfg.EnableRevertReasonIndex = ctx.GlobalBool(RevertReasonIndexFlag.Name)
+	}
+	if ctx.GlobalBool(GraphQLEnabledFlag.Name) {
+		cfg.GraphQLEndpoint = fmt.Sprintf("%s:%d", ctx.GlobalString(GraphQLListenAddrFlag.Name), ctx.GlobalInt(GraphQLPortFlag.Name))
+	}
+	if ctx.GlobalIsSet(GraphQLCORSDomainFlag.Name) {
+		cfg.GraphQLCors = splitAndTrim(ctx.GlobalString(GraphQLCORSDomainFlag.Name))
+	}
+	if ctx.GlobalIsSet(AuditFlag.Name) {
+		cfg.EnableAudit = ctx.GlobalBool(AuditFlag.Name)
+	}
 }
 
 // SetDashboardConfig applies dashboard related command line flags to the config.
@@ -959,9 +1415,9 @@ func RegisterShhService(stack *node.Node, cfg *whisper.Config) {
 	}
 }
 
-// RegisterkokStatsService configures the kokereum Stats daemon and adds it to
-// th egiven node.
-func RegisterkokStatsService(stack *node.Node, url string) {
+// RegisterkokStatsService configures the kokereum Stats daemon reporting to the
+// given monitoring servers and adds it to the given node.
+func RegisterkokStatsService(stack *node.Node, targets []kokstats.Target) {
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 		// Retrieve both kok and les services
 		var kokServ *kok.kokereum
@@ -970,12 +1426,51 @@ func RegisterkokStatsService(stack *node.Node, url string) {
 		var lesServ *les.Lightkokereum
 		ctx.Service(&lesServ)
 
-		return kokstats.New(url, kokServ, lesServ)
+		return kokstats.New(targets, kokServ, lesServ)
 	}); err != nil {
 		Fatalf("Failed to register the kokereum Stats service: %v", err)
 	}
 }
 
+// RegisterPluginService loads the Go plugins at the given paths and adds them to
+// the given node, backed by whichever of the kok or les services is running.
+func RegisterPluginService(stack *node.Node, paths []string) {
+	loaded := make([]plugins.Plugin, 0, len(paths))
+	for _, path := range paths {
+		p, err := plugins.Load(path)
+		if err != nil {
+			Fatalf("Failed to load plugin %s: %v", path, err)
+		}
+		log.Info("Loaded plugin", "name", p.Name(), "path", path)
+		loaded = append(loaded, p)
+	}
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var kokServ *kok.kokereum
+		if err := ctx.Service(&kokServ); err == nil {
+			return plugins.New(loaded, kokServ.ApiBackend)
+		}
+		var lesServ *les.Lightkokereum
+		if err := ctx.Service(&lesServ); err == nil {
+			return plugins.New(loaded, lesServ.ApiBackend)
+		}
+		return nil, fmt.Errorf("plugins require the kokereum service")
+	}); err != nil {
+		Fatalf("Failed to register the plugin service: %v", err)
+	}
+}
+
+// SetupTracing enables the tracing of requests if requested on the command line.
+func SetupTracing(ctx *cli.Context) error {
+	if !ctx.GlobalBool(TracingEnabledFlag.Name) {
+		return nil
+	}
+	return tracing.Setup(tracing.Config{
+		Endpoint:    ctx.GlobalString(TracingEndpointFlag.Name),
+		SampleRatio: ctx.GlobalFloat64(TracingSampleRatioFlag.Name),
+		ServiceName: ctx.GlobalString(TracingServiceFlag.Name),
+	})
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
@@ -989,7 +1484,7 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) kokdb.Database {
 		handles = makeDatabaseHandles()
 	)
 	name := "chaindata"
-	if ctx.GlobalBool(LightModeFlag.Name) {
+	if lightClientMode(ctx) {
 		name = "lightchaindata"
 	}
 	chainDb, err := stack.OpenDatabase(name, cache, handles)
@@ -1029,7 +1524,18 @@ func MakeConsolePreloads(ctx *cli.Context) []string {
 
 	assets := ctx.GlobalString(JSpathFlag.Name)
 	for _, file := range strings.Split(ctx.GlobalString(PreloadJSFlag.Name), ",") {
-		preloads = append(preloads, common.AbsolutePath(assets, strings.TrimSpace(file)))
+		path := common.AbsolutePath(assets, strings.TrimSpace(file))
+
+		// Directories are expanded into the scripts they contain, in lexical order
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			scripts, err := filepath.Glob(filepath.Join(path, "*.js"))
+			if err != nil {
+				Fatalf("Failed to list preload directory %s: %v", path, err)
+			}
+			preloads = append(preloads, scripts...)
+			continue
+		}
+		preloads = append(preloads, path)
 	}
 	return preloads
 }
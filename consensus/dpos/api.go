@@ -17,23 +17,53 @@
 package dpos
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
 	"github.com/kokprojects/go-kok/consensus"
+	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/rlp"
 	"github.com/kokprojects/go-kok/rpc"
+	"github.com/kokprojects/go-kok/trie"
 
 	"math/big"
 )
 
+// maxExportHeaders is the maximum number of headers exported in a single batch.
+const maxExportHeaders = 256
+
 // API is a user facing RPC API to allow controlling the delegate and voting
 // mechanisms of the delegated-proof-of-stake
 type API struct {
 	chain consensus.ChainReader
 	dpos  *Dpos
+	light LightBackend // Retriever of the data missing locally, nil on full nodes
 }
 
-// GetValidators retrieves the list of the validators at specified block
-func (api *API) GetValidators(number *rpc.BlockNumber) ([]common.Address, error) {
+// stateChain is implemented by chains opening the state of their blocks through
+// their own state database, which may hold recent states not yet written to disk.
+type stateChain interface {
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// LightBackend provides the API with the data light clients don't store locally,
+// retrieving it from the network on demand.
+type LightBackend interface {
+	// RetrieveTrieNode retrieves the trie node reported missing by a lookup in
+	// the dpos trie with the given root, committed to by the given header, into
+	// the database of the engine.
+	RetrieveTrieNode(ctx context.Context, header *types.Header, root common.Hash, missing *trie.MissingNodeError) error
+
+	// State returns the account state of the given header, retrieving the
+	// accounts on demand.
+	State(ctx context.Context, header *types.Header) *state.StateDB
+}
+
+// header retrieves the header of the specified block, the current head if none.
+func (api *API) header(number *rpc.BlockNumber) (*types.Header, error) {
 	var header *types.Header
 	if number == nil || *number == rpc.LatestBlockNumber {
 		header = api.chain.CurrentHeader()
@@ -43,29 +73,323 @@ func (api *API) GetValidators(number *rpc.BlockNumber) ([]common.Address, error)
 	if header == nil {
 		return nil, errUnknownBlock
 	}
+	return header, nil
+}
+
+// do runs a lookup in the dpos trie with the given root, committed to by the
+// given header. On light clients the trie nodes missing from the database are
+// retrieved and the lookup rerun until it succeeds.
+func (api *API) do(ctx context.Context, header *types.Header, root common.Hash, lookup func() error) error {
+	var last common.Hash
+	for {
+		err := lookup()
+		missing, ok := err.(*trie.MissingNodeError)
+		if !ok || api.light == nil {
+			return err
+		}
+		if missing.NodeHash == last {
+			return fmt.Errorf("retrieve loop for trie node %x", missing.NodeHash)
+		}
+		last = missing.NodeHash
+		if err := api.light.RetrieveTrieNode(ctx, header, root, missing); err != nil {
+			return err
+		}
+	}
+}
+
+// validators retrieves the validator set stored in the epoch trie with the given
+// root, committed to by the given header.
+func (api *API) validators(ctx context.Context, header *types.Header, root common.Hash) ([]common.Address, error) {
+	var validators []common.Address
+	err := api.do(ctx, header, root, func() error {
+		epochTrie, err := types.NewEpochTrie(root, api.dpos.db)
+		if err != nil {
+			return err
+		}
+		dposContext := types.DposContext{}
+		dposContext.SetEpoch(epochTrie)
+		validators, err = dposContext.GetValidators()
+		return err
+	})
+	return validators, err
+}
+
+// GetValidators retrieves the list of the validators at specified block
+func (api *API) GetValidators(ctx context.Context, number *rpc.BlockNumber) ([]common.Address, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, err
+	}
+	return api.validators(ctx, header, header.DposContext.EpochHash)
+}
+
+// Candidate is a registered validator candidate along with the stake delegated
+// to it, the sum of the balances of its delegators.
+type Candidate struct {
+	Address    common.Address `json:"address"`
+	Stake      *hexutil.Big   `json:"stake"`
+	Delegators int            `json:"delegators"`
+}
 
-	epochTrie, err := types.NewEpochTrie(header.DposContext.EpochHash, api.dpos.db)
+// Delegation is the vote of a delegator for a candidate, weighted by the balance
+// of the delegator.
+type Delegation struct {
+	Delegator common.Address `json:"delegator"`
+	Candidate common.Address `json:"candidate"`
+	Weight    *hexutil.Big   `json:"weight"`
+}
+
+// Votes is the delegation state of an account: the candidate it votes for, if
+// any, and the votes it received if it is a candidate itself.
+type Votes struct {
+	Address   common.Address `json:"address"`
+	Candidate bool           `json:"candidate"` // Whether the account is a registered candidate
+	Vote      *Delegation    `json:"vote"`      // Vote cast by the account, nil if none
+	Stake     *hexutil.Big   `json:"stake"`     // Total weight of the received votes
+	Received  []*Delegation  `json:"received"`  // Votes received by the account
+}
+
+// GetCandidates retrieves the registered candidates at the specified block along
+// with the stake delegated to them.
+func (api *API) GetCandidates(ctx context.Context, number *rpc.BlockNumber) ([]*Candidate, error) {
+	header, statedb, err := api.stateAt(ctx, number)
 	if err != nil {
 		return nil, err
 	}
-	dposContext := types.DposContext{}
-	dposContext.SetEpoch(epochTrie)
-	validators, err := dposContext.GetValidators()
+	var addresses []common.Address
+
+	root := header.DposContext.CandidateHash
+	err = api.do(ctx, header, root, func() error {
+		candidateTrie, err := types.NewCandidateTrie(root, api.dpos.db)
+		if err != nil {
+			return err
+		}
+		addresses = addresses[:0]
+		it := trie.NewIterator(candidateTrie.NodeIterator(nil))
+		for it.Next() {
+			addresses = append(addresses, common.BytesToAddress(it.Value))
+		}
+		return it.Err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return validators, nil
+	candidates := []*Candidate{}
+	for _, address := range addresses {
+		received, stake, err := api.delegations(ctx, header, statedb, address)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, &Candidate{
+			Address:    address,
+			Stake:      (*hexutil.Big)(stake),
+			Delegators: len(received),
+		})
+	}
+	return candidates, nil
+}
+
+// GetVotes retrieves the delegation state of an account at the specified block.
+func (api *API) GetVotes(ctx context.Context, address common.Address, number *rpc.BlockNumber) (*Votes, error) {
+	header, statedb, err := api.stateAt(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	votes := new(Votes)
+	votes.Address = address
+
+	var candidate, voted []byte
+
+	root := header.DposContext.CandidateHash
+	err = api.do(ctx, header, root, func() error {
+		candidateTrie, err := types.NewCandidateTrie(root, api.dpos.db)
+		if err != nil {
+			return err
+		}
+		candidate, err = candidateTrie.TryGet(address.Bytes())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	votes.Candidate = candidate != nil
+
+	root = header.DposContext.VoteHash
+	err = api.do(ctx, header, root, func() error {
+		voteTrie, err := types.NewVoteTrie(root, api.dpos.db)
+		if err != nil {
+			return err
+		}
+		voted, err = voteTrie.TryGet(address.Bytes())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if voted != nil {
+		votes.Vote = &Delegation{
+			Delegator: address,
+			Candidate: common.BytesToAddress(voted),
+			Weight:    (*hexutil.Big)(statedb.GetBalance(address)),
+		}
+	}
+	received, stake, err := api.delegations(ctx, header, statedb, address)
+	if err != nil {
+		return nil, err
+	}
+	votes.Received, votes.Stake = received, (*hexutil.Big)(stake)
+
+	return votes, statedb.Error()
+}
+
+// stateAt retrieves the header and account state at the specified block.
+func (api *API) stateAt(ctx context.Context, number *rpc.BlockNumber) (*types.Header, *state.StateDB, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, nil, err
+	}
+	if api.light != nil {
+		return header, api.light.State(ctx, header), nil
+	}
+	var statedb *state.StateDB
+	if chain, ok := api.chain.(stateChain); ok {
+		statedb, err = chain.StateAt(header.Root)
+	} else {
+		statedb, err = state.New(header.Root, state.NewDatabase(api.dpos.db))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, statedb, nil
+}
+
+// delegations collects the votes received by a candidate, weighting them the
+// same way the epoch election does.
+func (api *API) delegations(ctx context.Context, header *types.Header, statedb *state.StateDB, candidate common.Address) ([]*Delegation, *big.Int, error) {
+	var delegators []common.Address
+
+	root := header.DposContext.DelegateHash
+	err := api.do(ctx, header, root, func() error {
+		delegateTrie, err := types.NewDelegateTrie(root, api.dpos.db)
+		if err != nil {
+			return err
+		}
+		delegators = delegators[:0]
+		it := trie.NewIterator(delegateTrie.PrefixIterator(candidate.Bytes()))
+		for it.Next() {
+			delegators = append(delegators, common.BytesToAddress(it.Value))
+		}
+		return it.Err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		received = []*Delegation{}
+		stake    = new(big.Int)
+	)
+	for _, delegator := range delegators {
+		weight := statedb.GetBalance(delegator)
+
+		received = append(received, &Delegation{Delegator: delegator, Candidate: candidate, Weight: (*hexutil.Big)(weight)})
+		stake.Add(stake, weight)
+	}
+	return received, stake, statedb.Error()
 }
 
 // GetConfirmedBlockNumber retrieves the latest irreversible block
 func (api *API) GetConfirmedBlockNumber() (*big.Int, error) {
-	var err error
-	header := api.dpos.confirmedBlockHeader
-	if header == nil {
-		header, err = api.dpos.loadConfirmedBlockHeader(api.chain)
+	header, err := api.dpos.ConfirmedHeader(api.chain)
+	if err != nil {
+		return nil, err
+	}
+	return header.Number, nil
+}
+
+// ExportedHeader is a header along with the data needed to verify its seal
+// without access to the chain.
+type ExportedHeader struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	Header     hexutil.Bytes  `json:"header"`     // RLP encoded header, hashing to Hash
+	SealData   hexutil.Bytes  `json:"sealData"`   // RLP encoded header fields covered by the signature
+	SealHash   common.Hash    `json:"sealHash"`   // Keccak256 hash of SealData, the signed digest
+	Signature  hexutil.Bytes  `json:"signature"`  // 65 byte [R || S || V] secp256k1 signature
+	Signer     common.Address `json:"signer"`     // Validator recovered from the signature
+	Validators common.Hash    `json:"validators"` // Epoch trie root of the validator set the block was sealed under
+}
+
+// EpochValidators is a validator set identified by the root of the epoch trie
+// it is stored in, which is committed to in the dpos context of every header.
+type EpochValidators struct {
+	EpochHash  common.Hash      `json:"epochHash"`
+	Validators []common.Address `json:"validators"`
+}
+
+// HeaderExport is a contiguous batch of headers with the validator sets they
+// were sealed under, for light verification of the chain from another chain.
+type HeaderExport struct {
+	Headers    []*ExportedHeader  `json:"headers"`
+	Validators []*EpochValidators `json:"validators"`
+}
+
+// ExportHeaders retrieves a contiguous batch of at most 256 canonical headers
+// starting at the given block, along with their recovered signers and the
+// validator sets in effect when they were sealed. Each block is sealed under
+// the validator set committed to by its parent, so the validator set of the
+// first header is proven by the parent header preceding the batch.
+func (api *API) ExportHeaders(ctx context.Context, from hexutil.Uint64, count hexutil.Uint64) (*HeaderExport, error) {
+	if from == 0 {
+		return nil, fmt.Errorf("genesis header is not sealed")
+	}
+	if count == 0 || count > maxExportHeaders {
+		count = maxExportHeaders
+	}
+	parent := api.chain.GkokeaderByNumber(uint64(from) - 1)
+	if parent == nil {
+		return nil, errUnknownBlock
+	}
+	export := &HeaderExport{
+		Headers:    []*ExportedHeader{},
+		Validators: []*EpochValidators{},
+	}
+	epochs := make(map[common.Hash]bool)
+	for number := uint64(from); number < uint64(from+count); number++ {
+		header := api.chain.GkokeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		if len(header.Extra) < extraSeal {
+			return nil, errMissingSignature
+		}
+		signer, err := ecrecover(header, api.dpos.signatures)
+		if err != nil {
+			return nil, err
+		}
+		enc, err := rlp.EncodeToBytes(header)
 		if err != nil {
 			return nil, err
 		}
+		epochHash := parent.DposContext.EpochHash
+		if !epochs[epochHash] {
+			validators, err := api.validators(ctx, parent, epochHash)
+			if err != nil {
+				return nil, err
+			}
+			export.Validators = append(export.Validators, &EpochValidators{EpochHash: epochHash, Validators: validators})
+			epochs[epochHash] = true
+		}
+		export.Headers = append(export.Headers, &ExportedHeader{
+			Number:     hexutil.Uint64(number),
+			Hash:       header.Hash(),
+			Header:     enc,
+			SealData:   sealData(header),
+			SealHash:   sigHash(header),
+			Signature:  common.CopyBytes(header.Extra[len(header.Extra)-extraSeal:]),
+			Signer:     signer,
+			Validators: epochHash,
+		})
+		parent = header
 	}
-	return header.Number, nil
+	return export, nil
 }
@@ -0,0 +1,207 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/trie"
+	"github.com/stretchr/testify/assert"
+)
+
+// testChainReader is a canonical chain of headers kept in memory.
+type testChainReader struct {
+	headers []*types.Header
+}
+
+func (c *testChainReader) Config() *params.ChainConfig  { return params.TestChainConfig }
+func (c *testChainReader) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+
+func (c *testChainReader) Gkokeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GkokeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c *testChainReader) GkokeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+func (c *testChainReader) GkokeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+func (c *testChainReader) GetBlock(hash common.Hash, number uint64) *types.Block { return nil }
+
+// newTestStakingChain creates a chain of a single header committing to a few
+// candidates and delegations, returning the database holding its state.
+func newTestStakingChain(t *testing.T) (kokdb.Database, *testChainReader) {
+	db, _ := kokdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	dposContext, err := types.NewDposContext(db)
+	assert.Nil(t, err)
+
+	var (
+		alice = common.StringToAddress("alice")
+		bob   = common.StringToAddress("bob")
+		carol = common.StringToAddress("carol")
+		dave  = common.StringToAddress("dave")
+	)
+	assert.Nil(t, dposContext.BecomeCandidate(alice))
+	assert.Nil(t, dposContext.BecomeCandidate(bob))
+	for delegator, balance := range map[common.Address]int64{carol: 5, dave: 7, alice: 11} {
+		statedb.SetBalance(delegator, big.NewInt(balance))
+		assert.Nil(t, dposContext.Delegate(delegator, alice))
+	}
+	assert.Nil(t, dposContext.SetValidators([]common.Address{alice, bob}))
+
+	root, err := statedb.CommitTo(db, false)
+	assert.Nil(t, err)
+	proto, err := dposContext.CommitTo(db)
+	assert.Nil(t, err)
+
+	return db, &testChainReader{headers: []*types.Header{{Number: new(big.Int), Root: root, DposContext: proto}}}
+}
+
+// Tests that the candidates and votes are reported with the stake backing them.
+func TestAPICandidatesAndVotes(t *testing.T) {
+	db, chain := newTestStakingChain(t)
+	api := &API{chain: chain, dpos: &Dpos{db: db}}
+	testAPICandidatesAndVotes(t, api)
+}
+
+// Tests that light clients look the staking state up from trie nodes retrieved
+// on demand.
+func TestAPILightRetrieval(t *testing.T) {
+	remote, chain := newTestStakingChain(t)
+	local, _ := kokdb.NewMemDatabase()
+
+	backend := &testLightBackend{local: local, remote: remote}
+	api := &API{chain: chain, dpos: &Dpos{db: local}, light: backend}
+
+	validators, err := api.GetValidators(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Address{common.StringToAddress("alice"), common.StringToAddress("bob")}, validators)
+
+	testAPICandidatesAndVotes(t, api)
+	if backend.retrievals == 0 {
+		t.Fatalf("no trie nodes retrieved")
+	}
+	// Everything is available locally now, nothing should be retrieved again
+	retrievals := backend.retrievals
+	testAPICandidatesAndVotes(t, api)
+	assert.Equal(t, retrievals, backend.retrievals)
+
+	// Without the retriever, the API should report the missing nodes
+	local, _ = kokdb.NewMemDatabase()
+	api = &API{chain: chain, dpos: &Dpos{db: local}}
+	if _, err := api.GetValidators(context.Background(), nil); err == nil {
+		t.Errorf("validators reported without the trie nodes")
+	}
+}
+
+// testLightBackend serves the trie nodes missing from the database of a light
+// client from the database of a full node, proving the keys leading to them
+// like a les server does. Account state is served from the full node directly.
+type testLightBackend struct {
+	local, remote kokdb.Database
+	retrievals    int
+}
+
+func (b *testLightBackend) RetrieveTrieNode(ctx context.Context, header *types.Header, root common.Hash, missing *trie.MissingNodeError) error {
+	tr, err := trie.New(root, b.remote)
+	if err != nil {
+		return err
+	}
+	b.retrievals++
+
+	// Convert the hex path of the missing node into a key passing through it
+	path := missing.Path
+	if len(path) > 0 && path[len(path)-1] == 0x10 {
+		path = path[:len(path)-1]
+	}
+	if len(path)%2 == 1 {
+		path = append(path, 0)
+	}
+	key := make([]byte, len(path)/2)
+	for i := range key {
+		key[i] = path[2*i]<<4 | path[2*i+1]
+	}
+	return tr.Prove(key, 0, b.local)
+}
+
+func (b *testLightBackend) State(ctx context.Context, header *types.Header) *state.StateDB {
+	statedb, _ := state.New(header.Root, state.NewDatabase(b.remote))
+	return statedb
+}
+
+func testAPICandidatesAndVotes(t *testing.T, api *API) {
+	var (
+		alice = common.StringToAddress("alice")
+		bob   = common.StringToAddress("bob")
+		dave  = common.StringToAddress("dave")
+	)
+	candidates, err := api.GetCandidates(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(candidates))
+	for _, candidate := range candidates {
+		switch candidate.Address {
+		case alice:
+			assert.Equal(t, int64(23), candidate.Stake.ToInt().Int64())
+			assert.Equal(t, 3, candidate.Delegators)
+		case bob:
+			assert.Equal(t, int64(0), candidate.Stake.ToInt().Int64())
+			assert.Equal(t, 0, candidate.Delegators)
+		default:
+			t.Errorf("unexpected candidate %x", candidate.Address)
+		}
+	}
+	votes, err := api.GetVotes(context.Background(), alice, nil)
+	assert.Nil(t, err)
+	assert.True(t, votes.Candidate)
+	assert.Equal(t, alice, votes.Vote.Candidate)
+	assert.Equal(t, int64(11), votes.Vote.Weight.ToInt().Int64())
+	assert.Equal(t, 3, len(votes.Received))
+	assert.Equal(t, int64(23), votes.Stake.ToInt().Int64())
+
+	votes, err = api.GetVotes(context.Background(), dave, nil)
+	assert.Nil(t, err)
+	assert.False(t, votes.Candidate)
+	assert.Equal(t, alice, votes.Vote.Candidate)
+	assert.Equal(t, int64(7), votes.Vote.Weight.ToInt().Int64())
+	assert.Equal(t, 0, len(votes.Received))
+
+	votes, err = api.GetVotes(context.Background(), common.StringToAddress("nobody"), nil)
+	assert.Nil(t, err)
+	assert.Nil(t, votes.Vote)
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/crypto/sha3"
+	"github.com/kokprojects/go-kok/event"
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/params"
@@ -43,9 +44,6 @@ var (
 	big8  = big.NewInt(8)
 	big32 = big.NewInt(32)
 
-	frontierBlockReward  *big.Int = big.NewInt(5e+18) // Block reward in wei for successfully mining a block
-	byzantiumBlockReward *big.Int = big.NewInt(3e+18) // Block reward in wei for successfully mining a block upward from Byzantium
-
 	timeOfFirstBlock = int64(0)
 
 	confirmedBlockHead = []byte("confirmed-block-head")
@@ -88,10 +86,19 @@ type Dpos struct {
 	signer               common.Address
 	signFn               SignerFn
 	signatures           *lru.ARCCache // Signatures of recent blocks to speed up mining
-	confirmedBlockHeader *types.Header
+	confirmedBlockHeader *types.Header // Latest irreversible block, protected by mu
 
 	mu   sync.RWMutex
 	stop chan bool
+
+	epochFeed   event.Feed
+	epochPosted map[epochKey]struct{} // Epoch transitions already posted, by parent and epoch
+	epochMu     sync.Mutex
+
+	heartbeats  map[common.Address]int64 // Time of the last heartbeat of each validator
+	started     int64                    // Time the engine started tracking heartbeats
+	skipOffline bool                     // Whkoker to stop waiting for the blocks of offline validators
+	heartbeatMu sync.Mutex
 }
 
 type SignerFn func(accounts.Account, []byte) ([]byte, error)
@@ -106,8 +113,15 @@ type SignerFn func(accounts.Account, []byte) ([]byte, error)
 // or not), which could be abused to produce different hashes for the same header.
 func sigHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewKeccak256()
+	hasher.Write(sealData(header))
+	hasher.Sum(hash[:0])
+	return hash
+}
 
-	rlp.Encode(hasher, []interface{}{
+// sealData returns the RLP encoded header fields covered by the block signature,
+// the Keccak256 hash of which is the sigHash of the header.
+func sealData(header *types.Header) []byte {
+	data, _ := rlp.EncodeToBytes([]interface{}{
 		header.ParentHash,
 		header.UncleHash,
 		header.Validator,
@@ -126,8 +140,7 @@ func sigHash(header *types.Header) (hash common.Hash) {
 		header.Nonce,
 		header.DposContext.Root(),
 	})
-	hasher.Sum(hash[:0])
-	return hash
+	return data
 }
 
 func New(config *params.DposConfig, db kokdb.Database) *Dpos {
@@ -136,6 +149,7 @@ func New(config *params.DposConfig, db kokdb.Database) *Dpos {
 		config:     config,
 		db:         db,
 		signatures: signatures,
+		started:    time.Now().Unix(),
 	}
 }
 
@@ -269,6 +283,9 @@ func (d *Dpos) verifyBlockSigner(validator common.Address, header *types.Header)
 }
 
 func (d *Dpos) updateConfirmedBlockHeader(chain consensus.ChainReader) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	if d.confirmedBlockHeader == nil {
 		header, err := d.loadConfirmedBlockHeader(chain)
 		if err != nil {
@@ -326,6 +343,18 @@ func (s *Dpos) loadConfirmedBlockHeader(chain consensus.ChainReader) (*types.Hea
 	return header, nil
 }
 
+// ConfirmedHeader retrieves the header of the latest irreversible block.
+func (d *Dpos) ConfirmedHeader(chain consensus.ChainReader) (*types.Header, error) {
+	d.mu.RLock()
+	header := d.confirmedBlockHeader
+	d.mu.RUnlock()
+
+	if header != nil {
+		return header, nil
+	}
+	return d.loadConfirmedBlockHeader(chain)
+}
+
 // store inserts the snapshot into the database.
 func (s *Dpos) storeConfirmedBlockHeader(db kokdb.Database) error {
 	return db.Put(confirmedBlockHead, s.confirmedBlockHeader.Hash().Bytes())
@@ -348,21 +377,22 @@ func (d *Dpos) Prepare(chain consensus.ChainReader, header *types.Header) error
 	return nil
 }
 
+// AccumulateRewards credits the coinbase of the given block with the reward of
+// the emission schedule configured for the chain. DPoS chains emit nothing
+// without an active one, validators being paid by transaction fees only.
 func AccumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
-	// Select the correct block reward based on chain progression
-	blockReward := frontierBlockReward
-	if config.IsByzantium(header.Number) {
-		blockReward = byzantiumBlockReward
+	if !config.IsEmission(header.Number) {
+		return
+	}
+	if reward := config.Emission.BlockReward(header.Number); reward.Sign() > 0 {
+		state.AddBalance(header.Coinbase, reward)
 	}
-	// Accumulate the rewards for the miner and any included uncles
-	reward := new(big.Int).Set(blockReward)
-	state.AddBalance(header.Coinbase, reward)
 }
 
 func (d *Dpos) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction,
 	uncles []*types.Header, receipts []*types.Receipt, dposContext *types.DposContext) (*types.Block, error) {
 	// Accumulate block rewards and commit the final state root
-	//AccumulateRewards(chain.Config(), state, header, uncles)
+	AccumulateRewards(chain.Config(), state, header, uncles)
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 
 	parent := chain.GkokeaderByHash(header.ParentHash)
@@ -377,10 +407,15 @@ func (d *Dpos) Finalize(chain consensus.ChainReader, header *types.Header, state
 		}
 	}
 	genesis := chain.GkokeaderByNumber(0)
+	oldValidators, _ := dposContext.GetValidators()
 	err := epochContext.tryElect(genesis, parent)
 	if err != nil {
 		return nil, fmt.Errorf("got error when elect next epoch, err: %s", err)
 	}
+	if epochContext.votes != nil {
+		d.postEpochEvent(header, parent, oldValidators, epochContext)
+		d.storeEpochRecord(parent, oldValidators, epochContext)
+	}
 
 	//update mint count trie
 	updateMintCnt(parent.Time.Int64(), header.Time.Int64(), header.Validator, dposContext)
@@ -398,6 +433,10 @@ func (d *Dpos) checkDeadline(lastBlock *types.Block, now int64) error {
 	if lastBlock.Time().Int64() == prevSlot || nextSlot-now <= 1 {
 		return nil
 	}
+	// don't wait for the block of an offline validator
+	if d.skipsOffline() && d.prevValidatorOffline(lastBlock, now) {
+		return nil
+	}
 	return ErrWaitForPrevBlock
 }
 
@@ -462,6 +501,17 @@ func (d *Dpos) APIs(chain consensus.ChainReader) []rpc.API {
 	}}
 }
 
+// LightAPIs returns the RPC APIs of the engine for light clients, which look the
+// staking state up from data retrieved on demand through the given backend.
+func (d *Dpos) LightAPIs(chain consensus.ChainReader, backend LightBackend) []rpc.API {
+	return []rpc.API{{
+		Namespace: "dpos",
+		Version:   "1.0",
+		Service:   &API{chain: chain, dpos: d, light: backend},
+		Public:    true,
+	}}
+}
+
 func (d *Dpos) Authorize(signer common.Address, signFn SignerFn) {
 	d.mu.Lock()
 	d.signer = signer
@@ -500,6 +550,18 @@ func NextSlot(now int64) int64 {
 	return int64((now+blockInterval-1)/blockInterval) * blockInterval
 }
 
+// SealHash returns the hash of a block header which is signed by its validator.
+func SealHash(header *types.Header) common.Hash {
+	return sigHash(header)
+}
+
+// LookupValidator returns the validator entitled to mint the block at the given
+// time, based on the dpos context of its parent.
+func LookupValidator(dposContext *types.DposContext, now int64) (common.Address, error) {
+	epochContext := &EpochContext{DposContext: dposContext}
+	return epochContext.lookupValidator(now)
+}
+
 // update counts in MintCntTrie for the miner of newBlock
 func updateMintCnt(parentBlockTime, currentBlockTime int64, validator common.Address, dposContext *types.DposContext) {
 	currentMintCntTrie := dposContext.MintCntTrie()
@@ -1,6 +1,8 @@
 package dpos
 
 import (
+	"math/big"
+	"sync"
 	"testing"
 
 	"encoding/binary"
@@ -116,3 +118,36 @@ func TestUpdateMintCnt(t *testing.T) {
 	assert.Equal(t, int64(0), beforeUpdateCnt)
 	assert.Equal(t, int64(1), afterUpdateCnt)
 }
+
+// Tests that the confirmed header can be read while block verification updates
+// it, e.g. by the archiver. Run with -race to catch unguarded accesses.
+func TestConfirmedHeaderConcurrency(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	headers := []*types.Header{{Number: new(big.Int), Time: new(big.Int)}}
+	for i := 1; i <= 8; i++ {
+		parent := headers[i-1]
+		headers = append(headers, &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent.Hash(), Time: big.NewInt(int64(i) * blockInterval)})
+	}
+	chain := &testChainReader{headers: headers}
+	engine := &Dpos{db: db}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, engine.updateConfirmedBlockHeader(chain))
+		}()
+		go func() {
+			defer wg.Done()
+			if header, err := engine.ConfirmedHeader(chain); err == nil {
+				assert.Equal(t, uint64(0), header.Number.Uint64())
+			}
+		}()
+	}
+	wg.Wait()
+
+	header, err := engine.ConfirmedHeader(chain)
+	assert.Nil(t, err)
+	assert.Equal(t, headers[0].Hash(), header.Hash())
+}
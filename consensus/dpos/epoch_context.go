@@ -21,6 +21,9 @@ type EpochContext struct {
 	TimeStamp   int64
 	DposContext *types.DposContext
 	statedb     *state.StateDB
+
+	votes    map[common.Address]*big.Int // Votes counted by the last election
+	kickouts []common.Address            // Candidates kicked out for inactivity
 }
 
 // countVotes
@@ -122,6 +125,7 @@ func (ec *EpochContext) kickoutValidator(epoch int64) error {
 		}
 		// if kickout success, candidateCount minus 1
 		candidateCount--
+		ec.kickouts = append(ec.kickouts, validator.address)
 		log.Info("Kickout candidate", "prevEpochID", epoch, "candidate", validator.address.String(), "mintCnt", validator.weight.String())
 	}
 	return nil
@@ -171,6 +175,7 @@ func (ec *EpochContext) tryElect(genesis, parent *types.Header) error {
 		if err != nil {
 			return err
 		}
+		ec.votes = votes
 		candidates := sortableAddresses{}
 		for candidate, cnt := range votes {
 			candidates = append(candidates, &sortableAddress{candidate, cnt})
@@ -0,0 +1,119 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/event"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// maxPostedEpochs is the number of recent epoch transitions remembered to avoid
+// posting the same transition when a block is both mined and imported.
+const maxPostedEpochs = 64
+
+// EpochEvent is posted when the engine finalises a block crossing an epoch
+// boundary, electing the validators of the new epoch.
+type EpochEvent struct {
+	Number        *big.Int                    `json:"number"`     // Number of the first block of the new epoch
+	ParentHash    common.Hash                 `json:"parentHash"` // Hash of the last block of the previous epoch
+	PrevEpoch     int64                       `json:"prevEpoch"`
+	Epoch         int64                       `json:"epoch"`
+	OldValidators []common.Address            `json:"oldValidators"`
+	NewValidators []common.Address            `json:"newValidators"`
+	Votes         map[common.Address]*big.Int `json:"votes"`    // Votes of the candidates taking part in the election
+	Kickouts      []common.Address            `json:"kickouts"` // Candidates kicked out for inactivity in the previous epoch
+}
+
+// epochKey identifies an epoch transition on a given chain.
+type epochKey struct {
+	parent common.Hash
+	epoch  int64
+}
+
+// SubscribeEpochEvent registers a subscription of EpochEvent.
+func (d *Dpos) SubscribeEpochEvent(ch chan<- EpochEvent) event.Subscription {
+	return d.epochFeed.Subscribe(ch)
+}
+
+// postEpochEvent posts the epoch transition made by finalising header, unless
+// it was already posted for the same parent.
+func (d *Dpos) postEpochEvent(header, parent *types.Header, oldValidators []common.Address, ec *EpochContext) {
+	key := epochKey{parent: header.ParentHash, epoch: header.Time.Int64() / epochInterval}
+
+	d.epochMu.Lock()
+	if _, ok := d.epochPosted[key]; ok {
+		d.epochMu.Unlock()
+		return
+	}
+	if d.epochPosted == nil || len(d.epochPosted) >= maxPostedEpochs {
+		d.epochPosted = make(map[epochKey]struct{})
+	}
+	d.epochPosted[key] = struct{}{}
+	d.epochMu.Unlock()
+
+	newValidators, err := ec.DposContext.GetValidators()
+	if err != nil {
+		log.Warn("Failed to retrieve elected validators", "number", header.Number, "err", err)
+		return
+	}
+	ev := EpochEvent{
+		Number:        new(big.Int).Set(header.Number),
+		ParentHash:    header.ParentHash,
+		PrevEpoch:     parent.Time.Int64() / epochInterval,
+		Epoch:         key.epoch,
+		OldValidators: oldValidators,
+		NewValidators: newValidators,
+		Votes:         ec.votes,
+		Kickouts:      ec.kickouts,
+	}
+	d.epochFeed.Send(ev)
+}
+
+// Epochs creates a subscription that is triggered each time the engine enters a
+// new epoch, reporting the validators elected for it.
+func (api *API) Epochs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		epochs := make(chan EpochEvent, 16)
+		epochSub := api.dpos.SubscribeEpochEvent(epochs)
+
+		for {
+			select {
+			case ev := <-epochs:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-rpcSub.Err():
+				epochSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				epochSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that epoch transitions are posted once per parent, carrying the election
+// inputs and results.
+func TestEpochEvent(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	stateDB, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	dposContext, err := types.NewDposContext(db)
+	assert.Nil(t, err)
+
+	old := []common.Address{common.StringToAddress("old")}
+	assert.Nil(t, dposContext.SetValidators(old))
+	candidates := []common.Address{common.StringToAddress("addr0"), common.StringToAddress("addr1")}
+	for i, candidate := range candidates {
+		assert.Nil(t, dposContext.BecomeCandidate(candidate))
+		assert.Nil(t, dposContext.Delegate(candidate, candidate))
+		stateDB.SetBalance(candidate, big.NewInt(int64(i+1)))
+	}
+	genesis := &types.Header{Time: big.NewInt(0)}
+	parent := &types.Header{Number: big.NewInt(1), Time: big.NewInt(epochInterval - blockInterval)}
+	header := &types.Header{Number: big.NewInt(2), ParentHash: parent.Hash(), Time: big.NewInt(epochInterval)}
+
+	epochContext := &EpochContext{TimeStamp: header.Time.Int64(), DposContext: dposContext, statedb: stateDB}
+	assert.Nil(t, epochContext.tryElect(genesis, parent))
+
+	engine := New(&params.DposConfig{}, db)
+	events := make(chan EpochEvent, 2)
+	sub := engine.SubscribeEpochEvent(events)
+	defer sub.Unsubscribe()
+
+	engine.postEpochEvent(header, parent, old, epochContext)
+	engine.postEpochEvent(header, parent, old, epochContext)
+
+	ev := <-events
+	assert.Equal(t, header.Number, ev.Number)
+	assert.Equal(t, parent.Hash(), ev.ParentHash)
+	assert.Equal(t, int64(0), ev.PrevEpoch)
+	assert.Equal(t, int64(1), ev.Epoch)
+	assert.Equal(t, old, ev.OldValidators)
+	assert.Equal(t, len(candidates), len(ev.NewValidators))
+	assert.Equal(t, big.NewInt(2), ev.Votes[candidates[1]])
+	select {
+	case ev := <-events:
+		t.Fatalf("duplicate epoch event posted: %v", ev)
+	default:
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/consensus"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+// epochRecordPrefix + epoch (uint64 big endian) + last block hash -> epochRecord
+var epochRecordPrefix = []byte("dpos-epoch-")
+
+// errNoEpochBlocks is returned when epoch information is requested for an epoch
+// in which no block was sealed on the canonical chain.
+var errNoEpochBlocks = errors.New("no blocks sealed in epoch")
+
+// epochRecord is the accounting of a finished epoch, stored by the engine when
+// it finalises the first block of a later epoch.
+type epochRecord struct {
+	Validators []common.Address
+	Expected   []uint64 // Slots assigned to each validator
+	Produced   []uint64 // Blocks minted by each validator
+	Kickouts   []common.Address
+}
+
+// epochRecordKey returns the database key of the record of the given epoch,
+// ended by the block with the given hash.
+func epochRecordKey(epoch int64, last common.Hash) []byte {
+	key := make([]byte, len(epochRecordPrefix)+8+common.HashLength)
+	copy(key, epochRecordPrefix)
+	binary.BigEndian.PutUint64(key[len(epochRecordPrefix):], uint64(epoch))
+	copy(key[len(epochRecordPrefix)+8:], last.Bytes())
+	return key
+}
+
+// epochSpan returns the time range [start, end) of the slots of an epoch. The
+// first epoch starts at the first block, like in kickoutValidator.
+func epochSpan(epoch, firstBlockTime int64) (int64, int64) {
+	start, end := epoch*epochInterval, (epoch+1)*epochInterval
+	if firstBlockTime > start && firstBlockTime < end {
+		start = firstBlockTime
+	}
+	return start, end
+}
+
+// scheduledBlocks counts the slots in the time range [start, end) assigned to
+// each validator, following the rotation of lookupValidator.
+func scheduledBlocks(validators []common.Address, start, end int64) []uint64 {
+	expected := make([]uint64, len(validators))
+	if len(validators) == 0 {
+		return expected
+	}
+	for slot := (start + blockInterval - 1) / blockInterval * blockInterval; slot < end; slot += blockInterval {
+		expected[(slot%epochInterval/blockInterval)%int64(len(validators))]++
+	}
+	return expected
+}
+
+// mintedBlocks retrieves the number of blocks minted by each validator in the
+// given epoch from the mint count trie.
+func mintedBlocks(dposContext *types.DposContext, epoch int64, validators []common.Address) []uint64 {
+	produced := make([]uint64, len(validators))
+	for i, validator := range validators {
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, uint64(epoch))
+		key = append(key, validator.Bytes()...)
+		if cntBytes := dposContext.MintCntTrie().Get(key); cntBytes != nil {
+			produced[i] = binary.BigEndian.Uint64(cntBytes)
+		}
+	}
+	return produced
+}
+
+// storeEpochRecord persists the accounting of the epoch ended by parent, taken
+// from the dpos context after the election of the next epoch.
+func (d *Dpos) storeEpochRecord(parent *types.Header, validators []common.Address, ec *EpochContext) {
+	if parent.Number.Sign() == 0 {
+		return
+	}
+	epoch := parent.Time.Int64() / epochInterval
+	start, end := epochSpan(epoch, timeOfFirstBlock)
+
+	record := &epochRecord{
+		Validators: validators,
+		Expected:   scheduledBlocks(validators, start, end),
+		Produced:   mintedBlocks(ec.DposContext, epoch, validators),
+		Kickouts:   ec.kickouts,
+	}
+	blob, err := rlp.EncodeToBytes(record)
+	if err != nil {
+		log.Warn("Failed to encode epoch record", "epoch", epoch, "err", err)
+		return
+	}
+	if err := d.db.Put(epochRecordKey(epoch, parent.Hash()), blob); err != nil {
+		log.Warn("Failed to store epoch record", "epoch", epoch, "err", err)
+	}
+}
+
+// loadEpochRecord retrieves the accounting of the epoch ended by the block with
+// the given hash, nil if it was not recorded.
+func (d *Dpos) loadEpochRecord(epoch int64, last common.Hash) *epochRecord {
+	blob, err := d.db.Get(epochRecordKey(epoch, last))
+	if err != nil {
+		return nil
+	}
+	record := new(epochRecord)
+	if err := rlp.DecodeBytes(blob, record); err != nil {
+		log.Warn("Failed to decode epoch record", "epoch", epoch, "err", err)
+		return nil
+	}
+	return record
+}
+
+// ValidatorActivity is the block production of a validator during an epoch.
+type ValidatorActivity struct {
+	Address   common.Address `json:"address"`
+	Expected  uint64         `json:"expected"` // Slots assigned to the validator
+	Produced  uint64         `json:"produced"` // Blocks minted by the validator
+	Missed    uint64         `json:"missed"`   // Assigned slots without a block of the validator
+	KickedOut bool           `json:"kickedOut"`
+}
+
+// EpochInfo is the validator set of an epoch along with the reliability of each
+// validator and the candidates kicked out for inactivity at the end of it.
+type EpochInfo struct {
+	Epoch      hexutil.Uint64       `json:"epoch"`
+	Finished   bool                 `json:"finished"`  // Whkoker a later epoch was entered
+	LastBlock  hexutil.Uint64       `json:"lastBlock"` // Last canonical block sealed in the epoch
+	Validators []*ValidatorActivity `json:"validators"`
+	Kickouts   []common.Address     `json:"kickouts"` // Nil if the epoch is not finished or was not recorded
+}
+
+// lastEpochHeader retrieves the last canonical header sealed in the given epoch.
+func lastEpochHeader(chain consensus.ChainReader, epoch int64) (*types.Header, error) {
+	head := chain.CurrentHeader()
+	number := sort.Search(int(head.Number.Uint64())+1, func(n int) bool {
+		return chain.GkokeaderByNumber(uint64(n)).Time.Int64()/epochInterval > epoch
+	})
+	if number == 0 {
+		return nil, errNoEpochBlocks
+	}
+	header := chain.GkokeaderByNumber(uint64(number - 1))
+	if header.Number.Sign() == 0 || header.Time.Int64()/epochInterval != epoch {
+		return nil, errNoEpochBlocks
+	}
+	return header, nil
+}
+
+// GetEpochInfo retrieves the validator set of the given epoch, the blocks each
+// validator was expected to produce, produced and missed, and the candidates
+// kicked out for inactivity when the epoch ended. The production of an epoch
+// still in progress is counted up to the current head.
+func (api *API) GetEpochInfo(epoch hexutil.Uint64) (*EpochInfo, error) {
+	head := api.chain.CurrentHeader()
+	if int64(epoch) > head.Time.Int64()/epochInterval {
+		return nil, fmt.Errorf("epoch %d not reached", epoch)
+	}
+	last, err := lastEpochHeader(api.chain, int64(epoch))
+	if err != nil {
+		return nil, err
+	}
+	info := &EpochInfo{
+		Epoch:     epoch,
+		Finished:  last.Hash() != head.Hash(),
+		LastBlock: hexutil.Uint64(last.Number.Uint64()),
+	}
+	var record *epochRecord
+	if info.Finished {
+		record = api.dpos.loadEpochRecord(int64(epoch), last.Hash())
+	}
+	if record == nil {
+		// Not recorded yet or synced without executing the transition, account
+		// for the epoch from the state of its last block.
+		dposContext, err := types.NewDposContextFromProto(api.dpos.db, last.DposContext)
+		if err != nil {
+			return nil, err
+		}
+		validators, err := dposContext.GetValidators()
+		if err != nil {
+			return nil, err
+		}
+		var firstBlockTime int64
+		if first := api.chain.GkokeaderByNumber(1); first != nil {
+			firstBlockTime = first.Time.Int64()
+		}
+		start, end := epochSpan(int64(epoch), firstBlockTime)
+		if !info.Finished {
+			end = last.Time.Int64() + 1
+		}
+		record = &epochRecord{
+			Validators: validators,
+			Expected:   scheduledBlocks(validators, start, end),
+			Produced:   mintedBlocks(dposContext, int64(epoch), validators),
+		}
+	}
+	kickouts := make(map[common.Address]bool)
+	for _, candidate := range record.Kickouts {
+		kickouts[candidate] = true
+	}
+	info.Kickouts = record.Kickouts
+	info.Validators = make([]*ValidatorActivity, len(record.Validators))
+	for i, validator := range record.Validators {
+		activity := &ValidatorActivity{
+			Address:   validator,
+			Expected:  record.Expected[i],
+			Produced:  record.Produced[i],
+			KickedOut: kickouts[validator],
+		}
+		if activity.Expected > activity.Produced {
+			activity.Missed = activity.Expected - activity.Produced
+		}
+		info.Validators[i] = activity
+	}
+	return info, nil
+}
+
+// SignerStatus is the consensus health of the local node, as reported to
+// monitoring services.
+type SignerStatus struct {
+	Validator common.Address // Signer authorized to seal blocks, zero if none
+	Active    bool           // Whkoker the signer is a validator of the current epoch
+	Epoch     uint64         // Epoch of the current head
+	Expected  uint64         // Slots assigned to the signer so far in the epoch
+	Produced  uint64         // Blocks minted by the signer so far in the epoch
+	Missed    uint64         // Assigned slots without a block of the signer
+	Confirmed uint64         // Number of the latest irreversible block
+}
+
+// Status retrieves the block production of the local signer during the epoch
+// of the current head, along with the latest irreversible block.
+func (d *Dpos) Status(chain consensus.ChainReader) (*SignerStatus, error) {
+	d.mu.RLock()
+	signer := d.signer
+	d.mu.RUnlock()
+
+	head := chain.CurrentHeader()
+	status := &SignerStatus{
+		Validator: signer,
+		Epoch:     uint64(head.Time.Int64() / epochInterval),
+	}
+	// The genesis block doesn't belong to any epoch, nothing was produced yet
+	if head.Number.Sign() > 0 {
+		api := &API{chain: chain, dpos: d}
+		info, err := api.GetEpochInfo(hexutil.Uint64(status.Epoch))
+		if err != nil {
+			return nil, err
+		}
+		for _, activity := range info.Validators {
+			if activity.Address == signer {
+				status.Active = true
+				status.Expected = activity.Expected
+				status.Produced = activity.Produced
+				status.Missed = activity.Missed
+				break
+			}
+		}
+	}
+	// No block is confirmed until the first is stored by the engine
+	if header, err := d.ConfirmedHeader(chain); err == nil {
+		status.Confirmed = header.Number.Uint64()
+	}
+	return status, nil
+}
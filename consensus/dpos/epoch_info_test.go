@@ -0,0 +1,136 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that the block production of the validators is accounted per epoch, and
+// that recorded kickouts are reported once the epoch is finished.
+func TestEpochInfo(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	dposContext, err := types.NewDposContext(db)
+	assert.Nil(t, err)
+
+	var (
+		alice = common.StringToAddress("alice")
+		bob   = common.StringToAddress("bob")
+	)
+	validators := []common.Address{alice, bob}
+	assert.Nil(t, dposContext.SetValidators(validators))
+
+	// Alice seals both blocks of the first epoch although one slot is bob's,
+	// and alice seals the head of the second epoch in her own slot.
+	headers := []*types.Header{{Number: big.NewInt(0), Time: big.NewInt(0)}}
+	for i, time := range []int64{epochInterval - 2*blockInterval, epochInterval - blockInterval, epochInterval} {
+		updateMintCnt(headers[i].Time.Int64(), time, alice, dposContext)
+		proto, err := dposContext.CommitTo(db)
+		assert.Nil(t, err)
+		headers = append(headers, &types.Header{
+			Number:      big.NewInt(int64(i + 1)),
+			ParentHash:  headers[i].Hash(),
+			Time:        big.NewInt(time),
+			DposContext: proto,
+		})
+	}
+	api := &API{chain: &testChainReader{headers: headers}, dpos: &Dpos{db: db}}
+
+	info, err := api.GetEpochInfo(0)
+	assert.Nil(t, err)
+	assert.True(t, info.Finished)
+	assert.Equal(t, hexutil.Uint64(2), info.LastBlock)
+	assert.Nil(t, info.Kickouts)
+	assert.Equal(t, &ValidatorActivity{Address: alice, Expected: 1, Produced: 2}, info.Validators[0])
+	assert.Equal(t, &ValidatorActivity{Address: bob, Expected: 1, Missed: 1}, info.Validators[1])
+
+	info, err = api.GetEpochInfo(1)
+	assert.Nil(t, err)
+	assert.False(t, info.Finished)
+	assert.Equal(t, hexutil.Uint64(3), info.LastBlock)
+	assert.Equal(t, &ValidatorActivity{Address: alice, Expected: 1, Produced: 1}, info.Validators[0])
+	assert.Equal(t, &ValidatorActivity{Address: bob}, info.Validators[1])
+
+	_, err = api.GetEpochInfo(2)
+	assert.NotNil(t, err)
+
+	// Record the first epoch the way the engine does when entering the second
+	defer func(first int64) { timeOfFirstBlock = first }(timeOfFirstBlock)
+	timeOfFirstBlock = headers[1].Time.Int64()
+
+	ec := &EpochContext{DposContext: dposContext, kickouts: []common.Address{bob}}
+	api.dpos.storeEpochRecord(headers[2], validators, ec)
+
+	info, err = api.GetEpochInfo(0)
+	assert.Nil(t, err)
+	assert.Equal(t, []common.Address{bob}, info.Kickouts)
+	assert.Equal(t, &ValidatorActivity{Address: alice, Expected: 1, Produced: 2}, info.Validators[0])
+	assert.Equal(t, &ValidatorActivity{Address: bob, Expected: 1, Missed: 1, KickedOut: true}, info.Validators[1])
+}
+
+// Tests that the status of the local signer reflects its block production in
+// the epoch of the current head.
+func TestStatus(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	dposContext, err := types.NewDposContext(db)
+	assert.Nil(t, err)
+
+	var (
+		alice = common.StringToAddress("alice")
+		bob   = common.StringToAddress("bob")
+		carol = common.StringToAddress("carol")
+	)
+	assert.Nil(t, dposContext.SetValidators([]common.Address{alice, bob}))
+
+	// Alice seals the first slot of the second epoch, bob misses the next one
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0)}
+	updateMintCnt(0, epochInterval, alice, dposContext)
+	proto, err := dposContext.CommitTo(db)
+	assert.Nil(t, err)
+	first := &types.Header{Number: big.NewInt(1), ParentHash: genesis.Hash(), Time: big.NewInt(epochInterval), DposContext: proto}
+	head := &types.Header{Number: big.NewInt(2), ParentHash: first.Hash(), Time: big.NewInt(epochInterval + 2*blockInterval), DposContext: proto}
+
+	chain := &testChainReader{headers: []*types.Header{genesis, first, head}}
+	engine := &Dpos{db: db}
+
+	status, err := engine.Status(chain)
+	assert.Nil(t, err)
+	assert.Equal(t, &SignerStatus{Epoch: 1}, status)
+
+	engine.Authorize(bob, nil)
+	status, err = engine.Status(chain)
+	assert.Nil(t, err)
+	assert.Equal(t, &SignerStatus{Validator: bob, Active: true, Epoch: 1, Expected: 1, Missed: 1}, status)
+
+	engine.Authorize(alice, nil)
+	engine.confirmedBlockHeader = first
+	status, err = engine.Status(chain)
+	assert.Nil(t, err)
+	assert.Equal(t, &SignerStatus{Validator: alice, Active: true, Epoch: 1, Expected: 2, Produced: 1, Missed: 1, Confirmed: 1}, status)
+
+	engine.Authorize(carol, nil)
+	status, err = engine.Status(chain)
+	assert.Nil(t, err)
+	assert.False(t, status.Active)
+}
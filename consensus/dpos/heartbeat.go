@@ -0,0 +1,209 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kokprojects/go-kok/accounts"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/crypto/sha3"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+const (
+	// HeartbeatInterval is the interval at which validators announce their liveness.
+	HeartbeatInterval = time.Duration(blockInterval) * time.Second
+
+	heartbeatDrift   = 2 * blockInterval // Maximum clock difference tolerated on received heartbeats
+	heartbeatTimeout = 3 * blockInterval // Time after the last heartbeat at which a validator is considered offline
+)
+
+var (
+	// errUnauthorized is returned when a heartbeat is requested from an engine
+	// not authorized to sign as a validator.
+	errUnauthorized = errors.New("not authorized to sign heartbeats")
+
+	// ErrStaleHeartbeat is returned if a heartbeat is too far off the local time.
+	ErrStaleHeartbeat = errors.New("stale heartbeat")
+
+	// ErrUnknownValidator is returned if a heartbeat is not signed by a validator
+	// of the current epoch.
+	ErrUnknownValidator = errors.New("heartbeat from unknown validator")
+)
+
+// Heartbeat is a signed message gossiped by the validators to announce that they
+// are online, allowing the others to detect missing validators ahead of their
+// slots.
+type Heartbeat struct {
+	Validator common.Address
+	Time      uint64
+	Signature []byte
+}
+
+// Hash returns the hash identifying the heartbeat.
+func (h *Heartbeat) Hash() common.Hash {
+	hw := sha3.NewKeccak256()
+	rlp.Encode(hw, h)
+
+	var hash common.Hash
+	hw.Sum(hash[:0])
+	return hash
+}
+
+// sigHash returns the hash signed by the validator of the heartbeat.
+func (h *Heartbeat) sigHash() common.Hash {
+	hw := sha3.NewKeccak256()
+	rlp.Encode(hw, []interface{}{"heartbeat", h.Validator, h.Time})
+
+	var hash common.Hash
+	hw.Sum(hash[:0])
+	return hash
+}
+
+// SignHeartbeat creates a heartbeat of the authorized validator at the given time.
+func (d *Dpos) SignHeartbeat(now int64) (*Heartbeat, error) {
+	d.mu.RLock()
+	signer, signFn := d.signer, d.signFn
+	d.mu.RUnlock()
+
+	if signFn == nil || signer == (common.Address{}) {
+		return nil, errUnauthorized
+	}
+	hb := &Heartbeat{Validator: signer, Time: uint64(now)}
+	sig, err := signFn(accounts.Account{Address: signer}, hb.sigHash().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	hb.Signature = sig
+	return hb, nil
+}
+
+// AddHeartbeat verifies a heartbeat received at the given time against the
+// validators of the epoch the head block is in, and records the liveness of its
+// validator. It returns whkoker the heartbeat was the most recent one known from
+// the validator, and should thus be propagated.
+func (d *Dpos) AddHeartbeat(head *types.Header, hb *Heartbeat, now int64) (bool, error) {
+	if diff := int64(hb.Time) - now; diff > heartbeatDrift || diff < -heartbeatDrift {
+		return false, ErrStaleHeartbeat
+	}
+	pubkey, err := crypto.Ecrecover(hb.sigHash().Bytes(), hb.Signature)
+	if err != nil {
+		return false, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	if signer != hb.Validator {
+		return false, ErrMismatchSignerAndValidator
+	}
+	dposContext, err := types.NewDposContextFromProto(d.db, head.DposContext)
+	if err != nil {
+		return false, err
+	}
+	validators, err := dposContext.GetValidators()
+	if err != nil {
+		return false, err
+	}
+	known := false
+	for _, validator := range validators {
+		if validator == hb.Validator {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return false, ErrUnknownValidator
+	}
+	d.heartbeatMu.Lock()
+	defer d.heartbeatMu.Unlock()
+
+	if d.heartbeats == nil {
+		d.heartbeats = make(map[common.Address]int64)
+	}
+	if last, ok := d.heartbeats[hb.Validator]; ok && last >= int64(hb.Time) {
+		return false, nil
+	}
+	d.heartbeats[hb.Validator] = int64(hb.Time)
+	return true, nil
+}
+
+// Heartbeats returns the time of the last heartbeat received from each validator.
+func (d *Dpos) Heartbeats() map[common.Address]int64 {
+	d.heartbeatMu.Lock()
+	defer d.heartbeatMu.Unlock()
+
+	heartbeats := make(map[common.Address]int64, len(d.heartbeats))
+	for validator, last := range d.heartbeats {
+		heartbeats[validator] = last
+	}
+	return heartbeats
+}
+
+// Online reports whkoker a validator announced its liveness recently enough to
+// be expected to mint its slots. Validators not heard of yet are considered
+// online until the engine has been running for long enough to expect it.
+func (d *Dpos) Online(validator common.Address, now int64) bool {
+	d.heartbeatMu.Lock()
+	defer d.heartbeatMu.Unlock()
+
+	last, ok := d.heartbeats[validator]
+	if !ok {
+		last = d.started
+	}
+	return now-last <= heartbeatTimeout
+}
+
+// SetSkipOffline sets whkoker the engine stops waiting for the block of the
+// previous slot when its validator is offline, as detected from its heartbeats,
+// instead of waiting until the very end of the slot.
+func (d *Dpos) SetSkipOffline(skip bool) {
+	d.heartbeatMu.Lock()
+	defer d.heartbeatMu.Unlock()
+
+	d.skipOffline = skip
+}
+
+// skipsOffline reports whkoker the engine skips the slots of offline validators.
+func (d *Dpos) skipsOffline() bool {
+	d.heartbeatMu.Lock()
+	defer d.heartbeatMu.Unlock()
+
+	return d.skipOffline
+}
+
+// prevValidatorOffline reports whkoker the validator of the slot preceding now
+// on top of the given block is known to be offline.
+func (d *Dpos) prevValidatorOffline(lastBlock *types.Block, now int64) bool {
+	dposContext, err := types.NewDposContextFromProto(d.db, lastBlock.Header().DposContext)
+	if err != nil {
+		return false
+	}
+	validator, err := LookupValidator(dposContext, PrevSlot(now))
+	if err != nil {
+		return false
+	}
+	return !d.Online(validator, now)
+}
+
+// GetHeartbeats retrieves the time of the last heartbeat received from each
+// validator.
+func (api *API) GetHeartbeats() map[common.Address]int64 {
+	return api.dpos.Heartbeats()
+}
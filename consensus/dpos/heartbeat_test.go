@@ -0,0 +1,97 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/accounts"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that heartbeats are only accepted from validators of the current epoch,
+// and that their absence marks the validator offline.
+func TestHeartbeat(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	key, _ := crypto.GenerateKey()
+	validator := crypto.PubkeyToAddress(key.PublicKey)
+	other := common.StringToAddress("other")
+
+	dposContext, err := types.NewDposContext(db)
+	assert.Nil(t, err)
+	assert.Nil(t, dposContext.SetValidators([]common.Address{validator, other}))
+	proto, err := dposContext.CommitTo(db)
+	assert.Nil(t, err)
+	head := types.NewBlock(&types.Header{Number: big.NewInt(1), Time: big.NewInt(0), DposContext: proto}, nil, nil, nil)
+
+	engine := New(&params.DposConfig{}, db)
+	_, err = engine.SignHeartbeat(100)
+	assert.Equal(t, errUnauthorized, err)
+
+	engine.Authorize(validator, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	hb, err := engine.SignHeartbeat(100)
+	assert.Nil(t, err)
+
+	// Fresh heartbeats are accepted once, stale or forged ones rejected
+	fresh, err := engine.AddHeartbeat(head.Header(), hb, 101)
+	assert.Nil(t, err)
+	assert.True(t, fresh)
+	fresh, err = engine.AddHeartbeat(head.Header(), hb, 101)
+	assert.Nil(t, err)
+	assert.False(t, fresh)
+
+	_, err = engine.AddHeartbeat(head.Header(), hb, 100+heartbeatDrift+1)
+	assert.Equal(t, ErrStaleHeartbeat, err)
+
+	forged := &Heartbeat{Validator: other, Time: hb.Time, Signature: hb.Signature}
+	_, err = engine.AddHeartbeat(head.Header(), forged, 100)
+	assert.Equal(t, ErrMismatchSignerAndValidator, err)
+
+	outsider, _ := crypto.GenerateKey()
+	engine.Authorize(crypto.PubkeyToAddress(outsider.PublicKey), func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, outsider)
+	})
+	unknown, _ := engine.SignHeartbeat(100)
+	_, err = engine.AddHeartbeat(head.Header(), unknown, 100)
+	assert.Equal(t, ErrUnknownValidator, err)
+
+	// Validators are online until their heartbeats time out
+	engine.started = 0
+	assert.True(t, engine.Online(validator, 100+heartbeatTimeout))
+	assert.False(t, engine.Online(validator, 100+heartbeatTimeout+1))
+	assert.False(t, engine.Online(other, 100))
+
+	// The block of the previous slot is only skipped for offline validators if enabled
+	var now int64
+	for now = 3 * blockInterval; ; now += blockInterval {
+		if prev, _ := LookupValidator(dposContext, PrevSlot(now+1)); prev == other {
+			break
+		}
+	}
+	now++
+	assert.Equal(t, ErrWaitForPrevBlock, engine.checkDeadline(head, now))
+	engine.SetSkipOffline(true)
+	assert.Nil(t, engine.checkDeadline(head, now))
+}
@@ -0,0 +1,167 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"encoding/binary"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/rlp"
+	"github.com/kokprojects/go-kok/trie"
+)
+
+// ProofList is a Merkle proof of a dpos trie entry, holding the RLP encoded trie
+// nodes on the path from the root of the trie to the entry.
+type ProofList []rlp.RawValue
+
+// Put implements trie.DatabaseWriter, collecting the nodes of a proof.
+func (n *ProofList) Put(key []byte, value []byte) error {
+	*n = append(*n, common.CopyBytes(value))
+	return nil
+}
+
+// trieIterator is the common part of the dpos trie iterators, positioned on a
+// raw key-value entry of a trie.
+type trieIterator struct {
+	trie *trie.Trie
+	it   *trie.Iterator
+
+	Key []byte // Raw trie key of the current entry, including the trie prefix
+}
+
+func newTrieIterator(tr *trie.Trie, it trie.NodeIterator) trieIterator {
+	return trieIterator{trie: tr, it: trie.NewIterator(it)}
+}
+
+// next moves the iterator to the next entry with a key of at least the given
+// length, the entries being keyed by fixed size suffixes of their raw keys.
+func (it *trieIterator) next(keyLen int) bool {
+	for it.it.Next() {
+		if len(it.it.Key) >= keyLen {
+			it.Key = it.it.Key
+			return true
+		}
+	}
+	it.Key = nil
+	return false
+}
+
+// suffix returns the last n bytes of the current key.
+func (it *trieIterator) suffix(n int) []byte {
+	return it.Key[len(it.Key)-n:]
+}
+
+// Err returns the error, if any, encountered during iteration.
+func (it *trieIterator) Err() error {
+	return it.it.Err
+}
+
+// Root returns the root hash of the iterated trie, which proofs are made against.
+func (it *trieIterator) Root() common.Hash {
+	return it.trie.Hash()
+}
+
+// Prove returns a Merkle proof of the current entry, verifiable against the root
+// of the trie with trie.VerifyProof for the raw key of the entry.
+func (it *trieIterator) Prove() (ProofList, error) {
+	var proof ProofList
+	if err := it.trie.Prove(it.Key, 0, &proof); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// CandidateIterator iterates over the candidates of a dpos context, in the order
+// of their addresses.
+type CandidateIterator struct {
+	trieIterator
+	Candidate common.Address
+}
+
+// NewCandidateIterator creates an iterator over the candidates of a dpos context,
+// starting at the given address.
+func NewCandidateIterator(dposContext *types.DposContext, start common.Address) *CandidateIterator {
+	tr := dposContext.CandidateTrie()
+	return &CandidateIterator{trieIterator: newTrieIterator(tr, tr.NodeIterator(start.Bytes()))}
+}
+
+// Next moves the iterator to the next candidate, returning whkoker there was one.
+func (it *CandidateIterator) Next() bool {
+	if !it.next(common.AddressLength) {
+		it.Candidate = common.Address{}
+		return false
+	}
+	it.Candidate = common.BytesToAddress(it.suffix(common.AddressLength))
+	return true
+}
+
+// VoteIterator iterates over the votes of a dpos context, i.e. the candidate each
+// delegator delegated to, in the order of the delegator addresses.
+type VoteIterator struct {
+	trieIterator
+	Delegator common.Address
+	Candidate common.Address
+}
+
+// NewVoteIterator creates an iterator over the votes of a dpos context, starting
+// at the given delegator address.
+func NewVoteIterator(dposContext *types.DposContext, start common.Address) *VoteIterator {
+	tr := dposContext.VoteTrie()
+	return &VoteIterator{trieIterator: newTrieIterator(tr, tr.NodeIterator(start.Bytes()))}
+}
+
+// Next moves the iterator to the next vote, returning whkoker there was one.
+func (it *VoteIterator) Next() bool {
+	if !it.next(common.AddressLength) {
+		it.Delegator, it.Candidate = common.Address{}, common.Address{}
+		return false
+	}
+	it.Delegator = common.BytesToAddress(it.suffix(common.AddressLength))
+	it.Candidate = common.BytesToAddress(it.it.Value)
+	return true
+}
+
+// MintCntIterator iterates over the number of blocks minted by each validator in
+// an epoch, in the order of the validator addresses.
+type MintCntIterator struct {
+	trieIterator
+	Epoch     int64
+	Validator common.Address
+	Count     int64
+}
+
+// NewMintCntIterator creates an iterator over the mint counts of the validators
+// in the given epoch of a dpos context.
+func NewMintCntIterator(dposContext *types.DposContext, epoch int64) *MintCntIterator {
+	prefix := make([]byte, 8)
+	binary.BigEndian.PutUint64(prefix, uint64(epoch))
+
+	tr := dposContext.MintCntTrie()
+	return &MintCntIterator{trieIterator: newTrieIterator(tr, tr.PrefixIterator(prefix)), Epoch: epoch}
+}
+
+// Next moves the iterator to the next validator, returning whkoker there was one.
+func (it *MintCntIterator) Next() bool {
+	if !it.next(8 + common.AddressLength) {
+		it.Validator, it.Count = common.Address{}, 0
+		return false
+	}
+	it.Validator = common.BytesToAddress(it.suffix(common.AddressLength))
+	it.Count = int64(binary.BigEndian.Uint64(it.it.Value))
+	return true
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/trie"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDposTrieIterators(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	dposContext := mockNewDposContext(db)
+	for i := 0; i < 3; i++ {
+		setMintCntTrie(1, common.HexToAddress(MockEpoch[i]), dposContext.MintCntTrie(), int64(i+1))
+	}
+	setMintCntTrie(2, common.HexToAddress(MockEpoch[3]), dposContext.MintCntTrie(), 4)
+
+	// verify checks the proof of the current entry of an iterator
+	verify := func(it *trieIterator) {
+		proof, err := it.Prove()
+		assert.Nil(t, err)
+		proofDb, _ := kokdb.NewMemDatabase()
+		for _, node := range proof {
+			proofDb.Put(crypto.Keccak256(node), node)
+		}
+		value, err, _ := trie.VerifyProof(it.Root(), it.Key, proofDb)
+		assert.Nil(t, err)
+		assert.True(t, bytes.Equal(value, it.it.Value))
+	}
+	// Iterate over all the candidates, ensuring they are ordered and proven
+	var prev common.Address
+	count := 0
+	for it := NewCandidateIterator(dposContext, common.Address{}); it.Next(); count++ {
+		assert.True(t, bytes.Compare(prev.Bytes(), it.Candidate.Bytes()) < 0)
+		assert.NotNil(t, dposContext.CandidateTrie().Get(it.Candidate.Bytes()))
+		verify(&it.trieIterator)
+		prev = it.Candidate
+	}
+	assert.Equal(t, len(MockEpoch), count)
+
+	// Iterate over the votes starting after the first delegator
+	count = 0
+	for it := NewVoteIterator(dposContext, common.Address{1}); it.Next(); count++ {
+		assert.True(t, bytes.Compare(it.Delegator.Bytes(), common.Address{1}.Bytes()) >= 0)
+		assert.Equal(t, it.Delegator, it.Candidate)
+		verify(&it.trieIterator)
+	}
+	assert.True(t, count > 0 && count <= len(MockEpoch))
+
+	// Iterate over the mint counts of a single epoch
+	counts := make(map[common.Address]int64)
+	it := NewMintCntIterator(dposContext, 1)
+	for it.Next() {
+		assert.Equal(t, int64(1), it.Epoch)
+		counts[it.Validator] = it.Count
+		verify(&it.trieIterator)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 3, len(counts))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, int64(i+1), counts[common.HexToAddress(MockEpoch[i])])
+	}
+	// Ensure iterating over empty tries works
+	empty := NewMintCntIterator(mockNewDposContext(db), 1)
+	assert.False(t, empty.Next())
+}
@@ -0,0 +1,184 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core/types"
+)
+
+// maxSlots is the maximum number of upcoming slots returned by a single query.
+const maxSlots = 1024
+
+// Slot is a time slot in which a validator is entitled to mint a block.
+type Slot struct {
+	Time      hexutil.Uint64 `json:"time"` // Unix time of the slot
+	Validator common.Address `json:"validator"`
+}
+
+// ValidatorStatus is the state of a validator as seen from the current head.
+type ValidatorStatus struct {
+	Address   common.Address  `json:"address"`
+	Candidate bool            `json:"candidate"`          // Registered as a candidate
+	Active    bool            `json:"active"`             // Member of the validator set of the current epoch
+	Online    bool            `json:"online"`             // Announced its liveness recently
+	Epoch     hexutil.Uint64  `json:"epoch"`              // Current epoch
+	Minted    hexutil.Uint64  `json:"minted"`             // Blocks minted in the current epoch
+	Missed    hexutil.Uint64  `json:"missed"`             // Slots missed over the last epoch interval
+	NextSlot  *hexutil.Uint64 `json:"nextSlot,omitempty"` // Time of the next slot in the current epoch, if any
+}
+
+// GetValidatorStatus retrieves the status of a validator at the current head.
+func (api *API) GetValidatorStatus(validator common.Address) (*ValidatorStatus, error) {
+	head := api.chain.CurrentHeader()
+	dposContext, err := types.NewDposContextFromProto(api.dpos.db, head.DposContext)
+	if err != nil {
+		return nil, err
+	}
+	epoch := head.Time.Int64() / epochInterval
+	status := &ValidatorStatus{
+		Address:   validator,
+		Candidate: dposContext.CandidateTrie().Get(validator.Bytes()) != nil,
+		Online:    api.dpos.Online(validator, time.Now().Unix()),
+		Epoch:     hexutil.Uint64(epoch),
+	}
+	validators, err := dposContext.GetValidators()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range validators {
+		if v == validator {
+			status.Active = true
+			break
+		}
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(epoch))
+	if blob := dposContext.MintCntTrie().Get(append(key, validator.Bytes()...)); len(blob) == 8 {
+		status.Minted = hexutil.Uint64(binary.BigEndian.Uint64(blob))
+	}
+	if status.Active {
+		slots, err := upcomingSlots(head, dposContext, &validator, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(slots) > 0 {
+			status.NextSlot = &slots[0].Time
+		}
+	}
+	missed, err := api.missedSlots(head, &validator)
+	if err != nil {
+		return nil, err
+	}
+	status.Missed = hexutil.Uint64(len(missed))
+	return status, nil
+}
+
+// GetSlots retrieves the upcoming slots of the current epoch, optionally only
+// those of a given validator. The validators of the following epochs are not
+// known until elected by their first block.
+func (api *API) GetSlots(validator *common.Address, count *int) ([]Slot, error) {
+	head := api.chain.CurrentHeader()
+	dposContext, err := types.NewDposContextFromProto(api.dpos.db, head.DposContext)
+	if err != nil {
+		return nil, err
+	}
+	limit := maxSlots
+	if count != nil && *count > 0 && *count < maxSlots {
+		limit = *count
+	}
+	return upcomingSlots(head, dposContext, validator, limit)
+}
+
+// GetMissedSlots retrieves the slots left empty over the last epoch interval,
+// optionally only those of a given validator.
+func (api *API) GetMissedSlots(validator *common.Address) ([]Slot, error) {
+	return api.missedSlots(api.chain.CurrentHeader(), validator)
+}
+
+// upcomingSlots lists at most limit slots following both the head and the local
+// time, up to the end of the epoch of the head.
+func upcomingSlots(head *types.Header, dposContext *types.DposContext, validator *common.Address, limit int) ([]Slot, error) {
+	var (
+		epoch = head.Time.Int64() / epochInterval
+		from  = NextSlot(time.Now().Unix())
+		slots = []Slot{}
+	)
+	if next := head.Time.Int64() + blockInterval; from < next {
+		from = next
+	}
+	for slot := from; slot < (epoch+1)*epochInterval && len(slots) < limit; slot += blockInterval {
+		owner, err := LookupValidator(dposContext, slot)
+		if err != nil {
+			return nil, err
+		}
+		if validator == nil || owner == *validator {
+			slots = append(slots, Slot{Time: hexutil.Uint64(slot), Validator: owner})
+		}
+	}
+	return slots, nil
+}
+
+// missedSlots walks the chain back from the head over an epoch interval and
+// lists the slots no block was minted in, in chronological order.
+func (api *API) missedSlots(head *types.Header, validator *common.Address) ([]Slot, error) {
+	var (
+		since  = head.Time.Int64() - epochInterval
+		missed []Slot
+	)
+	for child := head; child.Number.Sign() > 0 && child.Time.Int64() > since; {
+		parent := api.chain.Gkokeader(child.ParentHash, child.Number.Uint64()-1)
+		if parent == nil {
+			return nil, errUnknownBlock
+		}
+		// The slots in between were assigned using the context of the epoch they
+		// belong to, elected either by the parent or by the child itself
+		var contexts [2]*types.DposContext
+		for slot := child.Time.Int64() - blockInterval; slot > parent.Time.Int64() && slot > since; slot -= blockInterval {
+			index, header := 0, child
+			if slot/epochInterval != child.Time.Int64()/epochInterval {
+				index, header = 1, parent
+			}
+			if contexts[index] == nil {
+				dposContext, err := types.NewDposContextFromProto(api.dpos.db, header.DposContext)
+				if err != nil {
+					return nil, err
+				}
+				contexts[index] = dposContext
+			}
+			owner, err := LookupValidator(contexts[index], slot)
+			if err != nil {
+				return nil, err
+			}
+			if validator == nil || owner == *validator {
+				missed = append(missed, Slot{Time: hexutil.Uint64(slot), Validator: owner})
+			}
+		}
+		child = parent
+	}
+	for i, j := 0, len(missed)-1; i < j; i, j = i+1, j-1 {
+		missed[i], missed[j] = missed[j], missed[i]
+	}
+	if missed == nil {
+		missed = []Slot{}
+	}
+	return missed, nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests that the upcoming and missed slots are derived from the validator set
+// and the gaps between the blocks of the chain.
+func TestSlots(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	dposContext, err := types.NewDposContext(db)
+	assert.Nil(t, err)
+
+	validators := []common.Address{common.StringToAddress("a"), common.StringToAddress("b"), common.StringToAddress("c")}
+	assert.Nil(t, dposContext.SetValidators(validators))
+	assert.Nil(t, dposContext.BecomeCandidate(validators[0]))
+
+	// Start the chain at the next epoch so that all its slots are upcoming
+	base := (time.Now().Unix()/epochInterval + 1) * epochInterval
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(base/epochInterval))
+	count := make([]byte, 8)
+	binary.BigEndian.PutUint64(count, 2)
+	dposContext.MintCntTrie().TryUpdate(append(key, validators[0].Bytes()...), count)
+
+	proto, err := dposContext.CommitTo(db)
+	assert.Nil(t, err)
+
+	// Slots cycle through a, b, c: leave the slots of c and a after block #1 empty
+	chain := new(testChainReader)
+	for i, offset := range []int64{0, 5, 20, 25} {
+		header := &types.Header{Number: big.NewInt(int64(i)), Time: big.NewInt(base + offset), DposContext: proto}
+		if i > 0 {
+			header.ParentHash = chain.headers[i-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	api := &API{chain: chain, dpos: New(&params.DposConfig{}, db)}
+
+	missed, err := api.GetMissedSlots(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []Slot{{hexutil.Uint64(base + 10), validators[2]}, {hexutil.Uint64(base + 15), validators[0]}}, missed)
+
+	missed, err = api.GetMissedSlots(&validators[1])
+	assert.Nil(t, err)
+	assert.Equal(t, []Slot{}, missed)
+
+	limit := 2
+	slots, err := api.GetSlots(&validators[0], &limit)
+	assert.Nil(t, err)
+	assert.Equal(t, []Slot{{hexutil.Uint64(base + 30), validators[0]}, {hexutil.Uint64(base + 45), validators[0]}}, slots)
+
+	status, err := api.GetValidatorStatus(validators[0])
+	assert.Nil(t, err)
+	assert.True(t, status.Candidate)
+	assert.True(t, status.Active)
+	assert.Equal(t, hexutil.Uint64(2), status.Minted)
+	assert.Equal(t, hexutil.Uint64(1), status.Missed)
+	assert.Equal(t, hexutil.Uint64(base+30), *status.NextSlot)
+
+	status, err = api.GetValidatorStatus(common.StringToAddress("d"))
+	assert.Nil(t, err)
+	assert.False(t, status.Candidate || status.Active)
+	assert.Nil(t, status.NextSlot)
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kokash
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core/types"
+)
+
+var (
+	errNoMiningWork = errors.New("no mining work available yet")
+	errFakeMode     = errors.New("kokash running in fake mode")
+)
+
+// API exposes kokash related mkokods for the RPC interface, allowing external
+// miners to fetch work from and submit solutions to the local sealer.
+type API struct {
+	kokash *kokash
+}
+
+// GetWork returns a work package for an external miner. The work package
+// consists of 3 strings:
+//
+//	result[0] - 32 bytes hex encoded current block header pow-hash
+//	result[1] - 32 bytes hex encoded seed hash used for DAG
+//	result[2] - 32 bytes hex encoded boundary condition ("target"), 2^256/difficulty
+func (api *API) GetWork() ([3]string, error) {
+	work := api.kokash.remoteWork()
+	if work == nil {
+		return [3]string{}, errNoMiningWork
+	}
+	header := work.block.Header()
+	target := new(big.Int).Div(maxUint256, header.Difficulty)
+
+	return [3]string{
+		header.HashNoNonce().Hex(),
+		common.BytesToHash(SeedHash(header.Number.Uint64())).Hex(),
+		common.BytesToHash(target.Bytes()).Hex(),
+	}, nil
+}
+
+// SubmitWork can be used by external miners to submit their proof-of-work
+// solution. It returns an indication if the work was accepted. Note, a solution
+// can be rejected both for being invalid and for belonging to stale work.
+func (api *API) SubmitWork(nonce types.BlockNonce, hash, digest common.Hash) bool {
+	return api.kokash.submitWork(nonce, hash, digest)
+}
+
+// SubmitHashRate can be used by remote miners to submit their hash rate, which
+// is accounted for in the node's reported hashrate. The id is an arbitrary value
+// chosen by the miner to tell apart its reports from those of others.
+func (api *API) SubmitHashRate(rate hexutil.Uint64, id common.Hash) bool {
+	api.kokash.submitHashrate(id, uint64(rate))
+	return true
+}
+
+// PrivateDebugAPI exposes kokash mkokods over the debug RPC namespace, allowing
+// node operators to manage the verification caches and mining datasets.
+type PrivateDebugAPI struct {
+	kokash *kokash
+}
+
+// DAGGeneration reports the epoch whose verification cache and mining dataset
+// are being generated in the background.
+type DAGGeneration struct {
+	Epoch   hexutil.Uint64 `json:"epoch"`
+	Cache   bool           `json:"cache"`   // Whkoker the cache generation was started
+	Dataset bool           `json:"dataset"` // Whkoker the dataset generation was started
+}
+
+// GenerateDAG starts generating the verification cache and mining dataset of the
+// epoch of the given block in the background, storing them on disk if the engine
+// is configured to. It returns immediately; a cache or dataset which is already
+// in use or being generated is not started again.
+func (api *PrivateDebugAPI) GenerateDAG(number hexutil.Uint64) (*DAGGeneration, error) {
+	if api.kokash.fakeMode {
+		return nil, errFakeMode
+	}
+	epoch := uint64(number) / epochLength
+	cache, dataset := api.kokash.pregenerate(epoch, true)
+
+	return &DAGGeneration{Epoch: hexutil.Uint64(epoch), Cache: cache, Dataset: dataset}, nil
+}
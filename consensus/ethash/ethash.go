@@ -31,6 +31,7 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/consensus"
 	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/rpc"
@@ -256,6 +257,7 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 
 			d.dataset = make([]uint32, dsize/4)
 			generateDataset(d.dataset, d.epoch, cache)
+			return
 		}
 		// Disk storage is needed, this will get fancy
 		var endian string
@@ -282,7 +284,7 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 		if err != nil {
 			logger.Error("Failed to generate mapped kokash dataset", "err", err)
 
-			d.dataset = make([]uint32, dsize/2)
+			d.dataset = make([]uint32, dsize/4)
 			generateDataset(d.dataset, d.epoch, cache)
 		}
 		// Iterate over all previous instances and delete old ones
@@ -341,6 +343,9 @@ type kokash struct {
 	update   chan struct{} // Notification channel to update mining parameters
 	hashrate metrics.Meter // Meter tracking the average hashrate
 
+	work        *sealWork                  // Sealing task currently exposed to remote miners
+	remoteRates map[common.Hash]remoteRate // Hashrates reported by remote miners
+
 	// The fields below are hooks for testing
 	tester    bool          // Flag whkoker to use a smaller test dataset
 	shared    *kokash       // Shared PoW verifier to avoid cache regeneration
@@ -513,8 +518,7 @@ func (kokash *kokash) dataset(block uint64) []uint32 {
 		// If we have the new cache pre-generated, use that, otherwise create a new one
 		if kokash.fdataset != nil && kokash.fdataset.epoch == epoch {
 			log.Trace("Using pre-generated dataset", "epoch", epoch)
-			current = &dataset{epoch: kokash.fdataset.epoch} // Reload from disk
-			kokash.fdataset = nil
+			current, kokash.fdataset = kokash.fdataset, nil
 		} else {
 			log.Trace("Requiring new kokash dataset", "epoch", epoch)
 			current = &dataset{epoch: epoch}
@@ -549,6 +553,43 @@ func (kokash *kokash) dataset(block uint64) []uint32 {
 	return current.dataset
 }
 
+// pregenerate starts generating the verification cache and, if full is set, the
+// mining dataset of the given epoch in the background, replacing the ones being
+// prepared for the estimated future epoch. This way they are readily available,
+// and persisted on disk if configured, by the time the chain reaches the epoch.
+// It reports whkoker the cache and dataset generations were started.
+func (kokash *kokash) pregenerate(epoch uint64, full bool) (bool, bool) {
+	// If we're running a shared PoW, generate on that instead
+	if kokash.shared != nil {
+		return kokash.shared.pregenerate(epoch, full)
+	}
+	kokash.lock.Lock()
+	defer kokash.lock.Unlock()
+
+	var cacheStarted, datasetStarted bool
+	if kokash.caches[epoch] == nil && (kokash.fcache == nil || kokash.fcache.epoch != epoch) {
+		if kokash.fcache != nil {
+			kokash.fcache.release()
+		}
+		log.Debug("Pre-generating kokash cache", "epoch", epoch)
+		future := &cache{epoch: epoch}
+		go future.generate(kokash.cachedir, kokash.cachesondisk, kokash.tester)
+
+		kokash.fcache, cacheStarted = future, true
+	}
+	if full && kokash.datasets[epoch] == nil && (kokash.fdataset == nil || kokash.fdataset.epoch != epoch) {
+		if kokash.fdataset != nil {
+			kokash.fdataset.release()
+		}
+		log.Debug("Pre-generating kokash dataset", "epoch", epoch)
+		future := &dataset{epoch: epoch}
+		go future.generate(kokash.dagdir, kokash.dagsondisk, kokash.tester)
+
+		kokash.fdataset, datasetStarted = future, true
+	}
+	return cacheStarted, datasetStarted
+}
+
 // Threads returns the number of mining threads currently enabled. This doesn't
 // necessarily mean that mining is running!
 func (kokash *kokash) Threads() int {
@@ -581,15 +622,25 @@ func (kokash *kokash) SetThreads(threads int) {
 }
 
 // Hashrate implements PoW, returning the measured rate of the search invocations
-// per second over the last minute.
+// per second over the last minute, including the rates reported by remote miners.
 func (kokash *kokash) Hashrate() float64 {
-	return kokash.hashrate.Rate1()
+	return kokash.hashrate.Rate1() + float64(kokash.remoteHashrate())
 }
 
-// APIs implements consensus.Engine, returning the user facing RPC APIs. Currently
-// that is empty.
+// APIs implements consensus.Engine, returning the user facing RPC APIs, which
+// allow external miners to work on the blocks sealed by this engine and node
+// operators to prepare the caches and datasets of upcoming epochs.
 func (kokash *kokash) APIs(chain consensus.ChainReader) []rpc.API {
-	return nil
+	return []rpc.API{{
+		Namespace: "kok",
+		Version:   "1.0",
+		Service:   &API{kokash},
+		Public:    true,
+	}, {
+		Namespace: "debug",
+		Version:   "1.0",
+		Service:   &PrivateDebugAPI{kokash},
+	}}
 }
 
 // SeedHash is the seed to use for generating a verification cache and the mining
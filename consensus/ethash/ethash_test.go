@@ -19,7 +19,10 @@ package kokash
 import (
 	"math/big"
 	"testing"
+	"time"
 
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
 	"github.com/kokprojects/go-kok/core/types"
 )
 
@@ -38,3 +41,92 @@ func TestTestMode(t *testing.T) {
 		t.Fatalf("unexpected verification error: %v", err)
 	}
 }
+
+// Tests that blocks can be sealed by remote miners through the work API.
+func TestRemoteSealer(t *testing.T) {
+	head := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(100)}
+	block := types.NewBlockWithHeader(head)
+
+	kokash := NewTester()
+	kokash.SetThreads(-1)
+	api := &API{kokash}
+
+	if _, err := api.GetWork(); err != errNoMiningWork {
+		t.Fatalf("work error mismatch: have %v, want %v", err, errNoMiningWork)
+	}
+	results := make(chan *types.Block)
+	go func() {
+		sealed, _ := kokash.Seal(nil, block, nil)
+		results <- sealed
+	}()
+	// Wait for the work to be published and check its contents
+	var work [3]string
+	for i := 0; ; i++ {
+		var err error
+		if work, err = api.GetWork(); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("no work published")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if work[0] != block.HashNoNonce().Hex() {
+		t.Errorf("work hash mismatch: have %s, want %s", work[0], block.HashNoNonce().Hex())
+	}
+	// Solve the block with a local sealer and submit the solution remotely
+	solved, err := NewTester().Seal(nil, block, nil)
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	if api.SubmitWork(solved.Header().Nonce, block.HashNoNonce(), common.Hash{}) {
+		t.Errorf("invalid solution accepted")
+	}
+	if !api.SubmitWork(solved.Header().Nonce, block.HashNoNonce(), solved.MixDigest()) {
+		t.Fatalf("valid solution rejected")
+	}
+	if sealed := <-results; sealed.Nonce() != solved.Nonce() {
+		t.Errorf("sealed nonce mismatch: have %d, want %d", sealed.Nonce(), solved.Nonce())
+	}
+	if _, err := api.GetWork(); err != errNoMiningWork {
+		t.Errorf("stale work error mismatch: have %v, want %v", err, errNoMiningWork)
+	}
+	// Remote hashrates should be accounted for
+	api.SubmitHashRate(hexutil.Uint64(100), common.Hash{0x01})
+	api.SubmitHashRate(hexutil.Uint64(50), common.Hash{0x02})
+	if rate := kokash.remoteHashrate(); rate != 150 {
+		t.Errorf("remote hashrate mismatch: have %d, want %d", rate, 150)
+	}
+}
+
+// Tests that the caches and datasets of an upcoming epoch can be generated in
+// advance and are picked up once the epoch is reached.
+func TestPregenerate(t *testing.T) {
+	kokash := NewTester()
+	api := &PrivateDebugAPI{kokash}
+
+	gen, err := api.GenerateDAG(hexutil.Uint64(2*epochLength + 1))
+	if err != nil {
+		t.Fatalf("failed to start generation: %v", err)
+	}
+	if want := (&DAGGeneration{Epoch: 2, Cache: true, Dataset: true}); *gen != *want {
+		t.Fatalf("generation mismatch: have %+v, want %+v", gen, want)
+	}
+	cache, dataset := kokash.fcache, kokash.fdataset
+
+	// Requesting the same epoch again must not restart the generation
+	if gen, _ = api.GenerateDAG(hexutil.Uint64(2 * epochLength)); gen.Cache || gen.Dataset {
+		t.Fatalf("generation restarted: %+v", gen)
+	}
+	kokash.cache(2 * epochLength)
+	kokash.dataset(2 * epochLength)
+	if kokash.caches[2] != cache {
+		t.Errorf("pre-generated cache not used")
+	}
+	if kokash.datasets[2] != dataset {
+		t.Errorf("pre-generated dataset not used")
+	}
+	if _, err := (&PrivateDebugAPI{NewFaker()}).GenerateDAG(0); err != errFakeMode {
+		t.Errorf("fake mode error mismatch: have %v, want %v", err, errFakeMode)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"math/rand"
 	"runtime"
 	"sync"
+	"time"
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/consensus"
@@ -30,6 +31,24 @@ import (
 	"github.com/kokprojects/go-kok/log"
 )
 
+// remoteRateTTL is the time after which a hashrate reported by a remote miner
+// is considered stale and dropped.
+const remoteRateTTL = 10 * time.Second
+
+// sealWork is a sealing task exposed to remote miners, along with the channels
+// of the seal operation to deliver solutions to.
+type sealWork struct {
+	block *types.Block
+	found chan *types.Block
+	abort chan struct{}
+}
+
+// remoteRate is a hashrate reported by a remote miner.
+type remoteRate struct {
+	rate uint64
+	ping time.Time
+}
+
 // Seal implements consensus.Engine, attempting to find a nonce that satisfies
 // the block's difficulty requirements.
 func (kokash *kokash) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
@@ -49,6 +68,8 @@ func (kokash *kokash) Seal(chain consensus.ChainReader, block *types.Block, stop
 
 	kokash.lock.Lock()
 	threads := kokash.threads
+	work := &sealWork{block: block, found: found, abort: abort}
+	kokash.work = work
 	if kokash.rand == nil {
 		seed, err := crand.Int(crand.Reader, big.NewInt(math.MaxInt64))
 		if err != nil {
@@ -87,11 +108,89 @@ func (kokash *kokash) Seal(chain consensus.ChainReader, block *types.Block, stop
 		pend.Wait()
 		return kokash.Seal(chain, block, stop)
 	}
-	// Wait for all miners to terminate and return the block
+	// Wait for all miners to terminate, retract the remote work and return the block
 	pend.Wait()
+
+	kokash.lock.Lock()
+	if kokash.work == work {
+		kokash.work = nil
+	}
+	kokash.lock.Unlock()
+
 	return result, nil
 }
 
+// remoteWork returns the sealing task currently exposed to remote miners, or
+// nil if the engine isn't sealing.
+func (kokash *kokash) remoteWork() *sealWork {
+	if kokash.shared != nil {
+		return kokash.shared.remoteWork()
+	}
+	kokash.lock.Lock()
+	defer kokash.lock.Unlock()
+
+	return kokash.work
+}
+
+// submitWork verifies a proof-of-work solution found by a remote miner, and if
+// it's valid for the current sealing task, delivers the sealed block to it.
+func (kokash *kokash) submitWork(nonce types.BlockNonce, hash, digest common.Hash) bool {
+	work := kokash.remoteWork()
+	if work == nil || work.block.HashNoNonce() != hash {
+		log.Info("Work submitted but none pending", "hash", hash)
+		return false
+	}
+	header := work.block.Header()
+	header.Nonce, header.MixDigest = nonce, digest
+
+	if err := kokash.VerifySeal(nil, header); err != nil {
+		log.Warn("Invalid proof-of-work submitted", "hash", hash, "err", err)
+		return false
+	}
+	select {
+	case work.found <- work.block.WithSeal(header):
+		log.Trace("Remote kokash nonce accepted", "hash", hash, "nonce", nonce.Uint64())
+		return true
+	case <-work.abort:
+		log.Trace("Remote kokash nonce found but discarded", "hash", hash, "nonce", nonce.Uint64())
+		return false
+	}
+}
+
+// submitHashrate records the hashrate reported by a remote miner.
+func (kokash *kokash) submitHashrate(id common.Hash, rate uint64) {
+	if kokash.shared != nil {
+		kokash.shared.submitHashrate(id, rate)
+		return
+	}
+	kokash.lock.Lock()
+	defer kokash.lock.Unlock()
+
+	if kokash.remoteRates == nil {
+		kokash.remoteRates = make(map[common.Hash]remoteRate)
+	}
+	kokash.remoteRates[id] = remoteRate{rate: rate, ping: time.Now()}
+}
+
+// remoteHashrate returns the accumulated hashrate of the remote miners which
+// reported recently, dropping the stale reports.
+func (kokash *kokash) remoteHashrate() (total uint64) {
+	if kokash.shared != nil {
+		return kokash.shared.remoteHashrate()
+	}
+	kokash.lock.Lock()
+	defer kokash.lock.Unlock()
+
+	for id, rate := range kokash.remoteRates {
+		if time.Since(rate.ping) > remoteRateTTL {
+			delete(kokash.remoteRates, id)
+			continue
+		}
+		total += rate.rate
+	}
+	return total
+}
+
 // mine is the actual proof-of-work miner that searches for a nonce starting from
 // seed that results in correct final block difficulty.
 func (kokash *kokash) mine(block *types.Block, id int, seed uint64, abort chan struct{}, found chan *types.Block) {
@@ -126,6 +126,10 @@ func (c *Console) init(preload []string) error {
 	if _, err := c.jsre.Run("var web3 = new Web3(jkok);"); err != nil {
 		return fmt.Errorf("web3 provider: %v", err)
 	}
+	// Allow preloaded and user scripts to require() helper modules from the docroot
+	if err := c.jsre.EnableModules(); err != nil {
+		return fmt.Errorf("module loader: %v", err)
+	}
 	// Load the supported APIs into the JavaScript runtime environment
 	apis, err := c.client.SupportedModules()
 	if err != nil {
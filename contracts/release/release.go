@@ -93,6 +93,10 @@ func (r *ReleaseService) Protocols() []p2p.Protocol { return nil }
 // expose any functioanlity to the outside world.
 func (r *ReleaseService) APIs() []rpc.API { return nil }
 
+// Auxiliary implements node.AuxiliaryService, allowing the release checker to
+// be stopped and restarted while the node is running.
+func (r *ReleaseService) Auxiliary() {}
+
 // Start spawns the periodic version checker goroutine
 func (r *ReleaseService) Start(server *p2p.Server) error {
 	go r.checker()
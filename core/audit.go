@@ -0,0 +1,114 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/types"
+)
+
+// AuditError is a violation of the accounting invariants checked in audit mode
+// (vm.Config.EnableAudit), carrying a report of the offending transaction.
+type AuditError struct {
+	Number  *big.Int              // Number of the block being processed
+	TxIndex int                   // Index of the transaction within the block
+	TxHash  common.Hash           // Hash of the offending transaction
+	Reason  string                // Description of the violated invariant
+	Changes []state.BalanceChange // Balance changes made by the transaction
+}
+
+func (e *AuditError) Error() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "audit violation in block %v, tx %d (%x): %s", e.Number, e.TxIndex, e.TxHash, e.Reason)
+	for _, change := range e.Changes {
+		fmt.Fprintf(&buf, "\n\t%x: %v -> %v (%v)", change.Address, change.Before, change.After, new(big.Int).Sub(change.After, change.Before))
+	}
+	return buf.String()
+}
+
+// auditTransaction checks the accounting of a transaction that was just applied
+// onto statedb with balance tracking enabled:
+//
+//   - the gas refund is capped to half of the gas used before refunding, and
+//     to the refund counter accumulated during execution
+//   - the fee split of contract calls accounts for all the gas used
+//   - the sum of all balances is left unchanged (fees move from the sender to
+//     the coinbase and contract developer), unless an account self-destructed,
+//     in which case funds may be burned but never minted
+//
+// Block rewards are applied outside of transactions and are not audited.
+func auditTransaction(statedb *state.StateDB, header *types.Header, index int, tx *types.Transaction, receipt *types.Receipt, requiredGas, usedGas, refund *big.Int) *AuditError {
+	fail := func(changes []state.BalanceChange, format string, args ...interface{}) *AuditError {
+		return &AuditError{
+			Number:  header.Number,
+			TxIndex: index,
+			TxHash:  tx.Hash(),
+			Reason:  fmt.Sprintf(format, args...),
+			Changes: changes,
+		}
+	}
+	if usedGas.Cmp(tx.Gas()) > 0 {
+		return fail(nil, "gas used %v exceeds gas limit %v", usedGas, tx.Gas())
+	}
+	refunded := new(big.Int).Sub(requiredGas, usedGas)
+	if refunded.Sign() < 0 {
+		return fail(nil, "negative gas refund %v", refunded)
+	}
+	if limit := new(big.Int).Div(requiredGas, common.Big2); refunded.Cmp(limit) > 0 {
+		return fail(nil, "gas refund %v exceeds cap %v (half of %v)", refunded, limit, requiredGas)
+	}
+	if refunded.Cmp(refund) > 0 {
+		return fail(nil, "gas refund %v exceeds refund counter %v", refunded, refund)
+	}
+	if receipt.GasDeveloper != nil || receipt.GasTreasury != nil {
+		split := new(big.Int).Set(receipt.GasMiner)
+		if receipt.GasDeveloper != nil {
+			split.Add(split, receipt.GasDeveloper)
+		}
+		if receipt.GasTreasury != nil {
+			split.Add(split, receipt.GasTreasury)
+		}
+		if split.Cmp(usedGas) != 0 {
+			return fail(nil, "fee split %v (miner) + %v (developer) + %v (treasury) does not match gas used %v", receipt.GasMiner, receipt.GasDeveloper, receipt.GasTreasury, usedGas)
+		}
+	}
+	// Find the balance changes of the transaction, none means nothing moved
+	var changes *state.TxBalanceChanges
+	if all := statedb.BalanceChanges(); len(all) > 0 && all[len(all)-1].TxHash == tx.Hash() {
+		changes = all[len(all)-1]
+	}
+	if changes == nil {
+		return nil
+	}
+	supply := new(big.Int)
+	for _, change := range changes.Changes {
+		supply.Add(supply, change.After)
+		supply.Sub(supply, change.Before)
+	}
+	switch {
+	case supply.Sign() > 0:
+		return fail(changes.Changes, "balances increased by %v", supply)
+	case supply.Sign() < 0 && !changes.Destructed:
+		return fail(changes.Changes, "balances decreased by %v without self-destruct", new(big.Int).Neg(supply))
+	}
+	return nil
+}
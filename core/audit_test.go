@@ -0,0 +1,109 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/consensus/kokash"
+	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+)
+
+// Tests that a chain of plain transfers passes import in audit mode.
+func TestAuditImport(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		db, _   = kokdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(1000000000000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	chain, _ := GenerateChain(gspec.Config, genesis, db, 3, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(types.Binary, gen.TxNonce(addr), common.Address{byte(i + 1)}, big.NewInt(1000), bigTxGas, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	blockchain, _ := NewBlockChain(db, gspec.Config, kokash.NewFaker(), vm.Config{EnableAudit: true})
+	defer blockchain.Stop()
+
+	if i, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("insert error (block %d): %v", chain[i].NumberU64(), err)
+	}
+	// Audit mode must not leak into the balance index
+	if changes := GetBlockBalanceChanges(db, chain[0].Hash(), chain[0].NumberU64()); changes != nil {
+		t.Errorf("balance changes indexed without recording enabled: %v", changes)
+	}
+}
+
+// Tests that the individual accounting invariants are enforced.
+func TestAuditTransaction(t *testing.T) {
+	var (
+		header = &types.Header{Number: big.NewInt(1)}
+		tx     = types.NewTransaction(types.Binary, 0, common.Address{}, new(big.Int), big.NewInt(100000), new(big.Int), nil)
+		addr1  = common.Address{0x01}
+		addr2  = common.Address{0x02}
+	)
+	tests := []struct {
+		apply     func(*state.StateDB)
+		required  int64
+		used      int64
+		refund    int64
+		violation string
+	}{
+		// Balanced transfer with a capped refund
+		{func(s *state.StateDB) { s.SubBalance(addr1, big.NewInt(10)); s.AddBalance(addr2, big.NewInt(10)) }, 30000, 21000, 9000, ""},
+		// Refund beyond half of the gas used
+		{nil, 30000, 14000, 16000, "exceeds cap"},
+		// Refund beyond the refund counter
+		{nil, 30000, 21000, 5000, "exceeds refund counter"},
+		// Minting and burning without self-destruct
+		{func(s *state.StateDB) { s.AddBalance(addr2, big.NewInt(1)) }, 21000, 21000, 0, "increased"},
+		{func(s *state.StateDB) { s.SubBalance(addr1, big.NewInt(1)) }, 21000, 21000, 0, "decreased"},
+		// Self-destructing to itself burns the balance
+		{func(s *state.StateDB) { s.Suicide(addr1) }, 21000, 21000, 0, ""},
+	}
+	for i, tt := range tests {
+		db, _ := kokdb.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		statedb.SetBalance(addr1, big.NewInt(100))
+		statedb.EnableBalanceTracking()
+		statedb.Prepare(tx.Hash(), common.Hash{}, 0)
+		if tt.apply != nil {
+			tt.apply(statedb)
+		}
+		statedb.FinaliseBalanceChanges()
+
+		receipt := &types.Receipt{}
+		err := auditTransaction(statedb, header, 0, tx, receipt, big.NewInt(tt.required), big.NewInt(tt.used), big.NewInt(tt.refund))
+		switch {
+		case tt.violation == "" && err != nil:
+			t.Errorf("test %d: unexpected violation: %v", i, err)
+		case tt.violation != "" && err == nil:
+			t.Errorf("test %d: violation not detected, want %q", i, tt.violation)
+		case tt.violation != "" && !strings.Contains(err.Error(), tt.violation):
+			t.Errorf("test %d: violation mismatch: have %v, want %q", i, err, tt.violation)
+		}
+	}
+}
@@ -69,6 +69,14 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+	// Ensure the transactions abide by the rules of their types after the fork
+	if v.config.IsTxTypes(header.Number) {
+		for i, tx := range block.Transactions() {
+			if err := tx.Validate(); err != nil {
+				return fmt.Errorf("invalid transaction %d [%x]: %v", i, tx.Hash(), err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -17,13 +17,18 @@
 package core
 
 import (
+	"crypto/ecdsa"
+	"math/big"
 	"runtime"
 	"testing"
 	"time"
 
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/consensus/dpos"
 	"github.com/kokprojects/go-kok/consensus/kokash"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/params"
 )
@@ -197,3 +202,90 @@ func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 		t.Errorf("verification count too large: have %d, want below %d", verified, 2*threads)
 	}
 }
+
+// Tests that block bodies carrying transactions violating the rules of their
+// types are rejected, not only by the transaction pool.
+func TestBodyTransactionTypeValidation(t *testing.T) {
+	var (
+		testdb, _ = kokdb.NewMemDatabase()
+		gspec     = &Genesis{Config: params.TestChainConfig}
+		genesis   = gspec.MustCommit(testdb)
+		blocks, _ = GenerateChain(params.TestChainConfig, genesis, testdb, 1, nil)
+	)
+	chain, _ := NewBlockChain(testdb, params.TestChainConfig, kokash.NewFaker(), vm.Config{})
+	defer chain.Stop()
+
+	tests := []struct {
+		tx    *types.Transaction
+		valid bool
+	}{
+		{types.NewTransaction(types.Binary, 0, common.Address{1}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), []byte{0x01}), true},
+		{types.NewTransaction(types.Delegate, 0, common.Address{1}, new(big.Int), big.NewInt(21000), big.NewInt(1), nil), true},
+		{types.NewTransaction(types.Delegate, 0, common.Address{1}, new(big.Int), big.NewInt(21000), big.NewInt(1), []byte{0x01}), false},
+		{types.NewTransaction(types.UnDelegate, 0, common.Address{1}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), false},
+		{types.NewTransaction(types.TxType(0xff), 0, common.Address{1}, new(big.Int), big.NewInt(21000), big.NewInt(1), nil), false},
+	}
+	for i, tt := range tests {
+		block := types.NewBlock(blocks[0].Header(), []*types.Transaction{tt.tx}, nil, nil)
+		if err := chain.Validator().ValidateBody(block); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
+
+// Tests that the transaction type rules are only enforced on block bodies from
+// the TxType fork block on, so chains carrying transactions accepted before the
+// rules existed (e.g. delegations transferring value) can still be imported.
+func TestTxTypeForkTransition(t *testing.T) {
+	testTxTypeForkTransition(t, 1, true)
+	testTxTypeForkTransition(t, 2, false)
+}
+
+func testTxTypeForkTransition(t *testing.T, number int, valid bool) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 2)
+		addrs = make([]common.Address, 2)
+		db, _ = kokdb.NewMemDatabase()
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	config := *params.DposChainConfig
+	config.Dpos = &params.DposConfig{Validators: addrs}
+	config.TxTypeBlock = big.NewInt(2)
+
+	gspec := &Genesis{
+		Config: &config,
+		Alloc:  GenesisAlloc{addrs[0]: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	genesis := gspec.MustCommit(db)
+
+	// Include a delegation transferring value, which the type rules forbid
+	signer := types.NewEIP155Signer(config.ChainId)
+	chain, _ := GenerateDposChain(&config, genesis, dpos.New(config.Dpos, db), db, keys, 3, func(i int, gen *BlockGen) {
+		if i == number-1 {
+			tx, _ := types.SignTx(types.NewTransaction(types.Delegate, gen.TxNonce(addrs[0]), addrs[1], big.NewInt(1), bigTxGas, nil, nil), signer, keys[0])
+			gen.AddTx(tx)
+		}
+	})
+	// Import the chain into a fresh node, as if re-syncing the history
+	db, _ = kokdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	blockchain, err := NewBlockChain(db, &config, dpos.New(config.Dpos, db), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	i, err := blockchain.InsertChain(chain)
+	switch {
+	case valid && err != nil:
+		t.Errorf("block #%d: pre-fork block rejected: %v", number, err)
+	case !valid && err == nil:
+		t.Errorf("block #%d: post-fork block accepted", number)
+	case !valid && chain[i].NumberU64() != uint64(number):
+		t.Errorf("block #%d: rejected block mismatch: have #%d", number, chain[i].NumberU64())
+	}
+}
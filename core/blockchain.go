@@ -58,6 +58,10 @@ const (
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
 
+	// maxBufferedState is the size of buffered state data above which it is
+	// flushed to disk regardless of the configured commit interval.
+	maxBufferedState = 256 * 1024 * 1024
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion = 3
 )
@@ -82,6 +86,7 @@ type BlockChain struct {
 	hc            *HeaderChain
 	chainDb       kokdb.Database
 	rmLogsFeed    event.Feed
+	reorgFeed     event.Feed
 	chainFeed     event.Feed
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
@@ -97,11 +102,13 @@ type BlockChain struct {
 	currentBlock     *types.Block // Current head of the block chain
 	currentFastBlock *types.Block // Current head of the fast-sync chain (may be above the block chain!)
 
-	stateCache   state.Database // State database to reuse between imports (contains state cache)
-	bodyCache    *lru.Cache     // Cache for the most recent block bodies
-	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
-	blockCache   *lru.Cache     // Cache for the most recent entire blocks
-	futureBlocks *lru.Cache     // future blocks are blocks added for later processing
+	stateCache   state.Database    // State database to reuse between imports (contains state cache)
+	stateBuffer  *state.NodeBuffer // Write buffer of the state database holding unflushed tries
+	stateReaders *state.ReaderSet  // State roots in use by concurrent read-only readers
+	bodyCache    *lru.Cache        // Cache for the most recent block bodies
+	bodyRLPCache *lru.Cache        // Cache for the most recent block bodies in RLP encoded format
+	blockCache   *lru.Cache        // Cache for the most recent entire blocks
+	futureBlocks *lru.Cache        // future blocks are blocks added for later processing
 
 	quit    chan struct{} // blockchain quit channel
 	running int32         // running must be called atomically
@@ -109,10 +116,17 @@ type BlockChain struct {
 	procInterrupt int32          // interrupt signaler for block processing
 	wg            sync.WaitGroup // chain processing wait group for shutting down
 
-	engine    consensus.Engine
-	processor Processor // block processor interface
-	validator Validator // block and state validator interface
-	vmConfig  vm.Config
+	engine     consensus.Engine
+	processor  Processor  // block processor interface
+	prefetcher Prefetcher // block state prefetcher interface
+	validator  Validator  // block and state validator interface
+	vmConfig   vm.Config
+
+	commitInterval uint64 // Number of blocks whose state is buffered in memory before being flushed (atomic)
+
+	pruning      PruningConfig   // State trie garbage collection settings
+	retained     []retainedState // Recent states kept in memory when pruning
+	lastSnapshot uint64          // Number of the block whose state was last persisted when pruning
 
 	badBlocks *lru.Cache // Bad block cache
 }
@@ -121,26 +135,35 @@ type BlockChain struct {
 // available in the database. It initialises the default kokereum Validator and
 // Processor.
 func NewBlockChain(chainDb kokdb.Database, config *params.ChainConfig, engine consensus.Engine, vmConfig vm.Config) (*BlockChain, error) {
+	if err := vm.CheckPrecompiles(config); err != nil {
+		return nil, err
+	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
 	badBlocks, _ := lru.New(badBlockLimit)
 
+	stateBuffer := state.NewNodeBuffer(chainDb)
+
 	bc := &BlockChain{
-		config:       config,
-		chainDb:      chainDb,
-		stateCache:   state.NewDatabase(chainDb),
-		quit:         make(chan struct{}),
-		bodyCache:    bodyCache,
-		bodyRLPCache: bodyRLPCache,
-		blockCache:   blockCache,
-		futureBlocks: futureBlocks,
-		engine:       engine,
-		vmConfig:     vmConfig,
-		badBlocks:    badBlocks,
+		config:         config,
+		chainDb:        chainDb,
+		stateCache:     state.NewDatabase(stateBuffer),
+		stateBuffer:    stateBuffer,
+		stateReaders:   state.NewReaderSet(),
+		quit:           make(chan struct{}),
+		bodyCache:      bodyCache,
+		bodyRLPCache:   bodyRLPCache,
+		blockCache:     blockCache,
+		futureBlocks:   futureBlocks,
+		engine:         engine,
+		vmConfig:       vmConfig,
+		commitInterval: 1,
+		badBlocks:      badBlocks,
 	}
 	bc.SetValidator(NewBlockValidator(config, bc, engine))
+	bc.SetPrefetcher(newStatePrefetcher(config, bc))
 	bc.SetProcessor(NewStateProcessor(config, bc, engine))
 
 	var err error
@@ -196,9 +219,15 @@ func (bc *BlockChain) loadLastState() error {
 	}
 	// Make sure the state associated with the block is available
 	if _, err := state.New(currentBlock.Root(), bc.stateCache); err != nil {
-		// Dangling block without a state associated, init from scratch
-		log.Warn("Head state missing, resetting chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
-		return bc.Reset()
+		// Dangling block without a state associated, rewind to the last flushed state
+		log.Warn("Head state missing, repairing chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
+		if err := bc.repair(&currentBlock); err != nil {
+			log.Warn("Chain repair failed, resetting chain", "err", err)
+			return bc.Reset()
+		}
+		if err := WriteHeadBlockHash(bc.chainDb, currentBlock.Hash()); err != nil {
+			return err
+		}
 	}
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock = currentBlock
@@ -348,6 +377,78 @@ func (bc *BlockChain) Status() (td *big.Int, currentBlock common.Hash, genesisBl
 	return bc.GetTd(bc.currentBlock.Hash(), bc.currentBlock.NumberU64()), bc.currentBlock.Hash(), bc.genesisBlock.Hash()
 }
 
+// repair tries to repair the current blockchain by rolling back the current block
+// until one with associated state is found. This is needed to recover from a
+// crash, where the state of the most recent blocks may not have been flushed.
+func (bc *BlockChain) repair(head **types.Block) error {
+	for {
+		// Abort if we've rewound to a head block that does have associated state
+		if _, err := state.New((*head).Root(), bc.stateCache); err == nil {
+			log.Info("Rewound blockchain to past state", "number", (*head).Number(), "hash", (*head).Hash())
+			return nil
+		}
+		// Otherwise rewind one block and recheck state availability there
+		parent := bc.GetBlock((*head).ParentHash(), (*head).NumberU64()-1)
+		if parent == nil {
+			return fmt.Errorf("missing block %d [%x]", (*head).NumberU64()-1, (*head).ParentHash())
+		}
+		*head = parent
+	}
+}
+
+// SetCommitInterval sets the number of blocks whose state is buffered in memory
+// before being flushed to disk together. An interval of 0 or 1 flushes the state
+// of every block immediately.
+func (bc *BlockChain) SetCommitInterval(interval uint64) {
+	if interval == 0 {
+		interval = 1
+	}
+	atomic.StoreUint64(&bc.commitInterval, interval)
+}
+
+// TrieCacheStats contains the statistics of the in-memory state trie caches.
+type TrieCacheStats struct {
+	CacheGens      uint16                `json:"cacheGens"`
+	PastTries      int                   `json:"pastTries"`
+	CommitInterval uint64                `json:"commitInterval"`
+	Buffer         state.NodeBufferStats `json:"buffer"`
+	Readers        int                   `json:"readers"`
+	Pruning        bool                  `json:"pruning"`
+	RetainedStates int                   `json:"retainedStates"`
+	LastSnapshot   uint64                `json:"lastSnapshot"`
+}
+
+// TrieCacheStats returns the current statistics of the in-memory state tries.
+func (bc *BlockChain) TrieCacheStats() TrieCacheStats {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return TrieCacheStats{
+		CacheGens:      state.MaxTrieCacheGen,
+		PastTries:      state.MaxPastTries,
+		CommitInterval: atomic.LoadUint64(&bc.commitInterval),
+		Buffer:         bc.stateBuffer.Stats(),
+		Readers:        bc.stateReaders.Len(),
+		Pruning:        bc.pruning.Enabled,
+		RetainedStates: len(bc.retained),
+		LastSnapshot:   bc.lastSnapshot,
+	}
+}
+
+// flushState writes the buffered state tries to disk.
+func (bc *BlockChain) flushState() error {
+	stats := bc.stateBuffer.Stats()
+	if stats.Nodes == 0 {
+		return nil
+	}
+	start := time.Now()
+	if err := bc.stateBuffer.Flush(); err != nil {
+		return err
+	}
+	log.Debug("Flushed buffered state", "nodes", stats.Nodes, "size", stats.Size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
 // SetProcessor sets the processor required for making state modifications.
 func (bc *BlockChain) SetProcessor(processor Processor) {
 	bc.procmu.Lock()
@@ -355,6 +456,13 @@ func (bc *BlockChain) SetProcessor(processor Processor) {
 	bc.processor = processor
 }
 
+// SetPrefetcher sets the prefetcher used to warm caches ahead of block processing.
+func (bc *BlockChain) SetPrefetcher(prefetcher Prefetcher) {
+	bc.procmu.Lock()
+	defer bc.procmu.Unlock()
+	bc.prefetcher = prefetcher
+}
+
 // SetValidator sets the validator which is used to validate incoming blocks.
 func (bc *BlockChain) SetValidator(validator Validator) {
 	bc.procmu.Lock()
@@ -376,6 +484,13 @@ func (bc *BlockChain) Processor() Processor {
 	return bc.processor
 }
 
+// Prefetcher returns the current prefetcher.
+func (bc *BlockChain) Prefetcher() Prefetcher {
+	bc.procmu.RLock()
+	defer bc.procmu.RUnlock()
+	return bc.prefetcher
+}
+
 // State returns a new mutable state based on the current HEAD block.
 func (bc *BlockChain) State() (*state.StateDB, error) {
 	return bc.StateAt(bc.CurrentBlock().Root())
@@ -386,6 +501,30 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache)
 }
 
+// TrieDB returns the database holding the state trie nodes and contract codes,
+// including those of recent states not yet flushed from the write buffer. Any
+// raw access to the state must go through it instead of the chain database.
+func (bc *BlockChain) TrieDB() kokdb.Database {
+	return bc.stateBuffer
+}
+
+// StateReader returns a new read-only state based on a particular point in time,
+// which may be accessed concurrently with block import. The state root is kept
+// referenced until the returned release function is called, retaining its tries
+// when the state is pruned.
+func (bc *BlockChain) StateReader(root common.Hash) (*state.StateDB, func(), error) {
+	// Reference the root under the chain lock, so it can't be pruned in between
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.stateReaders.Open(root, bc.stateCache)
+}
+
+// StateInUse reports whether the given state root is referenced by a reader.
+func (bc *BlockChain) StateInUse(root common.Hash) bool {
+	return bc.stateReaders.InUse(root)
+}
+
 // Reset purges the entire blockchain, restoring it to its genesis state.
 func (bc *BlockChain) Reset() error {
 	return bc.ResetWithGenesisBlock(bc.genesisBlock)
@@ -457,26 +596,57 @@ func (bc *BlockChain) insert(block *types.Block) {
 	// If the block is on a side chain or an unknown one, force other heads onto it too
 	updateHeads := GetCanonicalHash(bc.chainDb, block.NumberU64()) != block.Hash()
 
+	bc.writeHead(bc.chainDb, block, updateHeads)
+	bc.setHead(block, updateHeads)
+}
+
+// writeHead writes the canonical and head markers of the block into the given
+// database writer, including the head header and fast block markers if the other
+// heads are to be moved onto the block too.
+func (bc *BlockChain) writeHead(db kokdb.Putter, block *types.Block, updateHeads bool) {
 	// Add the block to the canonical chain number scheme and mark as the head
-	if err := WriteCanonicalHash(bc.chainDb, block.Hash(), block.NumberU64()); err != nil {
+	if err := WriteCanonicalHash(db, block.Hash(), block.NumberU64()); err != nil {
 		log.Crit("Failed to insert block number", "err", err)
 	}
-	if err := WriteHeadBlockHash(bc.chainDb, block.Hash()); err != nil {
+	if err := WriteHeadBlockHash(db, block.Hash()); err != nil {
 		log.Crit("Failed to insert head block hash", "err", err)
 	}
-	bc.currentBlock = block
-
-	// If the block is better than out head or is on a different chain, force update heads
 	if updateHeads {
-		bc.hc.SetCurrentHeader(block.Header())
-
-		if err := WriteHeadFastBlockHash(bc.chainDb, block.Hash()); err != nil {
+		if err := WriteHeadHeaderHash(db, block.Hash()); err != nil {
+			log.Crit("Failed to insert head header hash", "err", err)
+		}
+		if err := WriteHeadFastBlockHash(db, block.Hash()); err != nil {
 			log.Crit("Failed to insert head fast block hash", "err", err)
 		}
+	}
+}
+
+// setHead makes the block the current head of the chain, along with the head
+// header and fast block if requested. The head markers must have been persisted
+// already, so that readers never observe a head whose data is missing on disk.
+func (bc *BlockChain) setHead(block *types.Block, updateHeads bool) {
+	bc.currentBlock = block
+	if updateHeads {
+		bc.hc.setCurrentHeader(block.Header())
 		bc.currentFastBlock = block
 	}
 }
 
+// flushChainBuffer writes the buffered chain data to disk and, if the buffer holds
+// the markers of a new head, publishes that head now that its data is readable.
+//
+// Note, this function assumes that the `mu` mutex is held!
+func (bc *BlockChain) flushChainBuffer(buffer *chainBuffer) error {
+	head, updateHeads := buffer.head, buffer.updateHeads
+	if err := buffer.flush(); err != nil {
+		return err
+	}
+	if head != nil {
+		bc.setHead(head, updateHeads)
+	}
+	return nil
+}
+
 // Genesis retrieves the chain's genesis block.
 func (bc *BlockChain) Genesis() *types.Block {
 	return bc.genesisBlock
@@ -607,6 +777,15 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+
+	// Persist any state still held in memory so a restart doesn't need to rewind
+	if bc.pruning.Enabled {
+		if err := bc.stateBuffer.Prune(bc.currentBlock.Root(), nil); err != nil {
+			log.Error("Failed to persist head state", "err", err)
+		}
+	} else if err := bc.flushState(); err != nil {
+		log.Error("Failed to flush buffered state", "err", err)
+	}
 	log.Info("Blockchain manager stopped")
 }
 
@@ -785,6 +964,15 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 
 // WriteBlock writes the block to the chain.
 func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.Receipt, state *state.StateDB) (status WriteStatus, err error) {
+	return bc.writeBlockWithState(block, receipts, state, nil)
+}
+
+// writeBlockWithState writes the block and all associated state to the database.
+// If a chain buffer is given, the chain data is accumulated in it instead of being
+// written out directly, and made available through the in-memory caches until
+// flushed. A new head is only published once the buffer is flushed, which happens
+// when it grows too large or a reorg is needed.
+func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.Receipt, state *state.StateDB, buffer *chainBuffer) (status WriteStatus, err error) {
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 
@@ -797,25 +985,81 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
-	localTd := bc.GetTd(bc.currentBlock.Hash(), bc.currentBlock.NumberU64())
+	// Blocks written into the buffer extend its pending head, not the published one
+	head := bc.currentBlock
+	if buffer != nil && buffer.head != nil {
+		head = buffer.head
+	}
+	localTd := bc.GetTd(head.Hash(), head.NumberU64())
 	externTd := new(big.Int).Add(block.Difficulty(), ptd)
 
-	// Irrelevant of the canonical status, write the block itself to the database
-	if err := bc.hc.WriteTd(block.Hash(), block.NumberU64(), externTd); err != nil {
+	// Irrelevant of the canonical status, write the block itself to the database.
+	// Tries are written using a dedicated batch, as they need to be readable by
+	// the next block, whilst the chain data may be buffered across blocks.
+	batch := bc.chainDb.NewBatch()
+
+	var chainWriter kokdb.Putter = batch
+	if buffer != nil {
+		chainWriter = buffer
+	}
+	if err := WriteTd(chainWriter, block.Hash(), block.NumberU64(), externTd); err != nil {
 		return NonStatTy, err
 	}
-	// Write other block data using a batch.
-	batch := bc.chainDb.NewBatch()
-	if err := WriteBlock(batch, block); err != nil {
+	if err := WriteBlock(chainWriter, block); err != nil {
 		return NonStatTy, err
 	}
+	if buffer != nil {
+		bc.hc.headerCache.Add(block.Hash(), block.Header())
+		bc.hc.numberCache.Add(block.Hash(), block.NumberU64())
+		bc.blockCache.Add(block.Hash(), block)
+		buffer.blocks++
+	}
+	bc.hc.tdCache.Add(block.Hash(), new(big.Int).Set(externTd))
+
 	if _, err := block.DposContext.CommitTo(batch); err != nil {
 		return NonStatTy, err
 	}
-	if _, err := state.CommitTo(batch, bc.config.IsEIP158(block.Number())); err != nil {
+	// Commit the state into the block batch, or the state buffer if commits are
+	// only flushed to disk periodically
+	interval := atomic.LoadUint64(&bc.commitInterval)
+
+	var stateWriter trie.DatabaseWriter = batch
+	if interval > 1 || bc.pruning.Enabled {
+		stateWriter = bc.stateBuffer
+	}
+	root, err := state.CommitTo(stateWriter, bc.config.IsEIP158(block.Number()))
+	if err != nil {
 		return NonStatTy, err
 	}
-	if err := WriteBlockReceipts(batch, block.Hash(), block.NumberU64(), receipts); err != nil {
+	if err := WriteBlockReceipts(chainWriter, block.Hash(), block.NumberU64(), receipts); err != nil {
+		return NonStatTy, err
+	}
+	if bc.vmConfig.EnableBalanceRecording {
+		if changes := state.BalanceChanges(); changes != nil {
+			if err := WriteBlockBalanceChanges(chainWriter, block.Hash(), block.NumberU64(), changes); err != nil {
+				return NonStatTy, err
+			}
+		}
+	}
+	if bc.vmConfig.EnableInternalTxRecording {
+		internals := make([][]*types.InternalTx, len(block.Transactions()))
+		for i, tx := range block.Transactions() {
+			internals[i] = state.InternalTxs(tx.Hash())
+		}
+		if err := WriteBlockInternalTxs(chainWriter, block.Hash(), block.NumberU64(), internals); err != nil {
+			return NonStatTy, err
+		}
+	}
+	if bc.vmConfig.EnableRevertRecording {
+		if err := WriteBlockRevertReasons(chainWriter, block.Hash(), block.NumberU64(), receipts); err != nil {
+			return NonStatTy, err
+		}
+	}
+	var creations []*types.ContractCreation
+	for _, tx := range block.Transactions() {
+		creations = append(creations, state.ContractCreations(tx.Hash())...)
+	}
+	if err := WriteContractCreations(chainWriter, creations); err != nil {
 		return NonStatTy, err
 	}
 
@@ -825,17 +1069,23 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	reorg := externTd.Cmp(localTd) > 0
 	if !reorg && externTd.Cmp(localTd) == 0 {
 		// Split same-difficulty blocks by number, then at random
-		reorg = block.NumberU64() < bc.currentBlock.NumberU64() || (block.NumberU64() == bc.currentBlock.NumberU64() && mrand.Float64() < 0.5)
+		reorg = block.NumberU64() < head.NumberU64() || (block.NumberU64() == head.NumberU64() && mrand.Float64() < 0.5)
 	}
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
-		if block.ParentHash() != bc.currentBlock.Hash() {
+		if block.ParentHash() != head.Hash() {
+			// The reorg reads back the new chain from the database, flush it first
+			if buffer != nil {
+				if err := bc.flushChainBuffer(buffer); err != nil {
+					return NonStatTy, err
+				}
+			}
 			if err := bc.reorg(bc.currentBlock, block); err != nil {
 				return NonStatTy, err
 			}
 		}
 		// Write the positional metadata for transaction and receipt lookups
-		if err := WriteTxLookupEntries(batch, block); err != nil {
+		if err := WriteTxLookupEntries(chainWriter, block); err != nil {
 			return NonStatTy, err
 		}
 		// Write hash preimages
@@ -849,10 +1099,30 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	if err := batch.Write(); err != nil {
 		return NonStatTy, err
 	}
+	if bc.pruning.Enabled {
+		if err := bc.pruneState(block.NumberU64(), root); err != nil {
+			return NonStatTy, err
+		}
+	} else if block.NumberU64()%interval == 0 || bc.stateBuffer.Size() > maxBufferedState {
+		if err := bc.flushState(); err != nil {
+			return NonStatTy, err
+		}
+	}
 
-	// Set new head.
+	// Set new head, deferring its publication until its buffered data is flushed
 	if status == CanonStatTy {
-		bc.insert(block)
+		if buffer != nil {
+			updateHeads := buffer.updateHeads || buffer.canonicalHash(block.NumberU64()) != block.Hash()
+			bc.writeHead(buffer, block, updateHeads)
+			buffer.setHead(block, updateHeads)
+		} else {
+			bc.insert(block)
+		}
+	}
+	if buffer != nil && buffer.full() {
+		if err := bc.flushChainBuffer(buffer); err != nil {
+			return NonStatTy, err
+		}
 	}
 	bc.futureBlocks.Remove(block.Hash())
 	return status, nil
@@ -912,6 +1182,19 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 	abort, results := bc.engine.VerifyHeaders(bc, headers, seals)
 	defer close(abort)
 
+	// Buffer the chain data of the imported blocks, flushing whatever's left and
+	// publishing the new head on return
+	buffer := newChainBuffer(bc.chainDb)
+	flush := func() {
+		bc.mu.Lock()
+		defer bc.mu.Unlock()
+
+		if err := bc.flushChainBuffer(buffer); err != nil {
+			log.Crit("Failed to flush buffered chain data", "err", err)
+		}
+	}
+	defer flush()
+
 	// Iterate over the blocks and insert when the verifier permits
 	for i, block := range chain {
 		// If the chain is terminating, stop processing blocks
@@ -971,12 +1254,25 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
+		// If there is a followup block, warm the database caches for it while the
+		// current one is being processed. Its pre-state isn't available yet, so the
+		// parent state is used as an approximation.
+		var followupInterrupt uint32
+		if prefetcher := bc.Prefetcher(); prefetcher != nil && i+1 < len(chain) {
+			if throwaway, err := state.New(parent.Root(), bc.stateCache); err == nil {
+				go prefetcher.Prefetch(chain[i+1], throwaway, &followupInterrupt)
+			}
+		}
 		state, err := state.New(parent.Root(), bc.stateCache)
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
+		if bc.vmConfig.EnableBalanceRecording {
+			state.EnableBalanceTracking()
+		}
 		// Process block using the parent state as reference point.
 		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
+		atomic.StoreUint32(&followupInterrupt, 1)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			return i, events, coalescedLogs, err
@@ -1006,7 +1302,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 
 		// Validate the dpos state using the default validator
 		// Write the block to the chain and get the status.
-		status, err := bc.WriteBlockAndState(block, receipts, state)
+		status, err := bc.writeBlockWithState(block, receipts, state, buffer)
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
@@ -1030,6 +1326,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		stats.usedGas += usedGas.Uint64()
 		stats.report(chain, i)
 	}
+	// Publish the new head before checking whether it's to be announced
+	flush()
+
 	// Append a single chain head event if we've progressed the chain
 	if lastCanon != nil && bc.LastBlockHash() == lastCanon.Hash() {
 		events = append(events, ChainHeadEvent{lastCanon})
@@ -1154,6 +1453,12 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 			return fmt.Errorf("Invalid new chain")
 		}
 	}
+	// Refuse reorgs past the retained states, which pruning may have discarded
+	if bc.pruning.Enabled && uint64(len(oldChain)) > bc.pruning.Retain {
+		log.Warn("Refusing reorg past retained states", "number", commonBlock.Number(), "hash", commonBlock.Hash(),
+			"drop", len(oldChain), "add", len(newChain), "retain", bc.pruning.Retain)
+		return ErrReorgTooDeep
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Debug
@@ -1187,7 +1492,16 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	if len(deletedLogs) > 0 {
 		go bc.rmLogsFeed.Send(RemovedLogsEvent{deletedLogs})
 	}
-
+	if len(oldChain) > 0 && len(newChain) > 0 {
+		go bc.reorgFeed.Send(ReorgEvent{
+			OldHead:    oldChain[0],
+			NewHead:    newChain[0],
+			Common:     commonBlock,
+			Dropped:    len(oldChain),
+			Added:      len(newChain),
+			DroppedTxs: diff,
+		})
+	}
 	return nil
 }
 
@@ -1245,6 +1559,50 @@ func (bc *BlockChain) addBadBlock(block *types.Block) {
 	bc.badBlocks.Add(block.Header().Hash(), block.Header())
 }
 
+// ValidateBlock runs the full import validation of a block against its parent
+// state without writing anything to the database. It is meant for locally
+// sealed blocks, so that a misconfigured node refuses to publish a block its
+// peers would reject.
+func (bc *BlockChain) ValidateBlock(block *types.Block) error {
+	if err := bc.engine.VerifyHeader(bc, block.Header(), true); err != nil && err != consensus.ErrFutureBlock {
+		return err
+	}
+	if err := bc.Validator().ValidateBody(block); err != nil && err != ErrKnownBlock {
+		return err
+	}
+	parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	// Process a copy of the block, so that the dpos context of the sealed one
+	// (which is committed when it is written) is left untouched.
+	dposContext, err := types.NewDposContextFromProto(bc.chainDb, parent.Header().DposContext)
+	if err != nil {
+		return err
+	}
+	cpy := types.NewBlockWithHeader(block.Header()).WithBody(block.Transactions(), block.Uncles())
+	cpy.DposContext = dposContext
+
+	statedb, err := state.New(parent.Root(), bc.stateCache)
+	if err != nil {
+		return err
+	}
+	receipts, _, usedGas, err := bc.processor.Process(cpy, statedb, bc.vmConfig)
+	if err != nil {
+		return err
+	}
+	if err := bc.Validator().ValidateState(cpy, parent, statedb, receipts, usedGas); err != nil {
+		return err
+	}
+	if err := bc.Validator().ValidateDposState(cpy); err != nil {
+		return err
+	}
+	if dposEngine, isDpos := bc.engine.(*dpos.Dpos); isDpos {
+		return dposEngine.VerifySeal(bc, block.Header())
+	}
+	return nil
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	bc.addBadBlock(block)
@@ -1372,6 +1730,9 @@ func (bc *BlockChain) GkokeaderByNumber(number uint64) *types.Header {
 // Config retrieves the blockchain's chain configuration.
 func (bc *BlockChain) Config() *params.ChainConfig { return bc.config }
 
+// GetVMConfig returns the block chain VM config.
+func (bc *BlockChain) GetVMConfig() *vm.Config { return &bc.vmConfig }
+
 // Engine retrieves the blockchain's consensus engine.
 func (bc *BlockChain) Engine() consensus.Engine { return bc.engine }
 
@@ -1380,6 +1741,11 @@ func (bc *BlockChain) SubscribeRemovedLogsEvent(ch chan<- RemovedLogsEvent) even
 	return bc.scope.Track(bc.rmLogsFeed.Subscribe(ch))
 }
 
+// SubscribeReorgEvent registers a subscription of ReorgEvent.
+func (bc *BlockChain) SubscribeReorgEvent(ch chan<- ReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
+
 // SubscribeChainEvent registers a subscription of ChainEvent.
 func (bc *BlockChain) SubscribeChainEvent(ch chan<- ChainEvent) event.Subscription {
 	return bc.scope.Track(bc.chainFeed.Subscribe(ch))
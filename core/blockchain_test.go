@@ -17,6 +17,8 @@
 package core
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -25,6 +27,7 @@ import (
 	"time"
 
 	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/consensus/dpos"
 	"github.com/kokprojects/go-kok/consensus/kokash"
 	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
@@ -32,6 +35,7 @@ import (
 	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/rpc"
 )
 
 // newTestBlockChain creates a blockchain without validation.
@@ -1116,3 +1120,262 @@ func TestEIP161AccountRemoval(t *testing.T) {
 		t.Error("account should not exist")
 	}
 }
+
+// Tests that with pruning enabled only the recent states and the periodic snapshots
+// are retained, and that the head state is persisted on shutdown.
+func TestStatePruning(t *testing.T) {
+	blockchain := newTestBlockChain(true)
+	blockchain.SetPruning(PruningConfig{Enabled: true, Retain: 4, Interval: 8})
+
+	// Generate the chain on a separate database, as the chain maker commits all states
+	db, _ := kokdb.NewMemDatabase()
+	genesis := (&Genesis{Config: params.TestChainConfig, Difficulty: big.NewInt(1)}).MustCommit(db)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, db, 40, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{byte(i)})
+	})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, number := range []int{8, 16, 24, 32, 37, 38, 39, 40} {
+		if _, err := blockchain.StateAt(blocks[number-1].Root()); err != nil {
+			t.Errorf("state %d inaccessible: %v", number, err)
+		}
+	}
+	for _, number := range []int{7, 20, 25} {
+		if _, err := blockchain.StateAt(blocks[number-1].Root()); err == nil {
+			t.Errorf("pruned state %d accessible", number)
+		}
+	}
+	blockchain.Stop()
+
+	if _, err := state.New(blocks[len(blocks)-1].Root(), state.NewDatabase(blockchain.chainDb)); err != nil {
+		t.Errorf("head state not persisted: %v", err)
+	}
+}
+
+// Tests that the dpos APIs of a pruning node can access the state of the recent
+// blocks, which is only kept in memory.
+func TestStatePruningDposAPI(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 2)
+		addrs = make([]common.Address, 2)
+		db, _ = kokdb.NewMemDatabase()
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	config := *params.DposChainConfig
+	config.Dpos = &params.DposConfig{Validators: addrs}
+
+	gspec := &Genesis{
+		Config: &config,
+		Alloc:  GenesisAlloc{addrs[0]: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	// Generate the chain on a separate database, as the chain maker commits all states
+	gendb, _ := kokdb.NewMemDatabase()
+	signer := types.NewEIP155Signer(config.ChainId)
+	chain, _ := GenerateDposChain(&config, gspec.MustCommit(gendb), dpos.New(config.Dpos, gendb), gendb, keys, 3, func(i int, gen *BlockGen) {
+		if i == 0 {
+			tx, _ := types.SignTx(types.NewTransaction(types.Delegate, gen.TxNonce(addrs[0]), addrs[1], new(big.Int), bigTxGas, nil, nil), signer, keys[0])
+			gen.AddTx(tx)
+		}
+	})
+	gspec.MustCommit(db)
+	engine := dpos.New(config.Dpos, db)
+
+	blockchain, err := NewBlockChain(db, &config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+	blockchain.SetPruning(PruningConfig{Enabled: true, Retain: 4, Interval: 8})
+
+	if i, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("insert error (block %d): %v", chain[i].NumberU64(), err)
+	}
+	if _, err := state.New(blockchain.CurrentBlock().Root(), state.NewDatabase(db)); err == nil {
+		t.Fatalf("head state persisted, pruning inactive")
+	}
+	api := engine.APIs(blockchain)[0].Service.(*dpos.API)
+	number := rpc.LatestBlockNumber
+
+	votes, err := api.GetVotes(context.Background(), addrs[0], &number)
+	if err != nil {
+		t.Fatalf("failed to retrieve votes: %v", err)
+	}
+	if votes.Vote == nil || votes.Vote.Candidate != addrs[1] || votes.Vote.Weight.ToInt().Sign() == 0 {
+		t.Errorf("vote mismatch: have %+v", votes.Vote)
+	}
+}
+
+// Tests that a sealed block is dry-run validated against its parent state, and
+// that a block with a tampered state root is rejected even if properly signed.
+func TestValidateSealedBlock(t *testing.T) {
+	var (
+		keys    = make([]*ecdsa.PrivateKey, 3)
+		signers = make(map[common.Address]*ecdsa.PrivateKey)
+		db, _   = kokdb.NewMemDatabase()
+	)
+	config := *params.DposChainConfig
+	config.Dpos = &params.DposConfig{}
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(keys[i].PublicKey)
+		config.Dpos.Validators = append(config.Dpos.Validators, addr)
+		signers[addr] = keys[i]
+	}
+	genesis := (&Genesis{Config: &config}).MustCommit(db)
+	chain, _ := GenerateDposChain(&config, genesis, dpos.New(config.Dpos, db), db, keys, 2, nil)
+
+	blockchain, err := NewBlockChain(db, &config, dpos.New(config.Dpos, db), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(chain[:1]); err != nil {
+		t.Fatalf("failed to insert parent block: %v", err)
+	}
+	if err := blockchain.ValidateBlock(chain[1]); err != nil {
+		t.Fatalf("valid block rejected: %v", err)
+	}
+	if blockchain.CurrentBlock().Hash() != chain[0].Hash() {
+		t.Fatalf("validation modified the chain head")
+	}
+	// Corrupt the state root and re-sign the block with the validator's key
+	header := chain[1].Header()
+	header.Root = common.Hash{0x01}
+	sig, err := crypto.Sign(dpos.SealHash(header).Bytes(), signers[header.Validator])
+	if err != nil {
+		t.Fatalf("failed to sign block: %v", err)
+	}
+	copy(header.Extra[len(header.Extra)-len(sig):], sig)
+	if err := blockchain.ValidateBlock(chain[1].WithSeal(header)); err == nil {
+		t.Fatalf("block with invalid state root accepted")
+	}
+	if _, err := blockchain.InsertChain(chain[1:]); err != nil {
+		t.Fatalf("valid block not importable after dry-run: %v", err)
+	}
+}
+
+// Tests that an active treasury receives its share of the block producer's fees,
+// and that the split is recorded in the stored receipts.
+func TestTreasuryFees(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		coinbase = common.Address{0xc0}
+		treasury = common.Address{0x7e}
+		db, _    = kokdb.NewMemDatabase()
+	)
+	config := *params.TestChainConfig
+	config.Treasury = &params.TreasuryConfig{Block: big.NewInt(2), Address: treasury, Percent: 20}
+
+	gspec := &Genesis{Config: &config, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(1000000000000000000)}}}
+	genesis := gspec.MustCommit(db)
+	signer := types.NewEIP155Signer(config.ChainId)
+
+	// Send a transfer before and after the treasury activation
+	chain, _ := GenerateChain(&config, genesis, db, 2, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(coinbase)
+		tx, _ := types.SignTx(types.NewTransaction(types.Binary, gen.TxNonce(addr), common.Address{0x01}, big.NewInt(1000), bigTxGas, big.NewInt(10), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	blockchain, _ := NewBlockChain(db, &config, kokash.NewFaker(), vm.Config{EnableAudit: true})
+	defer blockchain.Stop()
+
+	if i, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("insert error (block %d): %v", chain[i].NumberU64(), err)
+	}
+	if receipt := GetBlockReceipts(db, chain[0].Hash(), 1)[0]; receipt.GasTreasury != nil {
+		t.Errorf("treasury fees recorded before activation: %v", receipt.GasTreasury)
+	}
+	receipt := GetBlockReceipts(db, chain[1].Hash(), 2)[0]
+	if want := new(big.Int).Div(new(big.Int).Mul(receipt.GasUsed, big.NewInt(20)), big.NewInt(100)); receipt.GasTreasury == nil || receipt.GasTreasury.Cmp(want) != 0 {
+		t.Fatalf("treasury gas mismatch: have %v, want %v", receipt.GasTreasury, want)
+	}
+	if split := new(big.Int).Add(receipt.GasMiner, receipt.GasTreasury); split.Cmp(receipt.GasUsed) != 0 {
+		t.Errorf("fee split mismatch: %v + %v != %v", receipt.GasMiner, receipt.GasTreasury, receipt.GasUsed)
+	}
+	statedb, _ := blockchain.State()
+	if have, want := statedb.GetBalance(treasury), new(big.Int).Mul(receipt.GasTreasury, big.NewInt(10)); have.Cmp(want) != 0 {
+		t.Errorf("treasury balance mismatch: have %v, want %v", have, want)
+	}
+}
+
+// Tests that a state referenced by a reader survives pruning until released.
+func TestStatePruningReaders(t *testing.T) {
+	blockchain := newTestBlockChain(true)
+	defer blockchain.Stop()
+	blockchain.SetPruning(PruningConfig{Enabled: true, Retain: 4, Interval: 8})
+
+	db, _ := kokdb.NewMemDatabase()
+	genesis := (&Genesis{Config: params.TestChainConfig, Difficulty: big.NewInt(1)}).MustCommit(db)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, db, 48, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{byte(i)})
+	})
+	if _, err := blockchain.InsertChain(blocks[:2]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	root := blocks[1].Root()
+	reader, release, err := blockchain.StateReader(root)
+	if err != nil {
+		t.Fatalf("failed to open state reader: %v", err)
+	}
+	// Snapshot several times while the reader is active
+	if _, err := blockchain.InsertChain(blocks[2:40]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := blockchain.StateAt(root); err != nil {
+		t.Fatalf("referenced state pruned: %v", err)
+	}
+	if balance := reader.GetBalance(common.Address{1}); balance.Sign() == 0 {
+		t.Fatalf("referenced state unreadable")
+	}
+	// Release the reader and ensure the next snapshot prunes the state
+	release()
+	if _, err := blockchain.InsertChain(blocks[40:]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := blockchain.StateAt(root); err == nil {
+		t.Fatalf("released state not pruned")
+	}
+}
+
+// Tests that with pruning enabled, reorgs within the retained states are done,
+// but deeper ones are refused.
+func TestStatePruningReorgDepth(t *testing.T) {
+	blockchain := newTestBlockChain(true)
+	defer blockchain.Stop()
+	blockchain.SetPruning(PruningConfig{Enabled: true, Retain: 4, Interval: 8})
+
+	db, _ := kokdb.NewMemDatabase()
+	genesis := (&Genesis{Config: params.TestChainConfig, Difficulty: big.NewInt(1)}).MustCommit(db)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, db, 10, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{byte(i)})
+	})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Reorg the last two blocks, within the retained states
+	shallow, _ := GenerateChain(params.TestChainConfig, blocks[7], db, 3, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{0xaa, byte(i)})
+	})
+	if _, err := blockchain.InsertChain(shallow); err != nil {
+		t.Fatalf("failed to reorg within retained states: %v", err)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != shallow[2].Hash() {
+		t.Fatalf("head mismatch after shallow reorg: have %x, want %x", head, shallow[2].Hash())
+	}
+	// Try to reorg all the way to the genesis block, past the retained states
+	deep, _ := GenerateChain(params.TestChainConfig, genesis, db, 12, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{0xbb, byte(i)})
+	})
+	if _, err := blockchain.InsertChain(deep); err != ErrReorgTooDeep {
+		t.Fatalf("deep reorg error mismatch: have %v, want %v", err, ErrReorgTooDeep)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != shallow[2].Hash() {
+		t.Fatalf("head changed by refused reorg: have %x, want %x", head, shallow[2].Hash())
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/log"
+)
+
+// chainBufferLimit is the size of buffered chain data above which the import
+// write buffer is flushed to disk, even if the import is still in progress.
+const chainBufferLimit = 4 * kokdb.IdealBatchSize
+
+// chainBuffer is a write-ahead buffer coalescing the chain data written during
+// the import of a batch of blocks (headers, bodies, receipts, total difficulties,
+// lookup entries and head markers) into a few large database batches instead of
+// one small batch per block.
+//
+// The buffered data is not readable from the database until flushed, so the
+// importer must make sure it is reachable through the in-memory caches. Since a
+// block's data is always buffered ahead of the head markers pointing to it, any
+// flushed batch leaves the database in a consistent state. For the same reason
+// the head of the chain is only advanced once its markers are flushed.
+type chainBuffer struct {
+	db     kokdb.Database
+	batch  kokdb.Batch
+	blocks int // Number of blocks with data in the current batch
+
+	canon       map[uint64]common.Hash // Canonical hashes written into the current batch
+	head        *types.Block           // Head block whose markers are in the current batch
+	updateHeads bool                   // Whether the header and fast block heads move onto the head
+}
+
+// newChainBuffer creates an empty import write buffer on top of a database.
+func newChainBuffer(db kokdb.Database) *chainBuffer {
+	return &chainBuffer{
+		db:    db,
+		batch: db.NewBatch(),
+		canon: make(map[uint64]common.Hash),
+	}
+}
+
+// Put buffers a key-value pair until the next flush.
+func (b *chainBuffer) Put(key []byte, value []byte) error {
+	return b.batch.Put(key, value)
+}
+
+// canonicalHash retrieves the canonical hash of the given block number, taking
+// into account the markers written into the buffer but not yet flushed.
+func (b *chainBuffer) canonicalHash(number uint64) common.Hash {
+	if hash, ok := b.canon[number]; ok {
+		return hash
+	}
+	return GetCanonicalHash(b.db, number)
+}
+
+// setHead records the block whose head markers were written into the buffer, to
+// be published as the head of the chain once flushed.
+func (b *chainBuffer) setHead(block *types.Block, updateHeads bool) {
+	b.canon[block.NumberU64()] = block.Hash()
+	b.head = block
+	b.updateHeads = updateHeads
+}
+
+// full reports whether the buffered data grew above the flush threshold.
+func (b *chainBuffer) full() bool {
+	return b.batch.ValueSize() >= chainBufferLimit
+}
+
+// flush writes all the buffered data into the database in a single batch.
+func (b *chainBuffer) flush() error {
+	size := b.batch.ValueSize()
+	if size == 0 {
+		return nil
+	}
+	start := time.Now()
+	if err := b.batch.Write(); err != nil {
+		return err
+	}
+	log.Debug("Flushed buffered chain data", "blocks", b.blocks, "size", common.StorageSize(size), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	b.batch = b.db.NewBatch()
+	b.blocks = 0
+	b.canon = make(map[uint64]common.Hash)
+	b.head, b.updateHeads = nil, false
+	return nil
+}
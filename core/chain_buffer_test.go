@@ -0,0 +1,137 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/consensus/kokash"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+)
+
+// headChecker is a validator verifying, before each block is imported, that the
+// published head of the chain is fully readable from the database.
+type headChecker struct {
+	Validator
+	chain *BlockChain
+	heads []uint64
+	errs  []error
+}
+
+func (c *headChecker) ValidateBody(block *types.Block) error {
+	head := c.chain.CurrentBlock()
+	if err := checkHeadPersisted(c.chain.chainDb, head); err != nil {
+		c.errs = append(c.errs, err)
+	}
+	c.heads = append(c.heads, head.NumberU64())
+	return c.Validator.ValidateBody(block)
+}
+
+// checkHeadPersisted checks that the number to hash mapping, the receipts and
+// the transaction lookups of a head block are present in the database.
+func checkHeadPersisted(db kokdb.Database, head *types.Block) error {
+	if hash := GetCanonicalHash(db, head.NumberU64()); hash != head.Hash() {
+		return fmt.Errorf("head #%d canonical hash mismatch: have %x, want %x", head.NumberU64(), hash, head.Hash())
+	}
+	if receipts := GetBlockReceipts(db, head.Hash(), head.NumberU64()); len(receipts) != len(head.Transactions()) {
+		return fmt.Errorf("head #%d receipts missing: have %d, want %d", head.NumberU64(), len(receipts), len(head.Transactions()))
+	}
+	for _, tx := range head.Transactions() {
+		if hash, _, _ := GetTxLookupEntry(db, tx.Hash()); hash != head.Hash() {
+			return fmt.Errorf("head #%d lookup of tx %x missing", head.NumberU64(), tx.Hash())
+		}
+	}
+	return nil
+}
+
+// Tests that the head of the chain only advances once the buffered chain data
+// of the imported blocks reached the database, and that all head markers are
+// persisted along with it.
+func TestChainBufferHeadPublication(t *testing.T) {
+	var (
+		gendb, _ = kokdb.NewMemDatabase()
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		gspec    = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}}
+		genesis  = gspec.MustCommit(gendb)
+		signer   = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, gendb, 16, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(types.Binary, block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), bigTxGas, nil, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	db, _ := kokdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, err := NewBlockChain(db, gspec.Config, kokash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	checker := &headChecker{Validator: chain.Validator(), chain: chain}
+	chain.SetValidator(checker)
+
+	for _, batch := range [][]*types.Block{blocks[:8], blocks[8:]} {
+		before := chain.CurrentBlock().NumberU64()
+
+		if _, err := chain.InsertChain(batch); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+
+		// The batch is small enough to be buffered whole, so the head must stay
+		// put until the import is done
+		for _, number := range checker.heads {
+			if number != before {
+				t.Errorf("head advanced to #%d during import, want #%d", number, before)
+			}
+		}
+		checker.heads = nil
+
+		head := batch[len(batch)-1]
+		if err := checkHeadPersisted(db, chain.CurrentBlock()); err != nil {
+			t.Fatal(err)
+		}
+		if chain.CurrentBlock().Hash() != head.Hash() || chain.CurrentHeader().Hash() != head.Hash() || chain.CurrentFastBlock().Hash() != head.Hash() {
+			t.Fatalf("heads mismatch: block #%d, header #%d, fast block #%d, want #%d",
+				chain.CurrentBlock().NumberU64(), chain.CurrentHeader().Number, chain.CurrentFastBlock().NumberU64(), head.NumberU64())
+		}
+		if hash := GkokeadBlockHash(db); hash != head.Hash() {
+			t.Errorf("persisted head block mismatch: have %x, want %x", hash, head.Hash())
+		}
+		if hash := GkokeadHeaderHash(db); hash != head.Hash() {
+			t.Errorf("persisted head header mismatch: have %x, want %x", hash, head.Hash())
+		}
+		if hash := GkokeadFastBlockHash(db); hash != head.Hash() {
+			t.Errorf("persisted head fast block mismatch: have %x, want %x", hash, head.Hash())
+		}
+	}
+	for _, err := range checker.errs {
+		t.Error(err)
+	}
+}
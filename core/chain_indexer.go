@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"sync"
@@ -109,6 +110,27 @@ func NewChainIndexer(chainDb, indexDb kokdb.Database, backend ChainIndexerBacken
 	return c
 }
 
+// Rebuild discards all the processed sections, making the indexer (and all its
+// children) process the canonical chain again from the first section.
+func (c *ChainIndexer) Rebuild() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.log.Info("Rebuilding chain index", "sections", c.storedSections)
+	c.setValidSections(0)
+
+	if c.cascadedHead > 0 {
+		c.cascadedHead = 0
+		for _, child := range c.children {
+			child.newHead(0, true)
+		}
+	}
+	select {
+	case c.update <- struct{}{}:
+	default:
+	}
+}
+
 // AddKnownSectionHead marks a new section head as known/processed if it is newer
 // than the already known best section head
 func (c *ChainIndexer) AddKnownSectionHead(section uint64, shead common.Hash) {
@@ -390,12 +412,21 @@ func (c *ChainIndexer) AddChildIndexer(indexer *ChainIndexer) {
 }
 
 // loadValidSections reads the number of valid sections from the index database
-// and caches is into the local state.
+// and caches is into the local state. Sections indexed with a different section
+// size are discarded.
 func (c *ChainIndexer) loadValidSections() {
 	data, _ := c.indexDb.Get([]byte("count"))
 	if len(data) == 8 {
 		c.storedSections = binary.BigEndian.Uint64(data[:])
 	}
+	var size [8]byte
+	binary.BigEndian.PutUint64(size[:], c.sectionSize)
+
+	if data, _ := c.indexDb.Get([]byte("ssize")); len(data) == 8 && !bytes.Equal(data, size[:]) {
+		c.log.Warn("Section size changed, discarding chain index", "old", binary.BigEndian.Uint64(data), "new", c.sectionSize)
+		c.setValidSections(0)
+	}
+	c.indexDb.Put([]byte("ssize"), size[:])
 }
 
 // setValidSections writes the number of valid sections to the index database
@@ -132,6 +132,43 @@ func testChainIndexer(t *testing.T, count int) {
 	}
 }
 
+// Tests that the sections indexed with a different section size are discarded
+// when the indexer is recreated, and that rebuilding discards all sections.
+func TestChainIndexerSectionSize(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	defer db.Close()
+
+	table := kokdb.NewTable(db, string([]byte{0}))
+	create := func(size uint64) *ChainIndexer {
+		backend := &testChainIndexBackend{t: t, processCh: make(chan uint64)}
+		backend.indexer = NewChainIndexer(db, table, backend, size, 0, 0, "indexer")
+		return backend.indexer
+	}
+	indexer := create(10)
+	indexer.AddKnownSectionHead(2, common.Hash{0x01})
+	indexer.Close()
+
+	indexer = create(10)
+	if sections, _, _ := indexer.Sections(); sections != 3 {
+		t.Fatalf("section count mismatch with same size: have %d, want %d", sections, 3)
+	}
+	indexer.Rebuild()
+	if sections, _, _ := indexer.Sections(); sections != 0 {
+		t.Fatalf("section count mismatch after rebuild: have %d, want %d", sections, 0)
+	}
+	indexer.AddKnownSectionHead(2, common.Hash{0x01})
+	indexer.Close()
+
+	indexer = create(20)
+	defer indexer.Close()
+	if sections, _, _ := indexer.Sections(); sections != 0 {
+		t.Fatalf("section count mismatch with changed size: have %d, want %d", sections, 0)
+	}
+	if head := indexer.SectionHead(2); head != (common.Hash{}) {
+		t.Fatalf("stale section head retained: %x", head)
+	}
+}
+
 // testChainIndexBackend implements ChainIndexerBackend
 type testChainIndexBackend struct {
 	t                          *testing.T
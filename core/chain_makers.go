@@ -17,6 +17,7 @@
 package core
 
 import (
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 
@@ -27,6 +28,7 @@ import (
 	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/params"
 )
@@ -51,6 +53,9 @@ type BlockGen struct {
 	receipts []*types.Receipt
 	uncles   []*types.Header
 
+	dposContext *types.DposContext
+	engine      *dpos.Dpos // DPoS engine sealing the block, nil if unsealed
+
 	config *params.ChainConfig
 }
 
@@ -84,8 +89,13 @@ func (b *BlockGen) AddTx(tx *types.Transaction) {
 	if b.gasPool == nil {
 		b.SetCoinbase(common.Address{})
 	}
+	// Sealed blocks pay the fees to their validator, as the block processor does
+	author := &b.header.Coinbase
+	if b.engine != nil {
+		author = &b.header.Validator
+	}
 	b.statedb.Prepare(tx.Hash(), common.Hash{}, len(b.txs))
-	receipt, _, err := ApplyTransaction(b.config, nil, nil, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, b.header.GasUsed, vm.Config{})
+	receipt, _, err := ApplyTransaction(b.config, b.dposContext, nil, author, b.gasPool, b.statedb, b.header, tx, b.header.GasUsed, vm.Config{})
 	if err != nil {
 		panic(err)
 	}
@@ -116,6 +126,12 @@ func (b *BlockGen) TxNonce(addr common.Address) uint64 {
 	return b.statedb.GetNonce(addr)
 }
 
+// DposContext returns the dpos context of the block being generated, holding the
+// changes made by its candidate and delegation transactions so far.
+func (b *BlockGen) DposContext() *types.DposContext {
+	return b.dposContext
+}
+
 // AddUncle adds an uncle header to the generated block.
 func (b *BlockGen) AddUncle(h *types.Header) {
 	b.uncles = append(b.uncles, h)
@@ -137,14 +153,36 @@ func (b *BlockGen) PrevBlock(index int) *types.Block {
 // OffsetTime modifies the time instance of a block, implicitly changing its
 // associated difficulty. It's useful to test scenarios where forking is not
 // tied to chain length directly.
+//
+// For sealed blocks it also changes the validator of the block, so it must be
+// called before adding transactions. Offsetting the time past an epoch boundary
+// makes the block elect the validators of the new epoch.
 func (b *BlockGen) OffsetTime(seconds int64) {
+	if b.engine != nil && len(b.txs) > 0 {
+		panic("time must be offset before adding transactions")
+	}
 	b.header.Time.Add(b.header.Time, new(big.Int).SetInt64(seconds))
 	if b.header.Time.Cmp(b.parent.Header().Time) <= 0 {
 		panic("block time out of range")
 	}
+	if b.engine != nil {
+		b.header.Difficulty = b.engine.CalcDifficulty(nil, b.header.Time.Uint64(), b.parent.Header())
+		b.setValidator()
+		return
+	}
 	b.header.Difficulty = kokash.CalcDifficulty(b.config, b.header.Time.Uint64(), b.parent.Header())
 }
 
+// setValidator sets the validator of the block to the one entitled to mint it
+// at its current time. The dpos context must not yet be modified by the block.
+func (b *BlockGen) setValidator() {
+	validator, err := dpos.LookupValidator(b.dposContext, b.header.Time.Int64())
+	if err != nil {
+		panic(fmt.Sprintf("validator lookup error: %v", err))
+	}
+	b.header.Validator = validator
+}
+
 // GenerateChain creates a chain of n blocks. The first block's
 // parent will be the provided parent. db is used to store
 // intermediate states and should contain the parent's state trie.
@@ -158,12 +196,39 @@ func (b *BlockGen) OffsetTime(seconds int64) {
 // values. Inserting them into BlockChain requires use of FakePow or
 // a similar non-validating proof of work implementation.
 func GenerateChain(config *params.ChainConfig, parent *types.Block, db kokdb.Database, n int, gen func(int, *BlockGen)) ([]*types.Block, []types.Receipts) {
+	return generateChain(config, parent, nil, nil, db, n, gen)
+}
+
+// GenerateDposChain creates a chain of n blocks sealed by DPoS validators. It
+// works like GenerateChain, but the blocks are finalised by the engine and are
+// thus importable into a BlockChain running it.
+//
+// The validator of each block is looked up from the dpos context of its parent
+// and signs the block with its key, which must be among keys. Transactions of
+// any type may be added, with candidate and delegation changes being recorded
+// in the dpos context of the block. Blocks offset past an epoch boundary (see
+// BlockGen.OffsetTime) elect the validators of the new epoch.
+func GenerateDposChain(config *params.ChainConfig, parent *types.Block, engine *dpos.Dpos, db kokdb.Database, keys []*ecdsa.PrivateKey, n int, gen func(int, *BlockGen)) ([]*types.Block, []types.Receipts) {
+	signers := make(map[common.Address]*ecdsa.PrivateKey)
+	for _, key := range keys {
+		signers[crypto.PubkeyToAddress(key.PublicKey)] = key
+	}
+	return generateChain(config, parent, engine, signers, db, n, gen)
+}
+
+func generateChain(config *params.ChainConfig, parent *types.Block, engine *dpos.Dpos, signers map[common.Address]*ecdsa.PrivateKey, db kokdb.Database, n int, gen func(int, *BlockGen)) ([]*types.Block, []types.Receipts) {
 	if config == nil {
 		config = params.DposChainConfig
 	}
 	blocks, receipts := make(types.Blocks, n), make([]types.Receipts, n)
-	genblock := func(i int, h *types.Header, statedb *state.StateDB) (*types.Block, types.Receipts) {
-		b := &BlockGen{parent: parent, i: i, chain: blocks, header: h, statedb: statedb, config: config}
+	genblock := func(i int, h *types.Header, statedb *state.StateDB, dposContext *types.DposContext) (*types.Block, types.Receipts) {
+		b := &BlockGen{parent: parent, i: i, chain: blocks, header: h, statedb: statedb, dposContext: dposContext, engine: engine, config: config}
+		if engine != nil {
+			h.Difficulty = engine.CalcDifficulty(nil, h.Time.Uint64(), parent.Header())
+			b.setValidator()
+		}
+		dposRoot := dposContext.Root()
+
 		// Mutate the state and block according to any hard-fork specs
 		if daoBlock := config.DAOForkBlock; daoBlock != nil {
 			limit := new(big.Int).Add(daoBlock, params.DAOForkExtraRange)
@@ -180,13 +245,25 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, db kokdb.Dat
 		if gen != nil {
 			gen(i, b)
 		}
-		dpos.AccumulateRewards(config, statedb, h, b.uncles)
+		if engine != nil {
+			return sealBlock(config, engine, signers, db, blocks[:i], b)
+		}
+		if config.Dpos != nil {
+			dpos.AccumulateRewards(config, statedb, h, b.uncles)
+		} else {
+			kokash.AccumulateRewards(config, statedb, h, b.uncles)
+		}
 		root, err := statedb.CommitTo(db, config.IsEIP158(h.Number))
 		if err != nil {
 			panic(fmt.Sprintf("state write error: %v", err))
 		}
 		h.Root = root
 		h.DposContext = parent.Header().DposContext
+		if dposContext.Root() != dposRoot {
+			if h.DposContext, err = dposContext.CommitTo(db); err != nil {
+				panic(fmt.Sprintf("dpos context write error: %v", err))
+			}
+		}
 		return types.NewBlock(h, b.txs, b.uncles, b.receipts), b.receipts
 	}
 	for i := 0; i < n; i++ {
@@ -194,8 +271,16 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, db kokdb.Dat
 		if err != nil {
 			panic(err)
 		}
+		proto := parent.Header().DposContext
+		if proto == nil {
+			proto = new(types.DposContextProto)
+		}
+		dposContext, err := types.NewDposContextFromProto(db, proto)
+		if err != nil {
+			panic(err)
+		}
 		header := makeHeader(config, parent, statedb)
-		block, receipt := genblock(i, header, statedb)
+		block, receipt := genblock(i, header, statedb, dposContext)
 		blocks[i] = block
 		receipts[i] = receipt
 		parent = block
@@ -203,6 +288,98 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, db kokdb.Dat
 	return blocks, receipts
 }
 
+// sealBlock finalises a generated block with the DPoS engine, writes its state
+// and dpos context into the database and signs it with the key of its validator.
+func sealBlock(config *params.ChainConfig, engine *dpos.Dpos, signers map[common.Address]*ecdsa.PrivateKey, db kokdb.Database, chain []*types.Block, b *BlockGen) (*types.Block, types.Receipts) {
+	reader := &genChainReader{config: config, db: db, chain: append([]*types.Block{b.parent}, chain...)}
+
+	validator := b.header.Validator
+	if err := engine.Prepare(reader, b.header); err != nil {
+		panic(fmt.Sprintf("block prepare error: %v", err))
+	}
+	b.header.Validator = validator
+
+	block, err := engine.Finalize(reader, b.header, b.statedb, b.txs, b.uncles, b.receipts, b.dposContext)
+	if err != nil {
+		panic(fmt.Sprintf("block finalize error: %v", err))
+	}
+	if _, err := b.statedb.CommitTo(db, config.IsEIP158(b.header.Number)); err != nil {
+		panic(fmt.Sprintf("state write error: %v", err))
+	}
+	if _, err := b.dposContext.CommitTo(db); err != nil {
+		panic(fmt.Sprintf("dpos context write error: %v", err))
+	}
+	key, ok := signers[validator]
+	if !ok {
+		panic(fmt.Sprintf("no key for validator %x", validator))
+	}
+	header := block.Header()
+	sig, err := crypto.Sign(dpos.SealHash(header).Bytes(), key)
+	if err != nil {
+		panic(fmt.Sprintf("block signing error: %v", err))
+	}
+	copy(header.Extra[len(header.Extra)-len(sig):], sig)
+	return block.WithSeal(header), b.receipts
+}
+
+// genChainReader is a consensus.ChainReader over the blocks being generated,
+// falling back to the database for their ancestors.
+type genChainReader struct {
+	config *params.ChainConfig
+	db     kokdb.Database
+	chain  []*types.Block // Parent of the generated chain and the blocks generated so far
+}
+
+func (r *genChainReader) Config() *params.ChainConfig {
+	return r.config
+}
+
+func (r *genChainReader) CurrentHeader() *types.Header {
+	return r.chain[len(r.chain)-1].Header()
+}
+
+func (r *genChainReader) Gkokeader(hash common.Hash, number uint64) *types.Header {
+	if block := r.GetBlock(hash, number); block != nil {
+		return block.Header()
+	}
+	return nil
+}
+
+func (r *genChainReader) GkokeaderByNumber(number uint64) *types.Header {
+	for _, block := range r.chain {
+		if block.NumberU64() == number {
+			return block.Header()
+		}
+	}
+	hash := GetCanonicalHash(r.db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return Gkokeader(r.db, hash, number)
+}
+
+func (r *genChainReader) GkokeaderByHash(hash common.Hash) *types.Header {
+	for _, block := range r.chain {
+		if block.Hash() == hash {
+			return block.Header()
+		}
+	}
+	number := GetBlockNumber(r.db, hash)
+	if number == missingNumber {
+		return nil
+	}
+	return Gkokeader(r.db, hash, number)
+}
+
+func (r *genChainReader) GetBlock(hash common.Hash, number uint64) *types.Block {
+	for _, block := range r.chain {
+		if block.Hash() == hash {
+			return block
+		}
+	}
+	return GetBlock(r.db, hash, number)
+}
+
 func makeHeader(config *params.ChainConfig, parent *types.Block, state *state.StateDB) *types.Header {
 	var time *big.Int
 	if parent.Time() == nil {
@@ -262,7 +439,7 @@ func makeHeaderChain(parent *types.Header, n int, db kokdb.Database, seed int) [
 
 // makeBlockChain creates a deterministic chain of blocks rooted at parent.
 func makeBlockChain(parent *types.Block, n int, db kokdb.Database, seed int) []*types.Block {
-	blocks, _ := GenerateChain(params.DposChainConfig, parent, db, n, func(i int, b *BlockGen) {
+	blocks, _ := GenerateChain(params.TestChainConfig, parent, db, n, func(i int, b *BlockGen) {
 		b.SetCoinbase(common.Address{0: byte(seed), 19: byte(i)})
 	})
 	return blocks
@@ -17,9 +17,13 @@
 package core
 
 import (
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
+	"testing"
 
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/consensus/dpos"
 	"github.com/kokprojects/go-kok/consensus/kokash"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/core/vm"
@@ -98,3 +102,56 @@ func ExampleGenerateChain() {
 	// balance of addr2: 10000
 	// balance of addr3: 15000000000000001000
 }
+
+// Tests that chains generated with DPoS sealing, including candidate transactions
+// and epoch transitions, are accepted by a blockchain running the DPoS engine.
+func TestGenerateDposChain(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 3)
+		addrs = make([]common.Address, 3)
+		db, _ = kokdb.NewMemDatabase()
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	config := *params.DposChainConfig
+	config.Dpos = &params.DposConfig{Validators: addrs}
+
+	gspec := &Genesis{
+		Config: &config,
+		Alloc:  GenesisAlloc{addrs[0]: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	genesis := gspec.MustCommit(db)
+
+	// Delegate to a validator in the first block, then skip into the next epoch
+	signer := types.NewEIP155Signer(config.ChainId)
+	chain, _ := GenerateDposChain(&config, genesis, dpos.New(config.Dpos, db), db, keys, 4, func(i int, gen *BlockGen) {
+		switch i {
+		case 0:
+			tx, _ := types.SignTx(types.NewTransaction(types.Delegate, gen.TxNonce(addrs[0]), addrs[1], new(big.Int), bigTxGas, nil, nil), signer, keys[0])
+			gen.AddTx(tx)
+		case 2:
+			gen.OffsetTime(86400)
+		}
+	})
+	if chain[2].Time().Uint64()/86400 == chain[1].Time().Uint64()/86400 {
+		t.Fatalf("epoch not crossed: block times %v and %v", chain[1].Time(), chain[2].Time())
+	}
+	blockchain, err := NewBlockChain(db, &config, dpos.New(config.Dpos, db), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	if i, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("insert error (block %d): %v", chain[i].NumberU64(), err)
+	}
+	dposContext, err := types.NewDposContextFromProto(db, blockchain.CurrentBlock().Header().DposContext)
+	if err != nil {
+		t.Fatalf("failed to open dpos context: %v", err)
+	}
+	if candidate := dposContext.VoteTrie().Get(addrs[0].Bytes()); common.BytesToAddress(candidate) != addrs[1] {
+		t.Errorf("delegation mismatch: have %x, want %x", candidate, addrs[1])
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"math/big"
 
 	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/log"
@@ -57,12 +58,18 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	lookupPrefix        = []byte("l") // lookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix     = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	addressBitsPrefix   = []byte("A") // addressBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> address bloom bits
+	balancesPrefix      = []byte("d") // balancesPrefix + num (uint64 big endian) + hash -> block balance changes
+	internalTxsPrefix   = []byte("c") // internalTxsPrefix + num (uint64 big endian) + hash -> block internal transactions
+	creationPrefix      = []byte("C") // creationPrefix + address -> contract creation metadata
+	revertsPrefix       = []byte("v") // revertsPrefix + num (uint64 big endian) + hash -> block revert reasons
 
 	preimagePrefix = "secure-key-"              // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("kokereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
-	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	BloomBitsIndexPrefix   = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	AddressBitsIndexPrefix = []byte("iA") // AddressBitsIndexPrefix is the data table of the address bloom bits indexer
 
 	// used by old db, now only used for conversion
 	oldReceiptsPrefix = []byte("receipts-")
@@ -358,6 +365,17 @@ func GetBloomBits(db DatabaseReader, bit uint, section uint64, head common.Hash)
 	return db.Get(key)
 }
 
+// GetAddressBloomBits retrieves the compressed address-only bloom bit vector
+// belonging to the given section and bit index.
+func GetAddressBloomBits(db DatabaseReader, bit uint, section uint64, head common.Hash) ([]byte, error) {
+	key := append(append(addressBitsPrefix, make([]byte, 10)...), head.Bytes()...)
+
+	binary.BigEndian.PutUint16(key[1:], uint16(bit))
+	binary.BigEndian.PutUint64(key[3:], section)
+
+	return db.Get(key)
+}
+
 // WriteCanonicalHash stores the canonical hash for the given block number.
 func WriteCanonicalHash(db kokdb.Putter, hash common.Hash, number uint64) error {
 	key := append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...)
@@ -476,6 +494,149 @@ func WriteBlockReceipts(db kokdb.Putter, hash common.Hash, number uint64, receip
 	return nil
 }
 
+// WriteBlockBalanceChanges stores the balance changes made by the transactions
+// of a block.
+func WriteBlockBalanceChanges(db kokdb.Putter, hash common.Hash, number uint64, changes []*state.TxBalanceChanges) error {
+	data, err := rlp.EncodeToBytes(changes)
+	if err != nil {
+		return err
+	}
+	key := append(append(balancesPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+	if err := db.Put(key, data); err != nil {
+		log.Crit("Failed to store block balance changes", "err", err)
+	}
+	return nil
+}
+
+// GetBlockBalanceChanges retrieves the balance changes made by the transactions
+// of a block, or nil if they weren't recorded.
+func GetBlockBalanceChanges(db DatabaseReader, hash common.Hash, number uint64) []*state.TxBalanceChanges {
+	data, _ := db.Get(append(append(balancesPrefix, encodeBlockNumber(number)...), hash[:]...))
+	if len(data) == 0 {
+		return nil
+	}
+	var changes []*state.TxBalanceChanges
+	if err := rlp.DecodeBytes(data, &changes); err != nil {
+		log.Error("Invalid balance changes RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return changes
+}
+
+// DeleteBlockBalanceChanges removes the balance changes recorded for a block.
+func DeleteBlockBalanceChanges(db DatabaseDeleter, hash common.Hash, number uint64) {
+	db.Delete(append(append(balancesPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+}
+
+// WriteBlockInternalTxs stores the value transfers made by contracts during the
+// execution of a block, one list per transaction.
+func WriteBlockInternalTxs(db kokdb.Putter, hash common.Hash, number uint64, txs [][]*types.InternalTx) error {
+	data, err := rlp.EncodeToBytes(txs)
+	if err != nil {
+		return err
+	}
+	key := append(append(internalTxsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+	if err := db.Put(key, data); err != nil {
+		log.Crit("Failed to store block internal transactions", "err", err)
+	}
+	return nil
+}
+
+// GetBlockInternalTxs retrieves the value transfers made by contracts during the
+// execution of a block, one list per transaction, or nil if they weren't recorded.
+func GetBlockInternalTxs(db DatabaseReader, hash common.Hash, number uint64) [][]*types.InternalTx {
+	data, _ := db.Get(append(append(internalTxsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	if len(data) == 0 {
+		return nil
+	}
+	var txs [][]*types.InternalTx
+	if err := rlp.DecodeBytes(data, &txs); err != nil {
+		log.Error("Invalid internal transactions RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return txs
+}
+
+// DeleteBlockInternalTxs removes the internal transactions recorded for a block.
+func DeleteBlockInternalTxs(db DatabaseDeleter, hash common.Hash, number uint64) {
+	db.Delete(append(append(internalTxsPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+}
+
+// WriteBlockRevertReasons stores the return data of the failed transactions of a
+// block, one entry per transaction. Nothing is stored if no transaction reverted
+// with data.
+func WriteBlockRevertReasons(db kokdb.Putter, hash common.Hash, number uint64, receipts types.Receipts) error {
+	var (
+		reasons = make([][]byte, len(receipts))
+		found   bool
+	)
+	for i, receipt := range receipts {
+		reasons[i], found = receipt.RevertReason, found || len(receipt.RevertReason) > 0
+	}
+	if !found {
+		return nil
+	}
+	data, err := rlp.EncodeToBytes(reasons)
+	if err != nil {
+		return err
+	}
+	key := append(append(revertsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+	if err := db.Put(key, data); err != nil {
+		log.Crit("Failed to store block revert reasons", "err", err)
+	}
+	return nil
+}
+
+// GetBlockRevertReasons retrieves the return data of the failed transactions of
+// a block, one entry per transaction, or nil if none were recorded.
+func GetBlockRevertReasons(db DatabaseReader, hash common.Hash, number uint64) [][]byte {
+	data, _ := db.Get(append(append(revertsPrefix, encodeBlockNumber(number)...), hash[:]...))
+	if len(data) == 0 {
+		return nil
+	}
+	var reasons [][]byte
+	if err := rlp.DecodeBytes(data, &reasons); err != nil {
+		log.Error("Invalid revert reasons RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return reasons
+}
+
+// DeleteBlockRevertReasons removes the revert reasons recorded for a block.
+func DeleteBlockRevertReasons(db DatabaseDeleter, hash common.Hash, number uint64) {
+	db.Delete(append(append(revertsPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+}
+
+// WriteContractCreations stores the creation metadata of every contract deployed
+// by a block, enabling address based creator lookups.
+func WriteContractCreations(db kokdb.Putter, creations []*types.ContractCreation) error {
+	for _, creation := range creations {
+		data, err := rlp.EncodeToBytes(creation)
+		if err != nil {
+			return err
+		}
+		if err := db.Put(append(creationPrefix, creation.Address.Bytes()...), data); err != nil {
+			log.Crit("Failed to store contract creation", "err", err)
+		}
+	}
+	return nil
+}
+
+// GetContractCreation retrieves the creation metadata of a contract, or nil if
+// it's not known. The transaction it references may not be canonical anymore.
+func GetContractCreation(db DatabaseReader, address common.Address) *types.ContractCreation {
+	data, _ := db.Get(append(creationPrefix, address.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	creation := new(types.ContractCreation)
+	if err := rlp.DecodeBytes(data, creation); err != nil {
+		log.Error("Invalid contract creation RLP", "address", address, "err", err)
+		return nil
+	}
+	return creation
+}
+
 // WriteTxLookupEntries stores a positional metadata for every transaction from
 // a block, enabling hash based transaction and receipt lookups.
 func WriteTxLookupEntries(db kokdb.Putter, block *types.Block) error {
@@ -510,6 +671,19 @@ func WriteBloomBits(db kokdb.Putter, bit uint, section uint64, head common.Hash,
 	}
 }
 
+// WriteAddressBloomBits writes the compressed address-only bloom bits vector
+// belonging to the given section and bit index.
+func WriteAddressBloomBits(db kokdb.Putter, bit uint, section uint64, head common.Hash, bits []byte) {
+	key := append(append(addressBitsPrefix, make([]byte, 10)...), head.Bytes()...)
+
+	binary.BigEndian.PutUint16(key[1:], uint16(bit))
+	binary.BigEndian.PutUint64(key[3:], section)
+
+	if err := db.Put(key, bits); err != nil {
+		log.Crit("Failed to store address bloom bits", "err", err)
+	}
+}
+
 // DeleteCanonicalHash removes the number to hash canonical mapping.
 func DeleteCanonicalHash(db DatabaseDeleter, number uint64) {
 	db.Delete(append(append(headerPrefix, encodeBlockNumber(number)...), numSuffix...))
@@ -534,6 +708,9 @@ func DeleteTd(db DatabaseDeleter, hash common.Hash, number uint64) {
 // DeleteBlock removes all block data associated with a hash.
 func DeleteBlock(db DatabaseDeleter, hash common.Hash, number uint64) {
 	DeleteBlockReceipts(db, hash, number)
+	DeleteBlockBalanceChanges(db, hash, number)
+	DeleteBlockInternalTxs(db, hash, number)
+	DeleteBlockRevertReasons(db, hash, number)
 	DeleteHeader(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
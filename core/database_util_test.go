@@ -388,3 +388,31 @@ func TestBlockReceiptStorage(t *testing.T) {
 		t.Fatalf("deleted receipts returned: %v", rs)
 	}
 }
+
+// Tests that the return data of reverted transactions is stored per block, and
+// only if any transaction reverted with data.
+func TestBlockRevertReasonStorage(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+
+	hash := common.BytesToHash([]byte{0x03, 0x14})
+	receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful}, {Status: types.ReceiptStatusFailed}}
+
+	if err := WriteBlockRevertReasons(db, hash, 0, receipts); err != nil {
+		t.Fatalf("failed to write revert reasons: %v", err)
+	}
+	if reasons := GetBlockRevertReasons(db, hash, 0); reasons != nil {
+		t.Fatalf("revert reasons stored without reverts: %x", reasons)
+	}
+	receipts[1].RevertReason = []byte{0x08, 0xc3, 0x79, 0xa0}
+	if err := WriteBlockRevertReasons(db, hash, 0, receipts); err != nil {
+		t.Fatalf("failed to write revert reasons: %v", err)
+	}
+	reasons := GetBlockRevertReasons(db, hash, 0)
+	if len(reasons) != 2 || len(reasons[0]) != 0 || !bytes.Equal(reasons[1], receipts[1].RevertReason) {
+		t.Fatalf("revert reasons mismatch: have %x, want [[] %x]", reasons, receipts[1].RevertReason)
+	}
+	DeleteBlock(db, hash, 0)
+	if reasons := GetBlockRevertReasons(db, hash, 0); reasons != nil {
+		t.Fatalf("deleted revert reasons returned: %x", reasons)
+	}
+}
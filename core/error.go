@@ -29,7 +29,23 @@ var (
 	// ErrBlacklistedHash is returned if a block to import is on the blacklist.
 	ErrBlacklistedHash = errors.New("blacklisted hash")
 
+	// ErrReorgTooDeep is returned if a reorg would drop more blocks than the
+	// states retained when pruning, past which the old states may be gone.
+	ErrReorgTooDeep = errors.New("reorg deeper than retained states")
+
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
+
+	// ErrFrozenAccount is returned if the sender of a transaction is frozen by the
+	// chain configuration.
+	ErrFrozenAccount = errors.New("sender account frozen")
+
+	// ErrSenderNotPermitted is returned on permissioned networks if the sender of
+	// a transaction isn't allowed to send transactions.
+	ErrSenderNotPermitted = errors.New("sender not permitted")
+
+	// ErrDeployerNotPermitted is returned on permissioned networks if the sender of
+	// a transaction deploying a template or contract isn't allowed to deploy.
+	ErrDeployerNotPermitted = errors.New("deployer not permitted")
 )
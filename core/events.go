@@ -41,6 +41,16 @@ type RemovedTransactionEvent struct{ Txs types.Transactions }
 // RemovedLogsEvent is posted when a reorg happens
 type RemovedLogsEvent struct{ Logs []*types.Log }
 
+// ReorgEvent is posted when a reorg happens, describing the replaced chain segment
+type ReorgEvent struct {
+	OldHead    *types.Block       // Head of the chain before the reorg
+	NewHead    *types.Block       // First block of the new chain written by the reorg
+	Common     *types.Block       // Common ancestor of the old and new chains
+	Dropped    int                // Number of blocks removed from the canonical chain
+	Added      int                // Number of blocks added to the canonical chain
+	DroppedTxs types.Transactions // Transactions of the old chain not included in the new one
+}
+
 type ChainEvent struct {
 	Block *types.Block
 	Hash  common.Hash
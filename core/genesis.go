@@ -153,6 +153,11 @@ func SetupGenesisBlock(db kokdb.Database, genesis *Genesis) (*params.ChainConfig
 	if genesis != nil && genesis.Config == nil {
 		return params.DposChainConfig, common.Hash{}, errGenesisNoConfig
 	}
+	if genesis != nil {
+		if err := genesis.Config.CheckConfig(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
 
 	// Just commit the new block if there is no stored genesis block.
 	stored := GetCanonicalHash(db, 0)
@@ -200,6 +205,11 @@ func SetupGenesisBlock(db kokdb.Database, genesis *Genesis) (*params.ChainConfig
 	if height == missingNumber {
 		return newcfg, stored, fmt.Errorf("missing block number for head header hash")
 	}
+	// The fee split is applied to every contract call since genesis, there is no
+	// block to rewind to for changing it on a live chain.
+	if height != 0 && storedcfg.DeveloperShare() != newcfg.DeveloperShare() {
+		return newcfg, stored, fmt.Errorf("mismatching developer fee share in database (have %d%%, want %d%%)", storedcfg.DeveloperShare(), newcfg.DeveloperShare())
+	}
 	compatErr := storedcfg.CheckCompatible(newcfg, height)
 	if compatErr != nil && height != 0 && compatErr.RewindTo != 0 {
 		return newcfg, stored, compatErr
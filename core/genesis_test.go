@@ -17,6 +17,7 @@
 package core
 
 import (
+	"errors"
 	"math/big"
 	"reflect"
 	"testing"
@@ -39,6 +40,9 @@ func TestSetupGenesis(t *testing.T) {
 			},
 		}
 		oldcustomg = customg
+		badfeeg    = Genesis{
+			Config: &params.ChainConfig{HomesteadBlock: big.NewInt(3), Fee: &params.FeeConfig{DeveloperShare: 101}},
+		}
 	)
 	oldcustomg.Config = &params.ChainConfig{HomesteadBlock: big.NewInt(2)}
 	tests := []struct {
@@ -89,6 +93,14 @@ func TestSetupGenesis(t *testing.T) {
 				RewindTo:     1,
 			},
 		},
+		{
+			name: "invalid fee split in genesis",
+			fn: func(db kokdb.Database) (*params.ChainConfig, common.Hash, error) {
+				return SetupGenesisBlock(db, &badfeeg)
+			},
+			wantConfig: badfeeg.Config,
+			wantErr:    errors.New("invalid developer fee share 101%, must be between 0 and 100"),
+		},
 	}
 
 	for _, test := range tests {
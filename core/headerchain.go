@@ -391,6 +391,12 @@ func (hc *HeaderChain) SetCurrentHeader(head *types.Header) {
 	if err := WriteHeadHeaderHash(hc.chainDb, head.Hash()); err != nil {
 		log.Crit("Failed to insert head header hash", "err", err)
 	}
+	hc.setCurrentHeader(head)
+}
+
+// setCurrentHeader sets the in-memory head header of the canonical chain, the
+// caller being responsible for persisting its marker beforehand.
+func (hc *HeaderChain) setCurrentHeader(head *types.Header) {
 	hc.currentHeader = head
 	hc.currentHeaderHash = head.Hash()
 }
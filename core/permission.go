@@ -0,0 +1,73 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/params"
+)
+
+// Permission flags granted by the registry contract of a permissioned network,
+// stored in a Solidity mapping(address => uint256) at storage slot 0.
+const (
+	PermitSend   = 1 << iota // Account may send transactions
+	PermitDeploy             // Account may deploy templates and contracts
+)
+
+// PermissionKey returns the storage key of an account's permission flags in the
+// registry contract.
+func PermissionKey(addr common.Address) common.Hash {
+	return crypto.Keccak256Hash(common.LeftPadBytes(addr[:], common.HashLength), make([]byte, common.HashLength))
+}
+
+// CheckPermission verifies on permissioned networks that the sender of a
+// transaction may send it, and deploy a template or contract if it does so.
+func CheckPermission(config *params.ChainConfig, statedb vm.StateDB, num *big.Int, from common.Address, to *common.Address, txType types.TxType) error {
+	if !config.IsPermissioned(num) {
+		return nil
+	}
+	perm := config.Permission
+
+	var granted uint64
+	if perm.Registry != nil {
+		granted = statedb.GetState(*perm.Registry, PermissionKey(from)).Big().Uint64()
+	}
+	senders, deployers := config.PermittedAccounts(num)
+	if granted&PermitSend == 0 && !containsAddress(senders, from) {
+		return ErrSenderNotPermitted
+	}
+	deploy := to == nil || (txType == types.Binary && GetAddressType(statedb.GetState(*to, HashTypeString("type"))) == "template")
+	if deploy && granted&PermitDeploy == 0 && !containsAddress(deployers, from) {
+		return ErrDeployerNotPermitted
+	}
+	return nil
+}
+
+// containsAddress reports whkoker addr is in the list.
+func containsAddress(list []common.Address, addr common.Address) bool {
+	for _, item := range list {
+		if item == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+)
+
+// Tests that senders and deployers are checked against the configured allowlists,
+// including the grants activating later, and the registry contract.
+func TestCheckPermission(t *testing.T) {
+	var (
+		listed   = common.Address{0x01} // Configured sender and deployer
+		sender   = common.Address{0x02} // Configured sender only
+		granted  = common.Address{0x03} // Registry granted sender and deployer
+		later    = common.Address{0x05} // Sender and deployer granted by the config at block 20
+		stranger = common.Address{0x04}
+		registry = common.Address{0xaa}
+		template = common.Address{0xbb}
+		contract = common.Address{0xcc}
+	)
+	config := *params.TestChainConfig
+	config.Permission = &params.PermissionConfig{
+		Block:     big.NewInt(10),
+		Senders:   []common.Address{listed, sender},
+		Deployers: []common.Address{listed},
+		Registry:  &registry,
+		Grants: []*params.PermissionGrant{
+			{Block: big.NewInt(20), Senders: []common.Address{later}, Deployers: []common.Address{later}},
+		},
+	}
+	db, _ := kokdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetState(registry, PermissionKey(granted), common.BigToHash(big.NewInt(PermitSend|PermitDeploy)))
+	statedb.SetState(template, HashTypeString("type"), HashTypeString("template"))
+	statedb.SetState(contract, HashTypeString("type"), HashTypeString("contract"))
+
+	tests := []struct {
+		number int64
+		from   common.Address
+		to     *common.Address
+		txType types.TxType
+		err    error
+	}{
+		{9, stranger, nil, types.Binary, nil}, // Not yet active
+		{10, stranger, &contract, types.Binary, ErrSenderNotPermitted},
+		{10, sender, &contract, types.Binary, nil},
+		{10, sender, nil, types.Binary, ErrDeployerNotPermitted},
+		{10, sender, &template, types.Binary, ErrDeployerNotPermitted},
+		{10, sender, &template, types.SourceCode, nil}, // Not a deployment
+		{10, listed, nil, types.Binary, nil},
+		{10, listed, &template, types.Binary, nil},
+		{10, granted, nil, types.Binary, nil},
+		{10, granted, &template, types.Binary, nil},
+		{19, later, &contract, types.Binary, ErrSenderNotPermitted}, // Grant not yet active
+		{20, later, &contract, types.Binary, nil},
+		{20, later, nil, types.Binary, nil},
+		{20, sender, nil, types.Binary, ErrDeployerNotPermitted},
+	}
+	for i, tt := range tests {
+		if err := CheckPermission(&config, statedb, big.NewInt(tt.number), tt.from, tt.to, tt.txType); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
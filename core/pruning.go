@@ -0,0 +1,119 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/log"
+)
+
+// PruningConfig are the configuration parameters of the state trie garbage
+// collection.
+type PruningConfig struct {
+	Enabled  bool   // Whether historical state tries are discarded (full) or kept (archive)
+	Retain   uint64 // Number of recent state roots kept available in memory
+	Interval uint64 // Number of blocks between state snapshots persisted to disk
+}
+
+// DefaultPruningConfig contains the default settings of the state trie garbage
+// collection, which is disabled unless requested.
+var DefaultPruningConfig = PruningConfig{
+	Retain:   128,
+	Interval: 1024,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *PruningConfig) sanitize() PruningConfig {
+	conf := *config
+	if conf.Retain < 1 {
+		log.Warn("Sanitizing invalid pruning retention", "provided", conf.Retain, "updated", DefaultPruningConfig.Retain)
+		conf.Retain = DefaultPruningConfig.Retain
+	}
+	if conf.Interval < 1 {
+		log.Warn("Sanitizing invalid pruning interval", "provided", conf.Interval, "updated", DefaultPruningConfig.Interval)
+		conf.Interval = DefaultPruningConfig.Interval
+	}
+	return conf
+}
+
+// retainedState is the state root of a recently written block, kept in memory
+// until it falls out of the pruning retention window.
+type retainedState struct {
+	number uint64
+	root   common.Hash
+}
+
+// SetPruning configures the garbage collection of the state tries. When enabled,
+// the tries of the most recent blocks are kept in memory only, and every pruning
+// interval the oldest of them is persisted as a snapshot, discarding all older
+// states not yet written to disk. Otherwise every state is kept (archive mode).
+func (bc *BlockChain) SetPruning(config PruningConfig) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	bc.pruning = config.sanitize()
+	bc.retained = nil
+	bc.lastSnapshot = bc.currentBlock.NumberU64()
+}
+
+// pruneState tracks the state root of a newly written block. Once a state falls
+// out of the retention window, it's persisted as a snapshot if the pruning
+// interval elapsed since the previous one (or too much data is being buffered),
+// otherwise it's left to be garbage collected.
+//
+// The caller must hold the chain mutex.
+func (bc *BlockChain) pruneState(number uint64, root common.Hash) error {
+	bc.retained = append(bc.retained, retainedState{number: number, root: root})
+	if uint64(len(bc.retained)) <= bc.pruning.Retain {
+		return nil
+	}
+	expired := bc.retained[0]
+	bc.retained = bc.retained[1:]
+
+	if expired.number < bc.lastSnapshot+bc.pruning.Interval && bc.stateBuffer.Size() <= maxBufferedState {
+		return nil
+	}
+	return bc.snapshotState(expired.number, expired.root)
+}
+
+// snapshotState persists the given state and discards all buffered tries not
+// reachable from the retained states, the current head or any active reader.
+//
+// The caller must hold the chain mutex.
+func (bc *BlockChain) snapshotState(number uint64, root common.Hash) error {
+	var (
+		start  = time.Now()
+		before = bc.stateBuffer.Stats()
+	)
+	retain := append(bc.stateReaders.Roots(), bc.currentBlock.Root())
+	for _, state := range bc.retained {
+		retain = append(retain, state.root)
+	}
+	if err := bc.stateBuffer.Prune(root, retain); err != nil {
+		return err
+	}
+	bc.lastSnapshot = number
+
+	after := bc.stateBuffer.Stats()
+	log.Info("Persisted state snapshot", "number", number, "root", root,
+		"flushed", after.FlushedNodes-before.FlushedNodes, "pruned", after.PrunedNodes-before.PrunedNodes,
+		"buffered", after.Nodes, "size", after.Size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/kokprojects/go-kok/common"
+)
+
+// BalanceChange is the change of an account balance made by a transaction.
+type BalanceChange struct {
+	Address common.Address
+	Before  *big.Int
+	After   *big.Int
+}
+
+// TxBalanceChanges are the balance changes made by a single transaction.
+type TxBalanceChanges struct {
+	TxHash  common.Hash
+	Changes []BalanceChange
+
+	// Destructed is set if an account self-destructed during the transaction,
+	// which may burn funds. It is only known while processing, not persisted.
+	Destructed bool `json:"-" rlp:"-"`
+}
+
+// EnableBalanceTracking starts recording the balance changes made by each of
+// the transactions applied onto the state, as delimited by Prepare. Changes
+// made outside of transactions (e.g. block rewards) are not attributed to any.
+func (self *StateDB) EnableBalanceTracking() {
+	if self.balanceOrigins == nil {
+		self.balanceOrigins = make(map[common.Address]*big.Int)
+	}
+}
+
+// trackBalance records the balance of an account before it's first modified by
+// the current transaction.
+func (self *StateDB) trackBalance(addr common.Address) {
+	if self.balanceOrigins == nil || !self.balanceTxOpen {
+		return
+	}
+	if _, ok := self.balanceOrigins[addr]; !ok {
+		self.balanceOrigins[addr] = new(big.Int).Set(self.GetBalance(addr))
+	}
+}
+
+// trackSuicide records the balance of a self-destructing account and flags the
+// current transaction as possibly burning funds.
+func (self *StateDB) trackSuicide(addr common.Address) {
+	if self.balanceOrigins == nil || !self.balanceTxOpen {
+		return
+	}
+	self.trackBalance(addr)
+	self.balanceDestructed = true
+}
+
+// FinaliseBalanceChanges closes the balance changes of the current transaction,
+// after which further changes are not attributed to it. It's called implicitly
+// when preparing for the next transaction.
+func (self *StateDB) FinaliseBalanceChanges() {
+	self.balanceTxOpen = false
+	destructed := self.balanceDestructed
+	self.balanceDestructed = false
+	if len(self.balanceOrigins) == 0 {
+		return
+	}
+	var changes []BalanceChange
+	for addr, before := range self.balanceOrigins {
+		if after := self.GetBalance(addr); before.Cmp(after) != 0 {
+			changes = append(changes, BalanceChange{Address: addr, Before: before, After: new(big.Int).Set(after)})
+		}
+	}
+	if len(changes) > 0 {
+		sort.Slice(changes, func(i, j int) bool {
+			return bytes.Compare(changes[i].Address[:], changes[j].Address[:]) < 0
+		})
+		self.balanceChanges = append(self.balanceChanges, &TxBalanceChanges{TxHash: self.thash, Changes: changes, Destructed: destructed})
+	}
+	self.balanceOrigins = make(map[common.Address]*big.Int)
+}
+
+// BalanceChanges returns the balance changes of the finalised transactions, if
+// tracking is enabled.
+func (self *StateDB) BalanceChanges() []*TxBalanceChanges {
+	return self.balanceChanges
+}
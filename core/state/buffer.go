@@ -0,0 +1,329 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/rlp"
+	"github.com/kokprojects/go-kok/trie"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// cleanCacheSize is the number of trie nodes and contract codes read from the
+// backing database which are cached by a NodeBuffer.
+const cleanCacheSize = 65536
+
+// NodeBuffer is an in-memory write buffer on top of a database, accumulating the
+// trie nodes and contract codes committed by recent blocks until they are
+// flushed to disk in one go. Reads are served from the buffer first, so a state
+// database backed by a NodeBuffer can access unflushed states. The data read from
+// disk is cached, so states warmed up by a reader are fast to access for others.
+//
+// NodeBuffer implements kokdb.Database.
+type NodeBuffer struct {
+	db     kokdb.Database
+	nodes  map[string][]byte
+	size   common.StorageSize
+	cleans *lru.Cache // Recently read data already persisted in the database
+	lock   sync.RWMutex
+
+	flushes      uint64             // Number of times the buffer was flushed
+	flushedNodes uint64             // Total number of nodes flushed to disk
+	flushedSize  common.StorageSize // Total size of the nodes flushed to disk
+	lastFlush    time.Time          // Time of the last flush
+	prunedNodes  uint64             // Total number of nodes discarded without flushing
+	prunedSize   common.StorageSize // Total size of the nodes discarded without flushing
+}
+
+// NodeBufferStats contains the statistics of a NodeBuffer.
+type NodeBufferStats struct {
+	Nodes        int                `json:"nodes"`
+	Size         common.StorageSize `json:"size"`
+	Flushes      uint64             `json:"flushes"`
+	FlushedNodes uint64             `json:"flushedNodes"`
+	FlushedSize  common.StorageSize `json:"flushedSize"`
+	LastFlush    time.Time          `json:"lastFlush"`
+	PrunedNodes  uint64             `json:"prunedNodes"`
+	PrunedSize   common.StorageSize `json:"prunedSize"`
+}
+
+// NewNodeBuffer creates an empty write buffer on top of the given database.
+func NewNodeBuffer(db kokdb.Database) *NodeBuffer {
+	cleans, _ := lru.New(cleanCacheSize)
+	return &NodeBuffer{
+		db:     db,
+		nodes:  make(map[string][]byte),
+		cleans: cleans,
+	}
+}
+
+// Put buffers a key-value pair until the next flush.
+func (b *NodeBuffer) Put(key []byte, value []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if _, ok := b.nodes[string(key)]; !ok {
+		b.nodes[string(key)] = common.CopyBytes(value)
+		b.size += common.StorageSize(len(key) + len(value))
+	}
+	return nil
+}
+
+// Get retrieves a value from the buffer, or the backing database if missing.
+func (b *NodeBuffer) Get(key []byte) ([]byte, error) {
+	b.lock.RLock()
+	value, ok := b.nodes[string(key)]
+	b.lock.RUnlock()
+
+	if ok {
+		return common.CopyBytes(value), nil
+	}
+	if cached, ok := b.cleans.Get(string(key)); ok {
+		return common.CopyBytes(cached.([]byte)), nil
+	}
+	value, err := b.db.Get(key)
+	if err == nil {
+		b.cleans.Add(string(key), common.CopyBytes(value))
+	}
+	return value, err
+}
+
+// Has checks whether a key is present in the buffer or the backing database.
+func (b *NodeBuffer) Has(key []byte) (bool, error) {
+	b.lock.RLock()
+	_, ok := b.nodes[string(key)]
+	b.lock.RUnlock()
+
+	if ok || b.cleans.Contains(string(key)) {
+		return true, nil
+	}
+	return b.db.Has(key)
+}
+
+// Delete removes a key from both the buffer and the backing database.
+func (b *NodeBuffer) Delete(key []byte) error {
+	b.lock.Lock()
+	if value, ok := b.nodes[string(key)]; ok {
+		delete(b.nodes, string(key))
+		b.size -= common.StorageSize(len(key) + len(value))
+	}
+	b.lock.Unlock()
+
+	b.cleans.Remove(string(key))
+	return b.db.Delete(key)
+}
+
+// NewBatch creates a batch writing directly into the backing database.
+func (b *NodeBuffer) NewBatch() kokdb.Batch {
+	return b.db.NewBatch()
+}
+
+// Close is a noop, the backing database is owned by the caller.
+func (b *NodeBuffer) Close() {}
+
+// Size returns the current storage size of the buffered data.
+func (b *NodeBuffer) Size() common.StorageSize {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	return b.size
+}
+
+// Flush writes all the buffered data into the backing database and empties the
+// buffer. Readers are blocked for the duration of the flush, as the data being
+// written is unavailable until the write completes.
+func (b *NodeBuffer) Flush() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	batch := b.db.NewBatch()
+	for key, value := range b.nodes {
+		if err := batch.Put([]byte(key), value); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= kokdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch = b.db.NewBatch()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	b.flushes++
+	b.flushedNodes += uint64(len(b.nodes))
+	b.flushedSize += b.size
+	b.lastFlush = time.Now()
+
+	b.nodes = make(map[string][]byte)
+	b.size = 0
+	return nil
+}
+
+// Prune writes the buffered data reachable from the given state root to the
+// backing database and discards everything else, apart from the data reachable
+// from any of the retained state roots, which is kept in the buffer. States not
+// referenced by either are lost, unless they were flushed previously.
+func (b *NodeBuffer) Prune(commit common.Hash, retain []common.Hash) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	// Persist the snapshot state, dropping its nodes from the buffer
+	flushed := make(map[string]struct{})
+	if err := b.mark(commit, true, flushed); err != nil {
+		return err
+	}
+	var size common.StorageSize
+
+	batch := b.db.NewBatch()
+	for key := range flushed {
+		value := b.nodes[key]
+		if err := batch.Put([]byte(key), value); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= kokdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch = b.db.NewBatch()
+		}
+		size += common.StorageSize(len(key) + len(value))
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	for key := range flushed {
+		delete(b.nodes, key)
+	}
+	b.size -= size
+
+	b.flushes++
+	b.flushedNodes += uint64(len(flushed))
+	b.flushedSize += size
+	b.lastFlush = time.Now()
+
+	// Collect the data still needed by the retained states and drop the rest
+	retained := make(map[string]struct{})
+	for _, root := range retain {
+		if err := b.mark(root, true, retained); err != nil {
+			return err
+		}
+	}
+	for key, value := range b.nodes {
+		if _, ok := retained[key]; !ok {
+			delete(b.nodes, key)
+			b.size -= common.StorageSize(len(key) + len(value))
+
+			b.prunedNodes++
+			b.prunedSize += common.StorageSize(len(key) + len(value))
+		}
+	}
+	return nil
+}
+
+// mark collects the keys of all the buffered trie nodes reachable from the given
+// root. If the trie is an account trie, the storage tries and contract codes of
+// the accounts are collected too. Subtries already persisted are not traversed,
+// as all their children are guaranteed to be in the database as well.
+//
+// The caller must hold the buffer lock.
+func (b *NodeBuffer) mark(root common.Hash, accounts bool, marked map[string]struct{}) error {
+	if _, ok := b.nodes[string(root[:])]; !ok {
+		return nil
+	}
+	tr, err := trie.New(root, (*lockedBuffer)(b))
+	if err != nil {
+		return err
+	}
+	it := tr.NodeIterator(nil)
+	for descend := true; it.Next(descend); {
+		descend = true
+
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if _, ok := b.nodes[string(hash[:])]; !ok {
+				descend = false
+				continue
+			}
+			marked[string(hash[:])] = struct{}{}
+			continue
+		}
+		if !accounts || !it.Leaf() {
+			continue
+		}
+		var account Account
+		if err := rlp.Decode(bytes.NewReader(it.LeafBlob()), &account); err != nil {
+			return err
+		}
+		if err := b.mark(account.Root, false, marked); err != nil {
+			return err
+		}
+		if !bytes.Equal(account.CodeHash, emptyCodeHash) {
+			if _, ok := b.nodes[string(account.CodeHash)]; ok {
+				marked[string(account.CodeHash)] = struct{}{}
+			}
+		}
+	}
+	return it.Error()
+}
+
+// lockedBuffer is a view of a NodeBuffer whose lock is already held, used to
+// resolve tries while the buffer is being pruned.
+type lockedBuffer NodeBuffer
+
+// Get retrieves a value from the buffer, or the backing database if missing.
+func (b *lockedBuffer) Get(key []byte) ([]byte, error) {
+	if value, ok := b.nodes[string(key)]; ok {
+		return value, nil
+	}
+	return b.db.Get(key)
+}
+
+// Has checks whether a key is present in the buffer or the backing database.
+func (b *lockedBuffer) Has(key []byte) (bool, error) {
+	if _, ok := b.nodes[string(key)]; ok {
+		return true, nil
+	}
+	return b.db.Has(key)
+}
+
+// Put is unsupported, tries are only read while pruning.
+func (b *lockedBuffer) Put(key []byte, value []byte) error {
+	panic("not supported")
+}
+
+// Stats returns the current statistics of the buffer.
+func (b *NodeBuffer) Stats() NodeBufferStats {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	return NodeBufferStats{
+		Nodes:        len(b.nodes),
+		Size:         b.size,
+		Flushes:      b.flushes,
+		FlushedNodes: b.flushedNodes,
+		FlushedSize:  b.flushedSize,
+		LastFlush:    b.lastFlush,
+		PrunedNodes:  b.prunedNodes,
+		PrunedSize:   b.prunedSize,
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/kokdb"
+)
+
+// Tests that states committed into a node buffer are accessible through it, but
+// only reach the backing database when the buffer is flushed.
+func TestNodeBufferFlush(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	buffer := NewNodeBuffer(db)
+
+	state, _ := New(common.Hash{}, NewDatabase(buffer))
+	for i := byte(0); i < 16; i++ {
+		state.AddBalance(common.Address{i}, big.NewInt(int64(i)+1))
+	}
+	root, err := state.CommitTo(buffer, false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if n := len(db.Keys()); n != 0 {
+		t.Fatalf("buffered commit leaked %d entries into the database", n)
+	}
+	if _, err := New(root, NewDatabase(db)); err == nil {
+		t.Fatalf("unflushed state accessible from the database")
+	}
+	if _, err := New(root, NewDatabase(buffer)); err != nil {
+		t.Fatalf("buffered state inaccessible: %v", err)
+	}
+	nodes := buffer.Stats().Nodes
+	if nodes == 0 {
+		t.Fatalf("no nodes buffered")
+	}
+	if err := buffer.Flush(); err != nil {
+		t.Fatalf("failed to flush buffer: %v", err)
+	}
+	stats := buffer.Stats()
+	if stats.Nodes != 0 || stats.Size != 0 {
+		t.Errorf("buffer not emptied: %d nodes, %v", stats.Nodes, stats.Size)
+	}
+	if stats.Flushes != 1 || stats.FlushedNodes != uint64(nodes) {
+		t.Errorf("flush stats mismatch: have %d flushes of %d nodes, want 1 of %d", stats.Flushes, stats.FlushedNodes, nodes)
+	}
+	state, err = New(root, NewDatabase(db))
+	if err != nil {
+		t.Fatalf("flushed state inaccessible: %v", err)
+	}
+	if balance := state.GetBalance(common.Address{15}); balance.Cmp(big.NewInt(16)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 16", balance)
+	}
+}
+
+// Tests that pruning a node buffer persists the snapshot state, keeps the retained
+// states in memory and discards everything else.
+func TestNodeBufferPrune(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	buffer := NewNodeBuffer(db)
+
+	// Create a chain of three states, each modifying a contract of the previous
+	state, _ := New(common.Hash{}, NewDatabase(buffer))
+	for i := byte(0); i < 16; i++ {
+		state.AddBalance(common.Address{i}, big.NewInt(int64(i)+1))
+	}
+	state.SetCode(common.Address{1}, []byte{0x01, 0x02})
+	state.SetState(common.Address{1}, common.Hash{1}, common.Hash{1})
+
+	var roots []common.Hash
+	for i := 0; i < 3; i++ {
+		state.AddBalance(common.Address{byte(i)}, big.NewInt(100))
+		state.SetState(common.Address{1}, common.Hash{2}, common.Hash{byte(i + 1)})
+
+		root, err := state.CommitTo(buffer, false)
+		if err != nil {
+			t.Fatalf("failed to commit state %d: %v", i, err)
+		}
+		roots = append(roots, root)
+		state, _ = New(root, NewDatabase(buffer))
+	}
+	nodes := buffer.Stats().Nodes
+
+	// Persist the first state, retain the last one and ensure the middle is dropped
+	if err := buffer.Prune(roots[0], roots[2:]); err != nil {
+		t.Fatalf("failed to prune buffer: %v", err)
+	}
+	stats := buffer.Stats()
+	if stats.FlushedNodes == 0 || stats.PrunedNodes == 0 {
+		t.Fatalf("prune stats mismatch: %d flushed, %d pruned", stats.FlushedNodes, stats.PrunedNodes)
+	}
+	if stats.Nodes+int(stats.FlushedNodes)+int(stats.PrunedNodes) != nodes {
+		t.Errorf("node count mismatch: %d buffered, %d flushed, %d pruned, want %d total", stats.Nodes, stats.FlushedNodes, stats.PrunedNodes, nodes)
+	}
+	persisted, err := New(roots[0], NewDatabase(db))
+	if err != nil {
+		t.Fatalf("snapshot state inaccessible from the database: %v", err)
+	}
+	if code := persisted.GetCode(common.Address{1}); len(code) != 2 {
+		t.Errorf("snapshot code mismatch: have %x, want 0102", code)
+	}
+	if value := persisted.GetState(common.Address{1}, common.Hash{2}); value != (common.Hash{1}) {
+		t.Errorf("snapshot storage mismatch: have %x, want %x", value, common.Hash{1})
+	}
+	if err := checkStateConsistency(buffer, roots[2]); err != nil {
+		t.Errorf("retained state incomplete: %v", err)
+	}
+	if _, err := New(roots[1], NewDatabase(buffer)); err == nil {
+		t.Errorf("pruned state still accessible")
+	}
+}
@@ -29,11 +29,12 @@ import (
 // Trie cache generation limit after which to evic trie nodes from memory.
 var MaxTrieCacheGen = uint16(120)
 
-const (
-	// Number of past tries to keep. This value is chosen such that
-	// reasonable chain reorg depths will hit an existing trie.
-	maxPastTries = 12
+// Number of past tries to keep in memory before they are dereferenced. The
+// default value is chosen such that reasonable chain reorg depths will hit an
+// existing trie.
+var MaxPastTries = 12
 
+const (
 	// Number of codehash->size associations to keep.
 	codeSizeCacheSize = 100000
 )
@@ -97,8 +98,12 @@ func (db *cachingDB) pushTrie(t *trie.SecureTrie) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	if len(db.pastTries) >= maxPastTries {
-		copy(db.pastTries, db.pastTries[1:])
+	if MaxPastTries <= 0 {
+		return
+	}
+	if len(db.pastTries) >= MaxPastTries {
+		copy(db.pastTries, db.pastTries[len(db.pastTries)-MaxPastTries+1:])
+		db.pastTries = db.pastTries[:MaxPastTries]
 		db.pastTries[len(db.pastTries)-1] = t
 	} else {
 		db.pastTries = append(db.pastTries, t)
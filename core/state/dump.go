@@ -71,6 +71,58 @@ func (self *StateDB) RawDump() Dump {
 	return dump
 }
 
+// RangeAccount is a single account of an AccountRange.
+type RangeAccount struct {
+	Address  *common.Address `json:"address"` // nil if the preimage of the account key is unknown
+	Balance  string          `json:"balance"`
+	Nonce    uint64          `json:"nonce"`
+	Root     common.Hash     `json:"root"`
+	CodeHash common.Hash     `json:"codeHash"`
+}
+
+// AccountRange is a page of the accounts of a state, keyed and ordered by the
+// hash of their address.
+type AccountRange struct {
+	Accounts map[common.Hash]RangeAccount `json:"accounts"`
+	Next     *common.Hash                 `json:"next"` // nil if the range includes the last account
+}
+
+// AccountRange returns at most maxResults accounts of the state, starting at the
+// given account key (address hash). The ordering of the keys is fixed for a given
+// state root, so a full state can be retrieved page by page, continuing from the
+// next key returned by the previous call.
+func (self *StateDB) AccountRange(start []byte, maxResults int) (AccountRange, error) {
+	result := AccountRange{Accounts: make(map[common.Hash]RangeAccount)}
+
+	it := trie.NewIterator(self.trie.NodeIterator(start))
+	for i := 0; i < maxResults && it.Next(); i++ {
+		var data Account
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			return AccountRange{}, err
+		}
+		account := RangeAccount{
+			Balance:  data.Balance.String(),
+			Nonce:    data.Nonce,
+			Root:     data.Root,
+			CodeHash: common.BytesToHash(data.CodeHash),
+		}
+		if preimage := self.trie.GetKey(it.Key); preimage != nil {
+			addr := common.BytesToAddress(preimage)
+			account.Address = &addr
+		}
+		result.Accounts[common.BytesToHash(it.Key)] = account
+	}
+	if it.Err != nil {
+		return AccountRange{}, it.Err
+	}
+	// Add the next key so clients can continue downloading
+	if it.Next() {
+		next := common.BytesToHash(it.Key)
+		result.Next = &next
+	}
+	return result, nil
+}
+
 func (self *StateDB) Dump() []byte {
 	json, err := json.MarshalIndent(self.RawDump(), "", "    ")
 	if err != nil {
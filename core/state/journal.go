@@ -22,119 +22,209 @@ import (
 	"github.com/kokprojects/go-kok/common"
 )
 
-type journalEntry interface {
-	undo(*StateDB)
-}
-
-type journal []journalEntry
+// journalKind is the type of a state modification tracked by the journal.
+type journalKind uint8
 
-type (
+const (
 	// Changes to the account trie.
-	createObjectChange struct {
-		account *common.Address
-	}
-	resetObjectChange struct {
-		prev *stateObject
-	}
-	suicideChange struct {
-		account     *common.Address
-		prev        bool // whkoker account had already suicided
-		prevbalance *big.Int
-	}
+	createObjectChange journalKind = iota
+	resetObjectChange
+	suicideChange
 
 	// Changes to individual accounts.
-	balanceChange struct {
-		account *common.Address
-		prev    *big.Int
-	}
-	nonceChange struct {
-		account *common.Address
-		prev    uint64
-	}
-	storageChange struct {
-		account       *common.Address
-		key, prevalue common.Hash
-	}
-	codeChange struct {
-		account            *common.Address
-		prevcode, prevhash []byte
-	}
+	balanceChange
+	nonceChange
+	storageChange
+	codeChange
+	touchChange
 
 	// Changes to other state values.
-	refundChange struct {
-		prev *big.Int
-	}
-	addLogChange struct {
-		txhash common.Hash
-	}
-	addPreimageChange struct {
-		hash common.Hash
-	}
-	touchChange struct {
-		account   *common.Address
-		prev      bool
-		prevDirty bool
-	}
+	refundChange
+	addLogChange
+	addPreimageChange
+	addInternalTxChange
+	addCreationChange
 )
 
-func (ch createObjectChange) undo(s *StateDB) {
-	delete(s.stateObjects, *ch.account)
-	delete(s.stateObjectsDirty, *ch.account)
+// dirties reports whether changes of this kind modify an account, which needs
+// to be finalised at the end of the transaction.
+func (k journalKind) dirties() bool {
+	return k < refundChange
 }
 
-func (ch resetObjectChange) undo(s *StateDB) {
-	s.setStateObject(ch.prev)
+// journalEntry is a single state modification, holding the data needed to
+// revert it. Entries are stored by value in the journal and reused across
+// transactions, together with their big integer buffers, so recording a change
+// doesn't allocate once the journal has warmed up.
+type journalEntry struct {
+	kind    journalKind
+	account common.Address // Account modified by the change, if any
+
+	prevObject *stateObject // Previous object of a resetObjectChange
+	prevInt    *big.Int     // Previous balance or refund, owned by the entry
+	prevNonce  uint64       // Previous nonce of a nonceChange
+	prevFlag   bool         // Previous suicided or touched flag
+	prevDirty  bool         // Whether the account was already dirty before a touchChange
+	hash       common.Hash  // Storage key, preimage hash or transaction hash of a log or record
+	prevValue  common.Hash  // Previous storage value of a storageChange
+	prevCode   []byte       // Previous code of a codeChange
+	prevHash   []byte       // Previous code hash of a codeChange
 }
 
-func (ch suicideChange) undo(s *StateDB) {
-	obj := s.getStateObject(*ch.account)
-	if obj != nil {
-		obj.suicided = ch.prev
-		obj.setBalance(ch.prevbalance)
+// setPrevInt stores a copy of a big integer in the entry's own buffer.
+func (e *journalEntry) setPrevInt(x *big.Int) {
+	if e.prevInt == nil {
+		e.prevInt = new(big.Int)
 	}
+	e.prevInt.Set(x)
 }
 
-var ripemd = common.HexToAddress("0000000000000000000000000000000000000003")
+// journal contains the list of state modifications applied since the last state
+// commit. These are tracked to be able to be reverted in case of an execution
+// exception or revertal request.
+type journal struct {
+	entries []journalEntry         // Current changes tracked by the journal
+	dirties map[common.Address]int // Dirty accounts and the number of changes
+}
 
-func (ch touchChange) undo(s *StateDB) {
-	if !ch.prev && *ch.account != ripemd {
-		s.getStateObject(*ch.account).touched = ch.prev
-		if !ch.prevDirty {
-			delete(s.stateObjectsDirty, *ch.account)
-		}
+// newJournal creates a new initialized journal.
+func newJournal() *journal {
+	return &journal{
+		dirties: make(map[common.Address]int),
 	}
 }
 
-func (ch balanceChange) undo(s *StateDB) {
-	s.getStateObject(*ch.account).setBalance(ch.prev)
-}
+// append reserves a new entry at the end of the journal and returns it for the
+// caller to fill in. The returned pointer is only valid until the next append.
+func (j *journal) append(kind journalKind, account common.Address) *journalEntry {
+	if len(j.entries) < cap(j.entries) {
+		j.entries = j.entries[:len(j.entries)+1]
+	} else {
+		j.entries = append(j.entries, journalEntry{})
+	}
+	entry := &j.entries[len(j.entries)-1]
+	*entry = journalEntry{kind: kind, account: account, prevInt: entry.prevInt}
 
-func (ch nonceChange) undo(s *StateDB) {
-	s.getStateObject(*ch.account).setNonce(ch.prev)
+	if kind.dirties() {
+		j.dirties[account]++
+	}
+	return entry
 }
 
-func (ch codeChange) undo(s *StateDB) {
-	s.getStateObject(*ch.account).setCode(common.BytesToHash(ch.prevhash), ch.prevcode)
+// revert undoes a batch of journalled modifications along with any reverted
+// dirty handling too.
+func (j *journal) revert(statedb *StateDB, snapshot int) {
+	for i := len(j.entries) - 1; i >= snapshot; i-- {
+		entry := &j.entries[i]
+		entry.undo(statedb)
+
+		// Drop any dirty tracking induced by the change
+		if entry.kind.dirties() {
+			if j.dirties[entry.account]--; j.dirties[entry.account] == 0 {
+				delete(j.dirties, entry.account)
+			}
+		}
+		entry.release()
+	}
+	j.entries = j.entries[:snapshot]
 }
 
-func (ch storageChange) undo(s *StateDB) {
-	s.getStateObject(*ch.account).setState(ch.key, ch.prevalue)
+// dirty explicitly sets an address to dirty, even if the change entries would
+// otherwise suggest it as clean. This mkokod is an ugly hack to handle the RIPEMD
+// precompile consensus exception.
+func (j *journal) dirty(addr common.Address) {
+	j.dirties[addr]++
 }
 
-func (ch refundChange) undo(s *StateDB) {
-	s.refund = ch.prev
+// length returns the current number of entries in the journal.
+func (j *journal) length() int {
+	return len(j.entries)
 }
 
-func (ch addLogChange) undo(s *StateDB) {
-	logs := s.logs[ch.txhash]
-	if len(logs) == 1 {
-		delete(s.logs, ch.txhash)
-	} else {
-		s.logs[ch.txhash] = logs[:len(logs)-1]
+// reset clears the journal, retaining the allocated entries for reuse.
+func (j *journal) reset() {
+	for i := range j.entries {
+		j.entries[i].release()
 	}
-	s.logSize--
+	j.entries = j.entries[:0]
+	for addr := range j.dirties {
+		delete(j.dirties, addr)
+	}
+}
+
+// release drops the references held by a discarded entry, keeping only its
+// reusable buffers.
+func (e *journalEntry) release() {
+	e.prevObject, e.prevCode, e.prevHash = nil, nil, nil
 }
 
-func (ch addPreimageChange) undo(s *StateDB) {
-	delete(s.preimages, ch.hash)
+var ripemd = common.HexToAddress("0000000000000000000000000000000000000003")
+
+// undo reverts the state modification recorded by the entry.
+func (e *journalEntry) undo(s *StateDB) {
+	switch e.kind {
+	case createObjectChange:
+		delete(s.stateObjects, e.account)
+		delete(s.stateObjectsDirty, e.account)
+
+	case resetObjectChange:
+		s.setStateObject(e.prevObject)
+
+	case suicideChange:
+		if obj := s.getStateObject(e.account); obj != nil {
+			obj.suicided = e.prevFlag
+			obj.setBalance(new(big.Int).Set(e.prevInt))
+		}
+
+	case touchChange:
+		if !e.prevFlag && e.account != ripemd {
+			s.getStateObject(e.account).touched = e.prevFlag
+			if !e.prevDirty {
+				delete(s.stateObjectsDirty, e.account)
+			}
+		}
+
+	case balanceChange:
+		s.getStateObject(e.account).setBalance(new(big.Int).Set(e.prevInt))
+
+	case nonceChange:
+		s.getStateObject(e.account).setNonce(e.prevNonce)
+
+	case codeChange:
+		s.getStateObject(e.account).setCode(common.BytesToHash(e.prevHash), e.prevCode)
+
+	case storageChange:
+		s.getStateObject(e.account).setState(e.hash, e.prevValue)
+
+	case refundChange:
+		s.refund = new(big.Int).Set(e.prevInt)
+
+	case addLogChange:
+		logs := s.logs[e.hash]
+		if len(logs) == 1 {
+			delete(s.logs, e.hash)
+		} else {
+			s.logs[e.hash] = logs[:len(logs)-1]
+		}
+		s.logSize--
+
+	case addPreimageChange:
+		delete(s.preimages, e.hash)
+
+	case addInternalTxChange:
+		txs := s.internalTxs[e.hash]
+		if len(txs) == 1 {
+			delete(s.internalTxs, e.hash)
+		} else {
+			s.internalTxs[e.hash] = txs[:len(txs)-1]
+		}
+
+	case addCreationChange:
+		creations := s.creations[e.hash]
+		if len(creations) == 1 {
+			delete(s.creations, e.hash)
+		} else {
+			s.creations[e.hash] = creations[:len(creations)-1]
+		}
+	}
 }
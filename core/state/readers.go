@@ -0,0 +1,92 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"sync"
+
+	"github.com/kokprojects/go-kok/common"
+)
+
+// ReaderSet reference counts the state roots in use by read-only readers running
+// concurrently with block import, such that any removal of trie nodes can retain
+// the ones still being accessed.
+type ReaderSet struct {
+	refs map[common.Hash]int
+	lock sync.Mutex
+}
+
+// NewReaderSet creates an empty set of state readers.
+func NewReaderSet() *ReaderSet {
+	return &ReaderSet{refs: make(map[common.Hash]int)}
+}
+
+// Open creates a new state on top of the given root, referencing the root until
+// the returned release function is called. The state is independent of any other,
+// so it may be read while new states are imported into the same database, but it
+// should not be committed.
+func (s *ReaderSet) Open(root common.Hash, db Database) (*StateDB, func(), error) {
+	s.lock.Lock()
+	s.refs[root]++
+	s.lock.Unlock()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			s.lock.Lock()
+			defer s.lock.Unlock()
+
+			if s.refs[root]--; s.refs[root] <= 0 {
+				delete(s.refs, root)
+			}
+		})
+	}
+	statedb, err := New(root, db)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return statedb, release, nil
+}
+
+// InUse reports whether the given state root is referenced by any reader.
+func (s *ReaderSet) InUse(root common.Hash) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.refs[root] > 0
+}
+
+// Roots returns the state roots currently referenced by readers.
+func (s *ReaderSet) Roots() []common.Hash {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	roots := make([]common.Hash, 0, len(s.refs))
+	for root := range s.refs {
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// Len returns the number of state roots referenced by readers.
+func (s *ReaderSet) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.refs)
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/kokdb"
+)
+
+// Tests that state readers reference their roots until released, and that they
+// keep reading their own state while newer states are committed.
+func TestReaderSet(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+
+	state, _ := New(common.Hash{}, sdb)
+	state.AddBalance(common.Address{1}, big.NewInt(1))
+	root, _ := state.CommitTo(db, false)
+
+	readers := NewReaderSet()
+	reader, release, err := readers.Open(root, sdb)
+	if err != nil {
+		t.Fatalf("failed to open reader: %v", err)
+	}
+	_, release2, _ := readers.Open(root, sdb)
+	if !readers.InUse(root) || readers.Len() != 1 {
+		t.Fatalf("root not referenced after opening readers")
+	}
+	// Import a newer state and ensure the reader is unaffected
+	state, _ = New(root, sdb)
+	state.AddBalance(common.Address{1}, big.NewInt(1))
+	if _, err := state.CommitTo(db, false); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if balance := reader.GetBalance(common.Address{1}); balance.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("reader balance mismatch: have %v, want 1", balance)
+	}
+	// Release the readers, repeatedly for the first, and ensure the root is freed
+	release()
+	release()
+	if !readers.InUse(root) {
+		t.Fatalf("root released while still referenced")
+	}
+	release2()
+	if readers.InUse(root) || readers.Len() != 0 {
+		t.Fatalf("root referenced after releasing all readers")
+	}
+	if _, _, err := readers.Open(common.Hash{0x01}, sdb); err == nil || readers.Len() != 0 {
+		t.Errorf("missing root opened or left referenced: err %v, roots %d", err, readers.Len())
+	}
+}
@@ -145,11 +145,14 @@ func (self *stateObject) markSuicided() {
 }
 
 func (c *stateObject) touch() {
-	c.db.journal = append(c.db.journal, touchChange{
-		account:   &c.address,
-		prev:      c.touched,
-		prevDirty: c.onDirty == nil,
-	})
+	entry := c.db.journal.append(touchChange, c.address)
+	entry.prevFlag = c.touched
+	entry.prevDirty = c.onDirty == nil
+
+	if c.address == ripemd {
+		// Explicitly put it in the dirty-cache, as the touch is not reverted
+		c.db.journal.dirty(c.address)
+	}
 	if c.onDirty != nil {
 		c.onDirty(c.Address())
 		c.onDirty = nil
@@ -175,8 +178,23 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 	if exists {
 		return value
 	}
+	return self.loadState(db, key)
+}
+
+// loadState retrieves a storage value from the trie and caches it. It's split
+// out of GetState to keep the key from being moved to the heap on cache hits.
+func (self *stateObject) loadState(db Database, key common.Hash) common.Hash {
+	var value common.Hash
+
+	span := self.db.traceLoad("state.loadStorage", self.address)
+	defer span.End()
+	if span != nil {
+		span.SetAttribute("key", key.Hex())
+	}
+
 	// Load from DB in case it is missing.
 	enc, err := self.getTrie(db).TryGet(key[:])
+	span.SetError(err)
 	if err != nil {
 		self.setError(err)
 		return common.Hash{}
@@ -196,11 +214,12 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 
 // SetState updates a value in account storage.
 func (self *stateObject) SetState(db Database, key, value common.Hash) {
-	self.db.journal = append(self.db.journal, storageChange{
-		account:  &self.address,
-		key:      key,
-		prevalue: self.GetState(db, key),
-	})
+	prev := self.GetState(db, key)
+
+	entry := self.db.journal.append(storageChange, self.address)
+	entry.hash = key
+	entry.prevValue = prev
+
 	self.setState(key, value)
 }
 
@@ -275,10 +294,7 @@ func (c *stateObject) SubBalance(amount *big.Int) {
 }
 
 func (self *stateObject) SetBalance(amount *big.Int) {
-	self.db.journal = append(self.db.journal, balanceChange{
-		account: &self.address,
-		prev:    new(big.Int).Set(self.data.Balance),
-	})
+	self.db.journal.append(balanceChange, self.address).setPrevInt(self.data.Balance)
 	self.setBalance(amount)
 }
 
@@ -324,7 +340,11 @@ func (self *stateObject) Code(db Database) []byte {
 	if bytes.Equal(self.CodeHash(), emptyCodeHash) {
 		return nil
 	}
+	span := self.db.traceLoad("state.loadCode", self.address)
+	defer span.End()
+
 	code, err := db.ContractCode(self.addrHash, common.BytesToHash(self.CodeHash()))
+	span.SetError(err)
 	if err != nil {
 		self.setError(fmt.Errorf("can't load code hash %x: %v", self.CodeHash(), err))
 	}
@@ -334,11 +354,10 @@ func (self *stateObject) Code(db Database) []byte {
 
 func (self *stateObject) SetCode(codeHash common.Hash, code []byte) {
 	prevcode := self.Code(self.db.db)
-	self.db.journal = append(self.db.journal, codeChange{
-		account:  &self.address,
-		prevhash: self.CodeHash(),
-		prevcode: prevcode,
-	})
+	entry := self.db.journal.append(codeChange, self.address)
+	entry.prevHash = self.CodeHash()
+	entry.prevCode = prevcode
+
 	self.setCode(codeHash, code)
 }
 
@@ -353,10 +372,7 @@ func (self *stateObject) setCode(codeHash common.Hash, code []byte) {
 }
 
 func (self *stateObject) SetNonce(nonce uint64) {
-	self.db.journal = append(self.db.journal, nonceChange{
-		account: &self.address,
-		prev:    self.data.Nonce,
-	})
+	self.db.journal.append(nonceChange, self.address).prevNonce = self.data.Nonce
 	self.setNonce(nonce)
 }
 
@@ -91,6 +91,51 @@ func (s *StateSuite) SetUpTest(c *checker.C) {
 	s.state, _ = New(common.Hash{}, NewDatabase(s.db))
 }
 
+func TestAccountRange(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	for i := byte(1); i <= 5; i++ {
+		state.SetBalance(toAddr([]byte{i}), big.NewInt(int64(i)))
+	}
+	root, _ := state.CommitTo(db, false)
+	state, _ = New(root, NewDatabase(db))
+
+	// Page through the accounts and ensure all of them are returned exactly once
+	var (
+		seen  = make(map[common.Address]bool)
+		start []byte
+	)
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("too many pages")
+		}
+		result, err := state.AccountRange(start, 2)
+		if err != nil {
+			t.Fatalf("failed to retrieve account range: %v", err)
+		}
+		for hash, account := range result.Accounts {
+			if account.Address == nil {
+				t.Fatalf("account %x: missing address", hash)
+			}
+			if hash != crypto.Keccak256Hash(account.Address[:]) {
+				t.Errorf("account %x: key mismatch for address %x", hash, *account.Address)
+			}
+			if seen[*account.Address] {
+				t.Errorf("account %x: returned multiple times", *account.Address)
+			}
+			seen[*account.Address] = true
+		}
+		if result.Next == nil {
+			break
+		}
+		start = result.Next[:]
+	}
+	if len(seen) != 5 {
+		t.Fatalf("account count mismatch: have %d, want %d", len(seen), 5)
+	}
+}
+
 func (s *StateSuite) TestNull(c *checker.C) {
 	address := common.HexToAddress("0x823140710bf13990e4500136726d8b55")
 	s.state.CreateAccount(address)
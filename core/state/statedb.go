@@ -18,6 +18,7 @@
 package state
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"sort"
@@ -28,6 +29,7 @@ import (
 	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/rlp"
+	"github.com/kokprojects/go-kok/tracing"
 	"github.com/kokprojects/go-kok/trie"
 )
 
@@ -66,12 +68,24 @@ type StateDB struct {
 
 	preimages map[common.Hash][]byte
 
+	internalTxs map[common.Hash][]*types.InternalTx
+	creations   map[common.Hash][]*types.ContractCreation
+
+	// Balance tracking, enabled if balanceOrigins is non-nil
+	balanceOrigins    map[common.Address]*big.Int
+	balanceChanges    []*TxBalanceChanges
+	balanceTxOpen     bool
+	balanceDestructed bool
+
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
-	journal        journal
+	journal        *journal
 	validRevisions []revision
 	nextRevisionId int
 
+	// Context of the trace the database loads are recorded in, nil if untraced
+	traceCtx context.Context
+
 	lock sync.Mutex
 }
 
@@ -89,6 +103,9 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 		refund:            new(big.Int),
 		logs:              make(map[common.Hash][]*types.Log),
 		preimages:         make(map[common.Hash][]byte),
+		internalTxs:       make(map[common.Hash][]*types.InternalTx),
+		creations:         make(map[common.Hash][]*types.ContractCreation),
+		journal:           newJournal(),
 	}, nil
 }
 
@@ -103,6 +120,25 @@ func (self *StateDB) Error() error {
 	return self.dbErr
 }
 
+// SetTraceContext records the accounts, storage slots and code subsequently
+// loaded from the database as spans of the trace in the given context. Values
+// already cached in the state aren't recorded, as they don't touch the disk.
+func (self *StateDB) SetTraceContext(ctx context.Context) {
+	self.traceCtx = ctx
+}
+
+// traceLoad opens a span for a database load if the state is traced. The span
+// is nil, and safe to use, if it isn't. Detached state objects (e.g. of dumps)
+// have no state, so a nil receiver is allowed.
+func (self *StateDB) traceLoad(name string, addr common.Address) *tracing.Span {
+	if self == nil || self.traceCtx == nil {
+		return nil
+	}
+	_, span := tracing.Start(self.traceCtx, name)
+	span.SetAttribute("address", addr.Hex())
+	return span
+}
+
 // Reset clears out all emphemeral state objects from the state db, but keeps
 // the underlying state trie to avoid reloading data for the next operations.
 func (self *StateDB) Reset(root common.Hash) error {
@@ -118,13 +154,21 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.txIndex = 0
 	self.logs = make(map[common.Hash][]*types.Log)
 	self.logSize = 0
+	if self.balanceOrigins != nil {
+		self.balanceOrigins = make(map[common.Address]*big.Int)
+	}
+	self.balanceChanges = nil
+	self.balanceTxOpen = false
+	self.balanceDestructed = false
 	self.preimages = make(map[common.Hash][]byte)
+	self.internalTxs = make(map[common.Hash][]*types.InternalTx)
+	self.creations = make(map[common.Hash][]*types.ContractCreation)
 	self.clearJournalAndRefund()
 	return nil
 }
 
 func (self *StateDB) AddLog(log *types.Log) {
-	self.journal = append(self.journal, addLogChange{txhash: self.thash})
+	self.journal.append(addLogChange, common.Address{}).hash = self.thash
 
 	log.TxHash = self.thash
 	log.BlockHash = self.bhash
@@ -149,7 +193,7 @@ func (self *StateDB) Logs() []*types.Log {
 // AddPreimage records a SHA3 preimage seen by the VM.
 func (self *StateDB) AddPreimage(hash common.Hash, preimage []byte) {
 	if _, ok := self.preimages[hash]; !ok {
-		self.journal = append(self.journal, addPreimageChange{hash: hash})
+		self.journal.append(addPreimageChange, common.Address{}).hash = hash
 		pi := make([]byte, len(preimage))
 		copy(pi, preimage)
 		self.preimages[hash] = pi
@@ -161,8 +205,34 @@ func (self *StateDB) Preimages() map[common.Hash][]byte {
 	return self.preimages
 }
 
+// AddInternalTx records a value transfer made by a contract during the current
+// transaction.
+func (self *StateDB) AddInternalTx(tx *types.InternalTx) {
+	self.journal.append(addInternalTxChange, common.Address{}).hash = self.thash
+	self.internalTxs[self.thash] = append(self.internalTxs[self.thash], tx)
+}
+
+// InternalTxs returns the value transfers made by contracts during the given
+// transaction.
+func (self *StateDB) InternalTxs(hash common.Hash) []*types.InternalTx {
+	return self.internalTxs[hash]
+}
+
+// AddContractCreation records a contract deployed during the current transaction.
+func (self *StateDB) AddContractCreation(creation *types.ContractCreation) {
+	self.journal.append(addCreationChange, common.Address{}).hash = self.thash
+
+	creation.TxHash = self.thash
+	self.creations[self.thash] = append(self.creations[self.thash], creation)
+}
+
+// ContractCreations returns the contracts deployed during the given transaction.
+func (self *StateDB) ContractCreations(hash common.Hash) []*types.ContractCreation {
+	return self.creations[hash]
+}
+
 func (self *StateDB) AddRefund(gas *big.Int) {
-	self.journal = append(self.journal, refundChange{prev: new(big.Int).Set(self.refund)})
+	self.journal.append(refundChange, common.Address{}).setPrevInt(self.refund)
 	self.refund.Add(self.refund, gas)
 }
 
@@ -261,6 +331,7 @@ func (self *StateDB) HasSuicided(addr common.Address) bool {
 
 // AddBalance adds amount to the account associated with addr
 func (self *StateDB) AddBalance(addr common.Address, amount *big.Int) {
+	self.trackBalance(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.AddBalance(amount)
@@ -269,6 +340,7 @@ func (self *StateDB) AddBalance(addr common.Address, amount *big.Int) {
 
 // SubBalance subtracts amount from the account associated with addr
 func (self *StateDB) SubBalance(addr common.Address, amount *big.Int) {
+	self.trackBalance(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SubBalance(amount)
@@ -276,6 +348,7 @@ func (self *StateDB) SubBalance(addr common.Address, amount *big.Int) {
 }
 
 func (self *StateDB) SetBalance(addr common.Address, amount *big.Int) {
+	self.trackBalance(addr)
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetBalance(amount)
@@ -313,11 +386,12 @@ func (self *StateDB) Suicide(addr common.Address) bool {
 	if stateObject == nil {
 		return false
 	}
-	self.journal = append(self.journal, suicideChange{
-		account:     &addr,
-		prev:        stateObject.suicided,
-		prevbalance: new(big.Int).Set(stateObject.Balance()),
-	})
+	self.trackSuicide(addr)
+
+	entry := self.journal.append(suicideChange, addr)
+	entry.prevFlag = stateObject.suicided
+	entry.setPrevInt(stateObject.Balance())
+
 	stateObject.markSuicided()
 	stateObject.data.Balance = new(big.Int)
 
@@ -354,9 +428,18 @@ func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObje
 		}
 		return obj
 	}
+	return self.loadStateObject(addr)
+}
+
+// loadStateObject retrieves a state object from the database and inserts it into
+// the live set. It's split out of getStateObject to keep the address from being
+// moved to the heap when the object is already live.
+func (self *StateDB) loadStateObject(addr common.Address) *stateObject {
+	span := self.traceLoad("state.loadAccount", addr)
+	defer span.End()
 
-	// Load the object from the database.
 	enc, err := self.trie.TryGet(addr[:])
+	span.SetError(err)
 	if len(enc) == 0 {
 		self.setError(err)
 		return nil
@@ -398,9 +481,9 @@ func (self *StateDB) createObject(addr common.Address) (newobj, prev *stateObjec
 	newobj = newObject(self, addr, Account{}, self.MarkStateObjectDirty)
 	newobj.setNonce(0) // sets the object to dirty
 	if prev == nil {
-		self.journal = append(self.journal, createObjectChange{account: &addr})
+		self.journal.append(createObjectChange, addr)
 	} else {
-		self.journal = append(self.journal, resetObjectChange{prev: prev})
+		self.journal.append(resetObjectChange, addr).prevObject = prev
 	}
 	self.setStateObject(newobj)
 	return newobj, prev
@@ -460,12 +543,24 @@ func (self *StateDB) Copy() *StateDB {
 		logs:              make(map[common.Hash][]*types.Log, len(self.logs)),
 		logSize:           self.logSize,
 		preimages:         make(map[common.Hash][]byte),
+		internalTxs:       make(map[common.Hash][]*types.InternalTx, len(self.internalTxs)),
+		creations:         make(map[common.Hash][]*types.ContractCreation, len(self.creations)),
+		journal:           newJournal(),
 	}
 	// Copy the dirty states, logs, and preimages
 	for addr := range self.stateObjectsDirty {
 		state.stateObjects[addr] = self.stateObjects[addr].deepCopy(state, state.MarkStateObjectDirty)
 		state.stateObjectsDirty[addr] = struct{}{}
 	}
+	// Objects modified by the current transaction still need finalising in the copy
+	for addr := range self.journal.dirties {
+		if object, exist := self.stateObjects[addr]; exist {
+			if _, copied := state.stateObjects[addr]; !copied {
+				state.stateObjects[addr] = object.deepCopy(state, state.MarkStateObjectDirty)
+			}
+			state.journal.dirty(addr)
+		}
+	}
 	for hash, logs := range self.logs {
 		state.logs[hash] = make([]*types.Log, len(logs))
 		copy(state.logs[hash], logs)
@@ -473,6 +568,21 @@ func (self *StateDB) Copy() *StateDB {
 	for hash, preimage := range self.preimages {
 		state.preimages[hash] = preimage
 	}
+	for hash, txs := range self.internalTxs {
+		state.internalTxs[hash] = append([]*types.InternalTx(nil), txs...)
+	}
+	for hash, creations := range self.creations {
+		state.creations[hash] = append([]*types.ContractCreation(nil), creations...)
+	}
+	if self.balanceOrigins != nil {
+		state.balanceOrigins = make(map[common.Address]*big.Int, len(self.balanceOrigins))
+		for addr, balance := range self.balanceOrigins {
+			state.balanceOrigins[addr] = balance
+		}
+		state.balanceChanges = append([]*TxBalanceChanges(nil), self.balanceChanges...)
+		state.balanceTxOpen = self.balanceTxOpen
+		state.balanceDestructed = self.balanceDestructed
+	}
 	return state
 }
 
@@ -480,7 +590,7 @@ func (self *StateDB) Copy() *StateDB {
 func (self *StateDB) Snapshot() int {
 	id := self.nextRevisionId
 	self.nextRevisionId++
-	self.validRevisions = append(self.validRevisions, revision{id, len(self.journal)})
+	self.validRevisions = append(self.validRevisions, revision{id, self.journal.length()})
 	return id
 }
 
@@ -496,10 +606,7 @@ func (self *StateDB) RevertToSnapshot(revid int) {
 	snapshot := self.validRevisions[idx].journalIndex
 
 	// Replay the journal to undo changes.
-	for i := len(self.journal) - 1; i >= snapshot; i-- {
-		self.journal[i].undo(self)
-	}
-	self.journal = self.journal[:snapshot]
+	self.journal.revert(self, snapshot)
 
 	// Remove invalidated snapshots from the stack.
 	self.validRevisions = self.validRevisions[:idx]
@@ -515,8 +622,14 @@ func (self *StateDB) GetRefund() *big.Int {
 // Finalise finalises the state by removing the self destructed objects
 // and clears the journal as well as the refunds.
 func (s *StateDB) Finalise(deleteEmptyObjects bool) {
-	for addr := range s.stateObjectsDirty {
-		stateObject := s.stateObjects[addr]
+	// Only the objects modified since the last finalisation need updating
+	for addr := range s.journal.dirties {
+		stateObject, exist := s.stateObjects[addr]
+		if !exist {
+			// The RIPEMD touch exception may leave an address dirty without an
+			// object, if the touch itself was part of an out-of-gas revert.
+			continue
+		}
 		if stateObject.suicided || (deleteEmptyObjects && stateObject.empty()) {
 			s.deleteStateObject(stateObject)
 		} else {
@@ -539,11 +652,19 @@ func (s *StateDB) IntermediateRoot(deleteEmptyObjects bool) (h common.Hash) {
 // Prepare sets the current transaction hash and index and block hash which is
 // used when the EVM emits new state logs.
 func (self *StateDB) Prepare(thash, bhash common.Hash, ti int) {
+	self.FinaliseBalanceChanges()
+	self.balanceTxOpen = true
+
 	self.thash = thash
 	self.bhash = bhash
 	self.txIndex = ti
 }
 
+// TxIndex returns the current transaction index set by Prepare.
+func (self *StateDB) TxIndex() int {
+	return self.txIndex
+}
+
 // DeleteSuicides flags the suicided objects for deletion so that it
 // won't be referenced again when called / queried up on.
 //
@@ -566,7 +687,7 @@ func (s *StateDB) DeleteSuicides() {
 }
 
 func (s *StateDB) clearJournalAndRefund() {
-	s.journal = nil
+	s.journal.reset()
 	s.validRevisions = s.validRevisions[:0]
 	s.refund = new(big.Int)
 }
@@ -117,6 +117,34 @@ func TestIntermediateLeaks(t *testing.T) {
 	}
 }
 
+// Tests that journal entries are reused across transactions, so snapshotting and
+// reverting changes doesn't allocate once the journal has warmed up.
+func TestJournalReuse(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	addr := common.BytesToAddress([]byte("journal"))
+	state.SetNonce(addr, 1)
+	state.SetState(addr, common.Hash{1}, common.Hash{1})
+
+	execute := func() {
+		snapshot := state.Snapshot()
+		for i := 0; i < 16; i++ {
+			state.SetNonce(addr, uint64(i))
+			state.SetState(addr, common.Hash{1}, common.Hash{byte(i)})
+		}
+		state.RevertToSnapshot(snapshot)
+	}
+	execute()
+
+	if allocs := testing.AllocsPerRun(100, execute); allocs > 0 {
+		t.Errorf("journaling allocated: have %v allocations per run, want 0", allocs)
+	}
+	if nonce := state.GetNonce(addr); nonce != 1 {
+		t.Errorf("nonce mismatch after revert: have %d, want 1", nonce)
+	}
+}
+
 func TestSnapshotRandom(t *testing.T) {
 	config := &quick.Config{MaxCount: 1000}
 	err := quick.Check((*snapshotTest).run, config)
@@ -372,3 +400,48 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+// Tests that balance changes are attributed to the transactions making them,
+// with reverted changes and changes outside of transactions being ignored.
+func TestBalanceTracking(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+	state.EnableBalanceTracking()
+
+	var (
+		a, b   = common.Address{0x01}, common.Address{0x02}
+		tx1    = common.Hash{0x01}
+		tx2    = common.Hash{0x02}
+		reward = common.Address{0x03}
+	)
+	state.SetBalance(a, big.NewInt(100))
+
+	state.Prepare(tx1, common.Hash{}, 0)
+	state.SubBalance(a, big.NewInt(30))
+	state.AddBalance(b, big.NewInt(30))
+	snapshot := state.Snapshot()
+	state.AddBalance(b, big.NewInt(1))
+	state.RevertToSnapshot(snapshot)
+
+	state.Prepare(tx2, common.Hash{}, 1)
+	state.AddBalance(a, big.NewInt(5))
+	state.SubBalance(a, big.NewInt(5))
+	state.Suicide(b)
+
+	state.FinaliseBalanceChanges()
+	state.AddBalance(reward, big.NewInt(1))
+
+	want := []string{
+		"01:01..:100->70", "01:02..:0->30",
+		"02:02..:30->0",
+	}
+	var have []string
+	for _, tx := range state.BalanceChanges() {
+		for _, change := range tx.Changes {
+			have = append(have, fmt.Sprintf("%x:%x..:%v->%v", tx.TxHash[:1], change.Address[:1], change.Before, change.After))
+		}
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("balance changes mismatch:\nhave %v\nwant %v", have, want)
+	}
+}
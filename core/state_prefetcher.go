@@ -0,0 +1,80 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/params"
+)
+
+// statePrefetcher is a basic Prefetcher, which loads the accounts a block is
+// expected to touch and blindly runs its contract calls on top of an arbitrary
+// state, with the goal of warming the database caches and the transaction sender
+// caches before the block is actually executed.
+//
+// statePrefetcher implements Prefetcher.
+type statePrefetcher struct {
+	config *params.ChainConfig // Chain configuration options
+	bc     *BlockChain         // Canonical block chain
+}
+
+// newStatePrefetcher initialises a new statePrefetcher.
+func newStatePrefetcher(config *params.ChainConfig, bc *BlockChain) *statePrefetcher {
+	return &statePrefetcher{
+		config: config,
+		bc:     bc,
+	}
+}
+
+// Prefetch loads the accounts of the block's coinbase and the transaction senders
+// and recipients, and runs the contract calls to load the accounts and storage
+// slots they access. The consensus transactions aren't executed, as they would
+// modify the block's dpos context.
+func (p *statePrefetcher) Prefetch(block *types.Block, statedb *state.StateDB, interrupt *uint32) {
+	var (
+		header = block.Header()
+		signer = types.MakeSigner(p.config, header.Number)
+	)
+	statedb.GetBalance(block.Coinbase())
+	for i, tx := range block.Transactions() {
+		if interrupt != nil && atomic.LoadUint32(interrupt) == 1 {
+			return
+		}
+		// Recovering the sender also caches it in the transaction for import
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			continue
+		}
+		statedb.GetNonce(from)
+
+		to := tx.To()
+		if to == nil || tx.Type() != types.Binary || len(statedb.GetCode(*to)) == 0 {
+			continue
+		}
+		// Contract call, execute it ignoring the nonce as the state is only an
+		// approximation of the block's pre-state
+		msg := types.NewMessage(from, to, tx.Nonce(), tx.Value(), tx.Gas(), tx.GasPrice(), tx.Data(), false)
+		vmenv := vm.NewEVM(NewEVMContext(msg, header, p.bc, nil), statedb, p.config, vm.Config{})
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		ApplyMessage(vmenv, msg, new(GasPool).AddGas(tx.Gas()), nil, tx.Hash().Bytes(), types.Binary)
+	}
+}
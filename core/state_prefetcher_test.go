@@ -0,0 +1,97 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/consensus/dpos"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+)
+
+// Tests that prefetching a block caches the state it accesses in the state
+// database of the chain, including the storage slots read by contract calls.
+func TestPrefetchCachesState(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		code     = common.FromHex("6001545000") // PUSH1 1 SLOAD POP STOP
+		slot     = common.BigToHash(big.NewInt(1))
+		value    = common.BigToHash(big.NewInt(42))
+		funds    = big.NewInt(1000000000000000000)
+		db, _    = kokdb.NewMemDatabase()
+	)
+	config := *params.DposChainConfig
+	config.Dpos = &params.DposConfig{Validators: []common.Address{addr}}
+
+	gspec := &Genesis{
+		Config: &config,
+		Alloc: GenesisAlloc{
+			addr:     {Balance: funds},
+			contract: {Balance: new(big.Int), Code: code, Storage: map[common.Hash]common.Hash{slot: value}},
+		},
+	}
+	// Generate the chain on a separate database, as the chain maker commits all states
+	gendb, _ := kokdb.NewMemDatabase()
+	signer := types.NewEIP155Signer(config.ChainId)
+	chain, _ := GenerateDposChain(&config, gspec.MustCommit(gendb), dpos.New(config.Dpos, gendb), gendb, []*ecdsa.PrivateKey{key}, 1, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(types.Binary, gen.TxNonce(addr), contract, new(big.Int), big.NewInt(100000), nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	genesis := gspec.MustCommit(db)
+
+	blockchain, err := NewBlockChain(db, &config, dpos.New(config.Dpos, db), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	statedb, err := blockchain.StateAt(genesis.Root())
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	blockchain.Prefetcher().Prefetch(chain[0], statedb, nil)
+
+	// Drop the state from disk, it must still be available from the caches
+	for _, key := range db.Keys() {
+		db.Delete(key)
+	}
+	if statedb, err = blockchain.StateAt(genesis.Root()); err != nil {
+		t.Fatalf("failed to reopen genesis state: %v", err)
+	}
+	if balance := statedb.GetBalance(addr); balance.Cmp(funds) != 0 {
+		t.Errorf("sender balance mismatch: have %v, want %v", balance, funds)
+	}
+	if have := statedb.GetCode(contract); !bytes.Equal(have, code) {
+		t.Errorf("contract code mismatch: have %x, want %x", have, code)
+	}
+	if have := statedb.GetState(contract, slot); have != value {
+		t.Errorf("storage slot mismatch: have %x, want %x", have, value)
+	}
+	if err := statedb.Error(); err != nil {
+		t.Errorf("state access failed: %v", err)
+	}
+}
@@ -81,6 +81,7 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		allLogs = append(allLogs, receipt.Logs...)
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
+	statedb.FinaliseBalanceChanges()
 	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts, block.DposCtx())
 
 	return receipts, allLogs, totalUsedGas, nil
@@ -110,11 +111,15 @@ func ApplyTransaction(config *params.ChainConfig, dposContext *types.DposContext
 	// Create a new environment which holds all relevant information
 	// about the transaction and calling mechanisms.
 	vmenv := vm.NewEVM(context, statedb, config, cfg)
+	if cfg.EnableAudit {
+		statedb.EnableBalanceTracking()
+	}
 	// Apply the transaction to the current state (included in the env)
-	_, gas, failed, err := ApplyMessage(vmenv, msg, gp, ValidatorsAddress, tx.Hash().Bytes(), msg.Type())
+	ret, requiredGas, gas, failed, err := NewStateTransition(vmenv, msg, gp, ValidatorsAddress, tx.Hash().Bytes(), msg.Type()).TransitionDb()
 	if err != nil {
 		return nil, nil, err
 	}
+	refund := new(big.Int).Set(statedb.GetRefund())
 	if msg.Type() == types.LoginCandidate || msg.Type() == types.LogoutCandidate || msg.Type() == types.Delegate || msg.Type() == types.UnDelegate {
 
 		if NetWorkId == 5 {
@@ -148,6 +153,9 @@ func ApplyTransaction(config *params.ChainConfig, dposContext *types.DposContext
 	receipt := types.NewReceipt(root, failed, usedGas)
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = new(big.Int).Set(gas)
+	if failed {
+		receipt.RevertReason = common.CopyBytes(ret)
+	}
 	// if the transaction created a contract, store the creation address in the receipt.
 	if msg.To() == nil {
 		receipt.TemplateAddress = crypto.CreateAddress(vmenv.Context.Origin, tx.Nonce())
@@ -173,7 +181,7 @@ func ApplyTransaction(config *params.ChainConfig, dposContext *types.DposContext
 		}
 
 		if addressType == "contract" {
-			gas_mine, gas_template := Layer(new(big.Int).Set(gas).Uint64(), uint64(1))
+			gas_mine, gas_template := Layer(new(big.Int).Set(gas).Uint64(), config.DeveloperShare())
 			receipt.GasMiner = new(big.Int).SetUint64(gas_mine)
 			receipt.GasDeveloper = new(big.Int).SetUint64(gas_template)
 			switch msg.Type() {
@@ -207,10 +215,26 @@ func ApplyTransaction(config *params.ChainConfig, dposContext *types.DposContext
 		}
 	}
 
+	// Record the part of the producer's fees diverted to the treasury
+	if config.IsTreasury(header.Number) {
+		producer := gas.Uint64()
+		if receipt.GasDeveloper != nil {
+			producer = receipt.GasMiner.Uint64()
+		}
+		gas_mine, gas_treasury := Treasury(config, header.Number, producer)
+		receipt.GasMiner = new(big.Int).SetUint64(gas_mine)
+		receipt.GasTreasury = new(big.Int).SetUint64(gas_treasury)
+	}
 	// Set the receipt logs and create a bloom for filtering
 	receipt.Logs = statedb.GetLogs(tx.Hash())
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
 
+	if cfg.EnableAudit {
+		statedb.FinaliseBalanceChanges()
+		if err := auditTransaction(statedb, header, statedb.TxIndex(), tx, receipt, requiredGas, gas, refund); err != nil {
+			return nil, nil, err
+		}
+	}
 	return receipt, gas, err
 }
 
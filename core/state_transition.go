@@ -201,6 +201,17 @@ func (st *StateTransition) preCheck() error {
 	msg := st.msg
 	sender := st.from()
 
+	// Make sure the sender isn't frozen by the chain configuration
+	if st.evm.ChainConfig().IsFrozen(st.evm.BlockNumber, sender.Address()) {
+		return ErrFrozenAccount
+	}
+	// On permissioned networks make sure the sender may send the transaction,
+	// calls not checking nonces aren't transactions and go unrestricted
+	if msg.CheckNonce() {
+		if err := CheckPermission(st.evm.ChainConfig(), st.state, st.evm.BlockNumber, sender.Address(), msg.To(), st.txType); err != nil {
+			return err
+		}
+	}
 	// Make sure this transaction's nonce is correct
 	if msg.CheckNonce() {
 		nonce := st.state.GetNonce(sender.Address())
@@ -324,22 +335,45 @@ func (st *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *big
 
 	st.refundGas()
 	if addressType == "contract" {
-		gas_mine, gas_coinbase := Layer(st.gasUsed().Uint64(), uint64(1))
-		st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(gas_mine), st.gasPrice))
+		gas_mine, gas_coinbase := Layer(st.gasUsed().Uint64(), st.evm.ChainConfig().DeveloperShare())
+		st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.payTreasury(gas_mine)), st.gasPrice))
 		address_coinbase := CommonHash2Address(st.state.GetState(*st.msg.To(), HashTypeString("coinbase")))
 		st.state.AddBalance(address_coinbase, new(big.Int).Mul(new(big.Int).SetUint64(gas_coinbase), st.gasPrice))
 	} else {
-		st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed().Uint64()), st.gasPrice))
+		st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.payTreasury(st.gasUsed().Uint64())), st.gasPrice))
 	}
 	return ret, requiredGas, st.gasUsed(), vmerr != nil, err
 }
 
-func Layer(gas, tail uint64) (gas_mine, gas_coinbase uint64) {
-	gas_coinbase = gas / 10 * tail
+// Layer splits the gas used by a contract call between the block validator and
+// the contract coinbase, the latter receiving share percent of it.
+func Layer(gas, share uint64) (gas_mine, gas_coinbase uint64) {
+	gas_coinbase = gas * share / 100
 	gas_mine = gas - gas_coinbase
 	return
 }
 
+// Treasury splits the block producer's share of a transaction fee, returning the
+// gas left to the producer and the gas diverted to the treasury, if active.
+func Treasury(config *params.ChainConfig, num *big.Int, gas uint64) (gas_mine, gas_treasury uint64) {
+	if !config.IsTreasury(num) {
+		return gas, 0
+	}
+	gas_treasury = gas * config.Treasury.Percent / 100
+	gas_mine = gas - gas_treasury
+	return
+}
+
+// payTreasury credits the treasury with its share of the given producer fee,
+// returning the gas left to pay the producer.
+func (st *StateTransition) payTreasury(gas uint64) uint64 {
+	gas_mine, gas_treasury := Treasury(st.evm.ChainConfig(), st.evm.BlockNumber, gas)
+	if gas_treasury > 0 {
+		st.state.AddBalance(st.evm.ChainConfig().Treasury.Address, new(big.Int).Mul(new(big.Int).SetUint64(gas_treasury), st.gasPrice))
+	}
+	return gas_mine
+}
+
 func CommonHash2Address(hash common.Hash) common.Address {
 	address := common.Address{}
 	for i := 0; i < len(address); i++ {
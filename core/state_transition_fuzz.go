@@ -0,0 +1,245 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build gofuzz
+// +build gofuzz
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+)
+
+// fuzzMaxTxs is the maximum number of transactions in a fuzzed block.
+const fuzzMaxTxs = 8
+
+var (
+	fuzzKey, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	fuzzSender    = crypto.PubkeyToAddress(fuzzKey.PublicKey)
+	fuzzCoinbase  = common.HexToAddress("0xc0")
+	fuzzAccount   = common.HexToAddress("0xa1") // Plain account
+	fuzzContract  = common.HexToAddress("0xa2") // Contract running the fuzzed code
+	fuzzTemplate  = common.HexToAddress("0xa3") // Template instantiating the fuzzed code
+	fuzzDeveloper = common.HexToAddress("0xa4") // Coinbase of the contract and the template
+)
+
+// Fuzz is the entry point for go-fuzz and libFuzzer (go-fuzz-build -libfuzzer).
+// It interprets the input as a block of transactions applied on top of a small
+// pre-state and panics if the state transition breaks its accounting: the block
+// gas pool must be charged exactly the gas used by the transactions, and the
+// total balance must be conserved, i.e. fees distributed equal those charged.
+//
+// It returns 1 if the whole block was applicable, 0 if a transaction was invalid
+// and -1 if the input was too short to be interesting.
+func Fuzz(input []byte) int {
+	if len(input) < 4 {
+		return -1
+	}
+	r := &fuzzReader{data: input}
+
+	// Assemble the pre-state, the code of the contracts being part of the input
+	db, _ := kokdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	code := r.bytes(int(r.uint(1)))
+	statedb.AddBalance(fuzzSender, new(big.Int).Lsh(new(big.Int).SetUint64(r.uint(2)), 24))
+	statedb.AddBalance(fuzzAccount, big.NewInt(1))
+
+	statedb.SetCode(fuzzContract, code)
+	statedb.SetState(fuzzContract, HashTypeString("type"), HashTypeString("contract"))
+	statedb.SetState(fuzzContract, HashTypeString("coinbase"), fuzzDeveloper.Hash())
+	statedb.SetCode(fuzzTemplate, code)
+	statedb.SetState(fuzzTemplate, HashTypeString("type"), HashTypeString("template"))
+	statedb.SetState(fuzzTemplate, HashTypeString("coinbase"), common.BytesToHash(common.RightPadBytes(fuzzDeveloper.Bytes(), common.HashLength)))
+
+	dposContext, err := types.NewDposContext(db)
+	if err != nil {
+		panic(err)
+	}
+	var (
+		config = params.TestChainConfig
+		signer = types.HomesteadSigner{}
+		header = &types.Header{Number: big.NewInt(1), Time: big.NewInt(1), Difficulty: big.NewInt(1), GasLimit: big.NewInt(8000000)}
+		gp     = new(GasPool).AddGas(header.GasLimit)
+		nonce  uint64
+	)
+	for i := 0; i < fuzzMaxTxs && len(r.data) > 0; i++ {
+		// Decode the next transaction of the block
+		kind := r.byte()
+		var to *common.Address
+		switch (kind >> 3) % 4 {
+		case 1:
+			to = &fuzzAccount
+		case 2:
+			to = &fuzzContract
+		case 3:
+			to = &fuzzTemplate
+		}
+		txNonce := nonce + uint64(kind>>7)
+		gas := new(big.Int).SetUint64(r.uint(3))
+		price := new(big.Int).SetUint64(r.uint(1))
+		value := new(big.Int).SetUint64(r.uint(4))
+		data := r.bytes(int(r.uint(1)))
+
+		var tx *types.Transaction
+		if to == nil {
+			tx = types.NewContractCreation(txNonce, value, gas, price, data)
+		} else {
+			tx = types.NewTransaction(types.TxType(kind%7), txNonce, *to, value, gas, price, data)
+		}
+		tx, err := types.SignTx(tx, signer, fuzzKey)
+		if err != nil {
+			panic(err)
+		}
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return 0
+		}
+		// Apply the transaction, tracking the balances and gas around it
+		before, err := fuzzSupply(statedb, db)
+		if err != nil {
+			panic(err)
+		}
+		available := new(big.Int).Set((*big.Int)(gp))
+		tracer := new(fuzzTracer)
+
+		context := vm.Context{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			Gkokash:     func(uint64) common.Hash { return common.Hash{} },
+			Origin:      msg.From(),
+			Coinbase:    fuzzCoinbase,
+			BlockNumber: new(big.Int).Set(header.Number),
+			Time:        new(big.Int).Set(header.Time),
+			Difficulty:  new(big.Int).Set(header.Difficulty),
+			GasLimit:    new(big.Int).Set(header.GasLimit),
+			GasPrice:    new(big.Int).Set(msg.GasPrice()),
+		}
+		evm := vm.NewEVM(context, statedb, config, vm.Config{Debug: true, Tracer: tracer})
+
+		statedb.Prepare(tx.Hash(), common.Hash{}, i)
+		_, used, _, err := ApplyMessage(evm, msg, gp, nil, tx.Hash().Bytes(), msg.Type())
+		if err != nil {
+			return 0 // Invalid transaction, the block would be rejected
+		}
+		switch msg.Type() {
+		case types.LoginCandidate, types.LogoutCandidate, types.Delegate, types.UnDelegate:
+			if err := applyDposMessage(dposContext, msg); err != nil {
+				return 0
+			}
+		}
+		statedb.Finalise(true)
+		nonce++
+
+		// Ensure the gas and the fees were accounted for correctly
+		if used.Cmp(msg.Gas()) > 0 {
+			panic(fmt.Sprintf("tx %d: gas used %v exceeds gas limit %v", i, used, msg.Gas()))
+		}
+		if charged := new(big.Int).Sub(available, (*big.Int)(gp)); charged.Cmp(used) != 0 {
+			panic(fmt.Sprintf("tx %d: gas pool charged %v, gas used %v", i, charged, used))
+		}
+		// Self-destructing contracts may legitimately burn their balance
+		if tracer.destructed {
+			continue
+		}
+		after, err := fuzzSupply(statedb, db)
+		if err != nil {
+			panic(err)
+		}
+		if before.Cmp(after) != 0 {
+			panic(fmt.Sprintf("tx %d: total balance changed from %v to %v (gas used %v, price %v)", i, before, after, used, msg.GasPrice()))
+		}
+	}
+	return 1
+}
+
+// fuzzSupply commits the state and returns the total balance of all accounts.
+func fuzzSupply(statedb *state.StateDB, db kokdb.Database) (*big.Int, error) {
+	root, err := statedb.CommitTo(db, true)
+	if err != nil {
+		return nil, err
+	}
+	committed, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return nil, err
+	}
+	supply := new(big.Int)
+	for _, account := range committed.RawDump().Accounts {
+		balance, ok := new(big.Int).SetString(account.Balance, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid balance %q", account.Balance)
+		}
+		supply.Add(supply, balance)
+	}
+	return supply, nil
+}
+
+// fuzzReader consumes the fuzzer input, padding it with zeroes when exhausted.
+type fuzzReader struct {
+	data []byte
+}
+
+func (r *fuzzReader) byte() byte {
+	if len(r.data) == 0 {
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *fuzzReader) bytes(n int) []byte {
+	if n > len(r.data) {
+		n = len(r.data)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *fuzzReader) uint(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		v = v<<8 | uint64(r.byte())
+	}
+	return v
+}
+
+// fuzzTracer is an EVM tracer detecting self-destructing contracts.
+type fuzzTracer struct {
+	destructed bool
+}
+
+func (t *fuzzTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if op == vm.SELFDESTRUCT {
+		t.destructed = true
+	}
+	return nil
+}
+
+func (t *fuzzTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
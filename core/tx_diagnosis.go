@@ -0,0 +1,119 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+)
+
+// maxMissingNonces is the maximum number of missing nonces listed when diagnosing
+// a transaction stuck behind a nonce gap.
+const maxMissingNonces = 32
+
+// TxHoldReason describes why a pooled transaction is not (yet) executable or is
+// at risk of being evicted.
+type TxHoldReason string
+
+const (
+	TxHoldNonceGap          TxHoldReason = "nonceGap"          // Transactions with lower nonces of the sender are missing
+	TxHoldInsufficientFunds TxHoldReason = "insufficientFunds" // Sender can't pay for all its transactions up to this one
+	TxHoldAccountLimit      TxHoldReason = "accountLimit"      // Sender exceeds its queued transaction allowance
+	TxHoldPoolLimit         TxHoldReason = "poolLimit"         // Pool is full, cheap transactions are evicted first
+	TxHoldUnderpriced       TxHoldReason = "underpriced"       // Gas price is below the minimum accepted by the pool
+)
+
+// TxDiagnosis explains the standing of a transaction in the pool.
+type TxDiagnosis struct {
+	Status  TxStatus       // Current status of the transaction in the pool
+	Dropped *DroppedTx     // Drop record if the transaction was recently dropped
+	From    common.Address // Sender of the transaction, if known
+	Nonce   uint64         // Nonce of the transaction, if known
+
+	ExpectedNonce uint64   // Next nonce executable for the sender
+	MissingNonces []uint64 // Nonces missing between the executable one and the transaction, capped
+	Balance       *big.Int // Current balance of the sender
+	Cost          *big.Int // Cumulative cost of the sender's transactions up to and including this one
+
+	Reasons []TxHoldReason // Reasons holding the transaction back, empty if none found
+}
+
+// Diagnose explains why a transaction is queued instead of pending, or at risk
+// of being evicted. Transactions not in the pool are reported with their drop
+// record, if any.
+func (pool *TxPool) Diagnose(hash common.Hash) *TxDiagnosis {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	tx := pool.all[hash]
+	if tx == nil {
+		return &TxDiagnosis{Status: TxStatusUnknown, Dropped: pool.DroppedTransaction(hash)}
+	}
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	diag := &TxDiagnosis{
+		Status:        TxStatusQueued,
+		From:          from,
+		Nonce:         tx.Nonce(),
+		ExpectedNonce: pool.pendingState.GetNonce(from),
+		Balance:       pool.currentState.GetBalance(from),
+		Cost:          new(big.Int),
+	}
+	if list := pool.pending[from]; list != nil && list.txs.Get(tx.Nonce()) != nil {
+		diag.Status = TxStatusPending
+	}
+	// Accumulate the cost of all the sender's transactions up to this one
+	queued := pool.queue[from]
+	for _, list := range []*txList{pool.pending[from], queued} {
+		if list == nil {
+			continue
+		}
+		for _, ptx := range list.Flatten() {
+			if ptx.Nonce() <= tx.Nonce() {
+				diag.Cost.Add(diag.Cost, ptx.Cost())
+			}
+		}
+	}
+	if diag.Cost.Cmp(diag.Balance) > 0 {
+		diag.Reasons = append(diag.Reasons, TxHoldInsufficientFunds)
+	}
+	if diag.Status == TxStatusQueued {
+		// Find any nonces missing in front of the transaction
+		for nonce := diag.ExpectedNonce; nonce < tx.Nonce() && len(diag.MissingNonces) < maxMissingNonces; nonce++ {
+			if queued == nil || queued.txs.Get(nonce) == nil {
+				diag.MissingNonces = append(diag.MissingNonces, nonce)
+			}
+		}
+		if len(diag.MissingNonces) > 0 {
+			diag.Reasons = append(diag.Reasons, TxHoldNonceGap)
+		}
+		if !pool.locals.contains(from) && queued != nil && uint64(queued.Len()) >= pool.config.AccountQueue {
+			diag.Reasons = append(diag.Reasons, TxHoldAccountLimit)
+		}
+	}
+	// Remote transactions are subject to the pricing and capacity limits
+	if !pool.locals.contains(from) {
+		if tx.GasPrice().Cmp(pool.gasPrice) < 0 {
+			diag.Reasons = append(diag.Reasons, TxHoldUnderpriced)
+		}
+		if uint64(len(pool.all)) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
+			diag.Reasons = append(diag.Reasons, TxHoldPoolLimit)
+		}
+	}
+	return diag
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/event"
+)
+
+// droppedTxLimit is the number of recently dropped transactions the pool keeps
+// track of for later lookups.
+const droppedTxLimit = 4096
+
+// TxDropReason describes why a transaction was dropped from the pool.
+type TxDropReason string
+
+const (
+	TxDropUnderpriced TxDropReason = "underpriced" // Evicted by better priced transactions or a raised price threshold
+	TxDropReplaced    TxDropReason = "replaced"    // Superseded by a transaction with the same nonce and a higher price
+	TxDropNonceTooLow TxDropReason = "nonceTooLow" // Invalidated by a different transaction with the same nonce being mined
+	TxDropUnpayable   TxDropReason = "unpayable"   // Sender can no longer pay for the transaction
+	TxDropRateLimited TxDropReason = "rateLimited" // Exceeded the pool limits of the sender or the pool as a whole
+	TxDropExpired     TxDropReason = "expired"     // Stayed in the pool past its expiry or the configured lifetime
+	TxDropReorged     TxDropReason = "reorged"     // Reorged out of the chain and could not be readmitted to the pool
+	TxDropFrozen      TxDropReason = "frozen"      // Sender was frozen by the chain configuration
+)
+
+// DroppedTx is the record of a transaction dropped from the pool without being
+// included in the canonical chain.
+type DroppedTx struct {
+	Hash       common.Hash    `json:"hash"`
+	From       common.Address `json:"from"`
+	Nonce      uint64         `json:"nonce"`
+	Reason     TxDropReason   `json:"reason"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"` // Hash of the superseding transaction, if replaced
+	Time       time.Time      `json:"time"`
+}
+
+// TxDropEvent is posted when a transaction is dropped from the pool.
+type TxDropEvent struct {
+	Tx      *types.Transaction
+	Dropped *DroppedTx
+}
+
+// dropTx records the removal of a transaction from the pool without it being
+// included in the chain, and notifies any subscribers. The caller is responsible
+// for actually removing the transaction.
+//
+// Note, this mkokod assumes the pool lock is held!
+func (pool *TxPool) dropTx(tx *types.Transaction, reason TxDropReason, replacement *types.Transaction) {
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	dropped := &DroppedTx{
+		Hash:   tx.Hash(),
+		From:   from,
+		Nonce:  tx.Nonce(),
+		Reason: reason,
+		Time:   time.Now(),
+	}
+	if replacement != nil {
+		hash := replacement.Hash()
+		dropped.ReplacedBy = &hash
+	}
+	pool.dropped.Add(dropped.Hash, dropped)
+	go pool.dropFeed.Send(TxDropEvent{Tx: tx, Dropped: dropped})
+}
+
+// DroppedTransaction returns the record of a recently dropped transaction, or
+// nil if the transaction wasn't dropped or was dropped too long ago.
+func (pool *TxPool) DroppedTransaction(hash common.Hash) *DroppedTx {
+	if dropped, ok := pool.dropped.Get(hash); ok {
+		return dropped.(*DroppedTx)
+	}
+	return nil
+}
+
+// SubscribeTxDropEvent registers a subscription of TxDropEvent and starts
+// sending event to the given channel.
+func (pool *TxPool) SubscribeTxDropEvent(ch chan<- TxDropEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
+// dropStaleTx records the removal of a transaction whose nonce was consumed on
+// chain. Transactions included by the new head are not dropped but mined, and
+// if the pool doesn't know what the head included, nothing is recorded.
+//
+// Note, this mkokod assumes the pool lock is held!
+func (pool *TxPool) dropStaleTx(tx *types.Transaction) {
+	if pool.mined == nil {
+		return
+	}
+	if _, ok := pool.mined[tx.Hash()]; !ok {
+		pool.dropTx(tx, TxDropNonceTooLow, nil)
+	}
+}
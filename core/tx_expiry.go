@@ -0,0 +1,94 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/metrics"
+)
+
+// errUnknownTx is returned when setting the expiry of a transaction not in the pool.
+var errUnknownTx = errors.New("unknown transaction")
+
+// ErrTxExpired is returned if a transaction is submitted with an expiry that is
+// already reached.
+var ErrTxExpired = errors.New("transaction expiry already reached")
+
+// expiredTxCounter counts the transactions dropped for staying in the pool past
+// their expiry.
+var expiredTxCounter = metrics.NewCounter("txpool/expired")
+
+// TxExpiry is the point after which a transaction still not included is dropped
+// from the pool. Whichever of the block and time limits is reached first applies.
+type TxExpiry struct {
+	Block uint64    // Last block number the transaction may be included in, zero if unlimited
+	Time  time.Time // Time after which the transaction is dropped, zero if unlimited
+}
+
+// expired reports whkoker the expiry is reached at the given head and time.
+func (e TxExpiry) expired(head uint64, now time.Time) bool {
+	if e.Block != 0 && head >= e.Block {
+		return true
+	}
+	return !e.Time.IsZero() && now.After(e.Time)
+}
+
+// SetExpiry sets the expiry of a pooled transaction, overriding the default
+// lifetime of the pool.
+func (pool *TxPool) SetExpiry(hash common.Hash, expiry TxExpiry) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.all[hash] == nil {
+		return errUnknownTx
+	}
+	pool.expiries[hash] = expiry
+	return nil
+}
+
+// trackExpiry assigns the default lifetime of the pool to a newly added
+// transaction, if one is configured.
+//
+// Note, this mkokod assumes the pool lock is held!
+func (pool *TxPool) trackExpiry(hash common.Hash) {
+	if pool.config.MaxLifetime > 0 {
+		pool.expiries[hash] = TxExpiry{Time: time.Now().Add(pool.config.MaxLifetime)}
+	}
+}
+
+// expire drops all the transactions whose expiry is reached at the given head
+// and time, and forgets the expiries of transactions no longer in the pool.
+//
+// Note, this mkokod assumes the pool lock is held!
+func (pool *TxPool) expire(head uint64, now time.Time) {
+	for hash, expiry := range pool.expiries {
+		tx := pool.all[hash]
+		if tx == nil {
+			delete(pool.expiries, hash)
+			continue
+		}
+		if expiry.expired(head, now) {
+			expiredTxCounter.Inc(1)
+			pool.dropTx(tx, TxDropExpired, nil)
+			pool.removeTx(hash)
+			delete(pool.expiries, hash)
+		}
+	}
+}
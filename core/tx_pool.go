@@ -32,6 +32,7 @@ import (
 	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/metrics"
 	"github.com/kokprojects/go-kok/params"
+	"github.com/hashicorp/golang-lru"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
 
@@ -126,6 +127,7 @@ type blockChain interface {
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
 	NoLocals  bool          // Whkoker local transaction handling should be disabled
+	Private   bool          // Whkoker local transactions are kept from being propagated by default
 	Journal   string        // Journal of local transactions to survive node restarts
 	Rejournal time.Duration // Time interval to regenerate the local transaction journal
 
@@ -137,7 +139,8 @@ type TxPoolConfig struct {
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
-	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+	Lifetime    time.Duration // Maximum amount of time non-executable transaction are queued
+	MaxLifetime time.Duration // Maximum amount of time any transaction is pooled, zero for unlimited
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -189,6 +192,7 @@ type TxPool struct {
 	chain        blockChain
 	gasPrice     *big.Int
 	txFeed       event.Feed
+	dropFeed     event.Feed
 	scope        event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
@@ -208,6 +212,13 @@ type TxPool struct {
 	all     map[common.Hash]*types.Transaction // All transactions to allow lookups
 	priced  *txPricedList                      // All transactions sorted by price
 
+	dropped *lru.Cache               // Recently dropped transactions, keyed by hash
+	mined   map[common.Hash]struct{} // Transactions included by the last head, nil if unknown
+	private map[common.Hash]struct{} // Local transactions not to be propagated to the network
+
+	expiries map[common.Hash]TxExpiry  // Points after which transactions are dropped, if any
+	bumps    map[common.Address]uint64 // Per-account overrides of the replacement price bump
+
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead bool
@@ -229,11 +240,15 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		queue:       make(map[common.Address]*txList),
 		beats:       make(map[common.Address]time.Time),
 		all:         make(map[common.Hash]*types.Transaction),
+		private:     make(map[common.Hash]struct{}),
+		expiries:    make(map[common.Hash]TxExpiry),
+		bumps:       make(map[common.Address]uint64),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	pool.priced = newTxPricedList(&pool.all)
+	pool.dropped, _ = lru.New(droppedTxLimit)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
 	// If local transactions and journaling is enabled, load from disk
@@ -312,6 +327,7 @@ func (pool *TxPool) loop() {
 		// Handle inactive account transaction eviction
 		case <-evict.C:
 			pool.mu.Lock()
+			pool.expire(head.NumberU64(), time.Now())
 			for addr := range pool.queue {
 				// Skip local transactions from the eviction mechanism
 				if pool.locals.contains(addr) {
@@ -320,6 +336,8 @@ func (pool *TxPool) loop() {
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					for _, tx := range pool.queue[addr].Flatten() {
+						expiredTxCounter.Inc(1)
+						pool.dropTx(tx, TxDropExpired, nil)
 						pool.removeTx(tx.Hash())
 					}
 				}
@@ -352,7 +370,11 @@ func (pool *TxPool) lockedReset(oldHead, newHead *types.Header) {
 // of the transaction pool is valid with regard to the chain state.
 func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
-	var reinject types.Transactions
+	var (
+		reinject types.Transactions
+		included types.Transactions
+	)
+	pool.mined = nil
 
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
 		// If the reorg is too deep, avoid doing it (will happen during fast sync)
@@ -363,7 +385,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 			log.Warn("Skipping deep transaction reorg", "depth", depth)
 		} else {
 			// Reorg seems shallow enough to pull in all transactions into memory
-			var discarded, included types.Transactions
+			var discarded types.Transactions
 
 			var (
 				rem = pool.chain.GetBlock(oldHead.Hash(), oldHead.Number.Uint64())
@@ -396,8 +418,18 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 				}
 			}
 			reinject = types.TxDifference(discarded, included)
+			pool.mined = make(map[common.Hash]struct{})
+		}
+	} else if newHead != nil {
+		// Plain chain extension, track the freshly mined transactions
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			included = block.Transactions()
+			pool.mined = make(map[common.Hash]struct{})
 		}
 	}
+	for _, tx := range included {
+		pool.mined[tx.Hash()] = struct{}{}
+	}
 	// Initialize the internal state to the current head
 	if newHead == nil {
 		newHead = pool.chain.CurrentBlock().Header() // Special case during testing
@@ -413,7 +445,17 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
-	pool.addTxsLocked(reinject, false)
+	for i, err := range pool.addTxsLocked(reinject, false, false) {
+		if err != nil {
+			pool.dropTx(reinject[i], TxDropReorged, nil)
+		}
+	}
+
+	// Drop any transactions expired by the new head
+	pool.expire(newHead.Number.Uint64(), time.Now())
+
+	// Drop all transactions of senders frozen as of the next block
+	pool.evictFrozen(new(big.Int).Add(newHead.Number, common.Big1))
 
 	// validate the pool of pending transactions, this will remove
 	// any transactions that have been included in the block or
@@ -421,6 +463,13 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// higher gas price)
 	pool.demoteUnexecutables()
 
+	// Forget the privacy of transactions no longer in the pool
+	for hash := range pool.private {
+		if pool.all[hash] == nil {
+			delete(pool.private, hash)
+		}
+	}
+
 	// Update all accounts to the latest known pending nonce
 	for addr, list := range pool.pending {
 		txs := list.Flatten() // Heavy but will be cached and is needed by the miner anyway
@@ -468,11 +517,70 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 
 	pool.gasPrice = price
 	for _, tx := range pool.priced.Cap(price, pool.locals) {
+		pool.dropTx(tx, TxDropUnderpriced, nil)
 		pool.removeTx(tx.Hash())
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// PriceBump returns the minimum price bump percentage required to replace a
+// pooled transaction of the given account.
+func (pool *TxPool) PriceBump(addr common.Address) uint64 {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.priceBump(addr)
+}
+
+// priceBump returns the replacement price bump of an account, falling back to
+// the pool wide setting if no override was configured.
+//
+// Note, this mkokod assumes the pool lock is held!
+func (pool *TxPool) priceBump(addr common.Address) uint64 {
+	if bump, ok := pool.bumps[addr]; ok {
+		return bump
+	}
+	return pool.config.PriceBump
+}
+
+// PriceBumps returns the pool wide replacement price bump along with the
+// per-account overrides.
+func (pool *TxPool) PriceBumps() (uint64, map[common.Address]uint64) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	bumps := make(map[common.Address]uint64, len(pool.bumps))
+	for addr, bump := range pool.bumps {
+		bumps[addr] = bump
+	}
+	return pool.config.PriceBump, bumps
+}
+
+// SetPriceBump updates the minimum price bump percentage required to replace a
+// pooled transaction with a same-nonce one. If an account is given, the bump
+// only applies to its transactions, overriding the pool wide setting.
+func (pool *TxPool) SetPriceBump(addr *common.Address, bump uint64) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if addr == nil {
+		pool.config.PriceBump = bump
+		log.Info("Transaction pool price bump updated", "bump", bump)
+		return
+	}
+	pool.bumps[*addr] = bump
+	log.Info("Transaction pool account price bump updated", "account", *addr, "bump", bump)
+}
+
+// ResetPriceBump removes the price bump override of an account, reverting its
+// transactions to the pool wide setting.
+func (pool *TxPool) ResetPriceBump(addr common.Address) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	delete(pool.bumps, addr)
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -563,6 +671,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Value().Sign() < 0 {
 		return ErrNegativeValue
 	}
+	// Ensure the transaction follows the rules of its type
+	if err := tx.Validate(); err != nil {
+		return err
+	}
 	// Ensure the transaction doesn't exceed the current block limit gas.
 	if pool.currentMaxGas.Cmp(tx.Gas()) < 0 {
 		return ErrGasLimit
@@ -572,6 +684,15 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
+	// Frozen accounts can't send transactions in the next block
+	next := new(big.Int).Add(pool.chain.CurrentBlock().Number(), common.Big1)
+	if pool.chainconfig.IsFrozen(next, from) {
+		return ErrFrozenAccount
+	}
+	// On permissioned networks the sender must be allowed to send (and deploy)
+	if err := CheckPermission(pool.chainconfig, pool.currentState, next, from, tx.To(), tx.Type()); err != nil {
+		return err
+	}
 	// Drop non-local transactions under our own minimal accepted gas price
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
 	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
@@ -631,6 +752,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
+			pool.dropTx(tx, TxDropUnderpriced, nil)
 			pool.removeTx(tx.Hash())
 		}
 	}
@@ -638,13 +760,16 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
-		inserted, old := list.Add(tx, pool.config.PriceBump)
+		bump := pool.priceBump(from)
+		inserted, old := list.Add(tx, bump)
 		if !inserted {
+			log.Trace("Discarding underpriced pending replacement", "hash", hash, "price", tx.GasPrice(), "bump", bump)
 			pendingDiscardCounter.Inc(1)
 			return false, ErrReplaceUnderpriced
 		}
 		// New transaction is better, replace old one
 		if old != nil {
+			pool.dropTx(old, TxDropReplaced, tx)
 			delete(pool.all, old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
@@ -684,14 +809,17 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	if pool.queue[from] == nil {
 		pool.queue[from] = newTxList(false)
 	}
-	inserted, old := pool.queue[from].Add(tx, pool.config.PriceBump)
+	bump := pool.priceBump(from)
+	inserted, old := pool.queue[from].Add(tx, bump)
 	if !inserted {
 		// An older transaction was better, discard this
+		log.Trace("Discarding underpriced queued replacement", "hash", hash, "price", tx.GasPrice(), "bump", bump)
 		queuedDiscardCounter.Inc(1)
 		return false, ErrReplaceUnderpriced
 	}
 	// Discard any previous transaction and mark this
 	if old != nil {
+		pool.dropTx(old, TxDropReplaced, tx)
 		delete(pool.all, old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
@@ -723,9 +851,10 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	}
 	list := pool.pending[addr]
 
-	inserted, old := list.Add(tx, pool.config.PriceBump)
+	inserted, old := list.Add(tx, pool.priceBump(addr))
 	if !inserted {
 		// An older transaction was better, discard this
+		pool.dropTx(tx, TxDropReplaced, list.txs.Get(tx.Nonce()))
 		delete(pool.all, hash)
 		pool.priced.Removed()
 
@@ -734,6 +863,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	}
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
+		pool.dropTx(old, TxDropReplaced, tx)
 		delete(pool.all, old.Hash())
 		pool.priced.Removed()
 
@@ -755,37 +885,51 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 // the sender as a local one in the mean time, ensuring it goes around the local
 // pricing constraints.
 func (pool *TxPool) AddLocal(tx *types.Transaction) error {
-	return pool.addTx(tx, !pool.config.NoLocals)
+	return pool.addTx(tx, !pool.config.NoLocals, pool.config.Private, nil)
+}
+
+// AddLocalTx enqueues a single transaction into the pool like AddLocal, but
+// overrides the configured privacy of local transactions. Private transactions
+// are never propagated to the network, only included into locally mined blocks.
+func (pool *TxPool) AddLocalTx(tx *types.Transaction, private bool) error {
+	return pool.addTx(tx, !pool.config.NoLocals, private, nil)
+}
+
+// AddLocalWithExpiry enqueues a single transaction into the pool like AddLocalTx,
+// but drops it once the given expiry is reached instead of after the default
+// lifetime of the pool. Transactions expiring already are rejected.
+func (pool *TxPool) AddLocalWithExpiry(tx *types.Transaction, private bool, expiry TxExpiry) error {
+	return pool.addTx(tx, !pool.config.NoLocals, private, &expiry)
 }
 
 // AddRemote enqueues a single transaction into the pool if it is valid. If the
 // sender is not among the locally tracked ones, full pricing constraints will
 // apply.
 func (pool *TxPool) AddRemote(tx *types.Transaction) error {
-	return pool.addTx(tx, false)
+	return pool.addTx(tx, false, false, nil)
 }
 
 // AddLocals enqueues a batch of transactions into the pool if they are valid,
 // marking the senders as a local ones in the mean time, ensuring they go around
 // the local pricing constraints.
 func (pool *TxPool) AddLocals(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, !pool.config.NoLocals)
+	return pool.addTxs(txs, !pool.config.NoLocals, pool.config.Private)
 }
 
 // AddRemotes enqueues a batch of transactions into the pool if they are valid.
 // If the senders are not among the locally tracked ones, full pricing constraints
 // will apply.
 func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, false)
+	return pool.addTxs(txs, false, false)
 }
 
 // addTx enqueues a single transaction into the pool if it is valid.
-func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
+func (pool *TxPool) addTx(tx *types.Transaction, local, private bool, expiry *TxExpiry) error {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
 	// Try to inject the transaction and update any state
-	replace, err := pool.add(tx, local)
+	replace, err := pool.addTracked(tx, local, private, expiry)
 	if err != nil {
 		return err
 	}
@@ -798,23 +942,23 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 }
 
 // addTxs attempts to queue a batch of transactions if they are valid.
-func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) []error {
+func (pool *TxPool) addTxs(txs []*types.Transaction, local, private bool) []error {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
-	return pool.addTxsLocked(txs, local)
+	return pool.addTxsLocked(txs, local, private)
 }
 
 // addTxsLocked attempts to queue a batch of transactions if they are valid,
 // whilst assuming the transaction pool lock is already held.
-func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) []error {
+func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local, private bool) []error {
 	// Add the batch of transaction, tracking the accepted ones
 	dirty := make(map[common.Address]struct{})
 	errs := make([]error, len(txs))
 
 	for i, tx := range txs {
 		var replace bool
-		if replace, errs[i] = pool.add(tx, local); errs[i] == nil {
+		if replace, errs[i] = pool.addTracked(tx, local, private, nil); errs[i] == nil {
 			if !replace {
 				from, _ := types.Sender(pool.signer, tx) // already validated
 				dirty[from] = struct{}{}
@@ -832,6 +976,42 @@ func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) []error {
 	return errs
 }
 
+// addTracked validates a transaction and inserts it into the pool like add, but
+// also tracks its privacy and expiry. Private transactions are marked as such
+// beforehand, so that they're never announced to the network, not even transiently.
+// The expiry, if set, overrides the default lifetime of the pool.
+func (pool *TxPool) addTracked(tx *types.Transaction, local, private bool, expiry *TxExpiry) (bool, error) {
+	if expiry != nil && expiry.expired(pool.chain.CurrentBlock().NumberU64(), time.Now()) {
+		return false, ErrTxExpired
+	}
+	hash := tx.Hash()
+	if private && pool.all[hash] == nil {
+		pool.private[hash] = struct{}{}
+	}
+	replace, err := pool.add(tx, local)
+	if err != nil {
+		if pool.all[hash] == nil {
+			delete(pool.private, hash)
+		}
+		return false, err
+	}
+	if expiry != nil {
+		pool.expiries[hash] = *expiry
+	} else {
+		pool.trackExpiry(hash)
+	}
+	return replace, nil
+}
+
+// IsPrivate reports whkoker a pooled transaction is to be kept from the network.
+func (pool *TxPool) IsPrivate(hash common.Hash) bool {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	_, ok := pool.private[hash]
+	return ok
+}
+
 // Status returns the status (unknown/pending/queued) of a batch of transactions
 // identified by their hashes.
 func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
@@ -904,6 +1084,25 @@ func (pool *TxPool) removeTx(hash common.Hash) {
 	}
 }
 
+// evictFrozen removes all pending and queued transactions of the accounts frozen
+// by the chain configuration at block num.
+func (pool *TxPool) evictFrozen(num *big.Int) {
+	for _, addr := range pool.chainconfig.FrozenAccounts(num) {
+		var txs types.Transactions
+		if list := pool.pending[addr]; list != nil {
+			txs = append(txs, list.Flatten()...)
+		}
+		if list := pool.queue[addr]; list != nil {
+			txs = append(txs, list.Flatten()...)
+		}
+		for _, tx := range txs {
+			log.Trace("Removed transaction of frozen sender", "hash", tx.Hash(), "from", addr)
+			pool.dropTx(tx, TxDropFrozen, nil)
+			pool.removeTx(tx.Hash())
+		}
+	}
+}
+
 // promoteExecutables moves transactions that have become processable from the
 // future queue to the set of pending transactions. During this process, all
 // invalidated transactions (low nonce, low balance) are deleted.
@@ -925,6 +1124,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		for _, tx := range list.Forward(pool.currentState.GetNonce(addr)) {
 			hash := tx.Hash()
 			log.Trace("Removed old queued transaction", "hash", hash)
+			pool.dropStaleTx(tx)
 			delete(pool.all, hash)
 			pool.priced.Removed()
 		}
@@ -933,6 +1133,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable queued transaction", "hash", hash)
+			pool.dropTx(tx, TxDropUnpayable, nil)
 			delete(pool.all, hash)
 			pool.priced.Removed()
 			queuedNofundsCounter.Inc(1)
@@ -947,6 +1148,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		if !pool.locals.contains(addr) {
 			for _, tx := range list.Cap(int(pool.config.AccountQueue)) {
 				hash := tx.Hash()
+				pool.dropTx(tx, TxDropRateLimited, nil)
 				delete(pool.all, hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
@@ -992,6 +1194,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						for _, tx := range list.Cap(list.Len() - 1) {
 							// Drop the transaction from the global pools too
 							hash := tx.Hash()
+							pool.dropTx(tx, TxDropRateLimited, nil)
 							delete(pool.all, hash)
 							pool.priced.Removed()
 
@@ -1014,6 +1217,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 					for _, tx := range list.Cap(list.Len() - 1) {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
+						pool.dropTx(tx, TxDropRateLimited, nil)
 						delete(pool.all, hash)
 						pool.priced.Removed()
 
@@ -1054,6 +1258,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Drop all transactions if they are less than the overflow
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.Flatten() {
+					pool.dropTx(tx, TxDropRateLimited, nil)
 					pool.removeTx(tx.Hash())
 				}
 				drop -= size
@@ -1063,6 +1268,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Otherwise drop only last few transactions
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+				pool.dropTx(txs[i], TxDropRateLimited, nil)
 				pool.removeTx(txs[i].Hash())
 				drop--
 				queuedRateLimitCounter.Inc(1)
@@ -1083,6 +1289,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range list.Forward(nonce) {
 			hash := tx.Hash()
 			log.Trace("Removed old pending transaction", "hash", hash)
+			pool.dropStaleTx(tx)
 			delete(pool.all, hash)
 			pool.priced.Removed()
 		}
@@ -1091,6 +1298,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		for _, tx := range drops {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
+			pool.dropTx(tx, TxDropUnpayable, nil)
 			delete(pool.all, hash)
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
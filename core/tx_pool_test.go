@@ -23,6 +23,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -261,6 +262,89 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that transactions sent by accounts frozen by the chain configuration are
+// rejected once the freeze is active.
+func TestTransactionFrozenSender(t *testing.T) {
+	t.Parallel()
+
+	db, _ := kokdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, big.NewInt(1000000), new(event.Feed)}
+
+	frozen, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	config := *params.TestChainConfig
+	config.Freezes = []*params.FreezeConfig{{Block: big.NewInt(1), Addresses: []common.Address{crypto.PubkeyToAddress(frozen.PublicKey)}}}
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	for _, key := range []*ecdsa.PrivateKey{frozen, other} {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	}
+	if err := pool.AddRemote(transaction(0, big.NewInt(100000), frozen)); err != ErrFrozenAccount {
+		t.Errorf("frozen sender error mismatch: have %v, want %v", err, ErrFrozenAccount)
+	}
+	if err := pool.AddRemote(transaction(0, big.NewInt(100000), other)); err != nil {
+		t.Errorf("failed to add transaction of unfrozen sender: %v", err)
+	}
+}
+
+// Tests that the pending and queued transactions of accounts frozen by a later
+// freeze are evicted once the freeze activates.
+func TestTransactionFrozenEviction(t *testing.T) {
+	t.Parallel()
+
+	db, _ := kokdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, big.NewInt(1000000), new(event.Feed)}
+
+	first, _ := crypto.GenerateKey()
+	second, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	config := *params.TestChainConfig
+	config.Freezes = []*params.FreezeConfig{
+		{Block: big.NewInt(1), Addresses: []common.Address{crypto.PubkeyToAddress(first.PublicKey)}},
+		{Block: big.NewInt(2), Addresses: []common.Address{crypto.PubkeyToAddress(second.PublicKey)}},
+	}
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	for _, key := range []*ecdsa.PrivateKey{first, second, other} {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	}
+	if err := pool.AddRemote(transaction(0, big.NewInt(100000), first)); err != ErrFrozenAccount {
+		t.Errorf("frozen sender error mismatch: have %v, want %v", err, ErrFrozenAccount)
+	}
+	queued := transaction(3, big.NewInt(100000), second)
+	pool.AddRemotes([]*types.Transaction{
+		transaction(0, big.NewInt(100000), second),
+		transaction(1, big.NewInt(100000), second),
+		queued,
+		transaction(0, big.NewInt(100000), other),
+	})
+	if pending, queued := pool.Stats(); pending != 3 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 3/1", pending, queued)
+	}
+	// Move the head so that the second freeze applies to the next block
+	pool.lockedReset(nil, &types.Header{Number: big.NewInt(1), GasLimit: blockchain.gasLimit})
+
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatalf("pool stats mismatch after freeze: have %d/%d, want 1/0", pending, queued)
+	}
+	if len(pool.all) != 1 {
+		t.Errorf("transaction count mismatch: have %d, want 1", len(pool.all))
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	if dropped := pool.DroppedTransaction(queued.Hash()); dropped == nil || dropped.Reason != TxDropFrozen {
+		t.Errorf("drop record mismatch: have %v, want reason %v", dropped, TxDropFrozen)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -1449,6 +1533,318 @@ func TestTransactionReplacement(t *testing.T) {
 	}
 }
 
+// Tests that the replacement price bump can be changed pool wide and overridden
+// for individual accounts.
+func TestTransactionReplacementPolicy(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	other, _ := crypto.GenerateKey()
+	addr, otherAddr := crypto.PubkeyToAddress(key.PublicKey), crypto.PubkeyToAddress(other.PublicKey)
+
+	pool.currentState.AddBalance(addr, big.NewInt(1000000000))
+	pool.currentState.AddBalance(otherAddr, big.NewInt(1000000000))
+
+	// Raise the pool wide bump and lower it for one account only
+	pool.SetPriceBump(nil, 50)
+	pool.SetPriceBump(&otherAddr, 5)
+
+	if bump, bumps := pool.PriceBumps(); bump != 50 || len(bumps) != 1 || bumps[otherAddr] != 5 {
+		t.Fatalf("policy mismatch: have %d and %v, want 50 and {%x: 5}", bump, bumps, otherAddr)
+	}
+	for _, key := range []*ecdsa.PrivateKey{key, other} {
+		if err := pool.AddRemote(pricedTransaction(0, big.NewInt(100000), big.NewInt(100), key)); err != nil {
+			t.Fatalf("failed to add original pending transaction: %v", err)
+		}
+		if err := pool.AddRemote(pricedTransaction(2, big.NewInt(100000), big.NewInt(100), key)); err != nil {
+			t.Fatalf("failed to add original queued transaction: %v", err)
+		}
+	}
+	// A 10% bump is enough for the overridden account only, for both pending and queued
+	for _, nonce := range []uint64{0, 2} {
+		if err := pool.AddRemote(pricedTransaction(nonce, big.NewInt(100001), big.NewInt(110), key)); err != ErrReplaceUnderpriced {
+			t.Errorf("nonce %d: replacement error mismatch: have %v, want %v", nonce, err, ErrReplaceUnderpriced)
+		}
+		if err := pool.AddRemote(pricedTransaction(nonce, big.NewInt(100001), big.NewInt(110), other)); err != nil {
+			t.Errorf("nonce %d: failed to replace overridden transaction: %v", nonce, err)
+		}
+		if err := pool.AddRemote(pricedTransaction(nonce, big.NewInt(100000), big.NewInt(150), key)); err != nil {
+			t.Errorf("nonce %d: failed to replace transaction: %v", nonce, err)
+		}
+	}
+	// Resetting the override reverts the account to the pool wide bump
+	pool.ResetPriceBump(otherAddr)
+	if bump := pool.PriceBump(otherAddr); bump != 50 {
+		t.Errorf("reset bump mismatch: have %d, want 50", bump)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, big.NewInt(100000), big.NewInt(120), other)); err != ErrReplaceUnderpriced {
+		t.Errorf("reset replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that transactions dropped from the pool are announced and can be looked
+// up along with the reason of their eviction.
+func TestTransactionDropTracking(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	events := make(chan TxDropEvent, 32)
+	sub := pool.SubscribeTxDropEvent(events)
+	defer sub.Unsubscribe()
+
+	// Replace a pending transaction and ensure the replacement is tracked
+	original := pricedTransaction(0, big.NewInt(100000), big.NewInt(1), key)
+	replacement := pricedTransaction(0, big.NewInt(100000), big.NewInt(2), key)
+
+	if err := pool.AddRemote(original); err != nil {
+		t.Fatalf("failed to add original transaction: %v", err)
+	}
+	if err := pool.AddRemote(replacement); err != nil {
+		t.Fatalf("failed to add replacement transaction: %v", err)
+	}
+	dropped := pool.DroppedTransaction(original.Hash())
+	if dropped == nil {
+		t.Fatalf("replaced transaction not tracked")
+	}
+	if dropped.Reason != TxDropReplaced {
+		t.Errorf("replaced transaction reason mismatch: have %v, want %v", dropped.Reason, TxDropReplaced)
+	}
+	if dropped.ReplacedBy == nil || *dropped.ReplacedBy != replacement.Hash() {
+		t.Errorf("replaced transaction successor mismatch: have %v, want %x", dropped.ReplacedBy, replacement.Hash())
+	}
+	// Raise the price threshold and ensure the eviction is tracked
+	pool.SetGasPrice(big.NewInt(3))
+
+	if dropped := pool.DroppedTransaction(replacement.Hash()); dropped == nil || dropped.Reason != TxDropUnderpriced {
+		t.Errorf("underpriced transaction mismatch: have %v, want reason %v", dropped, TxDropUnderpriced)
+	}
+	if dropped := pool.DroppedTransaction(common.Hash{}); dropped != nil {
+		t.Errorf("unknown transaction tracked as dropped: %v", dropped)
+	}
+	// Ensure both drops were announced
+	seen := make(map[common.Hash]TxDropReason)
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-events:
+			seen[ev.Tx.Hash()] = ev.Dropped.Reason
+		case <-time.After(time.Second):
+			t.Fatalf("drop event #%d not fired", i)
+		}
+	}
+	if seen[original.Hash()] != TxDropReplaced || seen[replacement.Hash()] != TxDropUnderpriced {
+		t.Errorf("drop events mismatch: %v", seen)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that private transactions are tracked by the pool, and forgotten once
+// they leave it.
+func TestTransactionPrivacy(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	private := pricedTransaction(0, big.NewInt(100000), big.NewInt(1), key)
+	public := pricedTransaction(1, big.NewInt(100000), big.NewInt(1), key)
+
+	if err := pool.AddLocalTx(private, true); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if err := pool.AddLocal(public); err != nil {
+		t.Fatalf("failed to add public transaction: %v", err)
+	}
+	if !pool.IsPrivate(private.Hash()) {
+		t.Errorf("private transaction not tracked as private")
+	}
+	if pool.IsPrivate(public.Hash()) {
+		t.Errorf("public transaction tracked as private")
+	}
+	// Resubmitting a known transaction must not change its privacy
+	if err := pool.AddLocalTx(public, true); err == nil {
+		t.Errorf("duplicate transaction accepted")
+	}
+	if pool.IsPrivate(public.Hash()) {
+		t.Errorf("duplicate submission made transaction private")
+	}
+	// Replace the private transaction and ensure it's forgotten on the next reset
+	replacement := pricedTransaction(0, big.NewInt(100000), big.NewInt(2), key)
+	if err := pool.AddLocal(replacement); err != nil {
+		t.Fatalf("failed to add replacement transaction: %v", err)
+	}
+	pool.lockedReset(nil, nil)
+
+	if pool.IsPrivate(private.Hash()) || pool.IsPrivate(replacement.Hash()) {
+		t.Errorf("privacy mismatch after replacement: original %v, replacement %v", pool.IsPrivate(private.Hash()), pool.IsPrivate(replacement.Hash()))
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that transactions are dropped once their explicit expiry or the default
+// lifetime of the pool is reached.
+func TestTransactionExpiry(t *testing.T) {
+	t.Parallel()
+
+	db, _ := kokdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, big.NewInt(1000000), new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.MaxLifetime = time.Hour
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	var (
+		byBlock  = transaction(0, big.NewInt(100000), keys[0])
+		byTime   = transaction(0, big.NewInt(100000), keys[1])
+		byConfig = transaction(0, big.NewInt(100000), keys[2])
+	)
+	for _, tx := range []*types.Transaction{byBlock, byTime, byConfig} {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	now := time.Now()
+	if err := pool.SetExpiry(byBlock.Hash(), TxExpiry{Block: 10}); err != nil {
+		t.Fatalf("failed to set block expiry: %v", err)
+	}
+	if err := pool.SetExpiry(byTime.Hash(), TxExpiry{Time: now.Add(time.Minute)}); err != nil {
+		t.Fatalf("failed to set time expiry: %v", err)
+	}
+	if err := pool.SetExpiry(common.Hash{}, TxExpiry{Block: 1}); err == nil {
+		t.Errorf("expiry set for unknown transaction")
+	}
+	// Step through the expiries and check that they're dropped in order
+	steps := []struct {
+		head    uint64
+		now     time.Time
+		dropped []*types.Transaction
+	}{
+		{9, now, nil},
+		{10, now, []*types.Transaction{byBlock}},
+		{10, now.Add(2 * time.Minute), []*types.Transaction{byBlock, byTime}},
+		{10, now.Add(2 * time.Hour), []*types.Transaction{byBlock, byTime, byConfig}},
+	}
+	for i, step := range steps {
+		pool.mu.Lock()
+		pool.expire(step.head, step.now)
+		pool.mu.Unlock()
+
+		if pending, _ := pool.Stats(); pending != 3-len(step.dropped) {
+			t.Errorf("step %d: pending transactions mismatch: have %d, want %d", i, pending, 3-len(step.dropped))
+		}
+		for _, tx := range step.dropped {
+			if dropped := pool.DroppedTransaction(tx.Hash()); dropped == nil || dropped.Reason != TxDropExpired {
+				t.Errorf("step %d: transaction %x not dropped as expired: %v", i, tx.Hash(), dropped)
+			}
+		}
+		if err := validateTxPoolInternals(pool); err != nil {
+			t.Fatalf("step %d: pool internal state corrupted: %v", i, err)
+		}
+	}
+	if len(pool.expiries) != 0 {
+		t.Errorf("expiries of dropped transactions retained: %d", len(pool.expiries))
+	}
+}
+
+// Tests that the expiry of a submitted transaction is checked before it's added
+// to the pool and attached along with it.
+func TestTransactionAddWithExpiry(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	unfunded, _ := crypto.GenerateKey()
+
+	// Transactions expiring already or failing validation must not be pooled
+	expired := transaction(0, big.NewInt(100000), key)
+	if err := pool.AddLocalWithExpiry(expired, false, TxExpiry{Time: time.Now().Add(-time.Minute)}); err != ErrTxExpired {
+		t.Errorf("expired transaction error mismatch: have %v, want %v", err, ErrTxExpired)
+	}
+	invalid := transaction(0, big.NewInt(100000), unfunded)
+	if err := pool.AddLocalWithExpiry(invalid, false, TxExpiry{Block: 10}); err != ErrInsufficientFunds {
+		t.Errorf("invalid transaction error mismatch: have %v, want %v", err, ErrInsufficientFunds)
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("rejected transactions pooled: pending %d, queued %d", pending, queued)
+	}
+	if len(pool.expiries) != 0 {
+		t.Errorf("expiries of rejected transactions retained: %d", len(pool.expiries))
+	}
+	// Valid transactions should be pooled with their expiry attached
+	expiry := TxExpiry{Block: 10}
+	if err := pool.AddLocalWithExpiry(expired, false, expiry); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if have := pool.expiries[expired.Hash()]; have != expiry {
+		t.Errorf("expiry mismatch: have %v, want %v", have, expiry)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the pool explains why transactions are held back from execution.
+func TestTransactionDiagnosis(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	// Fund the account for a single transaction only
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(150000))
+
+	executable := transaction(0, big.NewInt(100000), key)
+	gapped := transaction(3, big.NewInt(100000), key)
+	for _, tx := range []*types.Transaction{executable, gapped} {
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	if diag := pool.Diagnose(executable.Hash()); diag.Status != TxStatusPending || len(diag.Reasons) != 0 {
+		t.Errorf("executable transaction mismatch: have status %v, reasons %v; want pending without reasons", diag.Status, diag.Reasons)
+	}
+	diag := pool.Diagnose(gapped.Hash())
+	if diag.Status != TxStatusQueued {
+		t.Errorf("gapped transaction status mismatch: have %v, want %v", diag.Status, TxStatusQueued)
+	}
+	if diag.ExpectedNonce != 1 || !reflect.DeepEqual(diag.MissingNonces, []uint64{1, 2}) {
+		t.Errorf("nonce gap mismatch: have next %d, missing %v; want next 1, missing [1 2]", diag.ExpectedNonce, diag.MissingNonces)
+	}
+	if want := []TxHoldReason{TxHoldInsufficientFunds, TxHoldNonceGap}; !reflect.DeepEqual(diag.Reasons, want) {
+		t.Errorf("reasons mismatch: have %v, want %v", diag.Reasons, want)
+	}
+	if want := new(big.Int).Add(executable.Cost(), gapped.Cost()); diag.Cost.Cmp(want) != 0 {
+		t.Errorf("cumulative cost mismatch: have %v, want %v", diag.Cost, want)
+	}
+	if diag := pool.Diagnose(common.Hash{}); diag.Status != TxStatusUnknown || diag.Dropped != nil {
+		t.Errorf("unknown transaction mismatch: have status %v, drop %v", diag.Status, diag.Dropped)
+	}
+}
+
 // Tests that local transactions are journaled to disk, but remote transactions
 // get discarded between restarts.
 func TestTransactionJournaling(t *testing.T)         { testTransactionJournaling(t, false) }
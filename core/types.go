@@ -39,6 +39,16 @@ type Validator interface {
 	ValidateDposState(block *types.Block) error
 }
 
+// Prefetcher is an interface for pre-caching transaction signatures and state.
+//
+// Prefetch loads the data a block is expected to access on top of the given
+// statedb, with the goal of warming caches ahead of the block's execution. The
+// statedb may be modified, so callers should pass a throwaway copy. Prefetching
+// should stop as soon as the interrupt flag is set to 1.
+type Prefetcher interface {
+	Prefetch(block *types.Block, statedb *state.StateDB, interrupt *uint32)
+}
+
 // Processor is an interface for processing blocks using a given initial state.
 //
 // Process takes the block to be processed and the statedb upon which the
@@ -0,0 +1,28 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import "github.com/kokprojects/go-kok/common"
+
+// ContractCreation is the record of a contract or template deployed during the
+// execution of a transaction.
+type ContractCreation struct {
+	Address common.Address // Address of the deployed contract
+	Creator common.Address // Account deploying the contract, sender or contract
+	Type    string         // Deployment flow (CREATE or TEMPLATE)
+	TxHash  common.Hash    // Transaction during which the contract was deployed
+}
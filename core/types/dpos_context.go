@@ -341,7 +341,10 @@ func (dc *DposContext) SetMintCnt(mintCnt *trie.Trie)     { dc.mintCntTrie = min
 func (dc *DposContext) GetValidators() ([]common.Address, error) {
 	var validators []common.Address
 	key := []byte("validator")
-	validatorsRLP := dc.epochTrie.Get(key)
+	validatorsRLP, err := dc.epochTrie.TryGet(key)
+	if err != nil {
+		return nil, err
+	}
 	if err := rlp.DecodeBytes(validatorsRLP, &validators); err != nil {
 		return nil, fmt.Errorf("failed to decode validators: %s", err)
 	}
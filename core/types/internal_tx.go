@@ -0,0 +1,33 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/kokprojects/go-kok/common"
+)
+
+// InternalTx is a value transfer made by a contract during the execution of a
+// transaction, as opposed to by the transaction itself.
+type InternalTx struct {
+	Type  string         // Operation making the transfer (CALL, CREATE or SELFDESTRUCT)
+	From  common.Address // Contract sending the value
+	To    common.Address // Account receiving the value
+	Value *big.Int       // Amount of value transferred
+	Depth uint64         // Call depth of the sending contract, the transaction being 0
+}
@@ -58,7 +58,9 @@ type Receipt struct {
 	GasUsed         *big.Int       `json:"gasUsed" gencodec:"required"`
 	GasDeveloper    *big.Int       `json:"gasDeveloper" gencodec:"required"`
 	GasMiner        *big.Int       `json:"gasMiner " gencodec:"required"`
+	GasTreasury     *big.Int       `json:"gasTreasury,omitempty"`
 	TxType          string         `json:"TxType"`
+	RevertReason    []byte         `json:"revertReason,omitempty"` // Return data of a failed execution, not stored with the receipt
 }
 
 type receiptMarshaling struct {
@@ -90,6 +92,7 @@ type receiptStorageRLP struct {
 	GasUsed           *big.Int
 	GasDeveloper      *big.Int
 	GasMiner          *big.Int
+	GasTreasury       []*big.Int `rlp:"tail"` // Empty unless fees were diverted, keeps older receipts decodable
 }
 
 // NewReceipt creates a barebone transaction receipt, copying the init fields.
@@ -175,6 +178,9 @@ func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
 		GasUsed:           r.GasUsed,
 		TxType:            r.TxType,
 	}
+	if r.GasTreasury != nil {
+		enc.GasTreasury = []*big.Int{r.GasTreasury}
+	}
 	for i, log := range r.Logs {
 		enc.Logs[i] = (*LogForStorage)(log)
 	}
@@ -199,6 +205,9 @@ func (r *ReceiptForStorage) DecodeRLP(s *rlp.Stream) error {
 	}
 	// Assign the implementation fields
 	r.TxHash, r.ContractAddress, r.GasUsed, r.TemplateAddress, r.GasDeveloper, r.GasMiner, r.TxType = dec.TxHash, dec.ContractAddress, dec.GasUsed, dec.TemplateAddress, dec.GasDeveloper, dec.GasMiner, dec.TxType
+	if len(dec.GasTreasury) > 0 {
+		r.GasTreasury = dec.GasTreasury[0]
+	}
 	return nil
 }
 
@@ -17,12 +17,14 @@
 package types
 
 import (
+	"bytes"
 	"container/heap"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"sync/atomic"
+	"time"
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/common/hexutil"
@@ -32,19 +34,6 @@ import (
 
 //go:generate gencodec -type txdata -field-override txdataMarshaling -out gen_tx_json.go
 
-// transaction type
-type TxType uint8
-
-const (
-	Binary TxType = iota
-	LoginCandidate
-	LogoutCandidate
-	Delegate
-	UnDelegate
-	SourceCode
-	Endorse
-)
-
 const MortgageAsset = "10000000000000000000000"
 
 var (
@@ -69,6 +58,7 @@ func deriveSigner(V *big.Int) Signer {
 
 type Transaction struct {
 	data txdata
+	time time.Time // Time the transaction was created or first decoded locally
 	// caches
 	hash atomic.Value
 	size atomic.Value
@@ -143,7 +133,7 @@ func newTransaction(txType TxType, nonce uint64, to *common.Address, amount, gas
 		d.Price.Set(gasPrice)
 	}
 
-	return &Transaction{data: d}
+	return &Transaction{data: d, time: time.Now()}
 }
 
 // ChainId returns which chain id this transaction was signed for (if at all)
@@ -151,20 +141,10 @@ func (tx *Transaction) ChainId() *big.Int {
 	return deriveChainId(tx.data.V)
 }
 
-// Valid the transaction when the type isn't the binary
+// Validate checks the transaction against the rules of its type, e.g. only plain
+// binary transactions may transfer value.
 func (tx *Transaction) Validate() error {
-	if tx.Type() != Binary {
-		if tx.Value().Uint64() != 0 {
-			return errors.New("transaction value should be 0")
-		}
-		if tx.To() == nil && tx.Type() != LoginCandidate && tx.Type() != LogoutCandidate {
-			return errors.New("receipient was required")
-		}
-		//if tx.Data() != nil {
-		//	return errors.New("payload should be empty")
-		//}
-	}
-	return nil
+	return tx.data.Type.validate(tx)
 }
 
 // Protected returns whkoker the transaction is protected from replay protection.
@@ -181,20 +161,60 @@ func isProtectedV(V *big.Int) bool {
 	return true
 }
 
-// DecodeRLP implements rlp.Encoder
+// EncodeRLP implements rlp.Encoder, encoding legacy transaction types as an
+// RLP list and enveloped types as an RLP string of the type byte followed by
+// the RLP list.
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
+	if !tx.data.Type.enveloped() {
+		return rlp.Encode(w, &tx.data)
+	}
+	envelope := new(bytes.Buffer)
+	envelope.WriteByte(byte(tx.data.Type))
+	if err := rlp.Encode(envelope, &tx.data); err != nil {
+		return err
+	}
+	return rlp.Encode(w, envelope.Bytes())
 }
 
 // DecodeRLP implements rlp.Decoder
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.data)
-	if err == nil {
+	kind, size, err := s.Kind()
+	if err != nil {
+		return err
+	}
+	var data txdata
+	if kind == rlp.List {
+		// Legacy transaction, which enveloped types must not be encoded as
+		if err := s.Decode(&data); err != nil {
+			return err
+		}
+		if data.Type.enveloped() {
+			return ErrInvalidType
+		}
+		tx.data, tx.time = data, time.Now()
 		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
+		return nil
 	}
-
-	return err
+	// Typed envelope, reject the types unknown to the node
+	envelope, err := s.Bytes()
+	if err != nil {
+		return err
+	}
+	if len(envelope) == 0 {
+		return ErrInvalidType
+	}
+	if typ := TxType(envelope[0]); !typ.enveloped() {
+		return ErrTxTypeNotSupported
+	}
+	if err := rlp.DecodeBytes(envelope[1:], &data); err != nil {
+		return err
+	}
+	if data.Type != TxType(envelope[0]) {
+		return ErrInvalidType
+	}
+	tx.data, tx.time = data, time.Now()
+	tx.size.Store(common.StorageSize(rlp.ListSize(uint64(len(envelope)))))
+	return nil
 }
 
 func (tx *Transaction) MarshalJSON() ([]byte, error) {
@@ -220,7 +240,7 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 	if !crypto.ValidateSignatureValues(V, dec.R, dec.S, false) {
 		return ErrInvalidSig
 	}
-	*tx = Transaction{data: dec}
+	*tx = Transaction{data: dec, time: time.Now()}
 	return nil
 }
 
@@ -232,6 +252,9 @@ func (tx *Transaction) Nonce() uint64      { return tx.data.AccountNonce }
 func (tx *Transaction) CheckNonce() bool   { return true }
 func (tx *Transaction) Type() TxType       { return tx.data.Type }
 
+// Time returns the time the transaction was created or first received locally.
+func (tx *Transaction) Time() time.Time { return tx.time }
+
 // To returns the recipient address of the transaction.
 // It returns nil if the transaction is a contract creation.
 func (tx *Transaction) To() *common.Address {
@@ -259,7 +282,7 @@ func (tx *Transaction) Size() common.StorageSize {
 		return size.(common.StorageSize)
 	}
 	c := writeCounter(0)
-	rlp.Encode(&c, &tx.data)
+	rlp.Encode(&c, tx)
 	tx.size.Store(common.StorageSize(c))
 	return common.StorageSize(c)
 }
@@ -293,7 +316,7 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	if err != nil {
 		return nil, err
 	}
-	cpy := &Transaction{data: tx.data}
+	cpy := &Transaction{data: tx.data, time: tx.time}
 	cpy.data.R, cpy.data.S, cpy.data.V = r, s, v
 	return cpy, nil
 }
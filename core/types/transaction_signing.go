@@ -20,12 +20,11 @@ import (
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
+	"math/big"
+
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/crypto"
-	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/params"
-	"math/big"
-	"reflect"
 )
 
 var (
@@ -44,13 +43,10 @@ func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	var signer Signer
 	switch {
 	case config.IsEIP155(blockNumber):
-		log.Warn("EIP")
 		signer = NewEIP155Signer(config.ChainId)
 	case config.IsHomestead(blockNumber):
-		log.Warn("Homestead")
 		signer = HomesteadSigner{}
 	default:
-		log.Warn("default")
 		signer = FrontierSigner{}
 	}
 	return signer
@@ -74,7 +70,6 @@ func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, err
 // signing mkokod. The cache is invalidated if the cached signer does
 // not match the signer used in the current call.
 func Sender(signer Signer, tx *Transaction) (common.Address, error) {
-	//log.Warn(tx.data.Hash.Hex())
 	if sc := tx.from.Load(); sc != nil {
 		sigCache := sc.(sigCache)
 		// If the signer used to derive from in a previous
@@ -84,7 +79,6 @@ func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 			return sigCache.from, nil
 		}
 	}
-	log.Warn(reflect.TypeOf(signer).Name())
 	addr, err := signer.Sender(tx)
 	if err != nil {
 		return common.Address{}, err
@@ -137,9 +131,7 @@ func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
 		return common.Address{}, ErrInvalidChainId
 	}
 	V := new(big.Int).Sub(tx.data.V, s.chainIdMul)
-	log.Warn("V"+V.String())
 	V.Sub(V, big8)
-	log.Warn("Vsub"+V.String())
 	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
 }
 
@@ -160,7 +152,7 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
-	return rlpHash([]interface{}{
+	fields := []interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
 		tx.data.GasLimit,
@@ -168,7 +160,11 @@ func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
 		tx.data.Amount,
 		tx.data.Payload,
 		s.chainId, uint(0), uint(0),
-	})
+	}
+	if tx.data.Type.enveloped() {
+		fields = append(fields, tx.data.Type)
+	}
+	return rlpHash(fields)
 }
 
 // HomesteadTransaction implements TransactionInterface using the
@@ -212,14 +208,18 @@ func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
-	return rlpHash([]interface{}{
+	fields := []interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
 		tx.data.GasLimit,
 		tx.data.Recipient,
 		tx.data.Amount,
 		tx.data.Payload,
-	})
+	}
+	if tx.data.Type.enveloped() {
+		fields = append(fields, tx.data.Type)
+	}
+	return rlpHash(fields)
 }
 
 func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
@@ -22,8 +22,37 @@ import (
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/params"
 )
 
+func TestMakeSigner(t *testing.T) {
+	config := &params.ChainConfig{ChainId: big.NewInt(18), HomesteadBlock: big.NewInt(1), EIP155Block: big.NewInt(3)}
+	tests := []struct {
+		number int64
+		want   Signer
+	}{
+		{0, FrontierSigner{}},
+		{1, HomesteadSigner{}},
+		{2, HomesteadSigner{}},
+		{3, NewEIP155Signer(big.NewInt(18))},
+		{10, NewEIP155Signer(big.NewInt(18))},
+	}
+	for _, tt := range tests {
+		if signer := MakeSigner(config, big.NewInt(tt.number)); !signer.Equal(tt.want) {
+			t.Errorf("block %d: signer mismatch: have %T, want %T", tt.number, signer, tt.want)
+		}
+	}
+	// Transactions signed for another chain must be rejected after the fork
+	key, _ := crypto.GenerateKey()
+	tx, err := SignTx(NewTransaction(Binary, 0, common.Address{}, new(big.Int), new(big.Int), new(big.Int), nil), NewEIP155Signer(big.NewInt(19)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sender(MakeSigner(config, big.NewInt(3)), tx); err != ErrInvalidChainId {
+		t.Errorf("replayed transaction error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+}
+
 func TestEIP155Signing(t *testing.T) {
 	key, _ := crypto.GenerateKey()
 	addr := crypto.PubkeyToAddress(key.PublicKey)
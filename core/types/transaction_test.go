@@ -225,3 +225,71 @@ func TestTransactionValidate(t *testing.T) {
 		}
 	}
 }
+
+// Tests that enveloped transaction types are encoded with their type byte, are
+// signed along with their type and are rejected by nodes not supporting them.
+func TestTransactionEnvelope(t *testing.T) {
+	const typ = TxType(0x40)
+	txTypeSpecs[typ] = txTypeSpec{name: "test", enveloped: true, recipient: true}
+	defer delete(txTypeSpecs, typ)
+
+	key, _ := crypto.GenerateKey()
+	signer := NewEIP155Signer(common.Big1)
+
+	legacy, _ := SignTx(newTransaction(Delegate, 1, &common.Address{1}, common.Big0, common.Big1, common.Big2, nil), signer, key)
+	typed, _ := SignTx(newTransaction(typ, 1, &common.Address{1}, common.Big0, common.Big1, common.Big2, nil), signer, key)
+	if signer.Hash(legacy) == signer.Hash(typed) {
+		t.Errorf("type not covered by the signature hash")
+	}
+	blob, err := rlp.EncodeToBytes(typed)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	var envelope []byte
+	if err := rlp.DecodeBytes(blob, &envelope); err != nil || envelope[0] != byte(typ) {
+		t.Fatalf("envelope mismatch: have %x, err %v", envelope, err)
+	}
+	parsed := new(Transaction)
+	if err := rlp.DecodeBytes(blob, parsed); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if parsed.Hash() != typed.Hash() || parsed.Size() != typed.Size() {
+		t.Errorf("decoded transaction mismatch")
+	}
+	if from, err := Sender(signer, parsed); err != nil || from != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("sender mismatch: have %x, err %v", from, err)
+	}
+	// Forget the type and ensure the envelope is rejected
+	delete(txTypeSpecs, typ)
+	if err := rlp.DecodeBytes(blob, new(Transaction)); err != ErrTxTypeNotSupported {
+		t.Errorf("unsupported type error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
+// Tests that transaction types can be given as numbers, hex quantities or names.
+func TestTxTypeJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  TxType
+		fail  bool
+	}{
+		{input: `3`, want: Delegate},
+		{input: `"0x6"`, want: Endorse},
+		{input: `"sourceCode"`, want: SourceCode},
+		{input: `"unknown"`, fail: true},
+		{input: `"0x100"`, fail: true},
+	}
+	for i, tt := range tests {
+		var typ TxType
+		err := json.Unmarshal([]byte(tt.input), &typ)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("test %d: expected error for %s", i, tt.input)
+			}
+			continue
+		}
+		if err != nil || typ != tt.want {
+			t.Errorf("test %d: type mismatch: have %v (err %v), want %v", i, typ, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// transaction type
+type TxType uint8
+
+const (
+	Binary TxType = iota
+	LoginCandidate
+	LogoutCandidate
+	Delegate
+	UnDelegate
+	SourceCode
+	Endorse
+)
+
+var (
+	// ErrTxTypeNotSupported is returned if a transaction of a type unknown to the
+	// node is decoded or validated.
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+
+	errTxValue     = errors.New("transaction value should be 0")
+	errTxRecipient = errors.New("receipient was required")
+)
+
+// txTypeSpec describes the encoding and the validity rules of a transaction type.
+//
+// The types predating the envelope are encoded as a plain RLP list with the type
+// as first field, and their signature doesn't cover the type. Enveloped types
+// are encoded as an RLP string holding the type byte followed by the RLP list,
+// and are signed along with their type. Nodes not knowing an enveloped type
+// reject it with ErrTxTypeNotSupported instead of misinterpreting it, so new
+// kinds of transactions can be added as enveloped types.
+type txTypeSpec struct {
+	name      string
+	enveloped bool // Whkoker the type is encoded in a typed envelope
+	recipient bool // Whkoker the transaction requires a recipient
+	value     bool // Whkoker the transaction may transfer value
+	payload   bool // Whkoker the transaction may carry input data
+}
+
+// txTypeSpecs are the specifications of all supported transaction types.
+var txTypeSpecs = map[TxType]txTypeSpec{
+	Binary:          {name: "binary", value: true, payload: true},
+	LoginCandidate:  {name: "loginCandidate"},
+	LogoutCandidate: {name: "logoutCandidate"},
+	Delegate:        {name: "delegate", recipient: true},
+	UnDelegate:      {name: "unDelegate", recipient: true},
+	SourceCode:      {name: "sourceCode", recipient: true, payload: true},
+	Endorse:         {name: "endorse", recipient: true, payload: true},
+}
+
+// Supported returns whkoker the transaction type is known to the node.
+func (t TxType) Supported() bool {
+	_, ok := txTypeSpecs[t]
+	return ok
+}
+
+// enveloped returns whkoker the transaction type is encoded in a typed envelope.
+func (t TxType) enveloped() bool {
+	return txTypeSpecs[t].enveloped
+}
+
+// String implements fmt.Stringer, returning the name of the transaction type.
+func (t TxType) String() string {
+	if spec, ok := txTypeSpecs[t]; ok {
+		return spec.name
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(t))
+}
+
+// UnmarshalJSON parses a transaction type given either as a number, a hex
+// quantity or by its name.
+func (t *TxType) UnmarshalJSON(input []byte) error {
+	var number uint8
+	if err := json.Unmarshal(input, &number); err == nil {
+		*t = TxType(number)
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(input, &text); err != nil {
+		return fmt.Errorf("invalid transaction type %s", input)
+	}
+	if strings.HasPrefix(text, "0x") {
+		number, err := strconv.ParseUint(text[2:], 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid transaction type %q", text)
+		}
+		*t = TxType(number)
+		return nil
+	}
+	for typ, spec := range txTypeSpecs {
+		if spec.name == text {
+			*t = typ
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown transaction type %q", text)
+}
+
+// validate checks the fields of a transaction against the rules of its type.
+func (t TxType) validate(tx *Transaction) error {
+	spec, ok := txTypeSpecs[t]
+	if !ok {
+		return ErrTxTypeNotSupported
+	}
+	if !spec.value && tx.data.Amount.Sign() != 0 {
+		return errTxValue
+	}
+	if spec.recipient && tx.data.Recipient == nil {
+		return errTxRecipient
+	}
+	if !spec.payload && len(tx.data.Payload) != 0 {
+		return ErrInvalidInput
+	}
+	return nil
+}
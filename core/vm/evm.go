@@ -17,11 +17,13 @@
 package vm
 
 import (
+	"context"
 	"fmt"
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/tracing"
 	"math/big"
 	"strconv"
 	"sync/atomic"
@@ -42,11 +44,7 @@ type (
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, snapshot int, contract *Contract, input []byte) ([]byte, error) {
 	if contract.CodeAddr != nil {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if p := precompiles[*contract.CodeAddr]; p != nil {
+		if p := evm.precompile(*contract.CodeAddr); p != nil {
 			return RunPrecompiledContract(p, input, contract)
 		}
 	}
@@ -106,6 +104,9 @@ type EVM struct {
 	// abort is used to abort the EVM calling operations
 	// NOTE: must be set atomically
 	abort int32
+	// traceCtx is the context of the span of the current call frame, nil if
+	// the execution isn't traced
+	traceCtx context.Context
 }
 
 // NewEVM retutrns a new EVM . The returned EVM is not thread safe and should
@@ -117,6 +118,7 @@ func NewEVM(ctx Context, statedb StateDB, chainConfig *params.ChainConfig, vmCon
 		vmConfig:    vmConfig,
 		chainConfig: chainConfig,
 		chainRules:  chainConfig.Rules(ctx.BlockNumber),
+		traceCtx:    vmConfig.TraceContext,
 	}
 
 	evm.interpreter = NewInterpreter(evm, vmConfig)
@@ -129,6 +131,27 @@ func (evm *EVM) Cancel() {
 	atomic.StoreInt32(&evm.abort, 1)
 }
 
+// traceFrame opens a span for a call frame as a child of the current frame's,
+// if the execution is traced. The returned function, nil if it isn't, closes
+// the span with the outcome of the frame and makes its parent current again.
+func (evm *EVM) traceFrame(kind string, addr common.Address, gas uint64) func(error) {
+	if evm.traceCtx == nil {
+		return nil
+	}
+	parent := evm.traceCtx
+	ctx, span := tracing.Start(parent, "evm."+kind)
+	span.SetAttribute("address", addr.Hex())
+	span.SetAttribute("depth", evm.depth)
+	span.SetAttribute("gas", gas)
+	evm.traceCtx = ctx
+
+	return func(err error) {
+		span.SetError(err)
+		span.End()
+		evm.traceCtx = parent
+	}
+}
+
 // Call executes the contract associated with the addr with the given input as
 // parameters. It also handles any necessary value transfer required and takes
 // the necessary steps to create accounts and reverses the state in case of an
@@ -142,6 +165,9 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	if end := evm.traceFrame("CALL", addr, gas); end != nil {
+		defer func() { end(err) }()
+	}
 
 	if len(Validators) != 0 {
 		for _, ValidatorAddress := range Validators {
@@ -168,16 +194,13 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		snapshot = evm.StateDB.Snapshot()
 	)
 	if !evm.StateDB.Exist(addr) {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if precompiles[addr] == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
+		if evm.precompile(addr) == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
 			return nil, gas, nil
 		}
 		evm.StateDB.CreateAccount(addr)
 	}
 	evm.Transfer(evm.StateDB, caller.Address(), to.Address(), value)
+	evm.recordInternalTx("CALL", caller.Address(), to.Address(), value)
 
 	// initialise a new contract and set the code that is to be used by the
 	// E The contract is a scoped environment for this execution context
@@ -214,6 +237,9 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	if end := evm.traceFrame("CALLCODE", addr, gas); end != nil {
+		defer func() { end(err) }()
+	}
 	// Fail if we're trying to transfer more than the available balance
 	if !evm.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, gas, ErrInsufficientBalance
@@ -252,6 +278,9 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	if end := evm.traceFrame("DELEGATECALL", addr, gas); end != nil {
+		defer func() { end(err) }()
+	}
 
 	var (
 		snapshot = evm.StateDB.Snapshot()
@@ -284,6 +313,9 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	if end := evm.traceFrame("STATICCALL", addr, gas); end != nil {
+		defer func() { end(err) }()
+	}
 	// Make sure the readonly is only set if we aren't in readonly yet
 	// this makes also sure that the readonly flag isn't removed for
 	// child calls.
@@ -331,6 +363,9 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
 
 	contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	if end := evm.traceFrame("CREATE", contractAddr, gas); end != nil {
+		defer func() { end(err) }()
+	}
 	contractHash := evm.StateDB.GetCodeHash(contractAddr)
 	if evm.StateDB.GetNonce(contractAddr) != 0 || (contractHash != (common.Hash{}) && contractHash != emptyCodeHash) {
 		return nil, common.Address{}, 0, ErrContractAddressCollision
@@ -342,6 +377,8 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 		evm.StateDB.SetNonce(contractAddr, 1)
 	}
 	evm.Transfer(evm.StateDB, caller.Address(), contractAddr, value)
+	evm.recordInternalTx("CREATE", caller.Address(), contractAddr, value)
+	evm.StateDB.AddContractCreation(&types.ContractCreation{Address: contractAddr, Creator: caller.Address(), Type: "CREATE"})
 
 	// initialise a new contract and set the code that is to be used by the
 	// E The contract is a scoped evmironment for this execution context
@@ -442,6 +479,7 @@ func (evm *EVM) Template(caller ContractRef, code []byte, gas uint64, value *big
 		evm.StateDB.SetNonce(contractAddr, 1)
 	}
 	evm.Transfer(evm.StateDB, caller.Address(), contractAddr, value)
+	evm.StateDB.AddContractCreation(&types.ContractCreation{Address: contractAddr, Creator: caller.Address(), Type: "TEMPLATE"})
 
 	// initialise a new contract and set the code that is to be used by the
 	// E The contract is a scoped evmironment for this execution context
@@ -481,11 +519,7 @@ func (evm *EVM) Endorse(caller ContractRef, addr common.Address, input []byte, g
 		to = AccountRef(addr)
 	)
 	if !evm.StateDB.Exist(addr) {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if precompiles[addr] == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
+		if evm.precompile(addr) == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
 			return nil, gas, nil
 		}
 		evm.StateDB.CreateAccount(addr)
@@ -564,11 +598,7 @@ func (evm *EVM) SourceCode(caller ContractRef, addr common.Address, input []byte
 		to = AccountRef(addr)
 	)
 	if !evm.StateDB.Exist(addr) {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if precompiles[addr] == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
+		if evm.precompile(addr) == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
 			return nil, gas, nil
 		}
 		evm.StateDB.CreateAccount(addr)
@@ -609,3 +639,18 @@ func (evm *EVM) SourceCode(caller ContractRef, addr common.Address, input []byte
 
 	return ret, gas, err
 }
+
+// recordInternalTx records a value transfer made by a contract into the state,
+// if enabled. Transfers made by the transaction itself are not recorded.
+func (evm *EVM) recordInternalTx(kind string, from, to common.Address, value *big.Int) {
+	if !evm.vmConfig.EnableInternalTxRecording || evm.depth == 0 || value.Sign() == 0 {
+		return
+	}
+	evm.StateDB.AddInternalTx(&types.InternalTx{
+		Type:  kind,
+		From:  from,
+		To:    to,
+		Value: new(big.Int).Set(value),
+		Depth: uint64(evm.depth),
+	})
+}
@@ -742,7 +742,9 @@ func opStop(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Sta
 
 func opSuicide(pc *uint64, evm *EVM, contract *Contract, memory *Memory, stack *Stack) ([]byte, error) {
 	balance := evm.StateDB.GetBalance(contract.Address())
-	evm.StateDB.AddBalance(common.BigToAddress(stack.pop()), balance)
+	beneficiary := common.BigToAddress(stack.pop())
+	evm.StateDB.AddBalance(beneficiary, balance)
+	evm.recordInternalTx("SELFDESTRUCT", contract.Address(), beneficiary, balance)
 
 	evm.StateDB.Suicide(contract.Address())
 	return nil, nil
@@ -60,6 +60,8 @@ type StateDB interface {
 
 	AddLog(*types.Log)
 	AddPreimage(common.Hash, []byte)
+	AddInternalTx(*types.InternalTx)
+	AddContractCreation(*types.ContractCreation)
 
 	ForEachStorage(common.Address, func(common.Hash, common.Hash) bool)
 }
@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"context"
 	"fmt"
 	"sync/atomic"
 
@@ -43,6 +44,18 @@ type Config struct {
 	DisableGasMetering bool
 	// Enable recording of SHA3/keccak preimages
 	EnablePreimageRecording bool
+	// Enable recording of per-transaction balance changes
+	EnableBalanceRecording bool
+	// Enable recording of value transfers made by contracts
+	EnableInternalTxRecording bool
+	// Enable recording of the return data of reverted transactions
+	EnableRevertRecording bool
+	// Enable auditing of the balance and gas refund accounting of each
+	// transaction, failing the ones that violate it
+	EnableAudit bool
+	// TraceContext is the context of the trace the call frames are recorded
+	// in, nil if they aren't traced
+	TraceContext context.Context
 	// JumpTable contains the EVM instruction table. This
 	// may be left uninitialised and will be set to the default
 	// table.
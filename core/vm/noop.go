@@ -67,4 +67,6 @@ func (NoopStateDB) RevertToSnapshot(int)
 func (NoopStateDB) Snapshot() int                                                      { return 0 }
 func (NoopStateDB) AddLog(*types.Log)                                                  {}
 func (NoopStateDB) AddPreimage(common.Hash, []byte)                                    {}
+func (NoopStateDB) AddInternalTx(*types.InternalTx)                                    {}
+func (NoopStateDB) AddContractCreation(*types.ContractCreation)                        {}
 func (NoopStateDB) ForEachStorage(common.Address, func(common.Hash, common.Hash) bool) {}
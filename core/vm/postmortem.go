@@ -0,0 +1,104 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import "time"
+
+// Kinds of execution points captured by the PostMortemLogger.
+const (
+	PostMortemCall   = "call"   // Contract about to call or create another
+	PostMortemReturn = "return" // Contract about to return to its caller
+	PostMortemRevert = "revert" // Contract about to revert its changes
+	PostMortemError  = "error"  // Contract failing, consuming all its gas
+)
+
+// PostMortemConfig selects the execution points captured by the PostMortemLogger.
+type PostMortemConfig struct {
+	DisableCalls   bool // disable capture at call and return boundaries
+	DisableReverts bool // disable capture at reverts and failures
+}
+
+// PostMortemLogger is an EVM state logger capturing the full state only at call
+// boundaries and at the points where execution reverts or fails, rather than at
+// every step. It implements Tracer.
+type PostMortemLogger struct {
+	cfg    PostMortemConfig
+	logger *StructLogger
+	kinds  []string
+}
+
+// NewPostMortemLogger returns a new post-mortem logger, capturing the state as
+// configured by the given structured logging options.
+func NewPostMortemLogger(logCfg *LogConfig, cfg *PostMortemConfig) *PostMortemLogger {
+	logger := &PostMortemLogger{logger: NewStructLogger(logCfg)}
+	if cfg != nil {
+		logger.cfg = *cfg
+	}
+	return logger
+}
+
+// kind returns the kind of execution point of a step, or an empty string if it
+// shouldn't be captured.
+func (l *PostMortemLogger) kind(op OpCode, err error) string {
+	switch {
+	case err != nil && !l.cfg.DisableReverts:
+		return PostMortemError
+	case err != nil:
+		return ""
+	case op == REVERT && !l.cfg.DisableReverts:
+		return PostMortemRevert
+	case l.cfg.DisableCalls:
+		return ""
+	}
+	switch op {
+	case CALL, CALLCODE, DELEGATECALL, STATICCALL, CREATE:
+		return PostMortemCall
+	case RETURN, STOP, SELFDESTRUCT:
+		return PostMortemReturn
+	}
+	return ""
+}
+
+// CaptureState implements Tracer, logging the state at the selected execution
+// points and tracking storage changes elsewhere.
+func (l *PostMortemLogger) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	kind := l.kind(op, err)
+	if kind == "" {
+		l.logger.trackStorage(op, stack, contract)
+		return nil
+	}
+	if err := l.logger.CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err); err != nil {
+		return err
+	}
+	l.kinds = append(l.kinds, kind)
+	return nil
+}
+
+// CaptureEnd implements Tracer.
+func (l *PostMortemLogger) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	return nil
+}
+
+// StructLogs returns the captured log entries.
+func (l *PostMortemLogger) StructLogs() []StructLog {
+	return l.logger.StructLogs()
+}
+
+// Kinds returns the kinds of execution points of the captured log entries.
+func (l *PostMortemLogger) Kinds() []string {
+	return l.kinds
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/params"
+	"golang.org/x/crypto/ed25519"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]PrecompiledContract{
+		"ed25519Verify": &ed25519Verify{},
+	}
+)
+
+// RegisterPrecompile makes a precompiled contract available under the given name,
+// for chain configurations to activate at an address from a block on. It is meant
+// to be called from init functions, before any chain is opened.
+func RegisterPrecompile(name string, p PrecompiledContract) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[name]; exists {
+		return fmt.Errorf("precompile %q already registered", name)
+	}
+	registry[name] = p
+	return nil
+}
+
+// CheckPrecompiles verifies that all the precompiled contracts activated by the
+// chain configuration are registered.
+func CheckPrecompiles(config *params.ChainConfig) error {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, p := range config.Precompiles {
+		if registry[p.Name] == nil {
+			return fmt.Errorf("unknown precompile %v", p)
+		}
+	}
+	return nil
+}
+
+// precompile returns the precompiled contract at addr at the current block, nil
+// if none. Contracts activated by the chain configuration take precedence over
+// the built-in ones.
+func (evm *EVM) precompile(addr common.Address) PrecompiledContract {
+	if len(evm.ChainConfig().Precompiles) > 0 {
+		if p := evm.ChainConfig().Precompile(evm.BlockNumber, addr); p != nil {
+			registryMu.RLock()
+			defer registryMu.RUnlock()
+			return registry[p.Name]
+		}
+	}
+	precompiles := PrecompiledContractsHomestead
+	if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
+		precompiles = PrecompiledContractsByzantium
+	}
+	return precompiles[addr]
+}
+
+// ed25519Verify implements ed25519 signature verification as a native contract.
+// The input is the 32 byte public key, the 64 byte signature and the message,
+// the output a 32 byte word set to 1 if the signature is valid, 0 otherwise.
+type ed25519Verify struct{}
+
+func (c *ed25519Verify) RequiredGas(input []byte) uint64 {
+	return uint64(len(input)+31)/32*params.Ed25519VerifyPerWordGas + params.Ed25519VerifyBaseGas
+}
+
+func (c *ed25519Verify) Run(input []byte) ([]byte, error) {
+	if len(input) < ed25519.PublicKeySize+ed25519.SignatureSize {
+		return common.LeftPadBytes(nil, 32), nil
+	}
+	var (
+		pubkey = input[:ed25519.PublicKeySize]
+		sig    = input[ed25519.PublicKeySize : ed25519.PublicKeySize+ed25519.SignatureSize]
+		msg    = input[ed25519.PublicKeySize+ed25519.SignatureSize:]
+	)
+	if !ed25519.Verify(ed25519.PublicKey(pubkey), msg, sig) {
+		return common.LeftPadBytes(nil, 32), nil
+	}
+	return common.LeftPadBytes([]byte{1}, 32), nil
+}
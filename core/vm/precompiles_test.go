@@ -0,0 +1,98 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/params"
+	"golang.org/x/crypto/ed25519"
+)
+
+// testPrecompile is a precompiled contract returning a fixed output.
+type testPrecompile struct{ out byte }
+
+func (c *testPrecompile) RequiredGas(input []byte) uint64  { return 1 }
+func (c *testPrecompile) Run(input []byte) ([]byte, error) { return []byte{c.out}, nil }
+
+// Tests that precompiled contracts activated by the chain config are resolved
+// from their activation block on, overriding the built-in ones.
+func TestConfigPrecompiles(t *testing.T) {
+	if err := RegisterPrecompile("test-custom", &testPrecompile{1}); err != nil {
+		t.Fatalf("failed to register precompile: %v", err)
+	}
+	if err := RegisterPrecompile("test-custom", &testPrecompile{2}); err == nil {
+		t.Fatalf("duplicate precompile registered")
+	}
+	var (
+		custom  = common.BytesToAddress([]byte{0x01, 0x00})
+		builtin = common.BytesToAddress([]byte{8})
+		config  = &params.ChainConfig{
+			ByzantiumBlock: big.NewInt(0),
+			Precompiles: []*params.PrecompileConfig{
+				{Name: "test-custom", Address: custom, Block: big.NewInt(2)},
+				{Name: "test-custom", Address: builtin, Block: big.NewInt(3)},
+			},
+		}
+	)
+	if err := CheckPrecompiles(config); err != nil {
+		t.Fatalf("registered precompiles rejected: %v", err)
+	}
+	env := NewEVM(Context{BlockNumber: big.NewInt(2)}, nil, config, Config{})
+	if p := env.precompile(custom); p != registry["test-custom"] {
+		t.Errorf("custom precompile not active at its activation block: %v", p)
+	}
+	if p := env.precompile(builtin); p != PrecompiledContractsByzantium[builtin] {
+		t.Errorf("built-in precompile replaced before the upgrade: %v", p)
+	}
+	env = NewEVM(Context{BlockNumber: big.NewInt(3)}, nil, config, Config{})
+	if p := env.precompile(builtin); p != registry["test-custom"] {
+		t.Errorf("built-in precompile not upgraded: %v", p)
+	}
+	env = NewEVM(Context{BlockNumber: big.NewInt(1)}, nil, config, Config{})
+	if p := env.precompile(custom); p != nil {
+		t.Errorf("custom precompile active before its activation block: %v", p)
+	}
+	config.Precompiles = append(config.Precompiles, &params.PrecompileConfig{Name: "test-missing", Address: custom, Block: big.NewInt(4)})
+	if err := CheckPrecompiles(config); err == nil {
+		t.Errorf("unregistered precompile accepted")
+	}
+}
+
+func TestEd25519Verify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("kok precompile")
+	input := append(append(append([]byte{}, pub...), ed25519.Sign(priv, msg)...), msg...)
+
+	p := registry["ed25519Verify"]
+	if out, _ := p.Run(input); !bytes.Equal(out, common.LeftPadBytes([]byte{1}, 32)) {
+		t.Errorf("valid signature rejected: %x", out)
+	}
+	input[len(input)-1] ^= 0xff
+	if out, _ := p.Run(input); !bytes.Equal(out, make([]byte, 32)) {
+		t.Errorf("invalid signature accepted: %x", out)
+	}
+	if out, _ := p.Run(input[:10]); !bytes.Equal(out, make([]byte, 32)) {
+		t.Errorf("short input accepted: %x", out)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+)
+
+// OpcodeProfile is the aggregated gas consumption of an opcode.
+type OpcodeProfile struct {
+	Op    string `json:"op"`
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+// FrameProfile is the gas consumption of a call frame. Gas counts the opcodes of
+// the frame itself, whereas TotalGas also includes the frames called by it.
+type FrameProfile struct {
+	Address  common.Address `json:"address"`
+	Depth    int            `json:"depth"`
+	Steps    uint64         `json:"steps"`
+	Gas      uint64         `json:"gas"`
+	TotalGas uint64         `json:"totalGas"`
+}
+
+// profileStep is an executed opcode whose gas consumption is not yet known.
+type profileStep struct {
+	op     OpCode
+	gas    uint64 // Gas available before the step
+	cost   uint64 // Gas charged upfront by the step
+	failed bool   // Whether the step failed, consuming all the gas of the frame
+}
+
+// profileFrame is a call frame being executed.
+type profileFrame struct {
+	index    int          // Position of the frame in the profile
+	pending  *profileStep // Last step of the frame, settled by the next one
+	children uint64       // Gas consumed by the frames called by the pending step
+}
+
+// GasProfiler is an EVM tracer aggregating the gas consumed by each opcode and
+// each call frame, rather than logging every step.
+//
+// The gas of a step is measured as the difference in gas available before it and
+// before the next step of the same frame, less the gas consumed by any frames it
+// called. This way the gas forwarded by calls and refunded upon return is not
+// accounted to the calling opcode.
+type GasProfiler struct {
+	opcodes map[OpCode]*OpcodeProfile
+	frames  []*FrameProfile
+	stack   []*profileFrame
+}
+
+// NewGasProfiler creates a new gas profiling tracer.
+func NewGasProfiler() *GasProfiler {
+	return &GasProfiler{opcodes: make(map[OpCode]*OpcodeProfile)}
+}
+
+// CaptureState implements Tracer, settling the gas of the previous step of the
+// frame and entering or leaving frames as the call depth changes.
+func (p *GasProfiler) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	for len(p.stack) > depth || (len(p.stack) == depth && p.frames[p.stack[depth-1].index].Address != contract.Address()) {
+		p.leave()
+	}
+	if len(p.stack) < depth {
+		p.frames = append(p.frames, &FrameProfile{Address: contract.Address(), Depth: depth})
+		p.stack = append(p.stack, &profileFrame{index: len(p.frames) - 1})
+	}
+	frame := p.stack[len(p.stack)-1]
+	if frame.pending != nil {
+		used := frame.pending.gas - gas
+		if used >= frame.children {
+			used -= frame.children
+		}
+		p.account(frame, used)
+	}
+	frame.pending = &profileStep{op: op, gas: gas, cost: cost, failed: err != nil}
+	frame.children = 0
+	p.frames[frame.index].Steps++
+	return nil
+}
+
+// CaptureEnd implements Tracer.
+func (p *GasProfiler) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	return nil
+}
+
+// account attributes the gas used by the pending step of a frame.
+func (p *GasProfiler) account(frame *profileFrame, used uint64) {
+	step := frame.pending
+	prof, ok := p.opcodes[step.op]
+	if !ok {
+		prof = &OpcodeProfile{Op: step.op.String()}
+		p.opcodes[step.op] = prof
+	}
+	prof.Count++
+	prof.Gas += used
+	p.frames[frame.index].Gas += used
+	p.frames[frame.index].TotalGas += used
+	frame.pending = nil
+}
+
+// leave settles the last step of the innermost frame, which consumes either its
+// upfront cost or all the remaining gas if it failed, and exits the frame.
+func (p *GasProfiler) leave() {
+	frame := p.stack[len(p.stack)-1]
+	if step := frame.pending; step != nil {
+		if step.failed {
+			p.account(frame, step.gas)
+		} else {
+			p.account(frame, step.cost)
+		}
+	}
+	p.stack = p.stack[:len(p.stack)-1]
+	if len(p.stack) > 0 {
+		parent := p.stack[len(p.stack)-1]
+		parent.children += p.frames[frame.index].TotalGas
+		p.frames[parent.index].TotalGas += p.frames[frame.index].TotalGas
+	}
+}
+
+// Opcodes returns the gas profile of each executed opcode, most expensive first.
+func (p *GasProfiler) Opcodes() []OpcodeProfile {
+	p.settle()
+
+	opcodes := make([]OpcodeProfile, 0, len(p.opcodes))
+	for _, prof := range p.opcodes {
+		opcodes = append(opcodes, *prof)
+	}
+	sort.Slice(opcodes, func(i, j int) bool {
+		if opcodes[i].Gas != opcodes[j].Gas {
+			return opcodes[i].Gas > opcodes[j].Gas
+		}
+		return opcodes[i].Op < opcodes[j].Op
+	})
+	return opcodes
+}
+
+// Frames returns the gas profile of each call frame, in the order entered.
+func (p *GasProfiler) Frames() []FrameProfile {
+	p.settle()
+
+	frames := make([]FrameProfile, len(p.frames))
+	for i, frame := range p.frames {
+		frames[i] = *frame
+	}
+	return frames
+}
+
+// settle exits all the frames still open once execution finished.
+func (p *GasProfiler) settle() {
+	for len(p.stack) > 0 {
+		p.leave()
+	}
+}
@@ -17,7 +17,12 @@
 package runtime
 
 import (
+	"context"
+	"encoding/json"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -25,7 +30,10 @@ import (
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/tracing"
 )
 
 func TestDefaults(t *testing.T) {
@@ -149,3 +157,259 @@ func BenchmarkCall(b *testing.B) {
 		}
 	}
 }
+
+// Tests that value transfers made by contracts are recorded if enabled, unless
+// reverted.
+func TestInternalTxRecording(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	state, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	var (
+		caller   = common.HexToAddress("0x0a")
+		payee    = common.HexToAddress("0x0b")
+		reverter = common.HexToAddress("0x0c")
+		txHash   = common.HexToHash("0x01")
+	)
+	call := func(to common.Address, value byte) []byte {
+		return []byte{
+			byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+			byte(vm.PUSH1), value, byte(vm.PUSH1), to[19], byte(vm.PUSH2), 0xff, 0xff,
+			byte(vm.CALL), byte(vm.POP),
+		}
+	}
+	// The caller pays the payee directly, and through a reverting contract
+	state.SetCode(caller, append(call(payee, 5), call(reverter, 0)...))
+	state.SetBalance(caller, big.NewInt(10))
+
+	state.SetCode(reverter, append(call(payee, 1), 0xfe))
+	state.SetBalance(reverter, big.NewInt(10))
+
+	state.Prepare(txHash, common.Hash{}, 0)
+	if _, _, err := Call(caller, nil, &Config{State: state, EVMConfig: vm.Config{EnableInternalTxRecording: true}}); err != nil {
+		t.Fatalf("failed to execute call: %v", err)
+	}
+	txs := state.InternalTxs(txHash)
+	if len(txs) != 1 {
+		t.Fatalf("internal transaction count mismatch: have %d, want 1", len(txs))
+	}
+	if tx := txs[0]; tx.Type != "CALL" || tx.From != caller || tx.To != payee || tx.Value.Cmp(big.NewInt(5)) != 0 || tx.Depth != 1 {
+		t.Errorf("internal transaction mismatch: have %+v", tx)
+	}
+}
+
+// Tests that contracts deployed by other contracts are recorded along with their
+// creator, and that deployments reverted are dropped.
+func TestContractCreationRecording(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	state, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	var (
+		factory = common.HexToAddress("0x0a")
+		txHash  = common.HexToHash("0x01")
+	)
+	// The factory deploys an empty contract, then deploys one which fails. Init
+	// codes are trailed by the template and coinbase addresses.
+	create := []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CREATE), byte(vm.POP)}
+	failing := []byte{
+		byte(vm.PUSH1), 0xfe, byte(vm.PUSH1), 0, byte(vm.MSTORE8),
+		byte(vm.PUSH1), 41, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CREATE), byte(vm.POP),
+	}
+	state.SetCode(factory, append(create, failing...))
+
+	state.Prepare(txHash, common.Hash{}, 0)
+	if _, _, err := Call(factory, nil, &Config{State: state}); err != nil {
+		t.Fatalf("failed to execute call: %v", err)
+	}
+	creations := state.ContractCreations(txHash)
+	if len(creations) != 1 {
+		t.Fatalf("contract creation count mismatch: have %d, want 1", len(creations))
+	}
+	want := crypto.CreateAddress(factory, 0)
+	if c := creations[0]; c.Type != "CREATE" || c.Creator != factory || c.Address != want || c.TxHash != txHash {
+		t.Errorf("contract creation mismatch: have %+v, want address %x", c, want)
+	}
+}
+
+// Tests that the gas profiler accounts the gas of a call to the callee frame
+// rather than the calling opcode, and that the profile adds up to the gas used.
+func TestGasProfiler(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	state, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	var (
+		caller = common.HexToAddress("0x0a")
+		callee = common.HexToAddress("0x0b")
+	)
+	state.SetCode(caller, []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), callee[19], byte(vm.PUSH2), 0xff, 0xff,
+		byte(vm.CALL), byte(vm.POP),
+	})
+	state.SetCode(callee, []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE)})
+
+	profiler := vm.NewGasProfiler()
+	cfg := &Config{State: state, GasLimit: 1000000, EVMConfig: vm.Config{Debug: true, Tracer: profiler}}
+	_, left, err := Call(caller, nil, cfg)
+	if err != nil {
+		t.Fatalf("failed to execute call: %v", err)
+	}
+	used := cfg.GasLimit - left
+
+	frames := profiler.Frames()
+	if len(frames) != 2 {
+		t.Fatalf("frame count mismatch: have %d, want 2", len(frames))
+	}
+	if frames[0].Address != caller || frames[0].Depth != 1 || frames[0].TotalGas != used {
+		t.Errorf("caller frame mismatch: have %+v, want total gas %d", frames[0], used)
+	}
+	if frames[1].Address != callee || frames[1].Depth != 2 || frames[1].Steps != 4 || frames[1].Gas != 20006 {
+		t.Errorf("callee frame mismatch: have %+v", frames[1])
+	}
+	if frames[0].Gas+frames[1].Gas != used {
+		t.Errorf("frame gas mismatch: have %d+%d, want %d", frames[0].Gas, frames[1].Gas, used)
+	}
+	var total uint64
+	for _, op := range profiler.Opcodes() {
+		total += op.Gas
+		if op.Op == "SSTORE" && (op.Count != 1 || op.Gas != 20000) {
+			t.Errorf("SSTORE profile mismatch: have %+v", op)
+		}
+	}
+	if total != used {
+		t.Errorf("opcode gas mismatch: have %d, want %d", total, used)
+	}
+}
+
+// Tests that the post-mortem logger only captures the configured execution points,
+// along with the storage changes made in between.
+func TestPostMortemLogger(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	state, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	var (
+		caller   = common.HexToAddress("0x0a")
+		reverter = common.HexToAddress("0x0b")
+	)
+	state.SetCode(caller, []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), reverter[19], byte(vm.PUSH2), 0xff, 0xff,
+		byte(vm.CALL), byte(vm.POP),
+	})
+	state.SetCode(reverter, []byte{
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT),
+	})
+	tests := []struct {
+		config *vm.PostMortemConfig
+		kinds  []string
+		ops    []vm.OpCode
+	}{
+		{nil, []string{vm.PostMortemCall, vm.PostMortemRevert, vm.PostMortemReturn}, []vm.OpCode{vm.CALL, vm.REVERT, vm.STOP}},
+		{&vm.PostMortemConfig{DisableCalls: true}, []string{vm.PostMortemRevert}, []vm.OpCode{vm.REVERT}},
+		{&vm.PostMortemConfig{DisableReverts: true}, []string{vm.PostMortemCall, vm.PostMortemReturn}, []vm.OpCode{vm.CALL, vm.STOP}},
+	}
+	for i, tt := range tests {
+		logger := vm.NewPostMortemLogger(nil, tt.config)
+		if _, _, err := Call(caller, nil, &Config{State: state.Copy(), ChainConfig: params.TestChainConfig, EVMConfig: vm.Config{Debug: true, Tracer: logger}}); err != nil {
+			t.Fatalf("test %d: failed to execute call: %v", i, err)
+		}
+		logs, kinds := logger.StructLogs(), logger.Kinds()
+		if !reflect.DeepEqual(kinds, tt.kinds) || len(logs) != len(tt.ops) {
+			t.Fatalf("test %d: captured kinds mismatch: have %v, want %v", i, kinds, tt.kinds)
+		}
+		for j, log := range logs {
+			if log.Op != tt.ops[j] {
+				t.Errorf("test %d, log %d: op mismatch: have %v, want %v", i, j, log.Op, tt.ops[j])
+			}
+			if log.Op == vm.REVERT && log.Storage[common.Hash{}] != common.BigToHash(big.NewInt(1)) {
+				t.Errorf("test %d, log %d: storage change not tracked: have %v", i, j, log.Storage)
+			}
+		}
+	}
+}
+
+// Tests that traced executions record their call frames as nested spans, along
+// with the state loaded from the database.
+func TestCallTracing(t *testing.T) {
+	spans := make(chan tracedSpan, 64)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []tracedSpan
+				}
+			}
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode export request: %v", err)
+		}
+		for _, resource := range req.ResourceSpans {
+			for _, scope := range resource.ScopeSpans {
+				for _, span := range scope.Spans {
+					spans <- span
+				}
+			}
+		}
+	}))
+	defer collector.Close()
+
+	db, _ := kokdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	var (
+		caller = common.HexToAddress("0x0a")
+		callee = common.HexToAddress("0x0b")
+	)
+	statedb.SetCode(caller, []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), callee[19], byte(vm.PUSH2), 0xff, 0xff,
+		byte(vm.CALL), byte(vm.POP),
+	})
+	statedb.SetCode(callee, []byte{byte(vm.PUSH1), 1, byte(vm.POP)})
+	root, _ := statedb.CommitTo(db, false)
+	statedb, _ = state.New(root, state.NewDatabase(db))
+
+	if err := tracing.Setup(tracing.Config{Endpoint: collector.URL, SampleRatio: 1}); err != nil {
+		t.Fatalf("failed to enable tracing: %v", err)
+	}
+	ctx, span := tracing.Start(context.Background(), "test")
+	statedb.SetTraceContext(ctx)
+	if _, _, err := Call(caller, nil, &Config{State: statedb, EVMConfig: vm.Config{TraceContext: ctx}}); err != nil {
+		t.Fatalf("failed to execute call: %v", err)
+	}
+	span.End()
+	tracing.Stop()
+	close(spans)
+
+	var (
+		parent tracedSpan
+		frames []tracedSpan // Innermost first, as they end first
+		loads  int
+	)
+	for span := range spans {
+		switch span.Name {
+		case "test":
+			parent = span
+		case "evm.CALL":
+			frames = append(frames, span)
+		case "state.loadCode":
+			loads++
+		}
+	}
+	if len(frames) != 2 {
+		t.Fatalf("call frame count mismatch: have %d, want 2", len(frames))
+	}
+	if frames[1].ParentSpanID != parent.SpanID || frames[0].ParentSpanID != frames[1].SpanID {
+		t.Errorf("call frame linkage mismatch: root %+v, frames %+v", parent, frames)
+	}
+	if loads != 2 {
+		t.Errorf("code load count mismatch: have %d, want 2", loads)
+	}
+}
+
+// tracedSpan is the part of an exported span checked by the tests.
+type tracedSpan struct {
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+}
@@ -97,6 +97,10 @@ func (db *Dashboard) Protocols() []p2p.Protocol { return nil }
 // APIs is a meaningless implementation of node.Service.
 func (db *Dashboard) APIs() []rpc.API { return nil }
 
+// Auxiliary implements node.AuxiliaryService, allowing the dashboard to be
+// stopped and restarted while the node is running.
+func (db *Dashboard) Auxiliary() {}
+
 // Start implements node.Service, starting the data collection thread and the listening server of the dashboard.
 func (db *Dashboard) Start(server *p2p.Server) error {
 	db.wg.Add(2)
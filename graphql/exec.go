@@ -0,0 +1,461 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+)
+
+// maxDepth is the maximum nesting of the selection sets of a query, limiting the
+// amount of work a single query can cause through cyclic relations.
+const maxDepth = 16
+
+// object is implemented by the object types of the schema, resolving the value
+// of their fields. Resolvers return nil, a scalar marshalled as is, an object
+// or a list of objects.
+type object interface {
+	typeName() string
+	resolve(ctx context.Context, name string, args arguments) (interface{}, error)
+}
+
+// errUnknownField is returned by resolvers for fields not defined on their type.
+type errUnknownField struct {
+	typ, name string
+}
+
+func (err *errUnknownField) Error() string {
+	return fmt.Sprintf("cannot query field %q on type %q", err.name, err.typ)
+}
+
+// QueryError is an error encountered while parsing or executing a query.
+type QueryError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"` // Response path of the field that failed
+}
+
+// Response is the result of a query: the requested data, with null in place of
+// the fields that failed and the errors they failed with.
+type Response struct {
+	Data   *orderedMap   `json:"data"`
+	Errors []*QueryError `json:"errors,omitempty"`
+}
+
+// orderedMap is a JSON object keeping its keys in the order they were selected.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: make(map[string]interface{})}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// MarshalJSON implements json.Marshaler, encoding the keys in insertion order.
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// execute runs an operation of a query document against the given root object.
+func execute(ctx context.Context, root object, query string, operationName string, variables map[string]interface{}) *Response {
+	doc, err := parse(query)
+	if err != nil {
+		return &Response{Errors: []*QueryError{{Message: err.Error()}}}
+	}
+	var op *operation
+	for _, candidate := range doc.operations {
+		if operationName == "" || candidate.name == operationName {
+			if op != nil {
+				return &Response{Errors: []*QueryError{{Message: "operation name required for documents with multiple operations"}}}
+			}
+			op = candidate
+		}
+	}
+	if op == nil {
+		return &Response{Errors: []*QueryError{{Message: fmt.Sprintf("unknown operation %q", operationName)}}}
+	}
+	vars := make(map[string]interface{})
+	for _, def := range op.vars {
+		if v, ok := variables[def.name]; ok {
+			vars[def.name] = v
+		} else {
+			vars[def.name] = def.def
+		}
+	}
+	e := &executor{doc: doc, vars: vars}
+	data := e.object(ctx, root, op.selections, nil, 0)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// executor resolves the selections of an operation, collecting field errors.
+type executor struct {
+	doc    *document
+	vars   map[string]interface{}
+	errors []*QueryError
+}
+
+func (e *executor) fail(path []interface{}, err error) {
+	e.errors = append(e.errors, &QueryError{Message: err.Error(), Path: append([]interface{}{}, path...)})
+}
+
+// fields flattens the selections of a selection set, expanding the fragments
+// and dropping the selections excluded by their directives.
+func (e *executor) fields(selections []selection, visited map[string]bool) ([]*field, error) {
+	var fields []*field
+	for _, sel := range selections {
+		var directives []*directive
+		switch sel := sel.(type) {
+		case *field:
+			directives = sel.directives
+		case *inlineFragment:
+			directives = sel.directives
+		case *fragmentSpread:
+			directives = sel.directives
+		}
+		include, err := e.included(directives)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			continue
+		}
+		switch sel := sel.(type) {
+		case *field:
+			fields = append(fields, sel)
+		case *inlineFragment:
+			nested, err := e.fields(sel.selections, visited)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+		case *fragmentSpread:
+			selections, ok := e.doc.fragments[sel.name]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %q", sel.name)
+			}
+			if visited[sel.name] {
+				return nil, fmt.Errorf("fragment %q spreads itself", sel.name)
+			}
+			visited[sel.name] = true
+			nested, err := e.fields(selections, visited)
+			delete(visited, sel.name)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+		}
+	}
+	return fields, nil
+}
+
+// included evaluates the @skip and @include directives of a selection.
+func (e *executor) included(directives []*directive) (bool, error) {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			return false, fmt.Errorf("unknown directive @%s", d.name)
+		}
+		args, err := e.arguments(d.args)
+		if err != nil {
+			return false, err
+		}
+		cond, ok := args["if"].(bool)
+		if !ok {
+			return false, fmt.Errorf("directive @%s: argument \"if\" must be a boolean", d.name)
+		}
+		if cond == (d.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// object resolves the selected fields of an object.
+func (e *executor) object(ctx context.Context, obj object, selections []selection, path []interface{}, depth int) *orderedMap {
+	result := newOrderedMap()
+	if depth >= maxDepth {
+		e.fail(path, fmt.Errorf("query exceeds maximum depth of %d", maxDepth))
+		return nil
+	}
+	fields, err := e.fields(selections, make(map[string]bool))
+	if err != nil {
+		e.fail(path, err)
+		return nil
+	}
+	for _, f := range fields {
+		if err := ctx.Err(); err != nil {
+			e.fail(path, err)
+			return result
+		}
+		fpath := append(path, f.key())
+		if f.name == "__typename" {
+			result.set(f.key(), obj.typeName())
+			continue
+		}
+		args, err := e.arguments(f.args)
+		if err != nil {
+			e.fail(fpath, err)
+			result.set(f.key(), nil)
+			continue
+		}
+		value, err := obj.resolve(ctx, f.name, args)
+		if err != nil {
+			e.fail(fpath, err)
+			result.set(f.key(), nil)
+			continue
+		}
+		result.set(f.key(), e.value(ctx, value, f, fpath, depth))
+	}
+	return result
+}
+
+// value completes the resolved value of a field according to its selections.
+func (e *executor) value(ctx context.Context, value interface{}, f *field, path []interface{}, depth int) interface{} {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case object:
+		if len(f.selections) == 0 {
+			e.fail(path, fmt.Errorf("field %q of type %q must have a selection of subfields", f.name, value.typeName()))
+			return nil
+		}
+		return e.object(ctx, value, f.selections, path, depth+1)
+	case []object:
+		if len(f.selections) == 0 {
+			e.fail(path, fmt.Errorf("field %q of object list type must have a selection of subfields", f.name))
+			return nil
+		}
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			if item == nil {
+				continue
+			}
+			list[i] = e.object(ctx, item, f.selections, append(path, i), depth+1)
+		}
+		return list
+	default:
+		if len(f.selections) > 0 {
+			e.fail(path, fmt.Errorf("field %q of scalar type must not have a selection of subfields", f.name))
+			return nil
+		}
+		return value
+	}
+}
+
+// arguments substitutes the variables referenced by the arguments of a field.
+func (e *executor) arguments(raw map[string]interface{}) (arguments, error) {
+	args := make(arguments, len(raw))
+	for name, v := range raw {
+		value, err := e.substitute(v)
+		if err != nil {
+			return nil, err
+		}
+		args[name] = value
+	}
+	return args, nil
+}
+
+func (e *executor) substitute(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case variable:
+		value, ok := e.vars[string(v)]
+		if !ok {
+			return nil, fmt.Errorf("undeclared variable $%s", v)
+		}
+		return value, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			value, err := e.substitute(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			value, err := e.substitute(item)
+			if err != nil {
+				return nil, err
+			}
+			obj[name] = value
+		}
+		return obj, nil
+	case enumValue:
+		return string(v), nil
+	}
+	return v, nil
+}
+
+// arguments are the input values of a field, converted to the scalar types of
+// the schema on access. Values originate from query literals or JSON decoded
+// variables.
+type arguments map[string]interface{}
+
+// has reports whether a non-null value was given for the named argument.
+func (args arguments) has(name string) bool {
+	return args[name] != nil
+}
+
+// long converts the named argument to an unsigned integer, accepting numbers as
+// well as decimal and hex strings.
+func (args arguments) long(name string) (uint64, error) {
+	return toLong(name, args[name])
+}
+
+func toLong(name string, v interface{}) (uint64, error) {
+	switch v := v.(type) {
+	case int64:
+		if v >= 0 {
+			return uint64(v), nil
+		}
+	case float64:
+		if v >= 0 && v == float64(uint64(v)) {
+			return uint64(v), nil
+		}
+	case json.Number:
+		if n, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return n, nil
+		}
+	case string:
+		if strings.HasPrefix(v, "0x") {
+			if n, err := hexutil.DecodeUint64(v); err == nil {
+				return n, nil
+			}
+		} else if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q: invalid unsigned integer %v", name, v)
+}
+
+// int converts the named argument to an integer, returning def if it is null.
+func (args arguments) int(name string, def int) (int, error) {
+	if !args.has(name) {
+		return def, nil
+	}
+	n, err := args.long(name)
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// bigInt converts the named argument to a big integer.
+func (args arguments) bigInt(name string) (*big.Int, error) {
+	switch v := args[name].(type) {
+	case string:
+		if n, ok := new(big.Int).SetString(v, 0); ok {
+			return n, nil
+		}
+	default:
+		if n, err := args.long(name); err == nil {
+			return new(big.Int).SetUint64(n), nil
+		}
+	}
+	return nil, fmt.Errorf("argument %q: invalid integer %v", name, args[name])
+}
+
+// hash converts the named argument to a 32 byte hash.
+func (args arguments) hash(name string) (common.Hash, error) {
+	return toHash(name, args[name])
+}
+
+func toHash(name string, v interface{}) (common.Hash, error) {
+	s, ok := v.(string)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("argument %q: expected hex string, got %v", name, v)
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("argument %q: invalid 32 byte hash %q", name, s)
+	}
+	return common.BytesToHash(b), nil
+}
+
+// address converts the named argument to an account address.
+func (args arguments) address(name string) (common.Address, error) {
+	return toAddress(name, args[name])
+}
+
+func toAddress(name string, v interface{}) (common.Address, error) {
+	s, ok := v.(string)
+	if !ok {
+		return common.Address{}, fmt.Errorf("argument %q: expected hex string, got %v", name, v)
+	}
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("argument %q: invalid address %q", name, s)
+	}
+	return common.BytesToAddress(b), nil
+}
+
+// object converts the named argument to an input object, empty if null.
+func (args arguments) object(name string) (arguments, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return arguments{}, nil
+	case map[string]interface{}:
+		return arguments(v), nil
+	}
+	return nil, fmt.Errorf("argument %q: expected input object, got %v", name, args[name])
+}
+
+// list converts the named argument to a list, wrapping single values as the
+// input coercion rules of GraphQL require.
+func (args arguments) list(name string) []interface{} {
+	switch v := args[name].(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
@@ -0,0 +1,363 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/bloombits"
+	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kok/filters"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// testBackend serves the queries from a chain written directly into a database.
+// The event subscriptions of the log filters are not implemented.
+type testBackend struct {
+	filters.Backend
+
+	db kokdb.Database
+}
+
+func (b *testBackend) ChainDb() kokdb.Database { return b.db }
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	block, _ := b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, nil
+	}
+	return block.Header(), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	var hash common.Hash
+	if number == rpc.LatestBlockNumber {
+		hash = core.GkokeadBlockHash(b.db)
+	} else {
+		hash = core.GetCanonicalHash(b.db, uint64(number))
+	}
+	return b.GetBlock(ctx, hash)
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return core.GetBlock(b.db, hash, core.GetBlockNumber(b.db, hash)), nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return core.GetBlockReceipts(b.db, hash, core.GetBlockNumber(b.db, hash)), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, _ := b.HeaderByNumber(ctx, number)
+	if header == nil {
+		return nil, nil, nil
+	}
+	statedb, err := state.New(header.Root, state.NewDatabase(b.db))
+	return statedb, header, err
+}
+
+func (b *testBackend) GetTd(hash common.Hash) *big.Int {
+	return core.GetTd(b.db, hash, core.GetBlockNumber(b.db, hash))
+}
+
+func (b *testBackend) GetPoolTransaction(hash common.Hash) *types.Transaction { return nil }
+
+func (b *testBackend) BloomStatus() (uint64, uint64) { return params.BloomBitsBlocks, 0 }
+
+func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {}
+
+var (
+	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress  = crypto.PubkeyToAddress(testKey.PublicKey)
+	testContract = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+)
+
+// newTestBackend creates a chain of three blocks, the last one transferring
+// funds from the test account with a log attached to the receipt.
+func newTestBackend(t *testing.T) *testBackend {
+	db, _ := kokdb.NewMemDatabase()
+
+	genesis := core.GenesisBlockForTesting(db, testAddress, big.NewInt(1000000))
+	blocks, receipts := core.GenerateChain(params.TestChainConfig, genesis, db, 2, func(int, *core.BlockGen) {})
+
+	// Transactions can't be executed by the chain maker, assemble the block by hand
+	tx, _ := types.SignTx(types.NewTransaction(types.Binary, 0, testContract, big.NewInt(1000), new(big.Int).SetUint64(params.TxGas), nil, nil), types.HomesteadSigner{}, testKey)
+	receipt := types.NewReceipt(nil, false, new(big.Int).SetUint64(params.TxGas))
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = new(big.Int).SetUint64(params.TxGas)
+	receipt.Logs = []*types.Log{{Address: testContract, Topics: []common.Hash{common.HexToHash("0x01")}, TxHash: tx.Hash()}}
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+	header := types.CopyHeader(blocks[1].Header())
+	header.ParentHash = blocks[1].Hash()
+	header.Number = big.NewInt(3)
+	blocks = append(blocks, types.NewBlock(header, []*types.Transaction{tx}, nil, []*types.Receipt{receipt}))
+	receipts = append(receipts, types.Receipts{receipt})
+
+	td := genesis.Difficulty()
+	for i, block := range append([]*types.Block{genesis}, blocks...) {
+		if i > 0 {
+			td = new(big.Int).Add(td, block.Difficulty())
+			core.WriteBlock(db, block)
+			core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i-1])
+		}
+		core.WriteTd(db, block.Hash(), block.NumberU64(), td)
+		core.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		core.WriteTxLookupEntries(db, block)
+		core.WriteHeadBlockHash(db, block.Hash())
+	}
+	return &testBackend{db: db}
+}
+
+// runQuery executes a query and returns its response in JSON form.
+func runQuery(t *testing.T, backend Backend, query string, variables map[string]interface{}) (map[string]interface{}, []*QueryError) {
+	response := execute(context.Background(), &queryObj{backend: backend}, query, "", variables)
+	blob, err := json.Marshal(response.Data)
+	if err != nil {
+		t.Fatalf("failed to encode response: %v", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(blob, &data); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return data, response.Errors
+}
+
+func TestQueryBlocks(t *testing.T) {
+	backend := newTestBackend(t)
+
+	data, errs := runQuery(t, backend, `
+		query ($from: Long!) {
+			head: block { number }
+			blocks(from: $from, to: 3) {
+				number
+				__typename
+				parent { number }
+				transactionCount
+				transactions(first: 1) { ...transfer }
+			}
+		}
+		fragment transfer on Transaction {
+			from { address balance }
+			to { address }
+			value
+			status
+			block { number }
+		}
+	`, map[string]interface{}{"from": float64(1)})
+	if len(errs) != 0 {
+		t.Fatalf("query failed: %v", errs[0].Message)
+	}
+	if head := data["head"].(map[string]interface{}); head["number"] != float64(3) {
+		t.Errorf("head mismatch: %v", head)
+	}
+	blocks := data["blocks"].([]interface{})
+	if len(blocks) != 3 {
+		t.Fatalf("block count mismatch: have %d, want %d", len(blocks), 3)
+	}
+	first, last := blocks[0].(map[string]interface{}), blocks[2].(map[string]interface{})
+	if first["number"] != float64(1) || first["__typename"] != "Block" || first["parent"].(map[string]interface{})["number"] != float64(0) {
+		t.Errorf("first block mismatch: %v", first)
+	}
+	if last["transactionCount"] != float64(1) {
+		t.Fatalf("transaction count mismatch: %v", last["transactionCount"])
+	}
+	tx := last["transactions"].([]interface{})[0].(map[string]interface{})
+	from := tx["from"].(map[string]interface{})
+	if from["address"] != strings.ToLower(testAddress.Hex()) {
+		t.Errorf("sender mismatch: %v", from["address"])
+	}
+	if tx["value"] != "0x3e8" || tx["status"] != float64(1) || tx["block"].(map[string]interface{})["number"] != float64(3) {
+		t.Errorf("transaction mismatch: %v", tx)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	backend := newTestBackend(t)
+
+	data, errs := runQuery(t, backend, `{ a: block(number: 1) { hash unknown } b: block(number: "x") { hash } c: block { parent } }`, nil)
+	if len(errs) != 3 {
+		t.Fatalf("error count mismatch: have %d, want %d", len(errs), 3)
+	}
+	if a := data["a"].(map[string]interface{}); a["hash"] == nil || a["unknown"] != nil {
+		t.Errorf("partial result mismatch: %v", a)
+	}
+	if data["b"] != nil {
+		t.Errorf("failed field not null: %v", data["b"])
+	}
+	if len(errs[0].Path) != 2 || errs[0].Path[0] != "a" || errs[0].Path[1] != "unknown" {
+		t.Errorf("error path mismatch: %v", errs[0].Path)
+	}
+	if _, errs := runQuery(t, backend, `{ block { hash`, nil); len(errs) != 1 {
+		t.Errorf("syntax error not reported")
+	}
+}
+
+func TestQueryDirectives(t *testing.T) {
+	backend := newTestBackend(t)
+
+	query := `
+		query ($full: Boolean!) {
+			block(number: 1) {
+				number
+				hash @include(if: $full)
+				parent @skip(if: $full) { number }
+				... @include(if: $full) { stateRoot }
+				...extra @skip(if: true)
+			}
+		}
+		fragment extra on Block { gasUsed }
+	`
+	data, errs := runQuery(t, backend, query, map[string]interface{}{"full": true})
+	if len(errs) != 0 {
+		t.Fatalf("query failed: %v", errs[0].Message)
+	}
+	block := data["block"].(map[string]interface{})
+	if _, ok := block["hash"]; !ok || block["stateRoot"] == nil {
+		t.Errorf("included fields missing: %v", block)
+	}
+	if _, ok := block["parent"]; ok {
+		t.Errorf("skipped field present: %v", block)
+	}
+	if _, ok := block["gasUsed"]; ok {
+		t.Errorf("skipped fragment present: %v", block)
+	}
+	data, _ = runQuery(t, backend, query, map[string]interface{}{"full": false})
+	block = data["block"].(map[string]interface{})
+	if _, ok := block["hash"]; ok || block["parent"] == nil {
+		t.Errorf("directive evaluation mismatch: %v", block)
+	}
+	if _, errs := runQuery(t, backend, `{ block { hash @deprecated } }`, nil); len(errs) != 1 {
+		t.Errorf("unknown directive not reported")
+	}
+}
+
+func TestQueryIntrospection(t *testing.T) {
+	backend := newTestBackend(t)
+
+	data, errs := runQuery(t, backend, `
+		{
+			__schema {
+				queryType { name }
+				mutationType { name }
+				types { kind name }
+				directives { name locations args { name type { kind ofType { name } } } }
+			}
+			block: __type(name: "Block") {
+				kind
+				fields { name args { name } type { ...typeRef } }
+			}
+			filter: __type(name: "FilterCriteria") { inputFields { name type { ...typeRef } } }
+			unknown: __type(name: "Unknown") { name }
+		}
+		fragment typeRef on __Type { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+	`, nil)
+	if len(errs) != 0 {
+		t.Fatalf("query failed: %v", errs[0].Message)
+	}
+	schema := data["__schema"].(map[string]interface{})
+	if schema["queryType"].(map[string]interface{})["name"] != "Query" || schema["mutationType"] != nil {
+		t.Errorf("root types mismatch: %v", schema)
+	}
+	if types := schema["types"].([]interface{}); len(types) != len(schemaTypeDefs) {
+		t.Errorf("type count mismatch: have %d, want %d", len(types), len(schemaTypeDefs))
+	}
+	if directives := schema["directives"].([]interface{}); len(directives) != 2 {
+		t.Errorf("directive count mismatch: have %d, want %d", len(directives), 2)
+	}
+	block := data["block"].(map[string]interface{})
+	if block["kind"] != "OBJECT" {
+		t.Errorf("block kind mismatch: %v", block["kind"])
+	}
+	var blocks map[string]interface{}
+	for _, f := range block["fields"].([]interface{}) {
+		if f := f.(map[string]interface{}); f["name"] == "transactions" {
+			blocks = f["type"].(map[string]interface{})
+		}
+	}
+	// transactions: [Transaction!]!
+	list := blocks["ofType"].(map[string]interface{})
+	item := list["ofType"].(map[string]interface{})
+	if blocks["kind"] != "NON_NULL" || list["kind"] != "LIST" || item["kind"] != "NON_NULL" || item["ofType"].(map[string]interface{})["name"] != "Transaction" {
+		t.Errorf("type reference mismatch: %v", blocks)
+	}
+	if fields := data["filter"].(map[string]interface{})["inputFields"].([]interface{}); len(fields) != 4 {
+		t.Errorf("input field count mismatch: have %d, want %d", len(fields), 4)
+	}
+	if data["unknown"] != nil {
+		t.Errorf("unknown type resolved: %v", data["unknown"])
+	}
+}
+
+// Tests that the introspected schema matches the resolvers: every declared field
+// of the object types resolves, and every referenced type is declared.
+func TestIntrospectionSchema(t *testing.T) {
+	backend := newTestBackend(t)
+
+	block, _ := backend.BlockByNumber(context.Background(), 3)
+	tx := &transactionObj{backend: backend, tx: block.Transactions()[0], block: block}
+	objs := map[string]object{
+		"Query":        &queryObj{backend: backend},
+		"Block":        &blockObj{backend: backend, block: block},
+		"Transaction":  tx,
+		"Log":          &logObj{backend: backend, log: &types.Log{}, block: block},
+		"Account":      &accountObj{backend: backend, address: testAddress, number: rpc.LatestBlockNumber},
+		"__Schema":     &schemaObj{},
+		"__Type":       typeRef("Block"),
+		"__Field":      &fieldObj{def: schemaTypes["Block"].fields[0]},
+		"__InputValue": &inputValueObj{def: schemaDirectives[0].args[0]},
+		"__EnumValue":  &enumValueObj{name: "SCALAR"},
+		"__Directive":  &directiveObj{def: schemaDirectives[0]},
+	}
+	for _, def := range schemaTypeDefs {
+		for _, f := range def.fields {
+			for _, ref := range append([]*fieldDef{f}, f.args...) {
+				name := strings.TrimRight(strings.TrimLeft(ref.typ, "["), "]!")
+				if _, ok := schemaTypes[name]; !ok {
+					t.Errorf("%s.%s: undeclared type %s", def.name, f.name, name)
+				}
+			}
+		}
+		if def.kind != "OBJECT" {
+			continue
+		}
+		obj, ok := objs[def.name]
+		if !ok {
+			t.Errorf("no resolver for object type %s", def.name)
+			continue
+		}
+		for _, f := range def.fields {
+			if _, err := obj.resolve(context.Background(), f.name, arguments{}); err != nil {
+				if _, ok := err.(*errUnknownField); ok {
+					t.Errorf("%s.%s: %v", def.name, f.name, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,428 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"strings"
+)
+
+// typeDef describes a named type of the schema for introspection.
+type typeDef struct {
+	kind        string // SCALAR, OBJECT, INPUT_OBJECT or ENUM
+	name        string
+	description string
+	fields      []*fieldDef // Fields of objects, input fields of input objects
+	enumValues  []string
+}
+
+// fieldDef describes a field, an argument or an input field.
+type fieldDef struct {
+	name string
+	typ  string      // Type reference in SDL notation, e.g. [Block!]!
+	def  string      // Default value literal of arguments, empty if none
+	args []*fieldDef // Arguments of fields
+}
+
+// directiveDef describes a directive supported by the executor.
+type directiveDef struct {
+	name        string
+	description string
+	locations   []string
+	args        []*fieldDef
+}
+
+func fieldOf(name, typ string, args ...*fieldDef) *fieldDef {
+	return &fieldDef{name: name, typ: typ, args: args}
+}
+
+func scalarOf(name, description string) *typeDef {
+	return &typeDef{kind: "SCALAR", name: name, description: description}
+}
+
+// schemaTypeDefs lists the types of the schema, mirroring the resolvers of the
+// object types and the meta types of the introspection system itself.
+var schemaTypeDefs = []*typeDef{
+	{kind: "OBJECT", name: "Query", fields: []*fieldDef{
+		fieldOf("block", "Block", fieldOf("number", "Long"), fieldOf("hash", "Bytes32")),
+		fieldOf("blocks", "[Block!]!", fieldOf("from", "Long!"), fieldOf("to", "Long")),
+		fieldOf("transaction", "Transaction", fieldOf("hash", "Bytes32!")),
+		fieldOf("account", "Account!", fieldOf("address", "Address!"), fieldOf("block", "Long")),
+		fieldOf("logs", "[Log!]!", fieldOf("filter", "FilterCriteria")),
+	}},
+	{kind: "OBJECT", name: "Block", fields: []*fieldDef{
+		fieldOf("number", "Long!"),
+		fieldOf("hash", "Bytes32!"),
+		fieldOf("parent", "Block"),
+		fieldOf("nonce", "Bytes!"),
+		fieldOf("transactionsRoot", "Bytes32!"),
+		fieldOf("stateRoot", "Bytes32!"),
+		fieldOf("receiptsRoot", "Bytes32!"),
+		fieldOf("validator", "Account!"),
+		fieldOf("miner", "Account!"),
+		fieldOf("extraData", "Bytes!"),
+		fieldOf("gasLimit", "BigInt!"),
+		fieldOf("gasUsed", "BigInt!"),
+		fieldOf("timestamp", "BigInt!"),
+		fieldOf("logsBloom", "Bytes!"),
+		fieldOf("mixHash", "Bytes32!"),
+		fieldOf("difficulty", "BigInt!"),
+		fieldOf("totalDifficulty", "BigInt!"),
+		fieldOf("transactionCount", "Int!"),
+		fieldOf("transactions", "[Transaction!]!", fieldOf("skip", "Int"), fieldOf("first", "Int")),
+		fieldOf("transactionAt", "Transaction", fieldOf("index", "Int!")),
+		fieldOf("logs", "[Log!]!", fieldOf("filter", "BlockFilterCriteria")),
+		fieldOf("account", "Account!", fieldOf("address", "Address!")),
+	}},
+	{kind: "OBJECT", name: "Transaction", fields: []*fieldDef{
+		fieldOf("hash", "Bytes32!"),
+		fieldOf("type", "String!"),
+		fieldOf("nonce", "Long!"),
+		fieldOf("index", "Int"),
+		fieldOf("from", "Account!"),
+		fieldOf("to", "Account"),
+		fieldOf("value", "BigInt!"),
+		fieldOf("gasPrice", "BigInt!"),
+		fieldOf("gas", "BigInt!"),
+		fieldOf("inputData", "Bytes!"),
+		fieldOf("block", "Block"),
+		fieldOf("status", "Long"),
+		fieldOf("gasUsed", "BigInt"),
+		fieldOf("cumulativeGasUsed", "BigInt"),
+		fieldOf("createdContract", "Account"),
+		fieldOf("logs", "[Log!]"),
+	}},
+	{kind: "OBJECT", name: "Log", fields: []*fieldDef{
+		fieldOf("index", "Int!"),
+		fieldOf("account", "Account!"),
+		fieldOf("topics", "[Bytes32!]!"),
+		fieldOf("data", "Bytes!"),
+		fieldOf("transaction", "Transaction!"),
+	}},
+	{kind: "OBJECT", name: "Account", fields: []*fieldDef{
+		fieldOf("address", "Address!"),
+		fieldOf("balance", "BigInt!"),
+		fieldOf("transactionCount", "Long!"),
+		fieldOf("code", "Bytes!"),
+		fieldOf("storage", "Bytes32!", fieldOf("slot", "Bytes32!")),
+	}},
+	{kind: "INPUT_OBJECT", name: "FilterCriteria", fields: []*fieldDef{
+		fieldOf("fromBlock", "Long"),
+		fieldOf("toBlock", "Long"),
+		fieldOf("addresses", "[Address!]"),
+		fieldOf("topics", "[[Bytes32!]!]"),
+	}},
+	{kind: "INPUT_OBJECT", name: "BlockFilterCriteria", fields: []*fieldDef{
+		fieldOf("addresses", "[Address!]"),
+		fieldOf("topics", "[[Bytes32!]!]"),
+	}},
+	scalarOf("Bytes32", "32 byte binary string, hex encoded with 0x prefix."),
+	scalarOf("Address", "20 byte account address, hex encoded with 0x prefix."),
+	scalarOf("Bytes", "Arbitrary length binary string, hex encoded with 0x prefix."),
+	scalarOf("BigInt", "Arbitrary precision integer, hex encoded with 0x prefix. Decimal strings are accepted as input."),
+	scalarOf("Long", "64 bit unsigned integer. Hex and decimal strings are accepted as input."),
+	scalarOf("Int", ""),
+	scalarOf("String", ""),
+	scalarOf("Boolean", ""),
+
+	{kind: "OBJECT", name: "__Schema", fields: []*fieldDef{
+		fieldOf("description", "String"),
+		fieldOf("types", "[__Type!]!"),
+		fieldOf("queryType", "__Type!"),
+		fieldOf("mutationType", "__Type"),
+		fieldOf("subscriptionType", "__Type"),
+		fieldOf("directives", "[__Directive!]!"),
+	}},
+	{kind: "OBJECT", name: "__Type", fields: []*fieldDef{
+		fieldOf("kind", "__TypeKind!"),
+		fieldOf("name", "String"),
+		fieldOf("description", "String"),
+		fieldOf("fields", "[__Field!]", &fieldDef{name: "includeDeprecated", typ: "Boolean", def: "false"}),
+		fieldOf("interfaces", "[__Type!]"),
+		fieldOf("possibleTypes", "[__Type!]"),
+		fieldOf("enumValues", "[__EnumValue!]", &fieldDef{name: "includeDeprecated", typ: "Boolean", def: "false"}),
+		fieldOf("inputFields", "[__InputValue!]"),
+		fieldOf("ofType", "__Type"),
+		fieldOf("specifiedByURL", "String"),
+	}},
+	{kind: "OBJECT", name: "__Field", fields: []*fieldDef{
+		fieldOf("name", "String!"),
+		fieldOf("description", "String"),
+		fieldOf("args", "[__InputValue!]!"),
+		fieldOf("type", "__Type!"),
+		fieldOf("isDeprecated", "Boolean!"),
+		fieldOf("deprecationReason", "String"),
+	}},
+	{kind: "OBJECT", name: "__InputValue", fields: []*fieldDef{
+		fieldOf("name", "String!"),
+		fieldOf("description", "String"),
+		fieldOf("type", "__Type!"),
+		fieldOf("defaultValue", "String"),
+	}},
+	{kind: "OBJECT", name: "__EnumValue", fields: []*fieldDef{
+		fieldOf("name", "String!"),
+		fieldOf("description", "String"),
+		fieldOf("isDeprecated", "Boolean!"),
+		fieldOf("deprecationReason", "String"),
+	}},
+	{kind: "OBJECT", name: "__Directive", fields: []*fieldDef{
+		fieldOf("name", "String!"),
+		fieldOf("description", "String"),
+		fieldOf("locations", "[__DirectiveLocation!]!"),
+		fieldOf("args", "[__InputValue!]!"),
+		fieldOf("isRepeatable", "Boolean!"),
+	}},
+	{kind: "ENUM", name: "__TypeKind", enumValues: []string{
+		"SCALAR", "OBJECT", "INTERFACE", "UNION", "ENUM", "INPUT_OBJECT", "LIST", "NON_NULL",
+	}},
+	{kind: "ENUM", name: "__DirectiveLocation", enumValues: []string{
+		"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION", "FRAGMENT_SPREAD",
+		"INLINE_FRAGMENT", "VARIABLE_DEFINITION", "SCHEMA", "SCALAR", "OBJECT", "FIELD_DEFINITION",
+		"ARGUMENT_DEFINITION", "INTERFACE", "UNION", "ENUM", "ENUM_VALUE", "INPUT_OBJECT",
+		"INPUT_FIELD_DEFINITION",
+	}},
+}
+
+// schemaTypes indexes the types of the schema by name.
+var schemaTypes = func() map[string]*typeDef {
+	index := make(map[string]*typeDef, len(schemaTypeDefs))
+	for _, def := range schemaTypeDefs {
+		index[def.name] = def
+	}
+	return index
+}()
+
+// schemaDirectives lists the directives evaluated by the executor.
+var schemaDirectives = []*directiveDef{
+	{
+		name:        "skip",
+		description: "Directs the executor to skip this field or fragment when the `if` argument is true.",
+		locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		args:        []*fieldDef{fieldOf("if", "Boolean!")},
+	},
+	{
+		name:        "include",
+		description: "Directs the executor to include this field or fragment only when the `if` argument is true.",
+		locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		args:        []*fieldDef{fieldOf("if", "Boolean!")},
+	},
+}
+
+// nullable returns nil for empty strings, which introspection reports as null.
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// schemaObj is the __Schema type, the root of the introspection system.
+type schemaObj struct{}
+
+func (s *schemaObj) typeName() string { return "__Schema" }
+
+func (s *schemaObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "description", "mutationType", "subscriptionType":
+		return nil, nil
+	case "types":
+		objs := make([]object, len(schemaTypeDefs))
+		for i, def := range schemaTypeDefs {
+			objs[i] = &typeObj{kind: def.kind, def: def}
+		}
+		return objs, nil
+	case "queryType":
+		return typeRef("Query"), nil
+	case "directives":
+		objs := make([]object, len(schemaDirectives))
+		for i, def := range schemaDirectives {
+			objs[i] = &directiveObj{def: def}
+		}
+		return objs, nil
+	}
+	return nil, &errUnknownField{s.typeName(), name}
+}
+
+// typeObj is the __Type type, describing either a named type or a list or
+// non-null wrapper of another type.
+type typeObj struct {
+	kind   string
+	def    *typeDef // Named type, nil for wrappers
+	ofType *typeObj // Wrapped type, nil for named types
+}
+
+// typeRef resolves a type reference in SDL notation into its introspection type.
+func typeRef(ref string) *typeObj {
+	switch {
+	case strings.HasSuffix(ref, "!"):
+		return &typeObj{kind: "NON_NULL", ofType: typeRef(ref[:len(ref)-1])}
+	case strings.HasPrefix(ref, "["):
+		return &typeObj{kind: "LIST", ofType: typeRef(ref[1 : len(ref)-1])}
+	}
+	def := schemaTypes[ref]
+	return &typeObj{kind: def.kind, def: def}
+}
+
+func (t *typeObj) typeName() string { return "__Type" }
+
+func (t *typeObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "kind":
+		return t.kind, nil
+	case "name":
+		if t.def == nil {
+			return nil, nil
+		}
+		return t.def.name, nil
+	case "description":
+		if t.def == nil {
+			return nil, nil
+		}
+		return nullable(t.def.description), nil
+	case "fields":
+		if t.kind != "OBJECT" {
+			return nil, nil
+		}
+		objs := make([]object, len(t.def.fields))
+		for i, def := range t.def.fields {
+			objs[i] = &fieldObj{def: def}
+		}
+		return objs, nil
+	case "inputFields":
+		if t.kind != "INPUT_OBJECT" {
+			return nil, nil
+		}
+		return inputValues(t.def.fields), nil
+	case "interfaces":
+		if t.kind != "OBJECT" {
+			return nil, nil
+		}
+		return []object{}, nil
+	case "enumValues":
+		if t.kind != "ENUM" {
+			return nil, nil
+		}
+		objs := make([]object, len(t.def.enumValues))
+		for i, value := range t.def.enumValues {
+			objs[i] = &enumValueObj{name: value}
+		}
+		return objs, nil
+	case "ofType":
+		if t.ofType == nil {
+			return nil, nil
+		}
+		return t.ofType, nil
+	case "possibleTypes", "specifiedByURL":
+		return nil, nil
+	}
+	return nil, &errUnknownField{t.typeName(), name}
+}
+
+// fieldObj is the __Field type.
+type fieldObj struct {
+	def *fieldDef
+}
+
+func (f *fieldObj) typeName() string { return "__Field" }
+
+func (f *fieldObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "name":
+		return f.def.name, nil
+	case "description", "deprecationReason":
+		return nil, nil
+	case "args":
+		return inputValues(f.def.args), nil
+	case "type":
+		return typeRef(f.def.typ), nil
+	case "isDeprecated":
+		return false, nil
+	}
+	return nil, &errUnknownField{f.typeName(), name}
+}
+
+// inputValueObj is the __InputValue type, describing arguments and input fields.
+type inputValueObj struct {
+	def *fieldDef
+}
+
+// inputValues wraps argument or input field definitions into objects.
+func inputValues(defs []*fieldDef) []object {
+	objs := make([]object, len(defs))
+	for i, def := range defs {
+		objs[i] = &inputValueObj{def: def}
+	}
+	return objs
+}
+
+func (v *inputValueObj) typeName() string { return "__InputValue" }
+
+func (v *inputValueObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "name":
+		return v.def.name, nil
+	case "description":
+		return nil, nil
+	case "type":
+		return typeRef(v.def.typ), nil
+	case "defaultValue":
+		return nullable(v.def.def), nil
+	}
+	return nil, &errUnknownField{v.typeName(), name}
+}
+
+// enumValueObj is the __EnumValue type.
+type enumValueObj struct {
+	name string
+}
+
+func (v *enumValueObj) typeName() string { return "__EnumValue" }
+
+func (v *enumValueObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "name":
+		return v.name, nil
+	case "description", "deprecationReason":
+		return nil, nil
+	case "isDeprecated":
+		return false, nil
+	}
+	return nil, &errUnknownField{v.typeName(), name}
+}
+
+// directiveObj is the __Directive type.
+type directiveObj struct {
+	def *directiveDef
+}
+
+func (d *directiveObj) typeName() string { return "__Directive" }
+
+func (d *directiveObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "name":
+		return d.def.name, nil
+	case "description":
+		return d.def.description, nil
+	case "locations":
+		return d.def.locations, nil
+	case "args":
+		return inputValues(d.def.args), nil
+	case "isRepeatable":
+		return false, nil
+	}
+	return nil, &errUnknownField{d.typeName(), name}
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+		# Fetch a block with its transactions
+		query Block($number: Long = 7, $first: Int!) {
+			head: block(number: $number) {
+				hash
+				transactions(first: $first, skip: 0) { ...tx }
+				logs(filter: {addresses: ["0x01"], topics: [[], ["0x02", "0x03"]]}) { data }
+			}
+		}
+		fragment tx on Transaction { hash value ... on Transaction { gas } }
+	`)
+	if err != nil {
+		t.Fatalf("failed to parse query: %v", err)
+	}
+	if len(doc.operations) != 1 || len(doc.fragments) != 1 {
+		t.Fatalf("definition count mismatch: have %d operations and %d fragments", len(doc.operations), len(doc.fragments))
+	}
+	op := doc.operations[0]
+	if op.name != "Block" || len(op.vars) != 2 || op.vars[0].def != int64(7) || op.vars[1].def != nil {
+		t.Errorf("operation mismatch: %+v", op)
+	}
+	head := op.selections[0].(*field)
+	if head.alias != "head" || head.name != "block" || head.args["number"] != variable("number") || len(head.selections) != 3 {
+		t.Errorf("aliased field mismatch: %+v", head)
+	}
+	txs := head.selections[1].(*field)
+	want := map[string]interface{}{"first": variable("first"), "skip": int64(0)}
+	if !reflect.DeepEqual(txs.args, want) {
+		t.Errorf("arguments mismatch: have %v, want %v", txs.args, want)
+	}
+	if spread, ok := txs.selections[0].(*fragmentSpread); !ok || spread.name != "tx" {
+		t.Errorf("fragment spread mismatch: %+v", txs.selections[0])
+	}
+	logs := head.selections[2].(*field)
+	filter := map[string]interface{}{
+		"addresses": []interface{}{"0x01"},
+		"topics":    []interface{}{[]interface{}{}, []interface{}{"0x02", "0x03"}},
+	}
+	if !reflect.DeepEqual(logs.args["filter"], filter) {
+		t.Errorf("input object mismatch: have %v, want %v", logs.args["filter"], filter)
+	}
+	if _, ok := doc.fragments["tx"][2].(*inlineFragment); !ok {
+		t.Errorf("inline fragment mismatch: %+v", doc.fragments["tx"][2])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`{}`,
+		`{ block { hash }`,
+		`mutation { block }`,
+		`{ block(number: ) }`,
+		`{ block(hash: "0x01) }`,
+		`{ block(number: $n) } fragment a on Block { hash } fragment a on Block { hash }`,
+		`query ($n: ) { block }`,
+		`{ a.b }`,
+	}
+	for _, query := range tests {
+		if _, err := parse(query); err == nil {
+			t.Errorf("query %q: expected error", query)
+		}
+	}
+}
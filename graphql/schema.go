@@ -0,0 +1,662 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kok/filters"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// maxBlockRange is the maximum number of blocks a single blocks query can span.
+const maxBlockRange = 1000
+
+var errBlockRange = fmt.Errorf("block range exceeds %d blocks", maxBlockRange)
+
+// Backend is the chain access the query resolvers need.
+type Backend interface {
+	filters.Backend
+
+	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
+	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
+	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
+	GetTd(blockHash common.Hash) *big.Int
+	GetPoolTransaction(txHash common.Hash) *types.Transaction
+	ChainConfig() *params.ChainConfig
+}
+
+// queryObj is the root object of the schema:
+//
+//	type Query {
+//	  block(number: Long, hash: Bytes32): Block
+//	  blocks(from: Long!, to: Long): [Block!]!
+//	  transaction(hash: Bytes32!): Transaction
+//	  account(address: Address!, block: Long): Account!
+//	  logs(filter: FilterCriteria): [Log!]!
+//	}
+//
+//	input FilterCriteria {
+//	  fromBlock: Long
+//	  toBlock: Long
+//	  addresses: [Address!]
+//	  topics: [[Bytes32!]!]
+//	}
+//
+// Besides, the root object resolves the __schema and __type meta fields of the
+// introspection system.
+type queryObj struct {
+	backend Backend
+}
+
+func (q *queryObj) typeName() string { return "Query" }
+
+func (q *queryObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "block":
+		var (
+			block *types.Block
+			err   error
+		)
+		switch {
+		case args.has("hash"):
+			hash, herr := args.hash("hash")
+			if herr != nil {
+				return nil, herr
+			}
+			block, err = q.backend.GetBlock(ctx, hash)
+		case args.has("number"):
+			number, nerr := args.long("number")
+			if nerr != nil {
+				return nil, nerr
+			}
+			block, err = q.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+		default:
+			block, err = q.backend.BlockByNumber(ctx, rpc.LatestBlockNumber)
+		}
+		if block == nil || err != nil {
+			return nil, err
+		}
+		return &blockObj{backend: q.backend, block: block}, nil
+
+	case "blocks":
+		from, err := args.long("from")
+		if err != nil {
+			return nil, err
+		}
+		head, err := q.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if err != nil {
+			return nil, err
+		}
+		to := head.Number.Uint64()
+		if args.has("to") {
+			if to, err = args.long("to"); err != nil {
+				return nil, err
+			}
+		}
+		if to >= from && to-from >= maxBlockRange {
+			return nil, errBlockRange
+		}
+		blocks := []object{}
+		for number := from; number <= to; number++ {
+			block, err := q.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+			if err != nil {
+				return nil, err
+			}
+			if block == nil {
+				break
+			}
+			blocks = append(blocks, &blockObj{backend: q.backend, block: block})
+		}
+		return blocks, nil
+
+	case "transaction":
+		hash, err := args.hash("hash")
+		if err != nil {
+			return nil, err
+		}
+		tx, blockHash, _, index := core.GetTransaction(q.backend.ChainDb(), hash)
+		if tx == nil {
+			if tx = q.backend.GetPoolTransaction(hash); tx == nil {
+				return nil, nil
+			}
+			return &transactionObj{backend: q.backend, tx: tx}, nil
+		}
+		block, err := q.backend.GetBlock(ctx, blockHash)
+		if block == nil || err != nil {
+			return nil, err
+		}
+		return &transactionObj{backend: q.backend, tx: tx, block: block, index: index}, nil
+
+	case "account":
+		address, err := args.address("address")
+		if err != nil {
+			return nil, err
+		}
+		number := rpc.LatestBlockNumber
+		if args.has("block") {
+			n, err := args.long("block")
+			if err != nil {
+				return nil, err
+			}
+			number = rpc.BlockNumber(n)
+		}
+		return &accountObj{backend: q.backend, address: address, number: number}, nil
+
+	case "logs":
+		filter, err := args.object("filter")
+		if err != nil {
+			return nil, err
+		}
+		from, to := int64(rpc.LatestBlockNumber), int64(rpc.LatestBlockNumber)
+		if filter.has("fromBlock") {
+			n, err := filter.long("fromBlock")
+			if err != nil {
+				return nil, err
+			}
+			from = int64(n)
+		}
+		if filter.has("toBlock") {
+			n, err := filter.long("toBlock")
+			if err != nil {
+				return nil, err
+			}
+			to = int64(n)
+		}
+		addresses, topics, err := logCriteria(filter)
+		if err != nil {
+			return nil, err
+		}
+		logs, err := filters.New(q.backend, from, to, addresses, topics).Logs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return q.logs(ctx, logs)
+
+	case "__schema":
+		return &schemaObj{}, nil
+
+	case "__type":
+		typeName, ok := args["name"].(string)
+		if !ok {
+			return nil, fmt.Errorf("argument %q: expected string, got %v", "name", args["name"])
+		}
+		if _, ok := schemaTypes[typeName]; !ok {
+			return nil, nil
+		}
+		return typeRef(typeName), nil
+	}
+	return nil, &errUnknownField{q.typeName(), name}
+}
+
+// logs wraps filtered logs into objects, resolving the transactions emitting them.
+func (q *queryObj) logs(ctx context.Context, logs []*types.Log) ([]object, error) {
+	var (
+		objs   = make([]object, len(logs))
+		blocks = make(map[common.Hash]*types.Block)
+	)
+	for i, log := range logs {
+		block, ok := blocks[log.BlockHash]
+		if !ok {
+			var err error
+			if block, err = q.backend.GetBlock(ctx, log.BlockHash); err != nil {
+				return nil, err
+			}
+			blocks[log.BlockHash] = block
+		}
+		objs[i] = &logObj{backend: q.backend, log: log, block: block}
+	}
+	return objs, nil
+}
+
+// logCriteria extracts the address and topic filter criteria from an input object.
+func logCriteria(filter arguments) ([]common.Address, [][]common.Hash, error) {
+	var addresses []common.Address
+	for _, v := range filter.list("addresses") {
+		address, err := toAddress("addresses", v)
+		if err != nil {
+			return nil, nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	var topics [][]common.Hash
+	for _, v := range filter.list("topics") {
+		var position []common.Hash
+		for _, t := range (arguments{"topics": v}).list("topics") {
+			topic, err := toHash("topics", t)
+			if err != nil {
+				return nil, nil, err
+			}
+			position = append(position, topic)
+		}
+		topics = append(topics, position)
+	}
+	return addresses, topics, nil
+}
+
+// blockObj is the Block type:
+//
+//	type Block {
+//	  number: Long!
+//	  hash: Bytes32!
+//	  parent: Block
+//	  nonce: Bytes!
+//	  transactionsRoot: Bytes32!
+//	  stateRoot: Bytes32!
+//	  receiptsRoot: Bytes32!
+//	  validator: Account!
+//	  miner: Account!
+//	  extraData: Bytes!
+//	  gasLimit: BigInt!
+//	  gasUsed: BigInt!
+//	  timestamp: BigInt!
+//	  logsBloom: Bytes!
+//	  mixHash: Bytes32!
+//	  difficulty: BigInt!
+//	  totalDifficulty: BigInt!
+//	  transactionCount: Int!
+//	  transactions(skip: Int, first: Int): [Transaction!]!
+//	  transactionAt(index: Int!): Transaction
+//	  logs(filter: BlockFilterCriteria): [Log!]!
+//	  account(address: Address!): Account!
+//	}
+//
+//	input BlockFilterCriteria {
+//	  addresses: [Address!]
+//	  topics: [[Bytes32!]!]
+//	}
+type blockObj struct {
+	backend Backend
+	block   *types.Block
+}
+
+func (b *blockObj) typeName() string { return "Block" }
+
+func (b *blockObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	header := b.block.Header()
+	switch name {
+	case "number":
+		return header.Number.Uint64(), nil
+	case "hash":
+		return b.block.Hash(), nil
+	case "parent":
+		if header.Number.Sign() == 0 {
+			return nil, nil
+		}
+		parent, err := b.backend.GetBlock(ctx, header.ParentHash)
+		if parent == nil || err != nil {
+			return nil, err
+		}
+		return &blockObj{backend: b.backend, block: parent}, nil
+	case "nonce":
+		return hexutil.Bytes(header.Nonce[:]), nil
+	case "transactionsRoot":
+		return header.TxHash, nil
+	case "stateRoot":
+		return header.Root, nil
+	case "receiptsRoot":
+		return header.ReceiptHash, nil
+	case "validator":
+		return b.account(header.Validator), nil
+	case "miner":
+		return b.account(header.Coinbase), nil
+	case "extraData":
+		return hexutil.Bytes(header.Extra), nil
+	case "gasLimit":
+		return (*hexutil.Big)(header.GasLimit), nil
+	case "gasUsed":
+		return (*hexutil.Big)(header.GasUsed), nil
+	case "timestamp":
+		return (*hexutil.Big)(header.Time), nil
+	case "logsBloom":
+		return hexutil.Bytes(header.Bloom.Bytes()), nil
+	case "mixHash":
+		return header.MixDigest, nil
+	case "difficulty":
+		return (*hexutil.Big)(header.Difficulty), nil
+	case "totalDifficulty":
+		td := b.backend.GetTd(b.block.Hash())
+		if td == nil {
+			return nil, nil
+		}
+		return (*hexutil.Big)(td), nil
+	case "transactionCount":
+		return len(b.block.Transactions()), nil
+	case "transactions":
+		txs := b.block.Transactions()
+		skip, err := args.int("skip", 0)
+		if err != nil {
+			return nil, err
+		}
+		first, err := args.int("first", len(txs))
+		if err != nil {
+			return nil, err
+		}
+		objs := []object{}
+		for i := skip; i < len(txs) && i < skip+first; i++ {
+			objs = append(objs, &transactionObj{backend: b.backend, tx: txs[i], block: b.block, index: uint64(i)})
+		}
+		return objs, nil
+	case "transactionAt":
+		index, err := args.long("index")
+		if err != nil {
+			return nil, err
+		}
+		txs := b.block.Transactions()
+		if index >= uint64(len(txs)) {
+			return nil, nil
+		}
+		return &transactionObj{backend: b.backend, tx: txs[index], block: b.block, index: index}, nil
+	case "logs":
+		filter, err := args.object("filter")
+		if err != nil {
+			return nil, err
+		}
+		addresses, topics, err := logCriteria(filter)
+		if err != nil {
+			return nil, err
+		}
+		receipts, err := b.backend.GetReceipts(ctx, b.block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		objs := []object{}
+		for _, receipt := range receipts {
+			for _, log := range receipt.Logs {
+				if matchLog(log, addresses, topics) {
+					objs = append(objs, &logObj{backend: b.backend, log: log, block: b.block})
+				}
+			}
+		}
+		return objs, nil
+	case "account":
+		address, err := args.address("address")
+		if err != nil {
+			return nil, err
+		}
+		return b.account(address), nil
+	}
+	return nil, &errUnknownField{b.typeName(), name}
+}
+
+// account returns an account resolved against the state of the block.
+func (b *blockObj) account(address common.Address) *accountObj {
+	return &accountObj{backend: b.backend, address: address, number: rpc.BlockNumber(b.block.NumberU64())}
+}
+
+// matchLog reports whether a log matches the address and topic criteria, using
+// the same semantics as the log filters.
+func matchLog(log *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		found := false
+		for _, address := range addresses {
+			if log.Address == address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+	for i, position := range topics {
+		if len(position) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range position {
+			if log.Topics[i] == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// transactionObj is the Transaction type:
+//
+//	type Transaction {
+//	  hash: Bytes32!
+//	  type: String!
+//	  nonce: Long!
+//	  index: Int
+//	  from: Account!
+//	  to: Account
+//	  value: BigInt!
+//	  gasPrice: BigInt!
+//	  gas: BigInt!
+//	  inputData: Bytes!
+//	  block: Block
+//	  status: Long
+//	  gasUsed: BigInt
+//	  cumulativeGasUsed: BigInt
+//	  createdContract: Account
+//	  logs: [Log!]
+//	}
+//
+// The position and receipt fields are null for pending transactions.
+type transactionObj struct {
+	backend Backend
+	tx      *types.Transaction
+	block   *types.Block // Block containing the transaction, nil if pending
+	index   uint64
+}
+
+func (t *transactionObj) typeName() string { return "Transaction" }
+
+func (t *transactionObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "hash":
+		return t.tx.Hash(), nil
+	case "type":
+		return t.tx.Type().String(), nil
+	case "nonce":
+		return t.tx.Nonce(), nil
+	case "index":
+		if t.block == nil {
+			return nil, nil
+		}
+		return t.index, nil
+	case "from":
+		from, err := types.Sender(t.signer(), t.tx)
+		if err != nil {
+			return nil, err
+		}
+		return t.account(from), nil
+	case "to":
+		if t.tx.To() == nil {
+			return nil, nil
+		}
+		return t.account(*t.tx.To()), nil
+	case "value":
+		return (*hexutil.Big)(t.tx.Value()), nil
+	case "gasPrice":
+		return (*hexutil.Big)(t.tx.GasPrice()), nil
+	case "gas":
+		return (*hexutil.Big)(t.tx.Gas()), nil
+	case "inputData":
+		return hexutil.Bytes(t.tx.Data()), nil
+	case "block":
+		if t.block == nil {
+			return nil, nil
+		}
+		return &blockObj{backend: t.backend, block: t.block}, nil
+	case "status", "gasUsed", "cumulativeGasUsed", "createdContract", "logs":
+		receipt, err := t.receipt(ctx)
+		if receipt == nil || err != nil {
+			return nil, err
+		}
+		switch name {
+		case "status":
+			return receipt.Status, nil
+		case "gasUsed":
+			return (*hexutil.Big)(receipt.GasUsed), nil
+		case "cumulativeGasUsed":
+			return (*hexutil.Big)(receipt.CumulativeGasUsed), nil
+		case "createdContract":
+			if t.tx.To() != nil {
+				return nil, nil
+			}
+			return t.account(receipt.ContractAddress), nil
+		default:
+			objs := []object{}
+			for _, log := range receipt.Logs {
+				objs = append(objs, &logObj{backend: t.backend, log: log, block: t.block})
+			}
+			return objs, nil
+		}
+	}
+	return nil, &errUnknownField{t.typeName(), name}
+}
+
+// signer returns the signer the transaction was validated with.
+func (t *transactionObj) signer() types.Signer {
+	if t.block == nil {
+		return types.MakeSigner(t.backend.ChainConfig(), nil)
+	}
+	return types.MakeSigner(t.backend.ChainConfig(), t.block.Number())
+}
+
+// account returns an account resolved against the state of the block containing
+// the transaction, or the latest state if it is pending.
+func (t *transactionObj) account(address common.Address) *accountObj {
+	number := rpc.LatestBlockNumber
+	if t.block != nil {
+		number = rpc.BlockNumber(t.block.NumberU64())
+	}
+	return &accountObj{backend: t.backend, address: address, number: number}
+}
+
+// receipt retrieves the receipt of the transaction, nil if it is pending.
+func (t *transactionObj) receipt(ctx context.Context) (*types.Receipt, error) {
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.backend.GetReceipts(ctx, t.block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if t.index >= uint64(len(receipts)) {
+		return nil, errors.New("receipt not found")
+	}
+	return receipts[t.index], nil
+}
+
+// logObj is the Log type:
+//
+//	type Log {
+//	  index: Int!
+//	  account: Account!
+//	  topics: [Bytes32!]!
+//	  data: Bytes!
+//	  transaction: Transaction!
+//	}
+type logObj struct {
+	backend Backend
+	log     *types.Log
+	block   *types.Block
+}
+
+func (l *logObj) typeName() string { return "Log" }
+
+func (l *logObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	switch name {
+	case "index":
+		return l.log.Index, nil
+	case "account":
+		return &accountObj{backend: l.backend, address: l.log.Address, number: rpc.BlockNumber(l.log.BlockNumber)}, nil
+	case "topics":
+		return l.log.Topics, nil
+	case "data":
+		return hexutil.Bytes(l.log.Data), nil
+	case "transaction":
+		if l.block == nil {
+			return nil, nil
+		}
+		txs := l.block.Transactions()
+		if int(l.log.TxIndex) >= len(txs) {
+			return nil, errors.New("transaction not found")
+		}
+		return &transactionObj{backend: l.backend, tx: txs[l.log.TxIndex], block: l.block, index: uint64(l.log.TxIndex)}, nil
+	}
+	return nil, &errUnknownField{l.typeName(), name}
+}
+
+// accountObj is the Account type, resolved against the state of a block:
+//
+//	type Account {
+//	  address: Address!
+//	  balance: BigInt!
+//	  transactionCount: Long!
+//	  code: Bytes!
+//	  storage(slot: Bytes32!): Bytes32!
+//	}
+type accountObj struct {
+	backend Backend
+	address common.Address
+	number  rpc.BlockNumber
+}
+
+func (a *accountObj) typeName() string { return "Account" }
+
+func (a *accountObj) resolve(ctx context.Context, name string, args arguments) (interface{}, error) {
+	if name == "address" {
+		return a.address, nil
+	}
+	if name != "balance" && name != "transactionCount" && name != "code" && name != "storage" {
+		return nil, &errUnknownField{a.typeName(), name}
+	}
+	statedb, _, err := a.backend.StateAndHeaderByNumber(ctx, a.number)
+	if err != nil {
+		return nil, err
+	}
+	if statedb == nil {
+		return nil, errors.New("state not available")
+	}
+	var result interface{}
+	switch name {
+	case "balance":
+		result = (*hexutil.Big)(statedb.GetBalance(a.address))
+	case "transactionCount":
+		result = statedb.GetNonce(a.address)
+	case "code":
+		result = hexutil.Bytes(statedb.GetCode(a.address))
+	case "storage":
+		slot, err := args.hash("slot")
+		if err != nil {
+			return nil, err
+		}
+		result = statedb.GetState(a.address, slot)
+	}
+	return result, statedb.Error()
+}
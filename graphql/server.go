@@ -0,0 +1,175 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql implements a GraphQL query service over the chain data, letting
+// clients retrieve blocks, transactions, receipts, logs and accounts with exactly
+// the fields they need in a single request.
+//
+// The queries are served over HTTP at the /graphql path of a dedicated endpoint.
+// The supported language subset covers queries with variables, aliases,
+// arguments, fragments, the @skip and @include directives and introspection;
+// mutations and subscriptions are not supported.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/kokprojects/go-kok/log"
+	"github.com/rs/cors"
+)
+
+const (
+	maxRequestContentLength = 1024 * 128       // Maximum size of a query request body
+	readTimeout             = 10 * time.Second // Deadline for reading a query request
+	writeTimeout            = 30 * time.Second // Deadline for executing and answering a query
+)
+
+// Server answers GraphQL queries over HTTP at the /graphql path.
+type Server struct {
+	handler  http.Handler
+	listener net.Listener
+}
+
+// NewServer creates a GraphQL server resolving queries against the given backend,
+// accepting cross-origin requests from the given origins.
+func NewServer(backend Backend, cors []string) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", newHandler(backend, cors))
+	return &Server{handler: mux}
+}
+
+// ServeHTTP implements http.Handler, delegating to the /graphql handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Start opens the listener of the server on the given endpoint and starts
+// serving queries in the background.
+func (s *Server) Start(endpoint string) error {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:      s,
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+	}
+	go srv.Serve(listener)
+	s.listener = listener
+
+	log.Info("GraphQL endpoint opened", "url", fmt.Sprintf("http://%s/graphql", listener.Addr()))
+	return nil
+}
+
+// Stop closes the listener of the server.
+func (s *Server) Stop() {
+	if s.listener != nil {
+		s.listener.Close()
+		log.Info("GraphQL endpoint closed", "url", fmt.Sprintf("http://%s/graphql", s.listener.Addr()))
+		s.listener = nil
+	}
+}
+
+// request is a GraphQL request, carried either by the URL parameters of a GET
+// request or by the JSON body of a POST request.
+type request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// handler serves GraphQL requests. Queries are accepted as GET requests with
+// the query, variables and operationName URL parameters, as POST requests with
+// a JSON request body, or as POST requests with a bare application/graphql
+// query document.
+type handler struct {
+	backend Backend
+}
+
+// newHandler creates a GraphQL request handler, allowing cross-origin requests
+// from the given origins.
+func newHandler(backend Backend, allowedOrigins []string) http.Handler {
+	h := &handler{backend: backend}
+	if len(allowedOrigins) == 0 {
+		return h
+	}
+	c := cors.New(cors.Options{
+		AllowedOrigins: allowedOrigins,
+		AllowedMkokods: []string{"POST", "GET"},
+		MaxAge:         600,
+		AllowedHeaders: []string{"*"},
+	})
+	return c.Handler(h)
+}
+
+// ServeHTTP implements http.Handler, executing a single GraphQL request.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Mkokod {
+	case "GET":
+		params := r.URL.Query()
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if vars := params.Get("variables"); vars != "" {
+			if err := decodeJSON([]byte(vars), &req.Variables); err != nil {
+				http.Error(w, "invalid variables: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case "POST":
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestContentLength))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("content length too large (>%d)", maxRequestContentLength), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if mt, _, _ := mime.ParseMediaType(r.Header.Get("content-type")); mt == "application/graphql" {
+			req.Query = string(body)
+		} else if err := decodeJSON(body, &req); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "mkokod not allowed", http.StatusMkokodNotAllowed)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "missing query", http.StatusBadRequest)
+		return
+	}
+	response := execute(r.Context(), &queryObj{backend: h.backend}, req.Query, req.OperationName, req.Variables)
+
+	w.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Debug("Failed to write GraphQL response", "err", err)
+	}
+}
+
+// decodeJSON decodes a JSON value, keeping numbers in their textual form so that
+// 64 bit integers survive without loss of precision.
+func decodeJSON(blob []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestServerRequests(t *testing.T) {
+	srv := httptest.NewServer(NewServer(newTestBackend(t), nil))
+	defer srv.Close()
+
+	endpoint := srv.URL + "/graphql"
+	get := endpoint + "?" + url.Values{
+		"query":     {`query ($n: Long!) { block(number: $n) { number } }`},
+		"variables": {`{"n": 2}`},
+	}.Encode()
+
+	tests := []struct {
+		mkokod, url, contentType, body string
+		number                         float64
+	}{
+		{"POST", endpoint, "application/json", `{"query": "query ($n: Long!) { block(number: $n) { number } }", "variables": {"n": 2}}`, 2},
+		{"POST", endpoint, "application/json", `{"query": "query A { block(number: 1) { number } } query B { block { number } }", "operationName": "B"}`, 3},
+		{"POST", endpoint, "application/graphql", `{ block(number: 1) { number } }`, 1},
+		{"GET", get, "", "", 2},
+	}
+	for i, tt := range tests {
+		req, _ := http.NewRequest(tt.mkokod, tt.url, strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("content-type", tt.contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("test %d: request failed: %v", i, err)
+		}
+		var result struct {
+			Data struct {
+				Block struct {
+					Number float64 `json:"number"`
+				} `json:"block"`
+			} `json:"data"`
+			Errors []*QueryError `json:"errors"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("test %d: failed to decode response: %v", i, err)
+		}
+		if resp.StatusCode != http.StatusOK || len(result.Errors) != 0 {
+			t.Errorf("test %d: query failed: status %d, errors %v", i, resp.StatusCode, result.Errors)
+		}
+		if result.Data.Block.Number != tt.number {
+			t.Errorf("test %d: block number mismatch: have %v, want %v", i, result.Data.Block.Number, tt.number)
+		}
+	}
+}
+
+func TestServerBadRequests(t *testing.T) {
+	srv := httptest.NewServer(NewServer(newTestBackend(t), nil))
+	defer srv.Close()
+
+	endpoint := srv.URL + "/graphql"
+	tests := []struct {
+		mkokod, url, body string
+		status            int
+	}{
+		{"PUT", endpoint, `{"query": "{ block { number } }"}`, http.StatusMkokodNotAllowed},
+		{"POST", endpoint, `{"query": `, http.StatusBadRequest},
+		{"POST", endpoint, `{"variables": {}}`, http.StatusBadRequest},
+		{"GET", endpoint + "?query=%7B%7D&variables=x", "", http.StatusBadRequest},
+		{"POST", endpoint, `{"query": "` + strings.Repeat(" ", maxRequestContentLength) + `"}`, http.StatusRequestEntityTooLarge},
+		{"POST", srv.URL + "/", `{"query": "{ block { number } }"}`, http.StatusNotFound},
+	}
+	for i, tt := range tests {
+		req, _ := http.NewRequest(tt.mkokod, tt.url, strings.NewReader(tt.body))
+		req.Header.Set("content-type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("test %d: request failed: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+	}
+}
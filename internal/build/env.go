@@ -20,7 +20,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -38,19 +40,28 @@ type Environment struct {
 	Name                string // name of the environment
 	Repo                string // name of GitHub repo
 	Commit, Branch, Tag string // Git info
+	Date                string // Commit timestamp (RFC3339), used as the reproducible build date
+	Dirty               bool   // Whether the working tree has uncommitted changes
 	Buildnum            string
 	IsPullRequest       bool
 	IsCronJob           bool
 }
 
 func (env Environment) String() string {
-	return fmt.Sprintf("%s env (commit:%s branch:%s tag:%s buildnum:%s pr:%t)",
-		env.Name, env.Commit, env.Branch, env.Tag, env.Buildnum, env.IsPullRequest)
+	return fmt.Sprintf("%s env (commit:%s branch:%s tag:%s date:%s dirty:%t buildnum:%s pr:%t)",
+		env.Name, env.Commit, env.Branch, env.Tag, env.Date, env.Dirty, env.Buildnum, env.IsPullRequest)
 }
 
 // Env returns metadata about the current CI environment, falling back to LocalEnv
 // if not running on CI.
 func Env() Environment {
+	env := ciEnv()
+	env.Date = buildDate(env.Commit)
+	return env
+}
+
+// ciEnv returns metadata about the CI environment, if any.
+func ciEnv() Environment {
 	switch {
 	case os.Getenv("CI") == "true" && os.Getenv("TRAVIS") == "true":
 		return Environment{
@@ -97,12 +108,34 @@ func LocalEnv() Environment {
 			env.Branch = strings.TrimLeft(head, "refs/heads/")
 		}
 	}
-	if info, err := os.Stat(".git/objects"); err == nil && info.IsDir() && env.Tag == "" {
-		env.Tag = firstLine(RunGit("tag", "-l", "--points-at", "HEAD"))
+	if info, err := os.Stat(".git/objects"); err == nil && info.IsDir() {
+		if env.Tag == "" {
+			env.Tag = firstLine(RunGit("tag", "-l", "--points-at", "HEAD"))
+		}
+		env.Dirty = RunGit("status", "--porcelain", "--untracked-files=no") != ""
 	}
 	return env
 }
 
+// buildDate returns the timestamp to embed as the build date. To keep builds
+// reproducible, the wall clock is never used: SOURCE_DATE_EPOCH takes priority,
+// falling back to the commit timestamp of the built revision.
+func buildDate(commit string) string {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		if secs, err := strconv.ParseInt(epoch, 10, 64); err == nil {
+			return time.Unix(secs, 0).UTC().Format(time.RFC3339)
+		}
+	}
+	if info, err := os.Stat(".git/objects"); err != nil || !info.IsDir() || commit == "" {
+		return ""
+	}
+	secs, err := strconv.ParseInt(RunGit("show", "-s", "--format=%ct", commit), 10, 64)
+	if err != nil {
+		return ""
+	}
+	return time.Unix(secs, 0).UTC().Format(time.RFC3339)
+}
+
 func firstLine(s string) string {
 	return strings.Split(s, "\n")[0]
 }
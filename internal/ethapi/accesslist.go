@@ -0,0 +1,124 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kokapi
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// AccessTuple is an account touched by a call, along with its storage slots
+// read or written.
+type AccessTuple struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+// AccessListResult is the outcome of an access list generation.
+type AccessListResult struct {
+	AccessList []AccessTuple `json:"accessList"`
+	GasUsed    *hexutil.Big  `json:"gasUsed"`
+	Failed     bool          `json:"failed"`
+}
+
+// accessListTracer is an EVM tracer collecting the accounts and storage slots
+// accessed during execution.
+type accessListTracer struct {
+	list map[common.Address]map[common.Hash]struct{}
+}
+
+func newAccessListTracer() *accessListTracer {
+	return &accessListTracer{list: make(map[common.Address]map[common.Hash]struct{})}
+}
+
+// touch marks an account as accessed.
+func (t *accessListTracer) touch(addr common.Address) map[common.Hash]struct{} {
+	slots, ok := t.list[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		t.list[addr] = slots
+	}
+	return slots
+}
+
+// CaptureState implements vm.Tracer, recording the account executing and any
+// account or storage slot referenced by the current opcode.
+func (t *accessListTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	slots := t.touch(contract.Address())
+
+	size := len(stack.Data())
+	switch op {
+	case vm.SLOAD, vm.SSTORE:
+		if size >= 1 {
+			slots[common.BigToHash(stack.Back(0))] = struct{}{}
+		}
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.SELFDESTRUCT:
+		if size >= 1 {
+			t.touch(common.BigToAddress(stack.Back(0)))
+		}
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		if size >= 2 {
+			t.touch(common.BigToAddress(stack.Back(1)))
+		}
+	}
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *accessListTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// accessList returns the collected accesses, sorted by address and slot.
+func (t *accessListTracer) accessList() []AccessTuple {
+	list := make([]AccessTuple, 0, len(t.list))
+	for addr, slots := range t.list {
+		keys := make([]common.Hash, 0, len(slots))
+		for key := range slots {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+		list = append(list, AccessTuple{Address: addr, StorageKeys: keys})
+	}
+	sort.Slice(list, func(i, j int) bool { return bytes.Compare(list[i].Address[:], list[j].Address[:]) < 0 })
+	return list
+}
+
+// CreateAccessList executes the given call on the state of the given block and
+// returns the accounts and storage slots it accessed, including the sender and
+// the recipient. The call is not committed to the chain.
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (*AccessListResult, error) {
+	tracer := newAccessListTracer()
+	if args.From != (common.Address{}) {
+		tracer.touch(args.From)
+	}
+	if args.To != nil {
+		tracer.touch(*args.To)
+	}
+	_, gas, failed, err := s.doCall(ctx, args, blockNr, vm.Config{Debug: true, Tracer: tracer})
+	if err != nil {
+		return nil, err
+	}
+	return &AccessListResult{AccessList: tracer.accessList(), GasUsed: (*hexutil.Big)(gas), Failed: failed}, nil
+}
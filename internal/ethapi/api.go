@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/kokprojects/go-kok/accounts"
+	"github.com/kokprojects/go-kok/accounts/abi"
 	"github.com/kokprojects/go-kok/accounts/keystore"
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/common/hexutil"
@@ -38,6 +39,8 @@ import (
 	"github.com/kokprojects/go-kok/params"
 	"github.com/kokprojects/go-kok/rlp"
 	"github.com/kokprojects/go-kok/rpc"
+	"github.com/kokprojects/go-kok/tracing"
+	"github.com/kokprojects/go-kok/trie"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
@@ -63,6 +66,37 @@ func (s *PublickokereumAPI) GasPrice(ctx context.Context) (*big.Int, error) {
 	return s.b.SuggestPrice(ctx)
 }
 
+// FeeHistoryResult is the fee history of a range of blocks, oldest first.
+type FeeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"` // Gas prices at the requested percentiles of the gas used in each block
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the gas used ratio of up to blockCount blocks ending at
+// lastBlock, along with the gas prices paid at the given percentiles of the gas
+// used by the transactions of each block.
+func (s *PublickokereumAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*FeeHistoryResult, error) {
+	oldest, reward, ratios, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	result := &FeeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: ratios,
+	}
+	if reward != nil {
+		result.Reward = make([][]*hexutil.Big, len(reward))
+		for i, prices := range reward {
+			result.Reward[i] = make([]*hexutil.Big, len(prices))
+			for j, price := range prices {
+				result.Reward[i][j] = (*hexutil.Big)(price)
+			}
+		}
+	}
+	return result, nil
+}
+
 // ProtocolVersion returns the current kokereum protocol version this node supports
 func (s *PublickokereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -114,7 +148,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -122,7 +156,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
@@ -170,9 +204,47 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 		}
 		content["queued"][account.Hex()] = dump
 	}
+	// Append the replacement policy if the pool has one
+	if bump, bumps, err := s.b.TxPoolPriceBumps(); err == nil {
+		dump := map[string]string{"default": fmt.Sprintf("%d%%", bump)}
+		for account, bump := range bumps {
+			dump[account.Hex()] = fmt.Sprintf("%d%%", bump)
+		}
+		content["policy"] = map[string]map[string]string{"priceBump": dump}
+	}
 	return content
 }
 
+// PrivateTxPoolAPI offers an API to configure the transaction pool. It's only
+// exposed on the private interfaces.
+type PrivateTxPoolAPI struct {
+	b Backend
+}
+
+// NewPrivateTxPoolAPI creates a new tx pool service to configure the transaction pool.
+func NewPrivateTxPoolAPI(b Backend) *PrivateTxPoolAPI {
+	return &PrivateTxPoolAPI{b}
+}
+
+// SetGasPriceBump sets the minimum gas price increase percentage required to
+// replace a pooled transaction with one of the same nonce. If an account is
+// given, the bump only applies to its transactions.
+func (s *PrivateTxPoolAPI) SetGasPriceBump(percent uint64, account *common.Address) (bool, error) {
+	if err := s.b.SetTxPoolPriceBump(account, percent); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ResetGasPriceBump removes the replacement price bump set for an account, so
+// its transactions are subject to the pool wide one again.
+func (s *PrivateTxPoolAPI) ResetGasPriceBump(account common.Address) (bool, error) {
+	if err := s.b.ResetTxPoolPriceBump(account); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only mkokods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -366,7 +438,7 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 	if err != nil {
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, signed)
+	return submitTransaction(ctx, s.b, signed, nil, nil)
 }
 
 // signHash is a helper function that calculates a hash for the given message that can be
@@ -452,17 +524,35 @@ func NewPublicBlockChainAPI(b Backend) *PublicBlockChainAPI {
 	return &PublicBlockChainAPI{b}
 }
 
+// ChainId returns the chain ID transactions have to be signed with to be replay
+// protected, failing if the chain head is not yet past the EIP155 fork block.
+func (s *PublicBlockChainAPI) ChainId() (*hexutil.Big, error) {
+	config := s.b.ChainConfig()
+	if head := s.b.CurrentBlock(); !config.IsEIP155(head.Number()) {
+		return nil, fmt.Errorf("chain not synced beyond EIP155 replay protection fork block %v", config.EIP155Block)
+	}
+	return (*hexutil.Big)(config.ChainId), nil
+}
+
 // BlockNumber returns the block number of the chain head.
 func (s *PublicBlockChainAPI) BlockNumber() *big.Int {
 	header, _ := s.b.HeaderByNumber(context.Background(), rpc.LatestBlockNumber) // latest header should always be available
 	return header.Number
 }
 
+// GetChainConfig returns the chain configuration of the node, with the fee split
+// of contract calls filled in even if the genesis left it at its default.
+func (s *PublicBlockChainAPI) GetChainConfig() *params.ChainConfig {
+	config := *s.b.ChainConfig()
+	config.Fee = &params.FeeConfig{DeveloperShare: config.DeveloperShare()}
+	return &config
+}
+
 // GetBalance returns the amount of wei for the given address in the state of the
 // given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
 // block numbers are also allowed.
 func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*big.Int, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	state, _, err := stateAndHeaderByNumber(ctx, s.b, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -470,6 +560,20 @@ func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Add
 	return b, state.Error()
 }
 
+// GetFrozenAccounts returns the accounts barred from sending transactions by the
+// chain configuration as of the given block number.
+func (s *PublicBlockChainAPI) GetFrozenAccounts(ctx context.Context, blockNr rpc.BlockNumber) ([]common.Address, error) {
+	header, err := s.b.HeaderByNumber(ctx, blockNr)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	frozen := s.b.ChainConfig().FrozenAccounts(header.Number)
+	if frozen == nil {
+		frozen = []common.Address{}
+	}
+	return frozen, nil
+}
+
 // GetBlockByNumber returns the requested block. When blockNr is -1 the chain head is returned. When fullTx is true all
 // transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
@@ -549,7 +653,7 @@ func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, bloc
 
 // GetCode returns the code stored at the given address in the state for the given block number.
 func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	state, _, err := stateAndHeaderByNumber(ctx, s.b, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -558,10 +662,14 @@ func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Addres
 }
 
 func (s *PublicBlockChainAPI) GetDetail(ctx context.Context, contractAddress common.Address, blockNr rpc.BlockNumber) (map[string]string, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
+	keys := []common.Hash{core.HashTypeString("type"), core.HashTypeString("coinbase"), core.HashTypeString("template")}
+	if err := s.b.PrefetchStorage(ctx, header, contractAddress, keys); err != nil {
+		return nil, err
+	}
 	addressType := core.GetAddressType(state.GetState(contractAddress, core.HashTypeString("type")))
 	txMap := map[string]string{
 		"type":       addressType,
@@ -578,15 +686,18 @@ func (s *PublicBlockChainAPI) GetDetail(ctx context.Context, contractAddress com
 		txMap["coinbase"] = core.CommonHash2Address(coinbase).String()
 	}
 
-	return txMap, nil
+	return txMap, state.Error()
 }
 
 //Obtain endorsement transaction information
 func (s *PublicBlockChainAPI) GetEndorse(ctx context.Context, ddress common.Address, txhash common.Hash, blockNr rpc.BlockNumber) (map[string]interface{}, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
+	if err := s.b.PrefetchStorage(ctx, header, ddress, []common.Hash{core.HashTypeString("endorselength"), txhash}); err != nil {
+		return nil, err
+	}
 
 	data := make(map[string]interface{}, 3)
 
@@ -606,6 +717,9 @@ func (s *PublicBlockChainAPI) GetEndorse(ctx context.Context, ddress common.Addr
 		} else {
 			one, _ := new(big.Int).SetString("1", 10)
 			st := tag.Big().String() + "length"
+			if err := s.b.PrefetchStorage(ctx, header, ddress, []common.Hash{core.HashTypeString(st)}); err != nil {
+				return nil, err
+			}
 			taglength := state.GetState(ddress, core.HashTypeString(st)).Big()
 			if taglength.Cmp(one) == -1 {
 				data["successful"] = false
@@ -619,6 +733,9 @@ func (s *PublicBlockChainAPI) GetEndorse(ctx context.Context, ddress common.Addr
 				tagSorce := tag.Big()
 				index := new(big.Int).SetInt64(lengthTAG)
 				tagSorce.Add(tagSorce, index)
+				if err := s.b.PrefetchStorage(ctx, header, ddress, storageRange(tagSorce, lengthTAG)); err != nil {
+					return nil, err
+				}
 				for i := lengthTAG; i > 0; i-- {
 					txData := state.GetState(ddress, common.BigToHash(tagSorce))
 					st = append(st, txData.String())
@@ -631,15 +748,18 @@ func (s *PublicBlockChainAPI) GetEndorse(ctx context.Context, ddress common.Addr
 
 	}
 
-	return data, nil
+	return data, state.Error()
 }
 
 //Obtain source chain transaction information
 func (s *PublicBlockChainAPI) GetSourceTx(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (map[string]interface{}, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
+	if err := s.b.PrefetchStorage(ctx, header, address, []common.Hash{core.HashTypeString("txsourcelength"), core.HashTypeString("txsourcetag")}); err != nil {
+		return nil, err
+	}
 
 	data := make(map[string]interface{}, 3)
 
@@ -657,6 +777,9 @@ func (s *PublicBlockChainAPI) GetSourceTx(ctx context.Context, address common.Ad
 
 		sourceTxTAG := state.GetState(address, core.HashTypeString("txsourcetag")).Big()
 		sourceTxTAG.Add(sourceTxTAG, sourceTxLength)
+		if err := s.b.PrefetchStorage(ctx, header, address, storageRange(sourceTxTAG, lengthTx)); err != nil {
+			return nil, err
+		}
 		strs := make([]string, 0)
 		for j := lengthTx; j > 0; j-- {
 			Data := state.GetState(address, common.BigToHash(sourceTxTAG))
@@ -671,14 +794,27 @@ func (s *PublicBlockChainAPI) GetSourceTx(ctx context.Context, address common.Ad
 
 	}
 
-	return data, nil
+	return data, state.Error()
+}
+
+// storageRange returns the count storage slots ending with the given one, the
+// layout of the endorsement and source transaction records.
+func storageRange(last *big.Int, count int64) []common.Hash {
+	if count <= 0 {
+		return nil
+	}
+	keys := make([]common.Hash, 0, count)
+	for i := int64(0); i < count; i++ {
+		keys = append(keys, common.BigToHash(new(big.Int).Sub(last, big.NewInt(i))))
+	}
+	return keys
 }
 
 // GetStorageAt returns the storage from the state at the given address, key and
 // block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
 // numbers are also allowed.
 func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	state, _, err := stateAndHeaderByNumber(ctx, s.b, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -699,7 +835,7 @@ type CallArgs struct {
 func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, *big.Int, bool, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
-	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	state, header, err := stateAndHeaderByNumber(ctx, s.b, blockNr)
 	if state == nil || err != nil {
 		return nil, common.Big0, false, err
 	}
@@ -720,6 +856,9 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if gasPrice.Sign() == 0 {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
+	if err := ChargeCallCost(ctx, gas.Uint64()); err != nil {
+		return nil, common.Big0, false, err
+	}
 
 	// Create new call message
 	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
@@ -736,9 +875,17 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// this makes sure resources are cleaned up.
 	defer func() { cancel() }()
 
+	// Trace the execution, the call frames and state loads hang off its span
+	traceCtx, span := tracing.Start(ctx, "evm.ApplyMessage")
+	if span != nil {
+		span.SetAttribute("gas.limit", gas.Uint64())
+		vmCfg.TraceContext = traceCtx
+		state.SetTraceContext(traceCtx)
+	}
 	// Get a new instance of the EVM.
 	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
+		span.End()
 		return nil, common.Big0, false, err
 	}
 	// Wait for the context to be done and cancel the evm. Even if the
@@ -752,6 +899,13 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxBig256)
 	res, gas, failed, err := core.ApplyMessage(evm, msg, gp, nil, nil, 0)
+	if gas != nil {
+		span.SetAttribute("gas.used", gas.Uint64())
+	}
+	span.SetAttribute("failed", failed)
+	span.SetError(err)
+	span.End()
+
 	if err := vmError(); err != nil {
 		return nil, common.Big0, false, err
 	}
@@ -984,8 +1138,8 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 	return result
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 	return newRPCTransaction(tx, common.Hash{}, 0, 0)
 }
 
@@ -1097,12 +1251,158 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx)
+		return NewRPCPendingTransaction(tx)
 	}
 	// Transaction unknown, return as such
 	return nil
 }
 
+// GetDroppedTransaction returns the reason a recently dropped transaction was
+// evicted from the transaction pool, or nil if it is not known to be dropped.
+func (s *PublicTransactionPoolAPI) GetDroppedTransaction(ctx context.Context, hash common.Hash) *core.DroppedTx {
+	return s.b.GetDroppedTransaction(hash)
+}
+
+// BalanceChange is the change of an account balance made by a transaction.
+type BalanceChange struct {
+	Address common.Address `json:"address"`
+	Before  *hexutil.Big   `json:"before"`
+	After   *hexutil.Big   `json:"after"`
+	Delta   *hexutil.Big   `json:"delta"`
+}
+
+// GetBalanceChanges returns the native balance changes made by the transaction
+// with the given hash, including fees and internal transfers. The node needs to
+// run with the balance index enabled.
+func (s *PublicTransactionPoolAPI) GetBalanceChanges(ctx context.Context, hash common.Hash) ([]*BalanceChange, error) {
+	blockHash, number, _ := core.GetTxLookupEntry(s.b.ChainDb(), hash)
+	if blockHash == (common.Hash{}) {
+		return nil, nil
+	}
+	blockChanges := core.GetBlockBalanceChanges(s.b.ChainDb(), blockHash, number)
+	if blockChanges == nil {
+		return nil, fmt.Errorf("balance changes of block %x not indexed", blockHash)
+	}
+	changes := []*BalanceChange{}
+	for _, tx := range blockChanges {
+		if tx.TxHash != hash {
+			continue
+		}
+		for _, change := range tx.Changes {
+			changes = append(changes, &BalanceChange{
+				Address: change.Address,
+				Before:  (*hexutil.Big)(change.Before),
+				After:   (*hexutil.Big)(change.After),
+				Delta:   (*hexutil.Big)(new(big.Int).Sub(change.After, change.Before)),
+			})
+		}
+	}
+	return changes, nil
+}
+
+// InternalTransaction is a value transfer made by a contract during the execution
+// of a transaction.
+type InternalTransaction struct {
+	TxHash  common.Hash    `json:"transactionHash"`
+	TxIndex hexutil.Uint   `json:"transactionIndex"`
+	Type    string         `json:"type"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value"`
+	Depth   hexutil.Uint64 `json:"depth"`
+}
+
+// GetInternalTransactions returns the value transfers made by contracts during
+// the execution of the transaction with the given hash or, if no such transaction
+// exists, of all the transactions of the block with the given hash. The node
+// needs to run with the internal transaction index enabled.
+func (s *PublicTransactionPoolAPI) GetInternalTransactions(ctx context.Context, hash common.Hash) ([]*InternalTransaction, error) {
+	if blockHash, _, index := core.GetTxLookupEntry(s.b.ChainDb(), hash); blockHash != (common.Hash{}) {
+		block, err := s.b.GetBlock(ctx, blockHash)
+		if block == nil || err != nil {
+			return nil, err
+		}
+		return s.internalTransactions(block, int(index))
+	}
+	block, err := s.b.GetBlock(ctx, hash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return s.internalTransactions(block, -1)
+}
+
+// GetInternalTransactionsByNumber returns the value transfers made by contracts
+// during the execution of the block with the given number.
+func (s *PublicTransactionPoolAPI) GetInternalTransactionsByNumber(ctx context.Context, blockNr rpc.BlockNumber) ([]*InternalTransaction, error) {
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return s.internalTransactions(block, -1)
+}
+
+// internalTransactions retrieves the internal transactions of a block, limited
+// to the transaction at the given index if not negative.
+func (s *PublicTransactionPoolAPI) internalTransactions(block *types.Block, index int) ([]*InternalTransaction, error) {
+	internals := core.GetBlockInternalTxs(s.b.ChainDb(), block.Hash(), block.NumberU64())
+	if internals == nil && len(block.Transactions()) > 0 {
+		return nil, fmt.Errorf("internal transactions of block %x not indexed", block.Hash())
+	}
+	txs := []*InternalTransaction{}
+	for i, tx := range block.Transactions() {
+		if (index >= 0 && i != index) || i >= len(internals) {
+			continue
+		}
+		for _, internal := range internals[i] {
+			txs = append(txs, &InternalTransaction{
+				TxHash:  tx.Hash(),
+				TxIndex: hexutil.Uint(i),
+				Type:    internal.Type,
+				From:    internal.From,
+				To:      internal.To,
+				Value:   (*hexutil.Big)(internal.Value),
+				Depth:   hexutil.Uint64(internal.Depth),
+			})
+		}
+	}
+	return txs, nil
+}
+
+// ContractCreator is the origin of a deployed contract.
+type ContractCreator struct {
+	Address     common.Address `json:"address"`
+	Creator     common.Address `json:"creator"`
+	Type        string         `json:"type"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	TxIndex     hexutil.Uint64 `json:"transactionIndex"`
+}
+
+// GetContractCreator returns the transaction which deployed the contract at the
+// given address, along with the account deploying it: the transaction sender for
+// top level creations, or the creating contract for nested ones. Nil is returned
+// for accounts not deployed by a canonical transaction.
+func (s *PublicTransactionPoolAPI) GetContractCreator(ctx context.Context, address common.Address) (*ContractCreator, error) {
+	creation := core.GetContractCreation(s.b.ChainDb(), address)
+	if creation == nil {
+		return nil, nil
+	}
+	blockHash, number, index := core.GetTxLookupEntry(s.b.ChainDb(), creation.TxHash)
+	if blockHash == (common.Hash{}) {
+		return nil, nil
+	}
+	return &ContractCreator{
+		Address:     creation.Address,
+		Creator:     creation.Creator,
+		Type:        creation.Type,
+		TxHash:      creation.TxHash,
+		BlockHash:   blockHash,
+		BlockNumber: hexutil.Uint64(number),
+		TxIndex:     hexutil.Uint64(index),
+	}, nil
+}
+
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
 func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	var tx *types.Transaction
@@ -1118,8 +1418,59 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 	return rlp.EncodeToBytes(tx)
 }
 
+// ReceiptProof is a Merkle proof of the inclusion of a receipt in a block, which
+// can be verified against the hash of the RLP encoded header alone.
+type ReceiptProof struct {
+	Header       hexutil.Bytes   `json:"header"` // RLP encoded header of the including block
+	ReceiptsRoot common.Hash     `json:"receiptsRoot"`
+	Key          hexutil.Bytes   `json:"key"`   // Receipt trie key, the RLP encoded transaction index
+	Nodes        []hexutil.Bytes `json:"nodes"` // RLP encoded trie nodes on the path from the root to the receipt
+}
+
+// proofList collects the trie nodes of a Merkle proof in path order.
+type proofList []hexutil.Bytes
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, common.CopyBytes(value))
+	return nil
+}
+
+// newReceiptProof creates a Merkle proof of the inclusion of the receipt at the
+// given index in the receipt trie of a block.
+func newReceiptProof(header *types.Header, receipts types.Receipts, index uint64) (*ReceiptProof, error) {
+	if index >= uint64(len(receipts)) {
+		return nil, fmt.Errorf("receipt index %d out of range, block has %d receipts", index, len(receipts))
+	}
+	tr := new(trie.Trie)
+	for i := range receipts {
+		key, _ := rlp.EncodeToBytes(uint(i))
+		tr.Update(key, receipts.GetRlp(i))
+	}
+	if root := tr.Hash(); root != header.ReceiptHash {
+		return nil, fmt.Errorf("receipt trie root mismatch: have %x, want %x", root, header.ReceiptHash)
+	}
+	key, _ := rlp.EncodeToBytes(uint(index))
+
+	var nodes proofList
+	if err := tr.Prove(key, 0, &nodes); err != nil {
+		return nil, err
+	}
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	return &ReceiptProof{
+		Header:       enc,
+		ReceiptsRoot: header.ReceiptHash,
+		Key:          key,
+		Nodes:        nodes,
+	}, nil
+}
+
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
-func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[string]interface{}, error) {
+// If withProof is set, a Merkle proof of the receipt's inclusion in the block is
+// attached, allowing it to be verified without trusting the node.
+func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash, withProof *bool) (map[string]interface{}, error) {
 	tx, blockHash, blockNumber, index := core.GetTransaction(s.b.ChainDb(), hash)
 	if tx == nil {
 		return nil, nil
@@ -1143,6 +1494,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 		"gasUsed":           (*hexutil.Big)(receipt.GasUsed),
 		"gasDeveloper":      (*hexutil.Big)(receipt.GasDeveloper).ToInt(),
 		"gasMiner":          (*hexutil.Big)(receipt.GasMiner).ToInt(),
+		"gasTreasury":       (*hexutil.Big)(receipt.GasTreasury),
 		"cumulativeGasUsed": (*hexutil.Big)(receipt.CumulativeGasUsed),
 		"contractAddress":   nil,
 		"templateAddress":   nil,
@@ -1160,6 +1512,14 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 	if receipt.Logs == nil {
 		fields["logs"] = [][]*types.Log{}
 	}
+	// Surface the reason of a failed execution if its return data was recorded
+	if reasons := core.GetBlockRevertReasons(s.b.ChainDb(), blockHash, blockNumber); index < uint64(len(reasons)) && len(reasons[index]) > 0 {
+		if reason, err := abi.UnpackRevert(reasons[index]); err == nil {
+			fields["revertReason"] = reason
+		} else {
+			fields["revertReason"] = hexutil.Bytes(reasons[index])
+		}
+	}
 
 	fields["txType"] = receipt.TxType
 
@@ -1170,6 +1530,17 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[
 	if receipt.TemplateAddress != (common.Address{}) {
 		fields["templateAddress"] = receipt.TemplateAddress
 	}
+	if withProof != nil && *withProof {
+		header := core.Gkokeader(s.b.ChainDb(), blockHash, blockNumber)
+		if header == nil {
+			return nil, fmt.Errorf("block %x not found", blockHash)
+		}
+		proof, err := newReceiptProof(header, core.GetBlockReceipts(s.b.ChainDb(), blockHash, blockNumber), index)
+		if err != nil {
+			return nil, err
+		}
+		fields["proof"] = proof
+	}
 	return fields, nil
 }
 
@@ -1241,12 +1612,37 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 	return types.NewTransaction(args.Type, uint64(*args.Nonce), to, (*big.Int)(args.Value), (*big.Int)(args.Gas), (*big.Int)(args.GasPrice), args.Data)
 }
 
+// TxExpiryArgs represents the point after which a submitted transaction that is
+// still not included is dropped from the transaction pool.
+type TxExpiryArgs struct {
+	Block     *hexutil.Uint64 `json:"block"`     // Last block number the transaction may be included in
+	Timestamp *hexutil.Uint64 `json:"timestamp"` // Unix time after which the transaction is dropped
+}
+
 // submitTransaction is a helper function that submits tx to txPool and logs a message.
-func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+// If private is set, it overrides whkoker the transaction is kept from the network,
+// and if expiry is set, it overrides the default lifetime of the transaction.
+func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction, private *bool, expiry *TxExpiryArgs) (common.Hash, error) {
 	if err := tx.Validate(); err != nil {
 		return common.Hash{}, err
 	}
-	if err := b.SendTx(ctx, tx); err != nil {
+	var err error
+	switch {
+	case expiry != nil:
+		var limit core.TxExpiry
+		if expiry.Block != nil {
+			limit.Block = uint64(*expiry.Block)
+		}
+		if expiry.Timestamp != nil {
+			limit.Time = time.Unix(int64(*expiry.Timestamp), 0)
+		}
+		err = b.SendExpiringTx(ctx, tx, private, limit)
+	case private != nil:
+		err = b.SendPrivateTx(ctx, tx, *private)
+	default:
+		err = b.SendTx(ctx, tx)
+	}
+	if err != nil {
 		return common.Hash{}, err
 	}
 	if tx.To() == nil {
@@ -1297,12 +1693,16 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, signed)
+	return submitTransaction(ctx, s.b, signed, nil, nil)
 }
 
 // SendRawTransaction will add the signed transaction to the transaction pool.
 // The sender is responsible for signing the transaction and using the correct nonce.
-func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+// The optional private flag overrides whkoker the transaction is kept from being
+// propagated to the network until mined locally, defaulting to the node setting.
+// The optional expiry overrides when the pool drops the transaction if it's still
+// not included, defaulting to the lifetime configured for the pool.
+func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes, private *bool, expiry *TxExpiryArgs) (common.Hash, error) {
 	tx := new(types.Transaction)
 	log.Warn("encodeTx:" + encodedTx.String())
 	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
@@ -1310,7 +1710,7 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encod
 		return common.Hash{}, err
 	}
 	log.Warn(tx.String())
-	return submitTransaction(ctx, s.b, tx)
+	return submitTransaction(ctx, s.b, tx, private, expiry)
 }
 
 // Sign calculates an ECDSA signature for:
@@ -1384,7 +1784,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 		}
 		from, _ := types.Sender(signer, tx)
 		if _, err := s.b.AccountManager().Find(accounts.Account{Address: from}); err == nil {
-			transactions = append(transactions, newRPCPendingTransaction(tx))
+			transactions = append(transactions, NewRPCPendingTransaction(tx))
 		}
 	}
 	return transactions, nil
@@ -1480,14 +1880,20 @@ func NewPrivateDebugAPI(b Backend) *PrivateDebugAPI {
 	return &PrivateDebugAPI{b: b}
 }
 
-// ChaindbProperty returns leveldb properties of the chain database.
+// ChaindbProperty returns leveldb properties of the chain database. Besides the
+// native leveldb properties, "kokdb.stats" returns a summary of the level sizes,
+// compaction totals, open files and operation latencies.
 func (api *PrivateDebugAPI) ChaindbProperty(property string) (string, error) {
 	ldb, ok := api.b.ChainDb().(interface {
 		LDB() *leveldb.DB
+		Stats() (string, error)
 	})
 	if !ok {
 		return "", fmt.Errorf("chaindbProperty does not work for memory databases")
 	}
+	if property == "kokdb.stats" {
+		return ldb.Stats()
+	}
 	if property == "" {
 		property = "leveldb.stats"
 	} else if !strings.HasPrefix(property, "leveldb.") {
@@ -1,11 +1,19 @@
 package kokapi
 
 import (
+	"bytes"
+	"encoding/json"
 	"math/big"
 	"testing"
 
+	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/rpc"
+	"github.com/kokprojects/go-kok/trie"
 )
 
 func TestToTransaction(t *testing.T) {
@@ -23,3 +31,107 @@ func TestToTransaction(t *testing.T) {
 		t.Errorf("transaction receiptent nil is expected, but got %x", tx.To())
 	}
 }
+
+func TestReceiptProof(t *testing.T) {
+	receipts := make(types.Receipts, 20)
+	for i := range receipts {
+		receipts[i] = types.NewReceipt(nil, false, big.NewInt(int64(21000*(i+1))))
+		receipts[i].Logs = []*types.Log{}
+	}
+	header := &types.Header{Number: big.NewInt(1), ReceiptHash: types.DeriveSha(receipts)}
+
+	for i := range receipts {
+		proof, err := newReceiptProof(header, receipts, uint64(i))
+		if err != nil {
+			t.Fatalf("receipt %d: failed to create proof: %v", i, err)
+		}
+		db, _ := kokdb.NewMemDatabase()
+		for _, node := range proof.Nodes {
+			db.Put(crypto.Keccak256(node), node)
+		}
+		value, err, _ := trie.VerifyProof(proof.ReceiptsRoot, proof.Key, db)
+		if err != nil {
+			t.Fatalf("receipt %d: failed to verify proof: %v", i, err)
+		}
+		if !bytes.Equal(value, receipts.GetRlp(i)) {
+			t.Errorf("receipt %d: proven value mismatch: have %x, want %x", i, value, receipts.GetRlp(i))
+		}
+		if hash := crypto.Keccak256Hash(proof.Header); hash != header.Hash() {
+			t.Errorf("receipt %d: header hash mismatch: have %x, want %x", i, hash, header.Hash())
+		}
+	}
+	if _, err := newReceiptProof(header, receipts, uint64(len(receipts))); err == nil {
+		t.Errorf("out of range receipt proven")
+	}
+	header.ReceiptHash = common.Hash{}
+	if _, err := newReceiptProof(header, receipts, 0); err == nil {
+		t.Errorf("receipt proven against mismatching root")
+	}
+}
+
+func TestStateOverride(t *testing.T) {
+	var overrides StateOverride
+	input := `{"0x0000000000000000000000000000000000000001": {"balance": "0x64", "nonce": "0x5", "code": "0x6001", "storage": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"}}}`
+	if err := json.Unmarshal([]byte(input), &overrides); err != nil {
+		t.Fatalf("failed to decode overrides: %v", err)
+	}
+	db, _ := kokdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	overrides.apply(statedb)
+
+	addr := common.BytesToAddress([]byte{0x01})
+	if balance := statedb.GetBalance(addr); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", balance, 100)
+	}
+	if nonce := statedb.GetNonce(addr); nonce != 5 {
+		t.Errorf("nonce mismatch: have %d, want %d", nonce, 5)
+	}
+	if code := statedb.GetCode(addr); !bytes.Equal(code, []byte{0x60, 0x01}) {
+		t.Errorf("code mismatch: have %x, want %x", code, []byte{0x60, 0x01})
+	}
+	if value := statedb.GetState(addr, common.BytesToHash([]byte{0x01})); value != common.BytesToHash([]byte{0x02}) {
+		t.Errorf("storage mismatch: have %x, want %x", value, common.BytesToHash([]byte{0x02}))
+	}
+}
+
+func TestRESTBlockNumber(t *testing.T) {
+	tests := []struct {
+		input string
+		want  rpc.BlockNumber
+		fail  bool
+	}{
+		{input: "12", want: 12},
+		{input: "0x10", want: 16},
+		{input: "latest", want: rpc.LatestBlockNumber},
+		{input: "pending", want: rpc.PendingBlockNumber},
+		{input: "-1", fail: true},
+		{input: "head", fail: true},
+	}
+	for _, tt := range tests {
+		number, err := parseRESTBlockNumber(tt.input)
+		if (err != nil) != tt.fail {
+			t.Errorf("%q: error mismatch: have %v, want failure %v", tt.input, err, tt.fail)
+			continue
+		}
+		if err == nil && number != tt.want {
+			t.Errorf("%q: number mismatch: have %d, want %d", tt.input, number, tt.want)
+		}
+	}
+}
+
+func TestRESTEncodeCSV(t *testing.T) {
+	result := map[string]interface{}{
+		"number":       (*hexutil.Big)(big.NewInt(16)),
+		"balance":      "1000",
+		"transactions": []common.Hash{{0x01}},
+	}
+	blob, err := encodeCSV(result)
+	if err != nil {
+		t.Fatalf("failed to encode result: %v", err)
+	}
+	want := "balance,number,transactions\n" +
+		"1000,0x10,\"[\"\"0x0100000000000000000000000000000000000000000000000000000000000000\"\"]\"\n"
+	if string(blob) != want {
+		t.Errorf("csv mismatch: have %q, want %q", blob, want)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/kokprojects/go-kok/event"
 	"github.com/kokprojects/go-kok/params"
 	"github.com/kokprojects/go-kok/rpc"
+	"github.com/kokprojects/go-kok/tracing"
 )
 
 // Backend interface provides the common API services (that are provided by
@@ -41,6 +42,7 @@ type Backend interface {
 	Downloader() *downloader.Downloader
 	ProtocolVersion() int
 	SuggestPrice(ctx context.Context) (*big.Int, error)
+	FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []float64, error)
 	ChainDb() kokdb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
@@ -49,6 +51,7 @@ type Backend interface {
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
 	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
+	PrefetchStorage(ctx context.Context, header *types.Header, address common.Address, keys []common.Hash) error
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 	GetTd(blockHash common.Hash) *big.Int
@@ -58,12 +61,19 @@ type Backend interface {
 
 	// TxPool API
 	SendTx(ctx context.Context, signedTx *types.Transaction) error
+	SendPrivateTx(ctx context.Context, signedTx *types.Transaction, private bool) error
+	SendExpiringTx(ctx context.Context, signedTx *types.Transaction, private *bool, expiry core.TxExpiry) error
 	GetPoolTransactions() (types.Transactions, error)
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
 	SubscribeTxPreEvent(chan<- core.TxPreEvent) event.Subscription
+	GetDroppedTransaction(txHash common.Hash) *core.DroppedTx
+	DiagnoseTransaction(txHash common.Hash) *core.TxDiagnosis
+	TxPoolPriceBumps() (uint64, map[common.Address]uint64, error)
+	SetTxPoolPriceBump(account *common.Address, bump uint64) error
+	ResetTxPoolPriceBump(account common.Address) error
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
@@ -92,6 +102,10 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicTxPoolAPI(apiBackend),
 			Public:    true,
+		}, {
+			Namespace: "txpool",
+			Version:   "1.0",
+			Service:   NewPrivateTxPoolAPI(apiBackend),
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -114,3 +128,19 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 		},
 	}
 }
+
+// stateAndHeaderByNumber retrieves the state and header of a block from the
+// backend, recording the time spent on it in the trace of the request. The
+// database loads of the returned state are recorded in the trace too.
+func stateAndHeaderByNumber(ctx context.Context, b Backend, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	spanCtx, span := tracing.Start(ctx, "backend.StateAndHeaderByNumber")
+	defer span.End()
+	span.SetAttribute("block", blockNr.Int64())
+
+	statedb, header, err := b.StateAndHeaderByNumber(spanCtx, blockNr)
+	span.SetError(err)
+	if statedb != nil && span != nil {
+		statedb.SetTraceContext(ctx)
+	}
+	return statedb, header, err
+}
@@ -0,0 +1,170 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kokapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/rlp"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// maxBundleSize is the maximum number of transactions simulated in one bundle.
+const maxBundleSize = 64
+
+// BundleTxArgs represents a transaction of a simulated bundle. It is either a
+// signed transaction in its RLP encoded form, or the fields of an unsigned call
+// executed without nonce checks.
+type BundleTxArgs struct {
+	CallArgs
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+// BundleTxResult is the outcome of a transaction of a simulated bundle.
+type BundleTxResult struct {
+	TxHash      *common.Hash   `json:"txHash,omitempty"` // Hash of the transaction, if signed
+	From        common.Address `json:"from"`
+	ReturnValue hexutil.Bytes  `json:"returnValue"`
+	GasUsed     *hexutil.Big   `json:"gasUsed"`
+	Failed      bool           `json:"failed"`
+	Error       string         `json:"error,omitempty"` // Reason the transaction could not be applied at all
+	Logs        []*types.Log   `json:"logs"`
+}
+
+// SimulateBundle executes an ordered list of transactions on top of the state of
+// the given block, each seeing the state changes of the ones before it, and
+// returns the results of each. Transactions that cannot be applied (e.g. due to
+// a bad nonce or insufficient funds) are reported with an error and leave the
+// state untouched. Nothing is committed to the chain or the transaction pool.
+func (s *PublicBlockChainAPI) SimulateBundle(ctx context.Context, txs []BundleTxArgs, blockNr rpc.BlockNumber) ([]*BundleTxResult, error) {
+	defer func(start time.Time) {
+		log.Debug("Simulating transaction bundle finished", "runtime", time.Since(start))
+	}(time.Now())
+
+	if len(txs) > maxBundleSize {
+		return nil, fmt.Errorf("bundle too large: have %d transactions, max %d", len(txs), maxBundleSize)
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	var (
+		config = s.b.ChainConfig()
+		signer = types.MakeSigner(config, header.Number)
+		gp     = new(core.GasPool).AddGas(header.GasLimit)
+	)
+
+	results := make([]*BundleTxResult, 0, len(txs))
+	for i, args := range txs {
+		// Assemble the message to execute, from either the signed or the call form
+		var (
+			msg    types.Message
+			result = new(BundleTxResult)
+			hash   common.Hash
+		)
+		if len(args.Raw) > 0 {
+			tx := new(types.Transaction)
+			if err := rlp.DecodeBytes(args.Raw, tx); err != nil {
+				return nil, fmt.Errorf("transaction %d: %v", i, err)
+			}
+			if msg, err = tx.AsMessage(signer); err != nil {
+				return nil, fmt.Errorf("transaction %d: %v", i, err)
+			}
+			hash = tx.Hash()
+			result.TxHash = &hash
+		} else {
+			// Unsigned calls get a placeholder hash to collect their logs by
+			hash = common.BigToHash(big.NewInt(int64(i + 1)))
+
+			from := args.From
+			if from == (common.Address{}) {
+				if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+					if accounts := wallets[0].Accounts(); len(accounts) > 0 {
+						from = accounts[0].Address
+					}
+				}
+			}
+			gas, gasPrice := args.Gas.ToInt(), args.GasPrice.ToInt()
+			if gas.Sign() == 0 {
+				gas = new(big.Int).Set((*big.Int)(gp))
+			}
+			if gasPrice.Sign() == 0 {
+				gasPrice = new(big.Int).SetUint64(defaultGasPrice)
+			}
+			msg = types.NewMessage(from, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+		}
+		result.From = msg.From()
+
+		if err := ChargeCallCost(ctx, msg.Gas().Uint64()); err != nil {
+			return nil, err
+		}
+		evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		// Abort the execution if the request is cancelled meanwhile
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		// Apply the message, rolling back the state if it's not applicable
+		snapshot := state.Snapshot()
+		state.Prepare(hash, header.Hash(), i)
+
+		ret, gas, failed, err := core.ApplyMessage(evm, msg, gp, nil, hash.Bytes(), msg.Type())
+		close(done)
+		if verr := vmError(); verr != nil {
+			return nil, verr
+		}
+		if cerr := ctx.Err(); cerr != nil {
+			return nil, cerr
+		}
+		if err != nil {
+			state.RevertToSnapshot(snapshot)
+			result.GasUsed, result.Error, result.Logs = new(hexutil.Big), err.Error(), []*types.Log{}
+			results = append(results, result)
+			continue
+		}
+		state.Finalise(config.IsEIP158(header.Number))
+
+		result.ReturnValue, result.GasUsed, result.Failed = ret, (*hexutil.Big)(gas), failed
+		result.Logs = state.GetLogs(hash)
+		if result.Logs == nil {
+			result.Logs = []*types.Log{}
+		}
+		if result.TxHash == nil {
+			for _, l := range result.Logs {
+				l.TxHash = common.Hash{}
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
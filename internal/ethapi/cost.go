@@ -0,0 +1,51 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kokapi
+
+import (
+	"context"
+
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// Cost model of the expensive RPC mkokods. On top of the base cost of a single
+// unit charged by the RPC server for every call, these mkokods charge extra units
+// in proportion to the work they do against the per-connection budget of their
+// namespace (see rpc.CostBudget).
+const (
+	CallGasPerCost   = 1000000 // Gas allowance of an EVM call charged as one cost unit
+	LogBlocksPerCost = 100     // Blocks scanned by a log query charged as one cost unit
+	TraceTxsPerCost  = 10      // Transactions replayed by a trace charged as one cost unit
+)
+
+// ChargeCallCost charges the cost of executing an EVM call with the given gas
+// allowance.
+func ChargeCallCost(ctx context.Context, gas uint64) error {
+	return rpc.ChargeCost(ctx, gas/CallGasPerCost)
+}
+
+// ChargeLogsCost charges the cost of scanning the given number of blocks for
+// matching logs.
+func ChargeLogsCost(ctx context.Context, blocks uint64) error {
+	return rpc.ChargeCost(ctx, blocks/LogBlocksPerCost)
+}
+
+// ChargeTraceCost charges the cost of tracing a transaction, which requires the
+// replay of every preceding transaction in its block (i.e. its depth).
+func ChargeTraceCost(ctx context.Context, depth uint64) error {
+	return rpc.ChargeCost(ctx, depth/TraceTxsPerCost)
+}
@@ -0,0 +1,167 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kokapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+const (
+	// maxMulticallSize is the maximum number of calls executed in one batch.
+	maxMulticallSize = 1024
+
+	// maxMulticallGas is the gas allowance of a batched call, also used when
+	// the call doesn't specify one.
+	maxMulticallGas = 50000000
+
+	// multicallTimeout is the maximum time spent executing a batch of calls.
+	multicallTimeout = 5 * time.Second
+)
+
+// AccountOverride is a temporary replacement of the fields of an account, used
+// when executing calls against a modified state.
+type AccountOverride struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   *hexutil.Uint64             `json:"nonce"`
+	Code    *hexutil.Bytes              `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"` // Storage slots to set, others are kept
+}
+
+// StateOverride is the collection of account overrides applied to the state
+// before executing calls.
+type StateOverride map[common.Address]AccountOverride
+
+// apply writes the overrides into the given state.
+func (overrides StateOverride) apply(statedb *state.StateDB) {
+	for addr, account := range overrides {
+		if account.Balance != nil {
+			statedb.SetBalance(addr, account.Balance.ToInt())
+		}
+		if account.Nonce != nil {
+			statedb.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			statedb.SetCode(addr, *account.Code)
+		}
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+}
+
+// MulticallResult is the outcome of a call of a batch.
+type MulticallResult struct {
+	ReturnValue hexutil.Bytes `json:"returnValue"`
+	GasUsed     *hexutil.Big  `json:"gasUsed"`
+	Failed      bool          `json:"failed"`
+	Error       string        `json:"error,omitempty"` // Reason the call could not be executed at all
+}
+
+// Multicall executes a list of calls one after the other against the state of
+// the given block, optionally modified by the state overrides, and returns the
+// results of each. Every call sees the same state, unaffected by the calls
+// before it, and is limited to the gas it specifies, capped at maxMulticallGas.
+func (s *PublicBlockChainAPI) Multicall(ctx context.Context, calls []CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride) ([]*MulticallResult, error) {
+	defer func(start time.Time) {
+		log.Debug("Executing EVM multicall finished", "calls", len(calls), "runtime", time.Since(start))
+	}(time.Now())
+
+	if len(calls) > maxMulticallSize {
+		return nil, fmt.Errorf("too many calls: have %d, max %d", len(calls), maxMulticallSize)
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if overrides != nil {
+		overrides.apply(state)
+	}
+	// Limit the execution time of the whole batch, gas caps notwithstanding
+	ctx, cancel := context.WithTimeout(ctx, multicallTimeout)
+	defer cancel()
+
+	snapshot := state.Snapshot()
+
+	results := make([]*MulticallResult, 0, len(calls))
+	for _, args := range calls {
+		// Set sender address or use a default if none specified
+		from := args.From
+		if from == (common.Address{}) {
+			if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+				if accounts := wallets[0].Accounts(); len(accounts) > 0 {
+					from = accounts[0].Address
+				}
+			}
+		}
+		gas, gasPrice := args.Gas.ToInt(), args.GasPrice.ToInt()
+		if gas.Sign() == 0 || gas.Cmp(big.NewInt(maxMulticallGas)) > 0 {
+			gas = big.NewInt(maxMulticallGas)
+		}
+		if gasPrice.Sign() == 0 {
+			gasPrice = new(big.Int).SetUint64(defaultGasPrice)
+		}
+		if err := ChargeCallCost(ctx, gas.Uint64()); err != nil {
+			return nil, err
+		}
+		msg := types.NewMessage(from, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+
+		evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		// Abort the execution if the request is cancelled or times out meanwhile
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		gp := new(core.GasPool).AddGas(gas)
+		ret, used, failed, err := core.ApplyMessage(evm, msg, gp, nil, nil, 0)
+		close(done)
+		if verr := vmError(); verr != nil {
+			return nil, verr
+		}
+		if cerr := ctx.Err(); cerr != nil {
+			return nil, cerr
+		}
+		// Roll back the changes of the call, so the next one sees the same state
+		state.RevertToSnapshot(snapshot)
+		snapshot = state.Snapshot()
+
+		if err != nil {
+			results = append(results, &MulticallResult{GasUsed: new(hexutil.Big), Error: err.Error()})
+			continue
+		}
+		results = append(results, &MulticallResult{ReturnValue: ret, GasUsed: (*hexutil.Big)(used), Failed: failed})
+	}
+	return results, nil
+}
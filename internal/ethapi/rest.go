@@ -0,0 +1,247 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kokapi
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+const (
+	restReadTimeout  = 10 * time.Second // Deadline for reading a REST request
+	restWriteTimeout = 30 * time.Second // Deadline for serving a REST request
+)
+
+// RESTServer is a read-only HTTP gateway mapping a few resource style URLs onto
+// the JSON-RPC handlers of the node, for integrations unable to speak JSON-RPC:
+//
+//	GET /blocks/{number}               block by number (or latest, pending, earliest)
+//	GET /txs/{hash}                    transaction by hash
+//	GET /accounts/{address}/balance    account balance, at ?block= (default latest)
+//
+// Responses are JSON encoded, or CSV if requested via ?format=csv or an Accept
+// header of text/csv.
+type RESTServer struct {
+	router *httprouter.Router
+	chain  *PublicBlockChainAPI
+	txs    *PublicTransactionPoolAPI
+
+	listener net.Listener
+}
+
+// NewRESTServer creates a REST gateway serving the data of the given backend.
+func NewRESTServer(b Backend) *RESTServer {
+	s := &RESTServer{
+		router: httprouter.New(),
+		chain:  NewPublicBlockChainAPI(b),
+		txs:    NewPublicTransactionPoolAPI(b, new(AddrLocker)),
+	}
+	s.router.GET("/blocks/:number", s.getBlock)
+	s.router.GET("/txs/:hash", s.getTransaction)
+	s.router.GET("/accounts/:address/balance", s.getBalance)
+	return s
+}
+
+// ServeHTTP implements http.Handler, delegating to the underlying router.
+func (s *RESTServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.router.ServeHTTP(w, req)
+}
+
+// Start opens the listener of the gateway on the given endpoint and starts
+// serving requests in the background.
+func (s *RESTServer) Start(endpoint string) error {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:      s,
+		ReadTimeout:  restReadTimeout,
+		WriteTimeout: restWriteTimeout,
+	}
+	go srv.Serve(listener)
+	s.listener = listener
+
+	log.Info("REST gateway opened", "url", fmt.Sprintf("http://%s", listener.Addr()))
+	return nil
+}
+
+// Stop closes the listener of the gateway.
+func (s *RESTServer) Stop() {
+	if s.listener != nil {
+		s.listener.Close()
+		log.Info("REST gateway closed", "url", fmt.Sprintf("http://%s", s.listener.Addr()))
+		s.listener = nil
+	}
+}
+
+// getBlock serves the header fields and transaction hashes of a block.
+func (s *RESTServer) getBlock(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	number, err := parseRESTBlockNumber(params.ByName("number"))
+	if err != nil {
+		restError(w, req, http.StatusBadRequest, err)
+		return
+	}
+	block, err := s.chain.GetBlockByNumber(req.Context(), number, false)
+	if err != nil {
+		restError(w, req, http.StatusInternalServerError, err)
+		return
+	}
+	if block == nil {
+		restError(w, req, http.StatusNotFound, fmt.Errorf("block %s not found", params.ByName("number")))
+		return
+	}
+	restRespond(w, req, block)
+}
+
+// getTransaction serves a transaction, along with its inclusion position if mined.
+func (s *RESTServer) getTransaction(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	var hash common.Hash
+	if err := hash.UnmarshalText([]byte(params.ByName("hash"))); err != nil {
+		restError(w, req, http.StatusBadRequest, fmt.Errorf("invalid transaction hash: %v", err))
+		return
+	}
+	tx := s.txs.GetTransactionByHash(req.Context(), hash)
+	if tx == nil {
+		restError(w, req, http.StatusNotFound, fmt.Errorf("transaction %x not found", hash))
+		return
+	}
+	restRespond(w, req, tx)
+}
+
+// getBalance serves the balance of an account, in wei, as a decimal string.
+func (s *RESTServer) getBalance(w http.ResponseWriter, req *http.Request, params httprouter.Params) {
+	var address common.Address
+	if err := address.UnmarshalText([]byte(params.ByName("address"))); err != nil {
+		restError(w, req, http.StatusBadRequest, fmt.Errorf("invalid address: %v", err))
+		return
+	}
+	number := rpc.LatestBlockNumber
+	if block := req.URL.Query().Get("block"); block != "" {
+		var err error
+		if number, err = parseRESTBlockNumber(block); err != nil {
+			restError(w, req, http.StatusBadRequest, err)
+			return
+		}
+	}
+	balance, err := s.chain.GetBalance(req.Context(), address, number)
+	if err != nil {
+		restError(w, req, http.StatusInternalServerError, err)
+		return
+	}
+	restRespond(w, req, map[string]interface{}{
+		"address": address,
+		"balance": balance.String(),
+	})
+}
+
+// parseRESTBlockNumber parses a block number given either in decimal, in hex
+// or as one of the named blocks of the JSON-RPC API.
+func parseRESTBlockNumber(input string) (rpc.BlockNumber, error) {
+	if number, err := strconv.ParseInt(input, 10, 64); err == nil && number >= 0 {
+		return rpc.BlockNumber(number), nil
+	}
+	var number rpc.BlockNumber
+	if err := number.UnmarshalJSON([]byte(strconv.Quote(input))); err != nil {
+		return 0, fmt.Errorf("invalid block number %q", input)
+	}
+	return number, nil
+}
+
+// restWantsCSV reports whether the client asked for a CSV encoded response.
+func restWantsCSV(req *http.Request) bool {
+	if format := req.URL.Query().Get("format"); format != "" {
+		return format == "csv"
+	}
+	return strings.Contains(req.Header.Get("Accept"), "text/csv")
+}
+
+// restRespond writes the given result in the format requested by the client.
+func restRespond(w http.ResponseWriter, req *http.Request, result interface{}) {
+	if !restWantsCSV(req) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+	blob, err := encodeCSV(result)
+	if err != nil {
+		restError(w, req, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Write(blob)
+}
+
+// restError writes an error response in the format requested by the client.
+func restError(w http.ResponseWriter, req *http.Request, status int, err error) {
+	if restWantsCSV(req) {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// encodeCSV flattens the JSON representation of a result object into a CSV
+// document of a header row with the field names and a row with their values.
+// Nested values are kept in their JSON encoding.
+func encodeCSV(result interface{}) ([]byte, error) {
+	blob, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]string, len(names))
+	for i, name := range names {
+		var str string
+		if err := json.Unmarshal(fields[name], &str); err == nil {
+			values[i] = str
+		} else {
+			values[i] = string(fields[name])
+		}
+	}
+	buf := new(bytes.Buffer)
+	writer := csv.NewWriter(buf)
+	writer.Write(names)
+	writer.Write(values)
+	writer.Flush()
+
+	return buf.Bytes(), writer.Error()
+}
@@ -129,9 +129,9 @@ type dbWrapper struct {
 	db vm.StateDB
 }
 
-// getBalance retrieves an account's balance
+// getBalance retrieves a copy of an account's balance
 func (dw *dbWrapper) getBalance(addr []byte) *big.Int {
-	return dw.db.GetBalance(common.BytesToAddress(addr))
+	return new(big.Int).Set(dw.db.GetBalance(common.BytesToAddress(addr)))
 }
 
 // getNonce retrieves an account's nonce
@@ -144,9 +144,9 @@ func (dw *dbWrapper) getCode(addr []byte) []byte {
 	return dw.db.GetCode(common.BytesToAddress(addr))
 }
 
-// getState retrieves an account's state data for the given hash
-func (dw *dbWrapper) getState(addr []byte, hash common.Hash) common.Hash {
-	return dw.db.GetState(common.BytesToAddress(addr), hash)
+// getState retrieves an account's state data for the given key
+func (dw *dbWrapper) getState(addr []byte, key []byte) []byte {
+	return dw.db.GetState(common.BytesToAddress(addr), common.BytesToHash(key)).Bytes()
 }
 
 // exists returns true iff the account exists
@@ -197,6 +197,35 @@ func (c *contractWrapper) toValue(vm *otto.Otto) otto.Value {
 	return value
 }
 
+// toAddress converts a stack word, address, byte slice or hex string into the
+// 20 byte account address it represents.
+func toAddress(v interface{}) []byte {
+	switch v := v.(type) {
+	case *big.Int:
+		return common.BigToAddress(v).Bytes()
+	case common.Address:
+		return v.Bytes()
+	case []byte:
+		return common.BytesToAddress(v).Bytes()
+	case string:
+		return common.HexToAddress(v).Bytes()
+	}
+	panic(fmt.Sprintf("cannot convert %T to address", v))
+}
+
+// toWord converts a stack word, byte slice or hex string into a 32 byte word.
+func toWord(v interface{}) []byte {
+	switch v := v.(type) {
+	case *big.Int:
+		return common.BigToHash(v).Bytes()
+	case []byte:
+		return common.BytesToHash(v).Bytes()
+	case string:
+		return common.HexToHash(v).Bytes()
+	}
+	panic(fmt.Sprintf("cannot convert %T to word", v))
+}
+
 // JavascriptTracer provides an implementation of Tracer that evaluates a
 // Javascript function for each VM execution step.
 type JavascriptTracer struct {
@@ -212,19 +241,26 @@ type JavascriptTracer struct {
 	dbvalue       otto.Value             // JS view of `db`
 	contract      *contractWrapper       // Wrapper around the contract object
 	contractvalue otto.Value             // JS view of `contract`
+	ctx           map[string]interface{} // Transaction context passed to `result`
 	err           error                  // Error, if one has occurred
 }
 
 // NewJavascriptTracer instantiates a new JavascriptTracer instance.
-// code specifies a Javascript snippet, which must evaluate to an expression
-// returning an object with 'step' and 'result' functions.
+// code specifies either the name of a built-in tracer or a Javascript snippet,
+// which must evaluate to an expression returning an object with 'step' and
+// 'result' functions.
 func NewJavascriptTracer(code string) (*JavascriptTracer, error) {
+	if builtin, ok := builtinTracers[code]; ok {
+		code = builtin
+	}
 	vm := otto.New()
 	vm.Interrupt = make(chan func(), 1)
 
 	// Set up builtins for this environment
 	vm.Set("big", &fakeBig{})
 	vm.Set("toHex", hexutil.Encode)
+	vm.Set("toAddress", toAddress)
+	vm.Set("toWord", toWord)
 
 	jstracer, err := vm.Object("(" + code + ")")
 	if err != nil {
@@ -271,6 +307,7 @@ func NewJavascriptTracer(code string) (*JavascriptTracer, error) {
 		dbvalue:       db.toValue(vm),
 		contract:      contract,
 		contractvalue: contract.toValue(vm),
+		ctx:           make(map[string]interface{}),
 		err:           nil,
 	}, nil
 }
@@ -345,9 +382,31 @@ func (jst *JavascriptTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode,
 	return nil
 }
 
+// CaptureStart records the parameters of the traced message, which are passed
+// to the Javascript 'result' function as its ctx argument.
+func (jst *JavascriptTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	jst.db.db = env.StateDB
+
+	jst.ctx["type"] = "CALL"
+	if create {
+		jst.ctx["type"] = "CREATE"
+	}
+	jst.ctx["from"] = hexutil.Encode(from.Bytes())
+	jst.ctx["to"] = hexutil.Encode(to.Bytes())
+	jst.ctx["input"] = hexutil.Encode(input)
+	jst.ctx["gas"] = gas
+	jst.ctx["gasPrice"] = new(big.Int).Set(env.GasPrice)
+	jst.ctx["value"] = new(big.Int).Set(value)
+}
+
 // CaptureEnd is called after the call finishes
 func (jst *JavascriptTracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
-	//TODO! @Arachnid please figure out of there's anything we can use this mkokod for
+	jst.ctx["output"] = hexutil.Encode(output)
+	jst.ctx["gasUsed"] = gasUsed
+	jst.ctx["time"] = t.String()
+	if err != nil {
+		jst.ctx["error"] = err.Error()
+	}
 	return nil
 }
 
@@ -356,8 +415,11 @@ func (jst *JavascriptTracer) GetResult() (result interface{}, err error) {
 	if jst.err != nil {
 		return nil, jst.err
 	}
-
-	result, err = jst.callSafely("result")
+	ctxvalue, err := jst.vm.ToValue(jst.ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err = jst.callSafely("result", ctxvalue, jst.dbvalue)
 	if err != nil {
 		err = wrapError("result", err)
 	}
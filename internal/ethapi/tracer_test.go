@@ -24,7 +24,10 @@ import (
 	"time"
 
 	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/params"
 )
 
@@ -145,3 +148,95 @@ func TestHaltBetweenSteps(t *testing.T) {
 		t.Errorf("Expected timeout error, got %v", err)
 	}
 }
+
+// runBuiltinTrace executes a call from a plain account into a contract which
+// forwards a 4 byte selector to a second contract reading its storage.
+func runBuiltinTrace(t *testing.T, name string) interface{} {
+	var (
+		from   = common.HexToAddress("0x01ff")
+		outer  = common.HexToAddress("0x0a")
+		inner  = common.HexToAddress("0x0b")
+		db, _  = kokdb.NewMemDatabase()
+		sdb, _ = state.New(common.Hash{}, state.NewDatabase(db))
+	)
+	code := []byte{byte(vm.PUSH32), 0xde, 0xad, 0xbe, 0xef}
+	code = append(code, make([]byte, 28)...)
+	code = append(code, byte(vm.PUSH1), 0, byte(vm.MSTORE))
+	code = append(code, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 4, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0)
+	code = append(code, byte(vm.PUSH20))
+	code = append(code, inner.Bytes()...)
+	code = append(code, byte(vm.PUSH2), 0xff, 0xff, byte(vm.CALL), byte(vm.STOP))
+	sdb.SetCode(outer, code)
+	sdb.SetCode(inner, []byte{byte(vm.PUSH1), 0x01, byte(vm.SLOAD), byte(vm.POP), byte(vm.STOP)})
+	sdb.SetState(inner, common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(42)))
+	sdb.SetBalance(from, big.NewInt(1000))
+	sdb.SetNonce(from, 1) // as incremented by the state transition
+
+	tracer, err := NewJavascriptTracer(name)
+	if err != nil {
+		t.Fatalf("%s: failed to create tracer: %v", name, err)
+	}
+	ctx := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: big.NewInt(1),
+		GasPrice:    new(big.Int),
+	}
+	env := vm.NewEVM(ctx, sdb, params.TestChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	input := []byte{0x12, 0x34, 0x56, 0x78, 0x01}
+
+	tracer.CaptureStart(env, from, outer, false, input, 100000, new(big.Int))
+	_, left, err := env.Call(vm.AccountRef(from), outer, input, 100000, new(big.Int), nil)
+	tracer.CaptureEnd(nil, 100000-left, 0, err)
+
+	ret, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("%s: failed to retrieve result: %v", name, err)
+	}
+	return ret
+}
+
+func TestCallTracer(t *testing.T) {
+	ret := runBuiltinTrace(t, "callTracer").(map[string]interface{})
+	if ret["type"] != "CALL" || ret["to"] != "0x000000000000000000000000000000000000000a" || ret["input"] != "0x1234567801" {
+		t.Fatalf("top level call mismatch: %v", ret)
+	}
+	calls, ok := ret["calls"].([]map[string]interface{})
+	if !ok || len(calls) != 1 {
+		t.Fatalf("inner calls mismatch: %#v", ret["calls"])
+	}
+	call := calls[0]
+	if call["type"] != "CALL" || call["to"] != "0x000000000000000000000000000000000000000b" || call["input"] != "0xdeadbeef" || call["value"] != "0x0" {
+		t.Errorf("inner call mismatch: %v", call)
+	}
+	if _, ok := call["error"]; ok {
+		t.Errorf("inner call failed: %v", call["error"])
+	}
+	if call["gasUsed"] == nil {
+		t.Errorf("inner call gas usage missing")
+	}
+}
+
+func TestPrestateTracer(t *testing.T) {
+	ret := runBuiltinTrace(t, "prestateTracer").(map[string]interface{})
+	if len(ret) != 3 {
+		t.Fatalf("account count mismatch: have %d, want 3", len(ret))
+	}
+	from := ret["0x00000000000000000000000000000000000001ff"].(map[string]interface{})
+	if from["balance"] != "0x3e8" || from["nonce"] != float64(0) {
+		t.Errorf("sender mismatch: %v", from)
+	}
+	inner := ret["0x000000000000000000000000000000000000000b"].(map[string]interface{})
+	storage := inner["storage"].(map[string]interface{})
+	if slot := storage["0x0000000000000000000000000000000000000000000000000000000000000001"]; slot != "0x000000000000000000000000000000000000000000000000000000000000002a" {
+		t.Errorf("storage slot mismatch: have %v", slot)
+	}
+}
+
+func TestFourByteTracer(t *testing.T) {
+	ret := runBuiltinTrace(t, "4byteTracer")
+	want := map[string]interface{}{"0x12345678-1": float64(1), "0xdeadbeef-0": float64(1)}
+	if !reflect.DeepEqual(ret, want) {
+		t.Errorf("selector mismatch: have %v, want %v", ret, want)
+	}
+}
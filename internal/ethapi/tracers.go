@@ -0,0 +1,327 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kokapi
+
+// builtinTracers maps the names accepted by NewJavascriptTracer in place of
+// Javascript code to the sources of the tracers shipped with the node.
+var builtinTracers = map[string]string{
+	"callTracer":     callTracer,
+	"prestateTracer": prestateTracer,
+	"4byteTracer":    fourByteTracer,
+}
+
+// callTracer reconstructs the tree of calls and contract creations made during
+// the execution of a transaction, along with their inputs, outputs and gas.
+const callTracer = `{
+	// callstack is the current recursive call stack of the EVM execution.
+	callstack: [{}],
+
+	// descended tracks whether we've just descended from an outer frame into
+	// an inner call.
+	descended: false,
+
+	step: function(log, db) {
+		// If we've just descended into an inner call, retrieve its true allowance
+		if (this.descended) {
+			if (log.depth >= this.callstack.length) {
+				this.callstack[this.callstack.length - 1].gas = log.gas;
+			}
+			this.descended = false;
+		}
+		// If an inner call is returning, pop it off the call stack
+		if (log.depth == this.callstack.length - 1) {
+			this.exit(log, db);
+		}
+		// Record any failure of the current frame, it's reported on return
+		if (log.err) {
+			this.callstack[this.callstack.length - 1].error = log.err.Error();
+			return;
+		}
+		var op = log.op.toString();
+		switch (op) {
+		case 'CREATE':
+			var inOff = log.stack.peek(1).Int64();
+			var inEnd = inOff + log.stack.peek(2).Int64();
+
+			this.callstack.push({
+				type:    op,
+				from:    toHex(toAddress(log.contract.address())),
+				input:   toHex(log.memory.slice(inOff, inEnd)),
+				gasIn:   log.gas,
+				gasCost: log.gasPrice,
+				value:   '0x' + log.stack.peek(0).Text(16)
+			});
+			this.descended = true;
+			break;
+
+		case 'CALL': case 'CALLCODE': case 'DELEGATECALL': case 'STATICCALL':
+			// DELEGATECALL and STATICCALL don't have a value on the stack
+			var off = (op == 'DELEGATECALL' || op == 'STATICCALL') ? 0 : 1;
+
+			var inOff = log.stack.peek(2 + off).Int64();
+			var inEnd = inOff + log.stack.peek(3 + off).Int64();
+
+			var call = {
+				type:    op,
+				from:    toHex(toAddress(log.contract.address())),
+				to:      toHex(toAddress(log.stack.peek(1))),
+				input:   toHex(log.memory.slice(inOff, inEnd)),
+				gasIn:   log.gas,
+				gasCost: log.gasPrice,
+				outOff:  log.stack.peek(4 + off).Int64(),
+				outLen:  log.stack.peek(5 + off).Int64()
+			};
+			if (off == 1) {
+				call.value = '0x' + log.stack.peek(2).Text(16);
+			}
+			this.callstack.push(call);
+			this.descended = true;
+			break;
+
+		case 'SELFDESTRUCT':
+			var from = toAddress(log.contract.address());
+			this.append({
+				type:  op,
+				from:  toHex(from),
+				to:    toHex(toAddress(log.stack.peek(0))),
+				value: '0x' + db.getBalance(from).Text(16)
+			});
+			break;
+
+		case 'REVERT':
+			this.callstack[this.callstack.length - 1].error = 'execution reverted';
+			break;
+		}
+	},
+
+	// exit pops the innermost call off the stack, fills in its results from
+	// the state of the caller and appends it to the caller's list of calls.
+	exit: function(log, db) {
+		var call = this.callstack.pop();
+		var ret = log.stack.peek(0);
+
+		if (call.type == 'CREATE') {
+			call.gasUsed = call.gasIn - call.gasCost - log.gas;
+			if (ret.Sign() != 0) {
+				call.to = toHex(toAddress(ret));
+				call.output = toHex(db.getCode(toAddress(ret)));
+			} else if (call.error === undefined) {
+				call.error = 'internal failure';
+			}
+		} else {
+			// Calls to plain accounts and precompiles don't report their allowance
+			if (call.gas !== undefined) {
+				call.gasUsed = call.gasIn - call.gasCost + call.gas - log.gas;
+			}
+			if (ret.Sign() != 0) {
+				call.output = toHex(log.memory.slice(call.outOff, call.outOff + call.outLen));
+			} else if (call.error === undefined) {
+				call.error = 'internal failure';
+			}
+			delete call.outOff;
+			delete call.outLen;
+		}
+		delete call.gasIn;
+		delete call.gasCost;
+
+		if (call.gas !== undefined) {
+			call.gas = '0x' + call.gas.toString(16);
+		}
+		if (call.gasUsed !== undefined) {
+			call.gasUsed = '0x' + call.gasUsed.toString(16);
+		}
+		if (call.error !== undefined) {
+			delete call.output;
+		}
+		this.append(call);
+	},
+
+	// append adds a finished call to the calls of the current frame.
+	append: function(call) {
+		var frame = this.callstack[this.callstack.length - 1];
+		if (frame.calls === undefined) {
+			frame.calls = [];
+		}
+		frame.calls.push(call);
+	},
+
+	result: function(ctx, db) {
+		var result = {
+			type:    ctx.type,
+			from:    ctx.from,
+			to:      ctx.to,
+			value:   '0x' + ctx.value.Text(16),
+			gas:     '0x' + ctx.gas.toString(16),
+			gasUsed: '0x' + ctx.gasUsed.toString(16),
+			input:   ctx.input,
+			output:  ctx.output
+		};
+		var frame = this.callstack[0];
+		if (frame.calls !== undefined) {
+			result.calls = frame.calls;
+		}
+		if (frame.error !== undefined) {
+			result.error = frame.error;
+		} else if (ctx.error !== undefined) {
+			result.error = ctx.error;
+		}
+		if (result.error !== undefined) {
+			delete result.output;
+		}
+		return result;
+	}
+}`
+
+// prestateTracer collects the balance, nonce, code and accessed storage of
+// every account touched by a transaction, as they were before its execution.
+const prestateTracer = `{
+	// prestate is the collected state, null until the first step.
+	prestate: null,
+
+	// lookupAccount records the current state of an account if it's not yet
+	// part of the prestate.
+	lookupAccount: function(addr, db) {
+		var acc = toHex(addr);
+		if (this.prestate[acc] === undefined) {
+			this.prestate[acc] = {
+				balance: db.getBalance(addr),
+				nonce:   db.getNonce(addr),
+				code:    toHex(db.getCode(addr)),
+				storage: {}
+			};
+		}
+	},
+
+	// lookupStorage records the current value of a storage slot if it's not
+	// yet part of the prestate.
+	lookupStorage: function(addr, key, db) {
+		var acc = toHex(addr);
+		var idx = toHex(key);
+		if (this.prestate[acc].storage[idx] === undefined) {
+			this.prestate[acc].storage[idx] = toHex(db.getState(addr, key));
+		}
+	},
+
+	step: function(log, db) {
+		// The sender and recipient are only modified by the purchase of gas and
+		// the value transfer by the time the first step executes, undone in result
+		if (this.prestate === null) {
+			this.prestate = {};
+			this.lookupAccount(toAddress(log.contract.caller()), db);
+			this.lookupAccount(toAddress(log.contract.address()), db);
+		}
+		switch (log.op.toString()) {
+		case 'EXTCODECOPY': case 'EXTCODESIZE': case 'BALANCE': case 'SELFDESTRUCT':
+			this.lookupAccount(toAddress(log.stack.peek(0)), db);
+			break;
+
+		case 'CALL': case 'CALLCODE': case 'DELEGATECALL': case 'STATICCALL':
+			this.lookupAccount(toAddress(log.stack.peek(1)), db);
+			break;
+
+		case 'SLOAD': case 'SSTORE':
+			this.lookupStorage(toAddress(log.contract.address()), toWord(log.stack.peek(0)), db);
+			break;
+		}
+	},
+
+	result: function(ctx, db) {
+		// If no code was executed, the accounts are only known after the gas refund
+		var gas = ctx.gas;
+		if (this.prestate === null) {
+			this.prestate = {};
+			this.lookupAccount(toAddress(ctx.from), db);
+			this.lookupAccount(toAddress(ctx.to), db);
+			gas = ctx.gasUsed;
+		}
+		// Undo the gas purchase, the value transfer and the nonce increment
+		var from = this.prestate[ctx.from];
+		var to = this.prestate[ctx.to];
+
+		var cost = big.NewInt(gas);
+		cost.Mul(cost, ctx.gasPrice);
+		from.balance.Add(from.balance, cost);
+		from.balance.Add(from.balance, ctx.value);
+		to.balance.Sub(to.balance, ctx.value);
+		from.nonce--;
+
+		// A created contract didn't exist before the transaction
+		if (ctx.type == 'CREATE') {
+			delete this.prestate[ctx.to];
+		}
+		var result = {};
+		for (var acc in this.prestate) {
+			var account = this.prestate[acc];
+			result[acc] = {
+				balance: '0x' + account.balance.Text(16),
+				nonce:   account.nonce,
+				code:    account.code,
+				storage: account.storage
+			};
+		}
+		return result;
+	}
+}`
+
+// fourByteTracer counts the 4 byte function selectors and input data sizes of
+// every call made during the execution of a transaction, keyed as
+// 'selector-size'. Calls to precompiled contracts are ignored.
+const fourByteTracer = `{
+	// ids aggregates the number of calls made to each selector and size.
+	ids: {},
+
+	// isPrecompiled returns whether the address is that of a precompiled
+	// contract, which doesn't understand function selectors.
+	isPrecompiled: function(addr) {
+		return addr.Sign() > 0 && addr.Cmp(big.NewInt(8)) <= 0;
+	},
+
+	store: function(ids, id, size) {
+		var key = id + '-' + size;
+		ids[key] = (ids[key] || 0) + 1;
+	},
+
+	step: function(log, db) {
+		var op = log.op.toString();
+		if (op != 'CALL' && op != 'CALLCODE' && op != 'DELEGATECALL' && op != 'STATICCALL') {
+			return;
+		}
+		// DELEGATECALL and STATICCALL don't have a value on the stack
+		var off = (op == 'DELEGATECALL' || op == 'STATICCALL') ? 0 : 1;
+		if (this.isPrecompiled(log.stack.peek(1))) {
+			return;
+		}
+		var inOff = log.stack.peek(2 + off).Int64();
+		var inLen = log.stack.peek(3 + off).Int64();
+		if (inLen >= 4) {
+			this.store(this.ids, toHex(log.memory.slice(inOff, inOff + 4)), inLen - 4);
+		}
+	},
+
+	result: function(ctx, db) {
+		var ids = {};
+		for (var key in this.ids) {
+			ids[key] = this.ids[key];
+		}
+		// Account for the selector of the transaction itself
+		var size = (ctx.input.length - 2) / 2;
+		if (ctx.type == 'CALL' && size >= 4) {
+			this.store(ids, ctx.input.slice(0, 10), size - 4);
+		}
+		return ids;
+	}
+}`
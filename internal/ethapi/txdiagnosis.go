@@ -0,0 +1,96 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kokapi
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core"
+)
+
+// TxHoldReason is a reason holding a pooled transaction back, along with a human
+// readable explanation.
+type TxHoldReason struct {
+	Code    core.TxHoldReason `json:"code"`
+	Message string            `json:"message"`
+}
+
+// TxDiagnosis explains the standing of a transaction in the transaction pool.
+type TxDiagnosis struct {
+	Status        string           `json:"status"`
+	From          *common.Address  `json:"from,omitempty"`
+	Nonce         *hexutil.Uint64  `json:"nonce,omitempty"`
+	ExpectedNonce *hexutil.Uint64  `json:"expectedNonce,omitempty"`
+	MissingNonces []hexutil.Uint64 `json:"missingNonces,omitempty"`
+	Balance       *hexutil.Big     `json:"balance,omitempty"`
+	Cost          *hexutil.Big     `json:"cost,omitempty"`
+	Reasons       []TxHoldReason   `json:"reasons"`
+	Dropped       *core.DroppedTx  `json:"dropped,omitempty"`
+}
+
+// Why explains why the transaction with the given hash is queued instead of
+// pending (e.g. a nonce gap or insufficient funds), or at risk of eviction. For
+// transactions no longer in the pool, the reason they were dropped is returned
+// if it's still known.
+func (s *PublicTxPoolAPI) Why(hash common.Hash) (*TxDiagnosis, error) {
+	diag := s.b.DiagnoseTransaction(hash)
+	if diag == nil {
+		return nil, errors.New("transaction diagnostics not available")
+	}
+	result := &TxDiagnosis{Reasons: []TxHoldReason{}, Dropped: diag.Dropped}
+	switch diag.Status {
+	case core.TxStatusPending:
+		result.Status = "pending"
+	case core.TxStatusQueued:
+		result.Status = "queued"
+	default:
+		if diag.Dropped != nil {
+			result.Status = "dropped"
+		} else {
+			result.Status = "unknown"
+		}
+		return result, nil
+	}
+	nonce, expected := hexutil.Uint64(diag.Nonce), hexutil.Uint64(diag.ExpectedNonce)
+	result.From, result.Nonce, result.ExpectedNonce = &diag.From, &nonce, &expected
+	result.Balance, result.Cost = (*hexutil.Big)(diag.Balance), (*hexutil.Big)(diag.Cost)
+	for _, nonce := range diag.MissingNonces {
+		result.MissingNonces = append(result.MissingNonces, hexutil.Uint64(nonce))
+	}
+	for _, reason := range diag.Reasons {
+		var msg string
+		switch reason {
+		case core.TxHoldNonceGap:
+			msg = fmt.Sprintf("transactions with nonces %v of the sender are missing, the next executable nonce is %d", diag.MissingNonces, diag.ExpectedNonce)
+		case core.TxHoldInsufficientFunds:
+			msg = fmt.Sprintf("sender balance %v cannot cover the total cost %v of its transactions up to this one", diag.Balance, diag.Cost)
+		case core.TxHoldAccountLimit:
+			msg = "sender reached its allowance of queued transactions, further ones are dropped"
+		case core.TxHoldPoolLimit:
+			msg = "transaction pool is full, the cheapest remote transactions are evicted first"
+		case core.TxHoldUnderpriced:
+			msg = "gas price is below the minimum accepted by the pool"
+		default:
+			msg = string(reason)
+		}
+		result.Reasons = append(result.Reasons, TxHoldReason{Code: reason, Message: msg})
+	}
+	return result, nil
+}
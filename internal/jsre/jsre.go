@@ -50,6 +50,7 @@ type JSRE struct {
 	evalQueue     chan *evalReq
 	stopEventLoop chan bool
 	closed        chan struct{}
+	modules       map[string]otto.Value // Exports of already loaded modules, keyed by absolute path
 }
 
 // jsTimer is a single timer instance with a callback function
@@ -74,9 +75,11 @@ func New(assetPath string, output io.Writer) *JSRE {
 		closed:        make(chan struct{}),
 		evalQueue:     make(chan *evalReq),
 		stopEventLoop: make(chan bool),
+		modules:       make(map[string]otto.Value),
 	}
 	go re.runEventLoop()
 	re.Set("loadScript", re.loadScript)
+	re.Set("loadModule", re.loadModule)
 	re.Set("inspect", re.prettyPrintJS)
 	return re
 }
@@ -303,6 +306,60 @@ func (self *JSRE) loadScript(call otto.FunctionCall) otto.Value {
 	return otto.TrueValue()
 }
 
+// loadModule executes a CommonJS style module from inside the currently executing
+// JS code and returns its exports. The module source is wrapped into a function
+// receiving the module, exports and require objects, and the resulting exports
+// are cached so that requiring the same file twice yields the same object.
+func (self *JSRE) loadModule(call otto.FunctionCall) otto.Value {
+	file, err := call.Argument(0).ToString()
+	if err != nil {
+		panic(call.Otto.MakeCustomError("Error", "module path must be a string"))
+	}
+	file = common.AbsolutePath(self.assetPath, file)
+	if exports, ok := self.modules[file]; ok {
+		return exports
+	}
+	source, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(call.Otto.MakeCustomError("Error", fmt.Sprintf("cannot load module: %v", err)))
+	}
+	wrapper, err := compileAndRun(call.Otto, file, "(function(module, exports, require) {\n"+string(source)+"\n})")
+	if err != nil {
+		panic(call.Otto.MakeCustomError("Error", fmt.Sprintf("%s: %v", file, err)))
+	}
+	module, _ := call.Otto.Object(`({exports: {}})`)
+	exports, _ := module.Get("exports")
+	require, _ := call.Otto.Get("require")
+
+	// Cache the exports before running the module body to break require cycles
+	self.modules[file] = exports
+	if _, err := wrapper.Call(otto.NullValue(), module, exports, require); err != nil {
+		delete(self.modules, file)
+		panic(call.Otto.MakeCustomError("Error", fmt.Sprintf("%s: %v", file, err)))
+	}
+	exports, _ = module.Get("exports")
+	self.modules[file] = exports
+	return exports
+}
+
+// EnableModules overrides the global require function so that relative paths and
+// files ending in .js are loaded as modules from the asset path, while every other
+// name is resolved through the previously installed require (e.g. web3's bundle).
+func (self *JSRE) EnableModules() error {
+	_, err := self.Run(`require = (function(builtin) {
+		return function(name) {
+			if (/^\.{0,2}\//.test(name) || /\.js$/.test(name)) {
+				return loadModule(name);
+			}
+			if (typeof builtin !== 'function') {
+				throw new Error("Cannot find module '" + name + "'");
+			}
+			return builtin(name);
+		};
+	})(typeof require === 'undefined' ? undefined : require);`)
+	return err
+}
+
 // Evaluate executes code and pretty prints the result to the specified output
 // stream.
 func (self *JSRE) Evaluate(code string, w io.Writer) error {
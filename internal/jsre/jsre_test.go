@@ -135,3 +135,30 @@ func TestLoadScript(t *testing.T) {
 	}
 	jsre.Stop(false)
 }
+
+func TestRequireModule(t *testing.T) {
+	jsre, dir := newWithTestJS(t, `calls = (typeof calls === 'undefined') ? 1 : calls + 1; module.exports = {double: function(x) { return 2 * x; }};`)
+	defer os.RemoveAll(dir)
+	defer jsre.Stop(false)
+
+	if err := jsre.EnableModules(); err != nil {
+		t.Fatalf("failed to enable modules: %v", err)
+	}
+	val, err := jsre.Run(`require("./test.js").double(21) + require("test.js").double(0)`)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got, _ := val.ToInteger(); got != 42 {
+		t.Errorf("result mismatch: have %d, want %d", got, 42)
+	}
+	val, err = jsre.Run("calls")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got, _ := val.ToInteger(); got != 1 {
+		t.Errorf("module evaluated %d times, want once", got)
+	}
+	if _, err := jsre.Run(`require("unknown")`); err == nil {
+		t.Errorf("expected error for unknown module")
+	}
+}
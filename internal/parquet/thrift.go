@@ -0,0 +1,124 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parquet
+
+import "bytes"
+
+// Element types of the Thrift compact protocol.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter serializes structures using the Thrift compact protocol, the
+// encoding of all Parquet metadata. Fields must be written in increasing id
+// order within each structure.
+type thriftWriter struct {
+	buf   bytes.Buffer
+	last  int16   // Id of the last field written in the current structure
+	stack []int16 // Last field ids of the enclosing structures
+}
+
+// field writes a field header, using the short delta form whenever possible.
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.last = id
+}
+
+// varint writes a zigzag encoded variable length integer.
+func (t *thriftWriter) varint(n int64) {
+	u := uint64(n<<1) ^ uint64(n>>63)
+	for u >= 0x80 {
+		t.buf.WriteByte(byte(u) | 0x80)
+		u >>= 7
+	}
+	t.buf.WriteByte(byte(u))
+}
+
+// uvarint writes an unsigned variable length integer.
+func (t *thriftWriter) uvarint(u uint64) {
+	for u >= 0x80 {
+		t.buf.WriteByte(byte(u) | 0x80)
+		u >>= 7
+	}
+	t.buf.WriteByte(byte(u))
+}
+
+// bytes writes a length prefixed byte blob without a field header.
+func (t *thriftWriter) bytes(blob []byte) {
+	t.uvarint(uint64(len(blob)))
+	t.buf.Write(blob)
+}
+
+func (t *thriftWriter) i32(id int16, n int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(n))
+}
+
+func (t *thriftWriter) i64(id int16, n int64) {
+	t.field(id, thriftI64)
+	t.varint(n)
+}
+
+func (t *thriftWriter) binary(id int16, blob []byte) {
+	t.field(id, thriftBinary)
+	t.bytes(blob)
+}
+
+// listBegin writes the header of a list field, the elements of which must be
+// written right after it.
+func (t *thriftWriter) listBegin(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.uvarint(uint64(size))
+	}
+}
+
+// structBegin opens a nested structure field.
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+// elemBegin opens a structure element within a list.
+func (t *thriftWriter) elemBegin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+// structEnd closes a structure opened by structBegin or elemBegin.
+func (t *thriftWriter) structEnd() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+// stop terminates the outermost structure.
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
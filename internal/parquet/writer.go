@@ -0,0 +1,288 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package parquet implements a minimal writer for the Apache Parquet columnar
+// file format, sufficient to export flat tables of integers and strings.
+//
+// Every column is written as a required field using plain encoding without
+// compression, one data page per column chunk, which keeps the output readable
+// by any compliant implementation without pulling in external dependencies.
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// magic is the marker opening and closing every Parquet file.
+var magic = []byte("PAR1")
+
+// DefaultRowGroupSize is the number of rows buffered before a row group is
+// flushed to the underlying writer.
+const DefaultRowGroupSize = 65536
+
+// ErrClosed is returned when writing into a writer that was already closed.
+var ErrClosed = errors.New("parquet: writer closed")
+
+// Type is the physical type of a column.
+type Type int
+
+const (
+	Int64  Type = iota // Signed 64 bit integer, accepts int, int64 and uint64 values
+	String             // UTF-8 string, stored as an annotated byte array
+)
+
+// Column describes a single column of the written table.
+type Column struct {
+	Name string
+	Type Type
+}
+
+// Physical types, encodings and other enumerations from the Parquet format.
+const (
+	typeInt64     = 2
+	typeByteArray = 6
+
+	repetitionRequired = 0
+	convertedUTF8      = 0
+	encodingPlain      = 0
+	encodingRLE        = 3
+	codecUncompressed  = 0
+	pageTypeData       = 0
+)
+
+// columnChunk is the metadata of a column chunk already flushed to disk.
+type columnChunk struct {
+	offset int64 // Position of the data page header within the file
+	size   int64 // Total size of the chunk, page header included
+}
+
+// rowGroup is the metadata of a row group already flushed to disk.
+type rowGroup struct {
+	rows    int64
+	columns []columnChunk
+}
+
+// Writer streams rows into a Parquet file, buffering them column by column
+// until a full row group is accumulated.
+type Writer struct {
+	out     io.Writer
+	offset  int64
+	columns []Column
+	limit   int
+
+	values [][]byte // Plain encoded values of each column in the pending row group
+	rows   int      // Number of rows in the pending row group
+	groups []rowGroup
+	closed bool
+}
+
+// NewWriter creates a Parquet writer of the given table schema, writing the
+// file header into out straight away.
+func NewWriter(out io.Writer, columns []Column) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("parquet: no columns")
+	}
+	w := &Writer{
+		out:     out,
+		columns: columns,
+		limit:   DefaultRowGroupSize,
+		values:  make([][]byte, len(columns)),
+	}
+	if err := w.write(magic); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends a row to the table. The number and types of the values must
+// match the columns the writer was created with.
+func (w *Writer) Write(row []interface{}) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values, want %d", len(row), len(w.columns))
+	}
+	for i, value := range row {
+		switch w.columns[i].Type {
+		case Int64:
+			var n int64
+			switch v := value.(type) {
+			case int:
+				n = int64(v)
+			case int64:
+				n = v
+			case uint64:
+				n = int64(v)
+			default:
+				return fmt.Errorf("parquet: column %s: invalid integer %T", w.columns[i].Name, value)
+			}
+			var blob [8]byte
+			binary.LittleEndian.PutUint64(blob[:], uint64(n))
+			w.values[i] = append(w.values[i], blob[:]...)
+
+		case String:
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("parquet: column %s: invalid string %T", w.columns[i].Name, value)
+			}
+			var size [4]byte
+			binary.LittleEndian.PutUint32(size[:], uint32(len(s)))
+			w.values[i] = append(w.values[i], size[:]...)
+			w.values[i] = append(w.values[i], s...)
+		}
+	}
+	w.rows++
+	if w.rows >= w.limit {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush writes the pending rows out as a new row group.
+func (w *Writer) Flush() error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.rows == 0 {
+		return nil
+	}
+	group := rowGroup{rows: int64(w.rows)}
+	for i, values := range w.values {
+		header := new(thriftWriter)
+		header.i32(1, pageTypeData)
+		header.i32(2, int32(len(values)))
+		header.i32(3, int32(len(values)))
+		header.structBegin(5)
+		header.i32(1, int32(w.rows))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.structEnd()
+		header.stop()
+
+		chunk := columnChunk{offset: w.offset, size: int64(header.buf.Len() + len(values))}
+		if err := w.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := w.write(values); err != nil {
+			return err
+		}
+		group.columns = append(group.columns, chunk)
+		w.values[i] = values[:0]
+	}
+	w.groups = append(w.groups, group)
+	w.rows = 0
+	return nil
+}
+
+// Close flushes any pending rows and writes the file footer. It does not close
+// the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	w.closed = true
+
+	footer := new(thriftWriter)
+	footer.i32(1, 1)
+
+	// Flat schema: an unnamed root group followed by the leaf columns
+	footer.listBegin(2, thriftStruct, len(w.columns)+1)
+	footer.elemBegin()
+	footer.binary(4, []byte("schema"))
+	footer.i32(5, int32(len(w.columns)))
+	footer.structEnd()
+	for _, column := range w.columns {
+		footer.elemBegin()
+		footer.i32(1, column.physicalType())
+		footer.i32(3, repetitionRequired)
+		footer.binary(4, []byte(column.Name))
+		if column.Type == String {
+			footer.i32(6, convertedUTF8)
+		}
+		footer.structEnd()
+	}
+	var rows int64
+	for _, group := range w.groups {
+		rows += group.rows
+	}
+	footer.i64(3, rows)
+
+	footer.listBegin(4, thriftStruct, len(w.groups))
+	for _, group := range w.groups {
+		footer.elemBegin()
+		footer.listBegin(1, thriftStruct, len(group.columns))
+
+		var total int64
+		for i, chunk := range group.columns {
+			footer.elemBegin()
+			footer.i64(2, chunk.offset)
+			footer.structBegin(3)
+			footer.i32(1, w.columns[i].physicalType())
+			footer.listBegin(2, thriftI32, 2)
+			footer.varint(encodingPlain)
+			footer.varint(encodingRLE)
+			footer.listBegin(3, thriftBinary, 1)
+			footer.bytes([]byte(w.columns[i].Name))
+			footer.i32(4, codecUncompressed)
+			footer.i64(5, group.rows)
+			footer.i64(6, chunk.size)
+			footer.i64(7, chunk.size)
+			footer.i64(9, chunk.offset)
+			footer.structEnd()
+			footer.structEnd()
+
+			total += chunk.size
+		}
+		footer.i64(2, total)
+		footer.i64(3, group.rows)
+		footer.structEnd()
+	}
+	footer.binary(6, []byte("gkok"))
+	footer.stop()
+
+	if err := w.write(footer.buf.Bytes()); err != nil {
+		return err
+	}
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(footer.buf.Len()))
+	if err := w.write(size[:]); err != nil {
+		return err
+	}
+	return w.write(magic)
+}
+
+// write forwards a blob to the underlying writer, tracking the file offset.
+func (w *Writer) write(blob []byte) error {
+	n, err := w.out.Write(blob)
+	w.offset += int64(n)
+	return err
+}
+
+// physicalType maps a column type to its Parquet physical type.
+func (c Column) physicalType() int32 {
+	if c.Type == Int64 {
+		return typeInt64
+	}
+	return typeByteArray
+}
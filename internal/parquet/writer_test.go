@@ -0,0 +1,162 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+// thriftReader decodes Thrift compact encoded structures into generic maps of
+// field ids to values, enough to inspect the metadata written by the writer.
+type thriftReader struct {
+	blob []byte
+	pos  int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	n, size := binary.Uvarint(r.blob[r.pos:])
+	r.pos += size
+	return n
+}
+
+func (r *thriftReader) varint() int64 {
+	u := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		size := int(r.uvarint())
+		r.pos += size
+		return string(r.blob[r.pos-size : r.pos])
+	case thriftList:
+		head := r.blob[r.pos]
+		r.pos++
+		size := int(head >> 4)
+		if size == 15 {
+			size = int(r.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(head & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		fields := make(map[int16]interface{})
+		var last int16
+		for {
+			head := r.blob[r.pos]
+			r.pos++
+			if head == 0 {
+				return fields
+			}
+			if delta := int16(head >> 4); delta != 0 {
+				last += delta
+			} else {
+				last = int16(r.varint())
+			}
+			fields[last] = r.value(head & 0x0f)
+		}
+	}
+	panic(fmt.Sprintf("unsupported thrift type %d", typ))
+}
+
+// Tests that written files carry a well formed footer that points at the page
+// data of every column of every row group.
+func TestWriter(t *testing.T) {
+	columns := []Column{{"number", Int64}, {"hash", String}}
+
+	buf := new(bytes.Buffer)
+	w, err := NewWriter(buf, columns)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	w.limit = 3
+	for i := 0; i < 5; i++ {
+		if err := w.Write([]interface{}{uint64(i), fmt.Sprintf("0x%02x", i)}); err != nil {
+			t.Fatalf("failed to write row %d: %v", i, err)
+		}
+	}
+	if err := w.Write([]interface{}{"1", "0x01"}); err == nil {
+		t.Errorf("mistyped row accepted")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	if err := w.Write([]interface{}{0, ""}); err != ErrClosed {
+		t.Errorf("write after close: have %v, want %v", err, ErrClosed)
+	}
+	file := buf.Bytes()
+	if !bytes.HasPrefix(file, magic) || !bytes.HasSuffix(file, magic) {
+		t.Fatalf("missing magic markers")
+	}
+	size := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer := &thriftReader{blob: file[len(file)-8-size : len(file)-8]}
+	meta := footer.value(thriftStruct).(map[int16]interface{})
+	if footer.pos != size {
+		t.Fatalf("footer size mismatch: decoded %d, want %d", footer.pos, size)
+	}
+	if schema := meta[2].([]interface{}); len(schema) != len(columns)+1 {
+		t.Fatalf("schema length mismatch: have %d, want %d", len(schema), len(columns)+1)
+	}
+	if rows := meta[3].(int64); rows != 5 {
+		t.Errorf("row count mismatch: have %d, want 5", rows)
+	}
+	groups := meta[4].([]interface{})
+	if len(groups) != 2 {
+		t.Fatalf("row group count mismatch: have %d, want 2", len(groups))
+	}
+	var number int64
+	for _, group := range groups {
+		group := group.(map[int16]interface{})
+		rows := group[3].(int64)
+		chunks := group[1].([]interface{})
+
+		// Decode the integer column and ensure the values follow each other
+		chunk := chunks[0].(map[int16]interface{})[3].(map[int16]interface{})
+		if chunk[5].(int64) != rows {
+			t.Errorf("value count mismatch: have %d, want %d", chunk[5], rows)
+		}
+		offset := chunk[9].(int64)
+		page := &thriftReader{blob: file[offset:]}
+		header := page.value(thriftStruct).(map[int16]interface{})
+		if header[2].(int64) != rows*8 {
+			t.Errorf("page size mismatch: have %d, want %d", header[2], rows*8)
+		}
+		for i := int64(0); i < rows; i++ {
+			value := int64(binary.LittleEndian.Uint64(page.blob[page.pos+int(i)*8:]))
+			if value != number {
+				t.Errorf("value mismatch: have %d, want %d", value, number)
+			}
+			number++
+		}
+		// Ensure the string column follows right after the integer one
+		chunk = chunks[1].(map[int16]interface{})[3].(map[int16]interface{})
+		if have, want := chunk[9].(int64), offset+int64(page.pos)+rows*8; have != want {
+			t.Errorf("string column offset mismatch: have %d, want %d", have, want)
+		}
+		if path := chunk[3].([]interface{}); len(path) != 1 || path[0] != "hash" {
+			t.Errorf("column path mismatch: have %v", path)
+		}
+	}
+}
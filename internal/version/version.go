@@ -0,0 +1,79 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package version exposes the build metadata embedded into the executables.
+//
+// The values are injected at link time by build/ci.go, e.g.
+//
+//	go build -ldflags "-X github.com/kokprojects/go-kok/internal/version.gitCommit=..."
+package version
+
+import (
+	"runtime"
+
+	"github.com/kokprojects/go-kok/params"
+)
+
+// Build metadata, set via linker flags. They are strings so that they can be
+// overridden with -X, the dirty flag being "true" for modified source trees.
+var (
+	gitCommit = "" // Git SHA1 commit hash of the release
+	gitBranch = "" // Git branch the release was built from
+	gitDirty  = "" // Whether the source tree had uncommitted changes
+	buildDate = "" // Commit timestamp of the release (RFC3339), for reproducibility
+)
+
+// Info is the collection of build metadata of the running executable.
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit,omitempty"`
+	GitBranch string `json:"gitBranch,omitempty"`
+	Dirty     bool   `json:"dirty"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// Get returns the build metadata of the running executable.
+func Get() Info {
+	return Info{
+		Version:   params.Version,
+		GitCommit: gitCommit,
+		GitBranch: gitBranch,
+		Dirty:     gitDirty == "true",
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+}
+
+// Commit returns the git commit hash the executable was built from, or an empty
+// string if unknown.
+func Commit() string {
+	return gitCommit
+}
+
+// WithCommit returns the textual version string, suffixed with the short commit
+// hash and a dirty marker if the binary was built from a modified tree.
+func WithCommit() string {
+	vsn := params.VersionWithCommit(gitCommit)
+	if gitCommit != "" && gitDirty == "true" {
+		vsn += "-dirty"
+	}
+	return vsn
+}
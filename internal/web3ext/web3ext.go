@@ -31,6 +31,7 @@ var Modules = map[string]string{
 	"swarmfs":    SWARMFS_JS,
 	"txpool":     TxPool_JS,
 	"dpos":       Dpos_JS,
+	"les":        Les_JS,
 }
 
 const Chequebook_JS = `
@@ -80,6 +81,72 @@ web3._extend({
 			params: 0,
 			outputFormatter: web3._extend.utils.toBigNumber
 		}),
+		new web3._extend.Mkokod({
+			name: 'getCandidates',
+			call: 'dpos_getCandidates',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getVotes',
+			call: 'dpos_getVotes',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Mkokod({
+			name: 'exportHeaders',
+			call: 'dpos_exportHeaders',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getHeartbeats',
+			call: 'dpos_getHeartbeats',
+			params: 0
+		}),
+		new web3._extend.Mkokod({
+			name: 'getEpochInfo',
+			call: 'dpos_getEpochInfo',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getValidatorStatus',
+			call: 'dpos_getValidatorStatus',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getSlots',
+			call: 'dpos_getSlots',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getMissedSlots',
+			call: 'dpos_getMissedSlots',
+			params: 1,
+			inputFormatter: [null]
+		}),
+	]
+});
+`
+
+const Les_JS = `
+web3._extend({
+	property: 'les',
+	mkokods: [
+		new web3._extend.Mkokod({
+			name: 'reportMisbehavior',
+			call: 'les_reportMisbehavior',
+			params: 2
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'misbehaviors',
+			getter: 'les_misbehaviors'
+		}),
 	]
 });
 `
@@ -144,6 +211,16 @@ web3._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new web3._extend.Mkokod({
+			name: 'addTrustedPeer',
+			call: 'admin_addTrustedPeer',
+			params: 1
+		}),
+		new web3._extend.Mkokod({
+			name: 'removeTrustedPeer',
+			call: 'admin_removeTrustedPeer',
+			params: 1
+		}),
 		new web3._extend.Mkokod({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -160,11 +237,28 @@ web3._extend({
 			call: 'admin_sleepBlocks',
 			params: 2
 		}),
+		new web3._extend.Mkokod({
+			name: 'peerErrors',
+			call: 'admin_peerErrors',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Mkokod({
+			name: 'banPeer',
+			call: 'admin_banPeer',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Mkokod({
+			name: 'unbanPeer',
+			call: 'admin_unbanPeer',
+			params: 1
+		}),
 		new web3._extend.Mkokod({
 			name: 'startRPC',
 			call: 'admin_startRPC',
-			params: 4,
-			inputFormatter: [null, null, null, null]
+			params: 6,
+			inputFormatter: [null, null, null, null, null, null]
 		}),
 		new web3._extend.Mkokod({
 			name: 'stopRPC',
@@ -173,23 +267,41 @@ web3._extend({
 		new web3._extend.Mkokod({
 			name: 'startWS',
 			call: 'admin_startWS',
-			params: 4,
-			inputFormatter: [null, null, null, null]
+			params: 6,
+			inputFormatter: [null, null, null, null, null, null]
 		}),
 		new web3._extend.Mkokod({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Mkokod({
+			name: 'startService',
+			call: 'admin_startService',
+			params: 1
+		}),
+		new web3._extend.Mkokod({
+			name: 'stopService',
+			call: 'admin_stopService',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
 			name: 'nodeInfo',
 			getter: 'admin_nodeInfo'
 		}),
+		new web3._extend.Property({
+			name: 'services',
+			getter: 'admin_services'
+		}),
 		new web3._extend.Property({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'bannedPeers',
+			getter: 'admin_bannedPeers'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
@@ -257,6 +369,17 @@ web3._extend({
 			name: 'chaindbCompact',
 			call: 'debug_chaindbCompact',
 		}),
+		new web3._extend.Mkokod({
+			name: 'rebuildBloomIndex',
+			call: 'debug_rebuildBloomIndex',
+			params: 0
+		}),
+		new web3._extend.Mkokod({
+			name: 'generateDAG',
+			call: 'debug_generateDAG',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
 		new web3._extend.Mkokod({
 			name: 'metrics',
 			call: 'debug_metrics',
@@ -359,6 +482,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Mkokod({
+			name: 'traceCall',
+			call: 'debug_traceCall',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
+		}),
 		new web3._extend.Mkokod({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -370,11 +499,34 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Mkokod({
+			name: 'trieCacheStats',
+			call: 'debug_trieCacheStats',
+			params: 0,
+		}),
 		new web3._extend.Mkokod({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
 			params: 5,
 		}),
+		new web3._extend.Mkokod({
+			name: 'reorgHistory',
+			call: 'debug_reorgHistory',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, null],
+		}),
+		new web3._extend.Mkokod({
+			name: 'accountRange',
+			call: 'debug_accountRange',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null, null],
+		}),
+		new web3._extend.Mkokod({
+			name: 'storageRange',
+			call: 'debug_storageRange',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputAddressFormatter, null, null],
+		}),
 		new web3._extend.Mkokod({
 			name: 'getModifiedAccountsByNumber',
 			call: 'debug_getModifiedAccountsByNumber',
@@ -387,6 +539,12 @@ web3._extend({
 			params: 2,
 			inputFormatter:[null, null],
 		}),
+		new web3._extend.Mkokod({
+			name: 'getStateDiff',
+			call: 'debug_getStateDiff',
+			params: 3,
+			inputFormatter: [null, null, web3._extend.formatters.inputAddressFormatter],
+		}),
 	],
 	properties: []
 });
@@ -407,6 +565,44 @@ web3._extend({
 			call: 'kok_getDetail',
 			params: 2,
 		}),
+		new web3._extend.Mkokod({
+			name: 'getChainConfig',
+			call: 'kok_getChainConfig',
+			params: 0,
+		}),
+		new web3._extend.Mkokod({
+			name: 'chainId',
+			call: 'kok_chainId',
+			params: 0,
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Mkokod({
+			name: 'feeHistory',
+			call: 'kok_feeHistory',
+			params: 3,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getFrozenAccounts',
+			call: 'kok_getFrozenAccounts',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getWork',
+			call: 'kok_getWork',
+			params: 0,
+		}),
+		new web3._extend.Mkokod({
+			name: 'submitWork',
+			call: 'kok_submitWork',
+			params: 3,
+		}),
+		new web3._extend.Mkokod({
+			name: 'submitHashrate',
+			call: 'kok_submitHashRate',
+			params: 2,
+		}),
 		new web3._extend.Mkokod({
 			name: 'getEndorse',
 			call: 'kok_getEndorse',
@@ -448,6 +644,79 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Mkokod({
+			name: 'getLogsPaged',
+			call: 'kok_getLogsPaged',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getTokenTransfers',
+			call: 'kok_getTokenTransfers',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getTransactionsByAddress',
+			call: 'kok_getTransactionsByAddress',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getChainStats',
+			call: 'kok_getChainStats',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getDroppedTransaction',
+			call: 'kok_getDroppedTransaction',
+			params: 1
+		}),
+		new web3._extend.Mkokod({
+			name: 'getBalanceChanges',
+			call: 'kok_getBalanceChanges',
+			params: 1
+		}),
+		new web3._extend.Mkokod({
+			name: 'getInternalTransactions',
+			call: 'kok_getInternalTransactions',
+			params: 1
+		}),
+		new web3._extend.Mkokod({
+			name: 'getInternalTransactionsByNumber',
+			call: 'kok_getInternalTransactionsByNumber',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Mkokod({
+			name: 'getContractCreator',
+			call: 'kok_getContractCreator',
+			params: 1
+		}),
+		new web3._extend.Mkokod({
+			name: 'simulateBundle',
+			call: 'kok_simulateBundle',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Mkokod({
+			name: 'multicall',
+			call: 'kok_multicall',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
+		}),
+		new web3._extend.Mkokod({
+			name: 'createAccessList',
+			call: 'kok_createAccessList',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Mkokod({
+			name: 'activeFilters',
+			call: 'kok_activeFilters',
+			params: 0
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -507,6 +776,10 @@ web3._extend({
 			name: 'gkokashrate',
 			call: 'miner_gkokashrate'
 		}),
+		new web3._extend.Mkokod({
+			name: 'reloadPolicy',
+			call: 'miner_reloadPolicy'
+		}),
 	],
 	properties: []
 });
@@ -625,7 +898,25 @@ web3._extend({
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	mkokods: [],
+	mkokods: [
+		new web3._extend.Mkokod({
+			name: 'why',
+			call: 'txpool_why',
+			params: 1
+		}),
+		new web3._extend.Mkokod({
+			name: 'setGasPriceBump',
+			call: 'txpool_setGasPriceBump',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Mkokod({
+			name: 'resetGasPriceBump',
+			call: 'txpool_resetGasPriceBump',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
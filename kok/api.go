@@ -25,6 +25,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -34,7 +35,9 @@ import (
 	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/internal/kokapi"
+	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/params"
 	"github.com/kokprojects/go-kok/rlp"
@@ -170,6 +173,15 @@ func (api *PrivateMinerAPI) SetCoinbase(coinbase common.Address) bool {
 	return true
 }
 
+// ReloadPolicy rereads the local transaction inclusion policy of the miner from
+// its file, applying it from the next block the miner starts working on.
+func (api *PrivateMinerAPI) ReloadPolicy() (bool, error) {
+	if err := api.e.Miner().ReloadPolicy(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // Gkokashrate returns the current hashrate of the miner.
 func (api *PrivateMinerAPI) Gkokashrate() uint64 {
 	return uint64(api.e.miner.HashRate())
@@ -219,53 +231,41 @@ func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	return true
 }
 
-// ImportChain imports a blockchain from a local file.
+// ImportChain imports a blockchain from a local file. An interrupted import of
+// the same file is resumed after the last imported batch of blocks.
 func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
-	// Make sure the can access the file to import
-	in, err := os.Open(file)
-	if err != nil {
+	if err := api.kok.importer.importChain(api.kok.BlockChain(), file); err != nil {
 		return false, err
 	}
-	defer in.Close()
+	return true, nil
+}
 
-	var reader io.Reader = in
-	if strings.HasSuffix(file, ".gz") {
-		if reader, err = gzip.NewReader(reader); err != nil {
-			return false, err
-		}
+// ImportProgress creates a subscription that is notified after each batch of
+// blocks processed by a chain import, and once the import finished.
+func (api *PrivateAdminAPI) ImportProgress(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
-
-	// Run actual the import in pre-configured batches
-	stream := rlp.NewStream(reader, 0)
-
-	blocks, index := make([]*types.Block, 0, 2500), 0
-	for batch := 0; ; batch++ {
-		// Load a batch of blocks from the input file
-		for len(blocks) < cap(blocks) {
-			block := new(types.Block)
-			if err := stream.Decode(block); err == io.EOF {
-				break
-			} else if err != nil {
-				return false, fmt.Errorf("block %d: failed to parse: %v", index, err)
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		progress := make(chan ImportProgress, 16)
+		sub := api.kok.importer.subscribe(progress)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case p := <-progress:
+				notifier.Notify(rpcSub.ID, p)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
 			}
-			blocks = append(blocks, block)
-			index++
-		}
-		if len(blocks) == 0 {
-			break
 		}
-
-		if hasAllBlocks(api.kok.BlockChain(), blocks) {
-			blocks = blocks[:0]
-			continue
-		}
-		// Import the batch and reset the buffer
-		if _, err := api.kok.BlockChain().InsertChain(blocks); err != nil {
-			return false, fmt.Errorf("batch %d: failed to insert: %v", batch, err)
-		}
-		blocks = blocks[:0]
-	}
-	return true, nil
+	}()
+	return rpcSub, nil
 }
 
 // PublicDebugAPI is the collection of kokereum full node APIs exposed
@@ -329,10 +329,45 @@ type BlockTraceResult struct {
 // TraceArgs holds extra parameters to trace functions
 type TraceArgs struct {
 	*vm.LogConfig
+	*vm.PostMortemConfig
 	Tracer  *string
 	Timeout *string
 }
 
+const (
+	// gasProfilerTracer is the name of the built-in tracer aggregating gas usage
+	// per opcode and call frame instead of returning a full step log.
+	gasProfilerTracer = "gasProfiler"
+
+	// postMortemTracer is the name of the built-in tracer logging the state only
+	// at call boundaries and failure points instead of at every step.
+	postMortemTracer = "postMortem"
+)
+
+// GasProfileResult is the result of tracing a transaction with the gas profiler.
+type GasProfileResult struct {
+	Gas         *big.Int           `json:"gas"`
+	Failed      bool               `json:"failed"`
+	ReturnValue string             `json:"returnValue"`
+	Opcodes     []vm.OpcodeProfile `json:"opcodes"`
+	Frames      []vm.FrameProfile  `json:"frames"`
+}
+
+// PostMortemLog is a structured log captured by the post-mortem tracer.
+type PostMortemLog struct {
+	Kind string `json:"kind"`
+	kokapi.StructLogRes
+}
+
+// PostMortemResult is the result of tracing a transaction with the post-mortem
+// tracer.
+type PostMortemResult struct {
+	Gas         *big.Int         `json:"gas"`
+	Failed      bool             `json:"failed"`
+	ReturnValue string           `json:"returnValue"`
+	StructLogs  []*PostMortemLog `json:"structLogs"`
+}
+
 // TraceBlock processes the given block'api RLP but does not import the block in to
 // the chain.
 func (api *PrivateDebugAPI) TraceBlock(blockRlp []byte, config *vm.LogConfig) BlockTraceResult {
@@ -450,11 +485,80 @@ func (t *timeoutError) Error() string {
 	return "Execution time exceeded"
 }
 
+type executionFailedError struct{}
+
+func (e *executionFailedError) Error() string {
+	return "Execution failed"
+}
+
 // TraceTransaction returns the structured logs created during the execution of EVM
-// and returns them as a JSON object.
+// and returns them as a JSON object. If the tracer is set to "gasProfiler", only
+// the gas used per opcode and per call frame is returned. If it's set to
+// "postMortem", logs are only captured at call boundaries and failure points.
+// Any other tracer is either the name of a built-in Javascript tracer, one of
+// "callTracer", "prestateTracer" and "4byteTracer", or custom Javascript code.
 func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.Hash, config *TraceArgs) (interface{}, error) {
+	// Retrieve the tx from the chain and the containing block
+	tx, blockHash, _, txIndex := core.GetTransaction(api.kok.ChainDb(), txHash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", txHash)
+	}
+	if err := kokapi.ChargeTraceCost(ctx, txIndex+1); err != nil {
+		return nil, err
+	}
+	msg, vmctx, statedb, err := api.computeTxEnv(ctx, blockHash, int(txIndex))
+	if err != nil {
+		return nil, err
+	}
+	return api.traceMessage(ctx, msg, vmctx, statedb, config)
+}
+
+// TraceCall runs the given call on top of the state of the requested block with
+// tracing enabled, without the call needing to be mined. The tracers supported
+// are the same as for TraceTransaction.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args kokapi.CallArgs, blockNr rpc.BlockNumber, config *TraceArgs) (interface{}, error) {
+	statedb, header, err := api.kok.ApiBackend.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", blockNr)
+		}
+		return nil, err
+	}
+	if err := kokapi.ChargeTraceCost(ctx, 1); err != nil {
+		return nil, err
+	}
+	// Set sender address or use a default if none specified
+	from := args.From
+	if from == (common.Address{}) {
+		if wallets := api.kok.AccountManager().Wallets(); len(wallets) > 0 {
+			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
+				from = accounts[0].Address
+			}
+		}
+	}
+	// Set default gas & gas price if none were set
+	gas, gasPrice := args.Gas.ToInt(), args.GasPrice.ToInt()
+	if gas.Sign() == 0 {
+		gas = new(big.Int).Set(header.GasLimit)
+	}
+	if gasPrice.Sign() == 0 {
+		gasPrice = new(big.Int)
+	}
+	msg := types.NewMessage(from, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	vmctx := core.NewEVMContext(msg, header, api.kok.BlockChain(), nil)
+
+	return api.traceMessage(ctx, msg, vmctx, statedb, config)
+}
+
+// traceMessage executes a message on top of the given state with the tracer
+// selected by the config, returning the formatted results of the tracer.
+func (api *PrivateDebugAPI) traceMessage(ctx context.Context, msg core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceArgs) (interface{}, error) {
 	var tracer vm.Tracer
-	if config != nil && config.Tracer != nil {
+	if config != nil && config.Tracer != nil && *config.Tracer == gasProfilerTracer {
+		tracer = vm.NewGasProfiler()
+	} else if config != nil && config.Tracer != nil && *config.Tracer == postMortemTracer {
+		tracer = vm.NewPostMortemLogger(config.LogConfig, config.PostMortemConfig)
+	} else if config != nil && config.Tracer != nil {
 		timeout := defaultTraceTimeout
 		if config.Timeout != nil {
 			var err error
@@ -481,22 +585,42 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 		tracer = vm.NewStructLogger(config.LogConfig)
 	}
 
-	// Retrieve the tx from the chain and the containing block
-	tx, blockHash, _, txIndex := core.GetTransaction(api.kok.ChainDb(), txHash)
-	if tx == nil {
-		return nil, fmt.Errorf("transaction %x not found", txHash)
-	}
-	msg, context, statedb, err := api.computeTxEnv(blockHash, int(txIndex))
-	if err != nil {
-		return nil, err
-	}
+	// Run the message with tracing enabled, aborting if the request is cancelled
+	vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
 
-	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
-	ret, gas, failed, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas()), nil, nil, 0)
+	jst, _ := tracer.(*kokapi.JavascriptTracer)
+	if jst != nil {
+		if to := msg.To(); to != nil {
+			jst.CaptureStart(vmenv, msg.From(), *to, false, msg.Data(), msg.Gas().Uint64(), msg.Value())
+		} else {
+			jst.CaptureStart(vmenv, msg.From(), crypto.CreateAddress(msg.From(), msg.Nonce()), true, msg.Data(), msg.Gas().Uint64(), msg.Value())
+		}
+	}
+	start := time.Now()
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			vmenv.Cancel()
+		case <-done:
+		}
+	}()
+	ret, gas, failed, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()), nil, nil, 0)
+	close(done)
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %v", err)
 	}
+	if jst != nil {
+		var vmerr error
+		if failed {
+			vmerr = &executionFailedError{}
+		}
+		jst.CaptureEnd(ret, gas.Uint64(), time.Since(start), vmerr)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
 		return &kokapi.ExecutionResult{
@@ -505,6 +629,26 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 			ReturnValue: fmt.Sprintf("%x", ret),
 			StructLogs:  kokapi.FormatLogs(tracer.StructLogs()),
 		}, nil
+	case *vm.GasProfiler:
+		return &GasProfileResult{
+			Gas:         gas,
+			Failed:      failed,
+			ReturnValue: fmt.Sprintf("%x", ret),
+			Opcodes:     tracer.Opcodes(),
+			Frames:      tracer.Frames(),
+		}, nil
+	case *vm.PostMortemLogger:
+		kinds, logs := tracer.Kinds(), kokapi.FormatLogs(tracer.StructLogs())
+		result := &PostMortemResult{
+			Gas:         gas,
+			Failed:      failed,
+			ReturnValue: fmt.Sprintf("%x", ret),
+			StructLogs:  make([]*PostMortemLog, len(logs)),
+		}
+		for i, log := range logs {
+			result.StructLogs[i] = &PostMortemLog{Kind: kinds[i], StructLogRes: log}
+		}
+		return result, nil
 	case *kokapi.JavascriptTracer:
 		return tracer.GetResult()
 	default:
@@ -512,8 +656,9 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 	}
 }
 
-// computeTxEnv returns the execution environment of a certain transaction.
-func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (core.Message, vm.Context, *state.StateDB, error) {
+// computeTxEnv returns the execution environment of a certain transaction. The
+// replay of the preceding transactions is aborted if the context is cancelled.
+func (api *PrivateDebugAPI) computeTxEnv(ctx context.Context, blockHash common.Hash, txIndex int) (core.Message, vm.Context, *state.StateDB, error) {
 	// Create the parent state.
 	block := api.kok.BlockChain().GetBlockByHash(blockHash)
 	if block == nil {
@@ -523,7 +668,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (co
 	if parent == nil {
 		return nil, vm.Context{}, nil, fmt.Errorf("block parent %x not found", block.ParentHash())
 	}
-	statedb, err := api.kok.BlockChain().StateAt(parent.Root())
+	statedb, err := stateReader(ctx, api.kok.BlockChain(), parent.Root())
 	if err != nil {
 		return nil, vm.Context{}, nil, err
 	}
@@ -539,6 +684,9 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int) (co
 			return msg, context, statedb, nil
 		}
 
+		if err := ctx.Err(); err != nil {
+			return nil, vm.Context{}, nil, err
+		}
 		vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{})
 		gp := new(core.GasPool).AddGas(tx.Gas())
 		_, _, _, err := core.ApplyMessage(vmenv, msg, gp, nil, nil, 0)
@@ -562,6 +710,26 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]core.BadBlockAr
 	return api.kok.BlockChain().BadBlocks()
 }
 
+// ReorgHistory returns the records of the chain reorganisations observed by the
+// node, starting at the given record index. At most count (capped to 1024)
+// records are returned per call.
+func (api *PrivateDebugAPI) ReorgHistory(from hexutil.Uint64, count int) ([]*ReorgRecord, error) {
+	return api.kok.reorgLog.history(uint64(from), count)
+}
+
+// RebuildBloomIndex discards the bloom bits index, making the node generate it
+// again from the canonical chain in the background.
+func (api *PrivateDebugAPI) RebuildBloomIndex() bool {
+	api.kok.bloomIndexer.Rebuild()
+	return true
+}
+
+// TrieCacheStats returns statistics about the in-memory state tries, including
+// the number of buffered trie nodes and how often they are flushed to disk.
+func (api *PrivateDebugAPI) TrieCacheStats() core.TrieCacheStats {
+	return api.kok.BlockChain().TrieCacheStats()
+}
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
@@ -577,7 +745,7 @@ type storageEntry struct {
 
 // StorageRangeAt returns the storage at the given block height and transaction index.
 func (api *PrivateDebugAPI) StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex int, contractAddress common.Address, keyStart hexutil.Bytes, maxResult int) (StorageRangeResult, error) {
-	_, _, statedb, err := api.computeTxEnv(blockHash, txIndex)
+	_, _, statedb, err := api.computeTxEnv(ctx, blockHash, txIndex)
 	if err != nil {
 		return StorageRangeResult{}, err
 	}
@@ -607,6 +775,62 @@ func storageRangeAt(st state.Trie, start []byte, maxResult int) StorageRangeResu
 	return result
 }
 
+// AccountRangeMaxResults is the maximum number of results returned by a single
+// account or storage range query.
+const AccountRangeMaxResults = 256
+
+// stateAtBlock retrieves the state at the end of the given block, or the pending
+// state if requested. The state is retained until the context is done.
+func (api *PrivateDebugAPI) stateAtBlock(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, error) {
+	if blockNr == rpc.PendingBlockNumber {
+		_, stateDb := api.kok.miner.Pending()
+		return stateDb, nil
+	}
+	var block *types.Block
+	if blockNr == rpc.LatestBlockNumber {
+		block = api.kok.blockchain.CurrentBlock()
+	} else {
+		block = api.kok.blockchain.GetBlockByNumber(uint64(blockNr))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	return stateReader(ctx, api.kok.BlockChain(), block.Root())
+}
+
+// AccountRange enumerates the accounts of the state at the given block, ordered
+// by the hash of their address. At most maxResults (capped to 256) accounts are
+// returned, along with the key to start the next page from, if any.
+func (api *PrivateDebugAPI) AccountRange(ctx context.Context, blockNr rpc.BlockNumber, start hexutil.Bytes, maxResults int) (state.AccountRange, error) {
+	statedb, err := api.stateAtBlock(ctx, blockNr)
+	if err != nil {
+		return state.AccountRange{}, err
+	}
+	if maxResults <= 0 || maxResults > AccountRangeMaxResults {
+		maxResults = AccountRangeMaxResults
+	}
+	return statedb.AccountRange(start, maxResults)
+}
+
+// StorageRange returns the storage of a contract at the end of the given block,
+// ordered by the hash of the storage keys. Contrary to StorageRangeAt it doesn't
+// replay the transactions of the block, making it suitable for bulk exports. At
+// most maxResults (capped to 256) slots are returned per call.
+func (api *PrivateDebugAPI) StorageRange(ctx context.Context, blockNr rpc.BlockNumber, contractAddress common.Address, keyStart hexutil.Bytes, maxResults int) (StorageRangeResult, error) {
+	statedb, err := api.stateAtBlock(ctx, blockNr)
+	if err != nil {
+		return StorageRangeResult{}, err
+	}
+	st := statedb.StorageTrie(contractAddress)
+	if st == nil {
+		return StorageRangeResult{}, fmt.Errorf("account %x doesn't exist", contractAddress)
+	}
+	if maxResults <= 0 || maxResults > AccountRangeMaxResults {
+		maxResults = AccountRangeMaxResults
+	}
+	return storageRangeAt(st, keyStart, maxResults), nil
+}
+
 // GetModifiedAccountsByumber returns all accounts that have changed between the
 // two blocks specified. A change is defined as a difference in nonce, balance,
 // code hash, or storage hash.
@@ -667,11 +891,13 @@ func (api *PrivateDebugAPI) getModifiedAccounts(startBlock, endBlock *types.Bloc
 		return nil, fmt.Errorf("start block height (%d) must be less than end block height (%d)", startBlock.Number().Uint64(), endBlock.Number().Uint64())
 	}
 
-	oldTrie, err := trie.NewSecure(startBlock.Root(), api.kok.chainDb, 0)
+	db := api.kok.blockchain.TrieDB()
+
+	oldTrie, err := trie.NewSecure(startBlock.Root(), db, 0)
 	if err != nil {
 		return nil, err
 	}
-	newTrie, err := trie.NewSecure(endBlock.Root(), api.kok.chainDb, 0)
+	newTrie, err := trie.NewSecure(endBlock.Root(), db, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -689,3 +915,183 @@ func (api *PrivateDebugAPI) getModifiedAccounts(startBlock, endBlock *types.Bloc
 	}
 	return dirty, nil
 }
+
+// ValueDiff is the change of an account field, with nil denoting absence.
+type ValueDiff struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AccountDiff is the change of an account between two states. Unchanged fields
+// are omitted.
+type AccountDiff struct {
+	Balance *ValueDiff                 `json:"balance,omitempty"`
+	Nonce   *ValueDiff                 `json:"nonce,omitempty"`
+	Code    *ValueDiff                 `json:"code,omitempty"`
+	Storage map[common.Hash]*ValueDiff `json:"storage,omitempty"`
+}
+
+// GetStateDiff returns the accounts changed between the two blocks specified,
+// along with their balance, nonce, code and storage values before and after. If
+// an address is given, only that account is compared.
+//
+// With one block parameter, returns the changes made by the specified block.
+func (api *PrivateDebugAPI) GetStateDiff(startNum uint64, endNum *uint64, address *common.Address) (map[common.Address]*AccountDiff, error) {
+	var startBlock, endBlock *types.Block
+
+	startBlock = api.kok.blockchain.GetBlockByNumber(startNum)
+	if startBlock == nil {
+		return nil, fmt.Errorf("start block %x not found", startNum)
+	}
+	if endNum == nil {
+		endBlock = startBlock
+		startBlock = api.kok.blockchain.GetBlockByHash(startBlock.ParentHash())
+		if startBlock == nil {
+			return nil, fmt.Errorf("block %x has no parent", endBlock.Number())
+		}
+	} else {
+		endBlock = api.kok.blockchain.GetBlockByNumber(*endNum)
+		if endBlock == nil {
+			return nil, fmt.Errorf("end block %d not found", *endNum)
+		}
+	}
+	if startBlock.Number().Uint64() >= endBlock.Number().Uint64() {
+		return nil, fmt.Errorf("start block height (%d) must be less than end block height (%d)", startBlock.Number().Uint64(), endBlock.Number().Uint64())
+	}
+	return stateDiff(api.kok.blockchain.TrieDB(), startBlock.Root(), endBlock.Root(), address)
+}
+
+// stateDiff compares the two state tries with the given roots, returning the
+// changes of every differing account, or only the given one if not nil.
+func stateDiff(db kokdb.Database, oldRoot, newRoot common.Hash, address *common.Address) (map[common.Address]*AccountDiff, error) {
+	oldState, err := state.New(oldRoot, state.NewDatabase(db))
+	if err != nil {
+		return nil, err
+	}
+	newState, err := state.New(newRoot, state.NewDatabase(db))
+	if err != nil {
+		return nil, err
+	}
+	var dirty []common.Address
+	if address != nil {
+		dirty = []common.Address{*address}
+	} else {
+		oldTrie, err := trie.NewSecure(oldRoot, db, 0)
+		if err != nil {
+			return nil, err
+		}
+		newTrie, err := trie.NewSecure(newRoot, db, 0)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := diffTrieKeys(oldTrie, newTrie)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			dirty = append(dirty, common.BytesToAddress(key))
+		}
+	}
+	diffs := make(map[common.Address]*AccountDiff)
+	for _, addr := range dirty {
+		diff, err := accountDiff(db, oldState, newState, addr)
+		if err != nil {
+			return nil, err
+		}
+		if diff != nil {
+			diffs[addr] = diff
+		}
+	}
+	return diffs, nil
+}
+
+// accountDiff compares an account between two states, returning nil if it is
+// unchanged.
+func accountDiff(db kokdb.Database, oldState, newState *state.StateDB, addr common.Address) (*AccountDiff, error) {
+	var (
+		diff     = new(AccountDiff)
+		changed  bool
+		oldExist = oldState.Exist(addr)
+		newExist = newState.Exist(addr)
+	)
+	// Compare the account fields, reporting missing accounts as nil
+	field := func(exist bool, value interface{}) interface{} {
+		if !exist {
+			return nil
+		}
+		return value
+	}
+	if oldBal, newBal := oldState.GetBalance(addr), newState.GetBalance(addr); oldExist != newExist || oldBal.Cmp(newBal) != 0 {
+		diff.Balance = &ValueDiff{field(oldExist, (*hexutil.Big)(oldBal)), field(newExist, (*hexutil.Big)(newBal))}
+		changed = true
+	}
+	if oldNonce, newNonce := oldState.GetNonce(addr), newState.GetNonce(addr); oldExist != newExist || oldNonce != newNonce {
+		diff.Nonce = &ValueDiff{field(oldExist, hexutil.Uint64(oldNonce)), field(newExist, hexutil.Uint64(newNonce))}
+		changed = true
+	}
+	if oldCode, newCode := oldState.GetCode(addr), newState.GetCode(addr); !bytes.Equal(oldCode, newCode) {
+		diff.Code = &ValueDiff{field(oldExist, hexutil.Bytes(oldCode)), field(newExist, hexutil.Bytes(newCode))}
+		changed = true
+	}
+	// Compare the storage slots, treating missing tries as empty
+	empty, err := trie.NewSecure(common.Hash{}, db, 0)
+	if err != nil {
+		return nil, err
+	}
+	oldTrie, newTrie := oldState.StorageTrie(addr), newState.StorageTrie(addr)
+	if oldTrie == nil {
+		oldTrie = empty
+	}
+	if newTrie == nil {
+		newTrie = empty
+	}
+	keys, err := diffTrieKeys(oldTrie, newTrie)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		diff.Storage = make(map[common.Hash]*ValueDiff)
+		for _, key := range keys {
+			slot := common.BytesToHash(key)
+			diff.Storage[slot] = &ValueDiff{oldState.GetState(addr, slot), newState.GetState(addr, slot)}
+		}
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+	return diff, nil
+}
+
+// diffTrieKeys returns the preimages of the keys added, removed or modified
+// between two secure tries, ordered by their hashes.
+func diffTrieKeys(oldTrie, newTrie state.Trie) ([][]byte, error) {
+	added, _ := trie.NewDifferenceIterator(oldTrie.NodeIterator(nil), newTrie.NodeIterator(nil))
+	removed, _ := trie.NewDifferenceIterator(newTrie.NodeIterator(nil), oldTrie.NodeIterator(nil))
+
+	// Modified keys are yielded by both iterators, so deduplicate them by hash
+	preimages := make(map[common.Hash][]byte)
+	for _, diff := range []struct {
+		iter  trie.NodeIterator
+		owner state.Trie
+	}{{added, newTrie}, {removed, oldTrie}} {
+		for iter := trie.NewIterator(diff.iter); iter.Next(); {
+			key := diff.owner.GetKey(iter.Key)
+			if key == nil {
+				return nil, fmt.Errorf("no preimage found for hash %x", iter.Key)
+			}
+			preimages[common.BytesToHash(iter.Key)] = key
+		}
+	}
+	hashes := make([]common.Hash, 0, len(preimages))
+	for hash := range preimages {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+
+	keys := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		keys[i] = preimages[hash]
+	}
+	return keys, nil
+}
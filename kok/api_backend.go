@@ -92,15 +92,48 @@ func (b *kokApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	if header == nil || err != nil {
 		return nil, nil, err
 	}
-	stateDb, err := b.kok.BlockChain().StateAt(header.Root)
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	stateDb, err := stateReader(ctx, b.kok.BlockChain(), header.Root)
 	return stateDb, header, err
 }
 
+// stateReader opens a read-only state on top of the given root, keeping the root
+// referenced until the context is done so pruning retains it while the request
+// is being served. States opened with a context which is never cancelled are not
+// referenced, as nothing would release them.
+func stateReader(ctx context.Context, chain *core.BlockChain, root common.Hash) (*state.StateDB, error) {
+	if ctx.Done() == nil {
+		return chain.StateAt(root)
+	}
+	statedb, release, err := chain.StateReader(root)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		release()
+	}()
+	return statedb, nil
+}
+
+// PrefetchStorage is a no-op, the full state is available locally.
+func (b *kokApiBackend) PrefetchStorage(ctx context.Context, header *types.Header, address common.Address, keys []common.Hash) error {
+	return nil
+}
+
 func (b *kokApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return b.kok.blockchain.GetBlockByHash(blockHash), nil
 }
 
 func (b *kokApiBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return core.GetBlockReceipts(b.kok.chainDb, blockHash, core.GetBlockNumber(b.kok.chainDb, blockHash)), nil
 }
 
@@ -109,6 +142,9 @@ func (b *kokApiBackend) GetTd(blockHash common.Hash) *big.Int {
 }
 
 func (b *kokApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	state.SetBalance(msg.From(), math.MaxBig256)
 	vmError := func() error { return nil }
 
@@ -136,6 +172,18 @@ func (b *kokApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.kok.txPool.AddLocal(signedTx)
 }
 
+func (b *kokApiBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction, private bool) error {
+	return b.kok.txPool.AddLocalTx(signedTx, private)
+}
+
+func (b *kokApiBackend) SendExpiringTx(ctx context.Context, signedTx *types.Transaction, private *bool, expiry core.TxExpiry) error {
+	isPrivate := b.kok.config.TxPool.Private
+	if private != nil {
+		isPrivate = *private
+	}
+	return b.kok.txPool.AddLocalWithExpiry(signedTx, isPrivate, expiry)
+}
+
 func (b *kokApiBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending, err := b.kok.txPool.Pending()
 	if err != nil {
@@ -168,6 +216,33 @@ func (b *kokApiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Sub
 	return b.kok.TxPool().SubscribeTxPreEvent(ch)
 }
 
+func (b *kokApiBackend) SubscribeTxDropEvent(ch chan<- core.TxDropEvent) event.Subscription {
+	return b.kok.TxPool().SubscribeTxDropEvent(ch)
+}
+
+func (b *kokApiBackend) GetDroppedTransaction(hash common.Hash) *core.DroppedTx {
+	return b.kok.txPool.DroppedTransaction(hash)
+}
+
+func (b *kokApiBackend) DiagnoseTransaction(hash common.Hash) *core.TxDiagnosis {
+	return b.kok.txPool.Diagnose(hash)
+}
+
+func (b *kokApiBackend) TxPoolPriceBumps() (uint64, map[common.Address]uint64, error) {
+	bump, bumps := b.kok.txPool.PriceBumps()
+	return bump, bumps, nil
+}
+
+func (b *kokApiBackend) SetTxPoolPriceBump(account *common.Address, bump uint64) error {
+	b.kok.txPool.SetPriceBump(account, bump)
+	return nil
+}
+
+func (b *kokApiBackend) ResetTxPoolPriceBump(account common.Address) error {
+	b.kok.txPool.ResetPriceBump(account)
+	return nil
+}
+
 func (b *kokApiBackend) Downloader() *downloader.Downloader {
 	return b.kok.Downloader()
 }
@@ -180,6 +255,10 @@ func (b *kokApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *kokApiBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, rewardPercentiles)
+}
+
 func (b *kokApiBackend) ChainDb() kokdb.Database {
 	return b.kok.ChainDb()
 }
@@ -194,7 +273,7 @@ func (b *kokApiBackend) AccountManager() *accounts.Manager {
 
 func (b *kokApiBackend) BloomStatus() (uint64, uint64) {
 	sections, _, _ := b.kok.bloomIndexer.Sections()
-	return params.BloomBitsBlocks, sections
+	return b.kok.config.BloomSectionSize, sections
 }
 
 func (b *kokApiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
@@ -202,3 +281,14 @@ func (b *kokApiBackend) ServiceFilter(ctx context.Context, session *bloombits.Ma
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.kok.bloomRequests)
 	}
 }
+
+func (b *kokApiBackend) AddressBloomStatus() (uint64, uint64) {
+	sections, _, _ := b.kok.addressBloomIndexer.Sections()
+	return b.kok.config.BloomSectionSize, sections
+}
+
+func (b *kokApiBackend) ServiceAddressFilter(ctx context.Context, session *bloombits.MatcherSession) {
+	for i := 0; i < bloomFilterThreads; i++ {
+		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.kok.addressBloomRequests)
+	}
+}
@@ -17,12 +17,18 @@
 package kok
 
 import (
+	"math/big"
 	"reflect"
 	"testing"
 
 	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/consensus/kokash"
+	"github.com/kokprojects/go-kok/core"
 	"github.com/kokprojects/go-kok/core/state"
+	"github.com/kokprojects/go-kok/core/vm"
 	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
 	"github.com/davecgh/go-spew/spew"
 )
 
@@ -86,3 +92,102 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+func TestStateDiff(t *testing.T) {
+	var (
+		db, _   = kokdb.NewMemDatabase()
+		sdb, _  = state.New(common.Hash{}, state.NewDatabase(db))
+		changed = common.Address{0x01}
+		created = common.Address{0x02}
+		deleted = common.Address{0x03}
+		static  = common.Address{0x04}
+	)
+	sdb.SetBalance(changed, big.NewInt(1))
+	sdb.SetState(changed, common.Hash{0x01}, common.Hash{0x01})
+	sdb.SetState(changed, common.Hash{0x02}, common.Hash{0x02})
+	sdb.SetNonce(deleted, 1)
+	sdb.SetNonce(static, 1)
+	oldRoot, _ := sdb.CommitTo(db, true)
+
+	sdb, _ = state.New(oldRoot, state.NewDatabase(db))
+	sdb.SetBalance(changed, big.NewInt(2))
+	sdb.SetState(changed, common.Hash{0x01}, common.Hash{})
+	sdb.SetState(changed, common.Hash{0x02}, common.Hash{0x03})
+	sdb.SetState(changed, common.Hash{0x03}, common.Hash{0x04})
+	sdb.SetCode(created, []byte{0x60})
+	sdb.Suicide(deleted)
+	newRoot, _ := sdb.CommitTo(db, true)
+
+	diffs, err := stateDiff(db, oldRoot, newRoot, nil)
+	if err != nil {
+		t.Fatalf("failed to diff states: %v", err)
+	}
+	want := map[common.Address]*AccountDiff{
+		changed: {
+			Balance: &ValueDiff{(*hexutil.Big)(big.NewInt(1)), (*hexutil.Big)(big.NewInt(2))},
+			Storage: map[common.Hash]*ValueDiff{
+				{0x01}: {common.Hash{0x01}, common.Hash{}},
+				{0x02}: {common.Hash{0x02}, common.Hash{0x03}},
+				{0x03}: {common.Hash{}, common.Hash{0x04}},
+			},
+		},
+		created: {
+			Balance: &ValueDiff{nil, (*hexutil.Big)(new(big.Int))},
+			Nonce:   &ValueDiff{nil, hexutil.Uint64(0)},
+			Code:    &ValueDiff{nil, hexutil.Bytes{0x60}},
+		},
+		deleted: {
+			Balance: &ValueDiff{(*hexutil.Big)(new(big.Int)), nil},
+			Nonce:   &ValueDiff{hexutil.Uint64(1), nil},
+		},
+	}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Fatalf("state diff mismatch:\ngot %s\nwant %s", dumper.Sdump(diffs), dumper.Sdump(want))
+	}
+	// Ensure a single account can be diffed too
+	diffs, err = stateDiff(db, oldRoot, newRoot, &static)
+	if err != nil {
+		t.Fatalf("failed to diff account: %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("unchanged account reported: %s", dumper.Sdump(diffs))
+	}
+}
+
+// Tests that the state diffs of recent blocks can be retrieved from a pruning
+// node, which only keeps their state in memory.
+func TestGetStateDiffPruning(t *testing.T) {
+	var (
+		db, _ = kokdb.NewMemDatabase()
+		gspec = &core.Genesis{Config: params.TestChainConfig}
+	)
+	// Generate the chain on a separate database, as the chain maker commits all states
+	gendb, _ := kokdb.NewMemDatabase()
+	chain, _ := core.GenerateChain(gspec.Config, gspec.MustCommit(gendb), gendb, 3, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{byte(i + 1)})
+	})
+	gspec.MustCommit(db)
+
+	blockchain, _ := core.NewBlockChain(db, gspec.Config, kokash.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+	blockchain.SetPruning(core.PruningConfig{Enabled: true, Retain: 4, Interval: 8})
+
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := state.New(blockchain.CurrentBlock().Root(), state.NewDatabase(db)); err == nil {
+		t.Fatalf("head state persisted, pruning inactive")
+	}
+	api := NewPrivateDebugAPI(gspec.Config, &kokereum{blockchain: blockchain, chainDb: db})
+
+	diffs, err := api.GetStateDiff(3, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to diff block state: %v", err)
+	}
+	if diff := diffs[common.Address{3}]; diff == nil || diff.Balance == nil || diff.Balance.From != nil {
+		t.Errorf("coinbase diff mismatch: have %s", dumper.Sdump(diffs))
+	}
+	if _, err := api.GetModifiedAccountsByNumber(2, nil); err != nil {
+		t.Errorf("failed to retrieve modified accounts: %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package archive implements the continuous archival of the finalized chain into
+// S3 compatible object storage, and the bootstrapping of new nodes from it.
+//
+// The chain is archived in ranges of a fixed number of blocks, aligned to the
+// genesis block. Every range is uploaded as an RLP stream of blocks, in the format
+// consumed by gkok import, an RLP stream of the receipts of each block, and a JSON
+// manifest with the hashes of both. A top level manifest tracks the last archived
+// range, so that a new node can be bootstrapped by downloading and importing the
+// ranges, every block being fully verified again during the import.
+//
+// All the object keys referenced by the manifests are relative to the archive
+// prefix, so that an archive can be served from any location.
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+// archiveNextKey tracks the first block of the next range to archive.
+var archiveNextKey = []byte("archive-next")
+
+// headManifestKey is the key of the head manifest, relative to the archive prefix.
+const headManifestKey = "manifest.json"
+
+const (
+	// retryInterval is the time to wait before retrying a failed upload.
+	retryInterval = 30 * time.Second
+
+	// UnconfirmedDepth is the number of blocks kept out of the archive when the
+	// consensus engine has no notion of finality.
+	UnconfirmedDepth = 1024
+)
+
+// Config contains the settings of the chain archiver.
+type Config struct {
+	Endpoint  string // URL of the S3 compatible storage service
+	Bucket    string // Bucket to upload the archive into, archival is disabled if empty
+	Region    string // Region of the bucket, used for signing requests
+	Prefix    string // Key prefix of all the archived objects
+	RangeSize uint64 // Number of blocks archived per range, must not change once archival started
+
+	AccessKey string `toml:"-"` // Credentials of the storage service, taken from the environment
+	SecretKey string `toml:"-"`
+}
+
+// DefaultConfig contains the default settings of the chain archiver.
+var DefaultConfig = Config{
+	Endpoint:  "https://s3.amazonaws.com",
+	Region:    "us-east-1",
+	Prefix:    "gkok",
+	RangeSize: 1024,
+}
+
+// Manifest describes a single archived range of blocks.
+type Manifest struct {
+	Genesis        common.Hash   `json:"genesis"`
+	First          uint64        `json:"first"`
+	Last           uint64        `json:"last"`
+	ParentHash     common.Hash   `json:"parentHash"` // Parent of the first block, linking the range to the previous one
+	LastHash       common.Hash   `json:"lastHash"`
+	Blocks         string        `json:"blocks"`         // Key of the RLP stream of blocks
+	BlocksSHA256   hexutil.Bytes `json:"blocksSha256"`   // Hash of the blocks object
+	Receipts       string        `json:"receipts"`       // Key of the RLP stream of per block receipt lists
+	ReceiptsSHA256 hexutil.Bytes `json:"receiptsSha256"` // Hash of the receipts object
+}
+
+// HeadManifest describes the archive as a whole, pointing to its last range.
+type HeadManifest struct {
+	Genesis   common.Hash `json:"genesis"`
+	RangeSize uint64      `json:"rangeSize"`
+	Head      uint64      `json:"head"` // Last archived block
+	HeadHash  common.Hash `json:"headHash"`
+	Manifest  string      `json:"manifest"` // Key of the manifest of the last archived range
+}
+
+// Archiver uploads the ranges of the chain into object storage as soon as all
+// of their blocks become final.
+type Archiver struct {
+	config    Config
+	chain     *core.BlockChain
+	db        kokdb.Database
+	store     objectStore
+	finalized func() uint64 // Number of the last block that cannot be reorganised
+
+	next uint64 // First block of the next range to archive
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// New creates a chain archiver, resuming after the last range archived into the
+// database. The finalized callback reports the last block safe from reorgs.
+func New(config Config, chain *core.BlockChain, db kokdb.Database, finalized func() uint64) (*Archiver, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("no archive bucket configured")
+	}
+	if config.RangeSize == 0 {
+		return nil, errors.New("zero archive range size")
+	}
+	store, err := newS3Store(&config)
+	if err != nil {
+		return nil, err
+	}
+	return newArchiver(config, chain, db, store, finalized), nil
+}
+
+// newArchiver creates a chain archiver uploading into an arbitrary store.
+func newArchiver(config Config, chain *core.BlockChain, db kokdb.Database, store objectStore, finalized func() uint64) *Archiver {
+	a := &Archiver{
+		config:    config,
+		chain:     chain,
+		db:        db,
+		store:     store,
+		finalized: finalized,
+		quit:      make(chan struct{}),
+	}
+	if blob, _ := db.Get(archiveNextKey); len(blob) == 8 {
+		a.next = binary.BigEndian.Uint64(blob)
+	}
+	return a
+}
+
+// Start launches the archival loop, uploading the finalized ranges on every new
+// chain head.
+func (a *Archiver) Start() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := a.chain.SubscribeChainHeadEvent(heads)
+
+	log.Info("Starting chain archiver", "endpoint", a.config.Endpoint, "bucket", a.config.Bucket, "prefix", a.config.Prefix, "next", a.next)
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer sub.Unsubscribe()
+
+		retry := time.NewTimer(0)
+		defer retry.Stop()
+		for {
+			select {
+			case <-heads:
+			case <-retry.C:
+			case <-sub.Err():
+				return
+			case <-a.quit:
+				return
+			}
+			if err := a.catchUp(); err != nil {
+				log.Warn("Failed to archive chain range", "first", a.next, "err", err)
+				retry.Reset(retryInterval)
+			}
+		}
+	}()
+}
+
+// Stop terminates the archival loop, waiting for any upload in progress.
+func (a *Archiver) Stop() {
+	close(a.quit)
+	a.wg.Wait()
+}
+
+// catchUp archives all the ranges that are fully finalized.
+func (a *Archiver) catchUp() error {
+	for a.next+a.config.RangeSize-1 <= a.finalized() {
+		select {
+		case <-a.quit:
+			return nil
+		default:
+		}
+		if err := a.archive(a.next, a.next+a.config.RangeSize-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archive uploads a single range of blocks along with their receipts and the
+// manifests, and records the progress in the database.
+func (a *Archiver) archive(first, last uint64) error {
+	var (
+		blocks   = new(bytes.Buffer)
+		receipts = new(bytes.Buffer)
+		manifest = &Manifest{
+			Genesis:  a.chain.Genesis().Hash(),
+			First:    first,
+			Last:     last,
+			Blocks:   rangeKey("blocks", first, last, "rlp"),
+			Receipts: rangeKey("receipts", first, last, "rlp"),
+		}
+	)
+	for number := first; number <= last; number++ {
+		block := a.chain.GetBlockByNumber(number)
+		if block == nil {
+			return fmt.Errorf("block #%d not found", number)
+		}
+		if number == first {
+			manifest.ParentHash = block.ParentHash()
+		}
+		manifest.LastHash = block.Hash()
+
+		if err := block.EncodeRLP(blocks); err != nil {
+			return err
+		}
+		stored := core.GetBlockReceipts(a.db, block.Hash(), number)
+		if len(stored) != len(block.Transactions()) {
+			return fmt.Errorf("receipts of block #%d not found", number)
+		}
+		storage := make([]*types.ReceiptForStorage, len(stored))
+		for i, receipt := range stored {
+			storage[i] = (*types.ReceiptForStorage)(receipt)
+		}
+		if err := rlp.Encode(receipts, storage); err != nil {
+			return err
+		}
+	}
+	blocksSum, receiptsSum := sha256.Sum256(blocks.Bytes()), sha256.Sum256(receipts.Bytes())
+	manifest.BlocksSHA256, manifest.ReceiptsSHA256 = blocksSum[:], receiptsSum[:]
+
+	// Upload the data before the manifests referencing it
+	if err := a.store.put(a.key(manifest.Blocks), blocks.Bytes(), "application/octet-stream"); err != nil {
+		return err
+	}
+	if err := a.store.put(a.key(manifest.Receipts), receipts.Bytes(), "application/octet-stream"); err != nil {
+		return err
+	}
+	key := rangeKey("ranges", first, last, "json")
+	if err := a.putJSON(a.key(key), manifest); err != nil {
+		return err
+	}
+	head := &HeadManifest{
+		Genesis:   manifest.Genesis,
+		RangeSize: a.config.RangeSize,
+		Head:      last,
+		HeadHash:  manifest.LastHash,
+		Manifest:  key,
+	}
+	if err := a.putJSON(a.key(headManifestKey), head); err != nil {
+		return err
+	}
+	next := make([]byte, 8)
+	binary.BigEndian.PutUint64(next, last+1)
+	if err := a.db.Put(archiveNextKey, next); err != nil {
+		return err
+	}
+	a.next = last + 1
+
+	log.Info("Archived chain range", "first", first, "last", last, "hash", manifest.LastHash, "size", common.StorageSize(blocks.Len()+receipts.Len()))
+	return nil
+}
+
+// putJSON uploads a manifest into the store.
+func (a *Archiver) putJSON(key string, manifest interface{}) error {
+	blob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return a.store.put(key, blob, "application/json")
+}
+
+// rangeKey returns the key of an object of a range, relative to the archive prefix.
+func rangeKey(kind string, first, last uint64, ext string) string {
+	return fmt.Sprintf("%s/%012d-%012d.%s", kind, first, last, ext)
+}
+
+// key prepends the configured prefix to an object key.
+func (a *Archiver) key(name string) string {
+	prefix := strings.Trim(a.config.Prefix, "/")
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
@@ -0,0 +1,181 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+// errBootstrapInterrupted is returned if a bootstrap is aborted midway.
+var errBootstrapInterrupted = errors.New("archive bootstrap interrupted")
+
+// fetcher retrieves the objects of an archive, relative to its location.
+type fetcher interface {
+	fetch(key string) ([]byte, error)
+}
+
+// httpFetcher downloads archive objects over HTTP, which covers public S3 buckets
+// as well as archives mirrored onto plain web servers.
+type httpFetcher struct {
+	base   *url.URL
+	client *http.Client
+}
+
+func (f *httpFetcher) fetch(key string) ([]byte, error) {
+	ref, err := url.Parse(key)
+	if err != nil {
+		return nil, err
+	}
+	target := f.base.ResolveReference(ref)
+
+	res, err := f.client.Get(target.String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download of %s failed: %s", target, res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// Bootstrap downloads the ranges of a chain archive following the local head of
+// the chain and imports them. The location is either the URL of the archive head
+// manifest or of the directory containing it. Every imported block goes through
+// the full validation of the chain, so the archive itself needn't be trusted.
+func Bootstrap(chain *core.BlockChain, location string, stop <-chan struct{}) error {
+	base, err := url.Parse(location)
+	if err != nil {
+		return fmt.Errorf("invalid archive location: %v", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return fmt.Errorf("invalid archive location scheme %q", base.Scheme)
+	}
+	if !strings.HasSuffix(base.Path, ".json") {
+		base.Path = strings.TrimSuffix(base.Path, "/") + "/" + headManifestKey
+	}
+	return bootstrap(chain, &httpFetcher{base: base, client: &http.Client{Timeout: 10 * time.Minute}}, stop)
+}
+
+// bootstrap imports the ranges of a chain archive served by the given fetcher.
+func bootstrap(chain *core.BlockChain, archive fetcher, stop <-chan struct{}) error {
+	head := new(HeadManifest)
+	if err := fetchJSON(archive, headManifestKey, head); err != nil {
+		return err
+	}
+	genesis := chain.Genesis().Hash()
+	if head.Genesis != genesis {
+		return fmt.Errorf("archive genesis mismatch: have %x, want %x", head.Genesis, genesis)
+	}
+	if head.RangeSize == 0 {
+		return errors.New("archive has zero range size")
+	}
+	local := chain.CurrentBlock().NumberU64()
+	if head.Head <= local {
+		log.Info("Local chain ahead of archive", "local", local, "archive", head.Head)
+		return nil
+	}
+	log.Info("Bootstrapping from chain archive", "local", local, "archive", head.Head, "hash", head.HeadHash)
+
+	start := time.Now()
+	for first := (local + 1) / head.RangeSize * head.RangeSize; first <= head.Head; first += head.RangeSize {
+		select {
+		case <-stop:
+			return errBootstrapInterrupted
+		default:
+		}
+		last := first + head.RangeSize - 1
+
+		manifest := new(Manifest)
+		if err := fetchJSON(archive, rangeKey("ranges", first, last, "json"), manifest); err != nil {
+			return err
+		}
+		if manifest.Genesis != genesis || manifest.First != first || manifest.Last != last {
+			return fmt.Errorf("archive range %d-%d: mismatching manifest", first, last)
+		}
+		blob, err := archive.fetch(manifest.Blocks)
+		if err != nil {
+			return err
+		}
+		if sum := sha256.Sum256(blob); !bytes.Equal(sum[:], manifest.BlocksSHA256) {
+			return fmt.Errorf("archive range %d-%d: blocks hash mismatch: have %x, want %x", first, last, sum, manifest.BlocksSHA256)
+		}
+		blocks, err := decodeBlocks(blob)
+		if err != nil {
+			return fmt.Errorf("archive range %d-%d: %v", first, last, err)
+		}
+		// Skip the blocks already present locally and import the rest
+		for len(blocks) > 0 && chain.HasBlock(blocks[0].Hash(), blocks[0].NumberU64()) {
+			blocks = blocks[1:]
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if _, err := chain.InsertChain(blocks); err != nil {
+			return fmt.Errorf("archive range %d-%d: %v", first, last, err)
+		}
+		log.Info("Imported archived chain range", "first", first, "last", last, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	log.Info("Bootstrapped from chain archive", "head", chain.CurrentBlock().NumberU64(), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// fetchJSON downloads and decodes a manifest of the archive.
+func fetchJSON(archive fetcher, key string, manifest interface{}) error {
+	blob, err := archive.fetch(key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(blob, manifest); err != nil {
+		return fmt.Errorf("invalid archive manifest %s: %v", key, err)
+	}
+	return nil
+}
+
+// decodeBlocks parses an RLP stream of blocks.
+func decodeBlocks(blob []byte) (types.Blocks, error) {
+	var (
+		blocks types.Blocks
+		stream = rlp.NewStream(bytes.NewReader(blob), uint64(len(blob)))
+	)
+	for {
+		block := new(types.Block)
+		if err := stream.Decode(block); err == io.EOF {
+			return blocks, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("block %d: %v", len(blocks), err)
+		}
+		blocks = append(blocks, block)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"sync/atomic"
 
 	"github.com/kokprojects/go-kok/accounts"
+	"github.com/kokprojects/go-kok/accounts/keystore"
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/common/hexutil"
 	"github.com/kokprojects/go-kok/consensus"
@@ -34,11 +35,13 @@ import (
 	"github.com/kokprojects/go-kok/core/bloombits"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/kok/archive"
 	"github.com/kokprojects/go-kok/kok/downloader"
 	"github.com/kokprojects/go-kok/kok/filters"
 	"github.com/kokprojects/go-kok/kok/gasprice"
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/event"
+	"github.com/kokprojects/go-kok/graphql"
 	"github.com/kokprojects/go-kok/internal/kokapi"
 	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/miner"
@@ -81,6 +84,14 @@ type kokereum struct {
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
+	addressBloomRequests chan chan *bloombits.Retrieval // Channel receiving address bloom data retrieval requests
+	addressBloomIndexer  *core.ChainIndexer             // Address-only bloom indexer operating during block imports
+
+	chainStatsIndexer *core.ChainIndexer // Chain statistics indexer operating during block imports
+	txAddrIndexer     *core.ChainIndexer // Address transaction indexer, nil if disabled
+	reorgLog          *reorgLog          // Audit log of the chain reorganisations
+	importer          *chainImporter     // Resumable importer of exported chain files
+
 	ApiBackend *kokApiBackend
 
 	miner     *miner.Miner
@@ -90,6 +101,9 @@ type kokereum struct {
 
 	networkId     uint64
 	netRPCService *kokapi.PublicNetAPI
+	restServer    *kokapi.RESTServer
+	graphqlServer *graphql.Server
+	archiver      *archive.Archiver
 
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and coinbase)
 }
@@ -108,6 +122,15 @@ func New(ctx *node.ServiceContext, config *Config) (*kokereum, error) {
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
+	if config.BloomSectionSize == 0 {
+		config.BloomSectionSize = params.BloomBitsBlocks
+	}
+	if config.BloomSectionSize%8 != 0 {
+		return nil, fmt.Errorf("invalid bloom section size %d, must be a multiple of 8", config.BloomSectionSize)
+	}
+	if config.LightServ > 0 && config.BloomSectionSize != params.BloomBitsBlocks {
+		return nil, fmt.Errorf("light serving requires bloom section size %d", params.BloomBitsBlocks)
+	}
 	chainDb, err := CreateDB(ctx, config, "chaindata")
 	if err != nil {
 		return nil, err
@@ -133,7 +156,14 @@ func New(ctx *node.ServiceContext, config *Config) (*kokereum, error) {
 		validator:      config.Validator,
 		coinbase:       config.Coinbase,
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
-		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks),
+		bloomIndexer:   NewBloomIndexer(chainDb, config.BloomSectionSize),
+
+		addressBloomRequests: make(chan chan *bloombits.Retrieval),
+		addressBloomIndexer:  NewAddressBloomIndexer(chainDb, config.BloomSectionSize),
+
+		chainStatsIndexer: NewChainStatsIndexer(chainDb, chainConfig),
+		reorgLog:          newReorgLog(chainDb),
+		importer:          newChainImporter(chainDb),
 	}
 
 	log.Info("Initialising kokereum protocol", "versions", ProtocolVersions, "network", config.NetworkId)
@@ -145,11 +175,19 @@ func New(ctx *node.ServiceContext, config *Config) (*kokereum, error) {
 		}
 		core.WriteBlockChainVersion(chainDb, core.BlockChainVersion)
 	}
-	vmConfig := vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
+	vmConfig := vm.Config{
+		EnablePreimageRecording:   config.EnablePreimageRecording,
+		EnableBalanceRecording:    config.EnableBalanceIndex,
+		EnableInternalTxRecording: config.EnableInternalTxIndex,
+		EnableRevertRecording:     config.EnableRevertReasonIndex,
+		EnableAudit:               config.EnableAudit,
+	}
 	kok.blockchain, err = core.NewBlockChain(chainDb, kok.chainConfig, kok.engine, vmConfig)
 	if err != nil {
 		return nil, err
 	}
+	kok.blockchain.SetCommitInterval(config.TrieCommitInterval)
+	kok.blockchain.SetPruning(config.Pruning)
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -157,6 +195,13 @@ func New(ctx *node.ServiceContext, config *Config) (*kokereum, error) {
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	kok.bloomIndexer.Start(kok.blockchain)
+	kok.addressBloomIndexer.Start(kok.blockchain)
+	kok.chainStatsIndexer.Start(kok.blockchain)
+	if config.EnableTxAddressIndex {
+		kok.txAddrIndexer = NewTxAddrIndexer(chainDb, chainConfig)
+		kok.txAddrIndexer.Start(kok.blockchain)
+	}
+	kok.reorgLog.start(kok.blockchain)
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
@@ -168,6 +213,23 @@ func New(ctx *node.ServiceContext, config *Config) (*kokereum, error) {
 	}
 	kok.miner = miner.New(kok, kok.chainConfig, kok.EventMux(), kok.engine)
 	kok.miner.SetExtra(makeExtraData(config.ExtraData))
+	if err := kok.miner.SetPolicy(config.MinerPolicy); err != nil {
+		return nil, err
+	}
+	ordering, err := miner.NewOrdering(config.MinerOrdering)
+	if err != nil {
+		return nil, err
+	}
+	kok.miner.SetOrdering(ordering)
+	if dpos, ok := kok.engine.(*dpos.Dpos); ok {
+		dpos.SetSkipOffline(config.SkipOffline)
+	}
+
+	if config.Archive.Bucket != "" {
+		if kok.archiver, err = archive.New(config.Archive, kok.blockchain, chainDb, kok.finalizedBlock); err != nil {
+			return nil, err
+		}
+	}
 
 	kok.ApiBackend = &kokApiBackend{kok, nil}
 	gpoParams := config.GPO
@@ -242,7 +304,7 @@ func (s *kokereum) APIs() []rpc.API {
 		}, {
 			Namespace: "kok",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, false),
+			Service:   filters.NewPublicFilterAPI(s.ApiBackend, false, s.config.Filters),
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -336,6 +398,11 @@ func (s *kokereum) StartMining(local bool) error {
 			log.Error("Coinbase account unavailable locally", "err", err)
 			return fmt.Errorf("signer missing: %v", err)
 		}
+		// USB hardware wallets only sign transactions, not the raw header hashes
+		if scheme := wallet.URL().Scheme; scheme != keystore.KeyStoreScheme {
+			log.Error("Validator account can't sign blocks", "validator", validator, "wallet", scheme)
+			return fmt.Errorf("validator %x is held by a %s wallet, which can't sign blocks", validator, scheme)
+		}
 		dpos.Authorize(validator, wallet.SignHash)
 	}
 	if local {
@@ -364,6 +431,23 @@ func (s *kokereum) kokVersion() int                    { return int(s.protocolMa
 func (s *kokereum) NetVersion() uint64                 { return s.networkId }
 func (s *kokereum) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 
+// finalizedBlock returns the number of the latest block that cannot be reorged
+// anymore, as far as the consensus engine is concerned.
+func (s *kokereum) finalizedBlock() uint64 {
+	if engine, ok := s.engine.(*dpos.Dpos); ok {
+		header, err := engine.ConfirmedHeader(s.blockchain)
+		if err != nil {
+			return 0
+		}
+		return header.Number.Uint64()
+	}
+	head := s.blockchain.CurrentBlock().NumberU64()
+	if head < archive.UnconfirmedDepth {
+		return 0
+	}
+	return head - archive.UnconfirmedDepth
+}
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *kokereum) Protocols() []p2p.Protocol {
@@ -381,6 +465,21 @@ func (s *kokereum) Start(srvr *p2p.Server) error {
 
 	// Start the RPC service
 	s.netRPCService = kokapi.NewPublicNetAPI(srvr, s.NetVersion())
+	if s.config.RESTEndpoint != "" {
+		s.restServer = kokapi.NewRESTServer(s.ApiBackend)
+		if err := s.restServer.Start(s.config.RESTEndpoint); err != nil {
+			return err
+		}
+	}
+	if s.config.GraphQLEndpoint != "" {
+		s.graphqlServer = graphql.NewServer(s.ApiBackend, s.config.GraphQLCors)
+		if err := s.graphqlServer.Start(s.config.GraphQLEndpoint); err != nil {
+			return err
+		}
+	}
+	if s.archiver != nil {
+		s.archiver.Start()
+	}
 
 	// Figure out a max peers count based on the server limits
 	maxPeers := srvr.MaxPeers
@@ -404,7 +503,22 @@ func (s *kokereum) Stop() error {
 	if s.stopDbUpgrade != nil {
 		s.stopDbUpgrade()
 	}
+	if s.restServer != nil {
+		s.restServer.Stop()
+	}
+	if s.graphqlServer != nil {
+		s.graphqlServer.Stop()
+	}
+	if s.archiver != nil {
+		s.archiver.Stop()
+	}
 	s.bloomIndexer.Close()
+	s.addressBloomIndexer.Close()
+	s.chainStatsIndexer.Close()
+	if s.txAddrIndexer != nil {
+		s.txAddrIndexer.Close()
+	}
+	s.reorgLog.stop()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
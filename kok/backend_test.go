@@ -0,0 +1,86 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kokprojects/go-kok/accounts"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/consensus/dpos"
+	"github.com/kokprojects/go-kok/event"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+)
+
+// hardwareWallet is a wallet holding a single account behind a USB device URL,
+// none of whose signing methods are expected to be called.
+type hardwareWallet struct {
+	accounts.Wallet
+	account accounts.Account
+}
+
+func (w *hardwareWallet) URL() accounts.URL {
+	return accounts.URL{Scheme: "ledger", Path: "0001:0009:00"}
+}
+
+func (w *hardwareWallet) Accounts() []accounts.Account {
+	return []accounts.Account{w.account}
+}
+
+func (w *hardwareWallet) Contains(account accounts.Account) bool {
+	return account.Address == w.account.Address
+}
+
+// hardwareBackend is an account backend exposing a fixed set of wallets.
+type hardwareBackend struct {
+	wallets []accounts.Wallet
+	feed    event.Feed
+}
+
+func (b *hardwareBackend) Wallets() []accounts.Wallet {
+	return b.wallets
+}
+
+func (b *hardwareBackend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return b.feed.Subscribe(sink)
+}
+
+// Tests that mining can't be started with a validator account held by a wallet
+// other than the keystore, since only keystore wallets can sign header hashes.
+func TestStartMiningHardwareValidator(t *testing.T) {
+	validator := common.HexToAddress("0x1000000000000000000000000000000000000001")
+	wallet := &hardwareWallet{account: accounts.Account{Address: validator}}
+
+	db, _ := kokdb.NewMemDatabase()
+	kok := &kokereum{
+		accountManager: accounts.NewManager(&hardwareBackend{wallets: []accounts.Wallet{wallet}}),
+		engine:         dpos.New(&params.DposConfig{}, db),
+		validator:      validator,
+		coinbase:       validator,
+	}
+	defer kok.accountManager.Close()
+
+	err := kok.StartMining(false)
+	if err == nil {
+		t.Fatalf("mining started with a hardware wallet validator")
+	}
+	if !strings.Contains(err.Error(), "ledger wallet") {
+		t.Fatalf("error mismatch: have %v, want hardware wallet rejection", err)
+	}
+}
@@ -17,6 +17,7 @@
 package kok
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/kokprojects/go-kok/common"
@@ -25,7 +26,7 @@ import (
 	"github.com/kokprojects/go-kok/core/bloombits"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/kokdb"
-	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/log"
 )
 
 const (
@@ -49,6 +50,13 @@ const (
 // startBloomHandlers starts a batch of goroutines to accept bloom bit database
 // retrievals from possibly a range of filters and serving the data to satisfy.
 func (kok *kokereum) startBloomHandlers() {
+	kok.serveBloomRequests(kok.bloomRequests, core.GetBloomBits)
+	kok.serveBloomRequests(kok.addressBloomRequests, core.GetAddressBloomBits)
+}
+
+// serveBloomRequests starts the goroutines servicing the retrievals of a bloom
+// bits index, reading the bit vectors with the given accessor.
+func (kok *kokereum) serveBloomRequests(requests chan chan *bloombits.Retrieval, getBits func(core.DatabaseReader, uint, uint64, common.Hash) ([]byte, error)) {
 	for i := 0; i < bloomServickokreads; i++ {
 		go func() {
 			for {
@@ -56,13 +64,13 @@ func (kok *kokereum) startBloomHandlers() {
 				case <-kok.shutdownChan:
 					return
 
-				case request := <-kok.bloomRequests:
+				case request := <-requests:
 					task := <-request
 					task.Bitsets = make([][]byte, len(task.Sections))
 					for i, section := range task.Sections {
-						head := core.GetCanonicalHash(kok.chainDb, (section+1)*params.BloomBitsBlocks-1)
-						if compVector, err := core.GetBloomBits(kok.chainDb, task.Bit, section, head); err == nil {
-							if blob, err := bitutil.DecompressBytes(compVector, int(params.BloomBitsBlocks)/8); err == nil {
+						head := core.GetCanonicalHash(kok.chainDb, (section+1)*kok.config.BloomSectionSize-1)
+						if compVector, err := getBits(kok.chainDb, task.Bit, section, head); err == nil {
+							if blob, err := bitutil.DecompressBytes(compVector, int(kok.config.BloomSectionSize)/8); err == nil {
 								task.Bitsets[i] = blob
 							} else {
 								task.Error = err
@@ -86,6 +94,10 @@ const (
 	// bloomThrottling is the time to wait between processing two consecutive index
 	// sections. It's useful during chain upgrades to prevent disk overload.
 	bloomThrottling = 100 * time.Millisecond
+
+	// bloomRebuildStall is the maximum time to wait for the next section to be
+	// indexed when rebuilding the bloom index before giving up.
+	bloomRebuildStall = time.Minute
 )
 
 // BloomIndexer implements a core.ChainIndexer, building up a rotated bloom bits index
@@ -141,3 +153,100 @@ func (b *BloomIndexer) Commit() error {
 	}
 	return batch.Write()
 }
+
+// RebuildBloomIndex discards the bloom bits index of the database and generates
+// it again from the canonical chain with the given section size, returning once
+// all the confirmed sections are indexed.
+func RebuildBloomIndex(chain *core.BlockChain, db kokdb.Database, size uint64) error {
+	indexer := NewBloomIndexer(db, size)
+	defer indexer.Close()
+
+	indexer.Rebuild()
+	indexer.Start(chain)
+
+	var target uint64
+	if head := chain.CurrentHeader().Number.Uint64(); head >= bloomConfirms {
+		target = (head + 1 - bloomConfirms) / size
+	}
+	var (
+		indexed  uint64
+		progress = time.Now()
+	)
+	for indexed < target {
+		time.Sleep(bloomThrottling)
+
+		sections, _, _ := indexer.Sections()
+		switch {
+		case sections > indexed:
+			indexed, progress = sections, time.Now()
+			log.Info("Rebuilding bloom index", "sections", indexed, "total", target)
+		case time.Since(progress) > bloomRebuildStall:
+			return fmt.Errorf("bloom indexing stalled at section %d/%d", indexed, target)
+		}
+	}
+	return nil
+}
+
+// AddressBloomIndexer implements a core.ChainIndexer, building up a rotated bloom
+// bits index of blooms containing only the emitting addresses of the logs. With
+// topics left out the blooms are much sparser, so queries filtering on addresses
+// alone hit far fewer false positive blocks than through the header blooms.
+type AddressBloomIndexer struct {
+	size uint64 // section size to generate bloombits for
+
+	db  kokdb.Database       // database instance to read receipts from and write index data into
+	gen *bloombits.Generator // generator to rotate the bloom bits crating the bloom index
+
+	section uint64      // Section is the section number being processed currently
+	head    common.Hash // Head is the hash of the last header processed
+}
+
+// NewAddressBloomIndexer returns a chain indexer that generates address-only
+// bloom bits data for the canonical chain.
+func NewAddressBloomIndexer(db kokdb.Database, size uint64) *core.ChainIndexer {
+	backend := &AddressBloomIndexer{
+		db:   db,
+		size: size,
+	}
+	table := kokdb.NewTable(db, string(core.AddressBitsIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, bloomConfirms, bloomThrottling, "addressbits")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new bloombits index
+// section.
+func (b *AddressBloomIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	gen, err := bloombits.NewGenerator(uint(b.size))
+	b.gen, b.section, b.head = gen, section, common.Hash{}
+	return err
+}
+
+// Process implements core.ChainIndexerBackend, adding the bloom of the log
+// addresses of a new block into the index.
+func (b *AddressBloomIndexer) Process(header *types.Header) {
+	var addresses []*types.Log
+	if header.Bloom != (types.Bloom{}) {
+		for _, receipt := range core.GetBlockReceipts(b.db, header.Hash(), header.Number.Uint64()) {
+			for _, log := range receipt.Logs {
+				addresses = append(addresses, &types.Log{Address: log.Address})
+			}
+		}
+	}
+	b.gen.AddBloom(uint(header.Number.Uint64()-b.section*b.size), types.BytesToBloom(types.LogsBloom(addresses).Bytes()))
+	b.head = header.Hash()
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the bloom section and
+// writing it out into the database.
+func (b *AddressBloomIndexer) Commit() error {
+	batch := b.db.NewBatch()
+
+	for i := 0; i < types.BloomBitLength; i++ {
+		bits, err := b.gen.Bitset(uint(i))
+		if err != nil {
+			return err
+		}
+		core.WriteAddressBloomBits(batch, uint(i), b.section, b.head, bitutil.CompressBytes(bits))
+	}
+	return batch.Write()
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/bitutil"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+)
+
+// Tests that the address bloom indexer only indexes the emitting addresses of
+// the logs, leaving their topics out.
+func TestAddressBloomIndexer(t *testing.T) {
+	const size = 2048 // the generator can't rotate sections smaller than a bloom
+
+	db, _ := kokdb.NewMemDatabase()
+	indexer := &AddressBloomIndexer{db: db, size: size}
+	if err := indexer.Reset(0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	// Index a section of blocks, a few with a log of a distinct address and topic
+	expected := make([]types.Bloom, size)
+	for i := 0; i < size; i++ {
+		if i >= 8 {
+			indexer.Process(&types.Header{Number: big.NewInt(int64(i))})
+			continue
+		}
+		address := common.BytesToAddress([]byte{byte(i + 1)})
+		topic := common.BytesToHash([]byte{byte(i + 1), 0xff})
+
+		receipt := types.NewReceipt(nil, false, new(big.Int))
+		receipt.Logs = []*types.Log{{Address: address, Topics: []common.Hash{topic}}}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+		header := &types.Header{Number: big.NewInt(int64(i)), Bloom: receipt.Bloom}
+		core.WriteBlockReceipts(db, header.Hash(), header.Number.Uint64(), types.Receipts{receipt})
+		indexer.Process(header)
+
+		addressOnly := types.NewReceipt(nil, false, new(big.Int))
+		addressOnly.Logs = []*types.Log{{Address: address}}
+		expected[i] = types.CreateBloom(types.Receipts{addressOnly})
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit section: %v", err)
+	}
+	// Verify the rotated bits against the address-only blooms
+	for bit := 0; bit < types.BloomBitLength; bit++ {
+		comp, err := core.GetAddressBloomBits(db, uint(bit), 0, indexer.head)
+		if err != nil {
+			t.Fatalf("bit %d: failed to retrieve bits: %v", bit, err)
+		}
+		bits, err := bitutil.DecompressBytes(comp, size/8)
+		if err != nil {
+			t.Fatalf("bit %d: failed to decompress bits: %v", bit, err)
+		}
+		for block := 0; block < size; block++ {
+			have := bits[block/8]&(1<<uint(7-block%8)) != 0
+			want := expected[block][types.BloomByteLength-1-bit/8]&(1<<uint(bit%8)) != 0
+			if have != want {
+				t.Errorf("block %d, bit %d: indexed %v, want %v", block, bit, have, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/rlp"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+const (
+	// chainStatsSectionSize is the number of blocks aggregated into a single
+	// section of the chain statistics index.
+	chainStatsSectionSize = 4096
+
+	// chainStatsConfirms is the number of confirmation blocks before a statistics
+	// section is considered probably final and is aggregated.
+	chainStatsConfirms = 256
+
+	// chainStatsThrottling is the time to wait between processing two consecutive
+	// index sections.
+	chainStatsThrottling = 100 * time.Millisecond
+
+	// chainStatsMaxUnindexed is the maximum number of blocks not covered by the
+	// index that a single statistics query may aggregate on the fly.
+	chainStatsMaxUnindexed = 2 * chainStatsSectionSize
+)
+
+// chainStatsPrefix is the database table prefix of the chain statistics index.
+var chainStatsPrefix = []byte("cst-")
+
+// ChainStats contains aggregate statistics of a range of canonical blocks.
+type ChainStats struct {
+	FromBlock        hexutil.Uint64            `json:"fromBlock"`
+	ToBlock          hexutil.Uint64            `json:"toBlock"`
+	Blocks           hexutil.Uint64            `json:"blocks"`
+	Transactions     hexutil.Uint64            `json:"transactions"`
+	GasUsed          hexutil.Uint64            `json:"gasUsed"`
+	AvgBlockInterval float64                   `json:"avgBlockInterval"` // Seconds between consecutive blocks
+	TxTypes          map[string]hexutil.Uint64 `json:"txTypes"`
+	ActiveAddresses  hexutil.Uint64            `json:"activeAddresses"` // Distinct transaction senders and recipients
+}
+
+// chainStatsSection is the database representation of the statistics of an
+// index section.
+type chainStatsSection struct {
+	Txs       uint64
+	GasUsed   uint64
+	TxTypes   []uint64         // Transaction counts indexed by transaction type
+	Addresses []common.Address // Sorted distinct senders and recipients
+}
+
+// chainStatsAccumulator aggregates the statistics of a set of blocks.
+type chainStatsAccumulator struct {
+	txs       uint64
+	gasUsed   uint64
+	txTypes   []uint64
+	addresses map[common.Address]struct{}
+}
+
+func newChainStatsAccumulator() *chainStatsAccumulator {
+	return &chainStatsAccumulator{addresses: make(map[common.Address]struct{})}
+}
+
+// addBlock accumulates the statistics of a single block.
+func (acc *chainStatsAccumulator) addBlock(config *params.ChainConfig, header *types.Header, body *types.Body) {
+	acc.gasUsed += header.GasUsed.Uint64()
+	if body == nil {
+		return
+	}
+	signer := types.MakeSigner(config, header.Number)
+	for _, tx := range body.Transactions {
+		acc.txs++
+		for int(tx.Type()) >= len(acc.txTypes) {
+			acc.txTypes = append(acc.txTypes, 0)
+		}
+		acc.txTypes[tx.Type()]++
+
+		if from, err := types.Sender(signer, tx); err == nil {
+			acc.addresses[from] = struct{}{}
+		}
+		if to := tx.To(); to != nil {
+			acc.addresses[*to] = struct{}{}
+		}
+	}
+}
+
+// addSection accumulates the precomputed statistics of an index section.
+func (acc *chainStatsAccumulator) addSection(section *chainStatsSection) {
+	acc.txs += section.Txs
+	acc.gasUsed += section.GasUsed
+	for typ, count := range section.TxTypes {
+		for typ >= len(acc.txTypes) {
+			acc.txTypes = append(acc.txTypes, 0)
+		}
+		acc.txTypes[typ] += count
+	}
+	for _, addr := range section.Addresses {
+		acc.addresses[addr] = struct{}{}
+	}
+}
+
+// section converts the accumulated statistics into their database representation.
+func (acc *chainStatsAccumulator) section() *chainStatsSection {
+	addrs := make([]common.Address, 0, len(acc.addresses))
+	for addr := range acc.addresses {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	return &chainStatsSection{
+		Txs:       acc.txs,
+		GasUsed:   acc.gasUsed,
+		TxTypes:   acc.txTypes,
+		Addresses: addrs,
+	}
+}
+
+// chainStatsKey = "stats" + section (uint64 big endian) + section head hash
+func chainStatsKey(section uint64, head common.Hash) []byte {
+	key := make([]byte, 5+8+common.HashLength)
+	copy(key, "stats")
+	binary.BigEndian.PutUint64(key[5:], section)
+	copy(key[13:], head[:])
+	return key
+}
+
+// ChainStatsIndexer implements a core.ChainIndexer, aggregating the statistics of
+// fixed size sections of the canonical chain, so that statistics queries over
+// long block ranges only need to process the partially covered sections.
+type ChainStatsIndexer struct {
+	config *params.ChainConfig
+	db     kokdb.Database // Database to retrieve the block bodies from
+	table  kokdb.Database // Index table to write the section statistics into
+
+	acc     *chainStatsAccumulator // Statistics of the section being processed
+	section uint64                 // Section number being processed currently
+	head    common.Hash            // Hash of the last header processed
+}
+
+// NewChainStatsIndexer returns a chain indexer that aggregates transaction
+// statistics of the canonical chain.
+func NewChainStatsIndexer(db kokdb.Database, config *params.ChainConfig) *core.ChainIndexer {
+	table := kokdb.NewTable(db, string(chainStatsPrefix))
+	backend := &ChainStatsIndexer{
+		config: config,
+		db:     db,
+		table:  table,
+	}
+	return core.NewChainIndexer(db, table, backend, chainStatsSectionSize, chainStatsConfirms, chainStatsThrottling, "chainstats")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new statistics section.
+func (b *ChainStatsIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	b.acc, b.section, b.head = newChainStatsAccumulator(), section, common.Hash{}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the transactions of a new
+// block into the section statistics.
+func (b *ChainStatsIndexer) Process(header *types.Header) {
+	hash := header.Hash()
+	b.acc.addBlock(b.config, header, core.GetBody(b.db, hash, header.Number.Uint64()))
+	b.head = hash
+}
+
+// Commit implements core.ChainIndexerBackend, writing the section statistics
+// into the database.
+func (b *ChainStatsIndexer) Commit() error {
+	blob, err := rlp.EncodeToBytes(b.acc.section())
+	if err != nil {
+		return err
+	}
+	return b.table.Put(chainStatsKey(b.section, b.head), blob)
+}
+
+// chainStatsSectionAt retrieves the statistics of an index section, if the
+// section was indexed on the current canonical chain.
+func (s *kokereum) chainStatsSectionAt(section uint64) *chainStatsSection {
+	head := core.GetCanonicalHash(s.chainDb, (section+1)*chainStatsSectionSize-1)
+	if head == (common.Hash{}) || head != s.chainStatsIndexer.SectionHead(section) {
+		return nil
+	}
+	blob, err := kokdb.NewTable(s.chainDb, string(chainStatsPrefix)).Get(chainStatsKey(section, head))
+	if err != nil {
+		return nil
+	}
+	stats := new(chainStatsSection)
+	if err := rlp.DecodeBytes(blob, stats); err != nil {
+		return nil
+	}
+	return stats
+}
+
+// GetChainStats returns aggregate statistics of the canonical blocks in the
+// given range. Fully covered index sections are served from the statistics index,
+// whilst the remaining blocks are aggregated on the fly, of which there may be at
+// most 8192 per query.
+func (api *PublickokereumAPI) GetChainStats(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) (*ChainStats, error) {
+	resolve := func(number rpc.BlockNumber) uint64 {
+		if number < 0 {
+			return api.e.blockchain.CurrentBlock().NumberU64()
+		}
+		return uint64(number)
+	}
+	from, to := resolve(fromBlock), resolve(toBlock)
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d > %d", from, to)
+	}
+	first := api.e.blockchain.GkokeaderByNumber(from)
+	last := api.e.blockchain.GkokeaderByNumber(to)
+	if first == nil || last == nil {
+		return nil, fmt.Errorf("block range %d-%d not found", from, to)
+	}
+	// Gather the precomputed sections and make sure there's not too much left
+	sections := make(map[uint64]*chainStatsSection)
+	for section := (from + chainStatsSectionSize - 1) / chainStatsSectionSize; (section+1)*chainStatsSectionSize-1 <= to; section++ {
+		if stats := api.e.chainStatsSectionAt(section); stats != nil {
+			sections[section] = stats
+		}
+	}
+	if unindexed := to - from + 1 - uint64(len(sections))*chainStatsSectionSize; unindexed > chainStatsMaxUnindexed {
+		return nil, fmt.Errorf("block range not indexed yet (%d unindexed blocks, max %d)", unindexed, chainStatsMaxUnindexed)
+	}
+	// Aggregate the statistics of the range, block by block where needed
+	acc := newChainStatsAccumulator()
+	for number := from; number <= to; {
+		if stats, ok := sections[number/chainStatsSectionSize]; ok {
+			acc.addSection(stats)
+			number += chainStatsSectionSize
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header := api.e.blockchain.GkokeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		acc.addBlock(api.e.chainConfig, header, api.e.blockchain.GetBody(header.Hash()))
+		number++
+	}
+	stats := &ChainStats{
+		FromBlock:       hexutil.Uint64(from),
+		ToBlock:         hexutil.Uint64(to),
+		Blocks:          hexutil.Uint64(to - from + 1),
+		Transactions:    hexutil.Uint64(acc.txs),
+		GasUsed:         hexutil.Uint64(acc.gasUsed),
+		TxTypes:         make(map[string]hexutil.Uint64),
+		ActiveAddresses: hexutil.Uint64(len(acc.addresses)),
+	}
+	if to > from {
+		stats.AvgBlockInterval = float64(last.Time.Uint64()-first.Time.Uint64()) / float64(to-from)
+	}
+	for typ, count := range acc.txTypes {
+		if count > 0 {
+			stats.TxTypes[types.TxType(typ).String()] = hexutil.Uint64(count)
+		}
+	}
+	return stats, nil
+}
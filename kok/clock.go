@@ -0,0 +1,84 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kokprojects/go-kok/log"
+)
+
+const (
+	clockSamples      = 64               // Number of recent peer clock samples to estimate the offset from
+	clockMinSamples   = 3                // Minimum number of samples before warning about the offset
+	clockDriftWarn    = time.Second      // Estimated offset above which DPoS slots risk being mis-timed
+	clockWarnInterval = 10 * time.Minute // Minimum time between clock offset warnings
+)
+
+// clockEstimator estimates the offset of the local clock from the network time,
+// as the median of the offsets sampled from the handshakes of recent peers.
+type clockEstimator struct {
+	samples []time.Duration // Ring of the most recent offset samples
+	next    int             // Index in the ring of the next sample
+	warned  time.Time       // Time of the last warning about the offset
+
+	lock sync.Mutex
+}
+
+// add records the offset of a peer's clock from the local one.
+func (c *clockEstimator) add(offset time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.samples) < clockSamples {
+		c.samples = append(c.samples, offset)
+	} else {
+		c.samples[c.next] = offset
+	}
+	c.next = (c.next + 1) % clockSamples
+
+	// Warn about the local clock being off enough to mis-time slots
+	if len(c.samples) < clockMinSamples || time.Since(c.warned) < clockWarnInterval {
+		return
+	}
+	if drift := c.offset(); drift > clockDriftWarn || drift < -clockDriftWarn {
+		log.Warn("System clock seems off compared to peers, DPoS slots may be mis-timed", "offset", -drift, "peers", len(c.samples))
+		c.warned = time.Now()
+	}
+}
+
+// Offset returns the estimated offset of the network time from the local clock,
+// along with the number of samples it was estimated from.
+func (c *clockEstimator) Offset() (time.Duration, int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.offset(), len(c.samples)
+}
+
+// offset computes the median of the samples. The lock must be held.
+func (c *clockEstimator) offset() time.Duration {
+	if len(c.samples) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(c.samples))
+	copy(sorted, c.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
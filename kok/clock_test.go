@@ -0,0 +1,100 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kokprojects/go-kok/kok/downloader"
+	"github.com/kokprojects/go-kok/p2p"
+)
+
+// Tests that the clock offset is estimated as the median of the recent samples.
+func TestClockEstimator(t *testing.T) {
+	var c clockEstimator
+	if offset, samples := c.Offset(); offset != 0 || samples != 0 {
+		t.Fatalf("empty estimator: have offset %v from %d samples, want 0 from 0", offset, samples)
+	}
+	for _, offset := range []time.Duration{time.Second, -time.Hour, 2 * time.Second, 3 * time.Second, time.Hour} {
+		c.add(offset)
+	}
+	if offset, samples := c.Offset(); offset != 2*time.Second || samples != 5 {
+		t.Fatalf("offset mismatch: have %v from %d samples, want %v from 5", offset, samples, 2*time.Second)
+	}
+	// Ensure old samples are evicted
+	for i := 0; i < clockSamples; i++ {
+		c.add(-time.Second)
+	}
+	if offset, samples := c.Offset(); offset != -time.Second || samples != clockSamples {
+		t.Fatalf("offset mismatch: have %v from %d samples, want %v from %d", offset, samples, -time.Second, clockSamples)
+	}
+}
+
+// Tests that the clocks of kok/65 peers are sampled during the handshake, with
+// the network delay compensated for.
+func TestHandshakeClockSample(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	p, _ := newTestPeer("peer", kok65, pm, false)
+	defer p.close()
+
+	// Receive the status, simulating a one way delay of 200ms
+	msg, err := p.app.ReadMsg()
+	if err != nil {
+		t.Fatalf("status recv: %v", err)
+	}
+	var status statusData
+	if err := msg.Decode(&status); err != nil {
+		t.Fatalf("status decode: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	received := time.Now()
+
+	if err := p2p.Send(p.app, StatusMsg, &status); err != nil {
+		t.Fatalf("status send: %v", err)
+	}
+	if msg, err = p.app.ReadMsg(); err != nil || msg.Code != ClockMsg {
+		t.Fatalf("clock recv: code %x, err %v", msg.Code, err)
+	}
+	msg.Discard()
+
+	// Reply with a clock running five seconds ahead, and delay the reply too
+	skew := 5 * time.Second
+	reply := &clockData{
+		Received: uint64(received.Add(skew).UnixNano()),
+		Sent:     uint64(time.Now().Add(skew).UnixNano()),
+	}
+	time.Sleep(200 * time.Millisecond)
+	if err := p2p.Send(p.app, ClockMsg, reply); err != nil {
+		t.Fatalf("clock send: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if offset, samples := pm.clock.Offset(); samples > 0 {
+			if offset < skew-50*time.Millisecond || offset > skew+50*time.Millisecond {
+				t.Fatalf("offset mismatch: have %v, want ~%v", offset, skew)
+			}
+			if info := pm.NodeInfo(); info.ClockSamples != 1 {
+				t.Fatalf("node info samples mismatch: have %d, want 1", info.ClockSamples)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("clock not sampled")
+}
@@ -24,7 +24,9 @@ import (
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/common/hexutil"
 	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/kok/archive"
 	"github.com/kokprojects/go-kok/kok/downloader"
+	"github.com/kokprojects/go-kok/kok/filters"
 	"github.com/kokprojects/go-kok/kok/gasprice"
 	"github.com/kokprojects/go-kok/params"
 )
@@ -37,11 +39,16 @@ var DefaultConfig = Config{
 	DatabaseCache: 128,
 	GasPrice:      big.NewInt(18 * params.Shannon),
 
-	TxPool: core.DefaultTxPoolConfig,
+	TxPool:  core.DefaultTxPoolConfig,
+	Pruning: core.DefaultPruningConfig,
 	GPO: gasprice.Config{
 		Blocks:     10,
 		Percentile: 50,
 	},
+	Filters: filters.DefaultConfig,
+	Archive: archive.DefaultConfig,
+
+	BloomSectionSize: params.BloomBitsBlocks,
 }
 
 func init() {
@@ -65,20 +72,35 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 
 	// Light client options
-	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
-	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
+	LightServ  int  `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
+	LightPeers int  `toml:",omitempty"` // Maximum number of LES client peers
+	LightSign  bool `toml:",omitempty"` // Sign served LES responses with the node key
+
+	// LightMinimal makes a light client follow the chain head only through the
+	// signed announcements of its static servers, without looking for others or
+	// downloading the headers in between.
+	LightMinimal bool `toml:",omitempty"`
 
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
 
+	// Number of blocks whose state is kept in memory before being flushed to disk
+	TrieCommitInterval uint64 `toml:",omitempty"`
+
+	// State trie garbage collection options
+	Pruning core.PruningConfig
+
 	// Mining-related options
-	Validator    common.Address `toml:",omitempty"`
-	Coinbase     common.Address `toml:",omitempty"`
-	MinerThreads int            `toml:",omitempty"`
-	ExtraData    []byte         `toml:",omitempty"`
-	GasPrice     *big.Int
+	Validator     common.Address `toml:",omitempty"`
+	Coinbase      common.Address `toml:",omitempty"`
+	MinerThreads  int            `toml:",omitempty"`
+	ExtraData     []byte         `toml:",omitempty"`
+	GasPrice      *big.Int
+	MinerPolicy   string `toml:",omitempty"` // Path of the local transaction inclusion policy file
+	MinerOrdering string `toml:",omitempty"` // Transaction ordering strategy of the produced blocks
+	SkipOffline   bool   `toml:",omitempty"` // Stop waiting for the blocks of validators detected offline
 
 	// Transaction pool options
 	TxPool core.TxPoolConfig
@@ -86,9 +108,42 @@ type Config struct {
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
+	// Log filtering options
+	Filters filters.Config
+
+	// Number of blocks covered by a section of the bloom bits index
+	BloomSectionSize uint64 `toml:",omitempty"`
+
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables indexing the balance changes made by each transaction
+	EnableBalanceIndex bool
+
+	// Enables indexing the value transfers made by contracts
+	EnableInternalTxIndex bool
+
+	// Enables indexing the transactions sent and received by each account
+	EnableTxAddressIndex bool
+
+	// Enables storing the return data of reverted transactions
+	EnableRevertReasonIndex bool
+
+	// Enables auditing the balance and gas refund accounting of each transaction
+	EnableAudit bool
+
+	// Listening endpoint of the read-only REST gateway, disabled if empty
+	RESTEndpoint string `toml:",omitempty"`
+
+	// Listening endpoint of the GraphQL query service, disabled if empty
+	GraphQLEndpoint string `toml:",omitempty"`
+
+	// Origins from which to accept cross-origin GraphQL queries (browser enforced)
+	GraphQLCors []string `toml:",omitempty"`
+
+	// Archival of the finalized chain into object storage
+	Archive archive.Config
+
 	// Miscellaneous options
 	DocRoot   string `toml:"-"`
 	PowFake   bool   `toml:"-"`
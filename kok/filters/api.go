@@ -27,21 +27,21 @@ import (
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core"
 	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/internal/kokapi"
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/event"
 	"github.com/kokprojects/go-kok/rpc"
 )
 
-var (
-	deadline = 5 * time.Minute // consider a filter inactive if it has not been polled for within deadline
-)
-
 // filter is a helper struct that holds meta information over the filter type
 // and associated subscription in the event system.
 type filter struct {
 	typ      Type
 	deadline *time.Timer // filter is inactiv when deadline triggers
+	polled   time.Time   // last time the filter was created or polled
+	session  string      // client session the filter belongs to
 	hashes   []common.Hash
 	crit     FilterCriteria
 	logs     []*types.Log
@@ -58,26 +58,38 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+	persisted map[rpc.ID]*persistedFilter // log filters kept across restarts
+	config    Config
+
+	sessionsMu sync.Mutex
+	sessions   map[string]*session
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
+func NewPublicFilterAPI(backend Backend, lightMode bool, config Config) *PublicFilterAPI {
+	if config.FilterTimeout <= 0 {
+		config.FilterTimeout = DefaultConfig.FilterTimeout
+	}
 	api := &PublicFilterAPI{
-		backend: backend,
-		mux:     backend.EventMux(),
-		chainDb: backend.ChainDb(),
-		events:  NewEventSystem(backend.EventMux(), backend, lightMode),
-		filters: make(map[rpc.ID]*filter),
+		config:   config,
+		backend:  backend,
+		mux:      backend.EventMux(),
+		chainDb:  backend.ChainDb(),
+		events:   NewEventSystem(backend.EventMux(), backend, lightMode),
+		filters:   make(map[rpc.ID]*filter),
+		persisted: make(map[rpc.ID]*persistedFilter),
+		sessions:  make(map[string]*session),
 	}
+	api.restoreFilters()
 	go api.timeoutLoop()
 
 	return api
 }
 
-// timeoutLoop runs every 5 minutes and deletes filters that have not been recently used.
-// Tt is started when the api is created.
+// timeoutLoop runs every filter timeout period and deletes filters that have not
+// been recently used. Tt is started when the api is created.
 func (api *PublicFilterAPI) timeoutLoop() {
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(api.config.FilterTimeout)
 	for {
 		<-ticker.C
 		api.filtersMu.Lock()
@@ -85,7 +97,7 @@ func (api *PublicFilterAPI) timeoutLoop() {
 			select {
 			case <-f.deadline.C:
 				f.s.Unsubscribe()
-				delete(api.filters, id)
+				api.dropFilter(id)
 			default:
 				continue
 			}
@@ -94,6 +106,34 @@ func (api *PublicFilterAPI) timeoutLoop() {
 	}
 }
 
+// installFilter registers a polled filter on a previously reserved quota slot of
+// its session.
+func (api *PublicFilterAPI) installFilter(f *filter, typ string) {
+	f.deadline = time.NewTimer(api.config.FilterTimeout)
+	f.polled = time.Now()
+
+	api.filtersMu.Lock()
+	api.filters[f.s.ID] = f
+	api.filtersMu.Unlock()
+
+	api.register(f.session, &FilterInfo{ID: f.s.ID, Type: typ, Polled: true, Created: f.polled})
+}
+
+// dropFilter removes a polled filter, releasing its quota slot. It must be called
+// with filtersMu held.
+func (api *PublicFilterAPI) dropFilter(id rpc.ID) (*filter, bool) {
+	f, found := api.filters[id]
+	if found {
+		delete(api.filters, id)
+		api.release(f.session, id)
+	}
+	if _, persisted := api.persisted[id]; persisted {
+		delete(api.persisted, id)
+		api.savePersistedFilters()
+	}
+	return f, found
+}
+
 // NewPendingTransactionFilter creates a filter that fetches pending transaction hashes
 // as transactions enter the pending state.
 //
@@ -101,55 +141,70 @@ func (api *PublicFilterAPI) timeoutLoop() {
 // `kok_getFilterChanges` polling mkokod that is also used for log filters.
 //
 // https://github.com/kokereum/wiki/wiki/JSON-RPC#kok_newpendingtransactionfilter
-func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
+func (api *PublicFilterAPI) NewPendingTransactionFilter(ctx context.Context) (rpc.ID, error) {
+	session := sessionOf(ctx)
+	if err := api.reserve(session); err != nil {
+		return rpc.ID(""), err
+	}
 	var (
-		pendingTxs   = make(chan common.Hash)
-		pendingTxSub = api.events.SubscribePendingTxEvents(pendingTxs)
+		pendingTxs   = make(chan *types.Transaction)
+		pendingTxSub = api.events.SubscribePendingTxs(pendingTxs)
+		closed       = sessionClosed(ctx)
 	)
-
-	api.filtersMu.Lock()
-	api.filters[pendingTxSub.ID] = &filter{typ: PendingTransactionsSubscription, deadline: time.NewTimer(deadline), hashes: make([]common.Hash, 0), s: pendingTxSub}
-	api.filtersMu.Unlock()
+	api.installFilter(&filter{typ: PendingTransactionsSubscription, session: session, hashes: make([]common.Hash, 0), s: pendingTxSub}, "pendingTransactions")
 
 	go func() {
 		for {
 			select {
-			case ph := <-pendingTxs:
+			case tx := <-pendingTxs:
 				api.filtersMu.Lock()
 				if f, found := api.filters[pendingTxSub.ID]; found {
-					f.hashes = append(f.hashes, ph)
+					f.hashes = append(f.hashes, tx.Hash())
 				}
 				api.filtersMu.Unlock()
+			case <-closed:
+				closed = nil
+				api.UninstallFilter(pendingTxSub.ID)
 			case <-pendingTxSub.Err():
 				api.filtersMu.Lock()
-				delete(api.filters, pendingTxSub.ID)
+				api.dropFilter(pendingTxSub.ID)
 				api.filtersMu.Unlock()
 				return
 			}
 		}
 	}()
 
-	return pendingTxSub.ID
+	return pendingTxSub.ID, nil
 }
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// The transaction hash is sent unless fullTx is set, in which case the whole transaction is.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
 
-	rpcSub := notifier.CreateSubscription()
+	rpcSub, release, err := api.subscribe(ctx, notifier, "newPendingTransactions")
+	if err != nil {
+		return &rpc.Subscription{}, err
+	}
 
 	go func() {
-		txHashes := make(chan common.Hash)
-		pendingTxSub := api.events.SubscribePendingTxEvents(txHashes)
+		defer release()
+
+		txs := make(chan *types.Transaction)
+		pendingTxSub := api.events.SubscribePendingTxs(txs)
 
 		for {
 			select {
-			case h := <-txHashes:
-				notifier.Notify(rpcSub.ID, h)
+			case tx := <-txs:
+				if fullTx != nil && *fullTx {
+					notifier.Notify(rpcSub.ID, kokapi.NewRPCPendingTransaction(tx))
+				} else {
+					notifier.Notify(rpcSub.ID, tx.Hash())
+				}
 			case <-rpcSub.Err():
 				pendingTxSub.Unsubscribe()
 				return
@@ -163,19 +218,58 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// DroppedTransactions creates a subscription that is triggered each time a
+// transaction is dropped from the transaction pool without being mined, along
+// with the reason it was dropped for.
+func (api *PublicFilterAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub, release, err := api.subscribe(ctx, notifier, "droppedTransactions")
+	if err != nil {
+		return &rpc.Subscription{}, err
+	}
+
+	go func() {
+		defer release()
+
+		drops := make(chan core.TxDropEvent, txChanSize)
+		dropSub := api.backend.SubscribeTxDropEvent(drops)
+
+		for {
+			select {
+			case ev := <-drops:
+				notifier.Notify(rpcSub.ID, ev.Dropped)
+			case <-rpcSub.Err():
+				dropSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				dropSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with kok_getFilterChanges.
 //
 // https://github.com/kokereum/wiki/wiki/JSON-RPC#kok_newblockfilter
-func (api *PublicFilterAPI) NewBlockFilter() rpc.ID {
+func (api *PublicFilterAPI) NewBlockFilter(ctx context.Context) (rpc.ID, error) {
+	session := sessionOf(ctx)
+	if err := api.reserve(session); err != nil {
+		return rpc.ID(""), err
+	}
 	var (
 		headers   = make(chan *types.Header)
 		headerSub = api.events.SubscribeNewHeads(headers)
+		closed    = sessionClosed(ctx)
 	)
-
-	api.filtersMu.Lock()
-	api.filters[headerSub.ID] = &filter{typ: BlocksSubscription, deadline: time.NewTimer(deadline), hashes: make([]common.Hash, 0), s: headerSub}
-	api.filtersMu.Unlock()
+	api.installFilter(&filter{typ: BlocksSubscription, session: session, hashes: make([]common.Hash, 0), s: headerSub}, "blocks")
 
 	go func() {
 		for {
@@ -186,16 +280,19 @@ func (api *PublicFilterAPI) NewBlockFilter() rpc.ID {
 					f.hashes = append(f.hashes, h.Hash())
 				}
 				api.filtersMu.Unlock()
+			case <-closed:
+				closed = nil
+				api.UninstallFilter(headerSub.ID)
 			case <-headerSub.Err():
 				api.filtersMu.Lock()
-				delete(api.filters, headerSub.ID)
+				api.dropFilter(headerSub.ID)
 				api.filtersMu.Unlock()
 				return
 			}
 		}
 	}()
 
-	return headerSub.ID
+	return headerSub.ID, nil
 }
 
 // NewHeads send a notification each time a new (header) block is appended to the chain.
@@ -205,9 +302,14 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
 
-	rpcSub := notifier.CreateSubscription()
+	rpcSub, release, err := api.subscribe(ctx, notifier, "newHeads")
+	if err != nil {
+		return &rpc.Subscription{}, err
+	}
 
 	go func() {
+		defer release()
+
 		headers := make(chan *types.Header)
 		headersSub := api.events.SubscribeNewHeads(headers)
 
@@ -235,17 +337,19 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
 
-	var (
-		rpcSub      = notifier.CreateSubscription()
-		matchedLogs = make(chan []*types.Log)
-	)
-
+	rpcSub, release, err := api.subscribe(ctx, notifier, "logs")
+	if err != nil {
+		return &rpc.Subscription{}, err
+	}
+	matchedLogs := make(chan []*types.Log)
 	logsSub, err := api.events.SubscribeLogs(crit, matchedLogs)
 	if err != nil {
+		release()
 		return nil, err
 	}
 
 	go func() {
+		defer release()
 
 		for {
 			select {
@@ -287,16 +391,37 @@ type FilterCriteria struct {
 // In case "fromBlock" > "toBlock" an error is returned.
 //
 // https://github.com/kokereum/wiki/wiki/JSON-RPC#kok_newfilter
-func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
+func (api *PublicFilterAPI) NewFilter(ctx context.Context, crit FilterCriteria) (rpc.ID, error) {
+	session := sessionOf(ctx)
+	if err := api.reserve(session); err != nil {
+		return rpc.ID(""), err
+	}
+	closed := sessionClosed(ctx)
+	id, err := api.newLogFilter(session, "", crit, closed, nil)
+	if err != nil {
+		api.release(session, "")
+		return rpc.ID(""), err
+	}
+	// Filters outliving their connection may be resumed after a restart
+	if api.config.PersistFilters && closed == nil {
+		api.persistFilter(id, session, crit)
+	}
+	return id, nil
+}
+
+// newLogFilter installs a polled log filter on a reserved quota slot of a session,
+// under the given id or a fresh one if empty, with a backlog of logs to return on
+// the first poll. The filter is uninstalled when the closed channel fires.
+func (api *PublicFilterAPI) newLogFilter(session string, id rpc.ID, crit FilterCriteria, closed <-chan interface{}, backlog []*types.Log) (rpc.ID, error) {
 	logs := make(chan []*types.Log)
 	logsSub, err := api.events.SubscribeLogs(crit, logs)
 	if err != nil {
 		return rpc.ID(""), err
 	}
-
-	api.filtersMu.Lock()
-	api.filters[logsSub.ID] = &filter{typ: LogsSubscription, crit: crit, deadline: time.NewTimer(deadline), logs: make([]*types.Log, 0), s: logsSub}
-	api.filtersMu.Unlock()
+	if id != "" {
+		logsSub.ID = id
+	}
+	api.installFilter(&filter{typ: LogsSubscription, session: session, crit: crit, logs: append(make([]*types.Log, 0), backlog...), s: logsSub}, "logs")
 
 	go func() {
 		for {
@@ -307,9 +432,12 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 					f.logs = append(f.logs, l...)
 				}
 				api.filtersMu.Unlock()
+			case <-closed:
+				closed = nil
+				api.UninstallFilter(logsSub.ID)
 			case <-logsSub.Err():
 				api.filtersMu.Lock()
-				delete(api.filters, logsSub.ID)
+				api.dropFilter(logsSub.ID)
 				api.filtersMu.Unlock()
 				return
 			}
@@ -330,6 +458,9 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 	if crit.ToBlock == nil {
 		crit.ToBlock = big.NewInt(rpc.LatestBlockNumber.Int64())
 	}
+	if err := api.limitLogs(ctx, crit.FromBlock.Int64(), crit.ToBlock.Int64()); err != nil {
+		return nil, err
+	}
 	// Create and run the filter to get all the logs
 	filter := New(api.backend, crit.FromBlock.Int64(), crit.ToBlock.Int64(), crit.Addresses, crit.Topics)
 
@@ -337,18 +468,34 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 	if err != nil {
 		return nil, err
 	}
+	if err := api.checkResults(logs); err != nil {
+		return nil, err
+	}
 	return returnLogs(logs), err
 }
 
+// limitLogs ensures a log query over the given block range is within the
+// configured limits and charges its RPC cost.
+func (api *PublicFilterAPI) limitLogs(ctx context.Context, begin, end int64) error {
+	from, to, err := api.resolveRange(ctx, begin, end)
+	if err != nil {
+		return err
+	}
+	if to < from {
+		return nil
+	}
+	if err := api.checkRange(from, to); err != nil {
+		return err
+	}
+	return kokapi.ChargeLogsCost(ctx, to-from+1)
+}
+
 // UninstallFilter removes the filter with the given filter id.
 //
 // https://github.com/kokereum/wiki/wiki/JSON-RPC#kok_uninstallfilter
 func (api *PublicFilterAPI) UninstallFilter(id rpc.ID) bool {
 	api.filtersMu.Lock()
-	f, found := api.filters[id]
-	if found {
-		delete(api.filters, id)
-	}
+	f, found := api.dropFilter(id)
 	api.filtersMu.Unlock()
 	if found {
 		f.s.Unsubscribe()
@@ -378,6 +525,9 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	if f.crit.ToBlock != nil {
 		end = f.crit.ToBlock.Int64()
 	}
+	if err := api.limitLogs(ctx, begin, end); err != nil {
+		return nil, err
+	}
 	// Create and run the filter to get all the logs
 	filter := New(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
 
@@ -385,6 +535,9 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	if err != nil {
 		return nil, err
 	}
+	if err := api.checkResults(logs); err != nil {
+		return nil, err
+	}
 	return returnLogs(logs), nil
 }
 
@@ -405,7 +558,13 @@ func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
 			// receive timer value and reset timer
 			<-f.deadline.C
 		}
-		f.deadline.Reset(deadline)
+		f.deadline.Reset(api.config.FilterTimeout)
+		f.polled = time.Now()
+
+		if pf, persisted := api.persisted[id]; persisted {
+			pf.Head, pf.Polled = api.currentHead(), uint64(f.polled.Unix())
+			api.savePersistedFilters()
+		}
 
 		switch f.typ {
 		case PendingTransactionsSubscription, BlocksSubscription:
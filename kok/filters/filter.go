@@ -36,6 +36,7 @@ type Backend interface {
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 
 	SubscribeTxPreEvent(chan<- core.TxPreEvent) event.Subscription
+	SubscribeTxDropEvent(chan<- core.TxDropEvent) event.Subscription
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
@@ -44,6 +45,14 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// AddressIndexBackend is implemented by backends maintaining a secondary bloombits
+// index of the log addresses alone, which serves queries not filtering on topics
+// with far fewer false positives than the header blooms.
+type AddressIndexBackend interface {
+	AddressBloomStatus() (uint64, uint64)
+	ServiceAddressFilter(ctx context.Context, session *bloombits.MatcherSession)
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -53,7 +62,9 @@ type Filter struct {
 	addresses  []common.Address
 	topics     [][]common.Hash
 
-	matcher *bloombits.Matcher
+	matcher       *bloombits.Matcher
+	bloomStatus   func() (uint64, uint64)                          // Status of the bloombits index used
+	serviceFilter func(context.Context, *bloombits.MatcherSession) // Retrieval service of the bloombits index used
 }
 
 // New creates a new filter which uses a bloom filter on blocks to figure out whkoker
@@ -78,17 +89,37 @@ func New(backend Backend, begin, end int64, addresses []common.Address, topics [
 		filters = append(filters, filter)
 	}
 	// Assemble and return the filter
-	size, _ := backend.BloomStatus()
+	size, sections := backend.BloomStatus()
 
-	return &Filter{
-		backend:   backend,
-		begin:     begin,
-		end:       end,
-		addresses: addresses,
-		topics:    topics,
-		db:        backend.ChainDb(),
-		matcher:   bloombits.NewMatcher(size, filters),
+	filter := &Filter{
+		backend:       backend,
+		begin:         begin,
+		end:           end,
+		addresses:     addresses,
+		topics:        topics,
+		db:            backend.ChainDb(),
+		matcher:       bloombits.NewMatcher(size, filters),
+		bloomStatus:   backend.BloomStatus,
+		serviceFilter: backend.ServiceFilter,
+	}
+	// Filter on the address blooms if the topics don't matter and the address
+	// index has caught up with the header one
+	if index, ok := backend.(AddressIndexBackend); ok && len(addresses) > 0 && wildcardTopics(topics) {
+		if _, indexed := index.AddressBloomStatus(); indexed > 0 && indexed >= sections {
+			filter.bloomStatus, filter.serviceFilter = index.AddressBloomStatus, index.ServiceAddressFilter
+		}
 	}
+	return filter
+}
+
+// wildcardTopics reports whether the topic criteria match any log.
+func wildcardTopics(topics [][]common.Hash) bool {
+	for _, topicList := range topics {
+		if len(topicList) > 0 {
+			return false
+		}
+	}
+	return true
 }
 
 // Logs searches the blockchain for matching log entries, returning all from the
@@ -113,7 +144,7 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		logs []*types.Log
 		err  error
 	)
-	size, sections := f.backend.BloomStatus()
+	size, sections := f.bloomStatus()
 	if indexed := sections * size; indexed > uint64(f.begin) {
 		if indexed > end {
 			logs, err = f.indexedLogs(ctx, end)
@@ -141,7 +172,7 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	}
 	defer session.Close()
 
-	f.backend.ServiceFilter(ctx, session)
+	f.serviceFilter(ctx, session)
 
 	// Iterate over the matches until exhausted or context closed
 	var logs []*types.Log
@@ -43,6 +43,7 @@ type testBackend struct {
 	rmLogsFeed *event.Feed
 	logsFeed   *event.Feed
 	chainFeed  *event.Feed
+	dropFeed   *event.Feed
 }
 
 func (b *testBackend) ChainDb() kokdb.Database {
@@ -75,6 +76,10 @@ func (b *testBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subsc
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeTxDropEvent(ch chan<- core.TxDropEvent) event.Subscription {
+	return b.dropFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
@@ -133,8 +138,8 @@ func TestBlockSubscription(t *testing.T) {
 		rmLogsFeed  = new(event.Feed)
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
-		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api         = NewPublicFilterAPI(backend, false)
+		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		api         = NewPublicFilterAPI(backend, false, DefaultConfig)
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, db, 10, func(i int, gen *core.BlockGen) {})
 		chainEvents = []core.ChainEvent{}
@@ -190,8 +195,8 @@ func TestPendingTxFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		api        = NewPublicFilterAPI(backend, false, DefaultConfig)
 
 		transactions = []*types.Transaction{
 			types.NewTransaction(types.Binary, 0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), new(big.Int), new(big.Int), nil),
@@ -204,7 +209,7 @@ func TestPendingTxFilter(t *testing.T) {
 		hashes []common.Hash
 	)
 
-	fid0 := api.NewPendingTransactionFilter()
+	fid0, _ := api.NewPendingTransactionFilter(context.Background())
 
 	time.Sleep(1 * time.Second)
 	for _, tx := range transactions {
@@ -243,6 +248,68 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestPendingTxSubscription tests whkoker pending tx subscriptions deliver either the
+// hashes or the full transactions entering the pool, as requested.
+func TestPendingTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db, _      = kokdb.NewMemDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		api        = NewPublicFilterAPI(backend, false, DefaultConfig)
+
+		tx = types.NewTransaction(types.Binary, 7, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), big.NewInt(3), big.NewInt(21000), big.NewInt(1), []byte{0x01})
+	)
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("kok", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	hashes := make(chan common.Hash)
+	hashSub, err := client.kokSubscribe(context.Background(), hashes, "newPendingTransactions")
+	if err != nil {
+		t.Fatalf("failed to subscribe to pending hashes: %v", err)
+	}
+	defer hashSub.Unsubscribe()
+
+	full := make(chan map[string]interface{})
+	fullSub, err := client.kokSubscribe(context.Background(), full, "newPendingTransactions", true)
+	if err != nil {
+		t.Fatalf("failed to subscribe to pending transactions: %v", err)
+	}
+	defer fullSub.Unsubscribe()
+
+	time.Sleep(100 * time.Millisecond)
+	txFeed.Send(core.TxPreEvent{Tx: tx})
+
+	timeout := time.After(time.Second)
+	for received := 0; received < 2; received++ {
+		select {
+		case hash := <-hashes:
+			if hash != tx.Hash() {
+				t.Errorf("hash mismatch: have %x, want %x", hash, tx.Hash())
+			}
+		case fields := <-full:
+			if fields["hash"] != tx.Hash().Hex() {
+				t.Errorf("transaction hash mismatch: have %v, want %x", fields["hash"], tx.Hash())
+			}
+			if fields["nonce"] != "0x7" || fields["input"] != "0x01" {
+				t.Errorf("transaction body mismatch: have nonce %v input %v", fields["nonce"], fields["input"])
+			}
+		case <-timeout:
+			t.Fatalf("pending transaction not delivered to all subscriptions, got %d", received)
+		}
+	}
+}
+
 // TestLogFilterCreation test whkoker a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -253,8 +320,8 @@ func TestLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		api        = NewPublicFilterAPI(backend, false, DefaultConfig)
 
 		testCases = []struct {
 			crit    FilterCriteria
@@ -280,7 +347,7 @@ func TestLogFilterCreation(t *testing.T) {
 	)
 
 	for i, test := range testCases {
-		_, err := api.NewFilter(test.crit)
+		_, err := api.NewFilter(context.Background(), test.crit)
 		if test.success && err != nil {
 			t.Errorf("expected filter creation for case %d to success, got %v", i, err)
 		}
@@ -302,8 +369,8 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		api        = NewPublicFilterAPI(backend, false, DefaultConfig)
 	)
 
 	// different situations where log filter creation should fail.
@@ -315,12 +382,61 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 	}
 
 	for i, test := range testCases {
-		if _, err := api.NewFilter(test); err == nil {
+		if _, err := api.NewFilter(context.Background(), test); err == nil {
 			t.Errorf("Expected NewFilter for case #%d to fail", i)
 		}
 	}
 }
 
+// TestFilterQuota tests that a client session can't install more filters than
+// allowed, and that uninstalled filters free up their quota slot.
+func TestFilterQuota(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db, _      = kokdb.NewMemDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		api        = NewPublicFilterAPI(backend, false, Config{MaxFilters: 2, FilterTimeout: time.Minute})
+		ctx        = context.Background()
+	)
+	blockID, err := api.NewBlockFilter(ctx)
+	if err != nil {
+		t.Fatalf("failed to install block filter: %v", err)
+	}
+	if _, err := api.NewFilter(ctx, FilterCriteria{}); err != nil {
+		t.Fatalf("failed to install log filter: %v", err)
+	}
+	// Invalid filters don't leak quota slots, exhausted quotas are reported
+	invalid := FilterCriteria{FromBlock: big.NewInt(rpc.PendingBlockNumber.Int64()), ToBlock: big.NewInt(100)}
+	if _, err := api.NewFilter(ctx, invalid); err == nil {
+		t.Fatalf("invalid log filter installed")
+	}
+	if _, err := api.NewPendingTransactionFilter(ctx); err == nil {
+		t.Fatalf("filter installed beyond quota")
+	} else if _, ok := err.(*FilterQuotaError); !ok {
+		t.Fatalf("quota error mismatch: have %v", err)
+	}
+	infos := api.ActiveFilters(ctx)
+	if len(infos) != 2 {
+		t.Fatalf("active filter count mismatch: have %d, want 2", len(infos))
+	}
+	if infos[0].ID != blockID || infos[0].Type != "blocks" || !infos[0].Polled || infos[0].Expires == nil {
+		t.Errorf("block filter info mismatch: have %+v", infos[0])
+	}
+	// Uninstalling a filter makes room for a new one
+	if !api.UninstallFilter(blockID) {
+		t.Fatalf("failed to uninstall block filter")
+	}
+	if _, err := api.NewPendingTransactionFilter(ctx); err != nil {
+		t.Fatalf("failed to install filter after uninstall: %v", err)
+	}
+}
+
 // TestLogFilter tests whkoker log filters match the correct logs that are posted to the event feed.
 func TestLogFilter(t *testing.T) {
 	t.Parallel()
@@ -332,8 +448,8 @@ func TestLogFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		api        = NewPublicFilterAPI(backend, false, DefaultConfig)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -391,7 +507,7 @@ func TestLogFilter(t *testing.T) {
 
 	// create all filters
 	for i := range testCases {
-		testCases[i].id, _ = api.NewFilter(testCases[i].crit)
+		testCases[i].id, _ = api.NewFilter(context.Background(), testCases[i].crit)
 	}
 
 	// raise events
@@ -451,8 +567,8 @@ func TestPendingLogsSubscription(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		api        = NewPublicFilterAPI(backend, false, DefaultConfig)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -18,10 +18,13 @@ package filters
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/core"
@@ -30,6 +33,7 @@ import (
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/event"
 	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/rpc"
 )
 
 func makeReceipt(addr common.Address) *types.Receipt {
@@ -55,7 +59,7 @@ func BenchmarkFilters(b *testing.B) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr1      = crypto.PubkeyToAddress(key1.PublicKey)
 		addr2      = common.BytesToAddress([]byte("jeff"))
@@ -120,7 +124,7 @@ func TestFilters(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr       = crypto.PubkeyToAddress(key1.PublicKey)
 
@@ -240,3 +244,289 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+func TestGetLogsPaged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filtertest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		db, _      = kokdb.NewLDBDatabase(dir, 0, 0)
+		mux        = new(event.TypeMux)
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr       = crypto.PubkeyToAddress(key1.PublicKey)
+	)
+	defer db.Close()
+
+	// Create a chain with three logs in each of its ten blocks
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, db, 10, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, new(big.Int))
+		for j := 0; j < 3; j++ {
+			receipt.Logs = append(receipt.Logs, &types.Log{Address: addr, BlockNumber: uint64(i + 1), Index: uint(j)})
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		gen.AddUncheckedReceipt(receipt)
+	})
+	for i, block := range chain {
+		core.WriteBlock(db, block)
+		if err := core.WriteCanonicalHash(db, block.Hash(), block.NumberU64()); err != nil {
+			t.Fatalf("failed to insert block number: %v", err)
+		}
+		if err := core.WriteHeadBlockHash(db, block.Hash()); err != nil {
+			t.Fatalf("failed to insert block number: %v", err)
+		}
+		if err := core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i]); err != nil {
+			t.Fatal("error writing block receipts:", err)
+		}
+	}
+	api := NewPublicFilterAPI(backend, false, Config{MaxBlockRange: 4, MaxResults: 5})
+	crit := FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(10), Addresses: []common.Address{addr}}
+
+	// Unpaginated queries exceeding the limits must be rejected
+	if _, err := api.GetLogs(context.Background(), crit); err == nil {
+		t.Error("expected block range error for unpaginated query")
+	}
+	small := FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(2), Addresses: []common.Address{addr}}
+	if _, err := api.GetLogs(context.Background(), small); err == nil {
+		t.Error("expected result count error for unpaginated query")
+	}
+	// Paginated queries must return every log exactly once and in order
+	var (
+		logs   []*types.Log
+		cursor *string
+	)
+	for pages := 0; ; pages++ {
+		if pages > 20 {
+			t.Fatal("pagination did not terminate")
+		}
+		page, err := api.GetLogsPaged(context.Background(), crit, cursor)
+		if err != nil {
+			t.Fatalf("failed to retrieve page %d: %v", pages, err)
+		}
+		if len(page.Logs) > 5 {
+			t.Fatalf("page %d too large: have %d logs, want at most 5", pages, len(page.Logs))
+		}
+		logs = append(logs, page.Logs...)
+		if page.Next == "" {
+			break
+		}
+		cursor = &page.Next
+	}
+	if len(logs) != 30 {
+		t.Fatalf("log count mismatch: have %d, want 30", len(logs))
+	}
+	for i, log := range logs {
+		if log.BlockNumber != uint64(i/3+1) || log.Index != uint(i%3) {
+			t.Errorf("log %d: have block %d index %d, want block %d index %d", i, log.BlockNumber, log.Index, i/3+1, i%3)
+		}
+	}
+	// Cursors must not be accepted for different criteria
+	page, err := api.GetLogsPaged(context.Background(), crit, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve first page: %v", err)
+	}
+	other := FilterCriteria{Addresses: []common.Address{{0x01}}}
+	if _, err := api.GetLogsPaged(context.Background(), other, &page.Next); err != errInvalidCursor {
+		t.Errorf("cursor reuse error mismatch: have %v, want %v", err, errInvalidCursor)
+	}
+}
+
+func TestGetTokenTransfers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filtertest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		db, _      = kokdb.NewLDBDatabase(dir, 0, 0)
+		mux        = new(event.TypeMux)
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr       = crypto.PubkeyToAddress(key1.PublicKey)
+
+		token = common.Address{0x70}
+		alice = common.Address{0xaa}
+		bob   = common.Address{0xbb}
+		carol = common.Address{0xcc}
+	)
+	defer db.Close()
+
+	transfer := func(block uint64, index uint, from, to common.Address, value int64) *types.Log {
+		return &types.Log{
+			Address:     token,
+			Topics:      []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+			Data:        common.LeftPadBytes(big.NewInt(value).Bytes(), 32),
+			BlockNumber: block,
+			Index:       index,
+		}
+	}
+	// Create a chain with fungible transfers mixed with unrelated and non-fungible events
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, db, 3, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, new(big.Int))
+		switch i {
+		case 0:
+			receipt.Logs = []*types.Log{
+				transfer(1, 0, alice, bob, 5),
+				{Address: token, Topics: []common.Hash{transferTopic, common.BytesToHash(alice.Bytes()), common.BytesToHash(carol.Bytes()), {0x01}}, BlockNumber: 1, Index: 1},
+			}
+		case 1:
+			receipt.Logs = []*types.Log{
+				{Address: token, Topics: []common.Hash{{0x01}}, BlockNumber: 2, Index: 0},
+				transfer(2, 1, bob, carol, 7),
+			}
+		case 2:
+			receipt.Logs = []*types.Log{transfer(3, 0, bob, bob, 1)}
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		gen.AddUncheckedReceipt(receipt)
+	})
+	for i, block := range chain {
+		core.WriteBlock(db, block)
+		if err := core.WriteCanonicalHash(db, block.Hash(), block.NumberU64()); err != nil {
+			t.Fatalf("failed to insert block number: %v", err)
+		}
+		if err := core.WriteHeadBlockHash(db, block.Hash()); err != nil {
+			t.Fatalf("failed to insert block number: %v", err)
+		}
+		if err := core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i]); err != nil {
+			t.Fatal("error writing block receipts:", err)
+		}
+	}
+	api := NewPublicFilterAPI(backend, false, DefaultConfig)
+	from, to := rpc.BlockNumber(1), rpc.BlockNumber(3)
+
+	tests := []struct {
+		address *common.Address
+		want    []string
+	}{
+		{nil, []string{"1:aa->bb:5", "2:bb->cc:7", "3:bb->bb:1"}},
+		{&bob, []string{"1:aa->bb:5", "2:bb->cc:7", "3:bb->bb:1"}},
+		{&carol, []string{"2:bb->cc:7"}},
+		{&addr, []string{}},
+	}
+	for i, tt := range tests {
+		transfers, err := api.GetTokenTransfers(context.Background(), BlockRange{FromBlock: &from, ToBlock: &to}, tt.address)
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve transfers: %v", i, err)
+		}
+		have := make([]string, len(transfers))
+		for j, tr := range transfers {
+			if tr.Token != token {
+				t.Errorf("test %d, transfer %d: token mismatch: have %x, want %x", i, j, tr.Token, token)
+			}
+			have[j] = fmt.Sprintf("%d:%x->%x:%d", tr.BlockNumber, tr.From[:1], tr.To[:1], tr.Value.ToInt())
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: transfers mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+func TestPersistedFilters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filtertest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		db, _      = kokdb.NewLDBDatabase(dir, 0, 0)
+		mux        = new(event.TypeMux)
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		key1, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr       = crypto.PubkeyToAddress(key1.PublicKey)
+		config     = Config{MaxBlockRange: 100, FilterTimeout: time.Minute, PersistFilters: true}
+	)
+	defer db.Close()
+
+	// Create a chain with three logs in each of its ten blocks
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, db, 10, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, new(big.Int))
+		for j := 0; j < 3; j++ {
+			receipt.Logs = append(receipt.Logs, &types.Log{Address: addr, BlockNumber: uint64(i + 1), Index: uint(j)})
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		gen.AddUncheckedReceipt(receipt)
+	})
+	insert := func(from, to int) {
+		for i := from; i < to; i++ {
+			block := chain[i]
+			core.WriteBlock(db, block)
+			if err := core.WriteCanonicalHash(db, block.Hash(), block.NumberU64()); err != nil {
+				t.Fatalf("failed to insert block number: %v", err)
+			}
+			if err := core.WriteHeadBlockHash(db, block.Hash()); err != nil {
+				t.Fatalf("failed to insert block number: %v", err)
+			}
+			if err := core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts[i]); err != nil {
+				t.Fatal("error writing block receipts:", err)
+			}
+		}
+	}
+	// Install and poll a filter with half of the chain imported
+	insert(0, 5)
+	api := NewPublicFilterAPI(backend, false, config)
+	id, err := api.NewFilter(context.Background(), FilterCriteria{Addresses: []common.Address{addr}})
+	if err != nil {
+		t.Fatalf("failed to install filter: %v", err)
+	}
+	if _, err := api.GetFilterChanges(id); err != nil {
+		t.Fatalf("failed to poll filter: %v", err)
+	}
+	// Import the rest of the chain while the node is down, the restarted node
+	// must resume the filter with the logs of the missed blocks
+	insert(5, 10)
+	api = NewPublicFilterAPI(backend, false, config)
+	changes, err := api.GetFilterChanges(id)
+	if err != nil {
+		t.Fatalf("failed to poll restored filter: %v", err)
+	}
+	logs := changes.([]*types.Log)
+	if len(logs) != 15 {
+		t.Fatalf("missed log count mismatch: have %d, want 15", len(logs))
+	}
+	if logs[0].BlockNumber != 6 || logs[14].BlockNumber != 10 {
+		t.Errorf("missed log range mismatch: have %d-%d, want 6-10", logs[0].BlockNumber, logs[14].BlockNumber)
+	}
+	// Uninstalled filters must not be resumed
+	if !api.UninstallFilter(id) {
+		t.Fatalf("failed to uninstall restored filter")
+	}
+	api = NewPublicFilterAPI(backend, false, config)
+	if _, err := api.GetFilterChanges(id); err == nil {
+		t.Errorf("uninstalled filter restored")
+	}
+	// Filters not polled within the retention period must not be resumed
+	if id, err = api.NewFilter(context.Background(), FilterCriteria{Addresses: []common.Address{addr}}); err != nil {
+		t.Fatalf("failed to install filter: %v", err)
+	}
+	api.filtersMu.Lock()
+	api.persisted[id].Polled = uint64(time.Now().Add(-2 * time.Hour).Unix())
+	api.savePersistedFilters()
+	api.filtersMu.Unlock()
+
+	config.PersistRetention = time.Hour
+	api = NewPublicFilterAPI(backend, false, config)
+	if _, err := api.GetFilterChanges(id); err == nil {
+		t.Errorf("expired filter restored")
+	}
+}
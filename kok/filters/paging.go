@@ -0,0 +1,220 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/internal/kokapi"
+	"github.com/kokprojects/go-kok/rlp"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// Config contains the server side limits of log queries and filters.
+type Config struct {
+	MaxBlockRange uint64        // Maximum number of blocks a single log query may span (0 = unlimited)
+	MaxResults    int           // Maximum number of logs a single log query may return (0 = unlimited)
+	MaxFilters    int           // Maximum number of filters and subscriptions of a client session (0 = unlimited)
+	FilterTimeout time.Duration // Time after which a filter not polled for is uninstalled
+
+	// PersistFilters keeps the log filters installed over HTTP in the database,
+	// so polling clients can resume them after a restart of the node.
+	PersistFilters bool
+
+	// PersistRetention is the time after its last poll a persisted filter can
+	// still be resumed by a restarted node, older ones are dropped (0 = unlimited).
+	PersistRetention time.Duration
+}
+
+// DefaultConfig contains the default limits of log queries and filters.
+var DefaultConfig = Config{
+	MaxBlockRange: 10000,
+	MaxResults:    10000,
+	MaxFilters:    100,
+	FilterTimeout: 5 * time.Minute,
+
+	PersistRetention: 24 * time.Hour,
+}
+
+var errInvalidCursor = errors.New("invalid or mismatched logs cursor")
+
+// LogsPage is a single page of results of a paginated log query.
+type LogsPage struct {
+	Logs []*types.Log `json:"logs"`
+	Next string       `json:"next,omitempty"` // Opaque cursor of the next page, empty if the query is exhausted
+}
+
+// logsCursor is the decoded position of a paginated log query. The block range
+// is resolved on the first page, so that later pages don't drift with the head.
+type logsCursor struct {
+	Next uint64  // Next block to scan
+	End  uint64  // Last block of the query
+	Skip uint32  // Number of logs of the next block already returned
+	Crit [8]byte // Prefix of the hash of the query criteria
+}
+
+// criteriaID returns a short fingerprint of the address and topic criteria of a
+// query, used to reject cursors being reused with different criteria.
+func criteriaID(crit FilterCriteria) (id [8]byte) {
+	blob, _ := rlp.EncodeToBytes([]interface{}{crit.Addresses, crit.Topics})
+	copy(id[:], crypto.Keccak256(blob))
+	return id
+}
+
+// encode serializes the cursor into its opaque string representation.
+func (c *logsCursor) encode() string {
+	blob := make([]byte, 28)
+	binary.BigEndian.PutUint64(blob[0:], c.Next)
+	binary.BigEndian.PutUint64(blob[8:], c.End)
+	binary.BigEndian.PutUint32(blob[16:], c.Skip)
+	copy(blob[20:], c.Crit[:])
+	return hexutil.Encode(blob)
+}
+
+// decodeLogsCursor parses an opaque cursor and checks that it was issued for a
+// query with the given criteria.
+func decodeLogsCursor(s string, crit FilterCriteria) (*logsCursor, error) {
+	blob, err := hexutil.Decode(s)
+	if err != nil || len(blob) != 28 {
+		return nil, errInvalidCursor
+	}
+	c := &logsCursor{
+		Next: binary.BigEndian.Uint64(blob[0:]),
+		End:  binary.BigEndian.Uint64(blob[8:]),
+		Skip: binary.BigEndian.Uint32(blob[16:]),
+	}
+	copy(c.Crit[:], blob[20:])
+
+	if id := criteriaID(crit); !bytes.Equal(c.Crit[:], id[:]) || c.Next > c.End {
+		return nil, errInvalidCursor
+	}
+	return c, nil
+}
+
+// resolveRange converts the RPC block numbers of a query into absolute ones,
+// resolving the latest and pending block markers against the current head.
+func (api *PublicFilterAPI) resolveRange(ctx context.Context, begin, end int64) (uint64, uint64, error) {
+	if begin < 0 || end < 0 {
+		header, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if err != nil {
+			return 0, 0, err
+		}
+		if header == nil {
+			return 0, 0, errors.New("unknown head block")
+		}
+		if begin < 0 {
+			begin = header.Number.Int64()
+		}
+		if end < 0 {
+			end = header.Number.Int64()
+		}
+	}
+	return uint64(begin), uint64(end), nil
+}
+
+// checkRange ensures an unpaginated log query doesn't span more blocks than
+// allowed by the configured limits.
+func (api *PublicFilterAPI) checkRange(begin, end uint64) error {
+	if limit := api.config.MaxBlockRange; limit > 0 && end >= begin && end-begin+1 > limit {
+		return fmt.Errorf("block range too large (%d > %d), use kok_getLogsPaged", end-begin+1, limit)
+	}
+	return nil
+}
+
+// checkResults ensures an unpaginated log query doesn't return more logs than
+// allowed by the configured limits.
+func (api *PublicFilterAPI) checkResults(logs []*types.Log) error {
+	if limit := api.config.MaxResults; limit > 0 && len(logs) > limit {
+		return fmt.Errorf("query returned more than %d results, use kok_getLogsPaged", limit)
+	}
+	return nil
+}
+
+// GetLogsPaged returns a page of the logs matching the given criteria. Every page
+// spans at most the configured maximum number of blocks and holds at most the
+// configured maximum number of logs. If more logs may be available, the returned
+// page contains a cursor which can be passed back to retrieve the next page.
+func (api *PublicFilterAPI) GetLogsPaged(ctx context.Context, crit FilterCriteria, cursor *string) (*LogsPage, error) {
+	var c *logsCursor
+	if cursor != nil && *cursor != "" {
+		var err error
+		if c, err = decodeLogsCursor(*cursor, crit); err != nil {
+			return nil, err
+		}
+	} else {
+		begin, end := rpc.LatestBlockNumber.Int64(), rpc.LatestBlockNumber.Int64()
+		if crit.FromBlock != nil {
+			begin = crit.FromBlock.Int64()
+		}
+		if crit.ToBlock != nil {
+			end = crit.ToBlock.Int64()
+		}
+		from, to, err := api.resolveRange(ctx, begin, end)
+		if err != nil {
+			return nil, err
+		}
+		if from > to {
+			return &LogsPage{Logs: []*types.Log{}}, nil
+		}
+		c = &logsCursor{Next: from, End: to, Crit: criteriaID(crit)}
+	}
+	// Limit the scanned range to a single page worth of blocks
+	end := c.End
+	if limit := api.config.MaxBlockRange; limit > 0 && end-c.Next+1 > limit {
+		end = c.Next + limit - 1
+	}
+	if err := kokapi.ChargeLogsCost(ctx, end-c.Next+1); err != nil {
+		return nil, err
+	}
+	filter := New(api.backend, int64(c.Next), int64(end), crit.Addresses, crit.Topics)
+
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Drop the logs of the first block returned by the previous page
+	skip := 0
+	for skip < len(logs) && uint32(skip) < c.Skip && logs[skip].BlockNumber == c.Next {
+		skip++
+	}
+	logs = logs[skip:]
+
+	// Truncate the page if too many logs were found, resuming from the first omitted one
+	if limit := api.config.MaxResults; limit > 0 && len(logs) > limit {
+		next := &logsCursor{Next: logs[limit].BlockNumber, End: c.End, Crit: c.Crit}
+		for i := limit - 1; i >= 0 && logs[i].BlockNumber == next.Next; i-- {
+			next.Skip++
+		}
+		if next.Next == c.Next {
+			next.Skip += c.Skip
+		}
+		return &LogsPage{Logs: returnLogs(logs[:limit]), Next: next.encode()}, nil
+	}
+	page := &LogsPage{Logs: returnLogs(logs)}
+	if end < c.End {
+		page.Next = (&logsCursor{Next: end + 1, End: c.End, Crit: c.Crit}).encode()
+	}
+	return page, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/rlp"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// persistedFiltersKey is the database key of the list of persisted log filters.
+var persistedFiltersKey = []byte("persistedFilters")
+
+// persistedFilter is the database representation of an installed log filter,
+// allowing polling clients to resume it after a restart of the node.
+type persistedFilter struct {
+	ID        rpc.ID
+	Session   string
+	From, To  uint64 // Block numbers of the criteria, as two's complement for the named blocks
+	Addresses []common.Address
+	Topics    [][]common.Hash
+	Head      uint64 // Number of the chain head when the filter was last polled
+	Polled    uint64 // Unix time of the last poll of the filter
+}
+
+// newPersistedFilter creates the database representation of a log filter.
+func newPersistedFilter(id rpc.ID, session string, crit FilterCriteria, head uint64) *persistedFilter {
+	pf := &persistedFilter{
+		ID:        id,
+		Session:   session,
+		From:      uint64(rpc.LatestBlockNumber.Int64()),
+		To:        uint64(rpc.LatestBlockNumber.Int64()),
+		Addresses: crit.Addresses,
+		Topics:    crit.Topics,
+		Head:      head,
+		Polled:    uint64(time.Now().Unix()),
+	}
+	if crit.FromBlock != nil {
+		pf.From = uint64(crit.FromBlock.Int64())
+	}
+	if crit.ToBlock != nil {
+		pf.To = uint64(crit.ToBlock.Int64())
+	}
+	return pf
+}
+
+// criteria returns the filter criteria of a persisted log filter.
+func (pf *persistedFilter) criteria() FilterCriteria {
+	return FilterCriteria{
+		FromBlock: big.NewInt(int64(pf.From)),
+		ToBlock:   big.NewInt(int64(pf.To)),
+		Addresses: pf.Addresses,
+		Topics:    pf.Topics,
+	}
+}
+
+// currentHead returns the number of the current chain head.
+func (api *PublicFilterAPI) currentHead() uint64 {
+	header, err := api.backend.HeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if header == nil || err != nil {
+		return 0
+	}
+	return header.Number.Uint64()
+}
+
+// savePersistedFilters writes the persisted log filters into the database. It
+// must be called with filtersMu held.
+func (api *PublicFilterAPI) savePersistedFilters() {
+	list := make([]*persistedFilter, 0, len(api.persisted))
+	for _, pf := range api.persisted {
+		list = append(list, pf)
+	}
+	enc, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		log.Error("Failed to encode persisted filters", "err", err)
+		return
+	}
+	if err := api.chainDb.Put(persistedFiltersKey, enc); err != nil {
+		log.Error("Failed to store persisted filters", "err", err)
+	}
+}
+
+// persistFilter records an installed log filter in the database.
+func (api *PublicFilterAPI) persistFilter(id rpc.ID, session string, crit FilterCriteria) {
+	head := api.currentHead()
+
+	api.filtersMu.Lock()
+	defer api.filtersMu.Unlock()
+
+	if _, found := api.filters[id]; !found {
+		return // uninstalled in the meantime
+	}
+	api.persisted[id] = newPersistedFilter(id, session, crit, head)
+	api.savePersistedFilters()
+}
+
+// restoreFilters reinstalls the log filters persisted before the last shutdown,
+// under their original ids. The logs of the blocks imported since each filter
+// was last polled are queued up, so clients miss nothing across the restart.
+func (api *PublicFilterAPI) restoreFilters() {
+	enc, err := api.chainDb.Get(persistedFiltersKey)
+	if err != nil {
+		return
+	}
+	if !api.config.PersistFilters {
+		api.chainDb.Delete(persistedFiltersKey)
+		return
+	}
+	var list []*persistedFilter
+	if err := rlp.DecodeBytes(enc, &list); err != nil {
+		log.Error("Failed to decode persisted filters", "err", err)
+		return
+	}
+	head := api.currentHead()
+	for _, pf := range list {
+		if retention := api.config.PersistRetention; retention > 0 {
+			if polled := time.Unix(int64(pf.Polled), 0); time.Since(polled) > retention {
+				log.Info("Dropped expired persisted filter", "id", pf.ID, "polled", polled)
+				continue
+			}
+		}
+		if err := api.reserve(pf.Session); err != nil {
+			log.Warn("Dropped persisted filter", "id", pf.ID, "err", err)
+			continue
+		}
+		crit := pf.criteria()
+		backlog, err := api.missedLogs(pf, head)
+		if err != nil {
+			log.Warn("Failed to retrieve logs missed by persisted filter", "id", pf.ID, "err", err)
+		}
+		if _, err := api.newLogFilter(pf.Session, pf.ID, crit, nil, backlog); err != nil {
+			api.release(pf.Session, "")
+			log.Warn("Failed to restore persisted filter", "id", pf.ID, "err", err)
+			continue
+		}
+		api.filtersMu.Lock()
+		api.persisted[pf.ID] = newPersistedFilter(pf.ID, pf.Session, crit, head)
+		api.filtersMu.Unlock()
+	}
+	api.filtersMu.Lock()
+	api.savePersistedFilters()
+	api.filtersMu.Unlock()
+
+	log.Info("Restored persisted log filters", "count", len(api.persisted))
+}
+
+// missedLogs retrieves the logs matching a persisted filter in the blocks
+// imported since it was last polled, limited to the configured block range.
+func (api *PublicFilterAPI) missedLogs(pf *persistedFilter, head uint64) ([]*types.Log, error) {
+	from, to := pf.Head+1, head
+	if begin := int64(pf.From); begin == rpc.PendingBlockNumber.Int64() {
+		return nil, nil // pending logs are gone with the node anyway
+	} else if begin >= 0 && uint64(begin) > from {
+		from = uint64(begin)
+	}
+	if end := int64(pf.To); end >= 0 && uint64(end) < to {
+		to = uint64(end)
+	}
+	if to < from {
+		return nil, nil
+	}
+	if limit := api.config.MaxBlockRange; limit > 0 && to-from+1 > limit {
+		log.Warn("Truncated logs missed by persisted filter", "id", pf.ID, "from", from, "to", to, "limit", limit)
+		from = to - limit + 1
+	}
+	filter := New(api.backend, int64(from), int64(to), pf.Addresses, pf.Topics)
+	return filter.Logs(context.Background())
+}
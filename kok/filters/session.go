@@ -0,0 +1,152 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// FilterInfo describes an active filter or subscription of a client session.
+type FilterInfo struct {
+	ID      rpc.ID     `json:"id"`
+	Type    string     `json:"type"`
+	Polled  bool       `json:"polled"` // Whether changes are polled for, or pushed as notifications
+	Created time.Time  `json:"created"`
+	Expires *time.Time `json:"expires,omitempty"` // Time a polled filter is uninstalled unless polled before
+}
+
+// FilterQuotaError is returned when a client session attempts to create more
+// filters and subscriptions than allowed.
+type FilterQuotaError struct {
+	Limit int // Maximum number of active filters and subscriptions of a session
+}
+
+func (e *FilterQuotaError) Error() string {
+	return fmt.Sprintf("too many active filters and subscriptions (limit %d), uninstall some first", e.Limit)
+}
+
+// session tracks the filters and subscriptions of a single client session.
+type session struct {
+	reserved int                    // Number of quota slots taken, including filters being created
+	filters  map[rpc.ID]*FilterInfo // Filters and subscriptions fully set up
+}
+
+// sessionOf returns the client session a request was received on. Requests not
+// made through the RPC server (e.g. from within the process) share one session.
+func sessionOf(ctx context.Context) string {
+	name, _ := rpc.SessionFromContext(ctx)
+	return name
+}
+
+// sessionClosed returns a channel which is closed when the connection of the
+// request is, or nil if the request wasn't made over a persistent connection.
+func sessionClosed(ctx context.Context) <-chan interface{} {
+	if notifier, ok := rpc.NotifierFromContext(ctx); ok {
+		return notifier.Closed()
+	}
+	return nil
+}
+
+// reserve takes a quota slot of a session for a new filter or subscription,
+// failing if the session is already at its limit.
+func (api *PublicFilterAPI) reserve(name string) error {
+	api.sessionsMu.Lock()
+	defer api.sessionsMu.Unlock()
+
+	s := api.sessions[name]
+	if s == nil {
+		s = &session{filters: make(map[rpc.ID]*FilterInfo)}
+		api.sessions[name] = s
+	}
+	if api.config.MaxFilters > 0 && s.reserved >= api.config.MaxFilters {
+		return &FilterQuotaError{Limit: api.config.MaxFilters}
+	}
+	s.reserved++
+	return nil
+}
+
+// register records a filter or subscription set up on a previously reserved slot.
+func (api *PublicFilterAPI) register(name string, info *FilterInfo) {
+	api.sessionsMu.Lock()
+	defer api.sessionsMu.Unlock()
+
+	if s := api.sessions[name]; s != nil {
+		s.filters[info.ID] = info
+	}
+}
+
+// release frees the quota slot of a filter or subscription, or of a failed
+// creation if the id is empty.
+func (api *PublicFilterAPI) release(name string, id rpc.ID) {
+	api.sessionsMu.Lock()
+	defer api.sessionsMu.Unlock()
+
+	s := api.sessions[name]
+	if s == nil {
+		return
+	}
+	delete(s.filters, id)
+	if s.reserved--; s.reserved <= 0 {
+		delete(api.sessions, name)
+	}
+}
+
+// ActiveFilters returns the filters and subscriptions of the calling client
+// session, oldest first.
+func (api *PublicFilterAPI) ActiveFilters(ctx context.Context) []*FilterInfo {
+	infos := make([]*FilterInfo, 0)
+
+	api.sessionsMu.Lock()
+	if s := api.sessions[sessionOf(ctx)]; s != nil {
+		for _, info := range s.filters {
+			info := *info
+			infos = append(infos, &info)
+		}
+	}
+	api.sessionsMu.Unlock()
+
+	api.filtersMu.Lock()
+	for _, info := range infos {
+		if f, found := api.filters[info.ID]; found {
+			expires := f.polled.Add(api.config.FilterTimeout)
+			info.Expires = &expires
+		}
+	}
+	api.filtersMu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Created.Before(infos[j].Created) })
+	return infos
+}
+
+// subscribe creates a new notification subscription on a quota slot of the
+// session of the request, returning a function releasing the slot once the
+// subscription ends.
+func (api *PublicFilterAPI) subscribe(ctx context.Context, notifier *rpc.Notifier, typ string) (*rpc.Subscription, func(), error) {
+	session := sessionOf(ctx)
+	if err := api.reserve(session); err != nil {
+		return nil, nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+	api.register(session, &FilterInfo{ID: rpcSub.ID, Type: typ, Created: time.Now()})
+
+	return rpcSub, func() { api.release(session, rpcSub.ID) }, nil
+}
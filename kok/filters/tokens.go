@@ -0,0 +1,136 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/kokprojects/go-kok/accounts/abi"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// tokenABI is the interface definition of the standard token Transfer event.
+const tokenABI = `[{"type":"event","name":"Transfer","inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"value","type":"uint256"}]}]`
+
+var (
+	tokenEvents, _ = abi.JSON(strings.NewReader(tokenABI))
+	transferTopic  = tokenEvents.Events["Transfer"].Id()
+)
+
+// BlockRange is an inclusive range of blocks, defaulting to the latest one.
+type BlockRange struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`
+}
+
+// TokenTransfer is a decoded token Transfer event.
+type TokenTransfer struct {
+	Token       common.Address `json:"token"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *hexutil.Big   `json:"value"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint   `json:"transactionIndex"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+}
+
+// decodeTransfer decodes a log as a token Transfer event, returning nil if it's
+// not one. Events with an indexed value (i.e. non-fungible transfers) sharing
+// the same signature are skipped.
+func decodeTransfer(log *types.Log) *TokenTransfer {
+	if len(log.Topics) != 3 || log.Topics[0] != transferTopic {
+		return nil
+	}
+	var event struct {
+		Value *big.Int
+	}
+	if err := tokenEvents.Unpack(&event, "Transfer", log.Data); err != nil || event.Value == nil {
+		return nil
+	}
+	return &TokenTransfer{
+		Token:       log.Address,
+		From:        common.BytesToAddress(log.Topics[1].Bytes()),
+		To:          common.BytesToAddress(log.Topics[2].Bytes()),
+		Value:       (*hexutil.Big)(event.Value),
+		BlockNumber: hexutil.Uint64(log.BlockNumber),
+		BlockHash:   log.BlockHash,
+		TxHash:      log.TxHash,
+		TxIndex:     hexutil.Uint(log.TxIndex),
+		LogIndex:    hexutil.Uint(log.Index),
+	}
+}
+
+// GetTokenTransfers returns the token transfers emitted within the given block
+// range, decoded from the standard Transfer events. If an address is given, only
+// the transfers sending to or receiving from it are returned.
+func (api *PublicFilterAPI) GetTokenTransfers(ctx context.Context, blocks BlockRange, address *common.Address) ([]*TokenTransfer, error) {
+	begin, end := rpc.LatestBlockNumber.Int64(), rpc.LatestBlockNumber.Int64()
+	if blocks.FromBlock != nil {
+		begin = blocks.FromBlock.Int64()
+	}
+	if blocks.ToBlock != nil {
+		end = blocks.ToBlock.Int64()
+	}
+	if err := api.limitLogs(ctx, begin, end); err != nil {
+		return nil, err
+	}
+	// Filter for the transfers of the account on either side if requested
+	queries := [][][]common.Hash{{{transferTopic}}}
+	if address != nil {
+		topic := common.BytesToHash(address.Bytes())
+		queries = [][][]common.Hash{
+			{{transferTopic}, {topic}},
+			{{transferTopic}, nil, {topic}},
+		}
+	}
+	var logs []*types.Log
+	for _, topics := range queries {
+		found, err := New(api.backend, begin, end, nil, topics).Logs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, found...)
+	}
+	if err := api.checkResults(logs); err != nil {
+		return nil, err
+	}
+	// Decode the transfers in chain order, dropping self transfers found twice
+	sort.SliceStable(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+	transfers := []*TokenTransfer{}
+	for i, log := range logs {
+		if i > 0 && logs[i-1].BlockNumber == log.BlockNumber && logs[i-1].Index == log.Index {
+			continue
+		}
+		if transfer := decodeTransfer(log); transfer != nil {
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers, nil
+}
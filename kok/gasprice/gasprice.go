@@ -18,11 +18,13 @@ package gasprice
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"sort"
 	"sync"
 
 	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/internal/kokapi"
 	"github.com/kokprojects/go-kok/params"
 	"github.com/kokprojects/go-kok/rpc"
@@ -30,6 +32,9 @@ import (
 
 var maxPrice = big.NewInt(500 * params.Shannon)
 
+// maxFeeHistory is the maximum number of blocks covered by a fee history.
+const maxFeeHistory = 1024
+
 type Config struct {
 	Blocks     int
 	Percentile int
@@ -146,6 +151,105 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return price, nil
 }
 
+// FeeHistory returns the gas used ratio of up to the given number of blocks ending
+// at lastBlock, along with the gas prices paid at the requested percentiles of the
+// gas used by the transactions of each block, for wallets to build their own fee
+// estimation on. The number of the oldest block covered is returned first.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 || (i > 0 && p < rewardPercentiles[i-1]) {
+			return nil, nil, nil, fmt.Errorf("invalid reward percentile %f", p)
+		}
+	}
+	if blocks < 1 {
+		return new(big.Int), nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	// Pending blocks have no receipts yet, report up to the current head
+	if lastBlock == rpc.PendingBlockNumber {
+		lastBlock = rpc.LatestBlockNumber
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, lastBlock)
+	if head == nil {
+		if err == nil {
+			err = fmt.Errorf("block %d not found", lastBlock)
+		}
+		return nil, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	var (
+		oldest = last + 1 - uint64(blocks)
+		ratios = make([]float64, blocks)
+		reward [][]*big.Int
+	)
+	for i := 0; i < blocks; i++ {
+		block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(oldest+uint64(i)))
+		if block == nil {
+			if err == nil {
+				err = fmt.Errorf("block %d not found", oldest+uint64(i))
+			}
+			return nil, nil, nil, err
+		}
+		if block.GasLimit().Sign() > 0 {
+			ratios[i], _ = new(big.Rat).SetFrac(block.GasUsed(), block.GasLimit()).Float64()
+		}
+		if len(rewardPercentiles) == 0 {
+			continue
+		}
+		receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		reward = append(reward, blockRewards(block.Transactions(), receipts, rewardPercentiles))
+	}
+	return new(big.Int).SetUint64(oldest), reward, ratios, nil
+}
+
+// txGasAndPrice is the gas used by a transaction and the price paid for it.
+type txGasAndPrice struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+// blockRewards returns the gas prices paid at the given percentiles of the gas
+// used by the transactions of a block, zero for an empty block.
+func blockRewards(txs types.Transactions, receipts types.Receipts, percentiles []float64) []*big.Int {
+	reward := make([]*big.Int, len(percentiles))
+	if len(txs) == 0 || len(receipts) != len(txs) {
+		for i := range reward {
+			reward[i] = new(big.Int)
+		}
+		return reward
+	}
+	sorted := make([]txGasAndPrice, len(txs))
+	for i, tx := range txs {
+		used := receipts[i].CumulativeGasUsed.Uint64()
+		if i > 0 {
+			used -= receipts[i-1].CumulativeGasUsed.Uint64()
+		}
+		sorted[i] = txGasAndPrice{gasUsed: used, price: tx.GasPrice()}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].price.Cmp(sorted[j].price) < 0 })
+
+	total := receipts[len(receipts)-1].CumulativeGasUsed.Uint64()
+	var index int
+	sum := sorted[0].gasUsed
+	for i, p := range percentiles {
+		threshold := uint64(float64(total) * p / 100)
+		for sum < threshold && index < len(sorted)-1 {
+			index++
+			sum += sorted[index].gasUsed
+		}
+		reward[i] = new(big.Int).Set(sorted[index].price)
+	}
+	return reward
+}
+
 type getBlockPricesResult struct {
 	prices []*big.Int
 	err    error
@@ -0,0 +1,57 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+)
+
+// Tests that the fee history rewards are the prices paid at the percentiles of
+// the gas used in a block, rather than of the transaction count.
+func TestBlockRewards(t *testing.T) {
+	var (
+		to       = common.HexToAddress("0x01")
+		txs      types.Transactions
+		receipts types.Receipts
+		gasUsed  = []int64{21000, 84000, 21000, 42000}
+		prices   = []int64{30, 10, 20, 40}
+		total    = new(big.Int)
+	)
+	for i := range gasUsed {
+		txs = append(txs, types.NewTransaction(types.Binary, uint64(i), to, new(big.Int), big.NewInt(gasUsed[i]), big.NewInt(prices[i]), nil))
+		total.Add(total, big.NewInt(gasUsed[i]))
+		receipts = append(receipts, types.NewReceipt(nil, false, total))
+	}
+	// Sorted by price the transactions use 84000 (10), 21000 (20), 21000 (30) and
+	// 42000 (40) gas out of 168000.
+	reward := blockRewards(txs, receipts, []float64{0, 25, 50, 60, 75, 100})
+	want := []int64{10, 10, 10, 20, 30, 40}
+	for i, price := range reward {
+		if price.Int64() != want[i] {
+			t.Errorf("reward %d: have %v, want %d", i, price, want[i])
+		}
+	}
+	for i, price := range blockRewards(nil, nil, []float64{10, 90}) {
+		if price.Sign() != 0 {
+			t.Errorf("empty block reward %d: have %v, want 0", i, price)
+		}
+	}
+}
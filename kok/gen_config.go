@@ -8,7 +8,9 @@ import (
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/common/hexutil"
 	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/kok/archive"
 	"github.com/kokprojects/go-kok/kok/downloader"
+	"github.com/kokprojects/go-kok/kok/filters"
 	"github.com/kokprojects/go-kok/kok/gasprice"
 )
 
@@ -22,17 +24,35 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SyncMode                downloader.SyncMode
 		LightServ               int  `toml:",omitempty"`
 		LightPeers              int  `toml:",omitempty"`
+		LightSign               bool `toml:",omitempty"`
+		LightMinimal            bool `toml:",omitempty"`
 		SkipBcVersionCheck      bool `toml:"-"`
 		DatabaseHandles         int  `toml:"-"`
 		DatabaseCache           int
+		TrieCommitInterval      uint64 `toml:",omitempty"`
+		Pruning                 core.PruningConfig
 		Validator               common.Address `toml:",omitempty"`
 		Coinbase                common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
+		MinerPolicy             string `toml:",omitempty"`
+		MinerOrdering           string `toml:",omitempty"`
+		SkipOffline             bool   `toml:",omitempty"`
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
+		Filters                 filters.Config
+		BloomSectionSize        uint64 `toml:",omitempty"`
 		EnablePreimageRecording bool
+		EnableBalanceIndex      bool
+		EnableInternalTxIndex   bool
+		EnableTxAddressIndex    bool
+		EnableRevertReasonIndex bool
+		EnableAudit             bool
+		RESTEndpoint            string   `toml:",omitempty"`
+		GraphQLEndpoint         string   `toml:",omitempty"`
+		GraphQLCors             []string `toml:",omitempty"`
+		Archive                 archive.Config
 		DocRoot                 string `toml:"-"`
 		PowFake                 bool   `toml:"-"`
 		PowTest                 bool   `toml:"-"`
@@ -45,17 +65,35 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SyncMode = c.SyncMode
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.LightSign = c.LightSign
+	enc.LightMinimal = c.LightMinimal
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.TrieCommitInterval = c.TrieCommitInterval
+	enc.Pruning = c.Pruning
 	enc.Validator = c.Validator
 	enc.Coinbase = c.Coinbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
 	enc.GasPrice = c.GasPrice
+	enc.MinerPolicy = c.MinerPolicy
+	enc.MinerOrdering = c.MinerOrdering
+	enc.SkipOffline = c.SkipOffline
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
+	enc.Filters = c.Filters
+	enc.BloomSectionSize = c.BloomSectionSize
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.EnableBalanceIndex = c.EnableBalanceIndex
+	enc.EnableInternalTxIndex = c.EnableInternalTxIndex
+	enc.EnableTxAddressIndex = c.EnableTxAddressIndex
+	enc.EnableRevertReasonIndex = c.EnableRevertReasonIndex
+	enc.EnableAudit = c.EnableAudit
+	enc.RESTEndpoint = c.RESTEndpoint
+	enc.GraphQLEndpoint = c.GraphQLEndpoint
+	enc.GraphQLCors = c.GraphQLCors
+	enc.Archive = c.Archive
 	enc.DocRoot = c.DocRoot
 	enc.PowFake = c.PowFake
 	enc.PowTest = c.PowTest
@@ -72,17 +110,35 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SyncMode                *downloader.SyncMode
 		LightServ               *int  `toml:",omitempty"`
 		LightPeers              *int  `toml:",omitempty"`
+		LightSign               *bool `toml:",omitempty"`
+		LightMinimal            *bool `toml:",omitempty"`
 		SkipBcVersionCheck      *bool `toml:"-"`
 		DatabaseHandles         *int  `toml:"-"`
 		DatabaseCache           *int
+		TrieCommitInterval      *uint64 `toml:",omitempty"`
+		Pruning                 *core.PruningConfig
 		Validator               *common.Address `toml:",omitempty"`
 		Coinbase                *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
+		MinerPolicy             *string `toml:",omitempty"`
+		MinerOrdering           *string `toml:",omitempty"`
+		SkipOffline             *bool   `toml:",omitempty"`
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
+		Filters                 *filters.Config
+		BloomSectionSize        *uint64 `toml:",omitempty"`
 		EnablePreimageRecording *bool
+		EnableBalanceIndex      *bool
+		EnableInternalTxIndex   *bool
+		EnableTxAddressIndex    *bool
+		EnableRevertReasonIndex *bool
+		EnableAudit             *bool
+		RESTEndpoint            *string  `toml:",omitempty"`
+		GraphQLEndpoint         *string  `toml:",omitempty"`
+		GraphQLCors             []string `toml:",omitempty"`
+		Archive                 *archive.Config
 		DocRoot                 *string `toml:"-"`
 		PowFake                 *bool   `toml:"-"`
 		PowTest                 *bool   `toml:"-"`
@@ -108,6 +164,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.LightSign != nil {
+		c.LightSign = *dec.LightSign
+	}
+	if dec.LightMinimal != nil {
+		c.LightMinimal = *dec.LightMinimal
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -117,6 +179,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.TrieCommitInterval != nil {
+		c.TrieCommitInterval = *dec.TrieCommitInterval
+	}
+	if dec.Pruning != nil {
+		c.Pruning = *dec.Pruning
+	}
 	if dec.Validator != nil {
 		c.Validator = *dec.Validator
 	}
@@ -132,15 +200,57 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.GasPrice != nil {
 		c.GasPrice = dec.GasPrice
 	}
+	if dec.MinerPolicy != nil {
+		c.MinerPolicy = *dec.MinerPolicy
+	}
+	if dec.MinerOrdering != nil {
+		c.MinerOrdering = *dec.MinerOrdering
+	}
+	if dec.SkipOffline != nil {
+		c.SkipOffline = *dec.SkipOffline
+	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
+	if dec.Filters != nil {
+		c.Filters = *dec.Filters
+	}
+	if dec.BloomSectionSize != nil {
+		c.BloomSectionSize = *dec.BloomSectionSize
+	}
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.EnableBalanceIndex != nil {
+		c.EnableBalanceIndex = *dec.EnableBalanceIndex
+	}
+	if dec.EnableInternalTxIndex != nil {
+		c.EnableInternalTxIndex = *dec.EnableInternalTxIndex
+	}
+	if dec.EnableTxAddressIndex != nil {
+		c.EnableTxAddressIndex = *dec.EnableTxAddressIndex
+	}
+	if dec.EnableRevertReasonIndex != nil {
+		c.EnableRevertReasonIndex = *dec.EnableRevertReasonIndex
+	}
+	if dec.EnableAudit != nil {
+		c.EnableAudit = *dec.EnableAudit
+	}
+	if dec.RESTEndpoint != nil {
+		c.RESTEndpoint = *dec.RESTEndpoint
+	}
+	if dec.GraphQLEndpoint != nil {
+		c.GraphQLEndpoint = *dec.GraphQLEndpoint
+	}
+	if dec.GraphQLCors != nil {
+		c.GraphQLCors = dec.GraphQLCors
+	}
+	if dec.Archive != nil {
+		c.Archive = *dec.Archive
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
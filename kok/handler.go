@@ -28,6 +28,7 @@ import (
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/consensus"
+	"github.com/kokprojects/go-kok/consensus/dpos"
 	"github.com/kokprojects/go-kok/consensus/misc"
 	"github.com/kokprojects/go-kok/core"
 	"github.com/kokprojects/go-kok/core/types"
@@ -78,6 +79,8 @@ type ProtocolManager struct {
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
+	dpos       *dpos.Dpos // DPoS engine gossiping validator heartbeats, if any
+	clock      clockEstimator
 
 	SubProtocols []p2p.Protocol
 
@@ -114,6 +117,9 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		txsyncCh:    make(chan *txsync),
 		quitSync:    make(chan struct{}),
 	}
+	if engine, ok := engine.(*dpos.Dpos); ok {
+		manager.dpos = engine
+	}
 	// Figure out whkoker to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
 		log.Warn("Blockchain not empty, fast sync disabled")
@@ -217,6 +223,11 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 	// start sync handlers
 	go pm.syncer()
 	go pm.txsyncLoop()
+
+	// announce the liveness of the local validator
+	if pm.dpos != nil {
+		go pm.heartbeatLoop()
+	}
 }
 
 func (pm *ProtocolManager) Stop() {
@@ -265,6 +276,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
 		rw.Init(p.version)
 	}
+	if p.clockSampled {
+		pm.clock.add(p.clockOffset)
+	}
 	// Register the peer locally
 	if err := pm.peers.Register(p); err != nil {
 		p.Log().Error("kokereum peer registration failed", "err", err)
@@ -532,7 +546,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				return errResp(ErrDecode, "msg %v: %v", msg, err)
 			}
 			// Retrieve the requested state entry, stopping if enough was found
-			if entry, err := pm.chaindb.Get(hash.Bytes()); err == nil {
+			if entry, err := pm.blockchain.TrieDB().Get(hash.Bytes()); err == nil {
 				data = append(data, entry)
 				bytes += len(entry)
 			}
@@ -668,6 +682,25 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 		pm.txpool.AddRemotes(txs)
 
+	case p.version >= kok64 && msg.Code == HeartbeatMsg:
+		// A validator heartbeat arrived, record it if we're running dpos and relay if new
+		var hb dpos.Heartbeat
+		if err := msg.Decode(&hb); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.MarkHeartbeat(hb.Hash())
+		if pm.dpos == nil {
+			break
+		}
+		fresh, err := pm.dpos.AddHeartbeat(pm.blockchain.CurrentHeader(), &hb, time.Now().Unix())
+		if err != nil {
+			log.Trace("Discarded validator heartbeat", "peer", p, "validator", hb.Validator, "err", err)
+			break
+		}
+		if fresh {
+			pm.BroadcastHeartbeat(&hb)
+		}
+
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
@@ -735,7 +768,10 @@ func (self *ProtocolManager) txBroadcastLoop() {
 	for {
 		select {
 		case event := <-self.txCh:
-			self.BroadcastTx(event.Tx.Hash(), event.Tx)
+			// Private transactions are kept local until mined
+			if hash := event.Tx.Hash(); !self.txpool.IsPrivate(hash) {
+				self.BroadcastTx(hash, event.Tx)
+			}
 
 		// Err() channel will be closed when unsubscribing.
 		case <-self.txSub.Err():
@@ -751,15 +787,21 @@ type kokNodeInfo struct {
 	Difficulty *big.Int    `json:"difficulty"` // Total difficulty of the host's blockchain
 	Genesis    common.Hash `json:"genesis"`    // SHA3 hash of the host's genesis block
 	Head       common.Hash `json:"head"`       // SHA3 hash of the host's best owned block
+
+	ClockOffset  int64 `json:"clockOffset"`  // Estimated offset of the network time from the local clock, in milliseconds
+	ClockSamples int   `json:"clockSamples"` // Number of peer handshakes the clock offset was estimated from
 }
 
 // NodeInfo retrieves some protocol metadata about the running host node.
 func (self *ProtocolManager) NodeInfo() *kokNodeInfo {
 	currentBlock := self.blockchain.CurrentBlock()
+	offset, samples := self.clock.Offset()
 	return &kokNodeInfo{
-		Network:    self.networkId,
-		Difficulty: self.blockchain.GetTd(currentBlock.Hash(), currentBlock.NumberU64()),
-		Genesis:    self.blockchain.Genesis().Hash(),
-		Head:       currentBlock.Hash(),
+		Network:      self.networkId,
+		Difficulty:   self.blockchain.GetTd(currentBlock.Hash(), currentBlock.NumberU64()),
+		Genesis:      self.blockchain.Genesis().Hash(),
+		Head:         currentBlock.Hash(),
+		ClockOffset:  int64(offset / time.Millisecond),
+		ClockSamples: samples,
 	}
 }
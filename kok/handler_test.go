@@ -391,6 +391,63 @@ func testGetNodeData(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that the state of recent blocks, still buffered in memory by the chain,
+// is served to peers requesting it.
+func TestGetNodeDataBuffered63(t *testing.T) { testGetNodeDataBuffered(t, 63) }
+
+func testGetNodeDataBuffered(t *testing.T, protocol int) {
+	var (
+		evmux  = new(event.TypeMux)
+		engine = kokash.NewFaker()
+		db, _  = kokdb.NewMemDatabase()
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, _ := core.NewBlockChain(db, gspec.Config, engine, vm.Config{})
+	defer blockchain.Stop()
+	blockchain.SetCommitInterval(64)
+
+	// Generate the chain in a separate database, so only the import writes state
+	gendb, _ := kokdb.NewMemDatabase()
+	gspec.MustCommit(gendb)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, gendb, 2, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(types.Binary, block.TxNonce(testBank), common.Address{byte(i + 1)}, big.NewInt(1000), bigTxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+		block.AddTx(tx)
+	})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	root := blockchain.CurrentBlock().Root()
+	if ok, _ := db.Has(root.Bytes()); ok {
+		t.Fatalf("head state flushed to the chain database")
+	}
+	pm, err := NewProtocolManager(gspec.Config, downloader.FullSync, DefaultConfig.NetworkId, evmux, &testTxPool{}, engine, blockchain, db)
+	if err != nil {
+		t.Fatalf("failed to create protocol manager: %v", err)
+	}
+	pm.Start(1000)
+	defer pm.Stop()
+
+	peer, _ := newTestPeer("peer", protocol, pm, true)
+	defer peer.close()
+
+	p2p.Send(peer.app, 0x0d, []common.Hash{root})
+	msg, err := peer.app.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read node data response: %v", err)
+	}
+	var data [][]byte
+	if err := msg.Decode(&data); err != nil {
+		t.Fatalf("failed to decode response node data: %v", err)
+	}
+	if len(data) != 1 || crypto.Keccak256Hash(data[0]) != root {
+		t.Errorf("buffered state root not served: have %d entries", len(data))
+	}
+}
+
 // Tests that the transaction receipts can be retrieved based on hashes.
 func TestGetReceipt63(t *testing.T) { testGetReceipt(t, 63) }
 
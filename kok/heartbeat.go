@@ -0,0 +1,58 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"time"
+
+	"github.com/kokprojects/go-kok/consensus/dpos"
+	"github.com/kokprojects/go-kok/log"
+)
+
+// BroadcastHeartbeat propagates a validator heartbeat to the peers supporting
+// them which are not yet known to have it.
+func (pm *ProtocolManager) BroadcastHeartbeat(hb *dpos.Heartbeat) {
+	hash := hb.Hash()
+	for _, peer := range pm.peers.PeersWithoutHeartbeat(hash) {
+		peer.SendHeartbeat(hb)
+	}
+	log.Trace("Broadcast validator heartbeat", "validator", hb.Validator, "hash", hash)
+}
+
+// heartbeatLoop periodically announces the liveness of the local validator, as
+// long as the engine is authorized to sign on its behalf.
+func (pm *ProtocolManager) heartbeatLoop() {
+	ticker := time.NewTicker(dpos.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			hb, err := pm.dpos.SignHeartbeat(time.Now().Unix())
+			if err != nil {
+				continue // Not a validator, or not mining yet
+			}
+			if _, err := pm.dpos.AddHeartbeat(pm.blockchain.CurrentHeader(), hb, int64(hb.Time)); err != nil {
+				continue // Not a validator of the current epoch
+			}
+			pm.BroadcastHeartbeat(hb)
+
+		case <-pm.quitSync:
+			return
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"sort"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/consensus/kokash"
@@ -124,6 +125,11 @@ func (p *testTxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return batches, nil
 }
 
+// IsPrivate returns false, as the test pool has no private transactions.
+func (p *testTxPool) IsPrivate(hash common.Hash) bool {
+	return false
+}
+
 func (p *testTxPool) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subscription {
 	return p.txFeed.Subscribe(ch)
 }
@@ -188,6 +194,15 @@ func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesi
 	if err := p2p.Send(p.app, StatusMsg, msg); err != nil {
 		t.Fatalf("status send: %v", err)
 	}
+	if p.version >= kok65 {
+		if _, err := p.app.ReadMsg(); err != nil {
+			t.Fatalf("clock recv: %v", err)
+		}
+		now := uint64(time.Now().UnixNano())
+		if err := p2p.Send(p.app, ClockMsg, &clockData{Received: now, Sent: now}); err != nil {
+			t.Fatalf("clock send: %v", err)
+		}
+	}
 }
 
 // close terminates the local side of the peer, notifying the remote protocol
@@ -0,0 +1,187 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/event"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+// importCheckpointPrefix + hash of the absolute file path -> importCheckpoint
+var importCheckpointPrefix = []byte("import-")
+
+// importBatchSize is the number of blocks inserted into the chain at once.
+const importBatchSize = 2500
+
+// errImportRunning is returned if a chain import is requested while another one
+// is still running.
+var errImportRunning = errors.New("chain import already running")
+
+// ImportProgress is a notification about the progress of a chain import.
+type ImportProgress struct {
+	File     string `json:"file"`
+	Resumed  uint64 `json:"resumed"`  // Blocks skipped as imported by an earlier run
+	Read     uint64 `json:"read"`     // Blocks read from the file, including the resumed ones
+	Imported uint64 `json:"imported"` // Blocks inserted into the chain
+	Number   uint64 `json:"number"`   // Number of the last block read
+	Done     bool   `json:"done"`
+	Error    string `json:"error,omitempty"`
+}
+
+// importCheckpoint records how far an import of a file got, allowing it to be
+// resumed after an interruption.
+type importCheckpoint struct {
+	Read   uint64      // Number of blocks read from the file
+	Hash   common.Hash // Hash of the last block read
+	Number uint64      // Number of the last block read
+}
+
+// chainImporter imports blocks exported into files, one file at a time, while
+// reporting its progress and checkpointing it into the database.
+type chainImporter struct {
+	db      kokdb.Database
+	feed    event.Feed
+	running int32 // Flag whether an import is running
+}
+
+// newChainImporter creates an importer keeping its checkpoints in db.
+func newChainImporter(db kokdb.Database) *chainImporter {
+	return &chainImporter{db: db}
+}
+
+// subscribe registers a subscription for the import progress notifications.
+func (ci *chainImporter) subscribe(ch chan<- ImportProgress) event.Subscription {
+	return ci.feed.Subscribe(ch)
+}
+
+// checkpointKey returns the database key of the import checkpoint of a file.
+func checkpointKey(file string) []byte {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return append(append([]byte{}, importCheckpointPrefix...), crypto.Keccak256([]byte(file))...)
+}
+
+// importChain imports the blocks of the given file into the chain, resuming an
+// earlier import of the same file if it was interrupted. The checkpoint of the
+// file is removed once it has been fully imported.
+func (ci *chainImporter) importChain(chain *core.BlockChain, file string) error {
+	if !atomic.CompareAndSwapInt32(&ci.running, 0, 1) {
+		return errImportRunning
+	}
+	defer atomic.StoreInt32(&ci.running, 0)
+
+	progress := ImportProgress{File: file}
+	err := ci.run(chain, file, &progress)
+	if err != nil {
+		progress.Error = err.Error()
+	}
+	progress.Done = true
+	ci.feed.Send(progress)
+
+	return err
+}
+
+func (ci *chainImporter) run(chain *core.BlockChain, file string, progress *ImportProgress) error {
+	// Make sure the can access the file to import
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	// Skip the blocks imported by an interrupted run, unless the chain lost them
+	key := checkpointKey(file)
+	if blob, err := ci.db.Get(key); err == nil {
+		var checkpoint importCheckpoint
+		if err := rlp.DecodeBytes(blob, &checkpoint); err != nil {
+			log.Warn("Discarding corrupt import checkpoint", "file", file, "err", err)
+		} else if !chain.HasBlock(checkpoint.Hash, checkpoint.Number) {
+			log.Warn("Import checkpoint not in chain, restarting", "file", file, "number", checkpoint.Number)
+		} else {
+			for progress.Read < checkpoint.Read {
+				if _, err := stream.Raw(); err != nil {
+					return fmt.Errorf("block %d: failed to skip: %v", progress.Read, err)
+				}
+				progress.Read++
+			}
+			progress.Resumed, progress.Number = progress.Read, checkpoint.Number
+			log.Info("Resuming chain import", "file", file, "blocks", progress.Resumed, "number", progress.Number)
+		}
+	}
+	// Run actual the import in pre-configured batches
+	blocks := make([]*types.Block, 0, importBatchSize)
+	for batch := 0; ; batch++ {
+		// Load a batch of blocks from the input file
+		for len(blocks) < cap(blocks) {
+			block := new(types.Block)
+			if err := stream.Decode(block); err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("block %d: failed to parse: %v", progress.Read, err)
+			}
+			blocks = append(blocks, block)
+			progress.Read++
+		}
+		if len(blocks) == 0 {
+			break
+		}
+		// Import the batch unless already known and reset the buffer
+		if !hasAllBlocks(chain, blocks) {
+			if _, err := chain.InsertChain(blocks); err != nil {
+				return fmt.Errorf("batch %d: failed to insert: %v", batch, err)
+			}
+			progress.Imported += uint64(len(blocks))
+		}
+		last := blocks[len(blocks)-1]
+		progress.Number = last.NumberU64()
+
+		blob, err := rlp.EncodeToBytes(&importCheckpoint{Read: progress.Read, Hash: last.Hash(), Number: progress.Number})
+		if err != nil {
+			return err
+		}
+		if err := ci.db.Put(key, blob); err != nil {
+			return err
+		}
+		ci.feed.Send(*progress)
+		blocks = blocks[:0]
+	}
+	return ci.db.Delete(key)
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/kokprojects/go-kok/consensus/kokash"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/vm"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+// Tests that an interrupted chain import is resumed from its checkpoint and that
+// the progress is reported along the way.
+func TestImportChainResume(t *testing.T) {
+	// Export a chain into a file
+	var (
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		db, _   = kokdb.NewMemDatabase()
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, db, 10, nil)
+
+	src, _ := core.NewBlockChain(db, gspec.Config, kokash.NewFaker(), vm.Config{})
+	if _, err := src.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert source chain: %v", err)
+	}
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create export file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	if err := src.Export(file); err != nil {
+		t.Fatalf("failed to export chain: %v", err)
+	}
+	file.Close()
+
+	// Create a chain with the first blocks already imported by an interrupted run
+	dstDb, _ := kokdb.NewMemDatabase()
+	gspec.MustCommit(dstDb)
+	dst, _ := core.NewBlockChain(dstDb, gspec.Config, kokash.NewFaker(), vm.Config{})
+	if _, err := dst.InsertChain(blocks[:4]); err != nil {
+		t.Fatalf("failed to insert partial chain: %v", err)
+	}
+	importer := newChainImporter(dstDb)
+	blob, _ := rlp.EncodeToBytes(&importCheckpoint{Read: 5, Hash: blocks[3].Hash(), Number: 4})
+	dstDb.Put(checkpointKey(file.Name()), blob)
+
+	progress := make(chan ImportProgress, 16)
+	sub := importer.subscribe(progress)
+	defer sub.Unsubscribe()
+
+	if err := importer.importChain(dst, file.Name()); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if head := dst.CurrentBlock().NumberU64(); head != 10 {
+		t.Errorf("head mismatch: have %d, want %d", head, 10)
+	}
+	if _, err := dstDb.Get(checkpointKey(file.Name())); err == nil {
+		t.Errorf("checkpoint retained after finished import")
+	}
+	var last ImportProgress
+	for len(progress) > 0 {
+		last = <-progress
+	}
+	want := ImportProgress{File: file.Name(), Resumed: 5, Read: 11, Imported: 6, Number: 10, Done: true}
+	if last != want {
+		t.Errorf("progress mismatch: have %+v, want %+v", last, want)
+	}
+}
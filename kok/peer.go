@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/consensus/dpos"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/p2p"
 	"github.com/kokprojects/go-kok/rlp"
@@ -39,6 +40,7 @@ var (
 const (
 	maxKnownTxs      = 32768 // Maximum transactions hashes to keep in the known list (prevent DOS)
 	maxKnownBlocks   = 1024  // Maximum block hashes to keep in the known list (prevent DOS)
+	maxKnownBeats    = 1024  // Maximum heartbeat hashes to keep in the known list (prevent DOS)
 	handshakeTimeout = 5 * time.Second
 )
 
@@ -65,6 +67,10 @@ type peer struct {
 
 	knownTxs    *set.Set // Set of transaction hashes known to be known by this peer
 	knownBlocks *set.Set // Set of block hashes known to be known by this peer
+	knownBeats  *set.Set // Set of validator heartbeat hashes known to be known by this peer
+
+	clockOffset  time.Duration // Offset of the peer's clock from the local one at handshake
+	clockSampled bool          // Whkoker the peer advertised its clock during the handshake
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -77,6 +83,7 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		id:          fmt.Sprintf("%x", id[:8]),
 		knownTxs:    set.New(),
 		knownBlocks: set.New(),
+		knownBeats:  set.New(),
 	}
 }
 
@@ -130,6 +137,22 @@ func (p *peer) MarkTransaction(hash common.Hash) {
 	p.knownTxs.Add(hash)
 }
 
+// MarkHeartbeat marks a validator heartbeat as known for the peer, ensuring that
+// it will never be propagated to this particular peer.
+func (p *peer) MarkHeartbeat(hash common.Hash) {
+	// If we reached the memory allowance, drop a previously known heartbeat hash
+	for p.knownBeats.Size() >= maxKnownBeats {
+		p.knownBeats.Pop()
+	}
+	p.knownBeats.Add(hash)
+}
+
+// SendHeartbeat propagates a validator heartbeat to the peer.
+func (p *peer) SendHeartbeat(hb *dpos.Heartbeat) error {
+	p.knownBeats.Add(hb.Hash())
+	return p2p.Send(p.rw, HeartbeatMsg, hb)
+}
+
 // SendTransactions sends transactions to the peer and includes the hashes
 // in its transaction hash set for future reference.
 func (p *peer) SendTransactions(txs types.Transactions) error {
@@ -229,23 +252,44 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 }
 
 // Handshake executes the kok protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
+// network IDs, difficulties, head and genesis blocks. Since kok/65 both sides
+// also reply to the status with a clock message to sample the peer's clock.
 func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
 	var status statusData // safe to read after two values have been received from errc
 
+	var (
+		sent     = time.Now()              // Local time our status is sent at
+		received = make(chan time.Time, 1) // Local time the peer's status arrived at
+		quit     = make(chan struct{})
+	)
+	defer close(quit)
+
 	go func() {
-		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
+		err := p2p.Send(p.rw, StatusMsg, &statusData{
 			ProtocolVersion: uint32(p.version),
 			NetworkId:       network,
 			TD:              td,
 			CurrentBlock:    head,
 			GenesisBlock:    genesis,
 		})
+		if err != nil || p.version < kok65 {
+			errc <- err
+			return
+		}
+		// Reply to the peer's status once it arrives, after our own status
+		select {
+		case at := <-received:
+			errc <- p2p.Send(p.rw, ClockMsg, &clockData{
+				Received: uint64(at.UnixNano()),
+				Sent:     uint64(time.Now().UnixNano()),
+			})
+		case <-quit:
+		}
 	}()
 	go func() {
-		errc <- p.readStatus(network, &status, genesis)
+		errc <- p.readStatus(network, &status, genesis, sent, received)
 	}()
 	timeout := time.NewTimer(handshakeTimeout)
 	defer timeout.Stop()
@@ -263,11 +307,12 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 	return nil
 }
 
-func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash, sent time.Time, received chan<- time.Time) (err error) {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
 	}
+	arrived := time.Now()
 	if msg.Code != StatusMsg {
 		return errResp(ErrNoStatusMsg, "first msg has code %x (!= %x)", msg.Code, StatusMsg)
 	}
@@ -287,6 +332,41 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 	if int(status.ProtocolVersion) != p.version {
 		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
 	}
+	if p.version < kok65 {
+		return nil
+	}
+	received <- arrived
+	return p.readClock(sent)
+}
+
+// readClock reads the peer's reply to our status and samples the offset of its
+// clock from the local one, compensating for the one way network delay.
+func (p *peer) readClock(sent time.Time) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	replied := time.Now()
+	if msg.Code != ClockMsg {
+		return errResp(ErrNoClockMsg, "second msg has code %x (!= %x)", msg.Code, ClockMsg)
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	var clock clockData
+	if err := msg.Decode(&clock); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	// The round trip time is the time elapsed locally minus the time the peer
+	// held our status, and the status reached the peer after half of it
+	var (
+		peerReceived = time.Unix(0, int64(clock.Received))
+		peerSent     = time.Unix(0, int64(clock.Sent))
+		rtt          = replied.Sub(sent) - peerSent.Sub(peerReceived)
+	)
+	if rtt >= 0 {
+		p.clockOffset, p.clockSampled = peerReceived.Sub(sent)-rtt/2, true
+	}
 	return nil
 }
 
@@ -387,6 +467,21 @@ func (ps *peerSet) PeersWithoutTx(hash common.Hash) []*peer {
 	return list
 }
 
+// PeersWithoutHeartbeat retrieves a list of peers supporting validator heartbeats
+// that do not have a given heartbeat in their set of known hashes.
+func (ps *peerSet) PeersWithoutHeartbeat(hash common.Hash) []*peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	list := make([]*peer, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if p.version >= kok64 && !p.knownBeats.Has(hash) {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
 // BestPeer retrieves the known peer with the currently highest total difficulty.
 func (ps *peerSet) BestPeer() *peer {
 	ps.lock.RLock()
@@ -32,16 +32,18 @@ import (
 const (
 	kok62 = 62
 	kok63 = 63
+	kok64 = 64
+	kok65 = 65
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "kok"
 
 // Supported versions of the kok protocol (first is primary).
-var ProtocolVersions = []uint{kok63, kok62}
+var ProtocolVersions = []uint{kok65, kok64, kok63, kok62}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{19, 18, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -62,6 +64,12 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+	// Protocol messages belonging to kok/64
+	HeartbeatMsg = 0x11
+
+	// Protocol messages belonging to kok/65
+	ClockMsg = 0x12
 )
 
 type errCode int
@@ -76,6 +84,7 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrNoClockMsg
 )
 
 func (e errCode) String() string {
@@ -93,6 +102,7 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrNoClockMsg:              "No clock message",
 }
 
 type txPool interface {
@@ -103,6 +113,9 @@ type txPool interface {
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
 
+	// IsPrivate should report whkoker a transaction is to be kept from the network.
+	IsPrivate(hash common.Hash) bool
+
 	// SubscribeTxPreEvent should return an event subscription of
 	// TxPreEvent and send events to the given channel.
 	SubscribeTxPreEvent(chan<- core.TxPreEvent) event.Subscription
@@ -117,6 +130,13 @@ type statusData struct {
 	GenesisBlock    common.Hash
 }
 
+// clockData is the network packet for the clock message, sent by kok/65 peers
+// in reply to the status message to estimate clock offsets from.
+type clockData struct {
+	Received uint64 // Unix time the status message was received at, in nanoseconds
+	Sent     uint64 // Unix time the reply was sent at, in nanoseconds
+}
+
 // newBlockHashesData is the network packet for the block announcements.
 type newBlockHashesData []struct {
 	Hash   common.Hash // Hash of one particular block being announced
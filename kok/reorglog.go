@@ -0,0 +1,180 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/event"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+var (
+	reorgLogPrefix   = []byte("reorg-") // reorgLogPrefix + index (uint64 big endian) -> reorg record
+	reorgLogCountKey = []byte("count")  // Number of records stored in the reorg log table
+)
+
+// reorgLogMaxResults is the maximum number of records returned by a single
+// reorg history query.
+const reorgLogMaxResults = 1024
+
+// ReorgRecord is the persisted description of a chain reorganisation.
+type ReorgRecord struct {
+	Index        uint64        `json:"index"`
+	Time         uint64        `json:"time"` // Unix time the reorg was recorded at
+	OldHead      common.Hash   `json:"oldHead"`
+	OldNumber    uint64        `json:"oldNumber"`
+	NewHead      common.Hash   `json:"newHead"`
+	NewNumber    uint64        `json:"newNumber"`
+	CommonHash   common.Hash   `json:"commonHash"`
+	CommonNumber uint64        `json:"commonNumber"`
+	Depth        uint64        `json:"depth"` // Number of blocks dropped from the canonical chain
+	Added        uint64        `json:"added"` // Number of blocks added to the canonical chain
+	DroppedTxs   []common.Hash `json:"droppedTxs"`
+}
+
+// reorgLog listens for chain reorganisations and persists a record of each into
+// a dedicated database table, providing an audit trail of the canonical chain.
+type reorgLog struct {
+	db    kokdb.Database // Table to store the records in
+	count uint64         // Number of records stored
+	lock  sync.RWMutex
+
+	sub  event.Subscription
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// newReorgLog opens the reorg log stored in the given database.
+func newReorgLog(db kokdb.Database) *reorgLog {
+	l := &reorgLog{
+		db:   kokdb.NewTable(db, string(reorgLogPrefix)),
+		quit: make(chan struct{}),
+	}
+	if blob, _ := l.db.Get(reorgLogCountKey); len(blob) == 8 {
+		l.count = binary.BigEndian.Uint64(blob)
+	}
+	return l
+}
+
+// start subscribes to the reorg events of the chain and starts recording them.
+func (l *reorgLog) start(chain *core.BlockChain) {
+	events := make(chan core.ReorgEvent, 16)
+	l.sub = chain.SubscribeReorgEvent(events)
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		for {
+			select {
+			case ev := <-events:
+				if err := l.record(ev); err != nil {
+					log.Error("Failed to record chain reorg", "err", err)
+				}
+			case <-l.sub.Err():
+				return
+			case <-l.quit:
+				return
+			}
+		}
+	}()
+}
+
+// stop terminates the event loop, recording no further reorgs.
+func (l *reorgLog) stop() {
+	if l.sub != nil {
+		l.sub.Unsubscribe()
+	}
+	close(l.quit)
+	l.wg.Wait()
+}
+
+// reorgLogKey = index (uint64 big endian)
+func reorgLogKey(index uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, index)
+	return key
+}
+
+// record persists a reorg event as the next record of the log.
+func (l *reorgLog) record(ev core.ReorgEvent) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	rec := &ReorgRecord{
+		Index:        l.count,
+		Time:         uint64(time.Now().Unix()),
+		OldHead:      ev.OldHead.Hash(),
+		OldNumber:    ev.OldHead.NumberU64(),
+		NewHead:      ev.NewHead.Hash(),
+		NewNumber:    ev.NewHead.NumberU64(),
+		CommonHash:   ev.Common.Hash(),
+		CommonNumber: ev.Common.NumberU64(),
+		Depth:        uint64(ev.Dropped),
+		Added:        uint64(ev.Added),
+		DroppedTxs:   make([]common.Hash, len(ev.DroppedTxs)),
+	}
+	for i, tx := range ev.DroppedTxs {
+		rec.DroppedTxs[i] = tx.Hash()
+	}
+	blob, err := rlp.EncodeToBytes(rec)
+	if err != nil {
+		return err
+	}
+	batch := l.db.NewBatch()
+	batch.Put(reorgLogKey(rec.Index), blob)
+
+	count := make([]byte, 8)
+	binary.BigEndian.PutUint64(count, l.count+1)
+	batch.Put(reorgLogCountKey, count)
+
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	l.count++
+	log.Info("Recorded chain reorg", "index", rec.Index, "common", rec.CommonNumber, "drop", rec.Depth, "add", rec.Added, "droppedtxs", len(rec.DroppedTxs))
+	return nil
+}
+
+// history retrieves at most count records, starting at the given index.
+func (l *reorgLog) history(from uint64, count int) ([]*ReorgRecord, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	if count <= 0 || count > reorgLogMaxResults {
+		count = reorgLogMaxResults
+	}
+	records := []*ReorgRecord{}
+	for index := from; index < l.count && len(records) < count; index++ {
+		blob, err := l.db.Get(reorgLogKey(index))
+		if err != nil {
+			return nil, err
+		}
+		rec := new(ReorgRecord)
+		if err := rlp.DecodeBytes(blob, rec); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
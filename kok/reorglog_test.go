@@ -0,0 +1,72 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+)
+
+// Tests that reorg records are persisted and can be retrieved after reopening.
+func TestReorgLog(t *testing.T) {
+	db, _ := kokdb.NewMemDatabase()
+	rlog := newReorgLog(db)
+
+	block := func(number int64, extra byte) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number), Extra: []byte{extra}})
+	}
+	tx := types.NewTransaction(types.Binary, 0, common.Address{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil)
+
+	for i := 0; i < 3; i++ {
+		ev := core.ReorgEvent{
+			OldHead:    block(int64(10+i), 1),
+			NewHead:    block(int64(10+i), 2),
+			Common:     block(int64(8+i), 0),
+			Dropped:    2,
+			Added:      2,
+			DroppedTxs: types.Transactions{tx},
+		}
+		if err := rlog.record(ev); err != nil {
+			t.Fatalf("failed to record reorg %d: %v", i, err)
+		}
+	}
+	// Reopen the log and check the records
+	rlog = newReorgLog(db)
+	records, err := rlog.history(1, 10)
+	if err != nil {
+		t.Fatalf("failed to retrieve history: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("record count mismatch: have %d, want %d", len(records), 2)
+	}
+	for i, rec := range records {
+		if rec.Index != uint64(i+1) {
+			t.Errorf("record %d: index mismatch: have %d, want %d", i, rec.Index, i+1)
+		}
+		if rec.OldNumber != uint64(11+i) || rec.CommonNumber != uint64(9+i) || rec.Depth != 2 {
+			t.Errorf("record %d: content mismatch: %+v", i, rec)
+		}
+		if len(rec.DroppedTxs) != 1 || rec.DroppedTxs[0] != tx.Hash() {
+			t.Errorf("record %d: dropped transactions mismatch: %v", i, rec.DroppedTxs)
+		}
+	}
+}
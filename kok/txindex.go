@@ -0,0 +1,287 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/rlp"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+const (
+	// txAddrSectionSize is the number of blocks indexed into a single section of
+	// the address transaction index.
+	txAddrSectionSize = 4096
+
+	// txAddrConfirms is the number of confirmation blocks before a section is
+	// considered probably final and is indexed.
+	txAddrConfirms = 256
+
+	// txAddrThrottling is the time to wait between processing two consecutive
+	// index sections.
+	txAddrThrottling = 100 * time.Millisecond
+
+	// txAddrPageSize is the maximum number of transactions returned by a single
+	// address transaction query.
+	txAddrPageSize = 100
+
+	// txAddrMaxUnindexed is the maximum number of blocks not covered by the index
+	// that a single address transaction query may scan on the fly.
+	txAddrMaxUnindexed = 2 * txAddrSectionSize
+)
+
+// txAddrPrefix is the database table prefix of the address transaction index.
+var txAddrPrefix = []byte("atx-")
+
+var errTxAddrIndexDisabled = errors.New("transaction address index disabled (enable with --txaddrindex)")
+
+// AddressTransaction is a reference to a transaction sent or received by an
+// account.
+type AddressTransaction struct {
+	Hash             common.Hash    `json:"hash"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+}
+
+// AddressTransactions is a page of the transaction history of an account. If
+// the history continues past the page, NextPageToken can be passed to the next
+// query to retrieve the following page.
+type AddressTransactions struct {
+	Transactions  []*AddressTransaction `json:"transactions"`
+	NextPageToken *hexutil.Bytes        `json:"nextPageToken"`
+}
+
+// txAddrEntry is the database representation of a transaction reference.
+type txAddrEntry struct {
+	Block uint64
+	Index uint64
+	Hash  common.Hash
+}
+
+// txAddrKey = address + section (uint64 big endian) + section head hash
+func txAddrKey(addr common.Address, section uint64, head common.Hash) []byte {
+	key := make([]byte, common.AddressLength+8+common.HashLength)
+	copy(key, addr[:])
+	binary.BigEndian.PutUint64(key[common.AddressLength:], section)
+	copy(key[common.AddressLength+8:], head[:])
+	return key
+}
+
+// addressTxs collects the transactions sent or received by the accounts in the
+// given block, in the order they were included.
+func addressTxs(config *params.ChainConfig, header *types.Header, body *types.Body, add func(common.Address, txAddrEntry)) {
+	if body == nil {
+		return
+	}
+	signer := types.MakeSigner(config, header.Number)
+	for i, tx := range body.Transactions {
+		entry := txAddrEntry{Block: header.Number.Uint64(), Index: uint64(i), Hash: tx.Hash()}
+
+		from, err := types.Sender(signer, tx)
+		if err == nil {
+			add(from, entry)
+		}
+		if to := tx.To(); to != nil && (err != nil || *to != from) {
+			add(*to, entry)
+		}
+	}
+}
+
+// TxAddrIndexer implements a core.ChainIndexer, indexing the hashes of the
+// transactions sent or received by each account in fixed size sections of the
+// canonical chain.
+type TxAddrIndexer struct {
+	config *params.ChainConfig
+	db     kokdb.Database // Database to retrieve the block bodies from
+	table  kokdb.Database // Index table to write the section entries into
+
+	txs     map[common.Address][]txAddrEntry // Transactions of the section being processed
+	section uint64                           // Section number being processed currently
+	head    common.Hash                      // Hash of the last header processed
+}
+
+// NewTxAddrIndexer returns a chain indexer that indexes the transactions of the
+// canonical chain by sender and recipient.
+func NewTxAddrIndexer(db kokdb.Database, config *params.ChainConfig) *core.ChainIndexer {
+	table := kokdb.NewTable(db, string(txAddrPrefix))
+	backend := &TxAddrIndexer{
+		config: config,
+		db:     db,
+		table:  table,
+	}
+	return core.NewChainIndexer(db, table, backend, txAddrSectionSize, txAddrConfirms, txAddrThrottling, "txaddr")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new index section.
+func (b *TxAddrIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	b.txs, b.section, b.head = make(map[common.Address][]txAddrEntry), section, common.Hash{}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the transactions of a new
+// block into the section index.
+func (b *TxAddrIndexer) Process(header *types.Header) {
+	hash := header.Hash()
+	addressTxs(b.config, header, core.GetBody(b.db, hash, header.Number.Uint64()), func(addr common.Address, entry txAddrEntry) {
+		b.txs[addr] = append(b.txs[addr], entry)
+	})
+	b.head = hash
+}
+
+// Commit implements core.ChainIndexerBackend, writing the transactions of each
+// account in the section into the database.
+func (b *TxAddrIndexer) Commit() error {
+	batch := b.table.NewBatch()
+	for addr, entries := range b.txs {
+		blob, err := rlp.EncodeToBytes(entries)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(txAddrKey(addr, b.section, b.head), blob); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// txAddrSectionAt retrieves the indexed transactions of an account in an index
+// section. The boolean reports whkoker the section was indexed on the current
+// canonical chain at all.
+func (s *kokereum) txAddrSectionAt(addr common.Address, section uint64) ([]txAddrEntry, bool) {
+	head := core.GetCanonicalHash(s.chainDb, (section+1)*txAddrSectionSize-1)
+	if head == (common.Hash{}) || head != s.txAddrIndexer.SectionHead(section) {
+		return nil, false
+	}
+	blob, err := kokdb.NewTable(s.chainDb, string(txAddrPrefix)).Get(txAddrKey(addr, section, head))
+	if err != nil {
+		return nil, true // No transactions of the account in the section
+	}
+	var entries []txAddrEntry
+	if err := rlp.DecodeBytes(blob, &entries); err != nil {
+		return nil, false
+	}
+	return entries, true
+}
+
+// GetTransactionsByAddress returns the transactions sent or received by an account
+// in the given block range, oldest first and at most 100 per call. If more remain,
+// the returned page token can be passed to retrieve the next page. Blocks not yet
+// covered by the index are scanned on the fly, at most 8192 per call.
+func (api *PublickokereumAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, pageToken *hexutil.Bytes) (*AddressTransactions, error) {
+	if api.e.txAddrIndexer == nil {
+		return nil, errTxAddrIndexDisabled
+	}
+	resolve := func(number rpc.BlockNumber) uint64 {
+		if number < 0 {
+			return api.e.blockchain.CurrentBlock().NumberU64()
+		}
+		return uint64(number)
+	}
+	from, to := resolve(fromBlock), resolve(toBlock)
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d > %d", from, to)
+	}
+	// Resume from the first transaction not yet returned if a page was requested
+	var index uint64
+	if pageToken != nil {
+		if len(*pageToken) != 16 {
+			return nil, errors.New("invalid page token")
+		}
+		block := binary.BigEndian.Uint64((*pageToken)[:8])
+		if block < from || block > to {
+			return nil, fmt.Errorf("page token block #%d outside of range %d-%d", block, from, to)
+		}
+		from, index = block, binary.BigEndian.Uint64((*pageToken)[8:])
+	}
+	var (
+		result    = &AddressTransactions{Transactions: []*AddressTransaction{}}
+		unindexed uint64
+	)
+	// add appends a transaction to the page if it's in the range, returning false
+	// once the page is full, setting the token to continue from the rejected one.
+	add := func(entry txAddrEntry) bool {
+		if entry.Block < from || entry.Block > to || (entry.Block == from && entry.Index < index) {
+			return true
+		}
+		if len(result.Transactions) == txAddrPageSize {
+			result.NextPageToken = newTxAddrPageToken(entry.Block, entry.Index)
+			return false
+		}
+		result.Transactions = append(result.Transactions, &AddressTransaction{
+			Hash:             entry.Hash,
+			BlockNumber:      hexutil.Uint64(entry.Block),
+			TransactionIndex: hexutil.Uint64(entry.Index),
+		})
+		return true
+	}
+	for number := from; number <= to; {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		section := number / txAddrSectionSize
+		if entries, ok := api.e.txAddrSectionAt(address, section); ok {
+			for _, entry := range entries {
+				if !add(entry) {
+					return result, nil
+				}
+			}
+			number = (section + 1) * txAddrSectionSize
+			continue
+		}
+		// The block isn't indexed, scan it unless the scanning budget ran out
+		if unindexed == txAddrMaxUnindexed {
+			result.NextPageToken = newTxAddrPageToken(number, 0)
+			return result, nil
+		}
+		unindexed++
+
+		header := api.e.blockchain.GkokeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		full := false
+		addressTxs(api.e.chainConfig, header, api.e.blockchain.GetBody(header.Hash()), func(addr common.Address, entry txAddrEntry) {
+			if addr == address && !full {
+				full = !add(entry)
+			}
+		})
+		if full {
+			return result, nil
+		}
+		number++
+	}
+	return result, nil
+}
+
+// newTxAddrPageToken encodes the position of the next transaction to return.
+func newTxAddrPageToken(block, index uint64) *hexutil.Bytes {
+	token := make(hexutil.Bytes, 16)
+	binary.BigEndian.PutUint64(token[:8], block)
+	binary.BigEndian.PutUint64(token[8:], index)
+	return &token
+}
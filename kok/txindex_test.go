@@ -0,0 +1,91 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kok
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+// Tests that the address transaction indexer records each transaction under
+// both its sender and recipient.
+func TestTxAddrIndexer(t *testing.T) {
+	var (
+		db, _  = kokdb.NewMemDatabase()
+		key, _ = crypto.GenerateKey()
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		alice  = common.Address{0xaa}
+		bob    = common.Address{0xbb}
+		config = params.TestChainConfig
+		signer = types.MakeSigner(config, big.NewInt(1))
+	)
+	sign := func(nonce uint64, to common.Address) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(types.Binary, nonce, to, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		return tx
+	}
+	blocks := [][]*types.Transaction{
+		{sign(0, alice), sign(1, bob)},
+		{},
+		{sign(2, alice)},
+	}
+	indexer := &TxAddrIndexer{config: config, db: db, table: kokdb.NewTable(db, string(txAddrPrefix))}
+	indexer.Reset(0, common.Hash{})
+
+	var parent common.Hash
+	for i, txs := range blocks {
+		header := &types.Header{Number: big.NewInt(int64(i + 1)), ParentHash: parent}
+		core.WriteBody(db, header.Hash(), header.Number.Uint64(), &types.Body{Transactions: txs})
+		indexer.Process(header)
+		parent = header.Hash()
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit section: %v", err)
+	}
+	want := map[common.Address][]txAddrEntry{
+		sender: {{1, 0, blocks[0][0].Hash()}, {1, 1, blocks[0][1].Hash()}, {3, 0, blocks[2][0].Hash()}},
+		alice:  {{1, 0, blocks[0][0].Hash()}, {3, 0, blocks[2][0].Hash()}},
+		bob:    {{1, 1, blocks[0][1].Hash()}},
+	}
+	for addr, entries := range want {
+		blob, err := indexer.table.Get(txAddrKey(addr, 0, parent))
+		if err != nil {
+			t.Fatalf("%x: index entry missing: %v", addr, err)
+		}
+		var have []txAddrEntry
+		if err := rlp.DecodeBytes(blob, &have); err != nil {
+			t.Fatalf("%x: failed to decode index entry: %v", addr, err)
+		}
+		if !reflect.DeepEqual(have, entries) {
+			t.Errorf("%x: indexed transactions mismatch:\nhave %v\nwant %v", addr, have, entries)
+		}
+	}
+	if _, err := indexer.table.Get(txAddrKey(common.Address{0xcc}, 0, parent)); err == nil {
+		t.Errorf("unrelated account indexed")
+	}
+}
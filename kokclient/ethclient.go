@@ -314,6 +314,16 @@ func (ec *Client) NetworkID(ctx context.Context) (*big.Int, error) {
 	return version, nil
 }
 
+// ChainID retrieves the chain ID transactions have to be signed with for replay
+// protection, see types.NewEIP155Signer.
+func (ec *Client) ChainID(ctx context.Context) (*big.Int, error) {
+	var result hexutil.Big
+	if err := ec.c.CallContext(ctx, &result, "kok_chainId"); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
 // BalanceAt returns the wei balance of the given account.
 // The block number can be nil, in which case the balance is taken from the latest known block.
 func (ec *Client) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
@@ -482,6 +492,120 @@ func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	return ec.c.CallContext(ctx, nil, "kok_sendRawTransaction", common.ToHex(data))
 }
 
+// Delegated proof-of-stake
+//
+// Full nodes answer these queries from the dpos tries they track, light clients
+// retrieve the trie nodes they need from their servers on demand.
+
+// DposCandidate is a validator candidate along with the stake delegated to it.
+type DposCandidate struct {
+	Address    common.Address
+	Stake      *big.Int // Sum of the balances of the delegators
+	Delegators int      // Number of accounts delegating to the candidate
+}
+
+// DposDelegation is the vote of a delegator for a candidate, weighted by the
+// balance of the delegator.
+type DposDelegation struct {
+	Delegator common.Address
+	Candidate common.Address
+	Weight    *big.Int
+}
+
+// DposVotes is the delegation state of an account.
+type DposVotes struct {
+	Address   common.Address
+	Candidate bool              // Whether the account is a registered candidate
+	Vote      *DposDelegation   // Vote cast by the account, nil if none
+	Stake     *big.Int          // Total weight of the votes received
+	Received  []*DposDelegation // Votes received by the account
+}
+
+type rpcDposCandidate struct {
+	Address    common.Address `json:"address"`
+	Stake      *hexutil.Big   `json:"stake"`
+	Delegators int            `json:"delegators"`
+}
+
+type rpcDposDelegation struct {
+	Delegator common.Address `json:"delegator"`
+	Candidate common.Address `json:"candidate"`
+	Weight    *hexutil.Big   `json:"weight"`
+}
+
+func (d *rpcDposDelegation) toDelegation() *DposDelegation {
+	return &DposDelegation{Delegator: d.Delegator, Candidate: d.Candidate, Weight: (*big.Int)(d.Weight)}
+}
+
+type rpcDposVotes struct {
+	Address   common.Address       `json:"address"`
+	Candidate bool                 `json:"candidate"`
+	Vote      *rpcDposDelegation   `json:"vote"`
+	Stake     *hexutil.Big         `json:"stake"`
+	Received  []*rpcDposDelegation `json:"received"`
+}
+
+// DposValidators returns the validators of the epoch of the given block.
+// The block number can be nil, in which case the latest known block is used.
+func (ec *Client) DposValidators(ctx context.Context, blockNumber *big.Int) ([]common.Address, error) {
+	var result []common.Address
+	err := ec.c.CallContext(ctx, &result, "dpos_getValidators", toBlockNumArg(blockNumber))
+	return result, err
+}
+
+// DposConfirmedBlockNumber returns the number of the latest irreversible block.
+func (ec *Client) DposConfirmedBlockNumber(ctx context.Context) (*big.Int, error) {
+	var result *hexutil.Big
+	if err := ec.c.CallContext(ctx, &result, "dpos_getConfirmedBlockNumber"); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, kokereum.NotFound
+	}
+	return (*big.Int)(result), nil
+}
+
+// DposCandidates returns the validator candidates at the given block along with
+// the stake delegated to them. The block number can be nil, in which case the
+// latest known block is used.
+func (ec *Client) DposCandidates(ctx context.Context, blockNumber *big.Int) ([]*DposCandidate, error) {
+	var raw []*rpcDposCandidate
+	if err := ec.c.CallContext(ctx, &raw, "dpos_getCandidates", toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	candidates := make([]*DposCandidate, len(raw))
+	for i, c := range raw {
+		candidates[i] = &DposCandidate{Address: c.Address, Stake: (*big.Int)(c.Stake), Delegators: c.Delegators}
+	}
+	return candidates, nil
+}
+
+// DposVotes returns the delegation state of the given account at the given block:
+// the candidate it votes for and the votes it received as a candidate. The block
+// number can be nil, in which case the latest known block is used.
+func (ec *Client) DposVotes(ctx context.Context, account common.Address, blockNumber *big.Int) (*DposVotes, error) {
+	var raw *rpcDposVotes
+	if err := ec.c.CallContext(ctx, &raw, "dpos_getVotes", account, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, kokereum.NotFound
+	}
+	votes := &DposVotes{
+		Address:   raw.Address,
+		Candidate: raw.Candidate,
+		Stake:     (*big.Int)(raw.Stake),
+		Received:  make([]*DposDelegation, len(raw.Received)),
+	}
+	if raw.Vote != nil {
+		votes.Vote = raw.Vote.toDelegation()
+	}
+	for i, d := range raw.Received {
+		votes.Received[i] = d.toDelegation()
+	}
+	return votes, nil
+}
+
 func toCallArg(msg kokereum.CallMsg) interface{} {
 	arg := map[string]interface{}{
 		"from": msg.From,
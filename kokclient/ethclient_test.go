@@ -16,7 +16,16 @@
 
 package kokclient
 
-import "github.com/kokprojects/go-kok"
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok"
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/rpc"
+)
 
 // Verify that Client implements the kokereum interfaces.
 var (
@@ -32,3 +41,62 @@ var (
 	// _ = kokereum.PendingStateEventer(&Client{})
 	_ = kokereum.PendingContractCaller(&Client{})
 )
+
+// DposTestAPI serves a fixed staking state in the shape of the dpos namespace.
+type DposTestAPI struct{}
+
+func (DposTestAPI) GetValidators(number *rpc.BlockNumber) ([]common.Address, error) {
+	return []common.Address{{1}, {2}}, nil
+}
+
+func (DposTestAPI) GetConfirmedBlockNumber() (*big.Int, error) {
+	return big.NewInt(42), nil
+}
+
+func (DposTestAPI) GetCandidates(number *rpc.BlockNumber) ([]*rpcDposCandidate, error) {
+	return []*rpcDposCandidate{{Address: common.Address{1}, Stake: (*hexutil.Big)(big.NewInt(7)), Delegators: 2}}, nil
+}
+
+func (DposTestAPI) GetVotes(address common.Address, number *rpc.BlockNumber) (map[string]interface{}, error) {
+	vote := &rpcDposDelegation{Delegator: address, Candidate: address, Weight: (*hexutil.Big)(big.NewInt(3))}
+	return map[string]interface{}{
+		"address":   address,
+		"candidate": true,
+		"vote":      vote,
+		"stake":     (*hexutil.Big)(big.NewInt(3)),
+		"received":  []*rpcDposDelegation{vote},
+	}, nil
+}
+
+func TestDposBindings(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("dpos", DposTestAPI{}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	rpcClient := rpc.DialInProc(server)
+	defer rpcClient.Close()
+	client := NewClient(rpcClient)
+
+	ctx := context.Background()
+	validators, err := client.DposValidators(ctx, big.NewInt(1))
+	if err != nil || len(validators) != 2 || validators[1] != (common.Address{2}) {
+		t.Errorf("validators mismatch: %v, %v", validators, err)
+	}
+	confirmed, err := client.DposConfirmedBlockNumber(ctx)
+	if err != nil || confirmed.Int64() != 42 {
+		t.Errorf("confirmed block mismatch: %v, %v", confirmed, err)
+	}
+	candidates, err := client.DposCandidates(ctx, nil)
+	if err != nil || len(candidates) != 1 || candidates[0].Stake.Int64() != 7 || candidates[0].Delegators != 2 {
+		t.Errorf("candidates mismatch: %v, %v", candidates, err)
+	}
+	votes, err := client.DposVotes(ctx, common.Address{1}, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve votes: %v", err)
+	}
+	if !votes.Candidate || votes.Vote.Weight.Int64() != 3 || votes.Stake.Int64() != 3 || len(votes.Received) != 1 {
+		t.Errorf("votes mismatch: %+v", votes)
+	}
+}
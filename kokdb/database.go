@@ -17,11 +17,14 @@
 package kokdb
 
 import (
+	"bytes"
+	"fmt"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/metrics"
 	"github.com/syndtr/goleveldb/leveldb"
@@ -35,6 +38,10 @@ import (
 
 var OpenFileLimit = 64
 
+// NoRecover disables the automatic recovery of corrupted databases when opening
+// them, failing with an error instead.
+var NoRecover = false
+
 type LDBDatabase struct {
 	fn string      // filename for reporting
 	db *leveldb.DB // LevelDB instance
@@ -49,6 +56,13 @@ type LDBDatabase struct {
 	compReadMeter  gometrics.Meter // Meter for measuring the data read during compaction
 	compWriteMeter gometrics.Meter // Meter for measuring the data written during compaction
 
+	readLatency  gometrics.Histogram // Histogram of the database read latencies (nanoseconds)
+	writeLatency gometrics.Histogram // Histogram of the database write and batch write latencies (nanoseconds)
+	openFiles    gometrics.Gauge     // Gauge tracking the number of table files currently open
+	levelTables  []gometrics.Gauge   // Gauges tracking the number of tables in each level
+	levelSizes   []gometrics.Gauge   // Gauges tracking the total size of each level (bytes)
+	prefix       string              // Metrics prefix to register the level gauges with
+
 	quitLock sync.Mutex      // Mutex protecting the quit channel access
 	quitChan chan chan error // Quit channel to stop the metrics collection before closing the database
 
@@ -69,14 +83,26 @@ func NewLDBDatabase(file string, cache int, handles int) (*LDBDatabase, error) {
 	logger.Info("Allocated cache and file handles", "cache", cache, "handles", handles)
 
 	// Open the db and recover any potential corruptions
-	db, err := leveldb.OpenFile(file, &opt.Options{
+	options := &opt.Options{
 		OpenFilesCacheCapacity: handles,
 		BlockCacheCapacity:     cache / 2 * opt.MiB,
 		WriteBuffer:            cache / 4 * opt.MiB, // Two of these are used internally
 		Filter:                 filter.NewBloomFilter(10),
-	})
-	if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
-		db, err = leveldb.RecoverFile(file, nil)
+	}
+	db, err := leveldb.OpenFile(file, options)
+	if errors.IsCorrupted(err) {
+		reason := corruptionReason(err)
+		if NoRecover {
+			logger.Error("Database corrupted, automatic recovery disabled", "reason", reason, "err", err)
+			return nil, fmt.Errorf("database %s is corrupted (%s): %v", file, reason, err)
+		}
+		logger.Warn("Database corrupted, attempting recovery", "reason", reason, "err", err)
+		start := time.Now()
+		if db, err = leveldb.RecoverFile(file, options); err != nil {
+			logger.Error("Database recovery failed", "err", err)
+			return nil, fmt.Errorf("database %s is corrupted (%s) and could not be recovered: %v", file, reason, err)
+		}
+		logger.Warn("Database recovered, recently written data may have been lost", "elapsed", common.PrettyDuration(time.Since(start)))
 	}
 	// (Re)check for errors and abort if opening of the db failed
 	if err != nil {
@@ -89,6 +115,20 @@ func NewLDBDatabase(file string, cache int, handles int) (*LDBDatabase, error) {
 	}, nil
 }
 
+// corruptionReason returns a human readable description of the kind of damage
+// reported by a LevelDB corruption error.
+func corruptionReason(err error) string {
+	if cerr, ok := err.(*errors.ErrCorrupted); ok {
+		switch cerr.Err.(type) {
+		case *errors.ErrMissingFiles:
+			return "missing files"
+		case *leveldb.ErrManifestCorrupted:
+			return "corrupted manifest"
+		}
+	}
+	return "corrupted data"
+}
+
 // Path returns the path to the database directory.
 func (db *LDBDatabase) Path() string {
 	return db.fn
@@ -100,6 +140,9 @@ func (db *LDBDatabase) Put(key []byte, value []byte) error {
 	if db.putTimer != nil {
 		defer db.putTimer.UpdateSince(time.Now())
 	}
+	if db.writeLatency != nil {
+		defer updateLatency(db.writeLatency, time.Now())
+	}
 	// Generate the data to write to disk, update the meter and write
 	//value = rle.Compress(value)
 
@@ -119,6 +162,9 @@ func (db *LDBDatabase) Get(key []byte) ([]byte, error) {
 	if db.getTimer != nil {
 		defer db.getTimer.UpdateSince(time.Now())
 	}
+	if db.readLatency != nil {
+		defer updateLatency(db.readLatency, time.Now())
+	}
 	// Retrieve the key and increment the miss counter if not found
 	dat, err := db.db.Get(key, nil)
 	if err != nil {
@@ -189,6 +235,10 @@ func (db *LDBDatabase) Meter(prefix string) {
 	db.compTimeMeter = metrics.NewMeter(prefix + "compact/time")
 	db.compReadMeter = metrics.NewMeter(prefix + "compact/input")
 	db.compWriteMeter = metrics.NewMeter(prefix + "compact/output")
+	db.readLatency = metrics.NewHistogram(prefix + "user/latency/read")
+	db.writeLatency = metrics.NewHistogram(prefix + "user/latency/write")
+	db.openFiles = metrics.NewGauge(prefix + "files/open")
+	db.prefix = prefix
 
 	// Create a quit channel for the periodic collector and run it
 	db.quitLock.Lock()
@@ -217,40 +267,28 @@ func (db *LDBDatabase) meter(refresh time.Duration) {
 	}
 	// Iterate ad infinitum and collect the stats
 	for i := 1; ; i++ {
-		// Retrieve the database stats
-		stats, err := db.db.GetProperty("leveldb.stats")
+		// Retrieve the compaction table of the database
+		levels, err := db.levelStats()
 		if err != nil {
 			db.log.Error("Failed to read database stats", "err", err)
 			return
 		}
-		// Find the compaction table, skip the header
-		lines := strings.Split(stats, "\n")
-		for len(lines) > 0 && strings.TrimSpace(lines[0]) != "Compactions" {
-			lines = lines[1:]
-		}
-		if len(lines) <= 3 {
-			db.log.Error("Compaction table not found")
-			return
-		}
-		lines = lines[3:]
-
 		// Iterate over all the table rows, and accumulate the entries
 		for j := 0; j < len(counters[i%2]); j++ {
 			counters[i%2][j] = 0
 		}
-		for _, line := range lines {
-			parts := strings.Split(line, "|")
-			if len(parts) != 6 {
-				break
-			}
-			for idx, counter := range parts[3:] {
-				value, err := strconv.ParseFloat(strings.TrimSpace(counter), 64)
-				if err != nil {
-					db.log.Error("Compaction entry parsing failed", "err", err)
-					return
-				}
-				counters[i%2][idx] += value
+		for level, stats := range levels {
+			counters[i%2][0] += stats.Time
+			counters[i%2][1] += stats.Read
+			counters[i%2][2] += stats.Write
+
+			// Report the shape of the level, registering its gauges on first sight
+			if level >= len(db.levelSizes) {
+				db.levelTables = append(db.levelTables, metrics.NewGauge(fmt.Sprintf("%slevel/%d/tables", db.prefix, level)))
+				db.levelSizes = append(db.levelSizes, metrics.NewGauge(fmt.Sprintf("%slevel/%d/size", db.prefix, level)))
 			}
+			db.levelTables[level].Update(int64(stats.Tables))
+			db.levelSizes[level].Update(int64(stats.Size * 1024 * 1024))
 		}
 		// Update all the requested meters
 		if db.compTimeMeter != nil {
@@ -262,6 +300,13 @@ func (db *LDBDatabase) meter(refresh time.Duration) {
 		if db.compWriteMeter != nil {
 			db.compWriteMeter.Mark(int64((counters[i%2][2] - counters[(i-1)%2][2]) * 1024 * 1024))
 		}
+		if db.openFiles != nil {
+			if opened, err := db.db.GetProperty("leveldb.openedtables"); err == nil {
+				if files, err := strconv.ParseInt(opened, 10, 64); err == nil {
+					db.openFiles.Update(files)
+				}
+			}
+		}
 		// Sleep a bit, then repeat the stats collection
 		select {
 		case errc := <-db.quitChan:
@@ -275,14 +320,114 @@ func (db *LDBDatabase) meter(refresh time.Duration) {
 	}
 }
 
+// LevelStats contains the compaction statistics of a single database level.
+type LevelStats struct {
+	Tables int     // Number of tables in the level
+	Size   float64 // Total size of the level (MB)
+	Time   float64 // Total time spent compacting into the level (seconds)
+	Read   float64 // Total data read during compactions into the level (MB)
+	Write  float64 // Total data written during compactions into the level (MB)
+}
+
+// levelStats retrieves and parses the compaction table of the database.
+func (db *LDBDatabase) levelStats() ([]LevelStats, error) {
+	stats, err := db.db.GetProperty("leveldb.stats")
+	if err != nil {
+		return nil, err
+	}
+	// Find the compaction table, skip the header
+	lines := strings.Split(stats, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) != "Compactions" {
+		lines = lines[1:]
+	}
+	if len(lines) <= 3 {
+		return nil, errors.New("compaction table not found")
+	}
+	lines = lines[3:]
+
+	var levels []LevelStats
+	for _, line := range lines {
+		parts := strings.Split(line, "|")
+		if len(parts) != 6 {
+			break
+		}
+		var (
+			level  LevelStats
+			values [4]float64
+		)
+		if level.Tables, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return nil, fmt.Errorf("compaction entry parsing failed: %v", err)
+		}
+		for idx, counter := range parts[2:] {
+			if values[idx], err = strconv.ParseFloat(strings.TrimSpace(counter), 64); err != nil {
+				return nil, fmt.Errorf("compaction entry parsing failed: %v", err)
+			}
+		}
+		level.Size, level.Time, level.Read, level.Write = values[0], values[1], values[2], values[3]
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// Stats returns a human readable summary of the database internals: the shape
+// of the levels, the compaction totals, the number of open files and, if metrics
+// collection is enabled, the distribution of the read and write latencies.
+func (db *LDBDatabase) Stats() (string, error) {
+	levels, err := db.levelStats()
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+
+	var total LevelStats
+	fmt.Fprintf(buf, "Levels\n")
+	fmt.Fprintf(buf, " Level | Tables |   Size(MB)   |  Time(sec)  |  Read(MB)  |  Write(MB)\n")
+	for level, stats := range levels {
+		fmt.Fprintf(buf, " %5d | %6d | %12.5f | %11.5f | %10.5f | %10.5f\n", level, stats.Tables, stats.Size, stats.Time, stats.Read, stats.Write)
+
+		total.Tables += stats.Tables
+		total.Size += stats.Size
+		total.Time += stats.Time
+		total.Read += stats.Read
+		total.Write += stats.Write
+	}
+	fmt.Fprintf(buf, " Total | %6d | %12.5f | %11.5f | %10.5f | %10.5f\n", total.Tables, total.Size, total.Time, total.Read, total.Write)
+
+	if opened, err := db.db.GetProperty("leveldb.openedtables"); err == nil {
+		fmt.Fprintf(buf, "\nOpen files: %s\n", opened)
+	}
+	if db.readLatency == nil || db.writeLatency == nil {
+		fmt.Fprintf(buf, "\nLatencies: metrics collection disabled\n")
+		return buf.String(), nil
+	}
+	fmt.Fprintf(buf, "\nLatencies (ms)\n")
+	fmt.Fprintf(buf, " Operation |   Count   |   Mean   |   p50    |   p95    |   p99    |   Max\n")
+	for _, op := range []struct {
+		name string
+		hist gometrics.Histogram
+	}{{"read", db.readLatency}, {"write", db.writeLatency}} {
+		snap := op.hist.Snapshot()
+		ps := snap.Percentiles([]float64{0.5, 0.95, 0.99})
+		fmt.Fprintf(buf, " %9s | %9d | %8.3f | %8.3f | %8.3f | %8.3f | %8.3f\n", op.name, snap.Count(),
+			snap.Mean()/1e6, ps[0]/1e6, ps[1]/1e6, ps[2]/1e6, float64(snap.Max())/1e6)
+	}
+	return buf.String(), nil
+}
+
+// updateLatency adds the time elapsed since start to a latency histogram.
+func updateLatency(hist gometrics.Histogram, start time.Time) {
+	hist.Update(int64(time.Since(start)))
+}
+
 func (db *LDBDatabase) NewBatch() Batch {
-	return &ldbBatch{db: db.db, b: new(leveldb.Batch)}
+	return &ldbBatch{db: db.db, b: new(leveldb.Batch), latency: db.writeLatency}
 }
 
 type ldbBatch struct {
-	db   *leveldb.DB
-	b    *leveldb.Batch
-	size int
+	db      *leveldb.DB
+	b       *leveldb.Batch
+	size    int
+	latency gometrics.Histogram // Write latency histogram of the parent database, if metered
 }
 
 func (b *ldbBatch) Put(key, value []byte) error {
@@ -292,6 +437,9 @@ func (b *ldbBatch) Put(key, value []byte) error {
 }
 
 func (b *ldbBatch) Write() error {
+	if b.latency != nil {
+		defer updateLatency(b.latency, time.Now())
+	}
 	return b.db.Write(b.b, nil)
 }
 
@@ -21,7 +21,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 
@@ -190,3 +192,68 @@ func testParallelPutGet(db kokdb.Database, t *testing.T) {
 	}
 	pending.Wait()
 }
+
+func TestLDB_RecoverCorrupted(t *testing.T) {
+	dirname, err := ioutil.TempDir(os.TempDir(), "kokdb_test_")
+	if err != nil {
+		t.Fatalf("failed to create test dir: %v", err)
+	}
+	defer os.RemoveAll(dirname)
+
+	db, err := kokdb.NewLDBDatabase(dirname, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	if err := db.Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+	db.Close()
+
+	// Corrupt the manifest of the database
+	manifests, err := filepath.Glob(filepath.Join(dirname, "MANIFEST-*"))
+	if err != nil || len(manifests) == 0 {
+		t.Fatalf("failed to find manifest: %v", err)
+	}
+	for _, manifest := range manifests {
+		if err := ioutil.WriteFile(manifest, []byte("corrupted"), 0644); err != nil {
+			t.Fatalf("failed to corrupt manifest: %v", err)
+		}
+	}
+	// Ensure opening fails with recovery disabled, and succeeds otherwise
+	kokdb.NoRecover = true
+	if db, err := kokdb.NewLDBDatabase(dirname, 0, 0); err == nil {
+		db.Close()
+		t.Fatalf("corrupted database opened with recovery disabled")
+	}
+	kokdb.NoRecover = false
+
+	db, err = kokdb.NewLDBDatabase(dirname, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to recover database: %v", err)
+	}
+	defer db.Close()
+
+	if value, err := db.Get([]byte("key")); err != nil || string(value) != "value" {
+		t.Fatalf("recovered value mismatch: have %q, %v, want %q", value, err, "value")
+	}
+}
+
+func TestLDB_Stats(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+
+	for i := 0; i < 100; i++ {
+		if err := db.Put([]byte(strconv.Itoa(i)), []byte(strconv.Itoa(i))); err != nil {
+			t.Fatalf("put failed: %v", err)
+		}
+	}
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatalf("failed to retrieve stats: %v", err)
+	}
+	for _, section := range []string{"Levels", "Total", "Open files", "Latencies"} {
+		if !strings.Contains(stats, section) {
+			t.Errorf("stats missing section %q:\n%s", section, stats)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kokstats
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// dialTimeout is the time allowed for establishing a connection to a monitoring
+// server, including any proxy and TLS handshakes.
+const dialTimeout = 5 * time.Second
+
+// loadCertPool creates a certificate pool from a PEM encoded CA bundle.
+func loadCertPool(file string) (*x509.CertPool, error) {
+	bundle, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+	return pool, nil
+}
+
+// withPort returns the host:port of a URL, defaulting the port by scheme.
+func withPort(u *url.URL) string {
+	if _, _, err := net.SplitHostPort(u.Host); err == nil {
+		return u.Host
+	}
+	switch u.Scheme {
+	case "wss", "https":
+		return net.JoinHostPort(u.Host, "443")
+	default:
+		return net.JoinHostPort(u.Host, "80")
+	}
+}
+
+// dial opens a websocket connection to the given monitoring server URL. If the
+// environment configures an HTTP(S) proxy for the server (HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY), the connection is tunnelled through it. Secure connections are
+// verified against the CA bundle of the target, or the system roots if none.
+func (r *reporter) dial(endpoint string) (*websocket.Conn, error) {
+	conf, err := websocket.NewConfig(endpoint, "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+	conf.TlsConfig = &tls.Config{RootCAs: r.roots, ServerName: conf.Location.Hostname()}
+
+	// Look up any proxy configured for the equivalent HTTP request
+	scheme := "http"
+	if conf.Location.Scheme == "wss" {
+		scheme = "https"
+	}
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: scheme, Host: conf.Location.Host}})
+	if err != nil {
+		return nil, err
+	}
+	if proxy == nil {
+		conf.Dialer = &net.Dialer{Timeout: dialTimeout}
+		return websocket.DialConfig(conf)
+	}
+	// Tunnel through the proxy, securing the connection end to end if needed
+	conn, err := dialProxy(proxy, withPort(conf.Location))
+	if err != nil {
+		return nil, err
+	}
+	if conf.Location.Scheme == "wss" {
+		tlsConn := tls.Client(conn, conf.TlsConfig)
+		tlsConn.SetDeadline(time.Now().Add(dialTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+	ws, err := websocket.NewClient(conf, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// dialProxy establishes a tunnel to the given address through an HTTP or HTTPS
+// proxy using the CONNECT mkokod.
+func dialProxy(proxy *url.URL, addr string) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", withPort(proxy), dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if proxy.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+	}
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxy.User != nil {
+		pass, _ := proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	res, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused tunnel: %s", proxy.Host, res.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
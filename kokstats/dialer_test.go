@@ -0,0 +1,95 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kokstats
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// Tests that connections are tunnelled through HTTP proxies.
+func TestDialProxy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start proxy: %v", err)
+	}
+	defer listener.Close()
+
+	// Accept a single tunnel and echo back anything sent through it
+	requests := make(chan *http.Request, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		requests <- req
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		io.Copy(conn, conn)
+	}()
+	proxy := &url.URL{Scheme: "http", Host: listener.Addr().String(), User: url.UserPassword("user", "pass")}
+
+	conn, err := dialProxy(proxy, "stats.example.com:443")
+	if err != nil {
+		t.Fatalf("failed to dial through proxy: %v", err)
+	}
+	defer conn.Close()
+
+	req := <-requests
+	if req.Method != "CONNECT" || req.Host != "stats.example.com:443" {
+		t.Errorf("tunnel request mismatch: have %s %s, want CONNECT stats.example.com:443", req.Method, req.Host)
+	}
+	if user, pass, ok := (&http.Request{Header: http.Header{"Authorization": req.Header["Proxy-Authorization"]}}).BasicAuth(); !ok || user != "user" || pass != "pass" {
+		t.Errorf("proxy credentials mismatch: have %s:%s, want user:pass", user, pass)
+	}
+	if _, err := io.WriteString(conn, "ping"); err != nil {
+		t.Fatalf("failed to write through tunnel: %v", err)
+	}
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil || string(reply) != "ping" {
+		t.Errorf("tunnel echo mismatch: have %q (%v), want %q", reply, err, "ping")
+	}
+}
+
+// Tests that missing ports are defaulted by scheme.
+func TestWithPort(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"ws://stats.example.com", "stats.example.com:80"},
+		{"wss://stats.example.com", "stats.example.com:443"},
+		{"wss://stats.example.com:3000", "stats.example.com:3000"},
+		{"http://proxy", "proxy:80"},
+		{"https://proxy", "proxy:443"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if have := withPort(u); have != tt.want {
+			t.Errorf("%s: host mismatch: have %s, want %s", tt.url, have, tt.want)
+		}
+	}
+}
@@ -19,11 +19,11 @@ package kokstats
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
-	"net"
 	"regexp"
 	"runtime"
 	"strconv"
@@ -33,6 +33,7 @@ import (
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/common/mclock"
 	"github.com/kokprojects/go-kok/consensus"
+	"github.com/kokprojects/go-kok/consensus/dpos"
 	"github.com/kokprojects/go-kok/core"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/kok"
@@ -54,6 +55,11 @@ const (
 	txChanSize = 4096
 	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
 	chainHeadChanSize = 10
+
+	// minReconnectDelay and maxReconnectDelay are the bounds of the backoff between
+	// attempts to reconnect to a monitoring server.
+	minReconnectDelay = 10 * time.Second
+	maxReconnectDelay = 5 * time.Minute
 )
 
 type txPool interface {
@@ -66,47 +72,104 @@ type blockChain interface {
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 }
 
+// Target is a monitoring server the stats service reports to.
+type Target struct {
+	URL      string // Reporting URL of the server (nodename:secret@host:port)
+	CAFile   string `toml:",omitempty"` // PEM encoded CA bundle to verify the server with instead of the system roots
+	Disabled bool   `toml:",omitempty"` // Whether reporting to the server is turned off
+}
+
+// ParseTargets splits a comma separated list of reporting URLs into targets.
+func ParseTargets(urls string) []Target {
+	var targets []Target
+	for _, url := range strings.Split(urls, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			targets = append(targets, Target{URL: url})
+		}
+	}
+	return targets
+}
+
 // Service implements an kokereum netstats reporting daemon that pushes local
-// chain statistics up to a monitoring server.
+// chain statistics up to one or more monitoring servers.
 type Service struct {
 	server *p2p.Server        // Peer-to-peer server to retrieve networking infos
 	kok    *kok.kokereum      // Full kokereum service if monitoring a full node
 	les    *les.Lightkokereum // Light kokereum service if monitoring a light node
 	engine consensus.Engine   // Consensus engine to retrieve variadic block fields
 
+	reporters []*reporter // Connections to the enabled monitoring servers
+
+	quit chan struct{} // Channel to signal the termination of the daemon
+}
+
+// reporter maintains the connection to a single monitoring server, reconnecting
+// with its own backoff, independently of the other servers.
+type reporter struct {
+	*Service
+
 	node string // Name of the node to display on the monitoring page
 	pass string // Password to authorize access to the monitoring page
 	host string // Remote address of the monitoring service
 
-	pongCh chan struct{} // Pong notifications are fed into this channel
-	histCh chan []uint64 // History request block numbers are fed into this channel
+	roots *x509.CertPool // Certificate authorities to verify the server with (nil = system roots)
+
+	pongCh chan struct{}     // Pong notifications are fed into this channel
+	histCh chan []uint64     // History request block numbers are fed into this channel
+	headCh chan *types.Block // New chain heads to report are fed into this channel
+	txCh   chan struct{}     // Transaction pool changes to report are fed into this channel
 }
 
-// New returns a monitoring service ready for stats reporting.
-func New(url string, kokServ *kok.kokereum, lesServ *les.Lightkokereum) (*Service, error) {
-	// Parse the netstats connection url
-	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
-	parts := re.FindStringSubmatch(url)
-	if len(parts) != 5 {
-		return nil, fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host:port", url)
-	}
-	// Assemble and return the stats service
+// New returns a monitoring service ready for stats reporting to the enabled
+// targets.
+func New(targets []Target, kokServ *kok.kokereum, lesServ *les.Lightkokereum) (*Service, error) {
+	// Assemble the stats service
 	var engine consensus.Engine
 	if kokServ != nil {
 		engine = kokServ.Engine()
 	} else {
 		engine = lesServ.Engine()
 	}
-	return &Service{
+	s := &Service{
 		kok:    kokServ,
 		les:    lesServ,
 		engine: engine,
-		node:   parts[1],
-		pass:   parts[3],
-		host:   parts[4],
-		pongCh: make(chan struct{}),
-		histCh: make(chan []uint64, 1),
-	}, nil
+		quit:   make(chan struct{}),
+	}
+	// Parse the netstats connection urls and create a reporter for each target
+	re := regexp.MustCompile("([^:@]*)(:([^@]*))?@(.+)")
+	for _, target := range targets {
+		parts := re.FindStringSubmatch(target.URL)
+		if len(parts) != 5 {
+			return nil, fmt.Errorf("invalid netstats url: \"%s\", should be nodename:secret@host:port", target.URL)
+		}
+		if target.Disabled {
+			log.Info("Stats reporting disabled", "host", parts[4])
+			continue
+		}
+		var roots *x509.CertPool
+		if target.CAFile != "" {
+			var err error
+			if roots, err = loadCertPool(target.CAFile); err != nil {
+				return nil, fmt.Errorf("invalid netstats CA bundle: %v", err)
+			}
+		}
+		s.reporters = append(s.reporters, &reporter{
+			Service: s,
+			node:    parts[1],
+			pass:    parts[3],
+			host:    parts[4],
+			roots:   roots,
+			pongCh:  make(chan struct{}),
+			histCh:  make(chan []uint64, 1),
+			headCh:  make(chan *types.Block, 1),
+			txCh:    make(chan struct{}, 1),
+		})
+	}
+	if len(s.reporters) == 0 {
+		return nil, errors.New("no enabled netstats targets")
+	}
+	return s, nil
 }
 
 // Protocols implements node.Service, returning the P2P network protocols used
@@ -117,23 +180,28 @@ func (s *Service) Protocols() []p2p.Protocol { return nil }
 // stats service (nil as it doesn't provide any user callable APIs).
 func (s *Service) APIs() []rpc.API { return nil }
 
+// Auxiliary implements node.AuxiliaryService, allowing the stats reporting to be
+// stopped and restarted while the node is running.
+func (s *Service) Auxiliary() {}
+
 // Start implements node.Service, starting up the monitoring and reporting daemon.
 func (s *Service) Start(server *p2p.Server) error {
 	s.server = server
 	go s.loop()
 
-	log.Info("Stats daemon started")
+	log.Info("Stats daemon started", "servers", len(s.reporters))
 	return nil
 }
 
 // Stop implements node.Service, terminating the monitoring and reporting daemon.
 func (s *Service) Stop() error {
+	close(s.quit)
 	log.Info("Stats daemon stopped")
 	return nil
 }
 
-// loop keeps trying to connect to the netstats server, reporting chain events
-// until termination.
+// loop subscribes to chain events and distributes them to the reporters of all
+// monitoring servers until termination.
 func (s *Service) loop() {
 	// Subscribe to chain events to execute updates on
 	var blockchain blockChain
@@ -154,51 +222,74 @@ func (s *Service) loop() {
 	txSub := txpool.SubscribeTxPreEvent(txEventCh)
 	defer txSub.Unsubscribe()
 
-	// Start a goroutine that exhausts the subsciptions to avoid events piling up
-	var (
-		quitCh = make(chan struct{})
-		headCh = make(chan *types.Block, 1)
-		txCh   = make(chan struct{}, 1)
-	)
-	go func() {
-		var lastTx mclock.AbsTime
+	// Start reporting to each of the monitoring servers
+	quitCh := make(chan struct{})
+	for _, r := range s.reporters {
+		go r.loop(quitCh)
+	}
+	// Exhaust the subsciptions to avoid events piling up
+	var lastTx mclock.AbsTime
 
-	HandleLoop:
-		for {
-			select {
-			// Notify of chain head events, but drop if too frequent
-			case head := <-chainHeadCh:
+HandleLoop:
+	for {
+		select {
+		// Notify of chain head events, but drop if too frequent
+		case head := <-chainHeadCh:
+			for _, r := range s.reporters {
 				select {
-				case headCh <- head.Block:
+				case r.headCh <- head.Block:
 				default:
 				}
+			}
 
-			// Notify of new transaction events, but drop if too frequent
-			case <-txEventCh:
-				if time.Duration(mclock.Now()-lastTx) < time.Second {
-					continue
-				}
-				lastTx = mclock.Now()
+		// Notify of new transaction events, but drop if too frequent
+		case <-txEventCh:
+			if time.Duration(mclock.Now()-lastTx) < time.Second {
+				continue
+			}
+			lastTx = mclock.Now()
 
+			for _, r := range s.reporters {
 				select {
-				case txCh <- struct{}{}:
+				case r.txCh <- struct{}{}:
 				default:
 				}
-
-			// node stopped
-			case <-txSub.Err():
-				break HandleLoop
-			case <-headSub.Err():
-				break HandleLoop
 			}
+
+		// node or daemon stopped
+		case <-txSub.Err():
+			break HandleLoop
+		case <-headSub.Err():
+			break HandleLoop
+		case <-s.quit:
+			break HandleLoop
 		}
-		close(quitCh)
-		return
-	}()
-	// Loop reporting until termination
+	}
+	close(quitCh)
+}
+
+// loop keeps trying to connect to the netstats server, reporting chain events
+// until termination. Failed connection attempts are retried with exponential
+// backoff.
+func (r *reporter) loop(quitCh chan struct{}) {
+	backoff := minReconnectDelay
+
+	// wait sleeps until the next reconnection attempt, returning false if the
+	// service was terminated in the mean time
+	wait := func() bool {
+		select {
+		case <-time.After(backoff):
+		case <-quitCh:
+			return false
+		}
+		if backoff *= 2; backoff > maxReconnectDelay {
+			backoff = maxReconnectDelay
+		}
+		return true
+	}
 	for {
 		// Resolve the URL, defaulting to TLS, but falling back to none too
-		path := fmt.Sprintf("%s/api", s.host)
+		path := fmt.Sprintf("%s/api", r.host)
 		urls := []string{path}
 
 		if !strings.Contains(path, "://") { // url.Parse and url.IsAbs is unsuitable (https://github.com/golang/go/issues/19779)
@@ -206,36 +297,36 @@ func (s *Service) loop() {
 		}
 		// Establish a websocket connection to the server on any supported URL
 		var (
-			conf *websocket.Config
 			conn *websocket.Conn
 			err  error
 		)
 		for _, url := range urls {
-			if conf, err = websocket.NewConfig(url, "http://localhost/"); err != nil {
-				continue
-			}
-			conf.Dialer = &net.Dialer{Timeout: 5 * time.Second}
-			if conn, err = websocket.DialConfig(conf); err == nil {
+			if conn, err = r.dial(url); err == nil {
 				break
 			}
 		}
 		if err != nil {
-			log.Warn("Stats server unreachable", "err", err)
-			time.Sleep(10 * time.Second)
+			log.Warn("Stats server unreachable", "host", r.host, "err", err, "retry", backoff)
+			if !wait() {
+				return
+			}
 			continue
 		}
 		// Authenticate the client with the server
-		if err = s.login(conn); err != nil {
-			log.Warn("Stats login failed", "err", err)
+		if err = r.login(conn); err != nil {
+			log.Warn("Stats login failed", "host", r.host, "err", err, "retry", backoff)
 			conn.Close()
-			time.Sleep(10 * time.Second)
+			if !wait() {
+				return
+			}
 			continue
 		}
-		go s.readLoop(conn)
+		backoff = minReconnectDelay
+		go r.readLoop(conn)
 
 		// Send the initial stats so our node looks decent from the get go
-		if err = s.report(conn); err != nil {
-			log.Warn("Initial stats report failed", "err", err)
+		if err = r.report(conn); err != nil {
+			log.Warn("Initial stats report failed", "host", r.host, "err", err)
 			conn.Close()
 			continue
 		}
@@ -245,31 +336,33 @@ func (s *Service) loop() {
 		for err == nil {
 			select {
 			case <-quitCh:
+				fullReport.Stop()
 				conn.Close()
 				return
 
 			case <-fullReport.C:
-				if err = s.report(conn); err != nil {
-					log.Warn("Full stats report failed", "err", err)
+				if err = r.report(conn); err != nil {
+					log.Warn("Full stats report failed", "host", r.host, "err", err)
 				}
-			case list := <-s.histCh:
-				if err = s.reportHistory(conn, list); err != nil {
-					log.Warn("Requested history report failed", "err", err)
+			case list := <-r.histCh:
+				if err = r.reportHistory(conn, list); err != nil {
+					log.Warn("Requested history report failed", "host", r.host, "err", err)
 				}
-			case head := <-headCh:
-				if err = s.reportBlock(conn, head); err != nil {
-					log.Warn("Block stats report failed", "err", err)
+			case head := <-r.headCh:
+				if err = r.reportBlock(conn, head); err != nil {
+					log.Warn("Block stats report failed", "host", r.host, "err", err)
 				}
-				if err = s.reportPending(conn); err != nil {
-					log.Warn("Post-block transaction stats report failed", "err", err)
+				if err = r.reportPending(conn); err != nil {
+					log.Warn("Post-block transaction stats report failed", "host", r.host, "err", err)
 				}
-			case <-txCh:
-				if err = s.reportPending(conn); err != nil {
-					log.Warn("Transaction stats report failed", "err", err)
+			case <-r.txCh:
+				if err = r.reportPending(conn); err != nil {
+					log.Warn("Transaction stats report failed", "host", r.host, "err", err)
 				}
 			}
 		}
 		// Make sure the connection is closed
+		fullReport.Stop()
 		conn.Close()
 	}
 }
@@ -278,7 +371,7 @@ func (s *Service) loop() {
 // from the network socket. If any of them match an active request, it forwards
 // it, if they themselves are requests it initiates a reply, and lastly it drops
 // unknown packets.
-func (s *Service) readLoop(conn *websocket.Conn) {
+func (r *reporter) readLoop(conn *websocket.Conn) {
 	// If the read loop exists, close the connection
 	defer conn.Close()
 
@@ -302,7 +395,7 @@ func (s *Service) readLoop(conn *websocket.Conn) {
 		// If the message is a ping reply, deliver (someone must be listening!)
 		if len(msg["emit"]) == 2 && command == "node-pong" {
 			select {
-			case s.pongCh <- struct{}{}:
+			case r.pongCh <- struct{}{}:
 				// Pong delivered, continue listening
 				continue
 			default:
@@ -317,7 +410,7 @@ func (s *Service) readLoop(conn *websocket.Conn) {
 			request, ok := msg["emit"][1].(map[string]interface{})
 			if !ok {
 				log.Warn("Invalid stats history request", "msg", msg["emit"][1])
-				s.histCh <- nil
+				r.histCh <- nil
 				continue // kokstats sometime sends invalid history requests, ignore those
 			}
 			list, ok := request["list"].([]interface{})
@@ -336,7 +429,7 @@ func (s *Service) readLoop(conn *websocket.Conn) {
 				numbers[i] = uint64(n)
 			}
 			select {
-			case s.histCh <- numbers:
+			case r.histCh <- numbers:
 				continue
 			default:
 			}
@@ -369,9 +462,9 @@ type authMsg struct {
 }
 
 // login tries to authorize the client at the remote server.
-func (s *Service) login(conn *websocket.Conn) error {
+func (r *reporter) login(conn *websocket.Conn) error {
 	// Construct and send the login authentication
-	infos := s.server.NodeInfo()
+	infos := r.server.NodeInfo()
 
 	var network, protocol string
 	if info := infos.Protocols["kok"]; info != nil {
@@ -382,9 +475,9 @@ func (s *Service) login(conn *websocket.Conn) error {
 		protocol = fmt.Sprintf("les/%d", les.ClientProtocolVersions[0])
 	}
 	auth := &authMsg{
-		Id: s.node,
+		Id: r.node,
 		Info: nodeInfo{
-			Name:     s.node,
+			Name:     r.node,
 			Node:     infos.Name,
 			Port:     infos.Ports.Listener,
 			Network:  network,
@@ -395,7 +488,7 @@ func (s *Service) login(conn *websocket.Conn) error {
 			Client:   "0.1.1",
 			History:  true,
 		},
-		Secret: s.pass,
+		Secret: r.pass,
 	}
 	login := map[string][]interface{}{
 		"emit": {"hello", auth},
@@ -414,17 +507,17 @@ func (s *Service) login(conn *websocket.Conn) error {
 // report collects all possible data to report and send it to the stats server.
 // This should only be used on reconnects or rarely to avoid overloading the
 // server. Use the individual mkokods for reporting subscribed events.
-func (s *Service) report(conn *websocket.Conn) error {
-	if err := s.reportLatency(conn); err != nil {
+func (r *reporter) report(conn *websocket.Conn) error {
+	if err := r.reportLatency(conn); err != nil {
 		return err
 	}
-	if err := s.reportBlock(conn, nil); err != nil {
+	if err := r.reportBlock(conn, nil); err != nil {
 		return err
 	}
-	if err := s.reportPending(conn); err != nil {
+	if err := r.reportPending(conn); err != nil {
 		return err
 	}
-	if err := s.reportStats(conn); err != nil {
+	if err := r.reportStats(conn); err != nil {
 		return err
 	}
 	return nil
@@ -432,13 +525,13 @@ func (s *Service) report(conn *websocket.Conn) error {
 
 // reportLatency sends a ping request to the server, measures the RTT time and
 // finally sends a latency update.
-func (s *Service) reportLatency(conn *websocket.Conn) error {
+func (r *reporter) reportLatency(conn *websocket.Conn) error {
 	// Send the current time to the kokstats server
 	start := time.Now()
 
 	ping := map[string][]interface{}{
 		"emit": {"node-ping", map[string]string{
-			"id":         s.node,
+			"id":         r.node,
 			"clientTime": start.String(),
 		}},
 	}
@@ -447,7 +540,7 @@ func (s *Service) reportLatency(conn *websocket.Conn) error {
 	}
 	// Wait for the pong request to arrive back
 	select {
-	case <-s.pongCh:
+	case <-r.pongCh:
 		// Pong delivered, report the latency
 	case <-time.After(5 * time.Second):
 		// Ping timeout, abort
@@ -460,7 +553,7 @@ func (s *Service) reportLatency(conn *websocket.Conn) error {
 
 	stats := map[string][]interface{}{
 		"emit": {"latency", map[string]string{
-			"id":      s.node,
+			"id":      r.node,
 			"latency": latency,
 		}},
 	}
@@ -501,15 +594,15 @@ func (s uncleStats) MarshalJSON() ([]byte, error) {
 }
 
 // reportBlock retrieves the current chain head and repors it to the stats server.
-func (s *Service) reportBlock(conn *websocket.Conn, block *types.Block) error {
+func (r *reporter) reportBlock(conn *websocket.Conn, block *types.Block) error {
 	// Gather the block details from the header or block chain
-	details := s.assembleBlockStats(block)
+	details := r.assembleBlockStats(block)
 
 	// Assemble the block report and send it to the server
 	log.Trace("Sending new block to kokstats", "number", details.Number, "hash", details.Hash)
 
 	stats := map[string]interface{}{
-		"id":    s.node,
+		"id":    r.node,
 		"block": details,
 	}
 	report := map[string][]interface{}{
@@ -573,7 +666,7 @@ func (s *Service) assembleBlockStats(block *types.Block) *blockStats {
 
 // reportHistory retrieves the most recent batch of blocks and reports it to the
 // stats server.
-func (s *Service) reportHistory(conn *websocket.Conn, list []uint64) error {
+func (r *reporter) reportHistory(conn *websocket.Conn, list []uint64) error {
 	// Figure out the indexes that need reporting
 	indexes := make([]uint64, 0, historyUpdateRange)
 	if len(list) > 0 {
@@ -582,10 +675,10 @@ func (s *Service) reportHistory(conn *websocket.Conn, list []uint64) error {
 	} else {
 		// No indexes requested, send back the top ones
 		var head int64
-		if s.kok != nil {
-			head = s.kok.BlockChain().CurrentHeader().Number.Int64()
+		if r.kok != nil {
+			head = r.kok.BlockChain().CurrentHeader().Number.Int64()
 		} else {
-			head = s.les.BlockChain().CurrentHeader().Number.Int64()
+			head = r.les.BlockChain().CurrentHeader().Number.Int64()
 		}
 		start := head - historyUpdateRange + 1
 		if start < 0 {
@@ -600,16 +693,16 @@ func (s *Service) reportHistory(conn *websocket.Conn, list []uint64) error {
 	for i, number := range indexes {
 		// Retrieve the next block if it's known to us
 		var block *types.Block
-		if s.kok != nil {
-			block = s.kok.BlockChain().GetBlockByNumber(number)
+		if r.kok != nil {
+			block = r.kok.BlockChain().GetBlockByNumber(number)
 		} else {
-			if header := s.les.BlockChain().GkokeaderByNumber(number); header != nil {
+			if header := r.les.BlockChain().GkokeaderByNumber(number); header != nil {
 				block = types.NewBlockWithHeader(header)
 			}
 		}
 		// If we do have the block, add to the history and continue
 		if block != nil {
-			history[len(history)-1-i] = s.assembleBlockStats(block)
+			history[len(history)-1-i] = r.assembleBlockStats(block)
 			continue
 		}
 		// Ran out of blocks, cut the report short and send
@@ -622,7 +715,7 @@ func (s *Service) reportHistory(conn *websocket.Conn, list []uint64) error {
 		log.Trace("No history to send to stats server")
 	}
 	stats := map[string]interface{}{
-		"id":      s.node,
+		"id":      r.node,
 		"history": history,
 	}
 	report := map[string][]interface{}{
@@ -638,19 +731,19 @@ type pendStats struct {
 
 // reportPending retrieves the current number of pending transactions and reports
 // it to the stats server.
-func (s *Service) reportPending(conn *websocket.Conn) error {
+func (r *reporter) reportPending(conn *websocket.Conn) error {
 	// Retrieve the pending count from the local blockchain
 	var pending int
-	if s.kok != nil {
-		pending, _ = s.kok.TxPool().Stats()
+	if r.kok != nil {
+		pending, _ = r.kok.TxPool().Stats()
 	} else {
-		pending = s.les.TxPool().Stats()
+		pending = r.les.TxPool().Stats()
 	}
 	// Assemble the transaction stats and send it to the server
 	log.Trace("Sending pending transactions to kokstats", "count", pending)
 
 	stats := map[string]interface{}{
-		"id": s.node,
+		"id": r.node,
 		"stats": &pendStats{
 			Pending: pending,
 		},
@@ -663,18 +756,53 @@ func (s *Service) reportPending(conn *websocket.Conn) error {
 
 // nodeStats is the information to report about the local node.
 type nodeStats struct {
-	Active   bool `json:"active"`
-	Syncing  bool `json:"syncing"`
-	Mining   bool `json:"mining"`
-	Hashrate int  `json:"hashrate"`
-	Peers    int  `json:"peers"`
-	GasPrice int  `json:"gasPrice"`
-	Uptime   int  `json:"uptime"`
+	Active    bool            `json:"active"`
+	Syncing   bool            `json:"syncing"`
+	Mining    bool            `json:"mining"`
+	Hashrate  int             `json:"hashrate"`
+	Peers     int             `json:"peers"`
+	GasPrice  int             `json:"gasPrice"`
+	Uptime    int             `json:"uptime"`
+	Consensus *consensusStats `json:"consensus,omitempty"`
+}
+
+// consensusStats is the DPoS consensus health of the local node.
+type consensusStats struct {
+	Validator common.Address `json:"validator"`
+	Active    bool           `json:"active"`
+	Epoch     uint64         `json:"epoch"`
+	Expected  uint64         `json:"expected"`
+	Produced  uint64         `json:"produced"`
+	Missed    uint64         `json:"missed"`
+	Confirmed uint64         `json:"confirmed"`
+}
+
+// assembleConsensusStats retrieves the validator status of the local node if it
+// runs the DPoS engine on a full chain, nil otherwise.
+func (s *Service) assembleConsensusStats() *consensusStats {
+	engine, ok := s.engine.(*dpos.Dpos)
+	if !ok || s.kok == nil {
+		return nil
+	}
+	status, err := engine.Status(s.kok.BlockChain())
+	if err != nil {
+		log.Debug("Failed to retrieve validator status", "err", err)
+		return nil
+	}
+	return &consensusStats{
+		Validator: status.Validator,
+		Active:    status.Active,
+		Epoch:     status.Epoch,
+		Expected:  status.Expected,
+		Produced:  status.Produced,
+		Missed:    status.Missed,
+		Confirmed: status.Confirmed,
+	}
 }
 
 // reportPending retrieves various stats about the node at the networking and
 // mining layer and reports it to the stats server.
-func (s *Service) reportStats(conn *websocket.Conn) error {
+func (r *reporter) reportStats(conn *websocket.Conn) error {
 	// Gather the syncing and mining infos from the local miner instance
 	var (
 		mining   bool
@@ -682,32 +810,33 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 		syncing  bool
 		gasprice int
 	)
-	if s.kok != nil {
-		mining = s.kok.Miner().Mining()
-		hashrate = int(s.kok.Miner().HashRate())
+	if r.kok != nil {
+		mining = r.kok.Miner().Mining()
+		hashrate = int(r.kok.Miner().HashRate())
 
-		sync := s.kok.Downloader().Progress()
-		syncing = s.kok.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
+		sync := r.kok.Downloader().Progress()
+		syncing = r.kok.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
 
-		price, _ := s.kok.ApiBackend.SuggestPrice(context.Background())
+		price, _ := r.kok.ApiBackend.SuggestPrice(context.Background())
 		gasprice = int(price.Uint64())
 	} else {
-		sync := s.les.Downloader().Progress()
-		syncing = s.les.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
+		sync := r.les.Downloader().Progress()
+		syncing = r.les.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
 	}
 	// Assemble the node stats and send it to the server
 	log.Trace("Sending node details to kokstats")
 
 	stats := map[string]interface{}{
-		"id": s.node,
+		"id": r.node,
 		"stats": &nodeStats{
-			Active:   true,
-			Mining:   mining,
-			Hashrate: hashrate,
-			Peers:    s.server.PeerCount(),
-			GasPrice: gasprice,
-			Syncing:  syncing,
-			Uptime:   100,
+			Active:    true,
+			Mining:    mining,
+			Hashrate:  hashrate,
+			Peers:     r.server.PeerCount(),
+			GasPrice:  gasprice,
+			Syncing:   syncing,
+			Uptime:    100,
+			Consensus: r.assembleConsensusStats(),
 		},
 	}
 	report := map[string][]interface{}{
@@ -0,0 +1,67 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"github.com/kokprojects/go-kok/p2p"
+	"github.com/kokprojects/go-kok/p2p/discover"
+)
+
+// PrivateLightClientAPI provides an API to manage the light servers a light
+// client connects to.
+type PrivateLightClientAPI struct {
+	pm *ProtocolManager
+}
+
+// NewPrivateLightClientAPI creates a new light client API.
+func NewPrivateLightClientAPI(pm *ProtocolManager) *PrivateLightClientAPI {
+	return &PrivateLightClientAPI{pm}
+}
+
+// ReportMisbehavior blacklists a light server persistently and disconnects it.
+// If the server is connected and signs its responses, the last one received is
+// kept as proof of the misbehavior.
+func (api *PrivateLightClientAPI) ReportMisbehavior(id string, reason string) (bool, error) {
+	nodeID, err := discover.HexID(id)
+	if err != nil {
+		return false, err
+	}
+	var peer *peer
+	for _, p := range api.pm.peers.AllPeers() {
+		if p.ID() == nodeID {
+			peer = p
+			break
+		}
+	}
+	var evidence *signedResponse
+	if peer != nil {
+		evidence = peer.LastSigned()
+	}
+	if err := api.pm.misbehaviors.add(nodeID, reason, evidence); err != nil {
+		return false, err
+	}
+	if peer != nil {
+		peer.Disconnect(p2p.DiscUselessPeer)
+	}
+	return true, nil
+}
+
+// Misbehaviors returns the records of the blacklisted light servers, along with
+// the signed responses proving their misbehavior, if any.
+func (api *PrivateLightClientAPI) Misbehaviors() []*misbehavior {
+	return api.pm.misbehaviors.list()
+}
@@ -18,6 +18,7 @@ package les
 
 import (
 	"context"
+	"errors"
 	"math/big"
 
 	"github.com/kokprojects/go-kok/accounts"
@@ -79,6 +80,14 @@ func (b *LesApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	return light.NewState(ctx, header, b.kok.odr), header, nil
 }
 
+// PrefetchStorage retrieves storage slots of an account with a single verified
+// request, so that reading them from the state of the header needs no further
+// network round trips.
+func (b *LesApiBackend) PrefetchStorage(ctx context.Context, header *types.Header, address common.Address, keys []common.Hash) error {
+	_, err := light.GetStorage(ctx, b.kok.odr, header, address, keys)
+	return err
+}
+
 func (b *LesApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
 	return b.kok.blockchain.GetBlockByHash(ctx, blockHash)
 }
@@ -92,6 +101,9 @@ func (b *LesApiBackend) GetTd(blockHash common.Hash) *big.Int {
 }
 
 func (b *LesApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
 	state.SetBalance(msg.From(), math.MaxBig256)
 	context := core.NewEVMContext(msg, header, b.kok.blockchain, nil)
 	return vm.NewEVM(context, state, b.kok.chainConfig, vmCfg), state.Error, nil
@@ -101,6 +113,18 @@ func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.kok.txPool.Add(ctx, signedTx)
 }
 
+func (b *LesApiBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction, private bool) error {
+	// Light clients depend on servers to relay their transactions
+	if private {
+		return errors.New("private transactions not supported by light clients")
+	}
+	return b.kok.txPool.Add(ctx, signedTx)
+}
+
+func (b *LesApiBackend) SendExpiringTx(ctx context.Context, signedTx *types.Transaction, private *bool, expiry core.TxExpiry) error {
+	return errors.New("transaction expiry not supported by light clients")
+}
+
 func (b *LesApiBackend) RemoveTx(txHash common.Hash) {
 	b.kok.txPool.RemoveTx(txHash)
 }
@@ -129,6 +153,35 @@ func (b *LesApiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Sub
 	return b.kok.txPool.SubscribeTxPreEvent(ch)
 }
 
+// SubscribeTxDropEvent returns a subscription that never fires, as the light
+// transaction pool only tracks locally submitted transactions and drops none.
+func (b *LesApiBackend) SubscribeTxDropEvent(ch chan<- core.TxDropEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+func (b *LesApiBackend) GetDroppedTransaction(txHash common.Hash) *core.DroppedTx {
+	return nil
+}
+
+func (b *LesApiBackend) DiagnoseTransaction(txHash common.Hash) *core.TxDiagnosis {
+	return nil
+}
+
+func (b *LesApiBackend) TxPoolPriceBumps() (uint64, map[common.Address]uint64, error) {
+	return 0, nil, errors.New("transaction replacement not supported by light clients")
+}
+
+func (b *LesApiBackend) SetTxPoolPriceBump(account *common.Address, bump uint64) error {
+	return errors.New("transaction replacement not supported by light clients")
+}
+
+func (b *LesApiBackend) ResetTxPoolPriceBump(account common.Address) error {
+	return errors.New("transaction replacement not supported by light clients")
+}
+
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.kok.blockchain.SubscribeChainEvent(ch)
 }
@@ -157,6 +210,10 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *LesApiBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, rewardPercentiles)
+}
+
 func (b *LesApiBackend) ChainDb() kokdb.Database {
 	return b.kok.chainDb
 }
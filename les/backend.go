@@ -18,6 +18,7 @@
 package les
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/kokprojects/go-kok/consensus/dpos"
 	"github.com/kokprojects/go-kok/core"
 	"github.com/kokprojects/go-kok/core/bloombits"
+	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/kok"
 	"github.com/kokprojects/go-kok/kok/downloader"
@@ -36,6 +38,7 @@ import (
 	"github.com/kokprojects/go-kok/kok/gasprice"
 	"github.com/kokprojects/go-kok/kokdb"
 	"github.com/kokprojects/go-kok/event"
+	"github.com/kokprojects/go-kok/graphql"
 	"github.com/kokprojects/go-kok/internal/kokapi"
 	"github.com/kokprojects/go-kok/light"
 	"github.com/kokprojects/go-kok/log"
@@ -44,9 +47,12 @@ import (
 	"github.com/kokprojects/go-kok/p2p/discv5"
 	"github.com/kokprojects/go-kok/params"
 	rpc "github.com/kokprojects/go-kok/rpc"
+	"github.com/kokprojects/go-kok/trie"
 )
 
 type Lightkokereum struct {
+	config *kok.Config
+
 	odr         *LesOdr
 	relay       *LesTxRelay
 	chainConfig *params.ChainConfig
@@ -74,6 +80,8 @@ type Lightkokereum struct {
 
 	networkId     uint64
 	netRPCService *kokapi.PublicNetAPI
+	restServer    *kokapi.RESTServer
+	graphqlServer *graphql.Server
 
 	wg sync.WaitGroup
 }
@@ -93,6 +101,7 @@ func New(ctx *node.ServiceContext, config *kok.Config) (*Lightkokereum, error) {
 	quitSync := make(chan struct{})
 
 	lkok := &Lightkokereum{
+		config:           config,
 		chainConfig:      chainConfig,
 		chainDb:          chainDb,
 		eventMux:         ctx.EventMux,
@@ -127,6 +136,8 @@ func New(ctx *node.ServiceContext, config *kok.Config) (*Lightkokereum, error) {
 	if lkok.protocolManager, err = NewProtocolManager(lkok.chainConfig, true, ClientProtocolVersions, config.NetworkId, lkok.eventMux, lkok.engine, lkok.peers, lkok.blockchain, nil, chainDb, lkok.odr, lkok.relay, quitSync, &lkok.wg); err != nil {
 		return nil, err
 	}
+	lkok.protocolManager.minimalSync = config.LightMinimal
+
 	lkok.ApiBackend = &LesApiBackend{lkok, nil}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
@@ -169,7 +180,13 @@ func (s *LightDummyAPI) Mining() bool {
 // APIs returns the collection of RPC services the kokereum package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *Lightkokereum) APIs() []rpc.API {
-	return append(kokapi.GetAPIs(s.ApiBackend), []rpc.API{
+	apis := kokapi.GetAPIs(s.ApiBackend)
+
+	// Append the dpos APIs, retrieving the staking state on demand
+	if engine, ok := s.engine.(*dpos.Dpos); ok {
+		apis = append(apis, engine.LightAPIs(&dposChain{s.blockchain, s.chainConfig}, &dposBackend{odr: s.odr})...)
+	}
+	return append(apis, []rpc.API{
 		{
 			Namespace: "kok",
 			Version:   "1.0",
@@ -183,13 +200,17 @@ func (s *Lightkokereum) APIs() []rpc.API {
 		}, {
 			Namespace: "kok",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true),
+			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true, s.config.Filters),
 			Public:    true,
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
 			Service:   s.netRPCService,
 			Public:    true,
+		}, {
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPrivateLightClientAPI(s.protocolManager),
 		},
 	}...)
 }
@@ -217,10 +238,26 @@ func (s *Lightkokereum) Start(srvr *p2p.Server) error {
 	s.startBloomHandlers()
 	log.Warn("Light client mode is an experimental feature")
 	s.netRPCService = kokapi.NewPublicNetAPI(srvr, s.networkId)
+	if s.config.RESTEndpoint != "" {
+		s.restServer = kokapi.NewRESTServer(s.ApiBackend)
+		if err := s.restServer.Start(s.config.RESTEndpoint); err != nil {
+			return err
+		}
+	}
+	if s.config.GraphQLEndpoint != "" {
+		s.graphqlServer = graphql.NewServer(s.ApiBackend, s.config.GraphQLCors)
+		if err := s.graphqlServer.Start(s.config.GraphQLEndpoint); err != nil {
+			return err
+		}
+	}
 	// search the topic belonging to the oldest supported protocol because
 	// servers always advertise all supported protocols
 	protocolVersion := ClientProtocolVersions[len(ClientProtocolVersions)-1]
-	s.serverPool.start(srvr, lesTopic(s.blockchain.Genesis().Hash(), protocolVersion))
+	if s.config.LightMinimal {
+		log.Info("Minimal light sync, following static servers only")
+	} else {
+		s.serverPool.start(srvr, lesTopic(s.blockchain.Genesis().Hash(), protocolVersion))
+	}
 	s.protocolManager.Start()
 	return nil
 }
@@ -228,6 +265,12 @@ func (s *Lightkokereum) Start(srvr *p2p.Server) error {
 // Stop implements node.Service, terminating all internal goroutines used by the
 // kokereum protocol.
 func (s *Lightkokereum) Stop() error {
+	if s.restServer != nil {
+		s.restServer.Stop()
+	}
+	if s.graphqlServer != nil {
+		s.graphqlServer.Stop()
+	}
 	s.odr.Stop()
 	if s.bloomIndexer != nil {
 		s.bloomIndexer.Close()
@@ -250,3 +293,35 @@ func (s *Lightkokereum) Stop() error {
 
 	return nil
 }
+
+// dposChain adapts the light chain to the chain reader of the dpos APIs, which
+// only look headers up.
+type dposChain struct {
+	*light.LightChain
+	config *params.ChainConfig
+}
+
+// Config implements consensus.ChainReader, returning the chain configuration.
+func (c *dposChain) Config() *params.ChainConfig { return c.config }
+
+// GetBlock implements consensus.ChainReader. Light clients don't store block
+// bodies, so no block is ever found locally.
+func (c *dposChain) GetBlock(hash common.Hash, number uint64) *types.Block { return nil }
+
+// dposBackend retrieves the dpos staking state light clients don't store locally
+// from the network on demand.
+type dposBackend struct {
+	odr light.OdrBackend
+}
+
+// RetrieveTrieNode implements dpos.LightBackend, retrieving the missing node of
+// a dpos trie committed to by the header.
+func (b *dposBackend) RetrieveTrieNode(ctx context.Context, header *types.Header, root common.Hash, missing *trie.MissingNodeError) error {
+	return light.RetrieveDposTrieNode(ctx, b.odr, header, root, missing)
+}
+
+// State implements dpos.LightBackend, returning the on demand account state of
+// the header.
+func (b *dposBackend) State(ctx context.Context, header *types.Header) *state.StateDB {
+	return light.NewState(ctx, header, b.odr)
+}
@@ -413,6 +413,10 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 	if bestTd == f.maxConfirmedTd {
 		return nil, 0
 	}
+	if f.pm.minimalSync {
+		// Only the announced head is fetched in minimal sync mode, never its ancestors
+		bestSyncing, bestAmount = false, 1
+	}
 
 	f.syncing = bestSyncing
 
@@ -495,6 +499,9 @@ func (f *lightFetcher) processResponse(req fetchRequest, resp fetchResponse) boo
 		req.peer.Log().Debug("Response content mismatch", "requested", len(resp.headers), "reqfrom", resp.headers[0], "delivered", req.amount, "delfrom", req.hash)
 		return false
 	}
+	if f.pm.minimalSync {
+		return f.processTrustedHead(req, resp.headers[0])
+	}
 	headers := make([]*types.Header, req.amount)
 	for i, header := range resp.headers {
 		headers[int(req.amount)-1-i] = header
@@ -519,6 +526,26 @@ func (f *lightFetcher) processResponse(req fetchRequest, resp fetchResponse) boo
 	return true
 }
 
+// processTrustedHead sets a head header fetched in minimal sync mode as the head
+// of the chain, along with the total difficulty announced for it by the server.
+func (f *lightFetcher) processTrustedHead(req fetchRequest, header *types.Header) bool {
+	fp := f.peers[req.peer]
+	if fp == nil {
+		return false
+	}
+	n := fp.nodeByHash[req.hash]
+	if n == nil || n.td == nil || n.number != header.Number.Uint64() {
+		req.peer.Log().Debug("Trusted head mismatch", "number", header.Number, "hash", req.hash)
+		return false
+	}
+	if err := f.chain.SetTrustedHead(header, n.td); err != nil {
+		log.Debug("Failed to set trusted head", "err", err)
+		return false
+	}
+	f.newHeaders([]*types.Header{header}, []*big.Int{n.td})
+	return true
+}
+
 // newHeaders updates the block trees of all active peers according to a newly
 // downloaded and validated batch or headers
 func (f *lightFetcher) newHeaders(headers []*types.Header, tds []*big.Int) {
@@ -559,6 +586,10 @@ func (f *lightFetcher) checkAnnouncedHeaders(fp *fetcherPeerInfo, headers []*typ
 			// we ran out of recently delivered headers but have not reached a node known by this peer yet, continue matching
 			td = f.chain.GetTd(header.ParentHash, header.Number.Uint64()-1)
 			header = f.chain.Gkokeader(header.ParentHash, header.Number.Uint64()-1)
+			if header == nil || td == nil {
+				// the ancestors of trusted heads are not downloaded in minimal sync mode
+				return true
+			}
 		} else {
 			header = headers[i]
 			td = tds[i]
@@ -0,0 +1,94 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kok"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/light"
+	"github.com/kokprojects/go-kok/p2p/discover"
+	"github.com/kokprojects/go-kok/params"
+)
+
+// Tests that a light client in minimal sync mode follows the heads announced by
+// its trusted server without downloading the headers in between.
+func TestMinimalSyncLes1(t *testing.T) { testMinimalSync(t, 1) }
+func TestMinimalSyncLes2(t *testing.T) { testMinimalSync(t, 2) }
+
+func testMinimalSync(t *testing.T, protocol int) {
+	// Assemble the test environment
+	key, _ := crypto.GenerateKey()
+	peers := newPeerSet()
+	dist := newRequestDistributor(peers, make(chan struct{}))
+	rm := newRetrieveManager(peers, dist, nil)
+	db, _ := kokdb.NewMemDatabase()
+	ldb, _ := kokdb.NewMemDatabase()
+	odr := NewLesOdr(ldb, light.NewChtIndexer(db, true), light.NewBloomTrieIndexer(db, true), kok.NewBloomIndexer(db, light.BloomTrieFrequency), rm)
+
+	pm := newTestProtocolManagerMust(t, false, 4, nil, nil, nil, db)
+	pm.server.privateKey = key
+	pm.blockLoop()
+
+	lpm := newTestProtocolManagerMust(t, true, 0, nil, peers, odr, ldb)
+	lpm.minimalSync = true
+
+	_, err1, _, err2 := newTestPeerPairWithID(discover.PubkeyID(&key.PublicKey), "peer", protocol, pm, lpm)
+	select {
+	case <-time.After(time.Millisecond * 100):
+	case err := <-err1:
+		t.Fatalf("peer 1 handshake error: %v", err)
+	case err := <-err2:
+		t.Fatalf("peer 2 handshake error: %v", err)
+	}
+	bc := pm.blockchain.(*core.BlockChain)
+	lc := lpm.blockchain.(*light.LightChain)
+
+	waitHead := func(head *types.Header) {
+		for i := 0; i < 100 && lc.CurrentHeader().Hash() != head.Hash(); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if have := lc.CurrentHeader(); have.Hash() != head.Hash() {
+			t.Fatalf("head mismatch: have #%d [%x], want #%d [%x]", have.Number, have.Hash(), head.Number, head.Hash())
+		}
+		if have, want := lc.GetTd(head.Hash(), head.Number.Uint64()), bc.GetTd(head.Hash(), head.Number.Uint64()); have.Cmp(want) != 0 {
+			t.Fatalf("td mismatch: have %v, want %v", have, want)
+		}
+	}
+	// The client should jump to the head of the server from the handshake
+	waitHead(bc.CurrentHeader())
+	for i := uint64(1); i < bc.CurrentHeader().Number.Uint64(); i++ {
+		if hash := core.GetCanonicalHash(ldb, i); hash != (common.Hash{}) {
+			t.Errorf("header #%d downloaded", i)
+		}
+	}
+	// Extend the chain of the server, the client should follow the signed announcement
+	blocks, _ := core.GenerateChain(params.TestChainConfig, bc.CurrentBlock(), db, 2, nil)
+	if _, err := bc.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to extend server chain: %v", err)
+	}
+	waitHead(blocks[1].Header())
+	if hash := core.GetCanonicalHash(ldb, blocks[0].NumberU64()); hash != (common.Hash{}) {
+		t.Errorf("header #%d downloaded", blocks[0].NumberU64())
+	}
+}
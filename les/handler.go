@@ -101,6 +101,7 @@ type ProtocolManager struct {
 	chainConfig *params.ChainConfig
 	blockchain  BlockChain
 	chainDb     kokdb.Database
+	stateDb     kokdb.Database // Database serving the state tries, including the unflushed ones of a full node
 	odr         *LesOdr
 	server      *LesServer
 	serverPool  *serverPool
@@ -108,6 +109,9 @@ type ProtocolManager struct {
 	reqDist     *requestDistributor
 	retriever   *retrieveManager
 
+	misbehaviors *misbehaviorSet // Blacklist of misbehaving servers, nil if our node is server only
+	minimalSync  bool            // Whkoker to follow the announced heads only, without their ancestors
+
 	downloader *downloader.Downloader
 	fetcher    *lightFetcher
 	peers      *peerSet
@@ -146,6 +150,12 @@ func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, protoco
 		wg:          wg,
 		noMorePeers: make(chan struct{}),
 	}
+	// Serve the state of full nodes through the blockchain, whose recent tries may
+	// not have been flushed into the chain database yet
+	manager.stateDb = chainDb
+	if bc, ok := blockchain.(*core.BlockChain); ok {
+		manager.stateDb = bc.TrieDB()
+	}
 	if odr != nil {
 		manager.retriever = odr.retriever
 		manager.reqDist = odr.retriever.dist
@@ -208,6 +218,7 @@ func NewProtocolManager(chainConfig *params.ChainConfig, lightSync bool, protoco
 		manager.downloader = downloader.New(downloader.LightSync, chainDb, manager.eventMux, nil, blockchain, removePeer)
 		manager.peers.notify((*downloaderPeerNotify)(manager))
 		manager.fetcher = newLightFetcher(manager)
+		manager.misbehaviors = newMisbehaviorSet(chainDb)
 	}
 
 	return manager, nil
@@ -253,7 +264,11 @@ func (pm *ProtocolManager) Stop() {
 }
 
 func (pm *ProtocolManager) newPeer(pv int, nv uint64, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
-	return newPeer(pv, nv, p, newMeteredMsgWriter(rw))
+	peer := newPeer(pv, nv, p, newMeteredMsgWriter(rw))
+	if pm.minimalSync {
+		peer.requestAnnounceType = announceTypeSigned
+	}
+	return peer
 }
 
 // handle is the callback invoked to manage the life cycle of a les peer. When
@@ -261,6 +276,10 @@ func (pm *ProtocolManager) newPeer(pv int, nv uint64, p *p2p.Peer, rw p2p.MsgRea
 func (pm *ProtocolManager) handle(p *peer) error {
 	p.Log().Debug("Light kokereum peer connected", "name", p.Name())
 
+	if pm.misbehaviors != nil && pm.misbehaviors.has(p.ID()) {
+		p.Log().Debug("Refusing blacklisted light server")
+		return errResp(ErrUselessPeer, "server blacklisted for misbehavior")
+	}
 	// Execute the LES handshake
 	td, head, genesis := pm.blockchain.Status()
 	headNum := core.GetBlockNumber(pm.chainDb, head)
@@ -354,7 +373,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	}
 	defer msg.Discard()
 
-	var deliverMsg *Msg
+	var (
+		deliverMsg *Msg
+		signed     *signedResponse // Signed response of the server, if any
+	)
 
 	// Handle the message depending on its contents
 	switch msg.Code {
@@ -479,10 +501,14 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		var resp struct {
 			ReqID, BV uint64
 			Headers   []*types.Header
+			Rest      []rlp.RawValue `rlp:"tail"`
 		}
 		if err := msg.Decode(&resp); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if _, err := p.checkResponse(msg.Code, resp.ReqID, resp.Headers, resp.Rest); err != nil {
+			return errResp(ErrInvalidResponse, "msg %v: %v", msg, err)
+		}
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
 		if pm.fetcher != nil && pm.fetcher.requestedID(resp.ReqID) {
 			pm.fetcher.deliverHeaders(p, resp.ReqID, resp.Headers)
@@ -569,11 +595,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		for _, req := range req.Reqs {
 			// Retrieve the requested state entry, stopping if enough was found
 			if header := core.Gkokeader(pm.chainDb, req.BHash, core.GetBlockNumber(pm.chainDb, req.BHash)); header != nil {
-				if trie, _ := trie.New(header.Root, pm.chainDb); trie != nil {
+				if trie, _ := trie.New(header.Root, pm.stateDb); trie != nil {
 					sdata := trie.Get(req.AccKey)
 					var acc state.Account
 					if err := rlp.DecodeBytes(sdata, &acc); err == nil {
-						entry, _ := pm.chainDb.Get(acc.CodeHash)
+						entry, _ := pm.stateDb.Get(acc.CodeHash)
 						if bytes+len(entry) >= softResponseLimit {
 							break
 						}
@@ -696,13 +722,20 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			// Retrieve the requested state entry, stopping if enough was found
 			if header := core.Gkokeader(pm.chainDb, req.BHash, core.GetBlockNumber(pm.chainDb, req.BHash)); header != nil {
-				if tr, _ := trie.New(header.Root, pm.chainDb); tr != nil {
+				if root, ok := light.DposTrieRoot(req.AccKey); ok {
+					if tr := pm.dposTrie(header, root); tr != nil {
+						var proof light.NodeList
+						tr.Prove(req.Key, 0, &proof)
+						proofs = append(proofs, proof)
+						bytes += proof.DataSize()
+					}
+				} else if tr, _ := trie.New(header.Root, pm.stateDb); tr != nil {
 					if len(req.AccKey) > 0 {
 						sdata := tr.Get(req.AccKey)
 						tr = nil
 						var acc state.Account
 						if err := rlp.DecodeBytes(sdata, &acc); err == nil {
-							tr, _ = trie.New(acc.Root, pm.chainDb)
+							tr, _ = trie.New(acc.Root, pm.stateDb)
 						}
 					}
 					if tr != nil {
@@ -732,6 +765,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		var (
 			lastBHash  common.Hash
 			lastAccKey []byte
+			header     *types.Header
 			tr, str    *trie.Trie
 		)
 		reqCnt := len(req.Reqs)
@@ -746,8 +780,8 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				break
 			}
 			if tr == nil || req.BHash != lastBHash {
-				if header := core.Gkokeader(pm.chainDb, req.BHash, core.GetBlockNumber(pm.chainDb, req.BHash)); header != nil {
-					tr, _ = trie.New(header.Root, pm.chainDb)
+				if header = core.Gkokeader(pm.chainDb, req.BHash, core.GetBlockNumber(pm.chainDb, req.BHash)); header != nil {
+					tr, _ = trie.New(header.Root, pm.stateDb)
 				} else {
 					tr = nil
 				}
@@ -757,11 +791,15 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			if tr != nil {
 				if len(req.AccKey) > 0 {
 					if str == nil || !bytes.Equal(req.AccKey, lastAccKey) {
-						sdata := tr.Get(req.AccKey)
 						str = nil
-						var acc state.Account
-						if err := rlp.DecodeBytes(sdata, &acc); err == nil {
-							str, _ = trie.New(acc.Root, pm.chainDb)
+						if root, ok := light.DposTrieRoot(req.AccKey); ok {
+							str = pm.dposTrie(header, root)
+						} else {
+							sdata := tr.Get(req.AccKey)
+							var acc state.Account
+							if err := rlp.DecodeBytes(sdata, &acc); err == nil {
+								str, _ = trie.New(acc.Root, pm.stateDb)
+							}
 						}
 						lastAccKey = common.CopyBytes(req.AccKey)
 					}
@@ -788,10 +826,14 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		var resp struct {
 			ReqID, BV uint64
 			Data      []light.NodeList
+			Rest      []rlp.RawValue `rlp:"tail"`
 		}
 		if err := msg.Decode(&resp); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if signed, err = p.checkResponse(msg.Code, resp.ReqID, resp.Data, resp.Rest); err != nil {
+			return errResp(ErrInvalidResponse, "msg %v: %v", msg, err)
+		}
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
 		deliverMsg = &Msg{
 			MsgType: MsgProofsV1,
@@ -809,10 +851,14 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		var resp struct {
 			ReqID, BV uint64
 			Data      light.NodeList
+			Rest      []rlp.RawValue `rlp:"tail"`
 		}
 		if err := msg.Decode(&resp); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if signed, err = p.checkResponse(msg.Code, resp.ReqID, resp.Data, resp.Rest); err != nil {
+			return errResp(ErrInvalidResponse, "msg %v: %v", msg, err)
+		}
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
 		deliverMsg = &Msg{
 			MsgType: MsgProofsV2,
@@ -939,10 +985,14 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		var resp struct {
 			ReqID, BV uint64
 			Data      []ChtResp
+			Rest      []rlp.RawValue `rlp:"tail"`
 		}
 		if err := msg.Decode(&resp); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if signed, err = p.checkResponse(msg.Code, resp.ReqID, resp.Data, resp.Rest); err != nil {
+			return errResp(ErrInvalidResponse, "msg %v: %v", msg, err)
+		}
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
 		deliverMsg = &Msg{
 			MsgType: MsgHeaderProofs,
@@ -959,10 +1009,14 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		var resp struct {
 			ReqID, BV uint64
 			Data      HelperTrieResps
+			Rest      []rlp.RawValue `rlp:"tail"`
 		}
 		if err := msg.Decode(&resp); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		if signed, err = p.checkResponse(msg.Code, resp.ReqID, resp.Data, resp.Rest); err != nil {
+			return errResp(ErrInvalidResponse, "msg %v: %v", msg, err)
+		}
 
 		p.fcServer.GotReply(resp.ReqID, resp.BV)
 		deliverMsg = &Msg{
@@ -1071,6 +1125,13 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	if deliverMsg != nil {
 		err := pm.retriever.deliver(p, deliverMsg)
 		if err != nil {
+			// Signed invalid responses prove the server misbehaving, blacklist it
+			if _, invalid := err.(*invalidResponseError); invalid && signed != nil && pm.misbehaviors != nil {
+				if err := pm.misbehaviors.add(p.ID(), err.Error(), signed); err != nil {
+					p.Log().Error("Failed to blacklist misbehaving server", "err", err)
+				}
+				return err
+			}
 			p.responseErrors++
 			if p.responseErrors > maxResponseErrors {
 				return err
@@ -1106,6 +1167,22 @@ func (pm *ProtocolManager) gkokelperTrieAuxData(req HelperTrieReq) []byte {
 	return nil
 }
 
+// dposTrie opens the dpos trie with the given root if the header commits to it
+// in its dpos context, otherwise it returns nil.
+func (pm *ProtocolManager) dposTrie(header *types.Header, root common.Hash) *trie.Trie {
+	if header == nil || header.DposContext == nil {
+		return nil
+	}
+	ctx := header.DposContext
+	for _, committed := range []common.Hash{ctx.EpochHash, ctx.DelegateHash, ctx.CandidateHash, ctx.VoteHash, ctx.MintCntHash} {
+		if root == committed {
+			tr, _ := trie.New(root, pm.chainDb)
+			return tr
+		}
+	}
+	return nil
+}
+
 func (pm *ProtocolManager) txStatus(hashes []common.Hash) []txStatus {
 	stats := make([]txStatus, len(hashes))
 	for i, stat := range pm.txpool.Status(hashes) {
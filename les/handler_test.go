@@ -412,6 +412,72 @@ func testGetProofs(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that proofs of the state of recent blocks, still buffered in memory by
+// the chain, are served to light clients.
+func TestGetBufferedProofsLes1(t *testing.T) { testGetBufferedProofs(t, 1) }
+func TestGetBufferedProofsLes2(t *testing.T) { testGetBufferedProofs(t, 2) }
+
+func testGetBufferedProofs(t *testing.T, protocol int) {
+	db, _ := kokdb.NewMemDatabase()
+	pm := newTestProtocolManagerMust(t, false, 0, nil, nil, nil, db)
+	bc := pm.blockchain.(*core.BlockChain)
+	bc.SetCommitInterval(64)
+
+	// Generate the chain in a separate database, so only the import writes state
+	gendb, _ := kokdb.NewMemDatabase()
+	gspec := core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testBankAddress: {Balance: testBankFunds}},
+	}
+	chain, _ := core.GenerateChain(gspec.Config, gspec.MustCommit(gendb), gendb, 2, testChainGen)
+	if _, err := bc.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	header := bc.CurrentHeader()
+	if ok, _ := db.Has(header.Root.Bytes()); ok {
+		t.Fatalf("head state flushed to the chain database")
+	}
+	peer, _ := newTestPeer(t, "peer", protocol, pm, true)
+	defer peer.close()
+
+	key := crypto.Keccak256(testBankAddress[:])
+	reqs := []ProofReq{{BHash: header.Hash(), Key: key}}
+
+	trie, _ := trie.New(header.Root, bc.TrieDB())
+	switch protocol {
+	case 1:
+		var proof light.NodeList
+		trie.Prove(key, 0, &proof)
+
+		cost := peer.GetRequestCost(GetProofsV1Msg, len(reqs))
+		sendRequest(peer.app, GetProofsV1Msg, 42, cost, reqs)
+		if err := expectResponse(peer.app, ProofsV1Msg, 42, testBufLimit, [][]rlp.RawValue{proof}); err != nil {
+			t.Errorf("proofs mismatch: %v", err)
+		}
+	case 2:
+		proof := light.NewNodeSet()
+		trie.Prove(key, 0, proof)
+
+		cost := peer.GetRequestCost(GetProofsV2Msg, len(reqs))
+		sendRequest(peer.app, GetProofsV2Msg, 42, cost, reqs)
+		msg, err := peer.app.ReadMsg()
+		if err != nil {
+			t.Fatalf("message read error: %v", err)
+		}
+		var resp struct {
+			ReqID, BV uint64
+			Data      light.NodeList
+		}
+		if err := msg.Decode(&resp); err != nil {
+			t.Fatalf("reply decode error: %v", err)
+		}
+		if len(resp.Data) == 0 {
+			t.Fatalf("empty proof served")
+		}
+		testCheckProof(t, proof, resp.Data)
+	}
+}
+
 func TestTransactionStatusLes2(t *testing.T) {
 	db, _ := kokdb.NewMemDatabase()
 	pm := newTestProtocolManagerMust(t, false, 0, nil, nil, nil, db)
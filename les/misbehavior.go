@@ -0,0 +1,175 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kokprojects/go-kok/common/hexutil"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/p2p/discover"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+// misbehaviorKey is the database key of the list of misbehaving servers.
+var misbehaviorKey = []byte("lesMisbehaviors")
+
+var (
+	errMissingResponseSig = errors.New("missing response signature")
+	errInvalidResponseSig = errors.New("response not signed by server")
+)
+
+// signedResponse is a response of a server signed with its node key. Since it
+// binds the server to the served data, it can be presented to third parties as
+// proof of the server's misbehavior.
+type signedResponse struct {
+	MsgCode uint64        `json:"msgCode"`
+	ReqID   uint64        `json:"reqId"`
+	Data    hexutil.Bytes `json:"data"` // RLP encoded response data
+	Sig     hexutil.Bytes `json:"signature"`
+}
+
+// responseSigHash returns the hash signed by a server for the given response.
+func responseSigHash(msgcode, reqID uint64, data []byte) []byte {
+	enc, _ := rlp.EncodeToBytes([]interface{}{msgcode, reqID, rlp.RawValue(data)})
+	return crypto.Keccak256(enc)
+}
+
+// signResponse signs the given response data with the server's node key.
+func signResponse(prv *ecdsa.PrivateKey, msgcode, reqID uint64, data interface{}) ([]byte, error) {
+	enc, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Sign(responseSigHash(msgcode, reqID, enc), prv)
+}
+
+// newSignedResponse assembles a signed response from its received parts. The
+// signature is the first of the extra fields of the response, if any.
+func newSignedResponse(msgcode, reqID uint64, data interface{}, rest []rlp.RawValue) (*signedResponse, error) {
+	if len(rest) == 0 {
+		return nil, errMissingResponseSig
+	}
+	var sig []byte
+	if err := rlp.DecodeBytes(rest[0], &sig); err != nil {
+		return nil, err
+	}
+	enc, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &signedResponse{MsgCode: msgcode, ReqID: reqID, Data: enc, Sig: sig}, nil
+}
+
+// signer recovers the id of the server which signed the response.
+func (r *signedResponse) signer() (discover.NodeID, error) {
+	pub, err := crypto.SigToPub(responseSigHash(r.MsgCode, r.ReqID, r.Data), r.Sig)
+	if err != nil {
+		return discover.NodeID{}, err
+	}
+	return discover.PubkeyID(pub), nil
+}
+
+// misbehavior is the record of a server found misbehaving, which is refused
+// as a peer from then on.
+type misbehavior struct {
+	ID       discover.NodeID `json:"id"`
+	Reason   string          `json:"reason"`
+	Time     uint64          `json:"time"`
+	Evidence *signedResponse `json:"evidence" rlp:"nil"` // Signed response proving the misbehavior, if any
+}
+
+// misbehaviorSet is the persistent blacklist of misbehaving servers.
+type misbehaviorSet struct {
+	db      kokdb.Database
+	records map[discover.NodeID]*misbehavior
+	lock    sync.RWMutex
+}
+
+// newMisbehaviorSet creates a blacklist, loading the earlier records from the
+// database.
+func newMisbehaviorSet(db kokdb.Database) *misbehaviorSet {
+	set := &misbehaviorSet{
+		db:      db,
+		records: make(map[discover.NodeID]*misbehavior),
+	}
+	if enc, err := db.Get(misbehaviorKey); err == nil {
+		var list []*misbehavior
+		if err := rlp.DecodeBytes(enc, &list); err != nil {
+			log.Error("Failed to decode misbehaving servers", "err", err)
+		}
+		for _, record := range list {
+			set.records[record.ID] = record
+		}
+	}
+	return set
+}
+
+// add blacklists a server, keeping the record of its misbehavior. Evidence not
+// signed by the server itself is rejected, as it proves nothing.
+func (s *misbehaviorSet) add(id discover.NodeID, reason string, evidence *signedResponse) error {
+	if evidence != nil {
+		signer, err := evidence.signer()
+		if err != nil {
+			return err
+		}
+		if signer != id {
+			return errInvalidResponseSig
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.records[id] = &misbehavior{ID: id, Reason: reason, Time: uint64(time.Now().Unix()), Evidence: evidence}
+
+	list := make([]*misbehavior, 0, len(s.records))
+	for _, record := range s.records {
+		list = append(list, record)
+	}
+	enc, err := rlp.EncodeToBytes(list)
+	if err != nil {
+		return err
+	}
+	log.Warn("Blacklisted misbehaving light server", "id", id, "reason", reason, "proof", evidence != nil)
+	return s.db.Put(misbehaviorKey, enc)
+}
+
+// has returns whkoker the server is blacklisted.
+func (s *misbehaviorSet) has(id discover.NodeID) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	_, ok := s.records[id]
+	return ok
+}
+
+// list returns the records of all blacklisted servers.
+func (s *misbehaviorSet) list() []*misbehavior {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	list := make([]*misbehavior, 0, len(s.records))
+	for _, record := range s.records {
+		list = append(list, record)
+	}
+	return list
+}
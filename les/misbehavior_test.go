@@ -0,0 +1,82 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/kokdb"
+	"github.com/kokprojects/go-kok/p2p/discover"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+// Tests that signed responses can be attributed to the signing server, and that
+// misbehaving servers stay blacklisted across restarts.
+func TestMisbehaviorEvidence(t *testing.T) {
+	server, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	id := discover.PubkeyID(&server.PublicKey)
+
+	headers := []*types.Header{{Number: big.NewInt(1)}, {Number: big.NewInt(2)}}
+	sig, err := signResponse(server, BlockHeadersMsg, 42, headers)
+	if err != nil {
+		t.Fatalf("failed to sign response: %v", err)
+	}
+	enc, _ := rlp.EncodeToBytes(sig)
+
+	if _, err := newSignedResponse(BlockHeadersMsg, 42, headers, nil); err != errMissingResponseSig {
+		t.Errorf("unsigned response error mismatch: have %v, want %v", err, errMissingResponseSig)
+	}
+	evidence, err := newSignedResponse(BlockHeadersMsg, 42, headers, []rlp.RawValue{enc})
+	if err != nil {
+		t.Fatalf("failed to assemble signed response: %v", err)
+	}
+	if signer, err := evidence.signer(); err != nil || signer != id {
+		t.Errorf("signer mismatch: have %x/%v, want %x", signer[:8], err, id[:8])
+	}
+	// Responses with altered contents must not be attributed to the server
+	tampered, _ := newSignedResponse(BlockHeadersMsg, 42, headers[:1], []rlp.RawValue{enc})
+	if signer, _ := tampered.signer(); signer == id {
+		t.Errorf("tampered response attributed to server")
+	}
+	db, _ := kokdb.NewMemDatabase()
+	set := newMisbehaviorSet(db)
+	if err := set.add(discover.PubkeyID(&other.PublicKey), "framed", evidence); err != errInvalidResponseSig {
+		t.Errorf("foreign evidence error mismatch: have %v, want %v", err, errInvalidResponseSig)
+	}
+	if err := set.add(id, "invalid headers", evidence); err != nil {
+		t.Fatalf("failed to blacklist server: %v", err)
+	}
+	// Reload the blacklist and check the record survived
+	set = newMisbehaviorSet(db)
+	if !set.has(id) {
+		t.Fatalf("blacklisted server forgotten")
+	}
+	if set.has(discover.PubkeyID(&other.PublicKey)) {
+		t.Errorf("server blacklisted without valid evidence")
+	}
+	records := set.list()
+	if len(records) != 1 || records[0].Reason != "invalid headers" || records[0].Evidence == nil {
+		t.Fatalf("blacklist record mismatch: have %+v", records)
+	}
+	if signer, err := records[0].Evidence.signer(); err != nil || signer != id {
+		t.Errorf("persisted evidence signer mismatch: have %x/%v, want %x", signer[:8], err, id[:8])
+	}
+}
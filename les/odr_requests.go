@@ -25,6 +25,7 @@ import (
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/kokdb"
@@ -64,6 +65,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*TrieRequest)(r)
 	case *light.CodeRequest:
 		return (*CodeRequest)(r)
+	case *light.StorageRequest:
+		return (*StorageRequest)(r)
 	case *light.ChtRequest:
 		return (*ChtRequest)(r)
 	case *light.BloomRequest:
@@ -256,6 +259,101 @@ func (r *TrieRequest) Validate(db kokdb.Database, msg *Msg) error {
 	}
 }
 
+// ODR request type for a batch of storage slots of an account, see LesOdrRequest
+// interface
+type StorageRequest light.StorageRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *StorageRequest) GetCost(peer *peer) uint64 {
+	switch peer.version {
+	case lpv1:
+		return peer.GetRequestCost(GetProofsV1Msg, len(r.Keys)+1)
+	case lpv2:
+		return peer.GetRequestCost(GetProofsV2Msg, len(r.Keys)+1)
+	default:
+		panic(nil)
+	}
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *StorageRequest) CanSend(peer *peer) bool {
+	return peer.HasBlock(r.Id.BlockHash, r.Id.BlockNumber)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *StorageRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting storage proofs", "root", r.Id.Root, "address", r.Address, "keys", len(r.Keys))
+
+	// Prove the account itself, then each of the slots in its storage trie
+	accKey := crypto.Keccak256(r.Address[:])
+	reqs := []ProofReq{{BHash: r.Id.BlockHash, Key: accKey}}
+	for _, key := range r.Keys {
+		reqs = append(reqs, ProofReq{BHash: r.Id.BlockHash, AccKey: accKey, Key: crypto.Keccak256(key[:])})
+	}
+	return peer.RequestProofs(reqID, r.GetCost(peer), reqs)
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *StorageRequest) Validate(db kokdb.Database, msg *Msg) error {
+	log.Debug("Validating storage proofs", "root", r.Id.Root, "address", r.Address, "keys", len(r.Keys))
+
+	var nodeSet *light.NodeSet
+	switch msg.MsgType {
+	case MsgProofsV1:
+		// Storage proofs are omitted for missing accounts, verification
+		// below catches any other shortfall
+		proofs := msg.Obj.([]light.NodeList)
+		if len(proofs) == 0 || len(proofs) > len(r.Keys)+1 {
+			return errInvalidEntryCount
+		}
+		nodeSet = light.NewNodeSet()
+		for _, proof := range proofs {
+			proof.Store(nodeSet)
+		}
+	case MsgProofsV2:
+		nodeSet = msg.Obj.(light.NodeList).NodeSet()
+	default:
+		return errInvalidMessageType
+	}
+	// Verify the account, then the slots against its storage root
+	reads := &readTraceDB{db: nodeSet}
+	data, err, _ := trie.VerifyProof(r.Id.Root, crypto.Keccak256(r.Address[:]), reads)
+	if err != nil {
+		return fmt.Errorf("merkle proof verification failed: %v", err)
+	}
+	values := make([]common.Hash, len(r.Keys))
+	if data != nil {
+		var account state.Account
+		if err := rlp.DecodeBytes(data, &account); err != nil {
+			return err
+		}
+		if account.Root != types.EmptyRootHash {
+			for i, key := range r.Keys {
+				enc, err, _ := trie.VerifyProof(account.Root, crypto.Keccak256(key[:]), reads)
+				if err != nil {
+					return fmt.Errorf("merkle proof verification failed: %v", err)
+				}
+				if len(enc) > 0 {
+					_, content, _, err := rlp.Split(enc)
+					if err != nil {
+						return err
+					}
+					values[i].SetBytes(content)
+				}
+			}
+		}
+	}
+	// check if all nodes have been read by VerifyProof
+	if msg.MsgType == MsgProofsV2 && len(reads.reads) != nodeSet.KeyCount() {
+		return errUselessNodes
+	}
+	r.Values, r.Proof = values, nodeSet
+	return nil
+}
+
 type CodeReq struct {
 	BHash  common.Hash
 	AccKey []byte
@@ -19,12 +19,14 @@ package les
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"math/big"
 	"testing"
 	"time"
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/common/math"
+	"github.com/kokprojects/go-kok/consensus/dpos"
 	"github.com/kokprojects/go-kok/core"
 	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
@@ -34,6 +36,7 @@ import (
 	"github.com/kokprojects/go-kok/light"
 	"github.com/kokprojects/go-kok/params"
 	"github.com/kokprojects/go-kok/rlp"
+	"github.com/kokprojects/go-kok/rpc"
 )
 
 type odrTestFn func(ctx context.Context, db kokdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte
@@ -74,6 +77,33 @@ func odrGetReceipts(ctx context.Context, db kokdb.Database, config *params.Chain
 	return rlp
 }
 
+func TestOdrDposVotesLes1(t *testing.T) { testOdr(t, 1, 1, odrDposVotes) }
+
+func TestOdrDposVotesLes2(t *testing.T) { testOdr(t, 2, 1, odrDposVotes) }
+
+func odrDposVotes(ctx context.Context, db kokdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte {
+	var (
+		engine = dpos.New(&params.DposConfig{}, db)
+		api    *dpos.API
+	)
+	if bc != nil {
+		api = engine.APIs(bc)[0].Service.(*dpos.API)
+	} else {
+		api = engine.LightAPIs(&dposChain{lc, config}, &dposBackend{odr: lc.Odr()})[0].Service.(*dpos.API)
+	}
+	number := rpc.BlockNumber(core.GetBlockNumber(db, bhash))
+	candidates, err := api.GetCandidates(ctx, &number)
+	if err != nil {
+		return nil
+	}
+	votes, err := api.GetVotes(ctx, testBankAddress, &number)
+	if err != nil {
+		return nil
+	}
+	blob, _ := json.Marshal([]interface{}{candidates, votes})
+	return blob
+}
+
 func TestOdrAccountsLes1(t *testing.T) { testOdr(t, 1, 1, odrAccounts) }
 
 func TestOdrAccountsLes2(t *testing.T) { testOdr(t, 2, 1, odrAccounts) }
@@ -105,6 +135,38 @@ func odrAccounts(ctx context.Context, db kokdb.Database, config *params.ChainCon
 	return res
 }
 
+func TestOdrStorageLes1(t *testing.T) { testOdr(t, 1, 1, odrStorage) }
+
+func TestOdrStorageLes2(t *testing.T) { testOdr(t, 2, 1, odrStorage) }
+
+func odrStorage(ctx context.Context, db kokdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte {
+	dummyAddr := common.HexToAddress("1234567812345678123456781234567812345678")
+	keys := []common.Hash{{}, common.BytesToHash([]byte{1}), common.BytesToHash([]byte{2}), common.BytesToHash([]byte{0xff})}
+
+	var res []byte
+	for _, addr := range []common.Address{testContractAddr, dummyAddr} {
+		var values []common.Hash
+		if bc != nil {
+			header := bc.GkokeaderByHash(bhash)
+			st, err := state.New(header.Root, state.NewDatabase(db))
+			if err != nil {
+				return nil
+			}
+			for _, key := range keys {
+				values = append(values, st.GetState(addr, key))
+			}
+		} else {
+			var err error
+			if values, err = light.GetStorage(ctx, lc.Odr(), lc.GkokeaderByHash(bhash), addr, keys); err != nil {
+				return nil
+			}
+		}
+		rlp, _ := rlp.EncodeToBytes(values)
+		res = append(res, rlp...)
+	}
+	return res
+}
+
 func TestOdrContractCallLes1(t *testing.T) { testOdr(t, 1, 2, odrContractCall) }
 
 func TestOdrContractCallLes2(t *testing.T) { testOdr(t, 2, 2, odrContractCall) }
@@ -60,6 +60,10 @@ type peer struct {
 
 	announceType, requestAnnounceType uint64
 
+	signKey         *ecdsa.PrivateKey // Key to sign responses with, nil if the client didn't ask for it
+	signedResponses bool              // Whkoker the server signs its responses
+	lastSigned      *signedResponse   // Last signed response received from the server
+
 	id string
 
 	headInfo *announceData
@@ -163,6 +167,56 @@ func sendResponse(w p2p.MsgWriter, msgcode, reqID, bv uint64, data interface{})
 	return p2p.Send(w, msgcode, resp{reqID, bv, data})
 }
 
+// sendSignedResponse sends a response, signed by the node key if the remote
+// client asked for signed responses.
+func (p *peer) sendSignedResponse(msgcode, reqID, bv uint64, data interface{}) error {
+	if p.signKey == nil {
+		return sendResponse(p.rw, msgcode, reqID, bv, data)
+	}
+	sig, err := signResponse(p.signKey, msgcode, reqID, data)
+	if err != nil {
+		return err
+	}
+	type resp struct {
+		ReqID, BV uint64
+		Data      interface{}
+		Sig       []byte
+	}
+	return p2p.Send(p.rw, msgcode, resp{reqID, bv, data, sig})
+}
+
+// checkResponse verifies the signature of a response received from the server,
+// if it agreed to sign them, returning the signed response as proof of custody.
+func (p *peer) checkResponse(msgcode, reqID uint64, data interface{}, rest []rlp.RawValue) (*signedResponse, error) {
+	if !p.signedResponses {
+		return nil, nil
+	}
+	resp, err := newSignedResponse(msgcode, reqID, data, rest)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := resp.signer()
+	if err != nil {
+		return nil, err
+	}
+	if signer != p.ID() {
+		return nil, errInvalidResponseSig
+	}
+	p.lock.Lock()
+	p.lastSigned = resp
+	p.lock.Unlock()
+
+	return resp, nil
+}
+
+// LastSigned returns the last signed response received from the server.
+func (p *peer) LastSigned() *signedResponse {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.lastSigned
+}
+
 func (p *peer) GetRequestCost(msgcode uint64, amount int) uint64 {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
@@ -190,7 +244,7 @@ func (p *peer) SendAnnounce(request announceData) error {
 
 // SendBlockHeaders sends a batch of block headers to the remote peer.
 func (p *peer) SendBlockHeaders(reqID, bv uint64, headers []*types.Header) error {
-	return sendResponse(p.rw, BlockHeadersMsg, reqID, bv, headers)
+	return p.sendSignedResponse(BlockHeadersMsg, reqID, bv, headers)
 }
 
 // SendBlockBodiesRLP sends a batch of block contents to the remote peer from
@@ -213,22 +267,22 @@ func (p *peer) SendReceiptsRLP(reqID, bv uint64, receipts []rlp.RawValue) error
 
 // SendProofs sends a batch of legacy LES/1 merkle proofs, corresponding to the ones requested.
 func (p *peer) SendProofs(reqID, bv uint64, proofs proofsData) error {
-	return sendResponse(p.rw, ProofsV1Msg, reqID, bv, proofs)
+	return p.sendSignedResponse(ProofsV1Msg, reqID, bv, proofs)
 }
 
 // SendProofsV2 sends a batch of merkle proofs, corresponding to the ones requested.
 func (p *peer) SendProofsV2(reqID, bv uint64, proofs light.NodeList) error {
-	return sendResponse(p.rw, ProofsV2Msg, reqID, bv, proofs)
+	return p.sendSignedResponse(ProofsV2Msg, reqID, bv, proofs)
 }
 
 // SendHeaderProofs sends a batch of legacy LES/1 header proofs, corresponding to the ones requested.
 func (p *peer) SendHeaderProofs(reqID, bv uint64, proofs []ChtResp) error {
-	return sendResponse(p.rw, HeaderProofsMsg, reqID, bv, proofs)
+	return p.sendSignedResponse(HeaderProofsMsg, reqID, bv, proofs)
 }
 
 // SendHelperTrieProofs sends a batch of HelperTrie proofs, corresponding to the ones requested.
 func (p *peer) SendHelperTrieProofs(reqID, bv uint64, resp HelperTrieResps) error {
-	return sendResponse(p.rw, HelperTrieProofsMsg, reqID, bv, resp)
+	return p.sendSignedResponse(HelperTrieProofsMsg, reqID, bv, resp)
 }
 
 // SendTxStatus sends a batch of transaction status records, corresponding to the ones requested.
@@ -415,9 +469,15 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		list := server.fcCostStats.getCurrentList()
 		send = send.add("flowControl/MRC", list)
 		p.fcCosts = list.decode()
+		if server.signResponses {
+			send = send.add("signResponses", nil)
+		}
 	} else {
-		p.requestAnnounceType = announceTypeSimple // set to default until "very light" client mode is implemented
+		if p.requestAnnounceType == announceTypeNone {
+			p.requestAnnounceType = announceTypeSimple // signed announcements are only requested in minimal sync mode
+		}
 		send = send.add("announceType", p.requestAnnounceType)
+		send = send.add("signResponses", nil)
 	}
 	recvList, err := p.sendReceiveHandshake(send)
 	if err != nil {
@@ -465,6 +525,9 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		if recv.get("announceType", &p.announceType) != nil {
 			p.announceType = announceTypeSimple
 		}
+		if server.signResponses && recv.get("signResponses", nil) == nil {
+			p.signKey = server.privateKey
+		}
 		p.fcClient = flowcontrol.NewClientNode(server.fcManager, server.defParams)
 	} else {
 		if recv.get("serveChainSince", nil) != nil {
@@ -487,6 +550,7 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 		if err := recv.get("flowControl/MRC", &MRC); err != nil {
 			return err
 		}
+		p.signedResponses = recv.get("signResponses", nil) == nil
 		p.fcServerParams = params
 		p.fcServer = flowcontrol.NewServerNode(params)
 		p.fcCosts = MRC.decode()
@@ -363,15 +363,26 @@ func (r *sentReq) deliver(peer distPeer, msg *Msg) error {
 	if !ok || s.delivered {
 		return errResp(ErrUnexpectedResponse, "reqID = %v", msg.ReqID)
 	}
-	valid := r.validate(peer, msg) == nil
+	err := r.validate(peer, msg)
 	r.sentTo[peer] = sentReqToPeer{true, s.valid}
-	s.valid <- valid
-	if !valid {
-		return errResp(ErrInvalidResponse, "reqID = %v", msg.ReqID)
+	s.valid <- err == nil
+	if err != nil {
+		return &invalidResponseError{msg.ReqID, err}
 	}
 	return nil
 }
 
+// invalidResponseError is returned on the delivery of a reply failing validation,
+// as opposed to an unexpected one.
+type invalidResponseError struct {
+	reqID uint64
+	err   error
+}
+
+func (e *invalidResponseError) Error() string {
+	return errResp(ErrInvalidResponse, "reqID = %v: %v", e.reqID, e.err).Error()
+}
+
 // stop stops the retrieval process and sets an error code that will be returned
 // by getError
 func (r *sentReq) stop(err error) {
@@ -44,6 +44,7 @@ type LesServer struct {
 	defParams       *flowcontrol.ServerParams
 	lesTopics       []discv5.Topic
 	privateKey      *ecdsa.PrivateKey
+	signResponses   bool // Whkoker to sign responses for clients asking for it
 	quitSync        chan struct{}
 
 	chtIndexer, bloomTrieIndexer *core.ChainIndexer
@@ -65,6 +66,7 @@ func NewLesServer(kok *kok.kokereum, config *kok.Config) (*LesServer, error) {
 		protocolManager:  pm,
 		quitSync:         quitSync,
 		lesTopics:        lesTopics,
+		signResponses:    config.LightSign,
 		chtIndexer:       light.NewChtIndexer(kok.ChainDb(), false),
 		bloomTrieIndexer: light.NewBloomTrieIndexer(kok.ChainDb(), false),
 	}
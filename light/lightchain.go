@@ -475,6 +475,38 @@ func (self *LightChain) SyncCht(ctx context.Context) bool {
 	return false
 }
 
+// SetTrustedHead makes the given header the head of the chain along with the
+// total difficulty announced for it, without downloading or validating any of
+// its ancestors. It is used in minimal sync mode, where only the heads announced
+// by trusted servers are followed, so the previous head is only kept canonical
+// if it is the parent of the new one.
+func (self *LightChain) SetTrustedHead(header *types.Header, td *big.Int) error {
+	self.chainmu.Lock()
+	defer self.chainmu.Unlock()
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	hash, number := header.Hash(), header.Number.Uint64()
+	if head := self.hc.CurrentHeader(); head.Hash() != header.ParentHash && head.Number.Sign() > 0 && head.Number.Uint64() != number {
+		core.DeleteCanonicalHash(self.chainDb, head.Number.Uint64())
+	}
+	if err := core.WriteTd(self.chainDb, hash, number, td); err != nil {
+		return err
+	}
+	if err := core.WriteHeader(self.chainDb, header); err != nil {
+		return err
+	}
+	if err := core.WriteCanonicalHash(self.chainDb, hash, number); err != nil {
+		return err
+	}
+	self.hc.SetCurrentHeader(header)
+
+	log.Debug("Set trusted head", "number", number, "hash", hash, "td", td)
+	go self.postChainEvents([]interface{}{core.ChainEvent{Block: types.NewBlockWithHeader(header), Hash: hash}})
+	return nil
+}
+
 // LockChain locks the chain mutex for reading so that multiple canonical hashes can be
 // retrieved while it is guaranteed that they belong to the same version of the chain
 func (self *LightChain) LockChain() {
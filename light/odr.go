@@ -19,6 +19,7 @@
 package light
 
 import (
+	"bytes"
 	"context"
 	"math/big"
 
@@ -76,6 +77,30 @@ func StorageTrieID(state *TrieID, addrHash, root common.Hash) *TrieID {
 	}
 }
 
+// dposTrieKey is the account key marking requests for the entries of the dpos
+// tries, followed by the root of the requested trie.
+var dposTrieKey = []byte("dpos")
+
+// DposTrieID returns a TrieID for one of the dpos tries committed to by a block
+// header, identified by its root.
+func DposTrieID(header *types.Header, root common.Hash) *TrieID {
+	return &TrieID{
+		BlockHash:   header.Hash(),
+		BlockNumber: header.Number.Uint64(),
+		AccKey:      append(common.CopyBytes(dposTrieKey), root[:]...),
+		Root:        root,
+	}
+}
+
+// DposTrieRoot returns the root of the dpos trie designated by the account key
+// of a trie request, if it designates one.
+func DposTrieRoot(accKey []byte) (common.Hash, bool) {
+	if len(accKey) != len(dposTrieKey)+common.HashLength || !bytes.HasPrefix(accKey, dposTrieKey) {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(accKey[len(dposTrieKey):]), true
+}
+
 // TrieRequest is the ODR request type for state/storage trie entries
 type TrieRequest struct {
 	OdrRequest
@@ -102,6 +127,22 @@ func (req *CodeRequest) StoreResult(db kokdb.Database) {
 	db.Put(req.Hash[:], req.Data)
 }
 
+// StorageRequest is the ODR request type for retrieving a batch of storage slots
+// of an account along with their Merkle proofs
+type StorageRequest struct {
+	OdrRequest
+	Id      *TrieID // references the state trie of the block
+	Address common.Address
+	Keys    []common.Hash
+	Values  []common.Hash
+	Proof   *NodeSet
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *StorageRequest) StoreResult(db kokdb.Database) {
+	req.Proof.Store(db)
+}
+
 // BlockRequest is the ODR request type for retrieving block bodies
 type BlockRequest struct {
 	OdrRequest
@@ -22,6 +22,7 @@ import (
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/state"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/crypto"
 	"github.com/kokprojects/go-kok/rlp"
@@ -29,6 +30,10 @@ import (
 
 var sha3_nil = crypto.Keccak256Hash(nil)
 
+// maxStorageRequestKeys is the number of storage slots retrieved with a single
+// request, keeping the proofs within the limits of the serving peers.
+const maxStorageRequestKeys = 32
+
 func GkokeaderByNumber(ctx context.Context, odr OdrBackend, number uint64) (*types.Header, error) {
 	db := odr.Database()
 	hash := core.GetCanonicalHash(db, number)
@@ -140,6 +145,37 @@ func GetBlockReceipts(ctx context.Context, odr OdrBackend, hash common.Hash, num
 	return r.Receipts, nil
 }
 
+// GetStorage retrieves storage slots of an account in the state of the given
+// header, verified against its state root. The proofs are stored locally, so the
+// slots can subsequently be read from the state returned by NewState without
+// further network round trips.
+func GetStorage(ctx context.Context, odr OdrBackend, header *types.Header, address common.Address, keys []common.Hash) ([]common.Hash, error) {
+	// Serve the slots from the local database if all of them are present
+	if statedb, err := state.New(header.Root, state.NewDatabase(odr.Database())); err == nil {
+		values := make([]common.Hash, len(keys))
+		for i, key := range keys {
+			values[i] = statedb.GetState(address, key)
+		}
+		if statedb.Error() == nil {
+			return values, nil
+		}
+	}
+	values := make([]common.Hash, 0, len(keys))
+	for len(keys) > 0 {
+		batch := keys
+		if len(batch) > maxStorageRequestKeys {
+			batch = batch[:maxStorageRequestKeys]
+		}
+		r := &StorageRequest{Id: StateTrieID(header), Address: address, Keys: batch}
+		if err := odr.Retrieve(ctx, r); err != nil {
+			return nil, err
+		}
+		values = append(values, r.Values...)
+		keys = keys[len(batch):]
+	}
+	return values, nil
+}
+
 // GetBloomBits retrieves a batch of compressed bloomBits vectors belonging to the given bit index and section indexes
 func GetBloomBits(ctx context.Context, odr OdrBackend, bitIdx uint, sectionIdxList []uint64) ([][]byte, error) {
 	db := odr.Database()
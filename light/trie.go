@@ -156,6 +156,17 @@ func (t *odrTrie) do(key []byte, fn func() error) error {
 	}
 }
 
+// RetrieveDposTrieNode retrieves a trie node reported missing by a lookup in one
+// of the dpos tries committed to by a header, along with the rest of the proof
+// of a key passing through it.
+func RetrieveDposTrieNode(ctx context.Context, odr OdrBackend, header *types.Header, root common.Hash, missing *trie.MissingNodeError) error {
+	r := &TrieRequest{Id: DposTrieID(header, root), Key: nibblesToKey(missing.Path)}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return fmt.Errorf("can't fetch dpos trie node %x: %v", missing.NodeHash, err)
+	}
+	return nil
+}
+
 type nodeIterator struct {
 	trie.NodeIterator
 	t   *odrTrie
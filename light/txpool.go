@@ -19,6 +19,7 @@ package light
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"sync"
 	"time"
 
@@ -349,6 +350,10 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 	if from, err = types.Sender(pool.signer, tx); err != nil {
 		return core.ErrInvalidSender
 	}
+	// Ensure the transaction follows the rules of its type
+	if err := tx.Validate(); err != nil {
+		return err
+	}
 	// Last but not least check for nonce errors
 	currentState := pool.currentState(ctx)
 	if n := currentState.GetNonce(from); n > tx.Nonce() {
@@ -362,6 +367,16 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 		return core.ErrGasLimit
 	}
 
+	// Frozen accounts can't send transactions in the next block
+	next := new(big.Int).Add(header.Number, common.Big1)
+	if pool.config.IsFrozen(next, from) {
+		return core.ErrFrozenAccount
+	}
+	// On permissioned networks the sender must be allowed to send (and deploy)
+	if err := core.CheckPermission(pool.config, currentState, next, from, tx.To(), tx.Type()); err != nil {
+		return err
+	}
+
 	// Transactions can't be negative. This may never happen
 	// using RLP decoded transactions but may occur if you create
 	// a transaction using the RPC for example.
@@ -18,6 +18,7 @@
 package miner
 
 import (
+	"errors"
 	"fmt"
 	"sync/atomic"
 
@@ -34,6 +35,10 @@ import (
 	"github.com/kokprojects/go-kok/params"
 )
 
+// errNoPolicy is returned when reloading the inclusion policy of a miner that
+// has none configured.
+var errNoPolicy = errors.New("no miner policy configured")
+
 // Backend wraps all mkokods required for mining.
 type Backend interface {
 	AccountManager() *accounts.Manager
@@ -153,6 +158,38 @@ func (self *Miner) PendingBlock() *types.Block {
 	return self.worker.pendingBlock()
 }
 
+// SetPolicy loads the local transaction inclusion policy from the given file,
+// or removes it if the path is empty. The policy applies from the next block
+// the miner starts working on.
+func (self *Miner) SetPolicy(path string) error {
+	if path == "" {
+		self.worker.setPolicy(nil)
+		return nil
+	}
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		return err
+	}
+	self.worker.setPolicy(policy)
+	log.Info("Loaded miner inclusion policy", "path", path, "denied", len(policy.deny), "allowed", len(policy.allow))
+	return nil
+}
+
+// SetOrdering sets the strategy ordering the transactions of the blocks the
+// miner produces, from the next block it starts working on.
+func (self *Miner) SetOrdering(ordering TxOrdering) {
+	self.worker.setOrdering(ordering)
+}
+
+// ReloadPolicy rereads the local transaction inclusion policy from its file.
+func (self *Miner) ReloadPolicy() error {
+	policy := self.worker.getPolicy()
+	if policy == nil {
+		return errNoPolicy
+	}
+	return self.SetPolicy(policy.path)
+}
+
 func (self *Miner) SetCoinbase(addr common.Address) {
 	self.coinbase = addr
 	self.worker.setCoinbase(addr)
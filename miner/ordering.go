@@ -0,0 +1,205 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"sort"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+)
+
+// TxIterator walks the pending transactions in the order they should be included
+// into a block. The transactions of an account are always returned in nonce order.
+type TxIterator interface {
+	// Peek returns the next transaction to include, nil if there are none left.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one of the same account.
+	Shift()
+
+	// Pop removes the current transaction along with all later ones of the account.
+	Pop()
+}
+
+// TxOrdering is a strategy ordering the pending transactions of a block. It only
+// affects locally produced blocks.
+type TxOrdering interface {
+	// Order returns an iterator over the given nonce sorted transactions of each
+	// account.
+	Order(signer types.Signer, txs map[common.Address]types.Transactions) TxIterator
+}
+
+// Names of the built-in transaction ordering strategies.
+const (
+	PriceOrdering = "price" // Highest gas price first
+	FairOrdering  = "fair"  // One transaction per account in each round-robin pass
+	FIFOOrdering  = "fifo"  // Earliest received first
+)
+
+// NewOrdering returns the built-in transaction ordering strategy with the given
+// name, defaulting to price ordering if the name is empty.
+func NewOrdering(name string) (TxOrdering, error) {
+	switch name {
+	case "", PriceOrdering:
+		return priceOrdering{}, nil
+	case FairOrdering:
+		return fairOrdering{}, nil
+	case FIFOOrdering:
+		return fifoOrdering{}, nil
+	default:
+		return nil, fmt.Errorf("unknown transaction ordering %q", name)
+	}
+}
+
+// priceOrdering includes the transactions paying the highest gas price first.
+type priceOrdering struct{}
+
+func (priceOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions) TxIterator {
+	return types.NewTransactionsByPriceAndNonce(signer, txs)
+}
+
+// sortedAccounts returns the accounts with pending transactions, ordered by the
+// arrival of their first transaction and then by address.
+func sortedAccounts(txs map[common.Address]types.Transactions) []common.Address {
+	accounts := make([]common.Address, 0, len(txs))
+	for addr, list := range txs {
+		if len(list) > 0 {
+			accounts = append(accounts, addr)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		ti, tj := txs[accounts[i]][0].Time(), txs[accounts[j]][0].Time()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return bytes.Compare(accounts[i][:], accounts[j][:]) < 0
+	})
+	return accounts
+}
+
+// fairOrdering takes turns between the accounts, including one transaction of
+// each account per pass regardless of the gas price paid.
+type fairOrdering struct{}
+
+func (fairOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions) TxIterator {
+	it := &fairIterator{txs: make(map[common.Address]types.Transactions, len(txs))}
+	for _, addr := range sortedAccounts(txs) {
+		it.txs[addr] = txs[addr]
+		it.accounts = append(it.accounts, addr)
+	}
+	return it
+}
+
+// fairIterator is the round-robin iterator of the fair ordering.
+type fairIterator struct {
+	txs      map[common.Address]types.Transactions // Remaining transactions of each account
+	accounts []common.Address                      // Accounts in the order of their next turn
+}
+
+func (it *fairIterator) Peek() *types.Transaction {
+	if len(it.accounts) == 0 {
+		return nil
+	}
+	return it.txs[it.accounts[0]][0]
+}
+
+func (it *fairIterator) Shift() {
+	addr := it.accounts[0]
+	it.accounts = it.accounts[1:]
+	if rest := it.txs[addr][1:]; len(rest) > 0 {
+		it.txs[addr] = rest
+		it.accounts = append(it.accounts, addr)
+	} else {
+		delete(it.txs, addr)
+	}
+}
+
+func (it *fairIterator) Pop() {
+	delete(it.txs, it.accounts[0])
+	it.accounts = it.accounts[1:]
+}
+
+// fifoOrdering includes the transactions in the order they were received.
+type fifoOrdering struct{}
+
+func (fifoOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions) TxIterator {
+	it := &fifoIterator{signer: signer, txs: make(map[common.Address]types.Transactions, len(txs))}
+	for _, addr := range sortedAccounts(txs) {
+		it.heads = append(it.heads, txs[addr][0])
+		it.txs[addr] = txs[addr][1:]
+	}
+	heap.Init(&it.heads)
+	return it
+}
+
+// txByTime implements heap.Interface over the next transactions of the accounts,
+// earliest received first.
+type txByTime []*types.Transaction
+
+func (s txByTime) Len() int { return len(s) }
+func (s txByTime) Less(i, j int) bool {
+	if ti, tj := s[i].Time(), s[j].Time(); !ti.Equal(tj) {
+		return ti.Before(tj)
+	}
+	hi, hj := s[i].Hash(), s[j].Hash()
+	return bytes.Compare(hi[:], hj[:]) < 0
+}
+func (s txByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *txByTime) Push(x interface{}) {
+	*s = append(*s, x.(*types.Transaction))
+}
+
+func (s *txByTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[0 : n-1]
+	return x
+}
+
+// fifoIterator is the iterator of the first-in-first-out ordering.
+type fifoIterator struct {
+	signer types.Signer
+	txs    map[common.Address]types.Transactions // Remaining transactions of each account
+	heads  txByTime                              // Next transaction of each account
+}
+
+func (it *fifoIterator) Peek() *types.Transaction {
+	if len(it.heads) == 0 {
+		return nil
+	}
+	return it.heads[0]
+}
+
+func (it *fifoIterator) Shift() {
+	addr, _ := types.Sender(it.signer, it.heads[0])
+	if txs := it.txs[addr]; len(txs) > 0 {
+		it.heads[0], it.txs[addr] = txs[0], txs[1:]
+		heap.Fix(&it.heads, 0)
+		return
+	}
+	heap.Pop(&it.heads)
+}
+
+func (it *fifoIterator) Pop() {
+	heap.Pop(&it.heads)
+}
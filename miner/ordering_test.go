@@ -0,0 +1,80 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/crypto"
+)
+
+// Tests that the built-in orderings include the transactions in the expected
+// order while keeping the nonce order of each account.
+func TestOrdering(t *testing.T) {
+	var (
+		keyA, _ = crypto.GenerateKey()
+		keyB, _ = crypto.GenerateKey()
+		addrA   = crypto.PubkeyToAddress(keyA.PublicKey)
+		addrB   = crypto.PubkeyToAddress(keyB.PublicKey)
+		signer  = types.NewEIP155Signer(common.Big1)
+	)
+	// Create the transactions in the order a0, b0, b1, a1, a2 with b paying more
+	created := make(map[string]*types.Transaction)
+	for _, name := range []string{"a0", "b0", "b1", "a1", "a2"} {
+		key, price := keyA, big.NewInt(1)
+		if name[0] == 'b' {
+			key, price = keyB, big.NewInt(2)
+		}
+		tx := types.NewTransaction(types.Binary, uint64(name[1]-'0'), common.Address{}, new(big.Int), big.NewInt(21000), price, nil)
+		created[name], _ = types.SignTx(tx, signer, key)
+		time.Sleep(time.Millisecond)
+	}
+	tests := []struct {
+		ordering string
+		want     []string
+	}{
+		{PriceOrdering, []string{"b0", "b1", "a0", "a1", "a2"}},
+		{FairOrdering, []string{"a0", "b0", "a1", "b1", "a2"}},
+		{FIFOOrdering, []string{"a0", "b0", "b1", "a1", "a2"}},
+	}
+	for _, tt := range tests {
+		ordering, err := NewOrdering(tt.ordering)
+		if err != nil {
+			t.Fatalf("%s: failed to create ordering: %v", tt.ordering, err)
+		}
+		it := ordering.Order(signer, map[common.Address]types.Transactions{
+			addrA: {created["a0"], created["a1"], created["a2"]},
+			addrB: {created["b0"], created["b1"]},
+		})
+		for i, name := range tt.want {
+			if tx := it.Peek(); tx != created[name] {
+				t.Fatalf("%s: transaction %d mismatch: want %s", tt.ordering, i, name)
+			}
+			it.Shift()
+		}
+		if tx := it.Peek(); tx != nil {
+			t.Errorf("%s: unexpected transaction after all included: %x", tt.ordering, tx.Hash())
+		}
+	}
+	if _, err := NewOrdering("random"); err == nil {
+		t.Errorf("unknown ordering accepted")
+	}
+}
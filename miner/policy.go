@@ -0,0 +1,89 @@
+// Copyright 2016 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+)
+
+// Policy is a local inclusion policy of the miner, restricting the transactions
+// that may be included into locally produced blocks. It doesn't affect the
+// validation of blocks produced by others.
+type Policy struct {
+	path  string
+	deny  map[common.Address]struct{} // Accounts whose transactions are never included
+	allow map[common.Address]struct{} // Accounts whose transactions are exclusively included, if any
+}
+
+// policyJSON is the on-disk format of a miner policy file.
+type policyJSON struct {
+	Deny  []common.Address `json:"deny"`
+	Allow []common.Address `json:"allow"`
+}
+
+// LoadPolicy reads the miner inclusion policy from the given JSON file, in the
+// form of {"deny": [<address>, ...], "allow": [<address>, ...]}.
+func LoadPolicy(path string) (*Policy, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec policyJSON
+	if err := json.Unmarshal(blob, &spec); err != nil {
+		return nil, fmt.Errorf("invalid miner policy %s: %v", path, err)
+	}
+	policy := &Policy{
+		path:  path,
+		deny:  make(map[common.Address]struct{}),
+		allow: make(map[common.Address]struct{}),
+	}
+	for _, addr := range spec.Deny {
+		policy.deny[addr] = struct{}{}
+	}
+	for _, addr := range spec.Allow {
+		policy.allow[addr] = struct{}{}
+	}
+	return policy, nil
+}
+
+// Permits reports whether a transaction of the given sender may be included.
+// Transactions sent by or to a denied account are rejected, and if an allowlist
+// is configured, only transactions sent by its accounts are accepted.
+func (p *Policy) Permits(from common.Address, tx *types.Transaction) bool {
+	if p == nil {
+		return true
+	}
+	if _, ok := p.deny[from]; ok {
+		return false
+	}
+	if to := tx.To(); to != nil {
+		if _, ok := p.deny[*to]; ok {
+			return false
+		}
+	}
+	if len(p.allow) > 0 {
+		if _, ok := p.allow[from]; !ok {
+			return false
+		}
+	}
+	return true
+}
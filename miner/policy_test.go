@@ -0,0 +1,86 @@
+// Copyright 2016 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kokprojects/go-kok/common"
+	"github.com/kokprojects/go-kok/core/types"
+)
+
+// Tests that the miner policy is loaded from its file and filters transactions
+// by their sender and recipient.
+func TestPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "miner-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		denied  = common.HexToAddress("0x01")
+		allowed = common.HexToAddress("0x02")
+		other   = common.HexToAddress("0x03")
+	)
+	write := func(content string) string {
+		path := filepath.Join(dir, "policy.json")
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	transfer := func(to common.Address) *types.Transaction {
+		return types.NewTransaction(types.Binary, 0, to, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil)
+	}
+	// A denylist only rejects transactions touching the listed accounts
+	policy, err := LoadPolicy(write(`{"deny": ["` + denied.Hex() + `"]}`))
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	if policy.Permits(denied, transfer(other)) {
+		t.Errorf("transaction from denied account permitted")
+	}
+	if policy.Permits(other, transfer(denied)) {
+		t.Errorf("transaction to denied account permitted")
+	}
+	if !policy.Permits(other, transfer(allowed)) {
+		t.Errorf("unlisted transaction rejected")
+	}
+	// An allowlist rejects transactions of any unlisted sender
+	policy, err = LoadPolicy(write(`{"allow": ["` + allowed.Hex() + `"]}`))
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	if !policy.Permits(allowed, transfer(other)) {
+		t.Errorf("transaction from allowed account rejected")
+	}
+	if policy.Permits(other, transfer(allowed)) {
+		t.Errorf("transaction from unlisted account permitted")
+	}
+	// A missing policy permits everything, a malformed one fails to load
+	if !(*Policy)(nil).Permits(denied, transfer(denied)) {
+		t.Errorf("transaction rejected without a policy")
+	}
+	if _, err := LoadPolicy(write(`{"deny": "0x01"`)); err == nil {
+		t.Errorf("malformed policy loaded")
+	}
+}
@@ -72,6 +72,8 @@ type Work struct {
 	txs      []*types.Transaction
 	receipts []*types.Receipt
 
+	policy *Policy // Inclusion policy of the transactions, nil if unrestricted
+
 	createdAt time.Time
 }
 
@@ -105,6 +107,8 @@ type worker struct {
 
 	coinbase common.Address
 	extra    []byte
+	policy   *Policy
+	ordering TxOrdering
 
 	currentMu sync.Mutex
 	current   *Work
@@ -136,6 +140,7 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, coinbase com
 		proc:           kok.BlockChain().Validator(),
 		possibleUncles: make(map[common.Hash]*types.Block),
 		coinbase:       coinbase,
+		ordering:       priceOrdering{},
 		unconfirmed:    newUnconfirmedBlocks(kok.BlockChain(), miningLogAtDepth),
 		quitCh:         make(chan struct{}, 1),
 		stopper:        make(chan struct{}, 1),
@@ -164,6 +169,24 @@ func (self *worker) setExtra(extra []byte) {
 	self.extra = extra
 }
 
+func (self *worker) setPolicy(policy *Policy) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.policy = policy
+}
+
+func (self *worker) setOrdering(ordering TxOrdering) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.ordering = ordering
+}
+
+func (self *worker) getPolicy() *Policy {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.policy
+}
+
 func (self *worker) pending() (*types.Block, *state.StateDB) {
 	self.currentMu.Lock()
 	defer self.currentMu.Unlock()
@@ -309,6 +332,13 @@ func (self *worker) wait() {
 			block := result.Block
 			work := result.Work
 
+			// Dry-run the import of the sealed block before publishing it, peers
+			// would drop us for propagating a block they fail to validate.
+			if err := self.chain.ValidateBlock(block); err != nil {
+				log.Error("Refusing to publish invalid sealed block", "number", block.Number(), "hash", block.Hash(), "err", err)
+				continue
+			}
+
 			// Update the block hash in all logs since it is now available and not when the
 			// receipt/log of individual transactions were created.
 			for _, r := range work.receipts {
@@ -353,6 +383,9 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 	if err != nil {
 		return err
 	}
+	if self.chain.GetVMConfig().EnableBalanceRecording {
+		state.EnableBalanceTracking()
+	}
 	dposContext, err := types.NewDposContextFromProto(self.chainDb, parent.Header().DposContext)
 	if err != nil {
 		return err
@@ -366,6 +399,7 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 		family:      set.New(),
 		uncles:      set.New(),
 		header:      header,
+		policy:      self.policy,
 		createdAt:   time.Now(),
 	}
 
@@ -450,7 +484,7 @@ func (self *worker) createNewWork() (*Work, error) {
 	if err != nil {
 		return nil, fmt.Errorf("got error when fetch pending transactions, err: %s", err)
 	}
-	txs := types.NewTransactionsByPriceAndNonce(self.current.signer, pending)
+	txs := self.ordering.Order(self.current.signer, pending)
 	work.commitTransactions(self.mux, txs, self.chain, self.coinbase)
 
 	// compute uncles for the new block.
@@ -476,6 +510,7 @@ func (self *worker) createNewWork() (*Work, error) {
 		delete(self.possibleUncles, hash)
 	}
 	// Create the new block to seal with the consensus engine
+	work.state.FinaliseBalanceChanges()
 	if work.Block, err = self.engine.Finalize(self.chain, header, work.state, work.txs, uncles, work.receipts, work.dposContext); err != nil {
 		return nil, fmt.Errorf("got error when finalize block for sealing, err: %s", err)
 	}
@@ -505,7 +540,7 @@ func (self *worker) commitUncle(work *Work, uncle *types.Header) error {
 	return nil
 }
 
-func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsByPriceAndNonce, bc *core.BlockChain, coinbase common.Address) {
+func (env *Work) commitTransactions(mux *event.TypeMux, txs TxIterator, bc *core.BlockChain, coinbase common.Address) {
 	gp := new(core.GasPool).AddGas(env.header.GasLimit)
 
 	var coalescedLogs []*types.Log
@@ -530,6 +565,13 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 			txs.Pop()
 			continue
 		}
+		// Skip the sender if the local inclusion policy forbids the transaction
+		if !env.policy.Permits(from, tx) {
+			log.Trace("Ignoring transaction rejected by miner policy", "hash", tx.Hash(), "sender", from)
+
+			txs.Pop()
+			continue
+		}
 		// Start executing the transaction
 		env.state.Prepare(tx.Hash(), common.Hash{}, env.tcount)
 
@@ -587,7 +629,8 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 func (env *Work) commitTransaction(tx *types.Transaction, bc *core.BlockChain, coinbase common.Address, gp *core.GasPool) (error, []*types.Log) {
 	snap := env.state.Snapshot()
 	dposSnap := env.dposContext.Snapshot()
-	receipt, _, err := core.ApplyTransaction(env.config, env.dposContext, bc, &coinbase, gp, env.state, env.header, tx, env.header.GasUsed, vm.Config{})
+	vmConfig := vm.Config{EnableInternalTxRecording: bc.GetVMConfig().EnableInternalTxRecording}
+	receipt, _, err := core.ApplyTransaction(env.config, env.dposContext, bc, &coinbase, gp, env.state, env.header, tx, env.header.GasUsed, vmConfig)
 	if err != nil {
 		env.state.RevertToSnapshot(snap)
 		env.dposContext.RevertToSnapShot(dposSnap)
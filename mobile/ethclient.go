@@ -21,6 +21,7 @@ package gkok
 import (
 	"math/big"
 
+	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/core/types"
 	"github.com/kokprojects/go-kok/kokclient"
 )
@@ -188,6 +189,79 @@ func (ec *kokereumClient) GetNonceAt(ctx *Context, account *Address, number int6
 	return int64(rawNonce), err
 }
 
+// Delegated proof-of-stake
+//
+// Full nodes serve these queries from their local staking state, light nodes
+// retrieve the parts they need from their servers on demand.
+
+// GetValidatorsAt returns the validators of the epoch of the given block.
+// The block number can be <0, in which case the latest known block is used.
+func (ec *kokereumClient) GetValidatorsAt(ctx *Context, number int64) (validators *Addresses, _ error) {
+	var blockNumber *big.Int
+	if number >= 0 {
+		blockNumber = big.NewInt(number)
+	}
+	rawValidators, err := ec.client.DposValidators(ctx.context, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &Addresses{rawValidators}, nil
+}
+
+// GetCandidatesAt returns the validator candidates at the given block.
+// The block number can be <0, in which case the latest known block is used.
+func (ec *kokereumClient) GetCandidatesAt(ctx *Context, number int64) (candidates *Addresses, _ error) {
+	var blockNumber *big.Int
+	if number >= 0 {
+		blockNumber = big.NewInt(number)
+	}
+	rawCandidates, err := ec.client.DposCandidates(ctx.context, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	candidates = &Addresses{make([]common.Address, len(rawCandidates))}
+	for i, candidate := range rawCandidates {
+		candidates.addresses[i] = candidate.Address
+	}
+	return candidates, nil
+}
+
+// GetDelegatorsAt returns the accounts delegating to the given candidate at the given block.
+// The block number can be <0, in which case the latest known block is used.
+func (ec *kokereumClient) GetDelegatorsAt(ctx *Context, candidate *Address, number int64) (delegators *Addresses, _ error) {
+	var blockNumber *big.Int
+	if number >= 0 {
+		blockNumber = big.NewInt(number)
+	}
+	votes, err := ec.client.DposVotes(ctx.context, candidate.address, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	delegators = &Addresses{make([]common.Address, len(votes.Received))}
+	for i, delegation := range votes.Received {
+		delegators.addresses[i] = delegation.Delegator
+	}
+	return delegators, nil
+}
+
+// GetVoteAt returns the candidate the given account delegates to at the given block,
+// the zero address if none. The block number can be <0, in which case the latest
+// known block is used.
+func (ec *kokereumClient) GetVoteAt(ctx *Context, delegator *Address, number int64) (candidate *Address, _ error) {
+	var blockNumber *big.Int
+	if number >= 0 {
+		blockNumber = big.NewInt(number)
+	}
+	votes, err := ec.client.DposVotes(ctx.context, delegator.address, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if votes.Vote == nil {
+		return &Address{}, nil
+	}
+	return &Address{votes.Vote.Candidate}, nil
+}
+
 // Filters
 
 // FilterLogs executes a filter query.
@@ -21,6 +21,7 @@ package gkok
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
 
@@ -32,6 +33,7 @@ import (
 	"github.com/kokprojects/go-kok/les"
 	"github.com/kokprojects/go-kok/node"
 	"github.com/kokprojects/go-kok/p2p"
+	"github.com/kokprojects/go-kok/p2p/discover"
 	"github.com/kokprojects/go-kok/p2p/nat"
 	"github.com/kokprojects/go-kok/params"
 	whisper "github.com/kokprojects/go-kok/whisper/whisperv5"
@@ -70,6 +72,21 @@ type NodeConfig struct {
 	// It has the form "nodename:secret@host:port"
 	kokereumNetStats string
 
+	// kokereumSyncMode is the synchronisation mode of the kokereum protocol, one
+	// of "light", "fast" or "full". An empty mode is equivalent to "light", the
+	// fast and full modes run a complete kokereum node instead of a light client.
+	kokereumSyncMode string
+
+	// kokereumMinimalSync makes the light client follow the chain head only through
+	// the signed announcements of the trusted servers, retrieving any other data
+	// (e.g. balances or transaction status) on demand. It suits wallets which are
+	// idle most of the time, minimizing their network and battery usage.
+	kokereumMinimalSync bool
+
+	// kokereumTrustedServers are the light servers the node exclusively connects
+	// to in minimal sync mode.
+	kokereumTrustedServers *Enodes
+
 	// WhisperEnabled specifies whkoker the node should run the Whisper protocol.
 	WhisperEnabled bool
 }
@@ -107,6 +124,18 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 	if config.BootstrapNodes == nil || config.BootstrapNodes.Size() == 0 {
 		config.BootstrapNodes = defaultNodeConfig.BootstrapNodes
 	}
+	syncMode := downloader.LightSync
+	if config.kokereumSyncMode != "" {
+		if err := syncMode.UnmarshalText([]byte(config.kokereumSyncMode)); err != nil {
+			return nil, err
+		}
+	}
+	if config.kokereumMinimalSync && syncMode != downloader.LightSync {
+		return nil, errors.New("minimal sync requires light sync mode")
+	}
+	if config.kokereumMinimalSync && (config.kokereumTrustedServers == nil || config.kokereumTrustedServers.Size() == 0) {
+		return nil, errors.New("minimal sync requires trusted servers")
+	}
 	// Create the empty networking stack
 	nodeConf := &node.Config{
 		Name:        clientIdentifier,
@@ -123,6 +152,17 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 			MaxPeers:         config.MaxPeers,
 		},
 	}
+	if config.kokereumMinimalSync {
+		// Stick to the trusted servers, neither discovering nor accepting others
+		servers := make([]*discover.Node, 0, config.kokereumTrustedServers.Size())
+		for _, n := range config.kokereumTrustedServers.nodes {
+			servers = append(servers, discover.NewNode(discover.NodeID(n.ID), n.IP, n.UDP, n.TCP))
+		}
+		nodeConf.P2P.DiscoveryV5 = false
+		nodeConf.P2P.ListenAddr = ""
+		nodeConf.P2P.StaticNodes = servers
+		nodeConf.P2P.TrustedNodes = servers
+	}
 	rawStack, err := node.New(nodeConf)
 	if err != nil {
 		return nil, err
@@ -140,21 +180,29 @@ func NewNode(datadir string, config *NodeConfig) (stack *Node, _ error) {
 	if config.kokereumEnabled {
 		kokConf := kok.DefaultConfig
 		kokConf.Genesis = genesis
-		kokConf.SyncMode = downloader.LightSync
+		kokConf.SyncMode = syncMode
 		kokConf.NetworkId = uint64(config.kokereumNetworkID)
 		kokConf.DatabaseCache = config.kokereumDatabaseCache
+		kokConf.LightMinimal = config.kokereumMinimalSync
 		if err := rawStack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-			return les.New(ctx, &kokConf)
+			if syncMode == downloader.LightSync {
+				return les.New(ctx, &kokConf)
+			}
+			return kok.New(ctx, &kokConf)
 		}); err != nil {
 			return nil, fmt.Errorf("kokereum init: %v", err)
 		}
 		// If netstats reporting is requested, do it
 		if config.kokereumNetStats != "" {
 			if err := rawStack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+				// Retrieve whichever of the kok or les services is running
+				var kokServ *kok.kokereum
+				ctx.Service(&kokServ)
+
 				var lesServ *les.Lightkokereum
 				ctx.Service(&lesServ)
 
-				return kokstats.New(config.kokereumNetStats, nil, lesServ)
+				return kokstats.New(kokstats.ParseTargets(config.kokereumNetStats), kokServ, lesServ)
 			}); err != nil {
 				return nil, fmt.Errorf("netstats init: %v", err)
 			}
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/kokprojects/go-kok/common"
 	"github.com/kokprojects/go-kok/core/types"
@@ -216,6 +217,18 @@ func NewTransaction(nonce int64, to *Address, amount, gasLimit, gasPrice *BigInt
 	return &Transaction{types.NewTransaction(types.Binary, uint64(nonce), to.address, amount.bigint, gasLimit.bigint, gasPrice.bigint, common.CopyBytes(data))}
 }
 
+// NewDelegateTransaction creates a new transaction delegating the votes of the
+// sender to the given validator candidate, replacing any earlier delegation.
+func NewDelegateTransaction(nonce int64, candidate *Address, gasLimit, gasPrice *BigInt) *Transaction {
+	return &Transaction{types.NewTransaction(types.Delegate, uint64(nonce), candidate.address, new(big.Int), gasLimit.bigint, gasPrice.bigint, nil)}
+}
+
+// NewUnDelegateTransaction creates a new transaction withdrawing the votes of the
+// sender from the given validator candidate.
+func NewUnDelegateTransaction(nonce int64, candidate *Address, gasLimit, gasPrice *BigInt) *Transaction {
+	return &Transaction{types.NewTransaction(types.UnDelegate, uint64(nonce), candidate.address, new(big.Int), gasLimit.bigint, gasPrice.bigint, nil)}
+}
+
 // NewTransactionFromRLP parses a transaction from an RLP data dump.
 func NewTransactionFromRLP(data []byte) (*Transaction, error) {
 	tx := &Transaction{
@@ -260,6 +273,7 @@ func (tx *Transaction) GetGas() int64        { return tx.tx.Gas().Int64() }
 func (tx *Transaction) GetGasPrice() *BigInt { return &BigInt{tx.tx.GasPrice()} }
 func (tx *Transaction) GetValue() *BigInt    { return &BigInt{tx.tx.Value()} }
 func (tx *Transaction) GetNonce() int64      { return int64(tx.tx.Nonce()) }
+func (tx *Transaction) GetType() string      { return tx.tx.Type().String() }
 
 func (tx *Transaction) Gkokash() *Hash   { return &Hash{tx.tx.Hash()} }
 func (tx *Transaction) GetCost() *BigInt { return &BigInt{tx.tx.Cost()} }
@@ -24,6 +24,7 @@ import (
 
 	"github.com/kokprojects/go-kok/common/hexutil"
 	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/internal/version"
 	"github.com/kokprojects/go-kok/p2p"
 	"github.com/kokprojects/go-kok/p2p/discover"
 	"github.com/kokprojects/go-kok/rpc"
@@ -75,6 +76,117 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// AddTrustedPeer allows a remote node to always connect, even if slots are full.
+func (api *PrivateAdminAPI) AddTrustedPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.AddTrustedPeer(node)
+	return true, nil
+}
+
+// RemoveTrustedPeer removes a remote node from the trusted peer set, but it
+// does not disconnect it automatically.
+func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := discover.ParseNode(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	server.RemoveTrustedPeer(node)
+	return true, nil
+}
+
+// PeerErrors retrieves the recorded connection failures of the remote node
+// identified by an enode URL or a hex node ID, or of all nodes if none is given.
+func (api *PrivateAdminAPI) PeerErrors(id *string) ([]*p2p.PeerError, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	if id == nil || *id == "" {
+		return server.PeerErrors(nil), nil
+	}
+	nodeID, err := parseNodeID(*id)
+	if err != nil {
+		return nil, err
+	}
+	return server.PeerErrors(&nodeID), nil
+}
+
+// BanPeer disconnects a remote node identified by an enode URL or a hex node
+// ID and refuses any further connection to or from it. The ban lasts for the
+// given number of seconds, or forever if none is given.
+func (api *PrivateAdminAPI) BanPeer(id string, reason *string, seconds *uint64) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	nodeID, err := parseNodeID(id)
+	if err != nil {
+		return false, err
+	}
+	why := "banned by operator"
+	if reason != nil && *reason != "" {
+		why = *reason
+	}
+	var duration time.Duration
+	if seconds != nil {
+		duration = time.Duration(*seconds) * time.Second
+	}
+	if err := server.BanPeer(nodeID, why, duration); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnbanPeer lifts the ban of a remote node, reporting whether it was banned.
+func (api *PrivateAdminAPI) UnbanPeer(id string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	nodeID, err := parseNodeID(id)
+	if err != nil {
+		return false, err
+	}
+	return server.UnbanPeer(nodeID)
+}
+
+// BannedPeers retrieves the bans currently in effect.
+func (api *PrivateAdminAPI) BannedPeers() ([]*p2p.PeerBan, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.BannedPeers(), nil
+}
+
+// parseNodeID extracts the node identifier from an enode URL or a hex node ID.
+func parseNodeID(id string) (discover.NodeID, error) {
+	if node, err := discover.ParseNode(id); err == nil {
+		return node.ID, nil
+	}
+	nodeID, err := discover.HexID(id)
+	if err != nil {
+		return discover.NodeID{}, fmt.Errorf("invalid node id: %v", err)
+	}
+	return nodeID, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -114,7 +226,7 @@ func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription,
 }
 
 // StartRPC starts the HTTP RPC API server.
-func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis *string) (bool, error) {
+func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis *string, certFile *string, keyFile *string) (bool, error) {
 	api.node.lock.Lock()
 	defer api.node.lock.Unlock()
 
@@ -149,7 +261,14 @@ func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 		}
 	}
 
-	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins); err != nil {
+	cert, key := api.node.config.RPCCertFile, api.node.config.RPCKeyFile
+	if certFile != nil {
+		cert = *certFile
+	}
+	if keyFile != nil {
+		key = *keyFile
+	}
+	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, cert, key); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -168,7 +287,7 @@ func (api *PrivateAdminAPI) StopRPC() (bool, error) {
 }
 
 // StartWS starts the websocket RPC API server.
-func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *string, apis *string) (bool, error) {
+func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *string, apis *string, certFile *string, keyFile *string) (bool, error) {
 	api.node.lock.Lock()
 	defer api.node.lock.Unlock()
 
@@ -203,7 +322,14 @@ func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 		}
 	}
 
-	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll); err != nil {
+	cert, key := api.node.config.RPCCertFile, api.node.config.RPCKeyFile
+	if certFile != nil {
+		cert = *certFile
+	}
+	if keyFile != nil {
+		key = *keyFile
+	}
+	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll, cert, key); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -221,6 +347,24 @@ func (api *PrivateAdminAPI) StopWS() (bool, error) {
 	return true, nil
 }
 
+// StartService restarts a stopped auxiliary service, referenced by its name
+// (e.g. kokstats) or type.
+func (api *PrivateAdminAPI) StartService(name string) (bool, error) {
+	if err := api.node.StartService(name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// StopService terminates a running auxiliary service, referenced by its name
+// (e.g. kokstats) or type.
+func (api *PrivateAdminAPI) StopService(name string) (bool, error) {
+	if err := api.node.StopService(name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // PublicAdminAPI is the collection of administrative API mkokods exposed over
 // both secure and unsecure RPC channels.
 type PublicAdminAPI struct {
@@ -243,14 +387,27 @@ func (api *PublicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// NodeInfo is the host metadata reported by admin_nodeInfo: the p2p server
+// information extended with the build metadata of the running executable.
+type NodeInfo struct {
+	*p2p.NodeInfo
+	Build version.Info `json:"build"`
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
-func (api *PublicAdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
+func (api *PublicAdminAPI) NodeInfo() (*NodeInfo, error) {
 	server := api.node.Server()
 	if server == nil {
 		return nil, ErrNodeStopped
 	}
-	return server.NodeInfo(), nil
+	return &NodeInfo{NodeInfo: server.NodeInfo(), Build: version.Get()}, nil
+}
+
+// Services retrieves the descriptions of all the services registered into the
+// node, along with their running state and the protocols and APIs they provide.
+func (api *PublicAdminAPI) Services() ([]*ServiceInfo, error) {
+	return api.node.Services()
 }
 
 // Datadir retrieves the current data directory the node is using.
@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/kokprojects/go-kok/accounts"
 	"github.com/kokprojects/go-kok/accounts/keystore"
@@ -33,6 +34,7 @@ import (
 	"github.com/kokprojects/go-kok/log"
 	"github.com/kokprojects/go-kok/p2p"
 	"github.com/kokprojects/go-kok/p2p/discover"
+	"github.com/kokprojects/go-kok/rpc"
 )
 
 const (
@@ -41,6 +43,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirPeerErrors      = "peererrors"         // Path within the datadir to store the peer connection failures and bans
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -135,6 +138,22 @@ type Config struct {
 	// *WARNING* Only set this if the node is running in a trusted network, exposing
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
+
+	// RPCCertFile and RPCKeyFile are the paths of the PEM encoded certificate and
+	// private key with which the HTTP and websocket RPC servers serve TLS. If both
+	// are empty, the endpoints are served in plain text.
+	RPCCertFile string `toml:",omitempty"`
+	RPCKeyFile  string `toml:",omitempty"`
+
+	// RPCBudgets is the request cost allowance granted to every HTTP and websocket
+	// client, keyed by API namespace. Expensive mkokods charge extra cost units in
+	// proportion to the work they do; namespaces without a budget are unlimited.
+	RPCBudgets map[string]rpc.CostBudget `toml:",omitempty"`
+
+	// RPCTimeouts is the execution deadline of the requests served over IPC, HTTP
+	// and websocket, keyed by API namespace. Namespaces without a timeout are
+	// unlimited.
+	RPCTimeouts map[string]time.Duration `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -170,6 +189,14 @@ func (c *Config) NodeDB() string {
 	return c.resolvePath(datadirNodeDatabase)
 }
 
+// PeerErrorDB returns the path to the peer connection failure and ban database.
+func (c *Config) PeerErrorDB() string {
+	if c.DataDir == "" {
+		return "" // ephemeral
+	}
+	return c.resolvePath(datadirPeerErrors)
+}
+
 // DefaultIPCEndpoint returns the IPC path used by default.
 func DefaultIPCEndpoint(clientIdentifier string) string {
 	if clientIdentifier == "" {
@@ -21,6 +21,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/kokprojects/go-kok/p2p"
 	"github.com/kokprojects/go-kok/p2p/nat"
@@ -45,10 +46,13 @@ var DefaultConfig = Config{
 	P2P: p2p.Config{
 		//ListenAddr:      ":62000",
 		//DiscoveryV5Addr: ":62001",
-		ListenAddr:      ":30303",
-		DiscoveryV5Addr: ":30304",
-		MaxPeers:        25,
-		NAT:             nat.Any(),
+		ListenAddr:        ":30303",
+		DiscoveryV5Addr:   ":30304",
+		MaxPeers:          25,
+		MaxPeersPerIP:     2,
+		MaxPeersPerSubnet: 5,
+		InboundThrottle:   10 * time.Second,
+		NAT:               nat.Any(),
 	},
 }
 
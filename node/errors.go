@@ -29,6 +29,10 @@ var (
 	ErrNodeRunning    = errors.New("node already running")
 	ErrServiceUnknown = errors.New("unknown service")
 
+	ErrServiceNotAuxiliary = errors.New("service is not auxiliary")
+	ErrServiceRunning      = errors.New("service already running")
+	ErrServiceNotRunning   = errors.New("service not running")
+
 	datadirInUseErrnos = map[uint]bool{11: true, 32: true, 35: true}
 )
 
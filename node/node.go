@@ -17,6 +17,7 @@
 package node
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -51,6 +52,10 @@ type Node struct {
 	serviceFuncs []ServiceConstructor     // Service constructors (in dependency order)
 	services     map[reflect.Type]Service // Currently running services
 
+	serviceKinds []reflect.Type                      // Types of the constructed services (in dependency order)
+	serviceCons  map[reflect.Type]ServiceConstructor // Constructors of the services, for restarting auxiliaries
+	stopped      map[reflect.Type]Service            // Auxiliary services stopped while the node is running
+
 	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
@@ -155,11 +160,18 @@ func (n *Node) Start() error {
 	if n.serverConfig.NodeDatabase == "" {
 		n.serverConfig.NodeDatabase = n.config.NodeDB()
 	}
+	if n.serverConfig.PeerErrorDatabase == "" {
+		n.serverConfig.PeerErrorDatabase = n.config.PeerErrorDB()
+	}
 	running := &p2p.Server{Config: n.serverConfig}
 	log.Info("Starting peer-to-peer node", "instance", n.serverConfig.Name)
 
 	// Otherwise copy and specialize the P2P configuration
-	services := make(map[reflect.Type]Service)
+	var (
+		services = make(map[reflect.Type]Service)
+		kinds    []reflect.Type
+		cons     = make(map[reflect.Type]ServiceConstructor)
+	)
 	for _, constructor := range n.serviceFuncs {
 		// Create a new context for the particular service
 		ctx := &ServiceContext{
@@ -181,6 +193,8 @@ func (n *Node) Start() error {
 			return &DuplicateServiceError{Kind: kind}
 		}
 		services[kind] = service
+		kinds = append(kinds, kind)
+		cons[kind] = constructor
 	}
 	// Gather the protocols and start the freshly assembled P2P server
 	for _, service := range services {
@@ -214,9 +228,16 @@ func (n *Node) Start() error {
 	}
 	// Finish initializing the startup
 	n.services = services
+	n.serviceKinds = kinds
+	n.serviceCons = cons
+	n.stopped = make(map[reflect.Type]Service)
 	n.server = running
 	n.stop = make(chan struct{})
 
+	// Reload the static node list of the data directory when it's modified
+	if n.config.P2P.StaticNodes == nil && n.config.DataDir != "" {
+		go watchStaticNodes(n.config, running, n.serverConfig.StaticNodes, n.stop)
+	}
 	return nil
 }
 
@@ -256,12 +277,12 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
-	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors); err != nil {
+	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.RPCCertFile, n.config.RPCKeyFile); err != nil {
 		n.stopIPC()
 		n.stopInProc()
 		return err
 	}
-	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
+	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll, n.config.RPCCertFile, n.config.RPCKeyFile); err != nil {
 		n.stopHTTP()
 		n.stopIPC()
 		n.stopInProc()
@@ -302,6 +323,9 @@ func (n *Node) startIPC(apis []rpc.API) error {
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	for namespace, timeout := range n.config.RPCTimeouts {
+		handler.SetTimeout(namespace, timeout)
+	}
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return err
@@ -358,7 +382,7 @@ func (n *Node) stopIPC() {
 }
 
 // startHTTP initializes and starts the HTTP RPC endpoint.
-func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string) error {
+func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, certFile, keyFile string) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
@@ -370,6 +394,12 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	for namespace, budget := range n.config.RPCBudgets {
+		handler.SetCostBudget(namespace, budget)
+	}
+	for namespace, timeout := range n.config.RPCTimeouts {
+		handler.SetTimeout(namespace, timeout)
+	}
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -379,15 +409,16 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 		}
 	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, secure, err := listenRPC(endpoint, certFile, keyFile)
+	if err != nil {
 		return err
 	}
 	go rpc.NewHTTPServer(cors, handler).Serve(listener)
-	log.Info(fmt.Sprintf("HTTP endpoint opened: http://%s", endpoint))
+	if secure {
+		log.Info(fmt.Sprintf("HTTP endpoint opened: https://%s", endpoint))
+	} else {
+		log.Info(fmt.Sprintf("HTTP endpoint opened: http://%s", endpoint))
+	}
 
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
@@ -397,6 +428,31 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	return nil
 }
 
+// listenRPC opens a TCP listener for an HTTP or websocket RPC endpoint, serving
+// TLS with the given certificate and key files if they are set. It reports
+// whkoker the listener is secured.
+func listenRPC(endpoint string, certFile, keyFile string) (net.Listener, bool, error) {
+	var config *tls.Config
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, false, errors.New("both a TLS certificate and key are required")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to load TLS key pair: %v", err)
+		}
+		config = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, false, err
+	}
+	if config != nil {
+		return tls.NewListener(listener, config), true, nil
+	}
+	return listener, false, nil
+}
+
 // stopHTTP terminates the HTTP RPC endpoint.
 func (n *Node) stopHTTP() {
 	if n.httpListener != nil {
@@ -412,7 +468,7 @@ func (n *Node) stopHTTP() {
 }
 
 // startWS initializes and starts the websocket RPC endpoint.
-func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool) error {
+func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool, certFile, keyFile string) error {
 	// Short circuit if the WS endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
@@ -424,6 +480,12 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	}
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
+	for namespace, budget := range n.config.RPCBudgets {
+		handler.SetCostBudget(namespace, budget)
+	}
+	for namespace, timeout := range n.config.RPCTimeouts {
+		handler.SetTimeout(namespace, timeout)
+	}
 	for _, api := range apis {
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -433,15 +495,16 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 		}
 	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, secure, err := listenRPC(endpoint, certFile, keyFile)
+	if err != nil {
 		return err
 	}
 	go rpc.NewWSServer(wsOrigins, handler).Serve(listener)
-	log.Info(fmt.Sprintf("WebSocket endpoint opened: ws://%s", listener.Addr()))
+	if secure {
+		log.Info(fmt.Sprintf("WebSocket endpoint opened: wss://%s", listener.Addr()))
+	} else {
+		log.Info(fmt.Sprintf("WebSocket endpoint opened: ws://%s", listener.Addr()))
+	}
 
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
@@ -491,6 +554,9 @@ func (n *Node) Stop() error {
 	}
 	n.server.Stop()
 	n.services = nil
+	n.serviceKinds = nil
+	n.serviceCons = nil
+	n.stopped = nil
 	n.server = nil
 
 	// Release instance directory lock.
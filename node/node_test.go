@@ -17,10 +17,21 @@
 package node
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"io/ioutil"
+	"math/big"
+	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -232,6 +243,79 @@ func TestServiceRestarts(t *testing.T) {
 	}
 }
 
+// auxiliaryService is an instrumented service that can be stopped and restarted
+// while the node is running.
+type auxiliaryService struct{ InstrumentedService }
+
+func (s *auxiliaryService) Auxiliary() {}
+
+// Tests that auxiliary services can be stopped and restarted independently of
+// the node, whilst other services can not.
+func TestAuxiliaryServiceRestarts(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	started, stopped := 0, 0
+	constructor := func(*ServiceContext) (Service, error) {
+		service := new(auxiliaryService)
+		service.startHook = func(*p2p.Server) { started++ }
+		service.stopHook = func() { stopped++ }
+		return service, nil
+	}
+	if err := stack.Register(NewNoopServiceA); err != nil {
+		t.Fatalf("failed to register the noop service: %v", err)
+	}
+	if err := stack.Register(constructor); err != nil {
+		t.Fatalf("failed to register the auxiliary service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	// Check the service listing and that only auxiliary services can be controlled
+	infos, err := stack.Services()
+	if err != nil {
+		t.Fatalf("failed to list services: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("service count mismatch: have %d, want 2", len(infos))
+	}
+	if infos[0].Auxiliary || !infos[1].Auxiliary || !infos[1].Running || infos[1].Name != "node" {
+		t.Fatalf("auxiliary service info mismatch: %+v", infos[1])
+	}
+	if err := stack.StopService(infos[0].Type); err != ErrServiceNotAuxiliary {
+		t.Fatalf("non-auxiliary stop error mismatch: have %v, want %v", err, ErrServiceNotAuxiliary)
+	}
+	if err := stack.StopService("unknown"); err != ErrServiceUnknown {
+		t.Fatalf("unknown service stop error mismatch: have %v, want %v", err, ErrServiceUnknown)
+	}
+	// Stop and restart the auxiliary service, checking it's recreated
+	if err := stack.StopService(infos[1].Type); err != nil {
+		t.Fatalf("failed to stop auxiliary service: %v", err)
+	}
+	if err := stack.StopService(infos[1].Type); err != ErrServiceNotRunning {
+		t.Fatalf("stopped service stop error mismatch: have %v, want %v", err, ErrServiceNotRunning)
+	}
+	var service *auxiliaryService
+	if err := stack.Service(&service); err != ErrServiceUnknown {
+		t.Fatalf("stopped service retrieval error mismatch: have %v, want %v", err, ErrServiceUnknown)
+	}
+	if infos, _ := stack.Services(); infos[1].Running {
+		t.Fatalf("stopped service reported running")
+	}
+	if err := stack.StartService(infos[1].Type); err != nil {
+		t.Fatalf("failed to restart auxiliary service: %v", err)
+	}
+	if err := stack.StartService(infos[1].Type); err != ErrServiceRunning {
+		t.Fatalf("running service start error mismatch: have %v, want %v", err, ErrServiceRunning)
+	}
+	if started != 2 || stopped != 1 {
+		t.Fatalf("started/stopped mismatch: have %d/%d, want 2/1", started, stopped)
+	}
+}
+
 // Tests that if a service fails to initialize itself, none of the other services
 // will be allowed to even start.
 func TestServiceConstructionAbortion(t *testing.T) {
@@ -573,3 +657,89 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// writeTestCertificate creates a self signed certificate for localhost in the
+// given directory, returning the paths of the certificate and key files.
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// Tests that the HTTP RPC endpoint serves TLS if a certificate is configured.
+func TestHTTPTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeTestCertificate(t, dir)
+
+	// A certificate without a key must be rejected
+	config := testNodeConfig()
+	config.HTTPHost, config.RPCCertFile = "127.0.0.1", certFile
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err == nil {
+		stack.Stop()
+		t.Fatalf("node started with an incomplete TLS key pair")
+	}
+	// A complete key pair must serve RPC requests over TLS only
+	config = testNodeConfig()
+	config.HTTPHost, config.RPCCertFile, config.RPCKeyFile = "127.0.0.1", certFile, keyFile
+	if stack, err = New(config); err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	addr := stack.httpListener.Addr().String()
+	request := `{"jsonrpc":"2.0","id":1,"method":"rpc_modules"}`
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Post("https://"+addr, "application/json", strings.NewReader(request))
+	if err != nil {
+		t.Fatalf("failed to send request over TLS: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `"result"`) {
+		t.Fatalf("unexpected response over TLS: %s", body)
+	}
+	if resp, err := http.Post("http://"+addr, "application/json", strings.NewReader(request)); err == nil {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if strings.Contains(string(body), `"result"`) {
+			t.Fatalf("request served in plain text")
+		}
+	}
+}
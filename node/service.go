@@ -96,3 +96,14 @@ type Service interface {
 	// are all terminated.
 	Stop() error
 }
+
+// AuxiliaryService is an optional interface for services no other part of the
+// stack depends on, which may thus be stopped and restarted while the node is
+// running. Auxiliary services must not provide P2P protocols or RPC APIs, as
+// those are bound when the node starts.
+type AuxiliaryService interface {
+	Service
+
+	// Auxiliary marks the service as an auxiliary one.
+	Auxiliary()
+}
@@ -0,0 +1,162 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+
+	"github.com/kokprojects/go-kok/log"
+)
+
+// ServiceInfo is the description of a service registered into the node.
+type ServiceInfo struct {
+	Name      string   `json:"name"`      // Name of the package implementing the service
+	Type      string   `json:"type"`      // Go type of the service
+	Running   bool     `json:"running"`   // Whether the service is currently running
+	Auxiliary bool     `json:"auxiliary"` // Whether the service can be stopped and restarted
+	Protocols []string `json:"protocols"` // P2P protocols provided, as name/version
+	APIs      []string `json:"apis"`      // RPC namespaces provided, as namespace/version
+}
+
+// serviceName returns the name a service is referred to by, being the name of
+// the package it is implemented in.
+func serviceName(kind reflect.Type) string {
+	if kind.Kind() == reflect.Ptr {
+		kind = kind.Elem()
+	}
+	return path.Base(kind.PkgPath())
+}
+
+// Services retrieves the descriptions of all the services registered into the
+// running node, in the order they were constructed.
+func (n *Node) Services() ([]*ServiceInfo, error) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.server == nil {
+		return nil, ErrNodeStopped
+	}
+	infos := make([]*ServiceInfo, 0, len(n.serviceKinds))
+	for _, kind := range n.serviceKinds {
+		service, running := n.services[kind]
+		if !running {
+			service = n.stopped[kind]
+		}
+		_, auxiliary := service.(AuxiliaryService)
+
+		info := &ServiceInfo{
+			Name:      serviceName(kind),
+			Type:      kind.String(),
+			Running:   running,
+			Auxiliary: auxiliary,
+			Protocols: []string{},
+			APIs:      []string{},
+		}
+		for _, proto := range service.Protocols() {
+			info.Protocols = append(info.Protocols, fmt.Sprintf("%s/%d", proto.Name, proto.Version))
+		}
+		for _, api := range service.APIs() {
+			info.APIs = append(info.APIs, fmt.Sprintf("%s/%s", api.Namespace, api.Version))
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// auxiliaryKind resolves the type of an auxiliary service by its name or type.
+//
+// Note, this mkokod assumes the node lock is held!
+func (n *Node) auxiliaryKind(name string) (reflect.Type, error) {
+	if n.server == nil {
+		return nil, ErrNodeStopped
+	}
+	for _, kind := range n.serviceKinds {
+		if serviceName(kind) != name && kind.String() != name {
+			continue
+		}
+		service, ok := n.services[kind]
+		if !ok {
+			service = n.stopped[kind]
+		}
+		if _, ok := service.(AuxiliaryService); !ok {
+			return nil, ErrServiceNotAuxiliary
+		}
+		return kind, nil
+	}
+	return nil, ErrServiceUnknown
+}
+
+// StopService terminates a running auxiliary service, referenced by its name or
+// type, without affecting the rest of the node.
+func (n *Node) StopService(name string) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	kind, err := n.auxiliaryKind(name)
+	if err != nil {
+		return err
+	}
+	service, ok := n.services[kind]
+	if !ok {
+		return ErrServiceNotRunning
+	}
+	delete(n.services, kind)
+	n.stopped[kind] = service
+
+	log.Info("Stopping auxiliary service", "service", kind)
+	return service.Stop()
+}
+
+// StartService restarts a previously stopped auxiliary service, referenced by its
+// name or type. As with node startup, a fresh instance of the service is created.
+func (n *Node) StartService(name string) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	kind, err := n.auxiliaryKind(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := n.services[kind]; ok {
+		return ErrServiceRunning
+	}
+	ctx := &ServiceContext{
+		config:         n.config,
+		services:       make(map[reflect.Type]Service),
+		EventMux:       n.eventmux,
+		AccountManager: n.accman,
+	}
+	for kind, s := range n.services {
+		ctx.services[kind] = s
+	}
+	service, err := n.serviceCons[kind](ctx)
+	if err != nil {
+		return err
+	}
+	if reflect.TypeOf(service) != kind {
+		return fmt.Errorf("service constructor returned %v, want %v", reflect.TypeOf(service), kind)
+	}
+	log.Info("Starting auxiliary service", "service", kind)
+	if err := service.Start(n.server); err != nil {
+		return err
+	}
+	delete(n.stopped, kind)
+	n.services[kind] = service
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"os"
+	"time"
+
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/p2p/discover"
+)
+
+// staticNodesRefreshInterval is the time between two checks of the static node
+// list for modifications.
+var staticNodesRefreshInterval = 5 * time.Second
+
+// staticPeerSet is the part of the p2p server maintaining the static peers.
+type staticPeerSet interface {
+	AddPeer(node *discover.Node)
+	RemovePeer(node *discover.Node)
+}
+
+// watchStaticNodes monitors the static node list of the data directory and
+// updates the static peers of the server when it is modified, so peering can
+// be changed without restarting the node. It returns when stop is closed.
+func watchStaticNodes(config *Config, server staticPeerSet, nodes []*discover.Node, stop <-chan struct{}) {
+	path := config.resolvePath(datadirStaticNodes)
+
+	current := make(map[discover.NodeID]*discover.Node, len(nodes))
+	for _, n := range nodes {
+		current[n.ID] = n
+	}
+	// The list is diffed on the first tick too, catching changes made while
+	// the node was starting up.
+	var modified time.Time
+
+	ticker := time.NewTicker(staticNodesRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			var mtime time.Time
+			if info, err := os.Stat(path); err == nil {
+				mtime = info.ModTime()
+			}
+			if mtime.Equal(modified) {
+				continue
+			}
+			modified = mtime
+
+			// The list changed, connect to the new nodes and drop the removed ones
+			updated := make(map[discover.NodeID]*discover.Node)
+			for _, n := range config.StaticNodes() {
+				updated[n.ID] = n
+			}
+			changed := false
+			for id, n := range current {
+				if _, ok := updated[id]; !ok {
+					server.RemovePeer(n)
+					changed = true
+				}
+			}
+			for id, n := range updated {
+				if _, ok := current[id]; !ok {
+					server.AddPeer(n)
+					changed = true
+				}
+			}
+			if changed {
+				log.Info("Reloaded static node list", "path", path, "nodes", len(updated))
+			}
+			current = updated
+
+		case <-stop:
+			return
+		}
+	}
+}
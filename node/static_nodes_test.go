@@ -0,0 +1,121 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/p2p/discover"
+)
+
+// testPeerSet records the static peers added and removed by the watcher.
+type testPeerSet struct {
+	lock    sync.Mutex
+	added   []discover.NodeID
+	removed []discover.NodeID
+}
+
+func (s *testPeerSet) AddPeer(node *discover.Node) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.added = append(s.added, node.ID)
+}
+
+func (s *testPeerSet) RemovePeer(node *discover.Node) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.removed = append(s.removed, node.ID)
+}
+
+// Tests that modifications of the static node list are applied to the peer set.
+func TestStaticNodesReload(t *testing.T) {
+	defer func(interval time.Duration) { staticNodesRefreshInterval = interval }(staticNodesRefreshInterval)
+	staticNodesRefreshInterval = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &Config{Name: "unit-test", DataDir: dir}
+	if err := os.MkdirAll(filepath.Join(dir, config.name()), 0700); err != nil {
+		t.Fatalf("failed to create instance dir: %v", err)
+	}
+	nodes := make([]*discover.Node, 3)
+	for i := range nodes {
+		key, _ := crypto.GenerateKey()
+		nodes[i] = discover.NewNode(discover.PubkeyID(&key.PublicKey), []byte{127, 0, 0, 1}, 0, uint16(30303+i))
+	}
+	path := config.resolvePath(datadirStaticNodes)
+	write := func(nodes []*discover.Node, mtime time.Time) {
+		urls := make([]string, len(nodes))
+		for i, n := range nodes {
+			urls[i] = n.String()
+		}
+		blob, _ := json.Marshal(urls)
+		if err := ioutil.WriteFile(path, blob, 0600); err != nil {
+			t.Fatalf("failed to write static nodes: %v", err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("failed to set modification time: %v", err)
+		}
+	}
+	now := time.Now()
+	write(nodes[:2], now)
+
+	var (
+		peers  = new(testPeerSet)
+		static = config.StaticNodes()
+		stop   = make(chan struct{})
+		done   = make(chan struct{})
+	)
+	go func() {
+		watchStaticNodes(config, peers, static, stop)
+		close(done)
+	}()
+	// Replace the first node with the third one and wait for the reload
+	write(nodes[1:], now.Add(time.Second))
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		peers.lock.Lock()
+		reloaded := len(peers.added) > 0
+		peers.lock.Unlock()
+		if reloaded {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("static node list not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(stop)
+	<-done
+
+	if len(peers.added) != 1 || peers.added[0] != nodes[2].ID {
+		t.Errorf("added peers mismatch: have %x, want %x", peers.added, nodes[2].ID)
+	}
+	if len(peers.removed) != 1 || peers.removed[0] != nodes[0].ID {
+		t.Errorf("removed peers mismatch: have %x, want %x", peers.removed, nodes[0].ID)
+	}
+}
@@ -72,6 +72,7 @@ type dialstate struct {
 	maxDynDials int
 	ntab        discoverTable
 	netrestrict *netutil.Netlist
+	banned      func(discover.NodeID) bool // reports nodes that must not be dialed
 
 	lookupRunning bool
 	dialing       map[discover.NodeID]connFlag
@@ -268,6 +269,8 @@ func (s *dialstate) checkDial(n *discover.Node, peers map[discover.NodeID]*Peer)
 		return errSelf
 	case s.netrestrict != nil && !s.netrestrict.Contains(n.IP):
 		return errNotWhitelisted
+	case s.banned != nil && s.banned(n.ID):
+		return errBanned
 	case s.hist.contains(n.ID):
 		return errRecentlyDialed
 	}
@@ -0,0 +1,125 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"time"
+
+	"github.com/kokprojects/go-kok/p2p/discover"
+	"github.com/kokprojects/go-kok/p2p/netutil"
+)
+
+// Prefix lengths of the subnets inbound connections are grouped by.
+const (
+	inboundSubnet4 = 24 // IPv4 connections are grouped by /24
+	inboundSubnet6 = 64 // IPv6 connections are grouped by /64
+)
+
+// inboundSubnet returns the subnet an inbound connection is grouped by.
+func inboundSubnet(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(inboundSubnet4, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(inboundSubnet6, 128)).String()
+}
+
+// connIP returns the remote IP address of a connection, or nil if it is not
+// an IP connection.
+func connIP(fd net.Conn) net.IP {
+	if fd == nil {
+		return nil
+	}
+	if tcp, ok := fd.RemoteAddr().(*net.TCPAddr); ok {
+		return tcp.IP
+	}
+	return nil
+}
+
+// inboundLimited reports whether admitting an inbound connection would exceed
+// the per-IP or per-subnet peer limits. Connections from the local network are
+// never limited.
+func (srv *Server) inboundLimited(peers map[discover.NodeID]*Peer, c *conn) bool {
+	if srv.MaxPeersPerIP <= 0 && srv.MaxPeersPerSubnet <= 0 {
+		return false
+	}
+	ip := connIP(c.fd)
+	if ip == nil || netutil.IsLAN(ip) {
+		return false
+	}
+	var (
+		subnet  = inboundSubnet(ip)
+		sameIP  int
+		sameNet int
+	)
+	for _, p := range peers {
+		if !p.rw.is(inboundConn) || p.rw.is(trustedConn) {
+			continue
+		}
+		pip := connIP(p.rw.fd)
+		if pip == nil {
+			continue
+		}
+		if pip.Equal(ip) {
+			sameIP++
+		}
+		if inboundSubnet(pip) == subnet {
+			sameNet++
+		}
+	}
+	if srv.MaxPeersPerIP > 0 && sameIP >= srv.MaxPeersPerIP {
+		return true
+	}
+	return srv.MaxPeersPerSubnet > 0 && sameNet >= srv.MaxPeersPerSubnet
+}
+
+// inboundThrottle tracks the recent inbound connection attempts to reject the
+// ones arriving from the same IP faster than the configured rate. It is only
+// accessed from the server's listen loop.
+type inboundThrottle struct {
+	interval time.Duration
+	seen     map[string]time.Time
+	swept    time.Time
+}
+
+func newInboundThrottle(interval time.Duration) *inboundThrottle {
+	return &inboundThrottle{interval: interval, seen: make(map[string]time.Time)}
+}
+
+// allow records a connection attempt from the given IP, reporting whether it
+// arrived at least the throttle interval after the previous one. Attempts from
+// the local network are always allowed.
+func (t *inboundThrottle) allow(ip net.IP, now time.Time) bool {
+	if t.interval <= 0 || ip == nil || netutil.IsLAN(ip) {
+		return true
+	}
+	// Periodically drop the attempts that fell out of the throttle interval
+	if now.Sub(t.swept) >= t.interval {
+		for key, last := range t.seen {
+			if now.Sub(last) >= t.interval {
+				delete(t.seen, key)
+			}
+		}
+		t.swept = now
+	}
+	key := ip.String()
+	if last, ok := t.seen[key]; ok && now.Sub(last) < t.interval {
+		return false
+	}
+	t.seen[key] = now
+	return true
+}
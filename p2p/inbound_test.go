@@ -0,0 +1,105 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kokprojects/go-kok/p2p/discover"
+)
+
+// remoteConn is a connection stub reporting a fixed remote address.
+type remoteConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c remoteConn) RemoteAddr() net.Addr { return c.remote }
+
+func inboundTestConn(ip string, flags connFlag) *conn {
+	addr := &net.TCPAddr{IP: net.ParseIP(ip), Port: 30303}
+	return &conn{fd: remoteConn{remote: addr}, flags: flags, id: randomID()}
+}
+
+func TestInboundLimits(t *testing.T) {
+	srv := &Server{Config: Config{MaxPeers: 50, MaxPeersPerIP: 2, MaxPeersPerSubnet: 3}}
+
+	peers := make(map[discover.NodeID]*Peer)
+	for _, c := range []*conn{
+		inboundTestConn("1.2.3.4", inboundConn),
+		inboundTestConn("1.2.3.4", inboundConn),
+		inboundTestConn("1.2.3.5", inboundConn),
+		inboundTestConn("1.2.4.4", dynDialedConn),
+		inboundTestConn("1.2.4.4", dynDialedConn),
+		inboundTestConn("1.2.4.4", inboundConn|trustedConn),
+		inboundTestConn("1.2.4.4", inboundConn|trustedConn),
+		inboundTestConn("192.168.0.1", inboundConn),
+		inboundTestConn("192.168.0.1", inboundConn),
+	} {
+		peers[c.id] = &Peer{rw: c}
+	}
+	tests := []struct {
+		ip    string
+		flags connFlag
+		want  error
+	}{
+		{"1.2.3.4", inboundConn, DiscTooManyPeers},               // per-IP limit reached
+		{"1.2.3.6", inboundConn, DiscTooManyPeers},               // per-subnet limit reached
+		{"1.2.3.4", inboundConn | trustedConn, nil},              // trusted peers are exempt
+		{"1.2.3.4", dynDialedConn, nil},                          // outbound peers are exempt
+		{"1.2.4.4", inboundConn, nil},                            // outbound and trusted peers are not counted
+		{"192.168.0.1", inboundConn, nil},                        // local network is exempt
+		{"2001:db8::1", inboundConn, nil},                        // different subnet
+		{"2001:db8::2", inboundConn | staticDialedConn, nil},     // different subnet
+		{"2001:db8:0:1::1", inboundConn | staticDialedConn, nil}, // different subnet
+	}
+	for i, tt := range tests {
+		if err := srv.encHandshakeChecks(peers, inboundTestConn(tt.ip, tt.flags)); err != tt.want {
+			t.Errorf("test %d (%s): admission error mismatch: have %v, want %v", i, tt.ip, err, tt.want)
+		}
+	}
+}
+
+func TestInboundThrottle(t *testing.T) {
+	var (
+		throttle = newInboundThrottle(10 * time.Second)
+		start    = time.Now()
+		remote   = net.ParseIP("1.2.3.4")
+		other    = net.ParseIP("1.2.3.5")
+		local    = net.ParseIP("127.0.0.1")
+	)
+	if !throttle.allow(remote, start) {
+		t.Fatalf("first attempt rejected")
+	}
+	if throttle.allow(remote, start.Add(5*time.Second)) {
+		t.Errorf("repeated attempt allowed within the throttle interval")
+	}
+	if !throttle.allow(other, start.Add(5*time.Second)) {
+		t.Errorf("attempt from a different address rejected")
+	}
+	if !throttle.allow(local, start) || !throttle.allow(local, start) {
+		t.Errorf("local network attempts throttled")
+	}
+	if !throttle.allow(remote, start.Add(11*time.Second)) {
+		t.Errorf("attempt after the throttle interval rejected")
+	}
+	if len(throttle.seen) != 2 {
+		t.Errorf("tracked address count mismatch: have %d, want %d", len(throttle.seen), 2)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"errors"
+
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/p2p/discover"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+// Maximum length of the individual metadata fields, to keep handshakes small.
+const maxMetadataField = 256
+
+var (
+	errMetadataTooLarge = errors.New("metadata field too large")
+	errMetadataSigner   = errors.New("metadata not signed by node")
+)
+
+// NodeMetadata is operator information a node attaches to its protocol handshake,
+// allowing consortium networks to identify peers operationally. It is signed
+// with the node key, so it can be attributed to the node regardless of who
+// relays it.
+type NodeMetadata struct {
+	Operator  string `json:"operator,omitempty"` // Name of the organisation running the node
+	Contact   string `json:"contact,omitempty"`  // Operational contact (e.g. e-mail address)
+	Region    string `json:"region,omitempty"`   // Geographic or hosting region of the node
+	Signature []byte `json:"signature" toml:"-"` // Signature of the fields above by the node key
+}
+
+// sigHash returns the hash signed by the node, covering all fields but the
+// signature itself.
+func (m *NodeMetadata) sigHash() []byte {
+	enc, _ := rlp.EncodeToBytes([]string{m.Operator, m.Contact, m.Region})
+	return crypto.Keccak256(enc)
+}
+
+// validate checks the metadata fields against the size limits.
+func (m *NodeMetadata) validate() error {
+	if len(m.Operator) > maxMetadataField || len(m.Contact) > maxMetadataField || len(m.Region) > maxMetadataField {
+		return errMetadataTooLarge
+	}
+	return nil
+}
+
+// sign returns a copy of the metadata signed with the node key.
+func (m *NodeMetadata) sign(prv *ecdsa.PrivateKey) (*NodeMetadata, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	signed := &NodeMetadata{Operator: m.Operator, Contact: m.Contact, Region: m.Region}
+	sig, err := crypto.Sign(signed.sigHash(), prv)
+	if err != nil {
+		return nil, err
+	}
+	signed.Signature = sig
+	return signed, nil
+}
+
+// verify checks that the metadata was signed by the node with the given id.
+func (m *NodeMetadata) verify(id discover.NodeID) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	pub, err := crypto.SigToPub(m.sigHash(), m.Signature)
+	if err != nil {
+		return err
+	}
+	if discover.PubkeyID(pub) != id {
+		return errMetadataSigner
+	}
+	return nil
+}
+
+// handshakeMetadata extracts the signed operator metadata from the protocol
+// handshake of a remote node, nil if it sent none or it's invalid. The metadata
+// is carried as the first of the extra handshake fields, which nodes not aware
+// of it ignore.
+func handshakeMetadata(hs *protoHandshake) (*NodeMetadata, error) {
+	if len(hs.Rest) == 0 {
+		return nil, nil
+	}
+	meta := new(NodeMetadata)
+	if err := rlp.DecodeBytes(hs.Rest[0], meta); err != nil {
+		return nil, err
+	}
+	if err := meta.verify(hs.ID); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
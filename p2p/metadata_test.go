@@ -0,0 +1,60 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+
+	"github.com/kokprojects/go-kok/crypto"
+	"github.com/kokprojects/go-kok/p2p/discover"
+	"github.com/kokprojects/go-kok/rlp"
+)
+
+func TestHandshakeMetadata(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	meta, err := (&NodeMetadata{Operator: "acme", Contact: "ops@acme.test", Region: "eu-west"}).sign(key)
+	if err != nil {
+		t.Fatalf("failed to sign metadata: %v", err)
+	}
+	enc, _ := rlp.EncodeToBytes(meta)
+
+	// Metadata signed by the handshaking node must be accepted
+	hs := &protoHandshake{ID: discover.PubkeyID(&key.PublicKey), Rest: []rlp.RawValue{enc}}
+	got, err := handshakeMetadata(hs)
+	if err != nil {
+		t.Fatalf("valid metadata rejected: %v", err)
+	}
+	if got.Operator != meta.Operator || got.Contact != meta.Contact || got.Region != meta.Region {
+		t.Errorf("metadata mismatch: have %+v, want %+v", got, meta)
+	}
+	// Metadata relayed by a different node must be rejected
+	hs.ID = discover.PubkeyID(&other.PublicKey)
+	if _, err := handshakeMetadata(hs); err != errMetadataSigner {
+		t.Errorf("foreign metadata error mismatch: have %v, want %v", err, errMetadataSigner)
+	}
+	// Handshakes without metadata carry none
+	if got, err := handshakeMetadata(&protoHandshake{ID: hs.ID}); got != nil || err != nil {
+		t.Errorf("empty handshake metadata mismatch: have %v/%v, want nil/nil", got, err)
+	}
+	// Oversized fields must be refused at signing time
+	large := &NodeMetadata{Operator: string(make([]byte, maxMetadataField+1))}
+	if _, err := large.sign(key); err != errMetadataTooLarge {
+		t.Errorf("oversized metadata error mismatch: have %v, want %v", err, errMetadataTooLarge)
+	}
+}
@@ -130,6 +130,12 @@ func (p *Peer) Name() string {
 	return p.rw.name
 }
 
+// Metadata returns the signed operator metadata of the remote node, nil if it
+// didn't send any.
+func (p *Peer) Metadata() *NodeMetadata {
+	return p.rw.meta
+}
+
 // Caps returns the capabilities (supported subprotocols) of the remote peer.
 func (p *Peer) Caps() []Cap {
 	// TODO: maybe return copy
@@ -415,7 +421,8 @@ type PeerInfo struct {
 		LocalAddress  string `json:"localAddress"`  // Local endpoint of the TCP data connection
 		RemoteAddress string `json:"remoteAddress"` // Remote endpoint of the TCP data connection
 	} `json:"network"`
-	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Metadata  *NodeMetadata          `json:"metadata,omitempty"` // Operator metadata signed by the peer
+	Protocols map[string]interface{} `json:"protocols"`          // Sub-protocol specific metadata fields
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -430,6 +437,7 @@ func (p *Peer) Info() *PeerInfo {
 		ID:        p.ID().String(),
 		Name:      p.Name(),
 		Caps:      caps,
+		Metadata:  p.rw.meta,
 		Protocols: make(map[string]interface{}),
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
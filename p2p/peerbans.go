@@ -0,0 +1,272 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/p2p/discover"
+	"github.com/kokprojects/go-kok/rlp"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var (
+	violationWindow = time.Hour      // Period over which the protocol violations of a peer are counted
+	maxViolations   = 3              // Number of violations within the window after which a peer is banned
+	autoBanDuration = 24 * time.Hour // Duration of the bans imposed after repeated violations
+)
+
+var errBanned = errors.New("banned")
+
+// PeerBan is the record of a remote node being refused connectivity.
+type PeerBan struct {
+	ID      string     `json:"id"`      // Unique node identifier
+	Reason  string     `json:"reason"`  // Reason the node was banned for
+	Created time.Time  `json:"created"` // Time the ban was imposed
+	Expires *time.Time `json:"expires"` // Time the ban is lifted, nil if permanent
+}
+
+// expired returns whether the ban was already lifted at the given time.
+func (b *PeerBan) expired(now time.Time) bool {
+	return b.Expires != nil && !now.Before(*b.Expires)
+}
+
+// peerBanEntry is the persisted part of a ban record.
+type peerBanEntry struct {
+	Reason  string
+	Created uint64 // Unix time in nanoseconds
+	Expires uint64 // Unix time in nanoseconds, zero if permanent
+}
+
+// peerBanKey = peerBanPrefix + node id
+func peerBanKey(id discover.NodeID) []byte {
+	return append(append([]byte{}, peerBanPrefix...), id[:]...)
+}
+
+// storeBan persists a ban, overwriting any previous one of the same node.
+func (db *peerDB) storeBan(id discover.NodeID, ban *PeerBan) error {
+	entry := &peerBanEntry{Reason: ban.Reason, Created: uint64(ban.Created.UnixNano())}
+	if ban.Expires != nil {
+		entry.Expires = uint64(ban.Expires.UnixNano())
+	}
+	blob, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return err
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.lvl.Put(peerBanKey(id), blob, nil)
+}
+
+// deleteBan drops the ban of a node.
+func (db *peerDB) deleteBan(id discover.NodeID) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.lvl.Delete(peerBanKey(id), nil)
+}
+
+// loadBans retrieves all the persisted bans.
+func (db *peerDB) loadBans() map[discover.NodeID]*PeerBan {
+	it := db.lvl.NewIterator(util.BytesPrefix(peerBanPrefix), nil)
+	defer it.Release()
+
+	bans := make(map[discover.NodeID]*PeerBan)
+	for it.Next() {
+		key := it.Key()[len(peerBanPrefix):]
+		if len(key) != len(discover.NodeID{}) {
+			continue
+		}
+		entry := new(peerBanEntry)
+		if err := rlp.DecodeBytes(it.Value(), entry); err != nil {
+			continue
+		}
+		var id discover.NodeID
+		copy(id[:], key)
+
+		ban := &PeerBan{ID: id.String(), Reason: entry.Reason, Created: time.Unix(0, int64(entry.Created))}
+		if entry.Expires != 0 {
+			expires := time.Unix(0, int64(entry.Expires))
+			ban.Expires = &expires
+		}
+		bans[id] = ban
+	}
+	return bans
+}
+
+// expireBans drops the bans that were already lifted.
+func (db *peerDB) expireBans(now time.Time) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	it := db.lvl.NewIterator(util.BytesPrefix(peerBanPrefix), nil)
+	defer it.Release()
+
+	for it.Next() {
+		entry := new(peerBanEntry)
+		if err := rlp.DecodeBytes(it.Value(), entry); err != nil || (entry.Expires != 0 && entry.Expires <= uint64(now.UnixNano())) {
+			db.lvl.Delete(it.Key(), nil)
+		}
+	}
+}
+
+// BanPeer disconnects the given node and refuses any connection to or from it
+// until the ban expires. A zero duration bans the node permanently.
+func (srv *Server) BanPeer(id discover.NodeID, reason string, duration time.Duration) error {
+	if err := srv.ban(id, reason, duration); err != nil {
+		return err
+	}
+	select {
+	case srv.peerOp <- func(peers map[discover.NodeID]*Peer) {
+		if p := peers[id]; p != nil {
+			p.Disconnect(DiscUselessPeer)
+		}
+	}:
+		<-srv.peerOpDone
+	case <-srv.quit:
+	}
+	return nil
+}
+
+// UnbanPeer lifts the ban of the given node, reporting whether it was banned.
+func (srv *Server) UnbanPeer(id discover.NodeID) (bool, error) {
+	srv.banLock.Lock()
+	defer srv.banLock.Unlock()
+
+	if srv.bans == nil {
+		return false, errServerStopped
+	}
+	if _, ok := srv.bans[id]; !ok {
+		return false, nil
+	}
+	if err := srv.peerdb.deleteBan(id); err != nil {
+		return false, err
+	}
+	delete(srv.bans, id)
+	return true, nil
+}
+
+// BannedPeers retrieves the bans currently in effect, the most recent first.
+func (srv *Server) BannedPeers() []*PeerBan {
+	srv.banLock.RLock()
+	defer srv.banLock.RUnlock()
+
+	now := time.Now()
+	bans := []*PeerBan{}
+	for _, ban := range srv.bans {
+		if !ban.expired(now) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Created.After(bans[j].Created) })
+	return bans
+}
+
+// ban records and persists a ban of the given node.
+func (srv *Server) ban(id discover.NodeID, reason string, duration time.Duration) error {
+	ban := &PeerBan{ID: id.String(), Reason: reason, Created: time.Now()}
+	if duration > 0 {
+		expires := ban.Created.Add(duration)
+		ban.Expires = &expires
+	}
+	srv.banLock.Lock()
+	defer srv.banLock.Unlock()
+
+	if srv.bans == nil {
+		return errServerStopped
+	}
+	if err := srv.peerdb.storeBan(id, ban); err != nil {
+		return err
+	}
+	srv.bans[id] = ban
+	return nil
+}
+
+// isBanned reports whether connectivity to the given node is refused.
+func (srv *Server) isBanned(id discover.NodeID) bool {
+	srv.banLock.RLock()
+	defer srv.banLock.RUnlock()
+
+	ban, ok := srv.bans[id]
+	return ok && !ban.expired(time.Now())
+}
+
+// isViolation reports whether a peer was dropped locally because it breached
+// the protocol or served useless or invalid data.
+func isViolation(pd peerDrop) bool {
+	if pd.requested {
+		return false
+	}
+	switch err := pd.err.(type) {
+	case DiscReason:
+		return err == DiscUselessPeer || err == DiscProtocolError || err == DiscSubprotocolError
+	case *peerError:
+		return true
+	}
+	return false
+}
+
+// peerViolations tracks the recent protocol violations of the remote nodes. It
+// is only accessed from the server's run loop.
+type peerViolations map[discover.NodeID][]time.Time
+
+// add records a violation of the given node, returning the number of violations
+// within the tracking window.
+func (v peerViolations) add(id discover.NodeID, now time.Time) int {
+	recent := v[id][:0]
+	for _, t := range v[id] {
+		if now.Sub(t) < violationWindow {
+			recent = append(recent, t)
+		}
+	}
+	v[id] = append(recent, now)
+	return len(v[id])
+}
+
+// expire drops the violations that fell out of the tracking window.
+func (v peerViolations) expire(now time.Time) {
+	for id, times := range v {
+		if now.Sub(times[len(times)-1]) >= violationWindow {
+			delete(v, id)
+		}
+	}
+}
+
+// checkViolation counts a dropped peer's violation and bans it if it misbehaved
+// too many times recently. Trusted peers are never banned automatically.
+func (srv *Server) checkViolation(violations peerViolations, pd peerDrop) {
+	if !isViolation(pd) || pd.rw.is(trustedConn) {
+		return
+	}
+	now := time.Now()
+	violations.expire(now)
+	if violations.add(pd.ID(), now) < maxViolations {
+		return
+	}
+	delete(violations, pd.ID())
+
+	reason := "repeated protocol violations: " + pd.err.Error()
+	if err := srv.ban(pd.ID(), reason, autoBanDuration); err != nil {
+		pd.log.Warn("Failed to ban misbehaving peer", "err", err)
+		return
+	}
+	log.Info("Banned misbehaving peer", "id", pd.ID(), "duration", autoBanDuration, "reason", pd.err)
+}
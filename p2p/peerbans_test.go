@@ -0,0 +1,149 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/p2p/discover"
+)
+
+// Tests that bans are enforced, persisted across restarts and can be lifted.
+func TestServerBans(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peerbans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := func() *Server {
+		srv := &Server{Config: Config{
+			MaxPeers:          10,
+			PrivateKey:        newkey(),
+			NoDiscovery:       true,
+			PeerErrorDatabase: filepath.Join(dir, "peererrors"),
+		}}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("failed to start server: %v", err)
+		}
+		return srv
+	}
+	var (
+		permanent = randomID()
+		temporary = randomID()
+		expired   = randomID()
+	)
+	srv := start()
+	if err := srv.BanPeer(permanent, "spam", 0); err != nil {
+		t.Fatalf("failed to ban peer: %v", err)
+	}
+	if err := srv.BanPeer(temporary, "junk", time.Hour); err != nil {
+		t.Fatalf("failed to ban peer: %v", err)
+	}
+	if err := srv.BanPeer(expired, "junk", time.Nanosecond); err != nil {
+		t.Fatalf("failed to ban peer: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if err := srv.encHandshakeChecks(nil, &conn{id: permanent}); err != errBanned {
+		t.Errorf("banned peer admission error mismatch: have %v, want %v", err, errBanned)
+	}
+	if err := srv.encHandshakeChecks(nil, &conn{id: expired}); err != nil {
+		t.Errorf("expired ban still enforced: %v", err)
+	}
+	if bans := srv.BannedPeers(); len(bans) != 2 {
+		t.Fatalf("ban count mismatch: have %d, want %d", len(bans), 2)
+	}
+	srv.Stop()
+
+	// Restart the server and ensure the bans were persisted
+	srv = start()
+	defer srv.Stop()
+
+	bans := srv.BannedPeers()
+	if len(bans) != 2 {
+		t.Fatalf("persisted ban count mismatch: have %d, want %d", len(bans), 2)
+	}
+	for _, ban := range bans {
+		switch ban.ID {
+		case permanent.String():
+			if ban.Reason != "spam" || ban.Expires != nil {
+				t.Errorf("permanent ban mismatch: %+v", ban)
+			}
+		case temporary.String():
+			if ban.Reason != "junk" || ban.Expires == nil {
+				t.Errorf("temporary ban mismatch: %+v", ban)
+			}
+		default:
+			t.Errorf("unexpected ban: %+v", ban)
+		}
+	}
+	if lifted, err := srv.UnbanPeer(permanent); !lifted || err != nil {
+		t.Fatalf("failed to lift ban: %v, %v", lifted, err)
+	}
+	if lifted, err := srv.UnbanPeer(permanent); lifted || err != nil {
+		t.Fatalf("lifted missing ban: %v, %v", lifted, err)
+	}
+	if srv.isBanned(permanent) {
+		t.Errorf("lifted ban still enforced")
+	}
+}
+
+// Tests that peers repeatedly dropped for misbehaving are banned automatically.
+func TestServerAutoBan(t *testing.T) {
+	srv := &Server{Config: Config{MaxPeers: 10, PrivateKey: newkey(), NoDiscovery: true}}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer srv.Stop()
+
+	drop := func(id discover.NodeID, flags connFlag, err error, requested bool) peerDrop {
+		return peerDrop{&Peer{rw: &conn{id: id, flags: flags}, log: log.New()}, err, requested}
+	}
+	var (
+		violations = make(peerViolations)
+		abusive    = randomID()
+		remote     = randomID()
+		trusted    = randomID()
+	)
+	for i := 0; i < maxViolations; i++ {
+		if srv.isBanned(abusive) {
+			t.Fatalf("peer banned after %d violations", i)
+		}
+		srv.checkViolation(violations, drop(abusive, dynDialedConn, DiscUselessPeer, false))
+		srv.checkViolation(violations, drop(remote, inboundConn, DiscUselessPeer, true))
+		srv.checkViolation(violations, drop(trusted, trustedConn, DiscProtocolError, false))
+	}
+	if !srv.isBanned(abusive) {
+		t.Errorf("misbehaving peer not banned")
+	}
+	if srv.isBanned(remote) {
+		t.Errorf("peer banned for remote disconnects")
+	}
+	if srv.isBanned(trusted) {
+		t.Errorf("trusted peer banned")
+	}
+	bans := srv.BannedPeers()
+	if len(bans) != 1 || bans[0].Expires == nil || bans[0].Expires.Sub(bans[0].Created) != autoBanDuration {
+		t.Errorf("automatic ban mismatch: %+v", bans)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kokprojects/go-kok/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+// Key prefixes of the records kept in the peer database.
+var (
+	peerErrorPrefix = []byte("e") // peerErrorPrefix + node id + stage + 0x00 + reason -> peerErrorEntry
+	peerBanPrefix   = []byte("b") // peerBanPrefix + node id -> peerBanEntry
+)
+
+// peerDBVersionKey holds the version of the database layout. The databases of the
+// first version, lacking the key, only held connection failures with unprefixed
+// keys.
+var peerDBVersionKey = []byte("version")
+
+const peerDBVersion = 1
+
+// peerDB stores the local observations about remote nodes, namely their
+// connection failures and bans.
+type peerDB struct {
+	lvl  *leveldb.DB
+	lock sync.Mutex // Serializes the read-modify-write updates of the records
+}
+
+// newPeerDB opens the peer database at the given path, dropping all the expired
+// records. If no path is given, an in-memory database is constructed.
+func newPeerDB(path string) (*peerDB, error) {
+	var (
+		lvl *leveldb.DB
+		err error
+	)
+	if path == "" {
+		lvl, err = leveldb.Open(storage.NewMemStorage(), nil)
+	} else {
+		lvl, err = leveldb.OpenFile(path, &opt.Options{OpenFilesCacheCapacity: 5})
+		if _, iscorrupted := err.(*errors.ErrCorrupted); iscorrupted {
+			lvl, err = leveldb.RecoverFile(path, nil)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	db := &peerDB{lvl: lvl}
+	if err := db.migrate(); err != nil {
+		lvl.Close()
+		return nil, err
+	}
+	db.expireErrors(time.Now())
+	db.expireBans(time.Now())
+	return db, nil
+}
+
+// migrate upgrades the database to the current layout, prefixing the connection
+// failures recorded by the first version.
+func (db *peerDB) migrate() error {
+	blob, err := db.lvl.Get(peerDBVersionKey, nil)
+	switch {
+	case err == nil && len(blob) == 1 && blob[0] == peerDBVersion:
+		return nil
+	case err != nil && err != leveldb.ErrNotFound:
+		return err
+	case err == nil:
+		return fmt.Errorf("unsupported peer database version %x", blob)
+	}
+	batch := new(leveldb.Batch)
+	it := db.lvl.NewIterator(nil, nil)
+	for it.Next() {
+		batch.Delete(it.Key())
+		batch.Put(append(append([]byte{}, peerErrorPrefix...), it.Key()...), it.Value())
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if n := batch.Len(); n > 0 {
+		log.Info("Upgrading peer database", "records", n/2)
+	}
+	batch.Put(peerDBVersionKey, []byte{peerDBVersion})
+	return db.lvl.Write(batch, nil)
+}
+
+// close flushes and closes the database.
+func (db *peerDB) close() {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.lvl.Close()
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/p2p/discover"
+	"github.com/kokprojects/go-kok/rlp"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Connection stages at which failures are recorded.
+const (
+	StageEncHandshake     = "encryption handshake" // Failed to establish the encrypted transport
+	StageProtoHandshake   = "protocol handshake"   // Failed to exchange the capabilities
+	StageAdmission        = "admission"            // Rejected by the local peer checks
+	StageProtocol         = "protocol"             // Dropped due to a protocol or network error
+	StageRemoteDisconnect = "remote disconnect"    // Dropped upon request of the remote node
+)
+
+var (
+	peerErrorExpiration = 7 * 24 * time.Hour // Time after which failures not repeated are dropped
+	peerErrorMaxReason  = 256                // Maximum length of the recorded failure reasons
+)
+
+// PeerError is the record of a recurring failure to connect to, or to stay
+// connected with, a remote node.
+type PeerError struct {
+	ID            string    `json:"id"`            // Unique node identifier
+	Stage         string    `json:"stage"`         // Connection stage the failure happened at
+	Reason        string    `json:"reason"`        // Error the connection failed with
+	Count         uint64    `json:"count"`         // Number of times the failure happened
+	Last          time.Time `json:"lastTime"`      // Time of the last failure
+	RemoteAddress string    `json:"remoteAddress"` // Address of the last failed connection
+}
+
+// peerErrorEntry is the persisted part of a failure record.
+type peerErrorEntry struct {
+	Count uint64
+	Last  uint64 // Unix time in nanoseconds
+	Addr  string
+}
+
+// peerErrorKey = peerErrorPrefix + node id + stage + 0x00 + reason
+func peerErrorKey(id discover.NodeID, stage, reason string) []byte {
+	key := append(append([]byte{}, peerErrorPrefix...), id[:]...)
+	key = append(key, stage...)
+	key = append(key, 0)
+	return append(key, reason...)
+}
+
+// recordError counts a failure of the given node at a connection stage.
+func (db *peerDB) recordError(id discover.NodeID, stage string, reason error, addr net.Addr) {
+	msg := reason.Error()
+	if len(msg) > peerErrorMaxReason {
+		msg = msg[:peerErrorMaxReason]
+	}
+	key := peerErrorKey(id, stage, msg)
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	entry := new(peerErrorEntry)
+	if blob, err := db.lvl.Get(key, nil); err == nil {
+		if err := rlp.DecodeBytes(blob, entry); err != nil {
+			entry = new(peerErrorEntry)
+		}
+	}
+	entry.Count++
+	entry.Last = uint64(time.Now().UnixNano())
+	if addr != nil {
+		entry.Addr = addr.String()
+	}
+	blob, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		return
+	}
+	if err := db.lvl.Put(key, blob, nil); err != nil && err != leveldb.ErrClosed {
+		log.Warn("Failed to record peer error", "id", id, "err", err)
+	}
+}
+
+// listErrors retrieves the failures recorded for a node, or for all nodes if
+// none is given, the most recent first.
+func (db *peerDB) listErrors(id *discover.NodeID) []*PeerError {
+	prefix := peerErrorPrefix
+	if id != nil {
+		prefix = append(append([]byte{}, peerErrorPrefix...), id[:]...)
+	}
+	it := db.lvl.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+
+	failures := []*PeerError{}
+	for it.Next() {
+		key := it.Key()[len(peerErrorPrefix):]
+		if len(key) < len(discover.NodeID{}) {
+			continue
+		}
+		sep := bytes.IndexByte(key[len(discover.NodeID{}):], 0)
+		if sep < 0 {
+			continue
+		}
+		entry := new(peerErrorEntry)
+		if err := rlp.DecodeBytes(it.Value(), entry); err != nil {
+			continue
+		}
+		var node discover.NodeID
+		copy(node[:], key)
+		rest := key[len(node):]
+
+		failures = append(failures, &PeerError{
+			ID:            node.String(),
+			Stage:         string(rest[:sep]),
+			Reason:        string(rest[sep+1:]),
+			Count:         entry.Count,
+			Last:          time.Unix(0, int64(entry.Last)),
+			RemoteAddress: entry.Addr,
+		})
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Last.After(failures[j].Last) })
+	return failures
+}
+
+// expireErrors drops the records of the failures that did not happen again
+// recently.
+func (db *peerDB) expireErrors(now time.Time) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	it := db.lvl.NewIterator(util.BytesPrefix(peerErrorPrefix), nil)
+	defer it.Release()
+
+	threshold := uint64(now.Add(-peerErrorExpiration).UnixNano())
+	for it.Next() {
+		entry := new(peerErrorEntry)
+		if err := rlp.DecodeBytes(it.Value(), entry); err != nil || entry.Last < threshold {
+			db.lvl.Delete(it.Key(), nil)
+		}
+	}
+}
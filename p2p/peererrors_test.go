@@ -0,0 +1,124 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kokprojects/go-kok/p2p/discover"
+	"github.com/kokprojects/go-kok/rlp"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestPeerErrorDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peererrors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peererrors")
+
+	db, err := newPeerDB(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	var (
+		a    = discover.NodeID{1}
+		b    = discover.NodeID{2}
+		addr = &net.TCPAddr{IP: net.IP{127, 0, 0, 1}, Port: 30303}
+	)
+	db.recordError(a, StageProtoHandshake, DiscIncompatibleVersion, addr)
+	db.recordError(a, StageProtoHandshake, DiscIncompatibleVersion, addr)
+	db.recordError(a, StageEncHandshake, errors.New("EOF"), nil)
+	db.recordError(b, StageRemoteDisconnect, DiscTooManyPeers, addr)
+
+	if failures := db.listErrors(nil); len(failures) != 3 {
+		t.Fatalf("failure count mismatch: have %d, want %d", len(failures), 3)
+	}
+	failures := db.listErrors(&a)
+	if len(failures) != 2 {
+		t.Fatalf("failure count mismatch: have %d, want %d", len(failures), 2)
+	}
+	if failures[0].Stage != StageEncHandshake || failures[0].Reason != "EOF" || failures[0].RemoteAddress != "" {
+		t.Errorf("most recent failure mismatch: %+v", failures[0])
+	}
+	if failures[1].Stage != StageProtoHandshake || failures[1].Reason != DiscIncompatibleVersion.Error() || failures[1].Count != 2 {
+		t.Errorf("repeated failure mismatch: %+v", failures[1])
+	}
+	if failures[1].ID != a.String() || failures[1].RemoteAddress != addr.String() {
+		t.Errorf("failure origin mismatch: %+v", failures[1])
+	}
+	db.close()
+
+	// Reopen the database and ensure the records persisted
+	if db, err = newPeerDB(path); err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer db.close()
+
+	failures = db.listErrors(&b)
+	if len(failures) != 1 || failures[0].Reason != DiscTooManyPeers.Error() || failures[0].Count != 1 {
+		t.Fatalf("persisted failures mismatch: %+v", failures)
+	}
+}
+
+// Tests that the failures recorded by the first version of the database, before
+// the records were prefixed, are carried over.
+func TestPeerErrorDBMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peererrors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peererrors")
+
+	// Record a failure the way the first version did
+	lvl, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		t.Fatalf("failed to create legacy database: %v", err)
+	}
+	id := discover.NodeID{1}
+	key := append(append(id[:], StageProtoHandshake...), 0)
+	key = append(key, DiscIncompatibleVersion.Error()...)
+	blob, _ := rlp.EncodeToBytes(&peerErrorEntry{Count: 3, Last: uint64(time.Now().UnixNano())})
+	if err := lvl.Put(key, blob, nil); err != nil {
+		t.Fatalf("failed to write legacy record: %v", err)
+	}
+	lvl.Close()
+
+	// Open the database and ensure the failure is reported
+	for i := 0; i < 2; i++ {
+		db, err := newPeerDB(path)
+		if err != nil {
+			t.Fatalf("open %d: failed to open database: %v", i, err)
+		}
+		failures := db.listErrors(nil)
+		if len(failures) != 1 || failures[0].ID != id.String() || failures[0].Stage != StageProtoHandshake || failures[0].Count != 3 {
+			t.Errorf("open %d: migrated failures mismatch: %+v", i, failures)
+		}
+		if bans := db.loadBans(); len(bans) != 0 {
+			t.Errorf("open %d: bans found in migrated database: %v", i, bans)
+		}
+		db.close()
+	}
+}
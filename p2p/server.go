@@ -33,6 +33,7 @@ import (
 	"github.com/kokprojects/go-kok/p2p/discv5"
 	"github.com/kokprojects/go-kok/p2p/nat"
 	"github.com/kokprojects/go-kok/p2p/netutil"
+	"github.com/kokprojects/go-kok/rlp"
 )
 
 const (
@@ -70,6 +71,18 @@ type Config struct {
 	// Zero defaults to preset values.
 	MaxPendingPeers int `toml:",omitempty"`
 
+	// MaxPeersPerIP and MaxPeersPerSubnet limit the number of inbound peers that
+	// can be connected from a single IP address and from a single /24 (IPv4) or
+	// /64 (IPv6) subnet. Trusted peers and local network addresses are exempt.
+	// Zero means no limit.
+	MaxPeersPerIP     int `toml:",omitempty"`
+	MaxPeersPerSubnet int `toml:",omitempty"`
+
+	// InboundThrottle is the minimum time between two inbound connection attempts
+	// from the same IP address, faster attempts being rejected before the
+	// handshake. Zero disables throttling.
+	InboundThrottle time.Duration `toml:",omitempty"`
+
 	// NoDiscovery can be used to disable the peer discovery mechanism.
 	// Disabling is useful for protocol debugging (manual topology).
 	NoDiscovery bool
@@ -111,6 +124,10 @@ type Config struct {
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
 
+	// PeerErrorDatabase is the path to the database recording the connection
+	// failures and bans of the remote nodes. If empty, they are kept in memory.
+	PeerErrorDatabase string `toml:",omitempty"`
+
 	// Protocols should contain the protocols supported
 	// by the server. Matching protocols are launched for
 	// each peer.
@@ -139,6 +156,10 @@ type Config struct {
 	// If EnableMsgEvents is set then the server will emit PeerEvents
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
+
+	// Metadata is the operator information attached to the protocol handshake,
+	// signed with the node key when the server starts.
+	Metadata *NodeMetadata `toml:",omitempty"`
 }
 
 // Server manages all peer connections.
@@ -157,8 +178,13 @@ type Server struct {
 	ntab         discoverTable
 	listener     net.Listener
 	ourHandshake *protoHandshake
+	ourMetadata  *NodeMetadata
 	lastLookup   time.Time
 	DiscV5       *discv5.Network
+	peerdb       *peerDB
+
+	banLock sync.RWMutex // protects bans
+	bans    map[discover.NodeID]*PeerBan
 
 	// These are for Peers, PeerCount (and nothing else).
 	peerOp     chan peerOpFunc
@@ -167,6 +193,8 @@ type Server struct {
 	quit          chan struct{}
 	addstatic     chan *discover.Node
 	removestatic  chan *discover.Node
+	addtrusted    chan *discover.Node
+	removetrusted chan *discover.Node
 	posthandshake chan *conn
 	addpeer       chan *conn
 	delpeer       chan peerDrop
@@ -201,6 +229,7 @@ type conn struct {
 	id    discover.NodeID // valid after the encryption handshake
 	caps  []Cap           // valid after the protocol handshake
 	name  string          // valid after the protocol handshake
+	meta  *NodeMetadata   // valid after the protocol handshake, if sent
 }
 
 type transport interface {
@@ -297,6 +326,23 @@ func (srv *Server) RemovePeer(node *discover.Node) {
 	}
 }
 
+// AddTrustedPeer adds the given node to a reserved whitelist which allows the
+// node to always connect, even if the slots are full.
+func (srv *Server) AddTrustedPeer(node *discover.Node) {
+	select {
+	case srv.addtrusted <- node:
+	case <-srv.quit:
+	}
+}
+
+// RemoveTrustedPeer removes the given node from the trusted peer set.
+func (srv *Server) RemoveTrustedPeer(node *discover.Node) {
+	select {
+	case srv.removetrusted <- node:
+	case <-srv.quit:
+	}
+}
+
 // SubscribePeers subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -348,6 +394,13 @@ func (srv *Server) Stop() {
 	}
 	close(srv.quit)
 	srv.loopWG.Wait()
+	if srv.peerdb != nil {
+		srv.banLock.Lock()
+		srv.bans = nil
+		srv.banLock.Unlock()
+
+		srv.peerdb.close()
+	}
 }
 
 // Start starts running the server.
@@ -377,9 +430,18 @@ func (srv *Server) Start() (err error) {
 	srv.posthandshake = make(chan *conn)
 	srv.addstatic = make(chan *discover.Node)
 	srv.removestatic = make(chan *discover.Node)
+	srv.addtrusted = make(chan *discover.Node)
+	srv.removetrusted = make(chan *discover.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
+	// connection failure and ban records
+	if srv.peerdb, err = newPeerDB(srv.PeerErrorDatabase); err != nil {
+		return err
+	}
+	srv.banLock.Lock()
+	srv.bans = srv.peerdb.loadBans()
+	srv.banLock.Unlock()
 	// node table
 	if !srv.NoDiscovery {
 		ntab, err := discover.ListenUDP(srv.PrivateKey, srv.ListenAddr, srv.NAT, srv.NodeDatabase, srv.NetRestrict)
@@ -408,12 +470,20 @@ func (srv *Server) Start() (err error) {
 		dynPeers = 0
 	}
 	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	dialer.banned = srv.isBanned
 
 	// handshake
 	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
 	for _, p := range srv.Protocols {
 		srv.ourHandshake.Caps = append(srv.ourHandshake.Caps, p.cap())
 	}
+	if srv.Metadata != nil {
+		if srv.ourMetadata, err = srv.Metadata.sign(srv.PrivateKey); err != nil {
+			return fmt.Errorf("invalid node metadata: %v", err)
+		}
+		enc, _ := rlp.EncodeToBytes(srv.ourMetadata)
+		srv.ourHandshake.Rest = []rlp.RawValue{enc}
+	}
 	// listen/dial
 	if srv.ListenAddr != "" {
 		if err := srv.startListening(); err != nil {
@@ -467,10 +537,11 @@ func (srv *Server) run(dialstate dialer) {
 		taskdone     = make(chan task, maxActiveDialTasks)
 		runningTasks []task
 		queuedTasks  []task // tasks that can't run yet
+		violations   = make(peerViolations)
 	)
 	// Put trusted nodes into a map to speed up checks.
-	// Trusted peers are loaded on startup and cannot be
-	// modified while the server is running.
+	// Trusted peers are loaded on startup and can be
+	// modified through AddTrustedPeer and RemoveTrustedPeer.
 	for _, n := range srv.TrustedNodes {
 		trusted[n.ID] = true
 	}
@@ -528,6 +599,17 @@ running:
 			if p, ok := peers[n.ID]; ok {
 				p.Disconnect(DiscRequested)
 			}
+		case n := <-srv.addtrusted:
+			// This channel is used by AddTrustedPeer to add a node
+			// to the trusted node set. The flag only matters when
+			// the next connection to the node is checked.
+			log.Debug("Adding trusted node", "node", n)
+			trusted[n.ID] = true
+		case n := <-srv.removetrusted:
+			// This channel is used by RemoveTrustedPeer to remove a
+			// node from the trusted node set.
+			log.Debug("Removing trusted node", "node", n)
+			delete(trusted, n.ID)
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
@@ -582,6 +664,8 @@ running:
 			d := common.PrettyDuration(mclock.Now() - pd.created)
 			pd.log.Debug("Removing p2p peer", "duration", d, "peers", len(peers)-1, "req", pd.requested, "err", pd.err)
 			delete(peers, pd.ID())
+			srv.recordDrop(pd)
+			srv.checkViolation(violations, pd)
 		}
 	}
 
@@ -620,8 +704,12 @@ func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn
 
 func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, c *conn) error {
 	switch {
+	case srv.isBanned(c.id):
+		return errBanned
 	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers:
 		return DiscTooManyPeers
+	case c.is(inboundConn) && !c.is(trustedConn) && srv.inboundLimited(peers, c):
+		return DiscTooManyPeers
 	case peers[c.id] != nil:
 		return DiscAlreadyConnected
 	case c.id == srv.Self().ID:
@@ -652,6 +740,7 @@ func (srv *Server) listenLoop() {
 	for i := 0; i < tokens; i++ {
 		slots <- struct{}{}
 	}
+	throttle := newInboundThrottle(srv.InboundThrottle)
 
 	for {
 		// Wait for a handshake slot before accepting.
@@ -682,6 +771,13 @@ func (srv *Server) listenLoop() {
 				continue
 			}
 		}
+		// Reject connections arriving too fast from the same address.
+		if !throttle.allow(connIP(fd), time.Now()) {
+			log.Trace("Rejected conn (inbound throttle)", "addr", fd.RemoteAddr())
+			fd.Close()
+			slots <- struct{}{}
+			continue
+		}
 
 		fd = newMeteredConn(fd, true)
 		log.Trace("Accepted connection", "addr", fd.RemoteAddr())
@@ -712,6 +808,9 @@ func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 	var err error
 	if c.id, err = c.doEncHandshake(srv.PrivateKey, dialDest); err != nil {
 		log.Trace("Failed RLPx handshake", "addr", c.fd.RemoteAddr(), "conn", c.flags, "err", err)
+		if dialDest != nil {
+			srv.recordFailure(dialDest.ID, StageEncHandshake, err, c.fd.RemoteAddr())
+		}
 		c.close(err)
 		return
 	}
@@ -719,11 +818,13 @@ func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 	// For dialed connections, check that the remote public key matches.
 	if dialDest != nil && c.id != dialDest.ID {
 		c.close(DiscUnexpectedIdentity)
+		srv.recordFailure(dialDest.ID, StageEncHandshake, DiscUnexpectedIdentity, c.fd.RemoteAddr())
 		clog.Trace("Dialed identity mismatch", "want", c, dialDest.ID)
 		return
 	}
 	if err := srv.checkpoint(c, srv.posthandshake); err != nil {
 		clog.Trace("Rejected peer before protocol handshake", "err", err)
+		srv.recordFailure(c.id, StageAdmission, err, c.fd.RemoteAddr())
 		c.close(err)
 		return
 	}
@@ -731,17 +832,23 @@ func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 	phs, err := c.doProtoHandshake(srv.ourHandshake)
 	if err != nil {
 		clog.Trace("Failed proto handshake", "err", err)
+		srv.recordFailure(c.id, StageProtoHandshake, err, c.fd.RemoteAddr())
 		c.close(err)
 		return
 	}
 	if phs.ID != c.id {
 		clog.Trace("Wrong devp2p handshake identity", "err", phs.ID)
+		srv.recordFailure(c.id, StageProtoHandshake, DiscUnexpectedIdentity, c.fd.RemoteAddr())
 		c.close(DiscUnexpectedIdentity)
 		return
 	}
 	c.caps, c.name = phs.Caps, phs.Name
+	if c.meta, err = handshakeMetadata(phs); err != nil {
+		clog.Trace("Ignoring invalid handshake metadata", "err", err)
+	}
 	if err := srv.checkpoint(c, srv.addpeer); err != nil {
 		clog.Trace("Rejected peer", "err", err)
+		srv.recordFailure(c.id, StageAdmission, err, c.fd.RemoteAddr())
 		c.close(err)
 		return
 	}
@@ -749,6 +856,40 @@ func (srv *Server) SetupConn(fd net.Conn, flags connFlag, dialDest *discover.Nod
 	// launched by run.
 }
 
+// recordFailure stores a connection failure of a remote node, unless it was
+// caused by the local server shutting down.
+func (srv *Server) recordFailure(id discover.NodeID, stage string, err error, addr net.Addr) {
+	if srv.peerdb == nil || err == errServerStopped || err == DiscQuitting || err == errBanned {
+		return
+	}
+	srv.peerdb.recordError(id, stage, err, addr)
+}
+
+// recordDrop stores the reason an established peer connection was torn down,
+// unless it was requested locally.
+func (srv *Server) recordDrop(pd peerDrop) {
+	if pd.err == nil || (!pd.requested && pd.err == DiscRequested) {
+		return
+	}
+	stage := StageProtocol
+	if pd.requested {
+		stage = StageRemoteDisconnect
+	}
+	srv.recordFailure(pd.ID(), stage, pd.err, pd.RemoteAddr())
+}
+
+// PeerErrors retrieves the recorded connection failures of a remote node, or of
+// all remote nodes if id is nil, the most recent first.
+func (srv *Server) PeerErrors(id *discover.NodeID) []*PeerError {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if !srv.running || srv.peerdb == nil {
+		return nil
+	}
+	return srv.peerdb.listErrors(id)
+}
+
 func truncateName(s string) string {
 	if len(s) > 20 {
 		return s[:20] + "..."
@@ -812,6 +953,7 @@ type NodeInfo struct {
 		Listener  int `json:"listener"`  // TCP listening port for RLPx
 	} `json:"ports"`
 	ListenAddr string                 `json:"listenAddr"`
+	Metadata   *NodeMetadata          `json:"metadata,omitempty"` // Signed operator metadata
 	Protocols  map[string]interface{} `json:"protocols"`
 }
 
@@ -826,6 +968,7 @@ func (srv *Server) NodeInfo() *NodeInfo {
 		ID:         node.ID.String(),
 		IP:         node.IP.String(),
 		ListenAddr: srv.ListenAddr,
+		Metadata:   srv.ourMetadata,
 		Protocols:  make(map[string]interface{}),
 	}
 	info.Ports.Discovery = int(node.UDP)
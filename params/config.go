@@ -41,9 +41,9 @@ var (
 
 		Dpos: &DposConfig{},
 	}
-	TestChainConfig          = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil}
-	AllkokashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil}
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil}
+	TestChainConfig          = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil}
+	AllkokashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil}
 )
 
 // ChainConfig is the core config which determines the blockchain settings.
@@ -68,7 +68,16 @@ type ChainConfig struct {
 
 	ByzantiumBlock *big.Int `json:"byzantiumBlock,omitempty"` // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 
-	Dpos *DposConfig `json:"dpos,omitempty"`
+	TxTypeBlock *big.Int `json:"txTypeBlock,omitempty"` // Transaction type rules enforced on block bodies switch block (nil = no fork)
+
+	Dpos       *DposConfig       `json:"dpos,omitempty"`
+	Fee        *FeeConfig        `json:"fee,omitempty"`        // Fee sharing of contract calls (nil = DefaultDeveloperShare)
+	Emission   *EmissionConfig   `json:"emission,omitempty"`   // Native token emission schedule (nil = engine default)
+	Treasury   *TreasuryConfig   `json:"treasury,omitempty"`   // Transaction fee diversion to a treasury (nil = disabled)
+	Permission *PermissionConfig `json:"permission,omitempty"` // Allowlisted senders and deployers (nil = open network)
+
+	Freezes     []*FreezeConfig     `json:"freezes,omitempty"`     // Accounts barred from sending transactions, by activation block (nil = none)
+	Precompiles []*PrecompileConfig `json:"precompiles,omitempty"` // Registered precompiled contracts activated on the chain (nil = none)
 }
 
 // CliqueConfig is the consensus engine configs for proof-of-authority based sealing.
@@ -92,9 +101,247 @@ func (d *DposConfig) String() string {
 	return "dpos"
 }
 
+// DefaultDeveloperShare is the percentage of the gas fees of a contract call
+// paid to the contract's coinbase account if the chain doesn't configure one.
+const DefaultDeveloperShare = 10
+
+// FeeConfig configures how the gas fees of contract calls are split between the
+// block validator and the coinbase account of the called contract.
+type FeeConfig struct {
+	DeveloperShare uint64 `json:"developerShare"` // Percentage of the fees paid to the contract coinbase (0-100)
+}
+
+// String implements the stringer interface, returning the fee split details.
+func (f *FeeConfig) String() string {
+	return fmt.Sprintf("%d/%d", 100-f.DeveloperShare, f.DeveloperShare)
+}
+
+// DeveloperShare returns the percentage of the gas fees of contract calls that
+// is paid to the coinbase account of the called contract.
+func (c *ChainConfig) DeveloperShare() uint64 {
+	if c.Fee == nil {
+		return DefaultDeveloperShare
+	}
+	return c.Fee.DeveloperShare
+}
+
+// CheckConfig checks the chain configuration for values that can't be used to
+// run a chain, returning an error describing the first one found.
+func (c *ChainConfig) CheckConfig() error {
+	if c.Fee != nil && c.Fee.DeveloperShare > 100 {
+		return fmt.Errorf("invalid developer fee share %d%%, must be between 0 and 100", c.Fee.DeveloperShare)
+	}
+	if c.Emission != nil {
+		if c.Emission.Block == nil {
+			return fmt.Errorf("invalid emission: missing activation block")
+		}
+		if err := c.Emission.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Treasury != nil && (c.Treasury.Block == nil || c.Treasury.Percent > 100) {
+		return fmt.Errorf("invalid treasury: block %v, percent %d", c.Treasury.Block, c.Treasury.Percent)
+	}
+	for i, f := range c.Freezes {
+		if f.Block == nil {
+			return fmt.Errorf("invalid freeze %v: missing activation block", f)
+		}
+		for _, prev := range c.Freezes[:i] {
+			if prev.Block.Cmp(f.Block) == 0 {
+				return fmt.Errorf("invalid freeze %v: block already has a freeze", f)
+			}
+		}
+	}
+	if c.Permission != nil {
+		if c.Permission.Block == nil {
+			return fmt.Errorf("invalid permissioning: missing activation block")
+		}
+		for i, g := range c.Permission.Grants {
+			if g.Block == nil {
+				return fmt.Errorf("invalid permission grant %v: missing activation block", g)
+			}
+			for _, prev := range c.Permission.Grants[:i] {
+				if prev.Block.Cmp(g.Block) == 0 {
+					return fmt.Errorf("invalid permission grant %v: block already has a grant", g)
+				}
+			}
+		}
+	}
+	for i, p := range c.Precompiles {
+		if p.Name == "" || p.Block == nil {
+			return fmt.Errorf("invalid precompile %v: missing name or activation block", p)
+		}
+		for _, prev := range c.Precompiles[:i] {
+			if prev.Address == p.Address && prev.Block.Cmp(p.Block) == 0 {
+				return fmt.Errorf("invalid precompile %v: address already activated at block %v", p, p.Block)
+			}
+		}
+	}
+	return nil
+}
+
+// Native token emission schedules.
+const (
+	EmissionNone     = "none"     // No block rewards
+	EmissionFixed    = "fixed"    // Constant block reward
+	EmissionDecaying = "decaying" // Block reward reduced by a percentage at every interval
+)
+
+// EmissionConfig is the native token emission schedule, defining the reward
+// credited to the sealer of every block from the activation block on. Blocks
+// before it are rewarded by the consensus engine's default.
+type EmissionConfig struct {
+	Block         *big.Int `json:"block"`                   // Block number the schedule activates at
+	Schedule      string   `json:"schedule"`                // One of "none", "fixed" or "decaying"
+	Reward        *big.Int `json:"reward,omitempty"`        // Block reward in wei (initial one if decaying)
+	DecayInterval uint64   `json:"decayInterval,omitempty"` // Number of blocks between reward reductions
+	DecayPercent  uint64   `json:"decayPercent,omitempty"`  // Percentage the reward is reduced by at every interval
+}
+
+// String implements the stringer interface, returning the emission schedule.
+func (e *EmissionConfig) String() string {
+	switch e.Schedule {
+	case EmissionFixed:
+		return fmt.Sprintf("fixed(%v)@%v", e.Reward, e.Block)
+	case EmissionDecaying:
+		return fmt.Sprintf("decaying(%v -%d%%/%d)@%v", e.Reward, e.DecayPercent, e.DecayInterval, e.Block)
+	}
+	return fmt.Sprintf("%s@%v", e.Schedule, e.Block)
+}
+
+// Validate checks that the emission schedule is well formed.
+func (e *EmissionConfig) Validate() error {
+	switch e.Schedule {
+	case EmissionNone:
+		return nil
+	case EmissionFixed, EmissionDecaying:
+		if e.Reward == nil || e.Reward.Sign() < 0 {
+			return fmt.Errorf("invalid %s emission reward %v", e.Schedule, e.Reward)
+		}
+		if e.Schedule == EmissionDecaying && (e.DecayInterval == 0 || e.DecayPercent > 100) {
+			return fmt.Errorf("invalid decaying emission: interval %d, percent %d", e.DecayInterval, e.DecayPercent)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown emission schedule %q", e.Schedule)
+}
+
+// BlockReward returns the reward of the block with the given number, decaying
+// from the activation block on. Malformed schedules emit nothing.
+func (e *EmissionConfig) BlockReward(num *big.Int) *big.Int {
+	if e.Validate() != nil || e.Schedule == EmissionNone {
+		return new(big.Int)
+	}
+	reward := new(big.Int).Set(e.Reward)
+	if e.Schedule != EmissionDecaying || e.DecayPercent == 0 {
+		return reward
+	}
+	elapsed := new(big.Int).Set(num)
+	if e.Block != nil {
+		elapsed.Sub(elapsed, e.Block)
+	}
+	if elapsed.Sign() <= 0 {
+		return reward
+	}
+	periods := elapsed.Div(elapsed, new(big.Int).SetUint64(e.DecayInterval))
+	if periods.Sign() == 0 {
+		return reward
+	}
+	// Even the slowest decay of 1% halves the reward every 70 periods, so skip
+	// computing huge powers once the reward has certainly decayed to nothing.
+	if e.DecayPercent == 100 || !periods.IsUint64() || periods.Uint64() > uint64(reward.BitLen())*70 {
+		return new(big.Int)
+	}
+	keep := new(big.Int).Exp(new(big.Int).SetUint64(100-e.DecayPercent), periods, nil)
+	reward.Mul(reward, keep)
+	return reward.Div(reward, new(big.Int).Exp(big.NewInt(100), periods, nil))
+}
+
+// equal reports whkoker two emission schedules define the same rewards.
+func (e *EmissionConfig) equal(other *EmissionConfig) bool {
+	if other == nil {
+		return false
+	}
+	return configNumEqual(e.Block, other.Block) && e.Schedule == other.Schedule && configNumEqual(e.Reward, other.Reward) &&
+		e.DecayInterval == other.DecayInterval && e.DecayPercent == other.DecayPercent
+}
+
+// TreasuryConfig diverts a share of the transaction fees otherwise paid to the
+// block producer to a treasury or ecosystem fund.
+type TreasuryConfig struct {
+	Block   *big.Int       `json:"block"`   // Block number the diversion activates at
+	Address common.Address `json:"address"` // Treasury receiving the diverted fees
+	Percent uint64         `json:"percent"` // Percentage of the block producer's fees diverted
+}
+
+// String implements the stringer interface, returning the treasury details.
+func (t *TreasuryConfig) String() string {
+	return fmt.Sprintf("%d%%@%v->%x", t.Percent, t.Block, t.Address)
+}
+
+// FreezeConfig lists the accounts whose outgoing transactions are rejected from
+// the activation block on, for recovery scenarios on permissioned deployments.
+// Freezes activating at later blocks add to the accounts frozen by earlier ones,
+// so the list can be extended without touching already active entries.
+type FreezeConfig struct {
+	Block     *big.Int         `json:"block"`     // Block number the freeze activates at
+	Addresses []common.Address `json:"addresses"` // Frozen accounts
+}
+
+// String implements the stringer interface, returning the freeze details.
+func (f *FreezeConfig) String() string {
+	return fmt.Sprintf("%d@%v", len(f.Addresses), f.Block)
+}
+
+// PermissionConfig restricts who may send transactions and who may deploy
+// templates or contracts, for consortium deployments. Accounts are permitted if
+// listed here, listed by an active grant or granted by the registry contract, if
+// one is set. Permissions can only be revoked at runtime through the registry.
+type PermissionConfig struct {
+	Block     *big.Int           `json:"block"`               // Block number the permissioning activates at
+	Senders   []common.Address   `json:"senders,omitempty"`   // Accounts allowed to send transactions
+	Deployers []common.Address   `json:"deployers,omitempty"` // Accounts allowed to deploy templates and contracts
+	Registry  *common.Address    `json:"registry,omitempty"`  // Contract granting permissions on-chain
+	Grants    []*PermissionGrant `json:"grants,omitempty"`    // Accounts permitted later on, by activation block (nil = none)
+}
+
+// String implements the stringer interface, returning the permissioning details.
+func (p *PermissionConfig) String() string {
+	return fmt.Sprintf("%d/%d@%v registry: %x grants: %v", len(p.Senders), len(p.Deployers), p.Block, p.Registry, p.Grants)
+}
+
+// PermissionGrant permits additional accounts to send transactions and deploy
+// templates or contracts from the activation block on. Grants activating at later
+// blocks add to the accounts permitted by earlier ones, so the allowlists can be
+// extended without touching already active entries.
+type PermissionGrant struct {
+	Block     *big.Int         `json:"block"`               // Block number the grant activates at
+	Senders   []common.Address `json:"senders,omitempty"`   // Accounts allowed to send transactions
+	Deployers []common.Address `json:"deployers,omitempty"` // Accounts allowed to deploy templates and contracts
+}
+
+// String implements the stringer interface, returning the grant details.
+func (g *PermissionGrant) String() string {
+	return fmt.Sprintf("%d/%d@%v", len(g.Senders), len(g.Deployers), g.Block)
+}
+
+// PrecompileConfig activates the precompiled contract registered in the EVM under
+// the given name at an address, from the activation block on. A later activation
+// at the same address replaces it, which allows upgrading built-in contracts too.
+type PrecompileConfig struct {
+	Name    string         `json:"name"`    // Name the contract is registered under
+	Address common.Address `json:"address"` // Address the contract is called at
+	Block   *big.Int       `json:"block"`   // Block number the contract activates at
+}
+
+// String implements the stringer interface, returning the precompile details.
+func (p *PrecompileConfig) String() string {
+	return fmt.Sprintf("%s@%v->%x", p.Name, p.Block, p.Address)
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v TxTypes: %v Engine: %v Emission: %v FeeSplit: %d/%d Treasury: %v Permission: %v Freezes: %v Precompiles: %v}",
 		c.ChainId,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -103,7 +350,15 @@ func (c *ChainConfig) String() string {
 		c.EIP155Block,
 		c.EIP158Block,
 		c.ByzantiumBlock,
+		c.TxTypeBlock,
 		c.Dpos,
+		c.Emission,
+		100-c.DeveloperShare(),
+		c.DeveloperShare(),
+		c.Treasury,
+		c.Permission,
+		c.Freezes,
+		c.Precompiles,
 	)
 }
 
@@ -117,6 +372,73 @@ func (c *ChainConfig) IsDAOFork(num *big.Int) bool {
 	return isForked(c.DAOForkBlock, num)
 }
 
+// IsEmission returns whkoker num is either equal to the emission schedule activation block or greater.
+func (c *ChainConfig) IsEmission(num *big.Int) bool {
+	return c.Emission != nil && isForked(c.Emission.Block, num)
+}
+
+// IsTreasury returns whkoker num is either equal to the treasury activation block or greater.
+func (c *ChainConfig) IsTreasury(num *big.Int) bool {
+	return c.Treasury != nil && isForked(c.Treasury.Block, num)
+}
+
+// FrozenAccounts returns the accounts frozen by all freezes active at block num,
+// if any.
+func (c *ChainConfig) FrozenAccounts(num *big.Int) []common.Address {
+	var frozen []common.Address
+	for _, f := range c.Freezes {
+		if isForked(f.Block, num) {
+			frozen = append(frozen, f.Addresses...)
+		}
+	}
+	return frozen
+}
+
+// Precompile returns the configured precompiled contract active at addr at block
+// num, nil if none.
+func (c *ChainConfig) Precompile(num *big.Int, addr common.Address) *PrecompileConfig {
+	var active *PrecompileConfig
+	for _, p := range c.Precompiles {
+		if p.Address == addr && isForked(p.Block, num) && (active == nil || p.Block.Cmp(active.Block) > 0) {
+			active = p
+		}
+	}
+	return active
+}
+
+// IsFrozen returns whkoker addr is barred from sending transactions at block num.
+func (c *ChainConfig) IsFrozen(num *big.Int, addr common.Address) bool {
+	for _, frozen := range c.FrozenAccounts(num) {
+		if frozen == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPermissioned returns whkoker sending transactions requires permission at block num.
+func (c *ChainConfig) IsPermissioned(num *big.Int) bool {
+	return c.Permission != nil && isForked(c.Permission.Block, num)
+}
+
+// PermittedAccounts returns the accounts allowed to send transactions and to
+// deploy templates and contracts by the chain configuration at block num,
+// including those of all the grants active at it.
+func (c *ChainConfig) PermittedAccounts(num *big.Int) (senders, deployers []common.Address) {
+	if !c.IsPermissioned(num) {
+		return nil, nil
+	}
+	senders = append(senders, c.Permission.Senders...)
+	deployers = append(deployers, c.Permission.Deployers...)
+	for _, g := range c.Permission.Grants {
+		if isForked(g.Block, num) {
+			senders = append(senders, g.Senders...)
+			deployers = append(deployers, g.Deployers...)
+		}
+	}
+	return senders, deployers
+}
+
 func (c *ChainConfig) IsEIP150(num *big.Int) bool {
 	return isForked(c.EIP150Block, num)
 }
@@ -133,6 +455,12 @@ func (c *ChainConfig) IsByzantium(num *big.Int) bool {
 	return isForked(c.ByzantiumBlock, num)
 }
 
+// IsTxTypes returns whkoker num is either equal to the block from which on the
+// transactions of blocks must abide by the rules of their types or greater.
+func (c *ChainConfig) IsTxTypes(num *big.Int) bool {
+	return isForked(c.TxTypeBlock, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -193,9 +521,179 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ByzantiumBlock, newcfg.ByzantiumBlock, head) {
 		return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
 	}
+	if isForkIncompatible(c.TxTypeBlock, newcfg.TxTypeBlock, head) {
+		return newCompatError("TxType fork block", c.TxTypeBlock, newcfg.TxTypeBlock)
+	}
+	if isForkIncompatible(c.emissionBlock(), newcfg.emissionBlock(), head) {
+		return newCompatError("Emission fork block", c.emissionBlock(), newcfg.emissionBlock())
+	}
+	if c.IsEmission(head) && !c.Emission.equal(newcfg.Emission) {
+		return newCompatError("emission schedule", c.emissionBlock(), newcfg.emissionBlock())
+	}
+	if isForkIncompatible(c.treasuryBlock(), newcfg.treasuryBlock(), head) {
+		return newCompatError("Treasury fork block", c.treasuryBlock(), newcfg.treasuryBlock())
+	}
+	if c.IsTreasury(head) && (c.Treasury.Address != newcfg.Treasury.Address || c.Treasury.Percent != newcfg.Treasury.Percent) {
+		return newCompatError("treasury parameters", c.treasuryBlock(), newcfg.treasuryBlock())
+	}
+	for _, cfg := range [][]*FreezeConfig{c.Freezes, newcfg.Freezes} {
+		for _, f := range cfg {
+			if !isForked(f.Block, head) {
+				continue
+			}
+			stored, updated := c.freeze(f.Block), newcfg.freeze(f.Block)
+			if !freezeEqual(stored, updated) {
+				return newCompatError("frozen accounts", stored.activation(), updated.activation())
+			}
+		}
+	}
+	if isForkIncompatible(c.permissionBlock(), newcfg.permissionBlock(), head) {
+		return newCompatError("Permission fork block", c.permissionBlock(), newcfg.permissionBlock())
+	}
+	if c.IsPermissioned(head) {
+		if !c.Permission.equal(newcfg.Permission) {
+			return newCompatError("permissioned accounts", c.permissionBlock(), newcfg.permissionBlock())
+		}
+		for _, grants := range [][]*PermissionGrant{c.Permission.Grants, newcfg.Permission.Grants} {
+			for _, g := range grants {
+				if !isForked(g.Block, head) {
+					continue
+				}
+				stored, updated := c.Permission.grant(g.Block), newcfg.Permission.grant(g.Block)
+				if !grantEqual(stored, updated) {
+					return newCompatError("permission grant", stored.activation(), updated.activation())
+				}
+			}
+		}
+	}
+	for _, cfg := range [][]*PrecompileConfig{c.Precompiles, newcfg.Precompiles} {
+		for _, p := range cfg {
+			stored, updated := c.Precompile(head, p.Address), newcfg.Precompile(head, p.Address)
+			if !precompileEqual(stored, updated) {
+				return newCompatError(fmt.Sprintf("precompile at %x", p.Address), stored.activation(), updated.activation())
+			}
+		}
+	}
+	return nil
+}
+
+// permissionBlock returns the permissioning activation block, nil if not configured.
+func (c *ChainConfig) permissionBlock() *big.Int {
+	if c.Permission == nil {
+		return nil
+	}
+	return c.Permission.Block
+}
+
+// equal reports whkoker two permissioning configs grant the same permissions from
+// their activation on. The grants activating later are not compared.
+func (p *PermissionConfig) equal(other *PermissionConfig) bool {
+	if !addressesEqual(p.Senders, other.Senders) || !addressesEqual(p.Deployers, other.Deployers) {
+		return false
+	}
+	if p.Registry == nil || other.Registry == nil {
+		return p.Registry == other.Registry
+	}
+	return *p.Registry == *other.Registry
+}
+
+// grant returns the permission grant activating at block num, nil if none.
+func (p *PermissionConfig) grant(num *big.Int) *PermissionGrant {
+	for _, g := range p.Grants {
+		if g.Block.Cmp(num) == 0 {
+			return g
+		}
+	}
 	return nil
 }
 
+// grantEqual reports whkoker two permission grants are identical.
+func grantEqual(a, b *PermissionGrant) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Block.Cmp(b.Block) == 0 && addressesEqual(a.Senders, b.Senders) && addressesEqual(a.Deployers, b.Deployers)
+}
+
+// activation returns the permission grant activation block, nil if not configured.
+func (g *PermissionGrant) activation() *big.Int {
+	if g == nil {
+		return nil
+	}
+	return g.Block
+}
+
+// precompileEqual reports whkoker two precompile activations are identical.
+func precompileEqual(a, b *PrecompileConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Name == b.Name && a.Address == b.Address && a.Block.Cmp(b.Block) == 0
+}
+
+// activation returns the precompile activation block, nil if not configured.
+func (p *PrecompileConfig) activation() *big.Int {
+	if p == nil {
+		return nil
+	}
+	return p.Block
+}
+
+// addressesEqual reports whkoker two address lists are identical.
+func addressesEqual(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// freeze returns the freeze activating at block num, nil if none.
+func (c *ChainConfig) freeze(num *big.Int) *FreezeConfig {
+	for _, f := range c.Freezes {
+		if f.Block.Cmp(num) == 0 {
+			return f
+		}
+	}
+	return nil
+}
+
+// freezeEqual reports whkoker two freezes are identical.
+func freezeEqual(a, b *FreezeConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Block.Cmp(b.Block) == 0 && addressesEqual(a.Addresses, b.Addresses)
+}
+
+// activation returns the freeze activation block, nil if not configured.
+func (f *FreezeConfig) activation() *big.Int {
+	if f == nil {
+		return nil
+	}
+	return f.Block
+}
+
+// emissionBlock returns the emission schedule activation block, nil if not configured.
+func (c *ChainConfig) emissionBlock() *big.Int {
+	if c.Emission == nil {
+		return nil
+	}
+	return c.Emission.Block
+}
+
+// treasuryBlock returns the treasury activation block, nil if not configured.
+func (c *ChainConfig) treasuryBlock() *big.Int {
+	if c.Treasury == nil {
+		return nil
+	}
+	return c.Treasury.Block
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
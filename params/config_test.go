@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/kokprojects/go-kok/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -70,6 +72,124 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     9,
 			},
 		},
+		{
+			stored:  &ChainConfig{TxTypeBlock: big.NewInt(30)},
+			new:     &ChainConfig{TxTypeBlock: big.NewInt(40)},
+			head:    25,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{TxTypeBlock: big.NewInt(30)},
+			new:    &ChainConfig{},
+			head:   35,
+			wantErr: &ConfigCompatError{
+				What:         "TxType fork block",
+				StoredConfig: big.NewInt(30),
+				NewConfig:    nil,
+				RewindTo:     29,
+			},
+		},
+		{
+			stored:  &ChainConfig{Freezes: []*FreezeConfig{{Block: big.NewInt(10), Addresses: []common.Address{{1}}}}},
+			new:     &ChainConfig{Freezes: []*FreezeConfig{{Block: big.NewInt(10), Addresses: []common.Address{{1}, {2}}}}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Freezes: []*FreezeConfig{{Block: big.NewInt(10), Addresses: []common.Address{{1}}}}},
+			new:    &ChainConfig{Freezes: []*FreezeConfig{{Block: big.NewInt(10), Addresses: []common.Address{{1}, {2}}}}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "frozen accounts",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{Freezes: []*FreezeConfig{{Block: big.NewInt(10), Addresses: []common.Address{{1}}}}},
+			new:     &ChainConfig{Freezes: []*FreezeConfig{{Block: big.NewInt(10), Addresses: []common.Address{{1}}}, {Block: big.NewInt(20), Addresses: []common.Address{{2}}}}},
+			head:    15,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Freezes: []*FreezeConfig{{Block: big.NewInt(10), Addresses: []common.Address{{1}}}}},
+			new:    &ChainConfig{Freezes: []*FreezeConfig{{Block: big.NewInt(10), Addresses: []common.Address{{1}}}, {Block: big.NewInt(12), Addresses: []common.Address{{2}}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "frozen accounts",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(12),
+				RewindTo:     11,
+			},
+		},
+		{
+			stored:  &ChainConfig{Permission: &PermissionConfig{Block: big.NewInt(10), Senders: []common.Address{{1}}}},
+			new:     &ChainConfig{Permission: &PermissionConfig{Block: big.NewInt(10), Senders: []common.Address{{1}}, Grants: []*PermissionGrant{{Block: big.NewInt(20), Senders: []common.Address{{2}}}}}},
+			head:    15,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Permission: &PermissionConfig{Block: big.NewInt(10), Senders: []common.Address{{1}}}},
+			new:    &ChainConfig{Permission: &PermissionConfig{Block: big.NewInt(10), Senders: []common.Address{{1}, {2}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "permissioned accounts",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{Permission: &PermissionConfig{Block: big.NewInt(10), Grants: []*PermissionGrant{{Block: big.NewInt(12), Senders: []common.Address{{2}}}}}},
+			new:    &ChainConfig{Permission: &PermissionConfig{Block: big.NewInt(10), Grants: []*PermissionGrant{{Block: big.NewInt(12), Senders: []common.Address{{2}}, Deployers: []common.Address{{2}}}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "permission grant",
+				StoredConfig: big.NewInt(12),
+				NewConfig:    big.NewInt(12),
+				RewindTo:     11,
+			},
+		},
+		{
+			stored: &ChainConfig{Permission: &PermissionConfig{Block: big.NewInt(10)}},
+			new:    &ChainConfig{Permission: &PermissionConfig{Block: big.NewInt(10), Grants: []*PermissionGrant{{Block: big.NewInt(12), Senders: []common.Address{{2}}}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "permission grant",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(12),
+				RewindTo:     11,
+			},
+		},
+		{
+			stored:  &ChainConfig{Emission: &EmissionConfig{Block: big.NewInt(10), Schedule: EmissionFixed, Reward: big.NewInt(1)}},
+			new:     &ChainConfig{Emission: &EmissionConfig{Block: big.NewInt(10), Schedule: EmissionFixed, Reward: big.NewInt(2)}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Emission: &EmissionConfig{Block: big.NewInt(10), Schedule: EmissionFixed, Reward: big.NewInt(1)}},
+			new:    &ChainConfig{Emission: &EmissionConfig{Block: big.NewInt(10), Schedule: EmissionFixed, Reward: big.NewInt(2)}},
+			head:   10,
+			wantErr: &ConfigCompatError{
+				What:         "emission schedule",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{Emission: &EmissionConfig{Block: big.NewInt(10), Schedule: EmissionFixed, Reward: big.NewInt(1)}},
+			new:    &ChainConfig{},
+			head:   20,
+			wantErr: &ConfigCompatError{
+				What:         "Emission fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     9,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -79,3 +199,51 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestFeeConfig(t *testing.T) {
+	if share := (&ChainConfig{}).DeveloperShare(); share != DefaultDeveloperShare {
+		t.Errorf("default developer share mismatch: have %d, want %d", share, DefaultDeveloperShare)
+	}
+	config := &ChainConfig{Fee: &FeeConfig{DeveloperShare: 25}}
+	if share := config.DeveloperShare(); share != 25 {
+		t.Errorf("configured developer share mismatch: have %d, want %d", share, 25)
+	}
+	if err := config.CheckConfig(); err != nil {
+		t.Errorf("valid fee config rejected: %v", err)
+	}
+	config.Fee.DeveloperShare = 101
+	if err := config.CheckConfig(); err == nil {
+		t.Errorf("invalid fee config accepted")
+	}
+}
+
+func TestEmissionBlockReward(t *testing.T) {
+	tests := []struct {
+		emission *EmissionConfig
+		number   int64
+		want     *big.Int
+	}{
+		{&EmissionConfig{Schedule: EmissionNone}, 1, big.NewInt(0)},
+		{&EmissionConfig{Schedule: EmissionFixed, Reward: big.NewInt(1000)}, 1, big.NewInt(1000)},
+		{&EmissionConfig{Schedule: EmissionFixed, Reward: big.NewInt(1000)}, 1000000, big.NewInt(1000)},
+		{&EmissionConfig{Schedule: EmissionDecaying, Reward: big.NewInt(1000), DecayInterval: 100, DecayPercent: 50}, 99, big.NewInt(1000)},
+		{&EmissionConfig{Schedule: EmissionDecaying, Reward: big.NewInt(1000), DecayInterval: 100, DecayPercent: 50}, 100, big.NewInt(500)},
+		{&EmissionConfig{Schedule: EmissionDecaying, Reward: big.NewInt(1000), DecayInterval: 100, DecayPercent: 10}, 250, big.NewInt(810)},
+		{&EmissionConfig{Schedule: EmissionDecaying, Reward: big.NewInt(1000), DecayInterval: 1, DecayPercent: 50}, 1 << 40, big.NewInt(0)},
+		{&EmissionConfig{Schedule: EmissionDecaying, Reward: big.NewInt(1000), DecayInterval: 1, DecayPercent: 1}, 687, big.NewInt(1)},
+		{&EmissionConfig{Schedule: EmissionDecaying, Reward: big.NewInt(1000), DecayInterval: 1, DecayPercent: 1}, 688, big.NewInt(0)},
+		{&EmissionConfig{Schedule: EmissionDecaying, Reward: big.NewInt(1000), DecayInterval: 10, DecayPercent: 100}, 10, big.NewInt(0)},
+		// Decay is counted from the activation block
+		{&EmissionConfig{Block: big.NewInt(1000), Schedule: EmissionDecaying, Reward: big.NewInt(1000), DecayInterval: 100, DecayPercent: 50}, 1099, big.NewInt(1000)},
+		{&EmissionConfig{Block: big.NewInt(1000), Schedule: EmissionDecaying, Reward: big.NewInt(1000), DecayInterval: 100, DecayPercent: 50}, 1200, big.NewInt(250)},
+		// Malformed schedules emit nothing
+		{&EmissionConfig{Schedule: "halving", Reward: big.NewInt(1000)}, 1, big.NewInt(0)},
+		{&EmissionConfig{Schedule: EmissionFixed}, 1, big.NewInt(0)},
+		{&EmissionConfig{Schedule: EmissionDecaying, Reward: big.NewInt(1000), DecayPercent: 10}, 1, big.NewInt(0)},
+	}
+	for i, tt := range tests {
+		if have := tt.emission.BlockReward(big.NewInt(tt.number)); have.Cmp(tt.want) != 0 {
+			t.Errorf("test %d (%v at %d): reward mismatch: have %v, want %v", i, tt.emission, tt.number, have, tt.want)
+		}
+	}
+}
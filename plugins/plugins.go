@@ -0,0 +1,149 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package plugins implements loading third-party services into the node from Go
+// plugins, allowing them to provide RPC APIs and follow the chain without forking
+// the node.
+//
+// A plugin is a Go package built with -buildmode=plugin against the same version
+// of this repository as the node, exporting a variable named Plugin that
+// implements the Plugin interface:
+//
+//	var Plugin plugins.Plugin = &myPlugin{}
+package plugins
+
+import (
+	"fmt"
+	goplugin "plugin"
+
+	"github.com/kokprojects/go-kok/core"
+	"github.com/kokprojects/go-kok/core/types"
+	"github.com/kokprojects/go-kok/event"
+	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/p2p"
+	"github.com/kokprojects/go-kok/params"
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// symbolName is the name of the variable a plugin must export.
+const symbolName = "Plugin"
+
+// Backend is the minimal interface of the node a plugin has access to.
+type Backend interface {
+	ChainConfig() *params.ChainConfig
+	CurrentBlock() *types.Block
+
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+	SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subscription
+	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
+}
+
+// Plugin is the interface a plugin loaded into the node must implement.
+type Plugin interface {
+	// Name returns the name of the plugin to identify it by in logs.
+	Name() string
+
+	// Init is called once when the node assembles its services, handing the
+	// plugin the backend it may use for the rest of its life.
+	Init(backend Backend) error
+
+	// APIs retrieves the list of RPC descriptors the plugin provides.
+	APIs() []rpc.API
+
+	// Start is called when the node starts, to spawn any goroutines required
+	// by the plugin.
+	Start() error
+
+	// Stop terminates all goroutines belonging to the plugin, blocking until
+	// they are all terminated.
+	Stop() error
+}
+
+// Load opens the Go plugin at the given path and retrieves the Plugin it exports.
+func Load(path string) (Plugin, error) {
+	lib, err := goplugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := lib.Lookup(symbolName)
+	if err != nil {
+		return nil, err
+	}
+	// Exported variables are looked up as pointers to them
+	if p, ok := sym.(*Plugin); ok && *p != nil {
+		return *p, nil
+	}
+	return nil, fmt.Errorf("%s: exported %s symbol of type %T, want plugins.Plugin", path, symbolName, sym)
+}
+
+// Service is a node.Service running a set of plugins.
+type Service struct {
+	plugins []Plugin
+	started []Plugin
+}
+
+// New initializes the given plugins with the backend and bundles them into a
+// service for registration into a node.
+func New(plugins []Plugin, backend Backend) (*Service, error) {
+	for _, p := range plugins {
+		if err := p.Init(backend); err != nil {
+			return nil, fmt.Errorf("plugin %s: %v", p.Name(), err)
+		}
+	}
+	return &Service{plugins: plugins}, nil
+}
+
+// Protocols implements node.Service, returning no P2P protocols as plugins can't
+// extend the networking layer.
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC APIs of all the plugins.
+func (s *Service) APIs() []rpc.API {
+	var apis []rpc.API
+	for _, p := range s.plugins {
+		apis = append(apis, p.APIs()...)
+	}
+	return apis
+}
+
+// Start implements node.Service, starting all the plugins. If any of them fails,
+// the already started ones are stopped.
+func (s *Service) Start(server *p2p.Server) error {
+	for _, p := range s.plugins {
+		if err := p.Start(); err != nil {
+			s.Stop()
+			return fmt.Errorf("plugin %s: %v", p.Name(), err)
+		}
+		s.started = append(s.started, p)
+		log.Info("Started plugin", "name", p.Name())
+	}
+	return nil
+}
+
+// Stop implements node.Service, stopping all the running plugins in reverse
+// order of their startup.
+func (s *Service) Stop() error {
+	var failure error
+	for i := len(s.started) - 1; i >= 0; i-- {
+		if err := s.started[i].Stop(); err != nil {
+			log.Error("Failed to stop plugin", "name", s.started[i].Name(), "err", err)
+			failure = err
+		}
+	}
+	s.started = nil
+	return failure
+}
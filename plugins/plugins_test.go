@@ -0,0 +1,97 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package plugins
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kokprojects/go-kok/rpc"
+)
+
+// testPlugin is a plugin recording the calls made into it.
+type testPlugin struct {
+	name    string
+	fail    bool
+	calls   *[]string
+	backend Backend
+}
+
+func (p *testPlugin) Name() string { return p.name }
+
+func (p *testPlugin) Init(backend Backend) error {
+	p.backend = backend
+	*p.calls = append(*p.calls, p.name+".init")
+	return nil
+}
+
+func (p *testPlugin) APIs() []rpc.API {
+	return []rpc.API{{Namespace: p.name, Version: "1.0", Service: p}}
+}
+
+func (p *testPlugin) Start() error {
+	if p.fail {
+		return errors.New("start failure")
+	}
+	*p.calls = append(*p.calls, p.name+".start")
+	return nil
+}
+
+func (p *testPlugin) Stop() error {
+	*p.calls = append(*p.calls, p.name+".stop")
+	return nil
+}
+
+// Tests that plugins are driven through their lifecycle by the service, and that
+// a failing plugin rolls back the ones already started.
+func TestServiceLifecycle(t *testing.T) {
+	var calls []string
+	service, err := New([]Plugin{
+		&testPlugin{name: "a", calls: &calls},
+		&testPlugin{name: "b", calls: &calls},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	if apis := service.APIs(); len(apis) != 2 || apis[0].Namespace != "a" || apis[1].Namespace != "b" {
+		t.Errorf("api mismatch: have %v, want namespaces a and b", apis)
+	}
+	if err := service.Start(nil); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	if err := service.Stop(); err != nil {
+		t.Fatalf("failed to stop service: %v", err)
+	}
+	want := []string{"a.init", "b.init", "a.start", "b.start", "b.stop", "a.stop"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("call sequence mismatch: have %v, want %v", calls, want)
+	}
+	// Start a service with a failing plugin and ensure the rest are stopped
+	calls = nil
+	service, _ = New([]Plugin{
+		&testPlugin{name: "a", calls: &calls},
+		&testPlugin{name: "b", calls: &calls, fail: true},
+	}, nil)
+	if err := service.Start(nil); err == nil {
+		t.Fatalf("service started with failing plugin")
+	}
+	want = []string{"a.init", "b.init", "a.start", "a.stop"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("call sequence mismatch: have %v, want %v", calls, want)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// clientEvictInterval is the interval at which the cost trackers of connectionless
+// clients are checked, dropping those whose allowance has been fully restored.
+var clientEvictInterval = time.Minute
+
+// CostBudget is the allowance of request cost units a single client connection
+// may spend on the mkokods of a namespace. Spent units are restored gradually,
+// so a connection may burst up to Capacity units and sustain Refill units per
+// second afterwards.
+type CostBudget struct {
+	Capacity uint64 // Maximum number of cost units a connection may spend at once
+	Refill   uint64 // Number of cost units restored every second
+}
+
+// BudgetExceededError is returned when a request would overspend the cost budget
+// of its connection. It is the RPC equivalent of an HTTP 429 response.
+type BudgetExceededError struct {
+	Namespace  string        // Namespace whose budget was exceeded
+	Cost       uint64        // Cost units the request attempted to spend
+	Available  uint64        // Cost units currently left in the budget
+	RetryAfter time.Duration // Time until enough units are restored to retry
+}
+
+func (e *BudgetExceededError) ErrorCode() int { return -32005 }
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("request cost budget of namespace %s exceeded (cost %d, available %d)", e.Namespace, e.Cost, e.Available)
+}
+
+// ErrorData returns the structured details of the error, sent to the client in
+// the data field of the error response.
+func (e *BudgetExceededError) ErrorData() interface{} {
+	return map[string]interface{}{
+		"namespace":  e.Namespace,
+		"cost":       e.Cost,
+		"available":  e.Available,
+		"retryAfter": e.RetryAfter.Seconds(),
+	}
+}
+
+type budgetKey struct{}
+type namespaceKey struct{}
+
+// costBucket is the remaining allowance of a connection for a single namespace.
+type costBucket struct {
+	level   float64   // Cost units left to spend
+	updated time.Time // Last time the level was refilled
+}
+
+// connBudget tracks the cost units spent by a single client connection across
+// all the namespaces with a configured budget.
+type connBudget struct {
+	budgets map[string]CostBudget // Budget configuration of the server, keyed by namespace
+	buckets map[string]*costBucket
+	lock    sync.Mutex
+}
+
+// newConnBudget creates a cost tracker for a new connection, or nil if there
+// are no budgets configured.
+func newConnBudget(budgets map[string]CostBudget) *connBudget {
+	if len(budgets) == 0 {
+		return nil
+	}
+	return &connBudget{budgets: budgets, buckets: make(map[string]*costBucket)}
+}
+
+// charge spends the given cost units from the budget of a namespace, returning
+// an error if the remaining allowance is insufficient.
+func (b *connBudget) charge(namespace string, cost uint64) error {
+	budget, ok := b.budgets[namespace]
+	if !ok || cost == 0 {
+		return nil
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	bucket := b.buckets[namespace]
+	if bucket == nil {
+		bucket = &costBucket{level: float64(budget.Capacity), updated: now}
+		b.buckets[namespace] = bucket
+	}
+	bucket.level += now.Sub(bucket.updated).Seconds() * float64(budget.Refill)
+	if bucket.level > float64(budget.Capacity) {
+		bucket.level = float64(budget.Capacity)
+	}
+	bucket.updated = now
+
+	if float64(cost) > bucket.level {
+		err := &BudgetExceededError{Namespace: namespace, Cost: cost, Available: uint64(bucket.level)}
+		if budget.Refill > 0 && cost <= budget.Capacity {
+			err.RetryAfter = time.Duration((float64(cost) - bucket.level) / float64(budget.Refill) * float64(time.Second))
+		}
+		return err
+	}
+	bucket.level -= float64(cost)
+	return nil
+}
+
+// idle reports whether all the buckets of the connection have been fully
+// restored, i.e. forgetting the connection would not grant it extra allowance.
+func (b *connBudget) idle() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	for namespace, bucket := range b.buckets {
+		budget := b.budgets[namespace]
+		if bucket.level+now.Sub(bucket.updated).Seconds()*float64(budget.Refill) < float64(budget.Capacity) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetCostBudget configures the cost allowance of every client connection for
+// the mkokods of the given namespace. Each call is charged a base cost of one
+// unit; services may charge additional units for expensive requests through
+// ChargeCost. Budgets must be configured before the server starts serving.
+func (s *Server) SetCostBudget(namespace string, budget CostBudget) {
+	s.budgetsMu.Lock()
+	defer s.budgetsMu.Unlock()
+
+	if s.budgets == nil {
+		s.budgets = make(map[string]CostBudget)
+	}
+	s.budgets[namespace] = budget
+}
+
+// newConnBudget creates a cost tracker for a freshly accepted connection.
+func (s *Server) newConnBudget() *connBudget {
+	s.budgetsMu.Lock()
+	defer s.budgetsMu.Unlock()
+
+	return newConnBudget(s.budgets)
+}
+
+// clientBudget returns the cost tracker of a connectionless (HTTP) client,
+// identified by its remote host. Trackers of clients whose allowance has been
+// fully restored are dropped periodically to keep the set bounded.
+func (s *Server) clientBudget(host string) *connBudget {
+	s.budgetsMu.Lock()
+	defer s.budgetsMu.Unlock()
+
+	if len(s.budgets) == 0 {
+		return nil
+	}
+	if s.clients == nil {
+		s.clients = make(map[string]*connBudget)
+		go s.evictClients()
+	}
+	if budget, ok := s.clients[host]; ok {
+		return budget
+	}
+	budget := newConnBudget(s.budgets)
+	s.clients[host] = budget
+	return budget
+}
+
+// evictClients periodically drops the cost trackers of the connectionless clients
+// whose allowance has been fully restored, until the server is stopped.
+func (s *Server) evictClients() {
+	ticker := time.NewTicker(clientEvictInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if atomic.LoadInt32(&s.run) == 0 {
+			return
+		}
+		s.budgetsMu.Lock()
+		for client, budget := range s.clients {
+			if budget.idle() {
+				delete(s.clients, client)
+			}
+		}
+		s.budgetsMu.Unlock()
+	}
+}
+
+// ChargeCost spends additional cost units from the budget of the connection
+// serving the current request, on behalf of the namespace being invoked. It is
+// meant to be called by RPC mkokods whose cost depends on their parameters. If
+// the budget is exhausted, a *BudgetExceededError is returned which should be
+// passed back to the caller as is.
+func ChargeCost(ctx context.Context, cost uint64) error {
+	budget, _ := ctx.Value(budgetKey{}).(*connBudget)
+	if budget == nil {
+		return nil
+	}
+	namespace, _ := ctx.Value(namespaceKey{}).(string)
+	return budget.charge(namespace, cost)
+}
@@ -0,0 +1,133 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type CostlyService struct{}
+
+func (s *CostlyService) Work(ctx context.Context, cost uint64) (bool, error) {
+	if err := ChargeCost(ctx, cost); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// codedError is a service error carrying an error code of its own.
+type codedError struct{}
+
+func (e *codedError) ErrorCode() int { return -32005 }
+func (e *codedError) Error() string  { return "coded error" }
+
+func (s *CostlyService) Fail() error {
+	return new(codedError)
+}
+
+// Tests that requests are charged against the per-connection cost budget of
+// their namespace and rejected once it is exhausted.
+func TestCostBudget(t *testing.T) {
+	server := NewServer()
+	server.SetCostBudget("costly", CostBudget{Capacity: 10})
+	if err := server.RegisterName("costly", new(CostlyService)); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	var ok bool
+	if err := client.Call(&ok, "costly_work", 5); err != nil {
+		t.Fatalf("first request failed: %v", err)
+	}
+	if err := client.Call(&ok, "costly_work", 3); err != nil {
+		t.Fatalf("second request failed: %v", err)
+	}
+	// 2 base units + 8 charged units spent, any further request must fail
+	err := client.Call(&ok, "costly_work", 0)
+	if err == nil {
+		t.Fatalf("request succeeded with an exhausted budget")
+	}
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("error mismatch: have %v, want budget exceeded error", err)
+	}
+	// Other connections must have their own allowance
+	other := DialInProc(server)
+	defer other.Close()
+
+	if err := other.Call(&ok, "costly_work", 9); err != nil {
+		t.Fatalf("request on fresh connection failed: %v", err)
+	}
+	// The metadata namespace has no budget and must remain accessible
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatalf("unbudgeted request failed: %v", err)
+	}
+}
+
+// Tests that the error codes of service errors aren't passed through to clients,
+// so that only the budget errors of the server use the limit exceeded code.
+func TestServiceErrorCode(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("costly", new(CostlyService)); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	err := client.Call(nil, "costly_fail")
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32000 {
+		t.Fatalf("error mismatch: have %v, want callback error", err)
+	}
+}
+
+// Tests that the cost trackers of connectionless clients are dropped once their
+// allowance has been restored, but kept while they are still depleted.
+func TestClientBudgetEviction(t *testing.T) {
+	defer func(interval time.Duration) { clientEvictInterval = interval }(clientEvictInterval)
+	clientEvictInterval = 10 * time.Millisecond
+
+	server := NewServer()
+	defer server.Stop()
+	server.SetCostBudget("costly", CostBudget{Capacity: 10, Refill: 1000})
+	server.SetCostBudget("slow", CostBudget{Capacity: 10})
+
+	if err := server.clientBudget("restored").charge("costly", 10); err != nil {
+		t.Fatalf("failed to charge restored client: %v", err)
+	}
+	if err := server.clientBudget("depleted").charge("slow", 10); err != nil {
+		t.Fatalf("failed to charge depleted client: %v", err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		server.budgetsMu.Lock()
+		_, restored := server.clients["restored"]
+		_, depleted := server.clients["depleted"]
+		server.budgetsMu.Unlock()
+
+		if !depleted {
+			t.Fatalf("depleted client evicted")
+		}
+		if !restored {
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatalf("restored client not evicted")
+		}
+	}
+}
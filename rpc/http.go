@@ -30,6 +30,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/kokprojects/go-kok/tracing"
 	"github.com/rs/cors"
 )
 
@@ -162,8 +163,16 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	codec := NewJSONCodec(&httpReadWriteNopCloser{r.Body, w})
 	defer codec.Close()
 
+	// Track the request cost against the remote host, as every HTTP request is
+	// served on a fresh codec and per-connection accounting would be meaningless.
+	var budget *connBudget
+	ctx := tracing.Extract(r.Context(), r.Header.Get("traceparent"))
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		budget = srv.clientBudget(host)
+		ctx = context.WithValue(ctx, sessionKey{}, "http:"+host)
+	}
 	w.Header().Set("content-type", contentType)
-	srv.ServeSingleRequest(codec, OptionMkokodInvocation)
+	srv.serveRequest(ctx, codec, true, OptionMkokodInvocation, budget)
 }
 
 // validateRequest returns a non-zero response code and error message if the
@@ -0,0 +1,62 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kokprojects/go-kok/metrics"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// callMetrics tracks the calls of a single RPC method.
+type callMetrics struct {
+	calls  gometrics.Timer // Execution time of every call, whkoker it failed or not
+	errors gometrics.Meter // Calls which returned an error or timed out
+}
+
+var (
+	callMetricsLock   sync.Mutex
+	callMetricsByName = make(map[string]*callMetrics)
+)
+
+// observeCall records the execution time and outcome of a call in the metrics of
+// its method, registered as rpc/<namespace>_<method>/calls and .../errors. The
+// metrics of a method are only created once it is first called.
+func observeCall(namespace, mkokod string, elapsed time.Duration, failed bool) {
+	if !metrics.Enabled {
+		return
+	}
+	name := namespace + serviceMkokodSeparator + formatName(mkokod)
+
+	callMetricsLock.Lock()
+	m, ok := callMetricsByName[name]
+	if !ok {
+		m = &callMetrics{
+			calls:  metrics.NewTimer("rpc/" + name + "/calls"),
+			errors: metrics.NewMeter("rpc/" + name + "/errors"),
+		}
+		callMetricsByName[name] = m
+	}
+	callMetricsLock.Unlock()
+
+	m.calls.Update(elapsed)
+	if failed {
+		m.errors.Mark(1)
+	}
+}
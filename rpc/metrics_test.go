@@ -0,0 +1,83 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kokprojects/go-kok/metrics"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+type MetricsService struct{}
+
+func (s *MetricsService) Succeed() string { return "ok" }
+func (s *MetricsService) Fail() error     { return errors.New("failed") }
+
+// Tests that the calls of every mkokod are timed and their failures metered.
+func TestCallMetrics(t *testing.T) {
+	defer func(enabled bool) { metrics.Enabled = enabled }(metrics.Enabled)
+	metrics.Enabled = true
+
+	server := NewServer()
+	if err := server.RegisterName("metrics", new(MetricsService)); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result string
+	for i := 0; i < 3; i++ {
+		if err := client.Call(&result, "metrics_succeed"); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+	}
+	if err := client.Call(nil, "metrics_fail"); err == nil {
+		t.Fatalf("failing call succeeded")
+	}
+	if err := client.Call(nil, "metrics_missing"); err == nil {
+		t.Fatalf("call to missing mkokod succeeded")
+	}
+	tests := []struct {
+		name   string
+		calls  int64
+		errors int64
+	}{
+		{"metrics_succeed", 3, 0},
+		{"metrics_fail", 1, 1},
+	}
+	for _, tt := range tests {
+		calls, ok := gometrics.DefaultRegistry.Get("rpc/" + tt.name + "/calls").(gometrics.Timer)
+		if !ok {
+			t.Fatalf("%s: call timer not registered", tt.name)
+		}
+		if calls.Count() != tt.calls {
+			t.Errorf("%s: call count mismatch: have %d, want %d", tt.name, calls.Count(), tt.calls)
+		}
+		failures, ok := gometrics.DefaultRegistry.Get("rpc/" + tt.name + "/errors").(gometrics.Meter)
+		if !ok {
+			t.Fatalf("%s: error meter not registered", tt.name)
+		}
+		if failures.Count() != tt.errors {
+			t.Errorf("%s: error count mismatch: have %d, want %d", tt.name, failures.Count(), tt.errors)
+		}
+	}
+	if gometrics.DefaultRegistry.Get("rpc/metrics_missing/calls") != nil {
+		t.Errorf("metrics registered for missing mkokod")
+	}
+}
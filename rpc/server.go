@@ -24,8 +24,10 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/kokprojects/go-kok/log"
+	"github.com/kokprojects/go-kok/tracing"
 	"gopkg.in/fatih/set.v0"
 )
 
@@ -125,7 +127,16 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 // If singleShot is true it will process a single request, otherwise it will handle
 // requests until the codec returns an error when reading a request (in most cases
 // an EOF). It executes requests in parallel when singleShot is false.
-func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption) error {
+//
+// The given budget tracks the request cost spent by the client, nil disabling cost
+// accounting altogether.
+//
+// Requests are executed with a context derived from the given one, which is
+// cancelled once the codec is closed, i.e. the server stops or writing to the
+// client fails, such that pending requests can abandon their work. Reaching the
+// end of the input doesn't cancel them: a client may close only its writing
+// side and still wait for the responses.
+func (s *Server) serveRequest(parent context.Context, codec ServerCodec, singleShot bool, options CodecOption, budget *connBudget) error {
 	var pend sync.WaitGroup
 
 	defer func() {
@@ -140,9 +151,16 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		s.codecsMu.Unlock()
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(parent)
 	defer cancel()
 
+	if budget != nil {
+		ctx = context.WithValue(ctx, budgetKey{}, budget)
+	}
+	if _, ok := SessionFromContext(ctx); !ok {
+		ctx = context.WithValue(ctx, sessionKey{}, string(NewID()))
+	}
+
 	// if the codec supports notification include a notifier that callbacks can use
 	// to send notification to clients. It is thight to the codec/connection. If the
 	// connection is closed the notifier will stop and cancels all active subscriptions.
@@ -157,6 +175,14 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 	s.codecs.Add(codec)
 	s.codecsMu.Unlock()
 
+	go func() {
+		select {
+		case <-codec.Closed():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	// test if the server is ordered to stop
 	for atomic.LoadInt32(&s.run) == 1 {
 		reqs, batch, err := s.readRequest(codec)
@@ -215,14 +241,14 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 // stopped. In either case the codec is closed.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	s.serveRequest(context.Background(), codec, false, options, s.newConnBudget())
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
 // close the codec unless a non-recoverable error has occurred. Note, this mkokod will return after
 // a single request has been processed!
 func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) {
-	s.serveRequest(codec, true, options)
+	s.serveRequest(context.Background(), codec, true, options, nil)
 }
 
 // Stop will stop reading new requests, wait for stopPendingRequestTimeout to allow pending requests to finish,
@@ -299,6 +325,39 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, rpcErr), nil
 	}
 
+	// trace the execution of the call, child spans hang off the context
+	ctx, span := tracing.Start(ctx, req.svcname+serviceMkokodSeparator+formatName(req.callb.mkokod.Name))
+	defer span.End()
+	span.SetAttribute("rpc.system", "jsonrpc")
+	span.SetAttribute("rpc.service", req.svcname)
+	span.SetAttribute("rpc.method", formatName(req.callb.mkokod.Name))
+
+	// scope the context to the call, releasing anything the callback bound to it
+	// (e.g. referenced chain states) as soon as the call returns
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// charge the base cost of the call and let the callback charge any extra
+	if budget, _ := ctx.Value(budgetKey{}).(*connBudget); budget != nil {
+		if err := budget.charge(req.svcname, 1); err != nil {
+			span.SetError(err)
+			return s.errorResponse(codec, req.id, err), nil
+		}
+		ctx = context.WithValue(ctx, namespaceKey{}, req.svcname)
+	}
+	// enforce the execution deadline of the namespace, if any
+	timeout := s.timeout(req.svcname)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	// record the execution time and outcome of the call in the mkokod metrics
+	start, failed := time.Now(), false
+	defer func() {
+		observeCall(req.svcname, req.callb.mkokod.Name, time.Since(start), failed)
+	}()
+
 	arguments := []reflect.Value{req.callb.rcvr}
 	if req.callb.hasCtx {
 		arguments = append(arguments, reflect.ValueOf(ctx))
@@ -307,8 +366,28 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		arguments = append(arguments, req.args...)
 	}
 
-	// execute RPC mkokod and return result
-	reply := req.callb.mkokod.Func.Call(arguments)
+	// execute RPC mkokod and return result, abandoning it if it runs too long
+	var reply []reflect.Value
+	if timeout > 0 {
+		done := make(chan []reflect.Value, 1)
+		go func() {
+			done <- req.callb.mkokod.Func.Call(arguments)
+		}()
+		select {
+		case reply = <-done:
+		case <-ctx.Done():
+			if ctx.Err() != context.DeadlineExceeded {
+				reply = <-done
+				break
+			}
+			err := &RequestTimeoutError{req.svcname, formatName(req.callb.mkokod.Name), timeout}
+			span.SetError(err)
+			failed = true
+			return s.errorResponse(codec, req.id, err), nil
+		}
+	} else {
+		reply = req.callb.mkokod.Func.Call(arguments)
+	}
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
@@ -316,13 +395,28 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if mkokod returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
-			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
-			return res, nil
+			span.SetError(e)
+			failed = true
+			return s.errorResponse(codec, req.id, e), nil
 		}
 	}
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
 }
 
+// errorResponse assembles the error response for a failed callback. The errors
+// of the server's own request limits are reported with their error code and
+// structured details; every other error is reported as a generic callback error.
+func (s *Server) errorResponse(codec ServerCodec, id interface{}, err error) interface{} {
+	switch err := err.(type) {
+	case *BudgetExceededError:
+		return codec.CreateErrorResponseWithInfo(&id, err, err.ErrorData())
+	case *RequestTimeoutError:
+		return codec.CreateErrorResponseWithInfo(&id, err, err.ErrorData())
+	default:
+		return codec.CreateErrorResponse(&id, &callbackError{err.Error()})
+	}
+}
+
 // exec executes the given request and writes the result back using the codec.
 func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) {
 	var response interface{}
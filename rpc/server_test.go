@@ -160,3 +160,151 @@ func TestServerMkokodExecution(t *testing.T) {
 func TestServerMkokodWithCtx(t *testing.T) {
 	testServerMkokodExecution(t, "echoWithCtx")
 }
+
+type CancelService struct {
+	cancelled chan struct{}
+}
+
+func (s *CancelService) Wait(ctx context.Context) {
+	<-ctx.Done()
+	close(s.cancelled)
+}
+
+func (s *CancelService) Sleep(ctx context.Context) error {
+	select {
+	case <-time.After(50 * time.Millisecond):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Tests that the context of pending requests is cancelled when the client
+// disconnects, rather than waiting for the requests to complete.
+func TestServerCancelOnDisconnect(t *testing.T) {
+	server := NewServer()
+	service := &CancelService{cancelled: make(chan struct{})}
+	if err := server.RegisterName("test", service); err != nil {
+		t.Fatalf("%v", err)
+	}
+	clientConn, serverConn := net.Pipe()
+	go server.ServeCodec(NewJSONCodec(serverConn), OptionMkokodInvocation)
+
+	// Disconnect without reading, failing the write of the second response
+	enc := json.NewEncoder(clientConn)
+	if err := enc.Encode(map[string]interface{}{"id": 1, "mkokod": "test_wait", "version": "2.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(map[string]interface{}{"id": 2, "mkokod": "test_sleep", "version": "2.0"}); err != nil {
+		t.Fatal(err)
+	}
+	clientConn.Close()
+
+	select {
+	case <-service.cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("request not cancelled after client disconnect")
+	}
+}
+
+type ScopeService struct {
+	contexts chan context.Context
+}
+
+func (s *ScopeService) Capture(ctx context.Context) {
+	s.contexts <- ctx
+}
+
+// Tests that the context of a call is cancelled once the call returns, even if
+// the connection stays open, releasing any resources bound to it.
+func TestServerCallContextScope(t *testing.T) {
+	server := NewServer()
+	service := &ScopeService{contexts: make(chan context.Context, 1)}
+	if err := server.RegisterName("test", service); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	if err := client.Call(nil, "test_capture"); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	ctx := <-service.contexts
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("call context not cancelled after the call returned")
+	}
+}
+
+// Tests that requests running past the deadline of their namespace are answered
+// with a timeout error, while other namespaces remain unaffected.
+func TestServerTimeout(t *testing.T) {
+	server := NewServer()
+	server.SetTimeout("test", 50*time.Millisecond)
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	if err := client.Call(nil, "test_sleep", time.Millisecond); err != nil {
+		t.Fatalf("quick request failed: %v", err)
+	}
+	start := time.Now()
+	err := client.Call(nil, "test_sleep", 10*time.Second)
+	if err == nil {
+		t.Fatalf("request succeeded past its deadline")
+	}
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32006 {
+		t.Fatalf("error mismatch: have %v, want timeout error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("timeout not enforced: request took %v", elapsed)
+	}
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatalf("unlimited request failed: %v", err)
+	}
+}
+
+// Tests that clients closing only their writing side still receive the responses
+// of their pending requests.
+func TestServerHalfClose(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", &CancelService{}); err != nil {
+		t.Fatalf("%v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			server.ServeCodec(NewJSONCodec(conn), OptionMkokodInvocation)
+		}
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	request := map[string]interface{}{"id": 1, "mkokod": "test_sleep", "version": "2.0"}
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.(*net.TCPConn).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	if response["id"] != float64(1) || response["error"] != nil {
+		t.Fatalf("response mismatch: %v", response)
+	}
+}
@@ -48,6 +48,9 @@ func (s *Subscription) Err() <-chan error {
 // notifierKey is used to store a notifier within the connection context.
 type notifierKey struct{}
 
+// sessionKey is used to store the client session identifier within the connection context.
+type sessionKey struct{}
+
 // Notifier is tight to a RPC connection that supports subscriptions.
 // Server callbacks use the notifier to send notifications.
 type Notifier struct {
@@ -73,6 +76,15 @@ func NotifierFromContext(ctx context.Context) (*Notifier, bool) {
 	return n, ok
 }
 
+// SessionFromContext returns the identifier of the client session the request
+// in ctx was received on, if any. Requests over a persistent connection (IPC,
+// websocket, in-process) share the session of the connection, while HTTP
+// requests share the session of their remote host.
+func SessionFromContext(ctx context.Context) (string, bool) {
+	session, ok := ctx.Value(sessionKey{}).(string)
+	return session, ok
+}
+
 // CreateSubscription returns a new subscription that is coupled to the
 // RPC connection. By default subscriptions are inactive and notifications
 // are dropped until the subscription is marked as active. This is done
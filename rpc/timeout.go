@@ -0,0 +1,69 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"time"
+)
+
+// RequestTimeoutError is returned when a request doesn't complete within the
+// execution deadline configured for its namespace.
+type RequestTimeoutError struct {
+	Namespace string        // Namespace of the timed out mkokod
+	Mkokod    string        // Name of the timed out mkokod
+	Timeout   time.Duration // Execution deadline of the namespace
+}
+
+func (e *RequestTimeoutError) ErrorCode() int { return -32006 }
+
+func (e *RequestTimeoutError) Error() string {
+	return fmt.Sprintf("request %s%s%s timed out after %v", e.Namespace, serviceMkokodSeparator, e.Mkokod, e.Timeout)
+}
+
+// ErrorData returns the structured details of the error, sent to the client in
+// the data field of the error response.
+func (e *RequestTimeoutError) ErrorData() interface{} {
+	return map[string]interface{}{
+		"namespace": e.Namespace,
+		"mkokod":    e.Mkokod,
+		"timeout":   e.Timeout.Seconds(),
+	}
+}
+
+// SetTimeout configures the execution deadline of the mkokods of the given
+// namespace. Requests running past it are answered with a RequestTimeoutError
+// and their context is cancelled, so that services abandon the work. Timeouts
+// must be configured before the server starts serving.
+func (s *Server) SetTimeout(namespace string, timeout time.Duration) {
+	s.timeoutsMu.Lock()
+	defer s.timeoutsMu.Unlock()
+
+	if s.timeouts == nil {
+		s.timeouts = make(map[string]time.Duration)
+	}
+	s.timeouts[namespace] = timeout
+}
+
+// timeout returns the execution deadline of the given namespace, zero if the
+// namespace is unlimited.
+func (s *Server) timeout(namespace string) time.Duration {
+	s.timeoutsMu.Lock()
+	defer s.timeoutsMu.Unlock()
+
+	return s.timeouts[namespace]
+}
@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/kokprojects/go-kok/common/hexutil"
 	"gopkg.in/fatih/set.v0"
@@ -74,6 +75,13 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	budgets   map[string]CostBudget  // Per connection cost allowances, keyed by namespace
+	clients   map[string]*connBudget // Cost trackers of connectionless (HTTP) clients
+	budgetsMu sync.Mutex
+
+	timeouts   map[string]time.Duration // Execution deadlines of requests, keyed by namespace
+	timeoutsMu sync.Mutex
 }
 
 // rpcRequest represents a raw incoming RPC request
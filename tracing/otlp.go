@@ -0,0 +1,278 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kokprojects/go-kok/log"
+)
+
+const (
+	exportQueue    = 4096            // Maximum number of finished spans waiting for export
+	exportBatch    = 512             // Maximum number of spans sent in one request
+	exportInterval = 5 * time.Second // Time between two exports of the queued spans
+	exportTimeout  = 10 * time.Second
+)
+
+// Config contains the settings of the span exporter.
+type Config struct {
+	Endpoint    string  // URL of the OTLP/HTTP traces endpoint of the collector
+	SampleRatio float64 // Fraction of the locally started traces to record
+	ServiceName string  // Service name the spans are reported under
+}
+
+// DefaultConfig contains default settings for the span exporter.
+var DefaultConfig = Config{
+	Endpoint:    "http://localhost:4318/v1/traces",
+	SampleRatio: 1,
+	ServiceName: "gkok",
+}
+
+// exporter batches the finished spans and posts them to an OpenTelemetry
+// collector in the JSON encoding of the OTLP/HTTP protocol.
+type exporter struct {
+	config Config
+	client *http.Client
+
+	queue chan *Span
+	quit  chan chan struct{}
+}
+
+// Setup enables tracing, exporting the recorded spans as configured.
+func Setup(config Config) error {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid tracing endpoint: %v", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return fmt.Errorf("invalid tracing endpoint scheme %q", endpoint.Scheme)
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return fmt.Errorf("invalid tracing sample ratio %v, must be within [0, 1]", config.SampleRatio)
+	}
+	expLock.Lock()
+	defer expLock.Unlock()
+
+	if exp != nil {
+		return errors.New("tracing already enabled")
+	}
+	exp = &exporter{
+		config: config,
+		client: &http.Client{Timeout: exportTimeout},
+		queue:  make(chan *Span, exportQueue),
+		quit:   make(chan chan struct{}),
+	}
+	go exp.loop()
+
+	log.Info("Enabled request tracing", "endpoint", config.Endpoint, "ratio", config.SampleRatio)
+	return nil
+}
+
+// Stop disables tracing, flushing the spans waiting for export.
+func Stop() {
+	expLock.Lock()
+	e := exp
+	exp = nil
+	expLock.Unlock()
+
+	if e != nil {
+		done := make(chan struct{})
+		e.quit <- done
+		<-done
+	}
+}
+
+// export queues a finished span, dropping it if the collector can't keep up.
+func (e *exporter) export(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		log.Trace("Dropped trace span", "name", span.name)
+	}
+}
+
+// loop collects the finished spans and posts them to the collector in batches.
+func (e *exporter) loop() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case span := <-e.queue:
+			if batch = append(batch, span); len(batch) >= exportBatch {
+				e.post(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.post(batch)
+				batch = nil
+			}
+		case done := <-e.quit:
+			for len(e.queue) > 0 {
+				batch = append(batch, <-e.queue)
+			}
+			if len(batch) > 0 {
+				e.post(batch)
+			}
+			close(done)
+			return
+		}
+	}
+}
+
+// post sends a batch of spans to the collector.
+func (e *exporter) post(batch []*Span) {
+	blob, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		log.Warn("Failed to encode trace spans", "err", err)
+		return
+	}
+	res, err := e.client.Post(e.config.Endpoint, "application/json", bytes.NewReader(blob))
+	if err != nil {
+		log.Warn("Failed to export trace spans", "spans", len(batch), "err", err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		log.Warn("Trace collector rejected spans", "spans", len(batch), "status", res.Status)
+	}
+}
+
+// OTLP/JSON representation of the exported spans.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID      string          `json:"traceId"`
+		SpanID       string          `json:"spanId"`
+		ParentSpanID string          `json:"parentSpanId,omitempty"`
+		Name         string          `json:"name"`
+		Kind         int             `json:"kind"`
+		Start        string          `json:"startTimeUnixNano"`
+		End          string          `json:"endTimeUnixNano"`
+		Attributes   []otlpAttribute `json:"attributes,omitempty"`
+		Status       otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// Span kinds and status codes of the OTLP protocol.
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+
+	otlpStatusUnset = 0
+	otlpStatusError = 2
+)
+
+// encode converts a batch of spans into an OTLP export request.
+func (e *exporter) encode(batch []*Span) *otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, span := range batch {
+		span.lock.Lock()
+		encoded := otlpSpan{
+			TraceID: hex.EncodeToString(span.ctx.traceID[:]),
+			SpanID:  hex.EncodeToString(span.ctx.spanID[:]),
+			Name:    span.name,
+			Kind:    otlpKindInternal,
+			Start:   strconv.FormatInt(span.start.UnixNano(), 10),
+			End:     strconv.FormatInt(span.end.UnixNano(), 10),
+			Status:  otlpStatus{Code: otlpStatusUnset},
+		}
+		if span.parent != ([8]byte{}) {
+			encoded.ParentSpanID = hex.EncodeToString(span.parent[:])
+		}
+		if span.server {
+			encoded.Kind = otlpKindServer
+		}
+		for _, attr := range span.attrs {
+			encoded.Attributes = append(encoded.Attributes, encodeAttribute(attr.key, attr.value))
+		}
+		if span.err != nil {
+			encoded.Status = otlpStatus{Code: otlpStatusError, Message: span.err.Error()}
+		}
+		span.lock.Unlock()
+
+		spans = append(spans, encoded)
+	}
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{encodeAttribute("service.name", e.config.ServiceName)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/kokprojects/go-kok/tracing"},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+// encodeAttribute converts a key-value pair into its OTLP representation.
+func encodeAttribute(key string, value interface{}) otlpAttribute {
+	var encoded map[string]interface{}
+	switch v := value.(type) {
+	case string:
+		encoded = map[string]interface{}{"stringValue": v}
+	case bool:
+		encoded = map[string]interface{}{"boolValue": v}
+	case int:
+		encoded = map[string]interface{}{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int64:
+		encoded = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case uint64:
+		encoded = map[string]interface{}{"intValue": strconv.FormatUint(v, 10)}
+	case float64:
+		encoded = map[string]interface{}{"doubleValue": v}
+	default:
+		encoded = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+	}
+	return otlpAttribute{Key: key, Value: encoded}
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing records spans of request processing, from the RPC dispatch
+// down to state access and EVM execution, and exports them to an OpenTelemetry
+// collector (e.g. Jaeger or Tempo) over OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spanContext identifies a span and the trace it belongs to.
+type spanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+	sampled bool // Whether the spans of the trace are recorded
+	remote  bool // Whether the span was started by another process
+}
+
+// spanKey is the context key under which the current span is stored.
+type spanKey struct{}
+
+// attribute is a key-value pair annotating a span.
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// Span is a timed operation of a trace. Spans of unsampled traces, as well as
+// all spans while tracing is disabled, are nil; all methods are safe to call on
+// a nil span.
+type Span struct {
+	ctx    spanContext
+	parent [8]byte
+	name   string
+	server bool // Whether the span is the entry point of the process into the trace
+
+	lock  sync.Mutex
+	start time.Time
+	end   time.Time
+	attrs []attribute
+	err   error
+
+	exp *exporter
+}
+
+var (
+	exp     *exporter    // Exporter of the finished spans, nil if tracing is disabled
+	expLock sync.RWMutex // Protects the exporter during setup and shutdown
+)
+
+// Enabled reports whether spans are being recorded.
+func Enabled() bool {
+	expLock.RLock()
+	defer expLock.RUnlock()
+
+	return exp != nil
+}
+
+// Start opens a new span with the given name as a child of the span in the
+// context, or as the root of a new trace if there is none. The returned context
+// carries the new span, which must be closed with End.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	expLock.RLock()
+	e := exp
+	expLock.RUnlock()
+
+	if e == nil {
+		return ctx, nil
+	}
+	parent, ok := ctx.Value(spanKey{}).(spanContext)
+	if ok && !parent.sampled {
+		return ctx, nil
+	}
+	span := &Span{name: name, start: time.Now(), exp: e}
+	if ok {
+		span.ctx.traceID = parent.traceID
+		span.parent = parent.spanID
+		span.server = parent.remote
+	} else {
+		if mrand.Float64() >= e.config.SampleRatio {
+			// Remember the decision, so children don't start traces of their own
+			var unsampled spanContext
+			rand.Read(unsampled.traceID[:])
+			return context.WithValue(ctx, spanKey{}, unsampled), nil
+		}
+		rand.Read(span.ctx.traceID[:])
+		span.server = true
+	}
+	rand.Read(span.ctx.spanID[:])
+	span.ctx.sampled = true
+
+	return context.WithValue(ctx, spanKey{}, span.ctx), span
+}
+
+// SetAttribute annotates the span with a key-value pair. Strings, booleans,
+// integers and floats are exported as such, anything else in its printed form.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.attrs = append(s.attrs, attribute{key, value})
+}
+
+// SetError marks the operation of the span as failed. Nil errors are ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.err = err
+}
+
+// End closes the span and queues it for export. Calling End more than once has
+// no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if !s.end.IsZero() {
+		s.lock.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.lock.Unlock()
+
+	s.exp.export(s)
+}
+
+// Extract returns a context continuing the trace of a W3C traceparent header
+// (version-traceid-parentid-flags), as sent by instrumented callers. Spans are
+// recorded if the caller sampled the trace. Malformed headers are ignored.
+func Extract(ctx context.Context, traceparent string) context.Context {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return ctx
+	}
+	var parent spanContext
+	if err := decodeHex(parent.traceID[:], parts[1]); err != nil {
+		return ctx
+	}
+	if err := decodeHex(parent.spanID[:], parts[2]); err != nil {
+		return ctx
+	}
+	var flags [1]byte
+	if err := decodeHex(flags[:], parts[3]); err != nil {
+		return ctx
+	}
+	if parent.traceID == ([16]byte{}) || parent.spanID == ([8]byte{}) {
+		return ctx
+	}
+	parent.sampled = flags[0]&0x01 != 0
+	parent.remote = true
+
+	return context.WithValue(ctx, spanKey{}, parent)
+}
+
+// decodeHex decodes a hex string of exactly the length of the destination.
+func decodeHex(dst []byte, input string) error {
+	if len(input) != 2*len(dst) {
+		return fmt.Errorf("invalid length %d, want %d", len(input), 2*len(dst))
+	}
+	_, err := hex.Decode(dst, []byte(input))
+	return err
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 The go-kokereum Authors
+// This file is part of the go-kokereum library.
+//
+// The go-kokereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-kokereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-kokereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Tests that spans are only recorded while tracing is enabled, that they are
+// linked to their parents and that they are exported to the collector.
+func TestSpanExport(t *testing.T) {
+	if _, span := Start(context.Background(), "disabled"); span != nil {
+		t.Fatalf("span recorded while tracing disabled")
+	}
+	requests := make(chan *otlpRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(otlpRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Errorf("failed to decode export request: %v", err)
+		}
+		requests <- req
+	}))
+	defer collector.Close()
+
+	if err := Setup(Config{Endpoint: collector.URL, SampleRatio: 1, ServiceName: "test"}); err != nil {
+		t.Fatalf("failed to enable tracing: %v", err)
+	}
+	ctx, root := Start(context.Background(), "root")
+	_, child := Start(ctx, "child")
+	child.SetAttribute("gas", uint64(21000))
+	child.SetError(errors.New("out of gas"))
+	child.End()
+	root.End()
+	Stop()
+
+	req := <-requests
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("malformed export request: %+v", req)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("span count mismatch: have %d, want 2", len(spans))
+	}
+	have, parent := spans[0], spans[1]
+	if have.Name != "child" || parent.Name != "root" {
+		t.Fatalf("span order mismatch: have %s, %s", have.Name, parent.Name)
+	}
+	if have.TraceID != parent.TraceID || have.ParentSpanID != parent.SpanID || parent.ParentSpanID != "" {
+		t.Errorf("span linkage mismatch: child %+v, root %+v", have, parent)
+	}
+	if parent.Kind != otlpKindServer || have.Kind != otlpKindInternal {
+		t.Errorf("span kind mismatch: root %d, child %d", parent.Kind, have.Kind)
+	}
+	if have.Status.Code != otlpStatusError || have.Status.Message != "out of gas" {
+		t.Errorf("span status mismatch: have %+v", have.Status)
+	}
+	if len(have.Attributes) != 1 || have.Attributes[0].Value["intValue"] != "21000" {
+		t.Errorf("span attributes mismatch: have %+v", have.Attributes)
+	}
+}
+
+// Tests that traces started by remote callers are continued as advertised.
+func TestExtract(t *testing.T) {
+	if err := Setup(Config{Endpoint: "http://localhost:4318/v1/traces", SampleRatio: 0}); err != nil {
+		t.Fatalf("failed to enable tracing: %v", err)
+	}
+	defer Stop()
+
+	// Locally started traces are dropped with a zero sample ratio
+	if _, span := Start(context.Background(), "local"); span != nil {
+		t.Errorf("local trace sampled with zero ratio")
+	}
+	// Sampled remote traces are continued regardless
+	ctx := Extract(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span := Start(ctx, "remote")
+	if span == nil {
+		t.Fatalf("sampled remote trace not recorded")
+	}
+	if have := hex.EncodeToString(span.ctx.traceID[:]); have != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id mismatch: have %s", have)
+	}
+	if have := hex.EncodeToString(span.parent[:]); have != "00f067aa0ba902b7" || !span.server {
+		t.Errorf("remote parent mismatch: have %s, server %v", have, span.server)
+	}
+	// Unsampled and malformed headers don't produce spans
+	for _, header := range []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", "00-xyz-00f067aa0ba902b7-01", ""} {
+		if _, span := Start(Extract(context.Background(), header), "remote"); span != nil {
+			t.Errorf("header %q: span recorded", header)
+		}
+	}
+}